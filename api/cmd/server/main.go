@@ -15,12 +15,14 @@ import (
 	"github.com/neurondb/NeuronIP/api/internal/agents"
 	"github.com/neurondb/NeuronIP/api/internal/ai"
 	"github.com/neurondb/NeuronIP/api/internal/rag"
+	"github.com/neurondb/NeuronIP/api/internal/reporting"
 	"github.com/neurondb/NeuronIP/api/internal/alerts"
 	"github.com/neurondb/NeuronIP/api/internal/analytics"
 	"github.com/neurondb/NeuronIP/api/internal/audit"
 	"github.com/neurondb/NeuronIP/api/internal/auth"
 	"github.com/neurondb/NeuronIP/api/internal/backup"
 	"github.com/neurondb/NeuronIP/api/internal/billing"
+	"github.com/neurondb/NeuronIP/api/internal/cache"
 	"github.com/neurondb/NeuronIP/api/internal/catalog"
 	"github.com/neurondb/NeuronIP/api/internal/classification"
 	"github.com/neurondb/NeuronIP/api/internal/comments"
@@ -51,13 +53,14 @@ import (
 	"github.com/neurondb/NeuronIP/api/internal/support"
 	"github.com/neurondb/NeuronIP/api/internal/tenancy"
 	"github.com/neurondb/NeuronIP/api/internal/tracing"
+	"github.com/neurondb/NeuronIP/api/internal/users"
 	"github.com/neurondb/NeuronIP/api/internal/versioning"
 	"github.com/neurondb/NeuronIP/api/internal/warehouse"
+	"github.com/neurondb/NeuronIP/api/internal/warehouse/federation"
 	"github.com/neurondb/NeuronIP/api/internal/webhooks"
 	"github.com/neurondb/NeuronIP/api/internal/workflows"
 	"github.com/neurondb/NeuronIP/api/internal/session"
 	"github.com/neurondb/NeuronIP/api/internal/execution"
-	"github.com/neurondb/NeuronIP/api/internal/collaboration"
 	slackbot "github.com/neurondb/NeuronIP/api/internal/integrations/slack"
 	teamsbot "github.com/neurondb/NeuronIP/api/internal/integrations/teams"
 	bibot "github.com/neurondb/NeuronIP/api/internal/integrations/bi"
@@ -133,9 +136,6 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Create Pool wrapper for backward compatibility
-	poolWrapper := &db.Pool{Pool: pool}
-
 	logger.Info("Database pools created successfully",
 		"max_conns", cfg.Database.MaxOpenConns,
 		"min_conns", cfg.Database.MaxIdleConns,
@@ -198,111 +198,179 @@ func main() {
 	// Initialize agent client
 	agentClient := agent.NewClient(cfg.NeuronAgent.Endpoint, cfg.NeuronAgent.APIKey)
 
+	// Initialize semantic answer cache shared by RAG and warehouse Q&A
+	semanticCacheService := cache.NewSemanticCacheService(pool, cache.SemanticCacheConfig{
+		Enabled:             cfg.SemanticCache.Enabled,
+		SimilarityThreshold: cfg.SemanticCache.SimilarityThreshold,
+		TTL:                 cfg.SemanticCache.TTL,
+		ScopeByUser:         cfg.SemanticCache.ScopeByUser,
+	})
+	semanticCacheHandler := handlers.NewSemanticCacheHandler(semanticCacheService)
+
 	// Initialize services (use pool directly - services can be enhanced later to use context-aware pools)
-	semanticService := semantic.NewService(queries, pool, neurondbClient, mcpClient)
+	semanticService := semantic.NewServiceWithSemanticCache(queries, pool, neurondbClient, mcpClient, semanticCacheService)
 	approvalService := semantic.NewApprovalService(pool)
 	ownershipService := semantic.NewMetricOwnershipService(pool)
 	lineageService := semantic.NewLineageService(pool)
 	semanticHandler := handlers.NewSemanticHandler(semanticService, approvalService, ownershipService, lineageService)
 
 	// Initialize pipeline service
-	pipelineService := semantic.NewPipelineService(pool)
+	pipelineService := semantic.NewPipelineServiceWithSemanticCache(pool, neurondbClient, semanticCacheService)
 	pipelineHandler := handlers.NewPipelineHandler(pipelineService)
 
-	// Initialize warehouse service
-	warehouseService := warehouse.NewService(pool, agentClient, neurondbClient, mcpClient)
+	// Initialize governance service; queries over their EXPLAIN cost limit run as async queries
+	asyncQueryService := execution.NewAsyncQueryService(pool)
+	governanceService := warehouse.NewGovernanceServiceWithAdmission(pool, asyncQueryService, warehouse.AdmissionConfig{
+		MaxConcurrent:    cfg.WarehouseAdmission.MaxConcurrent,
+		MaxQueueWait:     cfg.WarehouseAdmission.MaxQueueWait,
+		ExplainTimeout:   cfg.WarehouseAdmission.ExplainTimeout,
+		DefaultCostLimit: cfg.WarehouseAdmission.DefaultCostLimit,
+		AsyncExpiryHours: cfg.WarehouseAdmission.AsyncExpiryHours,
+	})
+	governanceHandler := handlers.NewGovernanceHandler(governanceService)
+	asyncQueryHandler := handlers.NewAsyncQueryHandler(asyncQueryService)
+
+	// Initialize data contracts; every connector sync is validated against the active contracts of its datasets
+	contractService := catalog.NewContractService(pool, users.NewNotificationService(pool))
+	dataContractHandler := handlers.NewDataContractHandler(contractService)
+
+	// Initialize cache service
+	cacheService := warehouse.NewCacheServiceWithSemanticCache(pool, semanticCacheService)
+	cacheHandler := handlers.NewCacheHandler(cacheService)
+
+	// Initialize schema evolution tracking; detected changes invalidate cached results for the table
+	schemaEvolutionService := catalog.NewSchemaEvolutionServiceWithCacheInvalidation(pool, cacheService)
+	schemaEvolutionHandler := handlers.NewSchemaEvolutionHandler(schemaEvolutionService)
+
+	// Initialize connector framework service; federated warehouse queries read connector tables through it,
+	// and every sync's stored schema is tracked by schema evolution
+	connectorService := connectors.NewConnectorServiceWithSchemaTracking(pool, contractService, schemaEvolutionService)
+	federationService := federation.NewService(connectorService, federation.Config{
+		MemoryLimitBytes: int64(cfg.Federation.MemoryLimitMB) << 20,
+		SpillDir:         cfg.Federation.SpillDir,
+		SpillPartitions:  cfg.Federation.SpillPartitions,
+		MaxResultRows:    cfg.Federation.MaxResultRows,
+		QueryTimeout:     cfg.Federation.QueryTimeout,
+	})
+	defer federationService.Close()
+
+	// Initialize warehouse service; approved catalog metrics compile through the semantic layer and failed SQL is regenerated
+	metricsCatalogService := catalog.NewMetricsService(pool)
+	warehouseService := warehouse.NewServiceWithOptions(pool, agentClient, neurondbClient, mcpClient, warehouse.ServiceOptions{
+		SemanticCache: semanticCacheService,
+		ResultLimits: &warehouse.ResultLimits{
+			DefaultPageSize:   cfg.WarehouseResults.DefaultPageSize,
+			MaxPageSize:       cfg.WarehouseResults.MaxPageSize,
+			MaxTotalRows:      cfg.WarehouseResults.MaxTotalRows,
+			MaxOpenCursors:    cfg.WarehouseResults.MaxOpenCursors,
+			CursorIdleTimeout: cfg.WarehouseResults.CursorIdleTimeout,
+			StatementTimeout:  cfg.WarehouseResults.StatementTimeout,
+		},
+		Governance: governanceService,
+		Metrics:    metricsCatalogService,
+		Correction: &warehouse.CorrectionConfig{
+			MaxAttempts:       cfg.WarehouseCorrection.MaxAttempts,
+			RetryEmptyResults: cfg.WarehouseCorrection.RetryEmptyResults,
+		},
+		Federation: federationService,
+	})
 	warehouseHandler := handlers.NewWarehouseHandler(warehouseService)
 
+	// Queries moved off the interactive path by admission control are taken off the job queue and run in the background
+	warehouseService.StartAsyncQueryWorker(ctx, execution.NewJobQueueService(pool), "warehouse-async", 5*time.Second)
+
+	// Initialize query template service
+	queryTemplateService := warehouse.NewQueryTemplateService(pool)
+	queryTemplateHandler := handlers.NewQueryTemplateHandler(queryTemplateService)
+
 	// Initialize saved search service
-	savedSearchService := warehouse.NewSavedSearchService(pool)
+	savedSearchService := warehouse.NewSavedSearchServiceWithTemplates(pool, queryTemplateService)
 	savedSearchHandler := handlers.NewSavedSearchHandler(savedSearchService, warehouseService)
 
-	// Initialize governance service
-	governanceService := warehouse.NewGovernanceService(pool)
-	governanceHandler := handlers.NewGovernanceHandler(governanceService)
+	// Initialize reporting service; report sections can run query templates
+	reportingService := reporting.NewReportingServiceWithTemplates(pool, queryTemplateService)
+	reportingHandler := handlers.NewReportingHandler(reportingService)
 
-	// Initialize cache service
-	cacheService := warehouse.NewCacheService(pool)
-	cacheHandler := handlers.NewCacheHandler(cacheService)
 
 	// Initialize workflow service
 	workflowService := workflows.NewService(pool, agentClient, neurondbClient, mcpClient)
 	workflowHandler := handlers.NewWorkflowHandler(workflowService)
 
 	// Initialize compliance services
-	complianceService := compliance.NewService(pool.Pool, neurondbClient)
-	anomalyService := compliance.NewAnomalyService(pool.Pool, neurondbClient)
-	policyService := compliance.NewPolicyService(pool.Pool, neurondbClient)
+	complianceService := compliance.NewService(pool, neurondbClient)
+	anomalyService := compliance.NewAnomalyService(pool, neurondbClient)
+	policyService := compliance.NewPolicyService(pool, neurondbClient)
 	complianceHandler := handlers.NewComplianceHandler(complianceService, anomalyService, policyService)
 
 	// Initialize analytics service
-	analyticsService := analytics.NewService(pool.Pool, neurondbClient, mcpClient)
+	analyticsService := analytics.NewService(pool, neurondbClient, mcpClient)
 	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
 
 	// Initialize models service
-	modelsService := models.NewService(pool.Pool, neurondbClient, mcpClient)
+	modelsService := models.NewService(pool, neurondbClient, mcpClient)
 	modelsHandler := handlers.NewModelHandler(modelsService)
 
 	// Initialize integrations services
-	integrationsService := integrations.NewIntegrationsService(pool.Pool)
-	helpdeskService := integrations.NewHelpdeskService(pool.Pool)
-	webhookService := integrations.NewWebhookService(pool.Pool)
+	integrationsService := integrations.NewIntegrationsService(pool)
+	helpdeskService := integrations.NewHelpdeskService(pool)
+	webhookService := integrations.NewWebhookService(pool)
 	integrationHandler := handlers.NewIntegrationHandler(integrationsService, helpdeskService, webhookService)
 
 	// Initialize alerts service
-	alertsService := alerts.NewService(pool.Pool, anomalyService)
+	alertsService := alerts.NewService(pool, anomalyService)
 	alertsHandler := handlers.NewAlertsHandler(alertsService)
 
 	// Initialize support service
-	supportService := support.NewService(queries, pool.Pool, agentClient, neurondbClient)
+	groundednessService := observability.NewGroundednessService(pool, neurondbClient, mcpClient, cfg.Groundedness)
+	supportService := support.NewServiceWithGroundedness(queries, pool, agentClient, neurondbClient, groundednessService)
 	supportHandler := handlers.NewSupportHandler(supportService)
 
 	// Initialize knowledge graph service
-	knowledgeGraphService := knowledgegraph.NewService(pool.Pool, neurondbClient)
+	knowledgeGraphService := knowledgegraph.NewService(pool, neurondbClient)
 	knowledgeGraphHandler := handlers.NewKnowledgeGraphHandler(knowledgeGraphService)
 
 	// Initialize data sources service
-	dataSourceService := datasources.NewDataSourceService(pool.Pool)
+	dataSourceService := datasources.NewDataSourceService(pool)
 	dataSourceHandler := handlers.NewDataSourceHandler(dataSourceService)
 
 	// Initialize business metrics service (semantic layer)
-	businessMetricsService := metrics.NewMetricsService(pool.Pool)
+	businessMetricsService := metrics.NewMetricsService(pool)
 
 	// Initialize metrics service (metric catalog)
-	metricsCatalogService := catalog.NewMetricsService(pool.Pool)
 	businessMetricsHandler := handlers.NewMetricsHandler(businessMetricsService, metricsCatalogService)
 
 	// Initialize agents service
-	agentsService := agents.NewAgentsService(pool.Pool, agentClient)
+	agentsService := agents.NewAgentsService(pool, agentClient)
 	agentsHandler := handlers.NewAgentsHandler(agentsService)
 
 	// Initialize observability service
-	observabilityService := observability.NewObservabilityService(pool.Pool)
-	observabilityHandler := handlers.NewObservabilityHandler(pool.Pool)
+	observabilityHandler := handlers.NewObservabilityHandler(pool)
+	retrievalEvaluationService := observability.NewRetrievalEvaluationService(pool, neurondbClient, mcpClient)
+	retrievalEvaluationHandler := handlers.NewRetrievalEvaluationHandler(retrievalEvaluationService)
 	
 	// Initialize model governance handler
-	modelGovernanceHandler := handlers.NewModelGovernanceHandler(pool.Pool)
+	modelGovernanceHandler := handlers.NewModelGovernanceHandler(pool)
 	
 	// Initialize collaboration handler
-	collaborationHandler := handlers.NewCollaborationHandler(pool.Pool)
+	collaborationHandler := handlers.NewCollaborationHandlerWithTemplates(pool, queryTemplateService)
 	
 	// Initialize execution services
-	replicaService := execution.NewReplicaService(pool.Pool)
-	shardService := execution.NewShardService(pool.Pool)
-	jobQueueService := execution.NewJobQueueService(pool.Pool)
+	replicaService := execution.NewReplicaService(pool)
+	shardService := execution.NewShardService(pool)
+	jobQueueService := execution.NewJobQueueService(pool)
 	_ = replicaService // Used for read routing
 	_ = shardService   // Used for sharding
 	_ = jobQueueService // Used for job queue
 	
 	// Initialize quota service
-	quotaService := tenancy.NewQuotaService(pool.Pool)
+	quotaService := tenancy.NewQuotaService(pool)
 	_ = quotaService // Used for resource limits
 	
 	// Initialize row security service
 	rowSecurityService := auth.NewRowSecurityService(queries)
 	
 	// Initialize policy-aware service
-	policyAwareService := semantic.NewPolicyAwareService(pool.Pool, rowSecurityService)
+	policyAwareService := semantic.NewPolicyAwareService(pool, rowSecurityService)
 	_ = policyAwareService // Used for policy-aware retrieval
 	
 	// Initialize Slack bot service
@@ -318,7 +386,7 @@ func main() {
 	biExportService := bibot.NewBIExportService(warehouseService)
 
 	// Initialize metrics collector
-	metricsCollector := metrics.NewMetricsCollector(pool.Pool)
+	metricsCollector := metrics.NewMetricsCollector(pool)
 	metricsEnhancedHandler := handlers.NewMetricsEnhancedHandler(metricsCollector)
 
 	// Initialize tracing service
@@ -330,37 +398,52 @@ func main() {
 	apiRouter.Use(middleware.TimeoutByRoute(timeoutConfig))
 
 	// Initialize lineage service
-	lineageService := lineage.NewLineageService(pool.Pool)
-	lineageHandler := handlers.NewLineageHandler(lineageService)
+	dataLineageService := lineage.NewLineageService(pool)
+	lineageHandler := handlers.NewLineageHandler(dataLineageService)
 
 	// Audit service is initialized above with new audit package
 
 	// Initialize billing service
-	billingService := billing.NewBillingService(pool.Pool)
+	billingService := billing.NewBillingService(pool)
 	billingHandler := handlers.NewBillingHandler(billingService)
 
 	// Initialize versioning service
-	versioningService := versioning.NewVersioningService(pool.Pool)
+	versioningService := versioning.NewVersioningService(pool)
 	versioningHandler := handlers.NewVersioningHandler(versioningService)
 
 	// Initialize catalog service with NeuronDB client for multimodal embeddings
-	catalogService := catalog.NewCatalogServiceWithNeuronDB(pool.Pool, neurondbClient)
+	catalogService := catalog.NewCatalogServiceWithNeuronDB(pool, neurondbClient)
 	catalogHandler := handlers.NewCatalogHandler(catalogService)
 
 	// Initialize semantic definitions service
 	// Note: semantic search handler uses semantic.Service, not catalog.SemanticService
-	_ = catalog.NewSemanticService(pool.Pool) // Reserved for future use
+	_ = catalog.NewSemanticService(pool) // Reserved for future use
 
 	// Initialize unified AI service for orchestration
 	unifiedAIService := ai.NewUnifiedAIService(neurondbClient, mcpClient, agentClient)
 	unifiedAIHandler := handlers.NewUnifiedAIHandler(unifiedAIService)
 
 	// Initialize unified RAG service
-	unifiedRAGService := rag.NewUnifiedRAGService(neurondbClient, mcpClient, agentClient)
+	unifiedRAGService := rag.NewUnifiedRAGServiceWithOptions(neurondbClient, mcpClient, agentClient, rag.ServiceOptions{
+		Pool:                 pool,
+		SemanticCache:        semanticCacheService,
+		RetrievalMetrics:     observability.NewRetrievalMetricsService(pool),
+		HallucinationService: observability.NewHallucinationDetectionService(pool),
+		Groundedness:         groundednessService,
+	})
 	unifiedRAGHandler := handlers.NewUnifiedRAGHandler(unifiedRAGService)
 
-	// Initialize ingestion service
-	ingestionService := ingestion.NewService(pool.Pool, mcpClient)
+	// Initialize data quality service with MCP and Agent clients for ML-powered analysis, and connectors for consistency rules
+	dataQualityService := dataquality.NewServiceWithConnectors(pool, neurondbClient, mcpClient, agentClient, connectorService)
+
+	// Initialize ingestion service; jobs with quality gates check staged batches before commit, and jobs
+	// naming a catalog connector are validated against its data contracts
+	ingestionService := ingestion.NewServiceWithOptions(pool, mcpClient, ingestion.ServiceOptions{
+		TableListener: cacheService,
+		Quality:       dataQualityService,
+		Alerts:        alertsService,
+		Contracts:     contractService,
+	})
 	// Register connector factories to avoid import cycles
 	ingestionService.RegisterConnectorFactory("zendesk", func(ct string) ingestion.Connector {
 		return ingestionconnectors.NewZendeskConnector()
@@ -469,12 +552,12 @@ func main() {
 	ingestionHandler := handlers.NewIngestionHandler(ingestionService)
 
 	// Initialize audit service
-	auditService := audit.NewAuditService(pool.Pool)
+	auditService := audit.NewAuditService(pool)
 	auditHandler := handlers.NewAuditHandler(auditService)
 
 	// Initialize session manager
 	sessionManager := session.NewManager(
-		pool.Pool,
+		pool,
 		cfg.Auth.Session.AccessTokenTTL,
 		cfg.Auth.Session.RefreshTokenTTL,
 		cfg.Auth.Session.CookieDomain,
@@ -484,7 +567,7 @@ func main() {
 
 	// Start session cleanup service (runs every hour)
 	var cleanupService *session.CleanupService
-	cleanupService = session.NewCleanupService(pool.Pool, 1*time.Hour)
+	cleanupService = session.NewCleanupService(pool, 1*time.Hour)
 	cleanupService.Start(ctx)
 
 	// Apply session middleware to API routes (before API key middleware)
@@ -522,10 +605,10 @@ func main() {
 	// if tenancyMode == "" {
 	// 	tenancyMode = tenancy.TenancyModeSchema
 	// }
-	// tenancyService := tenancy.NewTenancyService(pool.Pool, tenancyMode)
+	// tenancyService := tenancy.NewTenancyService(pool, tenancyMode)
 
 	// Initialize policy engine
-	policyEngine := policy.NewPolicyEngine(pool.Pool)
+	policyEngine := policy.NewPolicyEngine(pool)
 	policyHandler := handlers.NewPolicyHandler(policyEngine)
 
 	// Initialize webhook handler (webhookService already initialized above)
@@ -536,6 +619,9 @@ func main() {
 	apiRouter.HandleFunc("/semantic/rag", semanticHandler.RAG).Methods("POST")
 	apiRouter.HandleFunc("/semantic/documents", semanticHandler.CreateDocument).Methods("POST")
 	apiRouter.HandleFunc("/semantic/documents/{id}", semanticHandler.UpdateDocument).Methods("PUT")
+	apiRouter.HandleFunc("/semantic/documents/{id}/duplicates", semanticHandler.GetDocumentDuplicates).Methods("GET")
+	apiRouter.HandleFunc("/semantic/indexes/advise", semanticHandler.AdviseVectorIndex).Methods("POST")
+	apiRouter.HandleFunc("/semantic/indexes/{name}/status", semanticHandler.GetVectorIndexStatus).Methods("GET")
 	apiRouter.HandleFunc("/semantic/collections/{id}", semanticHandler.GetCollection).Methods("GET")
 
 	// Unified AI routes
@@ -547,6 +633,9 @@ func main() {
 	apiRouter.HandleFunc("/rag/query", unifiedRAGHandler.PerformRAG).Methods("POST")
 	apiRouter.HandleFunc("/rag/query/stream", unifiedRAGHandler.PerformRAGStream).Methods("POST")
 	apiRouter.HandleFunc("/rag/status", unifiedRAGHandler.GetRAGStatus).Methods("GET")
+	apiRouter.HandleFunc("/rag/conversations", unifiedRAGHandler.CreateConversation).Methods("POST")
+	apiRouter.HandleFunc("/rag/conversations/{id}", unifiedRAGHandler.GetConversation).Methods("GET")
+	apiRouter.HandleFunc("/rag/conversations/{id}", unifiedRAGHandler.DeleteConversation).Methods("DELETE")
 
 	// Pipeline routes
 	apiRouter.HandleFunc("/semantic/pipelines", pipelineHandler.CreatePipeline).Methods("POST")
@@ -557,7 +646,18 @@ func main() {
 
 	// Warehouse routes
 	apiRouter.HandleFunc("/warehouse/query", warehouseHandler.Query).Methods("POST")
+	apiRouter.HandleFunc("/warehouse/query/cursor", warehouseHandler.QueryCursor).Methods("POST")
+	apiRouter.HandleFunc("/warehouse/query/pages", warehouseHandler.GetResultPage).Methods("GET")
+	apiRouter.HandleFunc("/warehouse/query/stream", warehouseHandler.StreamQuery).Methods("POST")
+	apiRouter.HandleFunc("/warehouse/metrics/query", warehouseHandler.QueryMetrics).Methods("POST")
+	apiRouter.HandleFunc("/warehouse/metrics/compile", warehouseHandler.CompileMetrics).Methods("POST")
+	apiRouter.HandleFunc("/warehouse/federated/query", warehouseHandler.FederatedQuery).Methods("POST")
+	apiRouter.HandleFunc("/warehouse/federated/plan", warehouseHandler.PlanFederatedQuery).Methods("POST")
+	apiRouter.HandleFunc("/warehouse/charts/recommend", warehouseHandler.RecommendCharts).Methods("POST")
+	apiRouter.HandleFunc("/warehouse/cursors/{id}", warehouseHandler.CloseResultCursor).Methods("DELETE")
+	apiRouter.HandleFunc("/warehouse/queries/corrections", warehouseHandler.GetCorrectionStats).Methods("GET")
 	apiRouter.HandleFunc("/warehouse/queries/{id}", warehouseHandler.GetQuery).Methods("GET")
+	apiRouter.HandleFunc("/warehouse/queries/{id}/attempts", warehouseHandler.GetQueryAttempts).Methods("GET")
 	apiRouter.HandleFunc("/warehouse/queries/history", warehouseHandler.GetQueryHistory).Methods("GET")
 	apiRouter.HandleFunc("/warehouse/optimize", warehouseHandler.GetQueryOptimization).Methods("POST")
 	apiRouter.HandleFunc("/warehouse/schemas", warehouseHandler.ListSchemas).Methods("GET")
@@ -565,6 +665,11 @@ func main() {
 	apiRouter.HandleFunc("/warehouse/schemas/{id}", warehouseHandler.GetSchema).Methods("GET")
 
 	// Saved searches routes
+	apiRouter.HandleFunc("/warehouse/templates", queryTemplateHandler.ListTemplates).Methods("GET")
+	apiRouter.HandleFunc("/warehouse/templates", queryTemplateHandler.CreateTemplate).Methods("POST")
+	apiRouter.HandleFunc("/warehouse/templates/{id}", queryTemplateHandler.GetTemplate).Methods("GET")
+	apiRouter.HandleFunc("/warehouse/templates/{id}", queryTemplateHandler.DeleteTemplate).Methods("DELETE")
+	apiRouter.HandleFunc("/warehouse/templates/{id}/execute", queryTemplateHandler.ExecuteTemplate).Methods("POST")
 	apiRouter.HandleFunc("/warehouse/saved-searches", savedSearchHandler.ListSavedSearches).Methods("GET")
 	apiRouter.HandleFunc("/warehouse/saved-searches", savedSearchHandler.CreateSavedSearch).Methods("POST")
 	apiRouter.HandleFunc("/warehouse/saved-searches/{id}", savedSearchHandler.GetSavedSearch).Methods("GET")
@@ -572,14 +677,28 @@ func main() {
 	apiRouter.HandleFunc("/warehouse/saved-searches/{id}", savedSearchHandler.DeleteSavedSearch).Methods("DELETE")
 	apiRouter.HandleFunc("/warehouse/saved-searches/{id}/execute", savedSearchHandler.ExecuteSavedSearch).Methods("POST")
 
+	// Reporting routes
+	apiRouter.HandleFunc("/reports", reportingHandler.ListReports).Methods("GET")
+	apiRouter.HandleFunc("/reports", reportingHandler.CreateReport).Methods("POST")
+	apiRouter.HandleFunc("/reports/{id}", reportingHandler.GetReport).Methods("GET")
+	apiRouter.HandleFunc("/reports/{id}/generate", reportingHandler.GenerateReport).Methods("POST")
+
 	// Query governance routes
 	apiRouter.HandleFunc("/warehouse/governance/validate", governanceHandler.ValidateQuery).Methods("POST")
 	apiRouter.HandleFunc("/warehouse/governance/sanitize", governanceHandler.SanitizeQuery).Methods("POST")
+	apiRouter.HandleFunc("/warehouse/async-queries", asyncQueryHandler.ListAsyncQueries).Methods("GET")
+	apiRouter.HandleFunc("/warehouse/async-queries/{id}", asyncQueryHandler.GetAsyncQuery).Methods("GET")
+	apiRouter.HandleFunc("/warehouse/async-queries/{id}", asyncQueryHandler.CancelAsyncQuery).Methods("DELETE")
 
 	// Cache routes
 	apiRouter.HandleFunc("/warehouse/cache", cacheHandler.GetCachedResult).Methods("GET")
 	apiRouter.HandleFunc("/warehouse/cache/invalidate", cacheHandler.InvalidateCache).Methods("POST")
 	apiRouter.HandleFunc("/warehouse/cache/stats", cacheHandler.GetCacheStats).Methods("GET")
+	apiRouter.HandleFunc("/warehouse/cache/invalidate/tables", cacheHandler.InvalidateTables).Methods("POST")
+	apiRouter.HandleFunc("/warehouse/cache/staleness", cacheHandler.ListTableStaleness).Methods("GET")
+	apiRouter.HandleFunc("/warehouse/cache/staleness/{table}", cacheHandler.SetTableStaleness).Methods("PUT")
+	apiRouter.HandleFunc("/cache/semantic/stats", semanticCacheHandler.GetStats).Methods("GET")
+	apiRouter.HandleFunc("/cache/semantic/invalidate", semanticCacheHandler.Invalidate).Methods("POST")
 
 	// Workflow routes
 	apiRouter.HandleFunc("/workflows", workflowHandler.ListWorkflows).Methods("GET")
@@ -640,6 +759,7 @@ func main() {
 	apiRouter.HandleFunc("/alerts/rules", alertsHandler.CreateAlertRule).Methods("POST")
 	apiRouter.HandleFunc("/alerts/rules/{id}", alertsHandler.UpdateAlertRule).Methods("PUT")
 	apiRouter.HandleFunc("/alerts/rules/{id}", alertsHandler.DeleteAlertRule).Methods("DELETE")
+	apiRouter.HandleFunc("/alerts/rules/{id}/band", alertsHandler.GetAlertRuleBand).Methods("GET")
 
 	// Support routes
 	apiRouter.HandleFunc("/support/tickets", supportHandler.CreateTicket).Methods("POST")
@@ -648,6 +768,7 @@ func main() {
 	apiRouter.HandleFunc("/support/tickets/{id}/conversations", supportHandler.AddConversation).Methods("POST")
 	apiRouter.HandleFunc("/support/tickets/{id}/conversations", supportHandler.GetConversations).Methods("GET")
 	apiRouter.HandleFunc("/support/tickets/{id}/similar-cases", supportHandler.GetSimilarCases).Methods("GET")
+	apiRouter.HandleFunc("/support/tickets/{id}/reply", supportHandler.GenerateReply).Methods("POST")
 
 	// Knowledge graph routes
 	apiRouter.HandleFunc("/knowledge-graph/entities/extract", knowledgeGraphHandler.ExtractEntities).Methods("POST")
@@ -740,12 +861,22 @@ func main() {
 	apiRouter.HandleFunc("/catalog/search", catalogHandler.SearchDatasets).Methods("GET")
 	apiRouter.HandleFunc("/catalog/owners", catalogHandler.ListOwners).Methods("GET")
 	apiRouter.HandleFunc("/catalog/discover", catalogHandler.DiscoverDatasets).Methods("POST")
+	apiRouter.HandleFunc("/catalog/schema-evolution/track", schemaEvolutionHandler.TrackSchemaEvolution).Methods("POST")
+	apiRouter.HandleFunc("/catalog/schema-evolution/{connector_id}/{schema_name}/{table_name}/history", schemaEvolutionHandler.GetSchemaHistory).Methods("GET")
+	apiRouter.HandleFunc("/catalog/contracts", dataContractHandler.CreateContract).Methods("POST")
+	apiRouter.HandleFunc("/catalog/contracts", dataContractHandler.ListContracts).Methods("GET")
+	apiRouter.HandleFunc("/catalog/contracts/{id}", dataContractHandler.GetContract).Methods("GET")
+	apiRouter.HandleFunc("/catalog/contracts/{id}/activate", dataContractHandler.ActivateContract).Methods("POST")
+	apiRouter.HandleFunc("/catalog/contracts/{id}/retire", dataContractHandler.RetireContract).Methods("POST")
+	apiRouter.HandleFunc("/catalog/contracts/{id}/violations", dataContractHandler.ListViolations).Methods("GET")
+	apiRouter.HandleFunc("/catalog/contracts/{connector_id}/{schema_name}/{table_name}/versions", dataContractHandler.GetContractVersions).Methods("GET")
 
 	// Metric catalog routes (using business metrics handler which has both services)
 	apiRouter.HandleFunc("/catalog/metrics", businessMetricsHandler.ListMetrics).Methods("GET")
 	apiRouter.HandleFunc("/catalog/metrics", businessMetricsHandler.CreateMetric).Methods("POST")
 	apiRouter.HandleFunc("/catalog/metrics/{id}", businessMetricsHandler.GetMetric).Methods("GET")
 	apiRouter.HandleFunc("/catalog/metrics/{id}/lineage", businessMetricsHandler.GetMetricLineage).Methods("GET")
+	apiRouter.HandleFunc("/catalog/metrics/{id}/definition", businessMetricsHandler.SetMetricDefinition).Methods("PUT")
 
 	// Ingestion routes
 	apiRouter.HandleFunc("/ingestion/jobs", ingestionHandler.CreateJob).Methods("POST")
@@ -798,44 +929,41 @@ func main() {
 		SessionTimeout:    24 * time.Hour,
 		EnableAutoMapping: true,
 	}
-	ssoService := auth.NewSSOService(pool.Pool, ssoConfig)
+	ssoService := auth.NewSSOService(pool, ssoConfig)
 	ssoHandler := handlers.NewSSOHandler(ssoService)
 
 	// Initialize comments service
-	commentsService := comments.NewService(pool.Pool)
+	commentsService := comments.NewService(pool)
 	commentsHandler := handlers.NewCommentsHandler(commentsService)
 
 	// Initialize ownership service
-	ownershipService := ownership.NewService(pool.Pool)
-	ownershipHandler := handlers.NewOwnershipHandler(ownershipService)
+	assetOwnershipService := ownership.NewService(pool)
+	ownershipHandler := handlers.NewOwnershipHandler(assetOwnershipService)
 
 	// Initialize webhooks service (new implementation)
-	webhooksService := webhooks.NewService(pool.Pool)
+	webhooksService := webhooks.NewService(pool)
 	webhooksHandler := handlers.NewWebhooksHandler(webhooksService)
 
-	// Initialize connector framework service
-	connectorService := connectors.NewConnectorService(pool.Pool)
+	// Initialize connector framework handler
 	connectorHandler := handlers.NewConnectorHandler(connectorService)
 
-	// Initialize data quality service
-	// Initialize data quality service with MCP and Agent clients for ML-powered analysis
-	dataQualityService := dataquality.NewServiceWithMCPAndAgent(pool.Pool, neurondbClient, mcpClient, agentClient)
+	// Initialize data quality handler
 	dataQualityHandler := handlers.NewDataQualityHandler(dataQualityService)
 
 	// Initialize profiling service
-	profilingService := profiling.NewService(pool.Pool, neurondbClient, mcpClient)
-	profilingHandler := handlers.NewProfilingHandler(profilingService)
+	profilingService := profiling.NewService(pool, neurondbClient, mcpClient)
+	profilingHandler := handlers.NewProfilingHandlerWithDriftAlerts(profilingService, alerts.NewDriftAlertService(alertsService, profilingService))
 
 	// Initialize classification service
-	classificationService := classification.NewService(pool.Pool)
+	classificationService := classification.NewService(pool)
 	classificationHandler := handlers.NewClassificationHandler(classificationService)
 
 	// Initialize column lineage service
-	columnLineageService := lineage.NewColumnLineageService(pool.Pool)
+	columnLineageService := lineage.NewColumnLineageService(pool)
 	columnLineageHandler := handlers.NewColumnLineageHandler(columnLineageService)
 
 	// Initialize region service (Phase 2.1)
-	regionService := tenancy.NewRegionService(pool.Pool)
+	regionService := tenancy.NewRegionService(pool)
 	regionHandler := handlers.NewRegionHandler(regionService)
 
 	// Initialize backup service (Phase 2.2)
@@ -844,7 +972,7 @@ func main() {
 		RetentionDays: 30,
 		Compress:      true,
 	}
-	backupService := backup.NewBackupService(pool.Pool, backupConfig)
+	backupService := backup.NewBackupService(pool, backupConfig)
 	backupHandler := handlers.NewBackupHandler(backupService)
 
 	// Initialize column and row security services (Phase 2.3 - already implemented)
@@ -853,19 +981,19 @@ func main() {
 	_ = auth.NewRowSecurityService(queries)
 
 	// Initialize DSAR service (Phase 2.4)
-	dsarService := compliance.NewDSARService(pool.Pool)
+	dsarService := compliance.NewDSARService(pool)
 	dsarHandler := handlers.NewDSARHandler(dsarService)
 
 	// Initialize PIA service (Phase 2.4)
-	piaService := compliance.NewPIAService(pool.Pool)
+	piaService := compliance.NewPIAService(pool)
 	piaHandler := handlers.NewPIAHandler(piaService)
 
 	// Initialize consent service (Phase 2.4)
-	consentService := compliance.NewConsentService(pool.Pool)
+	consentService := compliance.NewConsentService(pool)
 	consentHandler := handlers.NewConsentHandler(consentService)
 
 	// Initialize masking service (Phase 2.5)
-	maskingService := masking.NewMaskingService(pool.Pool)
+	maskingService := masking.NewMaskingService(pool)
 	maskingHandler := handlers.NewMaskingHandler(maskingService)
 
 	// SSO routes
@@ -903,14 +1031,25 @@ func main() {
 
 	// Data quality routes
 	apiRouter.HandleFunc("/data-quality/rules", dataQualityHandler.CreateRule).Methods("POST")
+	apiRouter.HandleFunc("/data-quality/rules/import", dataQualityHandler.ImportRules).Methods("POST")
 	apiRouter.HandleFunc("/data-quality/rules/{id}", dataQualityHandler.GetRule).Methods("GET")
 	apiRouter.HandleFunc("/data-quality/rules/{id}/execute", dataQualityHandler.ExecuteRule).Methods("POST")
+	apiRouter.HandleFunc("/data-quality/rules/{id}/band", dataQualityHandler.GetRuleBand).Methods("GET")
 	apiRouter.HandleFunc("/data-quality/dashboard", dataQualityHandler.GetDashboard).Methods("GET")
 	apiRouter.HandleFunc("/data-quality/trends", dataQualityHandler.GetTrends).Methods("GET")
+	apiRouter.HandleFunc("/data-quality/trends/band", dataQualityHandler.GetTrendBand).Methods("GET")
 
 	// Data profiling routes
 	apiRouter.HandleFunc("/profiling/connectors/{connector_id}/schemas/{schema_name}/tables/{table_name}", profilingHandler.ProfileTable).Methods("POST")
 	apiRouter.HandleFunc("/profiling/connectors/{connector_id}/schemas/{schema_name}/tables/{table_name}/columns/{column_name}", profilingHandler.ProfileColumn).Methods("POST")
+	apiRouter.HandleFunc("/profiling/connectors/{connector_id}/schemas/{schema_name}/tables/{table_name}/columns/{column_name}/sketch", profilingHandler.GetSketchProfile).Methods("GET")
+	apiRouter.HandleFunc("/profiling/connectors/{connector_id}/schemas/{schema_name}/tables/{table_name}/structure", profilingHandler.DiscoverStructure).Methods("POST")
+	apiRouter.HandleFunc("/profiling/connectors/{connector_id}/schemas/{schema_name}/tables/{table_name}/structure", profilingHandler.GetStructure).Methods("GET")
+	apiRouter.HandleFunc("/profiling/suggested-joins", profilingHandler.ListSuggestedJoins).Methods("GET")
+	apiRouter.HandleFunc("/profiling/suggested-joins/{id}", profilingHandler.ReviewSuggestedJoin).Methods("PUT")
+	apiRouter.HandleFunc("/profiling/connectors/{connector_id}/schemas/{schema_name}/tables/{table_name}/snapshots", profilingHandler.ListSnapshots).Methods("GET")
+	apiRouter.HandleFunc("/profiling/snapshots/{id}", profilingHandler.GetSnapshot).Methods("GET")
+	apiRouter.HandleFunc("/profiling/drift", profilingHandler.CompareSnapshots).Methods("POST")
 
 	// Classification routes
 	apiRouter.HandleFunc("/classification/connectors/{connector_id}/schemas/{schema_name}/tables/{table_name}/columns/{column_name}", classificationHandler.ClassifyColumn).Methods("POST")
@@ -989,6 +1128,16 @@ func main() {
 	apiRouter.HandleFunc("/observability/agents/{agent_id}/logs", observabilityHandler.GetAgentExecutionLogs).Methods("GET")
 	apiRouter.HandleFunc("/observability/retrieval/metrics", observabilityHandler.GetRetrievalMetrics).Methods("GET")
 	apiRouter.HandleFunc("/observability/retrieval/stats", observabilityHandler.GetRetrievalStats).Methods("GET")
+	apiRouter.HandleFunc("/observability/retrieval/golden-sets", retrievalEvaluationHandler.CreateGoldenSet).Methods("POST")
+	apiRouter.HandleFunc("/observability/retrieval/golden-sets", retrievalEvaluationHandler.ListGoldenSets).Methods("GET")
+	apiRouter.HandleFunc("/observability/retrieval/golden-sets/{id}", retrievalEvaluationHandler.GetGoldenSet).Methods("GET")
+	apiRouter.HandleFunc("/observability/retrieval/golden-sets/{id}/queries", retrievalEvaluationHandler.AddGoldenQuery).Methods("POST")
+	apiRouter.HandleFunc("/observability/retrieval/golden-sets/{id}/queries/{query_id}", retrievalEvaluationHandler.DeleteGoldenQuery).Methods("DELETE")
+	apiRouter.HandleFunc("/observability/retrieval/golden-sets/{id}/runs", retrievalEvaluationHandler.RunEvaluation).Methods("POST")
+	apiRouter.HandleFunc("/observability/retrieval/golden-sets/{id}/runs", retrievalEvaluationHandler.ListEvaluationRuns).Methods("GET")
+	apiRouter.HandleFunc("/observability/retrieval/golden-sets/{id}/baseline", retrievalEvaluationHandler.SetBaselineRun).Methods("PUT")
+	apiRouter.HandleFunc("/observability/retrieval/evaluation-runs/{id}", retrievalEvaluationHandler.GetEvaluationRun).Methods("GET")
+	apiRouter.HandleFunc("/observability/retrieval/evaluation-runs/{id}/compare", retrievalEvaluationHandler.CompareEvaluationRuns).Methods("GET")
 	apiRouter.HandleFunc("/observability/hallucination/signals", observabilityHandler.GetHallucinationSignals).Methods("GET")
 	apiRouter.HandleFunc("/observability/hallucination/stats", observabilityHandler.GetHallucinationStats).Methods("GET")
 	apiRouter.HandleFunc("/observability/queries/{id}/cost", observabilityHandler.GetQueryCost).Methods("GET")
@@ -1002,7 +1151,11 @@ func main() {
 	apiRouter.HandleFunc("/collaboration/dashboards", collaborationHandler.GetSharedDashboards).Methods("GET")
 	apiRouter.HandleFunc("/collaboration/dashboards/{id}/comments", collaborationHandler.AddDashboardComment).Methods("POST")
 	apiRouter.HandleFunc("/collaboration/dashboards/{id}/comments", collaborationHandler.GetDashboardComments).Methods("GET")
+	apiRouter.HandleFunc("/collaboration/dashboards/{id}/charts/{key}", collaborationHandler.SetDashboardChart).Methods("PUT")
 	apiRouter.HandleFunc("/collaboration/answer-cards", collaborationHandler.CreateAnswerCard).Methods("POST")
+	apiRouter.HandleFunc("/collaboration/answer-cards/{id}", collaborationHandler.GetAnswerCard).Methods("GET")
+	apiRouter.HandleFunc("/collaboration/answer-cards/{id}/refresh", collaborationHandler.RefreshAnswerCard).Methods("POST")
+	apiRouter.HandleFunc("/collaboration/answer-cards/{id}/chart", collaborationHandler.SetAnswerCardChart).Methods("PUT")
 	apiRouter.HandleFunc("/collaboration/saved-questions", collaborationHandler.SaveQuestion).Methods("POST")
 
 	// Enterprise Feature Routes - Governance (RLS)
@@ -1011,7 +1164,7 @@ func main() {
 	apiRouter.HandleFunc("/governance/rls/policies", rlsHandler.CreateRLSPolicy).Methods("POST")
 	
 	// Enterprise Feature Routes - Resource Quotas
-	quotaHandler := handlers.NewQuotaHandler(pool.Pool)
+	quotaHandler := handlers.NewQuotaHandler(pool)
 	apiRouter.HandleFunc("/quotas", quotaHandler.SetQuota).Methods("POST")
 	apiRouter.HandleFunc("/quotas", quotaHandler.ListQuotas).Methods("GET")
 	apiRouter.HandleFunc("/quotas/check", quotaHandler.CheckQuota).Methods("POST")
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -8,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 /* Client provides NeuronAgent integration */
@@ -159,10 +161,14 @@ func (c *Client) SetAgentMemory(ctx context.Context, agentID string, memoryKey s
 
 /* ConvertNLToSQL converts natural language to SQL via NeuronAgent API */
 func (c *Client) ConvertNLToSQL(ctx context.Context, query string, schema map[string]interface{}) (string, error) {
-	reqBody := map[string]interface{}{
+	return c.convertNLToSQL(ctx, map[string]interface{}{
 		"query":  query,
 		"schema": schema,
-	}
+	})
+}
+
+/* convertNLToSQL posts a conversion request and returns the generated SQL */
+func (c *Client) convertNLToSQL(ctx context.Context, reqBody map[string]interface{}) (string, error) {
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal request: %w", err)
@@ -198,6 +204,22 @@ func (c *Client) ConvertNLToSQL(ctx context.Context, query string, schema map[st
 	return sql, nil
 }
 
+/* SQLFeedback describes why a previously generated query was rejected */
+type SQLFeedback struct {
+	SQL   string `json:"sql"`
+	Stage string `json:"stage"` // validation, plan, execution or empty_result
+	Error string `json:"error"`
+}
+
+/* CorrectNLToSQL regenerates SQL for a question given the failures of earlier attempts and the relevant schema */
+func (c *Client) CorrectNLToSQL(ctx context.Context, query string, schema map[string]interface{}, feedback []SQLFeedback) (string, error) {
+	return c.convertNLToSQL(ctx, map[string]interface{}{
+		"query":    query,
+		"schema":   schema,
+		"feedback": feedback,
+	})
+}
+
 /* GenerateReply generates a context-aware reply via NeuronAgent API */
 func (c *Client) GenerateReply(ctx context.Context, context []map[string]interface{}, prompt string) (string, error) {
 	reqBody := map[string]interface{}{
@@ -239,6 +261,73 @@ func (c *Client) GenerateReply(ctx context.Context, context []map[string]interfa
 	return reply, nil
 }
 
+/* StreamReply generates a reply with context, calling handler with each token delta as it is produced.
+ * The reply is read from an SSE stream of {"delta": "..."} events terminated by [DONE]; the full reply is returned. */
+func (c *Client) StreamReply(ctx context.Context, context []map[string]interface{}, prompt string, handler func(delta string) error) (string, error) {
+	reqBody := map[string]interface{}{
+		"context": context,
+		"prompt":  prompt,
+		"stream":  true,
+	}
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint+"/api/v1/generate/reply/stream", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("agent API returned status %d", resp.StatusCode)
+	}
+
+	var reply strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return reply.String(), fmt.Errorf("failed to decode stream event: %w", err)
+		}
+		if msg, ok := event["error"].(string); ok && msg != "" {
+			return reply.String(), fmt.Errorf("agent stream error: %s", msg)
+		}
+		delta, _ := event["delta"].(string)
+		if delta == "" {
+			continue
+		}
+		reply.WriteString(delta)
+		if err := handler(delta); err != nil {
+			return reply.String(), err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return reply.String(), fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	return reply.String(), nil
+}
+
 // ============================================================================
 // Session Management
 // ============================================================================
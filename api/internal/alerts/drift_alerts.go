@@ -0,0 +1,99 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/neurondb/NeuronIP/api/internal/profiling"
+)
+
+/* DriftAlertService raises alerts when profile snapshots drift past their thresholds */
+type DriftAlertService struct {
+	alertService     *Service
+	profilingService *profiling.Service
+}
+
+/* NewDriftAlertService creates a new drift alert service */
+func NewDriftAlertService(alertService *Service, profilingService *profiling.Service) *DriftAlertService {
+	return &DriftAlertService{
+		alertService:     alertService,
+		profilingService: profilingService,
+	}
+}
+
+/* DriftCheck selects the snapshots to compare: BaselineID and CurrentID, or else the table's two newest */
+type DriftCheck struct {
+	ConnectorID uuid.UUID                 `json:"connector_id"`
+	SchemaName  string                    `json:"schema_name"`
+	TableName   string                    `json:"table_name"`
+	BaselineID  *uuid.UUID                `json:"baseline_id,omitempty"`
+	CurrentID   *uuid.UUID                `json:"current_id,omitempty"`
+	Thresholds  profiling.DriftThresholds `json:"thresholds"`
+	AlertRuleID *uuid.UUID                `json:"alert_rule_id,omitempty"` // Rule drift alerts are raised under; no alert without it
+}
+
+/* CheckDrift compares the snapshots and, when they drifted and the check names an alert rule, raises an alert.
+ * Severity is high when a column changed type or disappeared, or PSI reached 0.25, and medium otherwise. */
+func (s *DriftAlertService) CheckDrift(ctx context.Context, check DriftCheck) (*profiling.DriftReport, *Alert, error) {
+	var report *profiling.DriftReport
+	var err error
+	if check.BaselineID != nil && check.CurrentID != nil {
+		report, err = s.profilingService.CompareSnapshots(ctx, *check.BaselineID, *check.CurrentID, check.Thresholds)
+	} else {
+		report, err = s.profilingService.CompareLatestSnapshots(ctx, check.ConnectorID, check.SchemaName, check.TableName, check.Thresholds)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compare profile snapshots: %w", err)
+	}
+	if !report.Drifted || check.AlertRuleID == nil {
+		return report, nil, nil
+	}
+
+	severity := "medium"
+	if report.MaxPSI >= 0.25 {
+		severity = "high"
+	}
+	drifted := map[string][]string{}
+	for _, c := range report.Columns {
+		if len(c.Exceeded) == 0 {
+			continue
+		}
+		drifted[c.ColumnName] = c.Exceeded
+		for _, e := range c.Exceeded {
+			if e == "type" || c.Status == "removed" {
+				severity = "high"
+			}
+		}
+	}
+	columns := make([]string, 0, len(drifted))
+	for column := range drifted {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	alert, err := s.alertService.RaiseAlert(ctx, Alert{
+		RuleID:   *check.AlertRuleID,
+		Severity: severity,
+		Message: fmt.Sprintf("Profile of %s.%s drifted in %d column(s): %s",
+			report.SchemaName, report.TableName, len(columns), strings.Join(columns, ", ")),
+		Details: map[string]interface{}{
+			"connector_id":     report.ConnectorID.String(),
+			"schema_name":      report.SchemaName,
+			"table_name":       report.TableName,
+			"baseline_id":      report.BaselineID.String(),
+			"current_id":       report.CurrentID.String(),
+			"max_psi":          report.MaxPSI,
+			"max_ks":           report.MaxKS,
+			"drifted_columns":  drifted,
+			"row_count_before": report.RowCountBefore,
+			"row_count_after":  report.RowCountAfter,
+		},
+	})
+	if err != nil {
+		return report, nil, fmt.Errorf("failed to raise drift alert: %w", err)
+	}
+	return report, alert, nil
+}
@@ -0,0 +1,141 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neurondb/NeuronIP/api/internal/anomaly"
+)
+
+/* AnomalyMethodLearnedBand, set in an anomaly rule's config as method, alerts when a value leaves the band
+ * learned from its own history instead of reading anomaly detections */
+const AnomalyMethodLearnedBand = "learned_band"
+
+/* bandSource is the series a learned band rule watches and its current value */
+type bandSource struct {
+	series  anomaly.Series
+	value   float64
+	at      time.Time
+	records bool // whether the rule records the value itself
+}
+
+/* bandSourceFor resolves the series of a learned band rule. Config series_kind and series_key watch a series
+ * recorded elsewhere, such as a table's row counts or a freshness monitor's lag; otherwise the rule records its metric. */
+func (s *Service) bandSourceFor(ctx context.Context, rule AlertRule) (*bandSource, error) {
+	kind, _ := rule.Config["series_kind"].(string)
+	key, _ := rule.Config["series_key"].(string)
+	if kind == "" {
+		value, err := s.getMetricValue(ctx, rule.Metric)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metric value: %w", err)
+		}
+		return &bandSource{
+			series:  anomaly.Series{Kind: anomaly.SeriesMetric, Key: rule.Metric},
+			value:   value,
+			at:      time.Now(),
+			records: true,
+		}, nil
+	}
+
+	if key == "" {
+		return nil, fmt.Errorf("series_key is required with series_kind")
+	}
+	series := anomaly.Series{Kind: kind, Key: key}
+	latest, err := s.bands.Latest(ctx, series)
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	return &bandSource{series: series, value: latest.Value, at: latest.Time}, nil
+}
+
+/* checkLearnedBandRule alerts when the rule's value is outside its learned band.
+ * Config direction limits alerts to "above" or "below" the band; sensitivity and history_days tune the band. */
+func (s *Service) checkLearnedBandRule(ctx context.Context, rule AlertRule) (*Alert, error) {
+	source, err := s.bandSourceFor(ctx, rule)
+	if err != nil || source == nil {
+		return nil, err
+	}
+
+	opts := anomaly.BandOptionsFromConfig(rule.Config)
+	var band *anomaly.Band
+	var eval *anomaly.Evaluation
+	if source.records {
+		band, eval, err = s.bands.Observe(ctx, source.series, source.value, source.at, opts)
+	} else {
+		band, err = s.bands.LearnBand(ctx, source.series, source.at, opts)
+		if err == nil {
+			e := band.Evaluate(source.value)
+			eval = &e
+		}
+	}
+	if errors.Is(err, anomaly.ErrInsufficientHistory) {
+		return nil, nil // Nothing to compare with until the series has history
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to learn band: %w", err)
+	}
+
+	direction, _ := rule.Config["direction"].(string)
+	if !eval.Outside || (direction != "" && direction != "both" && direction != eval.Direction) {
+		return nil, nil
+	}
+
+	band.History = nil
+	alert := Alert{
+		ID:       uuid.New(),
+		RuleID:   rule.ID,
+		Severity: severityFromDeviation(eval.Deviation),
+		Message:  fmt.Sprintf("%s %s: %s", source.series.Kind, source.series.Key, eval.Describe()),
+		Details: map[string]interface{}{
+			"series_kind": source.series.Kind,
+			"series_key":  source.series.Key,
+			"value":       eval.Value,
+			"evaluation":  eval,
+			"band":        band,
+			"explanation": band.Explanation,
+		},
+		Status:    "active",
+		CreatedAt: time.Now(),
+	}
+	if err := s.storeAlert(ctx, alert); err != nil {
+		return nil, fmt.Errorf("failed to store alert: %w", err)
+	}
+	return &alert, nil
+}
+
+/* severityFromDeviation rates how many standard deviations a value is from its expected value */
+func severityFromDeviation(deviation float64) string {
+	switch d := math.Abs(deviation); {
+	case d >= 8:
+		return "critical"
+	case d >= 5:
+		return "high"
+	default:
+		return "medium"
+	}
+}
+
+/* GetRuleBand returns the band a learned band rule currently judges its series against, with the history behind it */
+func (s *Service) GetRuleBand(ctx context.Context, ruleID uuid.UUID) (*anomaly.Band, error) {
+	rule, err := s.GetAlertRule(ctx, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if method, _ := rule.Config["method"].(string); rule.RuleType != "anomaly" || method != AnomalyMethodLearnedBand {
+		return nil, fmt.Errorf("alert rule does not use a learned band")
+	}
+
+	series := anomaly.Series{Kind: anomaly.SeriesMetric, Key: rule.Metric}
+	if kind, _ := rule.Config["series_kind"].(string); kind != "" {
+		key, _ := rule.Config["series_key"].(string)
+		series = anomaly.Series{Kind: kind, Key: key}
+	}
+	return s.bands.LearnBand(ctx, series, time.Now(), anomaly.BandOptionsFromConfig(rule.Config))
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/anomaly"
 	"github.com/neurondb/NeuronIP/api/internal/compliance"
 )
 
@@ -15,6 +16,7 @@ import (
 type Service struct {
 	pool          *pgxpool.Pool
 	anomalyService *compliance.AnomalyService
+	bands          *anomaly.Service
 }
 
 /* NewService creates a new alerts service */
@@ -22,6 +24,7 @@ func NewService(pool *pgxpool.Pool, anomalyService *compliance.AnomalyService) *
 	return &Service{
 		pool:          pool,
 		anomalyService: anomalyService,
+		bands:          anomaly.NewService(pool),
 	}
 }
 
@@ -141,6 +144,10 @@ func (s *Service) checkThresholdRule(ctx context.Context, rule AlertRule) (*Aler
 
 /* checkAnomalyRule checks an anomaly-based alert rule */
 func (s *Service) checkAnomalyRule(ctx context.Context, rule AlertRule) (*Alert, error) {
+	if method, _ := rule.Config["method"].(string); method == AnomalyMethodLearnedBand {
+		return s.checkLearnedBandRule(ctx, rule)
+	}
+
 	// Use anomaly detection service to check for recent anomalies
 	entityType := "metric"
 	if entityTypeVal, ok := rule.Config["entity_type"].(string); ok {
@@ -526,6 +533,24 @@ func (s *Service) GetAlerts(ctx context.Context, status string, limit int) ([]Al
 	return alerts, nil
 }
 
+/* RaiseAlert records an alert raised by another service under one of its alert rules and triggers the rule's workflows */
+func (s *Service) RaiseAlert(ctx context.Context, alert Alert) (*Alert, error) {
+	if alert.RuleID == uuid.Nil {
+		return nil, fmt.Errorf("alert rule_id is required")
+	}
+	alert.ID = uuid.New()
+	alert.Status = "active"
+	alert.CreatedAt = time.Now()
+	if alert.Severity == "" {
+		alert.Severity = "medium"
+	}
+
+	if err := s.storeAlert(ctx, alert); err != nil {
+		return nil, fmt.Errorf("failed to store alert: %w", err)
+	}
+	return &alert, nil
+}
+
 /* ResolveAlert resolves an alert */
 func (s *Service) ResolveAlert(ctx context.Context, alertID uuid.UUID, resolution string) error {
 	now := time.Now()
@@ -0,0 +1,409 @@
+package anomaly
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+/* Band methods, from most to least history needed */
+const (
+	MethodHoltWinters = "holt_winters" // level, trend and day-of-week effects; needs two weeks of daily points
+	MethodHolt        = "holt"         // level and trend only; needs ten days
+)
+
+const (
+	seasonLength         = 7
+	minPoints            = 10
+	defaultSensitivity   = 3.0
+	defaultHistoryDays   = 90
+	minRelativeBandWidth = 0.01
+)
+
+/* ErrInsufficientHistory is returned when a series is too short to learn a band from */
+var ErrInsufficientHistory = errors.New("insufficient history to learn a band")
+
+/* Point is one observation of a series */
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+/* BandOptions tunes how a band is learned */
+type BandOptions struct {
+	Sensitivity float64  `json:"sensitivity,omitempty"`  // band half-width in standard deviations, default 3
+	HistoryDays int      `json:"history_days,omitempty"` // days of history to learn from, default 90
+	Min         *float64 `json:"min,omitempty"`          // values the series cannot go below, e.g. 0 for row counts
+	Max         *float64 `json:"max,omitempty"`          // values the series cannot go above, e.g. 100 for quality scores
+}
+
+/* BandOptionsFromConfig reads sensitivity and history_days from a rule's metadata or config */
+func BandOptionsFromConfig(config map[string]interface{}) BandOptions {
+	var opts BandOptions
+	if v, ok := config["sensitivity"].(float64); ok {
+		opts.Sensitivity = v
+	}
+	if v, ok := config["history_days"].(float64); ok {
+		opts.HistoryDays = int(v)
+	}
+	return opts
+}
+
+/* Bounded returns the options with the series limited to [min, max] */
+func (o BandOptions) Bounded(min, max float64) BandOptions {
+	o.Min = &min
+	o.Max = &max
+	return o
+}
+
+/* NonNegative returns the options with the series limited to values of at least zero */
+func (o BandOptions) NonNegative() BandOptions {
+	zero := 0.0
+	o.Min = &zero
+	return o
+}
+
+func (o BandOptions) sensitivity() float64 {
+	if o.Sensitivity > 0 {
+		return o.Sensitivity
+	}
+	return defaultSensitivity
+}
+
+/* Days returns the days of history to learn from */
+func (o BandOptions) Days() int {
+	if o.HistoryDays > 0 {
+		return o.HistoryDays
+	}
+	return defaultHistoryDays
+}
+
+/* Band is the expected range of a series on one day, with the model terms that produced it so it can be explained */
+type Band struct {
+	Method           string             `json:"method"`
+	Date             time.Time          `json:"date"`
+	Expected         float64            `json:"expected"`
+	Lower            float64            `json:"lower"`
+	Upper            float64            `json:"upper"`
+	Level            float64            `json:"level"`
+	Trend            float64            `json:"trend"` // change per day
+	DayEffect        float64            `json:"day_effect"`
+	DayOfWeekEffects map[string]float64 `json:"day_of_week_effects,omitempty"`
+	Sigma            float64            `json:"sigma"` // robust spread of one-day-ahead errors
+	Sensitivity      float64            `json:"sensitivity"`
+	Alpha            float64            `json:"alpha"`
+	Beta             float64            `json:"beta"`
+	Gamma            float64            `json:"gamma,omitempty"`
+	TrainingPoints   int                `json:"training_points"`
+	TrainedFrom      time.Time          `json:"trained_from"`
+	TrainedTo        time.Time          `json:"trained_to"`
+	History          []BandPoint        `json:"history,omitempty"` // in-sample expectations for charting the band against actuals
+	Explanation      string             `json:"explanation"`
+}
+
+/* BandPoint is a day of history with the band the model expected for it */
+type BandPoint struct {
+	Date     time.Time `json:"date"`
+	Actual   float64   `json:"actual"`
+	Expected float64   `json:"expected"`
+	Lower    float64   `json:"lower"`
+	Upper    float64   `json:"upper"`
+}
+
+/* Evaluation is a value judged against a band */
+type Evaluation struct {
+	Value     float64 `json:"value"`
+	Expected  float64 `json:"expected"`
+	Lower     float64 `json:"lower"`
+	Upper     float64 `json:"upper"`
+	Outside   bool    `json:"outside"`
+	Direction string  `json:"direction,omitempty"` // "above" or "below" when outside
+	Deviation float64 `json:"deviation"`           // distance from expected in standard deviations
+}
+
+/* Evaluate judges a value against the band */
+func (b *Band) Evaluate(value float64) Evaluation {
+	eval := Evaluation{Value: value, Expected: b.Expected, Lower: b.Lower, Upper: b.Upper}
+	if b.Sigma > 0 {
+		eval.Deviation = (value - b.Expected) / b.Sigma
+	}
+	switch {
+	case value > b.Upper:
+		eval.Outside, eval.Direction = true, "above"
+	case value < b.Lower:
+		eval.Outside, eval.Direction = true, "below"
+	}
+	return eval
+}
+
+/* Describe explains an evaluation in one sentence */
+func (e Evaluation) Describe() string {
+	if !e.Outside {
+		return fmt.Sprintf("%.4g is within the learned band [%.4g, %.4g]", e.Value, e.Lower, e.Upper)
+	}
+	return fmt.Sprintf("%.4g is %s the learned band [%.4g, %.4g] (expected %.4g, %.1fσ)", e.Value, e.Direction, e.Lower, e.Upper, e.Expected, e.Deviation)
+}
+
+/* LearnBand learns the expected range of a series on the day of at from points before that day.
+ * Points are averaged per UTC day and gaps are interpolated. With two weeks of days the model is additive
+ * Holt-Winters with a weekly season, so weekday effects are learned; with less it is Holt's linear trend.
+ * Smoothing parameters are picked from a small grid by one-day-ahead squared error, and the band is
+ * the forecast ± sensitivity × the median absolute one-day-ahead error scaled to a standard deviation. */
+func LearnBand(points []Point, at time.Time, opts BandOptions) (*Band, error) {
+	target := day(at)
+	days, values := dailySeries(points, target)
+	if len(values) < minPoints {
+		return nil, fmt.Errorf("%w: %d daily points, need %d", ErrInsufficientHistory, len(values), minPoints)
+	}
+
+	seasonal := len(values) >= 2*seasonLength
+	best := fitResult{sse: math.Inf(1)}
+	for _, alpha := range []float64{0.1, 0.2, 0.4, 0.6, 0.8} {
+		for _, beta := range []float64{0, 0.05, 0.15} {
+			gammas := []float64{0}
+			if seasonal {
+				gammas = []float64{0.05, 0.15, 0.3, 0.5}
+			}
+			for _, gamma := range gammas {
+				fit := fitSmoothing(values, alpha, beta, gamma, seasonal)
+				if fit.sse < best.sse {
+					best = fit
+				}
+			}
+		}
+	}
+
+	if seasonal {
+		// Centre the weekday effects on zero so each reads as a difference from an average day
+		offset := mean(best.season)
+		for i := range best.season {
+			best.season[i] -= offset
+		}
+		best.level += offset
+	}
+
+	horizon := int(target.Sub(days[len(days)-1]).Hours()/24 + 0.5)
+	if horizon < 1 {
+		horizon = 1
+	}
+	seasonIndex := (len(values) - 1 + horizon) % seasonLength
+	dayEffect := 0.0
+	if seasonal {
+		dayEffect = best.season[seasonIndex]
+	}
+	expected := best.level + float64(horizon)*best.trend + dayEffect
+
+	sigma := robustSigma(best.errors)
+	if floor := minRelativeBandWidth * math.Abs(expected); sigma < floor {
+		sigma = floor
+	}
+	if sigma == 0 {
+		sigma = minRelativeBandWidth
+	}
+	k := opts.sensitivity()
+	// Uncertainty grows with the number of days forecast ahead
+	width := k * sigma * math.Sqrt(float64(horizon))
+
+	band := &Band{
+		Method:         MethodHolt,
+		Date:           target,
+		Expected:       expected,
+		Lower:          expected - width,
+		Upper:          expected + width,
+		Level:          best.level,
+		Trend:          best.trend,
+		DayEffect:      dayEffect,
+		Sigma:          sigma,
+		Sensitivity:    k,
+		Alpha:          best.alpha,
+		Beta:           best.beta,
+		Gamma:          best.gamma,
+		TrainingPoints: len(values),
+		TrainedFrom:    days[0],
+		TrainedTo:      days[len(days)-1],
+	}
+	if seasonal {
+		band.Method = MethodHoltWinters
+		band.DayOfWeekEffects = make(map[string]float64, seasonLength)
+		for i := 0; i < seasonLength; i++ {
+			// Season slot i holds the effect of the weekday of days[i]
+			band.DayOfWeekEffects[days[i].Weekday().String()] = best.season[i]
+		}
+	}
+	opts.clamp(&band.Expected)
+	opts.clamp(&band.Lower)
+	opts.clamp(&band.Upper)
+
+	band.History = make([]BandPoint, 0, len(best.predictions))
+	for i, predicted := range best.predictions {
+		if math.IsNaN(predicted) {
+			continue
+		}
+		point := BandPoint{Date: days[i], Actual: values[i], Expected: predicted, Lower: predicted - k*sigma, Upper: predicted + k*sigma}
+		opts.clamp(&point.Expected)
+		opts.clamp(&point.Lower)
+		opts.clamp(&point.Upper)
+		band.History = append(band.History, point)
+	}
+	band.Explanation = band.explain()
+	return band, nil
+}
+
+/* explain renders the band's terms as a sentence for the UI */
+func (b *Band) explain() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Expected %.4g on %s, band [%.4g, %.4g]: level %.4g, trend %+.3g per day",
+		b.Expected, b.Date.Format("Monday 2006-01-02"), b.Lower, b.Upper, b.Level, b.Trend)
+	if b.Method == MethodHoltWinters {
+		fmt.Fprintf(&sb, ", %s effect %+.3g", b.Date.Weekday(), b.DayEffect)
+	}
+	fmt.Fprintf(&sb, "; ±%.3gσ with σ = %.3g from one-day-ahead errors", b.Sensitivity, b.Sigma)
+	fmt.Fprintf(&sb, "; learned from %d daily points (%s to %s)", b.TrainingPoints, b.TrainedFrom.Format("2006-01-02"), b.TrainedTo.Format("2006-01-02"))
+	if b.Method == MethodHoltWinters {
+		fmt.Fprintf(&sb, " by Holt-Winters with weekly seasonality (α=%.2g, β=%.2g, γ=%.2g)", b.Alpha, b.Beta, b.Gamma)
+	} else {
+		fmt.Fprintf(&sb, " by Holt's linear trend (α=%.2g, β=%.2g); two weeks of history add day-of-week effects", b.Alpha, b.Beta)
+	}
+	return sb.String()
+}
+
+func (o BandOptions) clamp(v *float64) {
+	if o.Min != nil && *v < *o.Min {
+		*v = *o.Min
+	}
+	if o.Max != nil && *v > *o.Max {
+		*v = *o.Max
+	}
+}
+
+/* fitResult is the final state of a smoothing run */
+type fitResult struct {
+	alpha, beta, gamma float64
+	level, trend       float64
+	season             []float64
+	errors             []float64 // one-day-ahead errors after warm-up
+	predictions        []float64 // one-day-ahead forecasts, NaN during warm-up
+	sse                float64
+}
+
+/* fitSmoothing runs additive Holt-Winters (or Holt when not seasonal) over the series.
+ * The first season initializes level, trend and season, so forecasting starts at the second. */
+func fitSmoothing(values []float64, alpha, beta, gamma float64, seasonal bool) fitResult {
+	m := seasonLength
+	fit := fitResult{alpha: alpha, beta: beta, gamma: gamma, season: make([]float64, m), predictions: make([]float64, len(values))}
+
+	first := mean(values[:m])
+	fit.level = first
+	if seasonal {
+		second := mean(values[m : 2*m])
+		fit.trend = (second - first) / float64(m)
+		for i := 0; i < m; i++ {
+			fit.season[i] = ((values[i] - first) + (values[i+m] - second)) / 2
+		}
+		// Level and trend describe the end of the first season
+		fit.level = first + fit.trend*float64(m-1)/2
+	} else {
+		fit.trend = (values[m-1] - values[0]) / float64(m-1)
+		fit.level = values[m-1]
+	}
+
+	for i := 0; i < m; i++ {
+		fit.predictions[i] = math.NaN()
+	}
+	for t := m; t < len(values); t++ {
+		s := fit.season[t%m]
+		forecast := fit.level + fit.trend + s
+		fit.predictions[t] = forecast
+		err := values[t] - forecast
+		fit.errors = append(fit.errors, err)
+		fit.sse += err * err
+
+		level := alpha*(values[t]-s) + (1-alpha)*(fit.level+fit.trend)
+		fit.trend = beta*(level-fit.level) + (1-beta)*fit.trend
+		fit.level = level
+		if seasonal {
+			fit.season[t%m] = gamma*(values[t]-level) + (1-gamma)*s
+		}
+	}
+	return fit
+}
+
+/* dailySeries averages points per UTC day before the target day and interpolates missing days */
+func dailySeries(points []Point, target time.Time) ([]time.Time, []float64) {
+	sums := make(map[time.Time]float64)
+	counts := make(map[time.Time]int)
+	for _, p := range points {
+		d := day(p.Time)
+		if !d.Before(target) || math.IsNaN(p.Value) || math.IsInf(p.Value, 0) {
+			continue
+		}
+		sums[d] += p.Value
+		counts[d]++
+	}
+	if len(sums) == 0 {
+		return nil, nil
+	}
+
+	observed := make([]time.Time, 0, len(sums))
+	for d := range sums {
+		observed = append(observed, d)
+	}
+	sort.Slice(observed, func(i, j int) bool { return observed[i].Before(observed[j]) })
+
+	var days []time.Time
+	var values []float64
+	for i, d := range observed {
+		v := sums[d] / float64(counts[d])
+		if i > 0 {
+			prev := observed[i-1]
+			prevValue := values[len(values)-1]
+			gap := int(d.Sub(prev).Hours() / 24)
+			for g := 1; g < gap; g++ {
+				days = append(days, prev.AddDate(0, 0, g))
+				values = append(values, prevValue+(v-prevValue)*float64(g)/float64(gap))
+			}
+		}
+		days = append(days, d)
+		values = append(values, v)
+	}
+	return days, values
+}
+
+/* robustSigma estimates a standard deviation from the median absolute deviation, so past outliers do not widen the band */
+func robustSigma(residuals []float64) float64 {
+	if len(residuals) == 0 {
+		return 0
+	}
+	centre := median(residuals)
+	deviations := make([]float64, len(residuals))
+	for i, e := range residuals {
+		deviations[i] = math.Abs(e - centre)
+	}
+	return 1.4826 * median(deviations)
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
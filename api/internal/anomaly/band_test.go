@@ -0,0 +1,129 @@
+package anomaly
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+/* dailyPoints returns one point per day for the days before at, oldest first */
+func dailyPoints(at time.Time, values []float64) []Point {
+	points := make([]Point, len(values))
+	for i, v := range values {
+		points[i] = Point{Time: at.AddDate(0, 0, i-len(values)), Value: v}
+	}
+	return points
+}
+
+/* TestLearnBand checks the method, expectation and width of learned bands */
+func TestLearnBand(t *testing.T) {
+	// 2026-03-07 is a Saturday
+	at := time.Date(2026, 3, 7, 12, 0, 0, 0, time.UTC)
+
+	constant := make([]float64, 21)
+	trend := make([]float64, 12)
+	weekly := make([]float64, 28)
+	nearMax := make([]float64, 21)
+	for i := range constant {
+		constant[i] = 100
+	}
+	for i := range trend {
+		trend[i] = 50 + 10*float64(i)
+	}
+	for i := range weekly {
+		weekly[i] = 100
+		if weekday := at.AddDate(0, 0, i-len(weekly)).Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+			weekly[i] = 40
+		}
+	}
+	for i := range nearMax {
+		nearMax[i] = 99 + float64(i%2)
+	}
+
+	tests := []struct {
+		name     string
+		points   []Point
+		opts     BandOptions
+		method   string
+		expected float64
+		within   float64 // tolerance on the expectation
+		inside   []float64
+		outside  []float64
+		maxUpper float64
+	}{
+		{name: "constant", points: dailyPoints(at, constant), method: MethodHoltWinters, expected: 100, within: 0.5, inside: []float64{100, 102}, outside: []float64{90, 110}},
+		{name: "linear trend", points: dailyPoints(at, trend), method: MethodHolt, expected: 170, within: 5, inside: []float64{170}, outside: []float64{100, 250}},
+		{name: "weekend dip", points: dailyPoints(at, weekly), method: MethodHoltWinters, expected: 40, within: 5, inside: []float64{40}, outside: []float64{100}},
+		{name: "bounded above", points: dailyPoints(at, nearMax), opts: BandOptions{}.Bounded(0, 100), method: MethodHoltWinters, expected: 99.5, within: 1, maxUpper: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			band, err := LearnBand(tt.points, at, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if band.Method != tt.method {
+				t.Errorf("expected method %s, got %s", tt.method, band.Method)
+			}
+			if math.Abs(band.Expected-tt.expected) > tt.within {
+				t.Errorf("expected %.4g ± %.2g, got %.4g (%s)", tt.expected, tt.within, band.Expected, band.Explanation)
+			}
+			if band.Lower > band.Expected || band.Upper < band.Expected {
+				t.Errorf("expected the band [%.4g, %.4g] to contain %.4g", band.Lower, band.Upper, band.Expected)
+			}
+			for _, v := range tt.inside {
+				if eval := band.Evaluate(v); eval.Outside {
+					t.Errorf("expected %v inside the band: %s", v, eval.Describe())
+				}
+			}
+			for _, v := range tt.outside {
+				if eval := band.Evaluate(v); !eval.Outside {
+					t.Errorf("expected %v outside the band: %s", v, eval.Describe())
+				}
+			}
+			if tt.maxUpper > 0 && band.Upper > tt.maxUpper {
+				t.Errorf("expected the band to stop at %v, got %v", tt.maxUpper, band.Upper)
+			}
+			if !band.Date.Equal(day(at)) || band.TrainingPoints != len(tt.points) {
+				t.Errorf("expected a band for %s from %d points, got %s from %d", day(at), len(tt.points), band.Date, band.TrainingPoints)
+			}
+		})
+	}
+}
+
+/* TestLearnBandHistory checks how points are turned into a daily series */
+func TestLearnBandHistory(t *testing.T) {
+	at := time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC)
+
+	if _, err := LearnBand(dailyPoints(at, []float64{1, 2, 3, 4, 5}), at, BandOptions{}); !errors.Is(err, ErrInsufficientHistory) {
+		t.Fatalf("expected insufficient history, got %v", err)
+	}
+
+	// Two points a day are averaged, a missing day is interpolated, and points on or after the target day are ignored
+	var points []Point
+	for i := 1; i <= 12; i++ {
+		if i == 5 {
+			continue
+		}
+		d := at.AddDate(0, 0, -i)
+		points = append(points, Point{Time: d.Add(time.Hour), Value: 90}, Point{Time: d.Add(20 * time.Hour), Value: 110})
+	}
+	points = append(points, Point{Time: at.Add(time.Hour), Value: 1e9}, Point{Time: at.AddDate(0, 0, -3), Value: math.NaN()})
+
+	band, err := LearnBand(points, at, BandOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if band.TrainingPoints != 12 {
+		t.Errorf("expected 12 daily points, got %d", band.TrainingPoints)
+	}
+	for _, p := range band.History {
+		if p.Actual != 100 {
+			t.Errorf("expected every day to average 100, got %v on %s", p.Actual, p.Date)
+		}
+	}
+	if math.Abs(band.Expected-100) > 0.5 {
+		t.Errorf("expected 100, got %v", band.Expected)
+	}
+}
@@ -0,0 +1,107 @@
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+/* Series kinds recorded for learned bands. Quality scores are learned from the check history instead. */
+const (
+	SeriesRowCount     = "row_count"     // keyed by connector/schema.table
+	SeriesFreshnessLag = "freshness_lag" // keyed by freshness monitor ID, in minutes
+	SeriesMetric       = "metric"        // keyed by alert rule metric name
+)
+
+/* Series identifies a recorded series */
+type Series struct {
+	Kind string `json:"kind"`
+	Key  string `json:"key"`
+}
+
+/* Service records series observations and learns bands from them */
+type Service struct {
+	pool *pgxpool.Pool
+}
+
+/* NewService creates a new anomaly service */
+func NewService(pool *pgxpool.Pool) *Service {
+	return &Service{pool: pool}
+}
+
+/* Record stores an observation of a series */
+func (s *Service) Record(ctx context.Context, series Series, value float64, at time.Time) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO neuronip.metric_observations (series_kind, series_key, value, observed_at)
+		VALUES ($1, $2, $3, $4)`, series.Kind, series.Key, value, at)
+	if err != nil {
+		return fmt.Errorf("failed to record observation: %w", err)
+	}
+	return nil
+}
+
+/* History returns a series' observations in [since, before) oldest first */
+func (s *Service) History(ctx context.Context, series Series, since, before time.Time) ([]Point, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT observed_at, value FROM neuronip.metric_observations
+		WHERE series_kind = $1 AND series_key = $2 AND observed_at >= $3 AND observed_at < $4
+		ORDER BY observed_at ASC`, series.Kind, series.Key, since, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get series history: %w", err)
+	}
+	defer rows.Close()
+
+	points := []Point{}
+	for rows.Next() {
+		var p Point
+		if err := rows.Scan(&p.Time, &p.Value); err != nil {
+			continue
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+/* LearnBand learns the band of a recorded series for the day of at from the days before it */
+func (s *Service) LearnBand(ctx context.Context, series Series, at time.Time, opts BandOptions) (*Band, error) {
+	target := day(at)
+	points, err := s.History(ctx, series, target.AddDate(0, 0, -opts.Days()), target)
+	if err != nil {
+		return nil, err
+	}
+	return LearnBand(points, at, opts)
+}
+
+/* Observe learns the band of a series for at, then records value. The value is judged against a band
+ * learned only from earlier days, so an anomaly today cannot widen its own band. */
+func (s *Service) Observe(ctx context.Context, series Series, value float64, at time.Time, opts BandOptions) (*Band, *Evaluation, error) {
+	band, err := s.LearnBand(ctx, series, at, opts)
+	if recordErr := s.Record(ctx, series, value, at); recordErr != nil {
+		return nil, nil, recordErr
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	eval := band.Evaluate(value)
+	return band, &eval, nil
+}
+
+/* Latest returns the most recent observation of a series, or nil when it has none */
+func (s *Service) Latest(ctx context.Context, series Series) (*Point, error) {
+	var p Point
+	err := s.pool.QueryRow(ctx, `
+		SELECT observed_at, value FROM neuronip.metric_observations
+		WHERE series_kind = $1 AND series_key = $2
+		ORDER BY observed_at DESC
+		LIMIT 1`, series.Kind, series.Key).Scan(&p.Time, &p.Value)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest observation: %w", err)
+	}
+	return &p, nil
+}
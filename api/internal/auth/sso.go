@@ -24,6 +24,7 @@ func truncateString(s string, maxLen int) string {
 		return s
 	}
 	return s[:maxLen] + "..."
+}
 
 /* SSOProvider represents an SSO identity provider */
 type SSOProvider struct {
@@ -714,7 +715,8 @@ func (s *SSOService) parseSAMLResponse(response []byte, provider *SSOProvider) (
 
 /* SAMLResponse represents a SAML 2.0 response */
 type SAMLResponse struct {
-	XMLName   xml.Name    `xml:"Response"`
+	XMLName    xml.Name    `xml:"Response"`
+	Issuer     string      `xml:"Issuer"`
 	Assertions []Assertion `xml:"Assertion"`
 }
 
@@ -0,0 +1,24 @@
+package cache
+
+import "context"
+
+/* TableChangeReason identifies what changed a table's contents or structure */
+type TableChangeReason string
+
+const (
+	TableChangeIngestion TableChangeReason = "ingestion"
+	TableChangeCDC       TableChangeReason = "cdc"
+	TableChangeSchema    TableChangeReason = "schema_change"
+)
+
+/* TableChange describes tables whose data or structure changed */
+type TableChange struct {
+	Tables []string          `json:"tables"`
+	Reason TableChangeReason `json:"reason"`
+	Source string            `json:"source,omitempty"` // Job, data source or connector that made the change
+}
+
+/* TableChangeListener is notified when ingestion, CDC or schema evolution touches tables */
+type TableChangeListener interface {
+	TablesChanged(ctx context.Context, change TableChange) error
+}
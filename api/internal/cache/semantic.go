@@ -0,0 +1,377 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/metrics"
+)
+
+/* Semantic cache namespaces */
+const (
+	NamespaceRAG       = "rag"
+	NamespaceWarehouse = "warehouse"
+)
+
+/* SemanticCacheConfig configures the semantic answer cache */
+type SemanticCacheConfig struct {
+	Enabled             bool
+	SimilarityThreshold float64       // Minimum cosine similarity for a hit
+	TTL                 time.Duration // Lifetime of a cached answer
+	ScopeByUser         bool          // Key entries per user; sharing per tenant and role set is only safe when retrieval is not filtered per user
+}
+
+/* DefaultSemanticCacheConfig returns the default semantic cache configuration */
+func DefaultSemanticCacheConfig() SemanticCacheConfig {
+	return SemanticCacheConfig{
+		Enabled:             true,
+		SimilarityThreshold: 0.95,
+		TTL:                 1 * time.Hour,
+		ScopeByUser:         true,
+	}
+}
+
+/* SemanticCacheService caches answers keyed on question embedding and permission scope */
+type SemanticCacheService struct {
+	pool   *pgxpool.Pool
+	config SemanticCacheConfig
+}
+
+/* NewSemanticCacheService creates a new semantic cache service */
+func NewSemanticCacheService(pool *pgxpool.Pool, config SemanticCacheConfig) *SemanticCacheService {
+	if config.SimilarityThreshold <= 0 || config.SimilarityThreshold > 1 {
+		config.SimilarityThreshold = DefaultSemanticCacheConfig().SimilarityThreshold
+	}
+	if config.TTL <= 0 {
+		config.TTL = DefaultSemanticCacheConfig().TTL
+	}
+	return &SemanticCacheService{pool: pool, config: config}
+}
+
+/* Enabled reports whether the cache should be consulted */
+func (s *SemanticCacheService) Enabled() bool {
+	return s != nil && s.pool != nil && s.config.Enabled
+}
+
+/* PermissionScope identifies what a caller is allowed to see */
+type PermissionScope struct {
+	TenantID string   `json:"tenant_id,omitempty"`
+	UserID   string   `json:"user_id,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+}
+
+/* Key returns a stable hash of the scope; answers are only shared within the same key */
+func (p PermissionScope) Key(byUser bool) string {
+	roles := make([]string, len(p.Roles))
+	for i, role := range p.Roles {
+		// Quoted so role names holding the separator cannot collide with another role set
+		roles[i] = strconv.Quote(role)
+	}
+	sort.Strings(roles)
+
+	parts := []string{"tenant=" + strconv.Quote(p.TenantID), "roles=" + strings.Join(roles, ",")}
+	if byUser || len(roles) == 0 {
+		// Without known roles the user is the only safe sharing boundary
+		parts = append(parts, "user="+strconv.Quote(p.UserID))
+	}
+
+	hash := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(hash[:])
+}
+
+/* ResolveScope builds the permission scope for a user from context and assigned roles.
+ * A scope that cannot be fully resolved is an error; callers must then skip the cache rather than share a looser key. */
+func (s *SemanticCacheService) ResolveScope(ctx context.Context, userID *string) (PermissionScope, error) {
+	scope := PermissionScope{}
+	if tenantID := ctx.Value("tenant_id"); tenantID != nil {
+		scope.TenantID = fmt.Sprintf("%v", tenantID)
+	}
+	if userID == nil || *userID == "" {
+		return scope, nil
+	}
+	scope.UserID = *userID
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT DISTINCT role_name
+		FROM neuronip.user_roles
+		WHERE user_id = $1`, *userID)
+	if err != nil {
+		return PermissionScope{}, fmt.Errorf("failed to resolve cache scope: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return PermissionScope{}, fmt.Errorf("failed to resolve cache scope: %w", err)
+		}
+		scope.Roles = append(scope.Roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return PermissionScope{}, fmt.Errorf("failed to resolve cache scope: %w", err)
+	}
+	return scope, nil
+}
+
+/* ScopeKey resolves the scope for a user and returns its cache key */
+func (s *SemanticCacheService) ScopeKey(ctx context.Context, userID *string) (string, error) {
+	scope, err := s.ResolveScope(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return scope.Key(s.config.ScopeByUser), nil
+}
+
+/* HitInfo describes a semantic cache hit returned alongside a cached answer */
+type HitInfo struct {
+	EntryID          uuid.UUID `json:"entry_id"`
+	Similarity       float64   `json:"similarity"`
+	OriginalQuestion string    `json:"original_question"`
+	CachedAt         time.Time `json:"cached_at"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+/* Lookup finds the closest cached answer for a question embedding within a scope and partition.
+ * Answers citing a document that was since deleted or edited are never served.
+ * On a hit the answer is decoded into dest; a nil HitInfo means a miss. */
+func (s *SemanticCacheService) Lookup(ctx context.Context, namespace, scopeKey, partition, embedding string, dest interface{}) (*HitInfo, error) {
+	if !s.Enabled() || embedding == "" {
+		return nil, nil
+	}
+
+	query := `
+		SELECT id, question_text, answer, created_at, expires_at,
+		       1 - (question_embedding <=> $4::vector) AS similarity
+		FROM neuronip.semantic_answer_cache c
+		WHERE namespace = $1 AND scope_key = $2 AND partition_key = $3
+		  AND expires_at > NOW()
+		  AND NOT EXISTS (
+		      SELECT 1 FROM unnest(c.source_document_ids) AS src(id)
+		      LEFT JOIN neuronip.knowledge_documents kd ON kd.id = src.id
+		      WHERE kd.id IS NULL OR kd.updated_at > c.created_at)
+		ORDER BY question_embedding <=> $4::vector
+		LIMIT 1`
+
+	var hit HitInfo
+	var answerJSON []byte
+	err := s.pool.QueryRow(ctx, query, namespace, scopeKey, partition, embedding).Scan(
+		&hit.EntryID, &hit.OriginalQuestion, &answerJSON, &hit.CachedAt, &hit.ExpiresAt, &hit.Similarity,
+	)
+	if err == pgx.ErrNoRows {
+		metrics.RecordSemanticCacheLookup(namespace, false, 0)
+		return nil, nil
+	}
+	if err != nil {
+		metrics.RecordSemanticCacheLookup(namespace, false, 0)
+		return nil, fmt.Errorf("failed to lookup semantic cache: %w", err)
+	}
+
+	if hit.Similarity < s.config.SimilarityThreshold {
+		metrics.RecordSemanticCacheLookup(namespace, false, 0)
+		return nil, nil
+	}
+
+	if err := json.Unmarshal(answerJSON, dest); err != nil {
+		metrics.RecordSemanticCacheLookup(namespace, false, 0)
+		return nil, fmt.Errorf("failed to decode cached answer: %w", err)
+	}
+
+	s.pool.Exec(ctx, `
+		UPDATE neuronip.semantic_answer_cache
+		SET hit_count = hit_count + 1, last_hit_at = NOW()
+		WHERE id = $1`, hit.EntryID)
+
+	metrics.RecordSemanticCacheLookup(namespace, true, hit.Similarity)
+	return &hit, nil
+}
+
+/* CacheEntrySources lists what a cached answer was derived from, for invalidation */
+type CacheEntrySources struct {
+	DocumentIDs []uuid.UUID
+	Tables      []string
+}
+
+/* Store caches an answer for a question embedding within a scope and partition */
+func (s *SemanticCacheService) Store(ctx context.Context, namespace, scopeKey, partition, question, embedding string, answer interface{}, sources CacheEntrySources) error {
+	if !s.Enabled() || embedding == "" {
+		return nil
+	}
+
+	answerJSON, err := json.Marshal(answer)
+	if err != nil {
+		return fmt.Errorf("failed to encode answer: %w", err)
+	}
+
+	documentIDs := sources.DocumentIDs
+	if documentIDs == nil {
+		documentIDs = []uuid.UUID{}
+	}
+
+	query := `
+		INSERT INTO neuronip.semantic_answer_cache
+		(namespace, scope_key, partition_key, question_text, question_embedding,
+		 answer, source_document_ids, source_tables, expires_at)
+		VALUES ($1, $2, $3, $4, $5::vector, $6, $7, $8, $9)`
+
+	_, err = s.pool.Exec(ctx, query,
+		namespace, scopeKey, partition, question, embedding,
+		answerJSON, documentIDs, NormalizeTableNames(sources.Tables), time.Now().Add(s.config.TTL),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store semantic cache entry: %w", err)
+	}
+	return nil
+}
+
+/* InvalidateDocuments removes cached answers derived from any of the given documents */
+func (s *SemanticCacheService) InvalidateDocuments(ctx context.Context, documentIDs []uuid.UUID) (int64, error) {
+	if s == nil || s.pool == nil || len(documentIDs) == 0 {
+		return 0, nil
+	}
+
+	result, err := s.pool.Exec(ctx, `
+		DELETE FROM neuronip.semantic_answer_cache
+		WHERE source_document_ids && $1`, documentIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to invalidate semantic cache by documents: %w", err)
+	}
+
+	metrics.RecordSemanticCacheInvalidations("document", result.RowsAffected())
+	return result.RowsAffected(), nil
+}
+
+/* InvalidateCollection removes cached RAG answers that searched the collection, including unscoped searches,
+ * so answers computed before a document was added are not served */
+func (s *SemanticCacheService) InvalidateCollection(ctx context.Context, collectionID uuid.UUID) (int64, error) {
+	if s == nil || s.pool == nil {
+		return 0, nil
+	}
+
+	result, err := s.pool.Exec(ctx, `
+		DELETE FROM neuronip.semantic_answer_cache
+		WHERE namespace = $1 AND partition_key IN ($2, '')`, NamespaceRAG, collectionID.String())
+	if err != nil {
+		return 0, fmt.Errorf("failed to invalidate semantic cache by collection: %w", err)
+	}
+
+	metrics.RecordSemanticCacheInvalidations("collection", result.RowsAffected())
+	return result.RowsAffected(), nil
+}
+
+/* InvalidateTables removes cached answers derived from any of the given tables */
+func (s *SemanticCacheService) InvalidateTables(ctx context.Context, tables []string) (int64, error) {
+	if s == nil || s.pool == nil || len(tables) == 0 {
+		return 0, nil
+	}
+
+	result, err := s.pool.Exec(ctx, `
+		DELETE FROM neuronip.semantic_answer_cache
+		WHERE source_tables && $1`, NormalizeTableNames(tables))
+	if err != nil {
+		return 0, fmt.Errorf("failed to invalidate semantic cache by tables: %w", err)
+	}
+
+	metrics.RecordSemanticCacheInvalidations("table", result.RowsAffected())
+	return result.RowsAffected(), nil
+}
+
+/* ExpireTables shortens the lifetime of cached answers derived from the given tables to at most within */
+func (s *SemanticCacheService) ExpireTables(ctx context.Context, tables []string, within time.Duration) (int64, error) {
+	if s == nil || s.pool == nil || len(tables) == 0 {
+		return 0, nil
+	}
+
+	result, err := s.pool.Exec(ctx, `
+		UPDATE neuronip.semantic_answer_cache
+		SET expires_at = LEAST(expires_at, NOW() + $2 * INTERVAL '1 second')
+		WHERE source_tables && $1 AND expires_at > NOW()`, NormalizeTableNames(tables), int64(within.Seconds()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire semantic cache by tables: %w", err)
+	}
+
+	metrics.RecordSemanticCacheInvalidations("table_stale", result.RowsAffected())
+	return result.RowsAffected(), nil
+}
+
+/* InvalidateNamespace removes all cached answers in a namespace, or everything when namespace is empty */
+func (s *SemanticCacheService) InvalidateNamespace(ctx context.Context, namespace string) (int64, error) {
+	if s == nil || s.pool == nil {
+		return 0, nil
+	}
+
+	result, err := s.pool.Exec(ctx, `
+		DELETE FROM neuronip.semantic_answer_cache
+		WHERE $1 = '' OR namespace = $1`, namespace)
+	if err != nil {
+		return 0, fmt.Errorf("failed to invalidate semantic cache namespace: %w", err)
+	}
+
+	metrics.RecordSemanticCacheInvalidations("manual", result.RowsAffected())
+	return result.RowsAffected(), nil
+}
+
+/* SemanticCacheStats summarizes semantic cache contents per namespace */
+type SemanticCacheStats struct {
+	Namespace     string  `json:"namespace"`
+	TotalEntries  int64   `json:"total_entries"`
+	ActiveEntries int64   `json:"active_entries"`
+	TotalHits     int64   `json:"total_hits"`
+	AvgHits       float64 `json:"avg_hits_per_entry"`
+}
+
+/* GetStats returns semantic cache statistics grouped by namespace */
+func (s *SemanticCacheService) GetStats(ctx context.Context) ([]SemanticCacheStats, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT namespace,
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE expires_at > NOW()),
+		       COALESCE(SUM(hit_count), 0),
+		       COALESCE(AVG(hit_count), 0)
+		FROM neuronip.semantic_answer_cache
+		GROUP BY namespace
+		ORDER BY namespace`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get semantic cache stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []SemanticCacheStats
+	for rows.Next() {
+		var st SemanticCacheStats
+		if err := rows.Scan(&st.Namespace, &st.TotalEntries, &st.ActiveEntries, &st.TotalHits, &st.AvgHits); err != nil {
+			continue
+		}
+		stats = append(stats, st)
+	}
+	return stats, nil
+}
+
+/* NormalizeTableNames lowercases table names and adds the unqualified form of schema-qualified names */
+func NormalizeTableNames(tables []string) []string {
+	seen := make(map[string]bool)
+	normalized := []string{}
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	for _, table := range tables {
+		name := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(table), `"`, ""))
+		add(name)
+		if idx := strings.LastIndex(name, "."); idx >= 0 {
+			add(name[idx+1:])
+		}
+	}
+	return normalized
+}
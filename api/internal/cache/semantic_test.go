@@ -0,0 +1,40 @@
+package cache
+
+import "testing"
+
+/* TestPermissionScopeKey checks which callers share cached answers */
+func TestPermissionScopeKey(t *testing.T) {
+	base := PermissionScope{TenantID: "t1", UserID: "u1", Roles: []string{"analyst", "viewer"}}
+
+	tests := []struct {
+		name   string
+		other  PermissionScope
+		byUser bool
+		shared bool
+	}{
+		{name: "same scope", other: base, byUser: true, shared: true},
+		{name: "role order", other: PermissionScope{TenantID: "t1", UserID: "u1", Roles: []string{"viewer", "analyst"}}, byUser: true, shared: true},
+		{name: "other tenant", other: PermissionScope{TenantID: "t2", UserID: "u1", Roles: base.Roles}, byUser: false, shared: false},
+		{name: "other roles", other: PermissionScope{TenantID: "t1", UserID: "u1", Roles: []string{"analyst"}}, byUser: false, shared: false},
+		{name: "other user keyed per user", other: PermissionScope{TenantID: "t1", UserID: "u2", Roles: base.Roles}, byUser: true, shared: false},
+		{name: "other user with the same roles", other: PermissionScope{TenantID: "t1", UserID: "u2", Roles: base.Roles}, byUser: false, shared: true},
+		{name: "role names holding the separator", other: PermissionScope{TenantID: "t1", UserID: "u1", Roles: []string{"analyst,viewer"}}, byUser: false, shared: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if shared := base.Key(tt.byUser) == tt.other.Key(tt.byUser); shared != tt.shared {
+				t.Errorf("expected shared=%v for %+v", tt.shared, tt.other)
+			}
+		})
+	}
+
+	// Without roles the user is the only safe sharing boundary, even when not keyed per user
+	first := PermissionScope{TenantID: "t1", UserID: "u1"}
+	second := PermissionScope{TenantID: "t1", UserID: "u2"}
+	if first.Key(false) == second.Key(false) {
+		t.Error("expected users without roles to get separate keys")
+	}
+	if len(first.Key(false)) != 64 {
+		t.Errorf("expected a hex sha256 key, got %q", first.Key(false))
+	}
+}
@@ -0,0 +1,888 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/connectors"
+	"github.com/neurondb/NeuronIP/api/internal/users"
+)
+
+/* Data contract statuses. A dataset has at most one active contract; activating a version supersedes the last. */
+const (
+	ContractStatusDraft      = "draft"
+	ContractStatusActive     = "active"
+	ContractStatusSuperseded = "superseded"
+	ContractStatusRetired    = "retired"
+)
+
+/* Contract enforcement modes. Block fails a sync with breaking violations and keeps the previously stored schema. */
+const (
+	ContractEnforcementWarn  = "warn"
+	ContractEnforcementBlock = "block"
+)
+
+const contractNotificationType = "data_contract"
+
+/* DataContract is a version of the agreement between a dataset's producer and its consumers */
+type DataContract struct {
+	ID           uuid.UUID        `json:"id"`
+	Name         string           `json:"name"`
+	ConnectorID  uuid.UUID        `json:"connector_id"`
+	SchemaName   string           `json:"schema_name"`
+	TableName    string           `json:"table_name"`
+	Version      int              `json:"version"`
+	Status       string           `json:"status"`
+	OwnerID      uuid.UUID        `json:"owner_id"` // Producer
+	Consumers    []uuid.UUID      `json:"consumers"`
+	Columns      []ContractColumn `json:"columns"`
+	FreshnessSLA *FreshnessSLA    `json:"freshness_sla,omitempty"`
+	Enforcement  string           `json:"enforcement"`
+	Changes      []SchemaChange   `json:"changes,omitempty"` // From the previous version
+	Breaking     bool             `json:"breaking"`
+	Description  *string          `json:"description,omitempty"`
+	CreatedAt    time.Time        `json:"created_at"`
+	ActivatedAt  *time.Time       `json:"activated_at,omitempty"`
+}
+
+/* ContractColumn declares a column of a contract */
+type ContractColumn struct {
+	Name        string             `json:"name"`
+	Type        string             `json:"type"`
+	Nullable    bool               `json:"nullable"`
+	Description string             `json:"description,omitempty"`
+	Constraints *ColumnConstraints `json:"constraints,omitempty"`
+}
+
+/* ColumnConstraints are the semantic constraints on a column's values, checked against the data on sync */
+type ColumnConstraints struct {
+	Unique        bool     `json:"unique,omitempty"`
+	Min           *float64 `json:"min,omitempty"`
+	Max           *float64 `json:"max,omitempty"`
+	AllowedValues []string `json:"allowed_values,omitempty"`
+}
+
+/* FreshnessSLA is the longest a dataset may go without new data, judged by the latest value of a timestamp column */
+type FreshnessSLA struct {
+	Column        string `json:"column"`
+	MaxLagMinutes int    `json:"max_lag_minutes"`
+}
+
+/* ContractViolation is a breach of an active contract found on a sync */
+type ContractViolation struct {
+	ID             uuid.UUID              `json:"id"`
+	ContractID     uuid.UUID              `json:"contract_id"`
+	SyncHistoryID  *uuid.UUID             `json:"sync_history_id,omitempty"`
+	IngestionJobID *uuid.UUID             `json:"ingestion_job_id,omitempty"`
+	ViolationType  string                 `json:"violation_type"` // table_missing, column_missing, type_mismatch, nullability, null_values, duplicate_values, out_of_range, disallowed_values, freshness_sla, unchecked
+	ColumnName     *string                `json:"column_name,omitempty"`
+	Message        string                 `json:"message"`
+	Severity       string                 `json:"severity"` // breaking or non_breaking
+	Details        map[string]interface{} `json:"details,omitempty"`
+	DetectedAt     time.Time              `json:"detected_at"`
+}
+
+/* Errors for contract versions that cannot be found or changed by the caller */
+var (
+	ErrContractNotFound = errors.New("data contract not found")
+	ErrContractNotOwner = errors.New("only the contract's owner can change its status")
+)
+
+/* IsContractNotFound reports whether err is for a contract version that does not exist */
+func IsContractNotFound(err error) bool {
+	return errors.Is(err, ErrContractNotFound)
+}
+
+/* IsContractNotOwner reports whether err is for a status change by someone other than the contract's owner */
+func IsContractNotOwner(err error) bool {
+	return errors.Is(err, ErrContractNotOwner)
+}
+
+/* ContractError reports a contract that is invalid or cannot change status */
+type ContractError struct {
+	Message string
+}
+
+func (e *ContractError) Error() string {
+	return e.Message
+}
+
+/* AsContractError returns the contract error in err's chain, or nil */
+func AsContractError(err error) *ContractError {
+	var contractErr *ContractError
+	if errors.As(err, &contractErr) {
+		return contractErr
+	}
+	return nil
+}
+
+func contractErrorf(format string, args ...interface{}) error {
+	return &ContractError{Message: fmt.Sprintf(format, args...)}
+}
+
+/* contractSampleRows bounds the rows each value check reads, so a sync never scans a whole source table */
+const contractSampleRows = 100000
+
+/* ContractService manages data contracts and validates connector syncs and ingestion jobs against them */
+type ContractService struct {
+	pool          *pgxpool.Pool
+	evolution     *SchemaEvolutionService
+	connectors    *connectors.ConnectorService
+	notifications *users.NotificationService
+}
+
+/* NewContractService creates a new contract service that notifies producers and consumers of violations */
+func NewContractService(pool *pgxpool.Pool, notifications *users.NotificationService) *ContractService {
+	return &ContractService{
+		pool:          pool,
+		evolution:     NewSchemaEvolutionService(pool),
+		connectors:    connectors.NewConnectorService(pool),
+		notifications: notifications,
+	}
+}
+
+const contractColumns = `id, name, connector_id, schema_name, table_name, version, status, owner_id, consumers,
+	columns, freshness_sla, enforcement, changes, breaking, description, created_at, activated_at`
+
+/* CreateContract stores a new draft version of a dataset's contract, with its changes from the previous version */
+func (s *ContractService) CreateContract(ctx context.Context, contract DataContract) (*DataContract, error) {
+	if err := validateContract(&contract); err != nil {
+		return nil, err
+	}
+
+	previous, err := s.latestContract(ctx, contract.ConnectorID, contract.SchemaName, contract.TableName)
+	if err != nil {
+		return nil, err
+	}
+	contract.ID = uuid.New()
+	contract.Version = 1
+	contract.Status = ContractStatusDraft
+	contract.Changes = nil
+	contract.Breaking = false
+	contract.CreatedAt = time.Now()
+	contract.ActivatedAt = nil
+	if previous != nil {
+		contract.Version = previous.Version + 1
+		contract.Changes = s.contractChanges(previous.Columns, contract.Columns)
+		for _, change := range contract.Changes {
+			if change.Severity == "breaking" {
+				contract.Breaking = true
+			}
+		}
+	}
+	if contract.Consumers == nil {
+		contract.Consumers = []uuid.UUID{}
+	}
+
+	columnsJSON, _ := json.Marshal(contract.Columns)
+	changesJSON, _ := json.Marshal(contract.Changes)
+	var slaJSON []byte
+	if contract.FreshnessSLA != nil {
+		slaJSON, _ = json.Marshal(contract.FreshnessSLA)
+	}
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO neuronip.data_contracts
+		(id, name, connector_id, schema_name, table_name, version, status, owner_id, consumers,
+		 columns, freshness_sla, enforcement, changes, breaking, description, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+		contract.ID, contract.Name, contract.ConnectorID, contract.SchemaName, contract.TableName,
+		contract.Version, contract.Status, contract.OwnerID, contract.Consumers,
+		columnsJSON, slaJSON, contract.Enforcement, changesJSON, contract.Breaking, contract.Description, contract.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create contract: %w", err)
+	}
+	return &contract, nil
+}
+
+/* validateContract checks a contract's declarations and fills in defaults */
+func validateContract(contract *DataContract) error {
+	if contract.Name == "" || contract.TableName == "" {
+		return contractErrorf("name and table_name are required")
+	}
+	if contract.ConnectorID == uuid.Nil || contract.OwnerID == uuid.Nil {
+		return contractErrorf("connector_id and owner_id are required")
+	}
+	if len(contract.Columns) == 0 {
+		return contractErrorf("a contract must declare at least one column")
+	}
+	switch contract.Enforcement {
+	case "":
+		contract.Enforcement = ContractEnforcementWarn
+	case ContractEnforcementWarn, ContractEnforcementBlock:
+	default:
+		return contractErrorf("enforcement must be %s or %s", ContractEnforcementWarn, ContractEnforcementBlock)
+	}
+
+	declared := make(map[string]bool, len(contract.Columns))
+	for _, column := range contract.Columns {
+		if column.Name == "" || column.Type == "" {
+			return contractErrorf("every column needs a name and type")
+		}
+		if declared[column.Name] {
+			return contractErrorf("column %s is declared twice", column.Name)
+		}
+		declared[column.Name] = true
+		if c := column.Constraints; c != nil && c.Min != nil && c.Max != nil && *c.Min > *c.Max {
+			return contractErrorf("column %s has min above max", column.Name)
+		}
+	}
+	if sla := contract.FreshnessSLA; sla != nil {
+		if !declared[sla.Column] {
+			return contractErrorf("freshness SLA column %s is not declared", sla.Column)
+		}
+		if sla.MaxLagMinutes <= 0 {
+			return contractErrorf("freshness SLA max_lag_minutes must be positive")
+		}
+	}
+	return nil
+}
+
+/* contractChanges compares two versions' columns with the schema evolution change detection, adding
+ * nullability changes it does not see. Relaxing a column to nullable breaks consumers that rely on values. */
+func (s *ContractService) contractChanges(previous, current []ContractColumn) []SchemaChange {
+	changes := s.evolution.detectChanges(contractSchema(previous), contractSchema(current))
+
+	prevNullable := make(map[string]bool, len(previous))
+	for _, column := range previous {
+		prevNullable[column.Name] = column.Nullable
+	}
+	for _, column := range current {
+		nullable, exists := prevNullable[column.Name]
+		if !exists || nullable == column.Nullable {
+			continue
+		}
+		change := SchemaChange{
+			ChangeType: "nullability_changed",
+			ColumnName: column.Name,
+			OldValue:   nullable,
+			NewValue:   column.Nullable,
+			Severity:   "non_breaking",
+			Impact:     "Column no longer allows nulls",
+		}
+		if column.Nullable {
+			change.Severity = "breaking"
+			change.Impact = "Consumers may receive nulls"
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+/* contractSchema is a contract's columns in the shape schema evolution compares */
+func contractSchema(columns []ContractColumn) map[string]interface{} {
+	defs := make(map[string]interface{}, len(columns))
+	for _, column := range columns {
+		defs[column.Name] = map[string]interface{}{"type": normalizeColumnType(column.Type), "nullable": column.Nullable}
+	}
+	return map[string]interface{}{"columns": defs}
+}
+
+/* discoveredSchema is a discovered table's columns in the shape schema evolution compares */
+func discoveredSchema(table *connectors.Table) map[string]interface{} {
+	defs := make(map[string]interface{}, len(table.Columns))
+	for _, column := range table.Columns {
+		defs[column.ColumnName] = map[string]interface{}{"type": normalizeColumnType(column.ColumnType), "nullable": column.IsNullable}
+	}
+	return map[string]interface{}{"columns": defs}
+}
+
+var typeModifier = regexp.MustCompile(`\s*\(.*\)`)
+
+var typeAliases = map[string]string{
+	"character varying":           "varchar",
+	"character":                   "char",
+	"int":                         "integer",
+	"int4":                        "integer",
+	"int8":                        "bigint",
+	"int2":                        "smallint",
+	"bool":                        "boolean",
+	"float8":                      "double precision",
+	"double":                      "double precision",
+	"float4":                      "real",
+	"decimal":                     "numeric",
+	"timestamp without time zone": "timestamp",
+	"timestamp with time zone":    "timestamptz",
+}
+
+/* normalizeColumnType reduces a column type to a comparable name, dropping length and precision */
+func normalizeColumnType(columnType string) string {
+	t := strings.ToLower(strings.TrimSpace(typeModifier.ReplaceAllString(columnType, "")))
+	if alias, ok := typeAliases[t]; ok {
+		return alias
+	}
+	return t
+}
+
+/* GetContract retrieves a contract version by ID */
+func (s *ContractService) GetContract(ctx context.Context, id uuid.UUID) (*DataContract, error) {
+	contract, err := scanContract(s.pool.QueryRow(ctx, `SELECT `+contractColumns+` FROM neuronip.data_contracts WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, ErrContractNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contract: %w", err)
+	}
+	return contract, nil
+}
+
+/* latestContract returns the newest version of a dataset's contract, or nil when it has none */
+func (s *ContractService) latestContract(ctx context.Context, connectorID uuid.UUID, schemaName, tableName string) (*DataContract, error) {
+	contract, err := scanContract(s.pool.QueryRow(ctx, `
+		SELECT `+contractColumns+` FROM neuronip.data_contracts
+		WHERE connector_id = $1 AND schema_name = $2 AND table_name = $3
+		ORDER BY version DESC
+		LIMIT 1`, connectorID, schemaName, tableName))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest contract: %w", err)
+	}
+	return contract, nil
+}
+
+/* ListContracts lists contract versions, optionally for one connector or status, newest first */
+func (s *ContractService) ListContracts(ctx context.Context, connectorID *uuid.UUID, status string) ([]DataContract, error) {
+	query := `SELECT ` + contractColumns + ` FROM neuronip.data_contracts WHERE 1=1`
+	args := []interface{}{}
+	if connectorID != nil {
+		args = append(args, *connectorID)
+		query += fmt.Sprintf(" AND connector_id = $%d", len(args))
+	}
+	if status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	query += " ORDER BY schema_name, table_name, version DESC"
+	return s.queryContracts(ctx, query, args...)
+}
+
+/* GetContractVersions lists every version of a dataset's contract, oldest first */
+func (s *ContractService) GetContractVersions(ctx context.Context, connectorID uuid.UUID, schemaName, tableName string) ([]DataContract, error) {
+	return s.queryContracts(ctx, `
+		SELECT `+contractColumns+` FROM neuronip.data_contracts
+		WHERE connector_id = $1 AND schema_name = $2 AND table_name = $3
+		ORDER BY version ASC`, connectorID, schemaName, tableName)
+}
+
+func (s *ContractService) queryContracts(ctx context.Context, query string, args ...interface{}) ([]DataContract, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contracts: %w", err)
+	}
+	defer rows.Close()
+
+	contracts := []DataContract{}
+	for rows.Next() {
+		contract, err := scanContract(rows)
+		if err != nil {
+			continue
+		}
+		contracts = append(contracts, *contract)
+	}
+	return contracts, nil
+}
+
+func scanContract(row pgx.Row) (*DataContract, error) {
+	var contract DataContract
+	var columnsJSON, slaJSON, changesJSON []byte
+	err := row.Scan(&contract.ID, &contract.Name, &contract.ConnectorID, &contract.SchemaName, &contract.TableName,
+		&contract.Version, &contract.Status, &contract.OwnerID, &contract.Consumers,
+		&columnsJSON, &slaJSON, &contract.Enforcement, &changesJSON, &contract.Breaking, &contract.Description,
+		&contract.CreatedAt, &contract.ActivatedAt)
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal(columnsJSON, &contract.Columns)
+	json.Unmarshal(changesJSON, &contract.Changes)
+	if slaJSON != nil {
+		json.Unmarshal(slaJSON, &contract.FreshnessSLA)
+	}
+	return &contract, nil
+}
+
+/* ActivateContract makes a draft the dataset's active contract, superseding the previous one. Only the contract's owner can activate it.
+ * A version with breaking changes needs acknowledgeBreaking, and its consumers are told it is active. */
+func (s *ContractService) ActivateContract(ctx context.Context, id uuid.UUID, userID uuid.UUID, acknowledgeBreaking bool) (*DataContract, error) {
+	contract, err := s.GetContract(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if contract.OwnerID != userID {
+		return nil, ErrContractNotOwner
+	}
+	if contract.Status != ContractStatusDraft {
+		return nil, contractErrorf("only a draft contract can be activated; this version is %s", contract.Status)
+	}
+	if contract.Breaking && !acknowledgeBreaking {
+		return nil, contractErrorf("version %d has breaking changes for consumers; acknowledge them to activate it", contract.Version)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE neuronip.data_contracts SET status = 'superseded'
+		WHERE connector_id = $1 AND schema_name = $2 AND table_name = $3 AND status = 'active'`,
+		contract.ConnectorID, contract.SchemaName, contract.TableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to supersede active contract: %w", err)
+	}
+	now := time.Now()
+	_, err = tx.Exec(ctx, `UPDATE neuronip.data_contracts SET status = 'active', activated_at = $1 WHERE id = $2`, now, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to activate contract: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	contract.Status = ContractStatusActive
+	contract.ActivatedAt = &now
+
+	if contract.Breaking {
+		s.notify(ctx, contract, fmt.Sprintf("Data contract %s v%d is active with breaking changes", contract.Name, contract.Version),
+			describeChanges(contract.Changes), map[string]interface{}{"event": "breaking_version_activated"})
+	}
+	return contract, nil
+}
+
+/* RetireContract retires a contract version; syncs are no longer validated against it. Only the contract's owner can retire it. */
+func (s *ContractService) RetireContract(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
+	contract, err := s.GetContract(ctx, id)
+	if err != nil {
+		return err
+	}
+	if contract.OwnerID != userID {
+		return ErrContractNotOwner
+	}
+
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE neuronip.data_contracts SET status = 'retired'
+		WHERE id = $1 AND owner_id = $2 AND status IN ('draft', 'active')`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to retire contract: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return contractErrorf("contract is already superseded or retired")
+	}
+	return nil
+}
+
+/* ListViolations lists the violations of a contract version, newest first */
+func (s *ContractService) ListViolations(ctx context.Context, contractID uuid.UUID, limit int) ([]ContractViolation, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, contract_id, sync_history_id, ingestion_job_id, violation_type, column_name, message, severity, details, detected_at
+		FROM neuronip.data_contract_violations
+		WHERE contract_id = $1
+		ORDER BY detected_at DESC
+		LIMIT $2`, contractID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contract violations: %w", err)
+	}
+	defer rows.Close()
+
+	violations := []ContractViolation{}
+	for rows.Next() {
+		var v ContractViolation
+		var detailsJSON []byte
+		if err := rows.Scan(&v.ID, &v.ContractID, &v.SyncHistoryID, &v.IngestionJobID, &v.ViolationType, &v.ColumnName,
+			&v.Message, &v.Severity, &detailsJSON, &v.DetectedAt); err != nil {
+			continue
+		}
+		json.Unmarshal(detailsJSON, &v.Details)
+		violations = append(violations, v)
+	}
+	return violations, nil
+}
+
+/* ValidateSync validates a connector sync's discovered schema against the active contracts of its datasets.
+ * Violations are stored and sent to each contract's producer and consumers; breaking violations of a
+ * contract with block enforcement block the sync. */
+func (s *ContractService) ValidateSync(ctx context.Context, connector *connectors.DataSourceConnector, syncHistoryID uuid.UUID, schema *connectors.Schema) (*connectors.SyncValidation, error) {
+	return s.validate(ctx, connector, schema, nil, func(v *ContractViolation) {
+		v.SyncHistoryID = &syncHistoryID
+	}, map[string]interface{}{"sync_history_id": syncHistoryID})
+}
+
+/* ValidateIngestion validates an ingestion job against the active contracts of the catalog connector it reads from,
+ * before the job loads anything. Only contracts on the job's tables are checked, or all of the connector's when
+ * tables is empty; tables are schema-qualified or bare table names. */
+func (s *ContractService) ValidateIngestion(ctx context.Context, connectorID uuid.UUID, jobID uuid.UUID, tables []string) (*connectors.SyncValidation, error) {
+	connector, err := s.connectors.GetConnector(ctx, connectorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connector: %w", err)
+	}
+	schema, err := s.connectors.DiscoverSchema(ctx, connector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover schema: %w", err)
+	}
+	return s.validate(ctx, connector, schema, tables, func(v *ContractViolation) {
+		v.IngestionJobID = &jobID
+	}, map[string]interface{}{"ingestion_job_id": jobID})
+}
+
+/* validate checks the contracts of a connector's datasets in tables (all when empty) against a discovered schema.
+ * link attaches each violation to the sync or job that found it. */
+func (s *ContractService) validate(ctx context.Context, connector *connectors.DataSourceConnector, schema *connectors.Schema, tables []string, link func(*ContractViolation), source map[string]interface{}) (*connectors.SyncValidation, error) {
+	contracts, err := s.ListContracts(ctx, &connector.ID, ContractStatusActive)
+	if err != nil {
+		return nil, err
+	}
+	contracts = contractsForTables(contracts, tables)
+	result := &connectors.SyncValidation{}
+	if len(contracts) == 0 {
+		return result, nil
+	}
+
+	discovered := make(map[string]*connectors.Table, len(schema.Tables))
+	for i := range schema.Tables {
+		t := &schema.Tables[i]
+		discovered[t.SchemaName+"."+t.TableName] = t
+	}
+
+	data := &contractData{connector: connector}
+	defer data.close()
+
+	for i := range contracts {
+		contract := &contracts[i]
+		violations := s.checkContract(ctx, contract, discovered[contract.SchemaName+"."+contract.TableName], data)
+		if len(violations) == 0 {
+			continue
+		}
+		result.Violations += len(violations)
+
+		breaking := 0
+		for j := range violations {
+			violations[j].ContractID = contract.ID
+			link(&violations[j])
+			if violations[j].Severity == "breaking" {
+				breaking++
+			}
+		}
+		s.storeViolations(ctx, violations)
+		if breaking > 0 && contract.Enforcement == ContractEnforcementBlock {
+			result.Blocking = append(result.Blocking,
+				fmt.Sprintf("contract %s v%d has %d breaking violations", contract.Name, contract.Version, breaking))
+		}
+		metadata := map[string]interface{}{
+			"event":      "violations",
+			"violations": len(violations),
+			"breaking":   breaking,
+			"blocked":    breaking > 0 && contract.Enforcement == ContractEnforcementBlock,
+		}
+		for k, v := range source {
+			metadata[k] = v
+		}
+		s.notify(ctx, contract,
+			fmt.Sprintf("Data contract %s v%d violated on sync of %s", contract.Name, contract.Version, connector.Name),
+			describeViolations(violations), metadata)
+	}
+	return result, nil
+}
+
+/* contractsForTables keeps the contracts on the given tables, matched by schema-qualified or bare name */
+func contractsForTables(contracts []DataContract, tables []string) []DataContract {
+	if len(tables) == 0 {
+		return contracts
+	}
+	wanted := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		wanted[table] = true
+	}
+	var matched []DataContract
+	for _, contract := range contracts {
+		if wanted[qualifiedName(contract.SchemaName, contract.TableName)] || wanted[contract.TableName] {
+			matched = append(matched, contract)
+		}
+	}
+	return matched
+}
+
+/* checkContract checks a discovered table against a contract: its schema with the schema evolution change
+ * detection, then its values and freshness against the data */
+func (s *ContractService) checkContract(ctx context.Context, contract *DataContract, table *connectors.Table, data *contractData) []ContractViolation {
+	if table == nil {
+		return []ContractViolation{contractViolation("table_missing", nil, "breaking",
+			fmt.Sprintf("Table %s was not found on sync", qualifiedName(contract.SchemaName, contract.TableName)), nil)}
+	}
+
+	var violations []ContractViolation
+	for _, change := range s.evolution.detectChanges(contractSchema(contract.Columns), discoveredSchema(table)) {
+		column := change.ColumnName
+		switch change.ChangeType {
+		case "column_removed":
+			violations = append(violations, contractViolation("column_missing", &column, "breaking",
+				fmt.Sprintf("Column %s is declared but missing", column), nil))
+		case "type_changed":
+			violations = append(violations, contractViolation("type_mismatch", &column, change.Severity,
+				fmt.Sprintf("Column %s is %v, contract declares %v", column, change.NewValue, change.OldValue),
+				map[string]interface{}{"declared": change.OldValue, "discovered": change.NewValue}))
+		}
+	}
+
+	discovered := make(map[string]connectors.Column, len(table.Columns))
+	for _, column := range table.Columns {
+		discovered[column.ColumnName] = column
+	}
+	for _, declared := range contract.Columns {
+		column, ok := discovered[declared.Name]
+		if !ok || declared.Nullable || !column.IsNullable {
+			continue
+		}
+		name := declared.Name
+		violations = append(violations, contractViolation("nullability", &name, "non_breaking",
+			fmt.Sprintf("Column %s allows nulls, contract declares it not null", name), nil))
+	}
+
+	return append(violations, s.checkContractData(ctx, contract, discovered, data)...)
+}
+
+/* checkContractData checks the declared columns' values and the freshness SLA by querying the table */
+func (s *ContractService) checkContractData(ctx context.Context, contract *DataContract, discovered map[string]connectors.Column, data *contractData) []ContractViolation {
+	type check struct {
+		violationType string
+		column        string
+		condition     string // Counts violating rows, or duplicated values for uniqueness
+		message       string
+	}
+	var checks []check
+	needsData := contract.FreshnessSLA != nil
+	for _, declared := range contract.Columns {
+		if !declared.Nullable || declared.Constraints != nil {
+			needsData = true
+		}
+	}
+	if !needsData {
+		return nil
+	}
+
+	db, dialect, err := data.open(ctx)
+	if err != nil {
+		return []ContractViolation{contractViolation("unchecked", nil, "non_breaking",
+			fmt.Sprintf("Values and freshness were not checked: %v", err), nil)}
+	}
+	table := qualifiedTable(dialect, contract.SchemaName, contract.TableName)
+
+	for _, declared := range contract.Columns {
+		if _, ok := discovered[declared.Name]; !ok {
+			continue // Already a column_missing violation
+		}
+		col := dialect.QuoteIdent(declared.Name)
+		if !declared.Nullable {
+			checks = append(checks, check{"null_values", declared.Name, col + " IS NULL", "%d sampled rows have a null %s"})
+		}
+		c := declared.Constraints
+		if c == nil {
+			continue
+		}
+		if c.Unique {
+			checks = append(checks, check{"duplicate_values", declared.Name, "", "%d sampled values of %s are duplicated"})
+		}
+		var bounds []string
+		if c.Min != nil {
+			bounds = append(bounds, fmt.Sprintf("%s < %v", col, *c.Min))
+		}
+		if c.Max != nil {
+			bounds = append(bounds, fmt.Sprintf("%s > %v", col, *c.Max))
+		}
+		if len(bounds) > 0 {
+			checks = append(checks, check{"out_of_range", declared.Name, strings.Join(bounds, " OR "), "%d sampled rows have %s out of range"})
+		}
+		if len(c.AllowedValues) > 0 {
+			quoted := make([]string, len(c.AllowedValues))
+			for i, v := range c.AllowedValues {
+				quoted[i] = dialect.QuoteString(v)
+			}
+			checks = append(checks, check{"disallowed_values", declared.Name,
+				fmt.Sprintf("%s IS NOT NULL AND %s NOT IN (%s)", col, col, strings.Join(quoted, ", ")), "%d sampled rows have a disallowed %s"})
+		}
+	}
+
+	var violations []ContractViolation
+	for _, c := range checks {
+		column := c.column
+		query := contractCheckQuery(dialect, table, column, c.condition, c.violationType == "duplicate_values")
+		var count int64
+		if err := db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+			violations = append(violations, contractViolation("unchecked", &column, "non_breaking",
+				fmt.Sprintf("Could not check %s of %s: %v", c.violationType, column, err), nil))
+			continue
+		}
+		if count > 0 {
+			violations = append(violations, contractViolation(c.violationType, &column, "breaking",
+				fmt.Sprintf(c.message, count, column), map[string]interface{}{"rows": count, "sampled_rows": contractSampleRows}))
+		}
+	}
+
+	if sla := contract.FreshnessSLA; sla != nil {
+		if _, ok := discovered[sla.Column]; ok {
+			violations = append(violations, checkFreshnessSLA(ctx, db, dialect, table, sla)...)
+		}
+	}
+	return violations
+}
+
+/* contractCheckQuery counts the violating rows, or the duplicated values, among a bounded sample of the table's rows */
+func contractCheckQuery(dialect connectors.QueryDialect, table, column, condition string, duplicates bool) string {
+	col := dialect.QuoteIdent(column)
+	sample := dialect.SelectLimited(col, table, contractSampleRows)
+	if duplicates {
+		return fmt.Sprintf("SELECT COUNT(*) FROM (SELECT %s FROM (%s) s WHERE %s IS NOT NULL GROUP BY %s HAVING COUNT(*) > 1) d",
+			col, sample, col, col)
+	}
+	return fmt.Sprintf("SELECT COUNT(*) FROM (%s) s WHERE %s", sample, condition)
+}
+
+/* checkFreshnessSLA checks the latest value of the SLA column is within the allowed lag */
+func checkFreshnessSLA(ctx context.Context, db *sql.DB, dialect connectors.QueryDialect, table string, sla *FreshnessSLA) []ContractViolation {
+	column := sla.Column
+	var latest sql.NullTime
+	query := fmt.Sprintf("SELECT MAX(%s) FROM %s", dialect.QuoteIdent(column), table)
+	if err := db.QueryRowContext(ctx, query).Scan(&latest); err != nil {
+		return []ContractViolation{contractViolation("unchecked", &column, "non_breaking",
+			fmt.Sprintf("Could not check freshness of %s: %v", column, err), nil)}
+	}
+	if !latest.Valid {
+		return []ContractViolation{contractViolation("freshness_sla", &column, "breaking",
+			fmt.Sprintf("Table has no %s values to judge freshness", column), nil)}
+	}
+	lag := time.Since(latest.Time)
+	if lag <= time.Duration(sla.MaxLagMinutes)*time.Minute {
+		return nil
+	}
+	return []ContractViolation{contractViolation("freshness_sla", &column, "breaking",
+		fmt.Sprintf("Latest %s is %.0f minutes old, SLA allows %d", column, lag.Minutes(), sla.MaxLagMinutes),
+		map[string]interface{}{"latest": latest.Time, "lag_minutes": lag.Minutes(), "max_lag_minutes": sla.MaxLagMinutes})}
+}
+
+func contractViolation(violationType string, column *string, severity, message string, details map[string]interface{}) ContractViolation {
+	return ContractViolation{
+		ID:            uuid.New(),
+		ViolationType: violationType,
+		ColumnName:    column,
+		Message:       message,
+		Severity:      severity,
+		Details:       details,
+		DetectedAt:    time.Now(),
+	}
+}
+
+/* contractData opens the connector's database once per sync, for the contracts that check values */
+type contractData struct {
+	connector *connectors.DataSourceConnector
+	db        *sql.DB
+	dialect   connectors.QueryDialect
+	err       error
+	opened    bool
+}
+
+func (d *contractData) open(ctx context.Context) (*sql.DB, connectors.QueryDialect, error) {
+	if !d.opened {
+		d.opened = true
+		d.dialect, d.err = connectors.GetQueryDialect(d.connector.ConnectorType)
+		if d.err == nil {
+			d.db, d.err = connectors.OpenQueryDB(ctx, d.connector)
+		}
+	}
+	return d.db, d.dialect, d.err
+}
+
+func (d *contractData) close() {
+	if d.db != nil {
+		d.db.Close()
+	}
+}
+
+func qualifiedTable(dialect connectors.QueryDialect, schemaName, tableName string) string {
+	if schemaName == "" {
+		return dialect.QuoteIdent(tableName)
+	}
+	return dialect.QuoteIdent(schemaName) + "." + dialect.QuoteIdent(tableName)
+}
+
+func qualifiedName(schemaName, tableName string) string {
+	if schemaName == "" {
+		return tableName
+	}
+	return schemaName + "." + tableName
+}
+
+/* storeViolations stores violations found on a sync */
+func (s *ContractService) storeViolations(ctx context.Context, violations []ContractViolation) {
+	for _, v := range violations {
+		detailsJSON, _ := json.Marshal(v.Details)
+		if v.Details == nil {
+			detailsJSON = []byte("{}")
+		}
+		s.pool.Exec(ctx, `
+			INSERT INTO neuronip.data_contract_violations
+			(id, contract_id, sync_history_id, ingestion_job_id, violation_type, column_name, message, severity, details, detected_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			v.ID, v.ContractID, v.SyncHistoryID, v.IngestionJobID, v.ViolationType, v.ColumnName, v.Message, v.Severity, detailsJSON, v.DetectedAt)
+	}
+}
+
+/* notify sends a notification about a contract to its producer and each of its consumers */
+func (s *ContractService) notify(ctx context.Context, contract *DataContract, title, message string, metadata map[string]interface{}) {
+	if s.notifications == nil {
+		return
+	}
+	recipients := map[uuid.UUID]string{contract.OwnerID: "producer"}
+	for _, consumer := range contract.Consumers {
+		if _, ok := recipients[consumer]; !ok {
+			recipients[consumer] = "consumer"
+		}
+	}
+	for userID, role := range recipients {
+		meta := map[string]interface{}{
+			"contract_id": contract.ID,
+			"version":     contract.Version,
+			"role":        role,
+		}
+		for k, v := range metadata {
+			meta[k] = v
+		}
+		// A recipient that no longer exists must not stop the others being told
+		s.notifications.CreateNotification(ctx, userID, contractNotificationType, title, message, meta)
+	}
+}
+
+const maxNotifiedItems = 5
+
+func describeViolations(violations []ContractViolation) string {
+	lines := make([]string, 0, maxNotifiedItems+1)
+	for i, v := range violations {
+		if i == maxNotifiedItems {
+			lines = append(lines, fmt.Sprintf("and %d more", len(violations)-maxNotifiedItems))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("[%s] %s", v.Severity, v.Message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func describeChanges(changes []SchemaChange) string {
+	lines := []string{}
+	for _, change := range changes {
+		if change.Severity != "breaking" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s %s: %s", change.ChangeType, change.ColumnName, change.Impact))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,176 @@
+package catalog
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/neurondb/NeuronIP/api/internal/connectors"
+)
+
+/* TestValidateContract checks the declarations a contract must make and the defaults it gets */
+func TestValidateContract(t *testing.T) {
+	min, max := 10.0, 1.0
+	valid := func() DataContract {
+		return DataContract{
+			Name: "orders", TableName: "orders", ConnectorID: uuid.New(), OwnerID: uuid.New(),
+			Columns: []ContractColumn{{Name: "id", Type: "bigint"}, {Name: "placed_at", Type: "timestamp"}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*DataContract)
+		errMsg string
+	}{
+		{name: "valid", modify: func(c *DataContract) {}},
+		{name: "no table", modify: func(c *DataContract) { c.TableName = "" }, errMsg: "table_name"},
+		{name: "no owner", modify: func(c *DataContract) { c.OwnerID = uuid.Nil }, errMsg: "owner_id"},
+		{name: "no columns", modify: func(c *DataContract) { c.Columns = nil }, errMsg: "at least one column"},
+		{name: "unknown enforcement", modify: func(c *DataContract) { c.Enforcement = "strict" }, errMsg: "enforcement"},
+		{name: "duplicate column", modify: func(c *DataContract) { c.Columns[1].Name = "id" }, errMsg: "declared twice"},
+		{
+			name:   "min above max",
+			modify: func(c *DataContract) { c.Columns[0].Constraints = &ColumnConstraints{Min: &min, Max: &max} },
+			errMsg: "min above max",
+		},
+		{
+			name:   "undeclared SLA column",
+			modify: func(c *DataContract) { c.FreshnessSLA = &FreshnessSLA{Column: "updated_at", MaxLagMinutes: 60} },
+			errMsg: "not declared",
+		},
+		{
+			name:   "non-positive SLA",
+			modify: func(c *DataContract) { c.FreshnessSLA = &FreshnessSLA{Column: "placed_at"} },
+			errMsg: "max_lag_minutes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contract := valid()
+			tt.modify(&contract)
+			err := validateContract(&contract)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if contract.Enforcement != ContractEnforcementWarn {
+					t.Errorf("expected enforcement to default to %s, got %s", ContractEnforcementWarn, contract.Enforcement)
+				}
+				return
+			}
+			if AsContractError(err) == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Fatalf("expected contract error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+/* TestCheckContractSchema checks the schema violations found against a discovered table */
+func TestCheckContractSchema(t *testing.T) {
+	s := &ContractService{evolution: NewSchemaEvolutionService(nil)}
+	contract := &DataContract{
+		SchemaName: "sales",
+		TableName:  "orders",
+		Columns: []ContractColumn{
+			{Name: "id", Type: "bigint"},
+			{Name: "status", Type: "text", Nullable: true},
+			{Name: "amount", Type: "numeric", Nullable: true},
+		},
+	}
+
+	if violations := s.checkContract(context.Background(), contract, nil, nil); len(violations) != 1 || violations[0].ViolationType != "table_missing" {
+		t.Fatalf("expected a table_missing violation, got %+v", violations)
+	}
+
+	table := &connectors.Table{SchemaName: "sales", TableName: "orders", Columns: []connectors.Column{
+		{ColumnName: "id", ColumnType: "bigint", IsNullable: true},
+		{ColumnName: "status", ColumnType: "text", IsNullable: true},
+	}}
+	// Every column is nullable without constraints, so the check never needs the data
+	contract.Columns[0].Nullable = true
+	violations := s.checkContract(context.Background(), contract, table, nil)
+	types := map[string]string{}
+	for _, v := range violations {
+		types[*v.ColumnName] = v.ViolationType
+	}
+	if len(violations) != 1 || types["amount"] != "column_missing" {
+		t.Fatalf("expected only a column_missing violation for amount, got %+v", violations)
+	}
+}
+
+/* TestContractCheckQuery checks that value checks only read a bounded sample of the table */
+func TestContractCheckQuery(t *testing.T) {
+	postgres, _ := connectors.GetQueryDialect(connectors.ConnectorPostgreSQL)
+	sqlserver, _ := connectors.GetQueryDialect(connectors.ConnectorSQLServer)
+
+	tests := []struct {
+		name       string
+		dialect    connectors.QueryDialect
+		condition  string
+		duplicates bool
+		want       string
+	}{
+		{
+			name:      "condition on postgres",
+			dialect:   postgres,
+			condition: `"status" IS NULL`,
+			want:      `SELECT COUNT(*) FROM (SELECT "status" FROM "sales"."orders" LIMIT 100000) s WHERE "status" IS NULL`,
+		},
+		{
+			name:       "duplicates on postgres",
+			dialect:    postgres,
+			duplicates: true,
+			want: `SELECT COUNT(*) FROM (SELECT "status" FROM (SELECT "status" FROM "sales"."orders" LIMIT 100000) s ` +
+				`WHERE "status" IS NOT NULL GROUP BY "status" HAVING COUNT(*) > 1) d`,
+		},
+		{
+			name:      "condition on sql server",
+			dialect:   sqlserver,
+			condition: `"status" IS NULL`,
+			want:      `SELECT COUNT(*) FROM (SELECT TOP 100000 "status" FROM "sales"."orders") s WHERE "status" IS NULL`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := qualifiedTable(tt.dialect, "sales", "orders")
+			if got := contractCheckQuery(tt.dialect, table, "status", tt.condition, tt.duplicates); got != tt.want {
+				t.Errorf("expected\n%s\ngot\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+/* TestContractsForTables checks that ingestion jobs only validate the contracts on the tables they read */
+func TestContractsForTables(t *testing.T) {
+	contracts := []DataContract{
+		{Name: "orders", SchemaName: "sales", TableName: "orders"},
+		{Name: "customers", SchemaName: "crm", TableName: "customers"},
+		{Name: "events", TableName: "events"},
+	}
+
+	tests := []struct {
+		name   string
+		tables []string
+		want   []string
+	}{
+		{name: "no tables", want: []string{"orders", "customers", "events"}},
+		{name: "qualified name", tables: []string{"sales.orders"}, want: []string{"orders"}},
+		{name: "bare name", tables: []string{"customers", "events"}, want: []string{"customers", "events"}},
+		{name: "wrong schema", tables: []string{"crm.orders"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := contractsForTables(contracts, tt.tables)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %+v", tt.want, got)
+			}
+			for i, name := range tt.want {
+				if got[i].Name != name {
+					t.Errorf("expected contract %d to be %s, got %s", i, name, got[i].Name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,861 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+)
+
+/* Measure aggregations */
+const (
+	AggregationSum           = "sum"
+	AggregationCount         = "count"
+	AggregationCountDistinct = "count_distinct"
+	AggregationAvg           = "avg"
+	AggregationMin           = "min"
+	AggregationMax           = "max"
+)
+
+/* Join relationships, from the joined-from table to the joined table */
+const (
+	RelationshipManyToOne = "many_to_one"
+	RelationshipOneToOne  = "one_to_one"
+	RelationshipOneToMany = "one_to_many" // Fans out rows; measures are deduplicated by the base table's primary key
+)
+
+/* Supported time grains, as accepted by date_trunc */
+var metricTimeGrains = []string{"day", "week", "month", "quarter", "year"}
+
+/* Time range presets and their bounds, relative to CURRENT_DATE */
+var metricTimePresets = map[string][2]string{
+	"today":        {"CURRENT_DATE", "CURRENT_DATE + INTERVAL '1 day'"},
+	"yesterday":    {"CURRENT_DATE - INTERVAL '1 day'", "CURRENT_DATE"},
+	"last_7_days":  {"CURRENT_DATE - INTERVAL '7 days'", "CURRENT_DATE"},
+	"last_30_days": {"CURRENT_DATE - INTERVAL '30 days'", "CURRENT_DATE"},
+	"this_month":   {"date_trunc('month', CURRENT_DATE)", "date_trunc('month', CURRENT_DATE) + INTERVAL '1 month'"},
+	"last_month":   {"date_trunc('month', CURRENT_DATE) - INTERVAL '1 month'", "date_trunc('month', CURRENT_DATE)"},
+	"this_quarter": {"date_trunc('quarter', CURRENT_DATE)", "date_trunc('quarter', CURRENT_DATE) + INTERVAL '3 months'"},
+	"last_quarter": {"date_trunc('quarter', CURRENT_DATE) - INTERVAL '3 months'", "date_trunc('quarter', CURRENT_DATE)"},
+	"this_year":    {"date_trunc('year', CURRENT_DATE)", "date_trunc('year', CURRENT_DATE) + INTERVAL '1 year'"},
+	"last_year":    {"date_trunc('year', CURRENT_DATE) - INTERVAL '1 year'", "date_trunc('year', CURRENT_DATE)"},
+}
+
+var metricNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+/* MetricDefinition is the semantic-layer declaration of an approved metric */
+type MetricDefinition struct {
+	BaseTable  string            `json:"base_table"`
+	PrimaryKey []string          `json:"primary_key,omitempty"` // Base table key; required to group by dimensions across one_to_many joins
+	Measure    MetricMeasure     `json:"measure"`
+	TimeColumn string            `json:"time_column,omitempty"` // Base table column used for time grains and ranges
+	TimeGrains []string          `json:"time_grains,omitempty"` // Allowed grains; empty allows all
+	Dimensions []MetricDimension `json:"dimensions,omitempty"`
+	Joins      []MetricJoin      `json:"joins,omitempty"`
+	Filters    []string          `json:"filters,omitempty"` // Conditions over base table columns applied to every query
+}
+
+/* MetricMeasure is the aggregated value of a metric */
+type MetricMeasure struct {
+	Aggregation string `json:"aggregation"`
+	Expression  string `json:"expression,omitempty"` // Over base table columns; empty counts rows
+}
+
+/* MetricDimension is an attribute a metric can be grouped or filtered by */
+type MetricDimension struct {
+	Name   string `json:"name"`
+	Join   string `json:"join,omitempty"` // Join providing the column; empty for the base table
+	Column string `json:"column"`
+}
+
+/* MetricJoin declares one step of a join path from the base table */
+type MetricJoin struct {
+	Name         string          `json:"name"`
+	Table        string          `json:"table"`
+	From         string          `json:"from,omitempty"` // Earlier join this one starts from; empty for the base table
+	On           []MetricJoinKey `json:"on"`
+	Relationship string          `json:"relationship"`
+}
+
+/* MetricJoinKey pairs a column of the joined-from table with a column of the joined table */
+type MetricJoinKey struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+/* MetricQuery asks for metrics grouped by dimensions and a time grain */
+type MetricQuery struct {
+	Metrics    []string         `json:"metrics"`
+	Dimensions []string         `json:"dimensions,omitempty"`
+	TimeGrain  string           `json:"time_grain,omitempty"`
+	TimeRange  *MetricTimeRange `json:"time_range,omitempty"`
+	Filters    []MetricFilter   `json:"filters,omitempty"`
+	Limit      int              `json:"limit,omitempty"`
+}
+
+/* MetricTimeRange restricts a query to a preset period or explicit bounds; End is exclusive */
+type MetricTimeRange struct {
+	Preset string     `json:"preset,omitempty"`
+	Start  *time.Time `json:"start,omitempty"`
+	End    *time.Time `json:"end,omitempty"`
+}
+
+/* MetricFilter restricts a dimension to values */
+type MetricFilter struct {
+	Dimension string        `json:"dimension"`
+	Operator  string        `json:"operator"` // =, !=, in, not_in, >, >=, <, <=
+	Values    []interface{} `json:"values"`
+}
+
+/* CompiledMetricQuery is the SQL generated for a metric query */
+type CompiledMetricQuery struct {
+	SQL        string   `json:"sql"`
+	Metrics    []string `json:"metrics"`
+	Dimensions []string `json:"dimensions"` // Grouping columns of the result, including the time grain
+	Tables     []string `json:"tables"`
+}
+
+/* MetricCompileError reports a metric query or definition that cannot be compiled */
+type MetricCompileError struct {
+	Message string
+}
+
+func (e *MetricCompileError) Error() string {
+	return e.Message
+}
+
+/* AsMetricCompileError returns the metric compile error in err's chain, or nil */
+func AsMetricCompileError(err error) *MetricCompileError {
+	var compileErr *MetricCompileError
+	if errors.As(err, &compileErr) {
+		return compileErr
+	}
+	return nil
+}
+
+func compileErrorf(format string, args ...interface{}) error {
+	return &MetricCompileError{Message: fmt.Sprintf(format, args...)}
+}
+
+/* ValidateMetricDefinition checks that a definition is complete and its SQL fragments parse */
+func ValidateMetricDefinition(def *MetricDefinition) error {
+	if def == nil {
+		return compileErrorf("semantic definition is required")
+	}
+	if err := validateTableName(def.BaseTable); err != nil {
+		return err
+	}
+
+	switch def.Measure.Aggregation {
+	case AggregationSum, AggregationAvg, AggregationMin, AggregationMax, AggregationCountDistinct:
+		if def.Measure.Expression == "" {
+			return compileErrorf("%s measure requires an expression", def.Measure.Aggregation)
+		}
+	case AggregationCount:
+	default:
+		return compileErrorf("unsupported aggregation %q", def.Measure.Aggregation)
+	}
+	if def.Measure.Expression != "" {
+		if err := validateFragment("SELECT "+def.Measure.Expression, "measure expression"); err != nil {
+			return err
+		}
+	}
+	for _, filter := range def.Filters {
+		if err := validateFragment("SELECT 1 WHERE "+filter, "filter"); err != nil {
+			return err
+		}
+	}
+
+	for _, grain := range def.TimeGrains {
+		if !containsName(metricTimeGrains, grain) {
+			return compileErrorf("unsupported time grain %q", grain)
+		}
+	}
+	if len(def.TimeGrains) > 0 && def.TimeColumn == "" {
+		return compileErrorf("time grains require a time column")
+	}
+
+	joins := make(map[string]bool)
+	for _, join := range def.Joins {
+		if !metricNamePattern.MatchString(join.Name) {
+			return compileErrorf("join name %q must be a lowercase identifier", join.Name)
+		}
+		if joins[join.Name] {
+			return compileErrorf("duplicate join %q", join.Name)
+		}
+		if join.From != "" && !joins[join.From] {
+			return compileErrorf("join %q starts from %q, which must be declared before it", join.Name, join.From)
+		}
+		if err := validateTableName(join.Table); err != nil {
+			return err
+		}
+		if len(join.On) == 0 {
+			return compileErrorf("join %q has no join keys", join.Name)
+		}
+		for _, key := range join.On {
+			if key.From == "" || key.To == "" {
+				return compileErrorf("join %q has an incomplete join key", join.Name)
+			}
+		}
+		switch join.Relationship {
+		case RelationshipManyToOne, RelationshipOneToOne, RelationshipOneToMany:
+		default:
+			return compileErrorf("join %q has unsupported relationship %q", join.Name, join.Relationship)
+		}
+		joins[join.Name] = true
+	}
+
+	dimensions := make(map[string]bool)
+	for _, dim := range def.Dimensions {
+		if !metricNamePattern.MatchString(dim.Name) {
+			return compileErrorf("dimension name %q must be a lowercase identifier", dim.Name)
+		}
+		if dimensions[dim.Name] || containsName(metricTimeGrains, dim.Name) {
+			return compileErrorf("dimension name %q is already used", dim.Name)
+		}
+		if dim.Join != "" && !joins[dim.Join] {
+			return compileErrorf("dimension %q uses undeclared join %q", dim.Name, dim.Join)
+		}
+		if dim.Column == "" {
+			return compileErrorf("dimension %q has no column", dim.Name)
+		}
+		dimensions[dim.Name] = true
+	}
+	return nil
+}
+
+/* CompileMetrics generates SQL for a query over approved metrics.
+ * Each metric is aggregated in its own CTE at the requested grain and the CTEs are joined on the grouping
+ * columns, so metrics from different fact tables never multiply each other. Within a metric, dimensions
+ * reached through one_to_many joins aggregate over distinct base rows so fan-out does not inflate measures. */
+func CompileMetrics(metrics []Metric, q MetricQuery) (*CompiledMetricQuery, error) {
+	if len(q.Metrics) == 0 {
+		return nil, compileErrorf("at least one metric is required")
+	}
+	if q.TimeGrain != "" && !containsName(metricTimeGrains, q.TimeGrain) {
+		return nil, compileErrorf("unsupported time grain %q", q.TimeGrain)
+	}
+
+	byName := make(map[string]*Metric, len(metrics))
+	for i := range metrics {
+		byName[metrics[i].Name] = &metrics[i]
+	}
+
+	groupColumns := append([]string{}, q.Dimensions...)
+	if q.TimeGrain != "" {
+		if containsName(q.Dimensions, q.TimeGrain) {
+			return nil, compileErrorf("dimension %q conflicts with the time grain", q.TimeGrain)
+		}
+		groupColumns = append(groupColumns, q.TimeGrain)
+	}
+
+	compiled := &CompiledMetricQuery{Metrics: q.Metrics, Dimensions: groupColumns}
+	tables := make(map[string]bool)
+	var ctes []string
+	for i, name := range q.Metrics {
+		metric := byName[name]
+		if metric == nil {
+			return nil, compileErrorf("metric %q is not an approved metric with a semantic definition", name)
+		}
+		if metric.Definition == nil {
+			return nil, compileErrorf("metric %q has no semantic definition", name)
+		}
+		cte, usedTables, err := compileMetric(metric, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, table := range usedTables {
+			tables[table] = true
+		}
+		ctes = append(ctes, fmt.Sprintf("%s AS (\n%s\n)", metricCTEName(i), cte))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("WITH ")
+	sb.WriteString(strings.Join(ctes, ",\n"))
+	sb.WriteString("\nSELECT ")
+
+	var selectList []string
+	for _, column := range groupColumns {
+		if len(q.Metrics) == 1 {
+			selectList = append(selectList, metricCTEName(0)+"."+quoteIdent(column))
+			continue
+		}
+		var parts []string
+		for i := range q.Metrics {
+			parts = append(parts, metricCTEName(i)+"."+quoteIdent(column))
+		}
+		selectList = append(selectList, fmt.Sprintf("COALESCE(%s) AS %s", strings.Join(parts, ", "), quoteIdent(column)))
+	}
+	for i, name := range q.Metrics {
+		selectList = append(selectList, metricCTEName(i)+"."+quoteIdent(name))
+	}
+	sb.WriteString(strings.Join(selectList, ", "))
+	sb.WriteString("\nFROM " + metricCTEName(0))
+
+	// Drill across: combine per-metric results on the grouping columns
+	for i := 1; i < len(q.Metrics); i++ {
+		var conditions []string
+		for _, column := range groupColumns {
+			var left []string
+			for j := 0; j < i; j++ {
+				left = append(left, metricCTEName(j)+"."+quoteIdent(column))
+			}
+			leftExpr := left[0]
+			if len(left) > 1 {
+				leftExpr = "COALESCE(" + strings.Join(left, ", ") + ")"
+			}
+			conditions = append(conditions, fmt.Sprintf("%s IS NOT DISTINCT FROM %s.%s", leftExpr, metricCTEName(i), quoteIdent(column)))
+		}
+		on := "TRUE"
+		if len(conditions) > 0 {
+			on = strings.Join(conditions, " AND ")
+		}
+		sb.WriteString(fmt.Sprintf("\nFULL OUTER JOIN %s ON %s", metricCTEName(i), on))
+	}
+
+	if len(groupColumns) > 0 {
+		var order []string
+		for i := range groupColumns {
+			order = append(order, strconv.Itoa(i+1))
+		}
+		sb.WriteString("\nORDER BY " + strings.Join(order, ", "))
+	}
+	if q.Limit > 0 {
+		sb.WriteString(fmt.Sprintf("\nLIMIT %d", q.Limit))
+	}
+
+	compiled.SQL = sb.String()
+	for table := range tables {
+		compiled.Tables = append(compiled.Tables, table)
+	}
+	sort.Strings(compiled.Tables)
+	return compiled, nil
+}
+
+/* compileMetric generates the aggregation of one metric grouped by the query's dimensions and grain */
+func compileMetric(metric *Metric, q MetricQuery) (string, []string, error) {
+	def := metric.Definition
+
+	dimensions := make(map[string]MetricDimension, len(def.Dimensions))
+	for _, dim := range def.Dimensions {
+		dimensions[dim.Name] = dim
+	}
+	joins := make(map[string]MetricJoin, len(def.Joins))
+	for _, join := range def.Joins {
+		joins[join.Name] = join
+	}
+
+	// Resolve requested and filtered dimensions and the joins they need
+	needed := make(map[string]bool)
+	resolve := func(name string) (MetricDimension, error) {
+		dim, ok := dimensions[name]
+		if !ok {
+			return dim, compileErrorf("metric %q has no dimension %q", metric.Name, name)
+		}
+		for join := dim.Join; join != ""; join = joins[join].From {
+			needed[join] = true
+		}
+		return dim, nil
+	}
+	var groupDims []MetricDimension
+	for _, name := range q.Dimensions {
+		dim, err := resolve(name)
+		if err != nil {
+			return "", nil, err
+		}
+		groupDims = append(groupDims, dim)
+	}
+	var conditions []string
+	for _, filter := range q.Filters {
+		dim, err := resolve(filter.Dimension)
+		if err != nil {
+			return "", nil, err
+		}
+		condition, err := filterCondition(dimensionExpr(dim), filter)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if (q.TimeGrain != "" || q.TimeRange != nil) && def.TimeColumn == "" {
+		return "", nil, compileErrorf("metric %q has no time column", metric.Name)
+	}
+	if q.TimeGrain != "" && len(def.TimeGrains) > 0 && !containsName(def.TimeGrains, q.TimeGrain) {
+		return "", nil, compileErrorf("metric %q does not support time grain %q", metric.Name, q.TimeGrain)
+	}
+	if q.TimeRange != nil {
+		rangeConditions, err := timeRangeConditions(*q.TimeRange)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, rangeConditions...)
+	}
+
+	tables := []string{def.BaseTable}
+	var joinClauses []string
+	fanOut := false
+	for _, join := range def.Joins {
+		if !needed[join.Name] {
+			continue
+		}
+		fromAlias := "f"
+		if join.From != "" {
+			fromAlias = quoteIdent(join.From)
+		}
+		var keys []string
+		for _, key := range join.On {
+			keys = append(keys, fmt.Sprintf("%s.%s = %s.%s", quoteIdent(join.Name), quoteIdent(key.To), fromAlias, quoteIdent(key.From)))
+		}
+		joinClauses = append(joinClauses, fmt.Sprintf("LEFT JOIN %s AS %s ON %s", quoteTable(join.Table), quoteIdent(join.Name), strings.Join(keys, " AND ")))
+		tables = append(tables, join.Table)
+		if join.Relationship == RelationshipOneToMany {
+			fanOut = true
+		}
+	}
+	if fanOut && len(def.PrimaryKey) == 0 {
+		return "", nil, compileErrorf("metric %q needs a primary key to group by dimensions across one-to-many joins", metric.Name)
+	}
+
+	// Base rows: the measure input and time column, with the metric's own filters applied
+	value := "1"
+	if def.Measure.Expression != "" {
+		value = def.Measure.Expression
+	}
+	base := fmt.Sprintf("SELECT *, (%s) AS __value", value)
+	if def.TimeColumn != "" {
+		base += fmt.Sprintf(", %s AS __time", quoteIdent(def.TimeColumn))
+	}
+	base += " FROM " + quoteTable(def.BaseTable)
+	if len(def.Filters) > 0 {
+		var filters []string
+		for _, filter := range def.Filters {
+			filters = append(filters, "("+filter+")")
+		}
+		base += " WHERE " + strings.Join(filters, " AND ")
+	}
+
+	var groupExprs []string
+	for _, dim := range groupDims {
+		groupExprs = append(groupExprs, fmt.Sprintf("%s AS %s", dimensionExpr(dim), quoteIdent(dim.Name)))
+	}
+	if q.TimeGrain != "" {
+		groupExprs = append(groupExprs, fmt.Sprintf("date_trunc('%s', f.__time) AS %s", q.TimeGrain, quoteIdent(q.TimeGrain)))
+	}
+
+	from := "(" + base + ") AS f"
+	if len(joinClauses) > 0 {
+		from += "\n" + strings.Join(joinClauses, "\n")
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "\nWHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var groupBy string
+	if len(groupExprs) > 0 {
+		var positions []string
+		for i := range groupExprs {
+			positions = append(positions, strconv.Itoa(i+1))
+		}
+		groupBy = "\nGROUP BY " + strings.Join(positions, ", ")
+	}
+
+	if !fanOut {
+		selectList := append(append([]string{}, groupExprs...), fmt.Sprintf("%s AS %s", aggregateExpr(def.Measure.Aggregation, "f.__value"), quoteIdent(metric.Name)))
+		return fmt.Sprintf("SELECT %s\nFROM %s%s%s", strings.Join(selectList, ", "), from, where, groupBy), tables, nil
+	}
+
+	// Fan-out: keep one row per base row and group, then aggregate
+	var distinct []string
+	for i, column := range def.PrimaryKey {
+		distinct = append(distinct, fmt.Sprintf("f.%s AS __pk%d", quoteIdent(column), i))
+	}
+	distinct = append(distinct, "f.__value")
+	distinct = append(distinct, groupExprs...)
+
+	var outer []string
+	for _, dim := range groupDims {
+		outer = append(outer, "x."+quoteIdent(dim.Name))
+	}
+	if q.TimeGrain != "" {
+		outer = append(outer, "x."+quoteIdent(q.TimeGrain))
+	}
+	outer = append(outer, fmt.Sprintf("%s AS %s", aggregateExpr(def.Measure.Aggregation, "x.__value"), quoteIdent(metric.Name)))
+
+	return fmt.Sprintf("SELECT %s\nFROM (SELECT DISTINCT %s\nFROM %s%s) AS x%s",
+		strings.Join(outer, ", "), strings.Join(distinct, ", "), from, where, groupBy), tables, nil
+}
+
+/* aggregateExpr applies a measure aggregation to a value column */
+func aggregateExpr(aggregation, column string) string {
+	switch aggregation {
+	case AggregationCountDistinct:
+		return "COUNT(DISTINCT " + column + ")"
+	case AggregationCount:
+		return "COUNT(" + column + ")"
+	default:
+		return strings.ToUpper(aggregation) + "(" + column + ")"
+	}
+}
+
+/* dimensionExpr is the column expression of a dimension in the joined query */
+func dimensionExpr(dim MetricDimension) string {
+	alias := "f"
+	if dim.Join != "" {
+		alias = quoteIdent(dim.Join)
+	}
+	return alias + "." + quoteIdent(dim.Column)
+}
+
+/* filterCondition renders a dimension filter with its values as SQL literals */
+func filterCondition(expr string, filter MetricFilter) (string, error) {
+	if len(filter.Values) == 0 {
+		return "", compileErrorf("filter on %q has no values", filter.Dimension)
+	}
+
+	switch filter.Operator {
+	case "in", "not_in":
+		var literals []string
+		for _, value := range filter.Values {
+			literal, err := sqlLiteral(value)
+			if err != nil {
+				return "", err
+			}
+			literals = append(literals, literal)
+		}
+		operator := "IN"
+		if filter.Operator == "not_in" {
+			operator = "NOT IN"
+		}
+		return fmt.Sprintf("%s %s (%s)", expr, operator, strings.Join(literals, ", ")), nil
+	case "=", "!=", ">", ">=", "<", "<=":
+		if len(filter.Values) != 1 {
+			return "", compileErrorf("filter on %q with %s takes one value", filter.Dimension, filter.Operator)
+		}
+		if filter.Values[0] == nil {
+			switch filter.Operator {
+			case "=":
+				return expr + " IS NULL", nil
+			case "!=":
+				return expr + " IS NOT NULL", nil
+			}
+			return "", compileErrorf("filter on %q compares with null", filter.Dimension)
+		}
+		literal, err := sqlLiteral(filter.Values[0])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %s %s", expr, filter.Operator, literal), nil
+	default:
+		return "", compileErrorf("unsupported filter operator %q", filter.Operator)
+	}
+}
+
+/* timeRangeConditions bounds the base rows' time column */
+func timeRangeConditions(r MetricTimeRange) ([]string, error) {
+	if r.Preset != "" {
+		bounds, ok := metricTimePresets[r.Preset]
+		if !ok {
+			return nil, compileErrorf("unsupported time range preset %q", r.Preset)
+		}
+		return []string{"f.__time >= " + bounds[0], "f.__time < " + bounds[1]}, nil
+	}
+	if r.Start == nil && r.End == nil {
+		return nil, compileErrorf("time range needs a preset, start or end")
+	}
+	var conditions []string
+	if r.Start != nil {
+		conditions = append(conditions, fmt.Sprintf("f.__time >= '%s'::timestamptz", r.Start.UTC().Format(time.RFC3339Nano)))
+	}
+	if r.End != nil {
+		conditions = append(conditions, fmt.Sprintf("f.__time < '%s'::timestamptz", r.End.UTC().Format(time.RFC3339Nano)))
+	}
+	return conditions, nil
+}
+
+/* sqlLiteral renders a JSON filter value as a SQL literal */
+func sqlLiteral(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		if strings.ContainsRune(v, 0) {
+			return "", compileErrorf("filter value contains a NUL character")
+		}
+		if strings.Contains(v, `\`) {
+			return "E'" + strings.ReplaceAll(strings.ReplaceAll(v, `\`, `\\`), "'", "''") + "'", nil
+		}
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'", nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	default:
+		return "", compileErrorf("unsupported filter value %v", value)
+	}
+}
+
+/* validateTableName checks a table name is a plain, optionally schema-qualified name */
+func validateTableName(name string) error {
+	parts := strings.Split(name, ".")
+	if name == "" || len(parts) > 2 {
+		return compileErrorf("invalid table name %q", name)
+	}
+	for _, part := range parts {
+		if part == "" {
+			return compileErrorf("invalid table name %q", name)
+		}
+	}
+	return nil
+}
+
+/* validateFragment checks a definition fragment parses as a single SELECT without FROM */
+func validateFragment(sql, what string) error {
+	tree, err := pg_query.Parse(sql)
+	if err != nil {
+		return compileErrorf("invalid %s: %v", what, err)
+	}
+	if len(tree.Stmts) != 1 {
+		return compileErrorf("invalid %s: must be a single expression", what)
+	}
+	stmt := tree.Stmts[0].Stmt.GetSelectStmt()
+	if stmt == nil || len(stmt.TargetList) != 1 || len(stmt.FromClause) > 0 || stmt.GroupClause != nil ||
+		stmt.HavingClause != nil || stmt.LimitCount != nil || len(stmt.SortClause) > 0 || stmt.Op != pg_query.SetOperation_SETOP_NONE {
+		return compileErrorf("invalid %s: must be a single expression", what)
+	}
+	return nil
+}
+
+func quoteIdent(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
+
+func quoteTable(name string) string {
+	return pgx.Identifier(strings.Split(name, ".")).Sanitize()
+}
+
+func metricCTEName(i int) string {
+	return fmt.Sprintf("m%d", i)
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+/* Phrases naming a time grain in a question */
+var metricGrainPhrases = map[string]string{
+	"daily": "day", "per day": "day", "by day": "day", "each day": "day",
+	"weekly": "week", "per week": "week", "by week": "week", "each week": "week",
+	"monthly": "month", "per month": "month", "by month": "month", "each month": "month",
+	"quarterly": "quarter", "per quarter": "quarter", "by quarter": "quarter", "each quarter": "quarter",
+	"yearly": "year", "annually": "year", "per year": "year", "by year": "year", "each year": "year",
+}
+
+/* Phrases naming a time range preset in a question */
+var metricPresetPhrases = map[string]string{
+	"today": "today", "yesterday": "yesterday",
+	"last 7 days": "last_7_days", "past 7 days": "last_7_days", "last week": "last_7_days", "past week": "last_7_days",
+	"last 30 days": "last_30_days", "past 30 days": "last_30_days",
+	"this month": "this_month", "last month": "last_month",
+	"this quarter": "this_quarter", "last quarter": "last_quarter",
+	"this year": "this_year", "last year": "last_year", "year to date": "this_year", "ytd": "this_year",
+}
+
+/* Words a question may contain besides metrics, dimensions, grains and presets; any other word is a condition the
+ * parser cannot express, such as a filter value, so the question is not a pure metric question */
+var metricQuestionStopwords = map[string]bool{
+	"what": true, "whats": true, "s": true, "is": true, "was": true, "are": true, "were": true, "the": true, "a": true, "an": true,
+	"of": true, "our": true, "my": true, "show": true, "me": true, "give": true, "get": true, "list": true, "tell": true,
+	"how": true, "much": true, "many": true, "did": true, "do": true, "does": true, "we": true, "have": true, "has": true,
+	"and": true, "for": true, "in": true, "over": true, "during": true, "please": true,
+}
+
+/* metricSummingAggregations are the aggregations a question may call a "total" */
+var metricSummingAggregations = []string{AggregationSum, AggregationCount, AggregationCountDistinct}
+
+var questionSeparator = regexp.MustCompile(`[^a-z0-9_]+`)
+
+/* questionTokens tracks which words of a question have been understood */
+type questionTokens struct {
+	words    []string
+	consumed []bool
+}
+
+/* match returns the positions where a phrase occurs as whole words */
+func (t *questionTokens) match(phrase string) []int {
+	words := strings.Fields(phrase)
+	if len(words) == 0 {
+		return nil
+	}
+	var positions []int
+	for i := 0; i+len(words) <= len(t.words); i++ {
+		found := true
+		for j, w := range words {
+			if t.words[i+j] != w {
+				found = false
+				break
+			}
+		}
+		if found {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+/* consume marks every occurrence of a phrase as understood and reports whether there was one */
+func (t *questionTokens) consume(phrase string) bool {
+	positions := t.match(phrase)
+	n := len(strings.Fields(phrase))
+	for _, i := range positions {
+		for j := i; j < i+n; j++ {
+			t.consumed[j] = true
+		}
+	}
+	return len(positions) > 0
+}
+
+/* ParseMetricQuestion maps a natural-language question onto approved metrics.
+ * It returns a query only when every word of the question is a metric, a dimension after "by", a time grain,
+ * a time range preset or a stopword; filters, aggregations other than a metric's own and anything else the
+ * parser does not understand make it report the metrics as mentioned without a query, so callers fall back
+ * to SQL generation with the definitions as context. */
+func ParseMetricQuestion(question string, metrics []Metric) (*MetricQuery, bool) {
+	t := &questionTokens{words: strings.Fields(questionSeparator.ReplaceAllString(strings.ToLower(question), " "))}
+	t.consumed = make([]bool, len(t.words))
+
+	q := &MetricQuery{}
+	var matched []*Metric
+	for i := range metrics {
+		metric := &metrics[i]
+		if metric.Definition == nil {
+			continue
+		}
+		for _, phrase := range []string{metric.Name, strings.ReplaceAll(metric.Name, "_", " "), strings.ToLower(metric.DisplayName)} {
+			if phrase != "" && t.consume(phrase) {
+				q.Metrics = append(q.Metrics, metric.Name)
+				matched = append(matched, metric)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return nil, false
+	}
+
+	for phrase, grain := range metricGrainPhrases {
+		if t.consume(phrase) {
+			if q.TimeGrain != "" && q.TimeGrain != grain {
+				return nil, true
+			}
+			q.TimeGrain = grain
+		}
+	}
+	for phrase, preset := range metricPresetPhrases {
+		if t.consume(phrase) {
+			if q.TimeRange != nil && q.TimeRange.Preset != preset {
+				return nil, true
+			}
+			q.TimeRange = &MetricTimeRange{Preset: preset}
+		}
+	}
+
+	// Every "by ..." clause not already read as a grain must name dimensions shared by all matched metrics
+	for _, i := range t.match("by") {
+		if t.consumed[i] {
+			continue
+		}
+		t.consumed[i] = true
+		for next := i + 1; ; {
+			name, n := matchDimension(t.words[next:], matched[0])
+			if name == "" || !metricsShareDimension(matched, name) {
+				return nil, true
+			}
+			for j := next; j < next+n; j++ {
+				t.consumed[j] = true
+			}
+			if !containsName(q.Dimensions, name) {
+				q.Dimensions = append(q.Dimensions, name)
+			}
+			next += n
+			if next >= len(t.words) || t.words[next] != "and" {
+				break
+			}
+			t.consumed[next] = true
+			next++
+		}
+	}
+
+	// "total" is only the metric's own aggregation for metrics that add up
+	summing := true
+	for _, metric := range matched {
+		if !containsName(metricSummingAggregations, metric.Definition.Measure.Aggregation) {
+			summing = false
+		}
+	}
+	for i, word := range t.words {
+		if t.consumed[i] || metricQuestionStopwords[word] || (word == "total" && summing) {
+			continue
+		}
+		return nil, true
+	}
+	return q, true
+}
+
+/* metricsShareDimension reports whether every metric defines the dimension */
+func metricsShareDimension(metrics []*Metric, name string) bool {
+	for _, metric := range metrics {
+		found := false
+		for _, dim := range metric.Definition.Dimensions {
+			if dim.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+/* matchDimension returns the metric dimension the words start with and how many words name it */
+func matchDimension(words []string, metric *Metric) (string, int) {
+	for _, dim := range metric.Definition.Dimensions {
+		for _, phrase := range []string{dim.Name, strings.ReplaceAll(dim.Name, "_", " ")} {
+			parts := strings.Fields(phrase)
+			if len(parts) > len(words) {
+				continue
+			}
+			found := true
+			for i, part := range parts {
+				if words[i] != part {
+					found = false
+					break
+				}
+			}
+			if found {
+				return dim.Name, len(parts)
+			}
+		}
+	}
+	return "", 0
+}
@@ -0,0 +1,204 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+)
+
+/* testMetrics are approved metrics over orders, order lines and sessions */
+func testMetrics() []Metric {
+	return []Metric{
+		{Name: "revenue", Definition: &MetricDefinition{
+			BaseTable:  "sales.orders",
+			PrimaryKey: []string{"id"},
+			Measure:    MetricMeasure{Aggregation: AggregationSum, Expression: "amount"},
+			TimeColumn: "placed_at",
+			TimeGrains: []string{"day", "month"},
+			Filters:    []string{"status <> 'cancelled'"},
+			Dimensions: []MetricDimension{
+				{Name: "region", Join: "customer", Column: "region"},
+				{Name: "product", Join: "lines", Column: "product_id"},
+				{Name: "channel", Column: "channel"},
+			},
+			Joins: []MetricJoin{
+				{Name: "customer", Table: "crm.customers", On: []MetricJoinKey{{From: "customer_id", To: "id"}}, Relationship: RelationshipManyToOne},
+				{Name: "lines", Table: "sales.order_lines", On: []MetricJoinKey{{From: "id", To: "order_id"}}, Relationship: RelationshipOneToMany},
+			},
+		}},
+		{Name: "sessions", Definition: &MetricDefinition{
+			BaseTable:  "web.sessions",
+			Measure:    MetricMeasure{Aggregation: AggregationCount},
+			TimeColumn: "started_at",
+			Dimensions: []MetricDimension{{Name: "channel", Column: "utm_channel"}, {Name: "country", Join: "visitor", Column: "country"}},
+			Joins:      []MetricJoin{{Name: "visitor", Table: "web.visitors", On: []MetricJoinKey{{From: "visitor_id", To: "id"}}, Relationship: RelationshipOneToMany}},
+		}},
+		{Name: "draft_metric"},
+	}
+}
+
+/* TestCompileMetrics checks the SQL generated for metric queries */
+func TestCompileMetrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    MetricQuery
+		contains []string
+		absent   []string
+		tables   []string
+	}{
+		{
+			name:  "grain and joined dimension",
+			query: MetricQuery{Metrics: []string{"revenue"}, Dimensions: []string{"region"}, TimeGrain: "month", Limit: 10},
+			contains: []string{
+				`LEFT JOIN "crm"."customers" AS "customer" ON "customer"."id" = f."customer_id"`,
+				`date_trunc('month', f.__time) AS "month"`,
+				`WHERE (status <> 'cancelled')`,
+				"SUM(f.__value)",
+				"GROUP BY 1, 2",
+				"LIMIT 10",
+			},
+			absent: []string{"order_lines", "DISTINCT"},
+			tables: []string{"crm.customers", "sales.orders"},
+		},
+		{
+			name:     "fan-out join",
+			query:    MetricQuery{Metrics: []string{"revenue"}, Dimensions: []string{"product"}},
+			contains: []string{"SELECT DISTINCT f.\"id\" AS __pk0, f.__value", "SUM(x.__value)"},
+			tables:   []string{"sales.order_lines", "sales.orders"},
+		},
+		{
+			name:  "drill across",
+			query: MetricQuery{Metrics: []string{"revenue", "sessions"}, Dimensions: []string{"channel"}},
+			contains: []string{
+				`COALESCE(m0."channel", m1."channel") AS "channel"`,
+				`FULL OUTER JOIN m1 ON m0."channel" IS NOT DISTINCT FROM m1."channel"`,
+				`f."utm_channel" AS "channel"`,
+				"COUNT(f.__value)",
+			},
+			tables: []string{"sales.orders", "web.sessions"},
+		},
+		{
+			name: "filters and time range",
+			query: MetricQuery{Metrics: []string{"revenue"}, TimeRange: &MetricTimeRange{Preset: "last_30_days"}, Filters: []MetricFilter{
+				{Dimension: "region", Operator: "in", Values: []interface{}{"EMEA", "it's"}},
+				{Dimension: "channel", Operator: "=", Values: []interface{}{nil}},
+			}},
+			contains: []string{
+				`"customer"."region" IN ('EMEA', 'it''s')`,
+				`f."channel" IS NULL`,
+				"f.__time >= CURRENT_DATE - INTERVAL '30 days'",
+			},
+			absent: []string{"GROUP BY", "ORDER BY"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compiled, err := CompileMetrics(testMetrics(), tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := pg_query.Parse(compiled.SQL); err != nil {
+				t.Fatalf("generated SQL does not parse: %v\n%s", err, compiled.SQL)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(compiled.SQL, s) {
+					t.Errorf("expected SQL to contain %q:\n%s", s, compiled.SQL)
+				}
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(compiled.SQL, s) {
+					t.Errorf("expected SQL not to contain %q:\n%s", s, compiled.SQL)
+				}
+			}
+			if tt.tables != nil && strings.Join(compiled.Tables, ",") != strings.Join(tt.tables, ",") {
+				t.Errorf("expected tables %v, got %v", tt.tables, compiled.Tables)
+			}
+		})
+	}
+}
+
+/* TestCompileMetricsRejects checks the metric queries that cannot be compiled */
+func TestCompileMetricsRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		query MetricQuery
+	}{
+		{name: "no metrics", query: MetricQuery{}},
+		{name: "unknown metric", query: MetricQuery{Metrics: []string{"profit"}}},
+		{name: "no definition", query: MetricQuery{Metrics: []string{"draft_metric"}}},
+		{name: "unknown dimension", query: MetricQuery{Metrics: []string{"revenue"}, Dimensions: []string{"country"}}},
+		{name: "unsupported grain", query: MetricQuery{Metrics: []string{"revenue"}, TimeGrain: "hour"}},
+		{name: "grain not allowed by metric", query: MetricQuery{Metrics: []string{"revenue"}, TimeGrain: "year"}},
+		{name: "dimension named like the grain", query: MetricQuery{Metrics: []string{"revenue"}, Dimensions: []string{"day"}, TimeGrain: "day"}},
+		{name: "fan-out without primary key", query: MetricQuery{Metrics: []string{"sessions"}, Dimensions: []string{"country"}}},
+		{name: "null comparison", query: MetricQuery{Metrics: []string{"revenue"}, Filters: []MetricFilter{{Dimension: "channel", Operator: ">", Values: []interface{}{nil}}}}},
+		{name: "unsupported operator", query: MetricQuery{Metrics: []string{"revenue"}, Filters: []MetricFilter{{Dimension: "channel", Operator: "like", Values: []interface{}{"a%"}}}}},
+		{name: "unknown preset", query: MetricQuery{Metrics: []string{"revenue"}, TimeRange: &MetricTimeRange{Preset: "last_decade"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compiled, err := CompileMetrics(testMetrics(), tt.query)
+			if AsMetricCompileError(err) == nil {
+				t.Fatalf("expected a compile error, got %v", compiled)
+			}
+		})
+	}
+}
+
+/* TestParseMetricQuestion checks that only questions fully understood in terms of metrics are compiled */
+func TestParseMetricQuestion(t *testing.T) {
+	tests := []struct {
+		question   string
+		mentioned  bool
+		metrics    []string
+		dimensions []string
+		grain      string
+		preset     string
+	}{
+		{question: "What was revenue by region last quarter?", mentioned: true, metrics: []string{"revenue"}, dimensions: []string{"region"}, preset: "last_quarter"},
+		{question: "monthly revenue by region and channel", mentioned: true, metrics: []string{"revenue"}, dimensions: []string{"region", "channel"}, grain: "month"},
+		{question: "Show me total revenue", mentioned: true, metrics: []string{"revenue"}},
+		{question: "revenue and sessions by channel", mentioned: true, metrics: []string{"revenue", "sessions"}, dimensions: []string{"channel"}},
+		// Filters, other aggregations and unknown dimensions fall back to SQL generation
+		{question: "revenue in France last quarter", mentioned: true},
+		{question: "revenue for enterprise customers", mentioned: true},
+		{question: "average revenue by region", mentioned: true},
+		{question: "revenue by country", mentioned: true},
+		{question: "top 5 channels by revenue", mentioned: true},
+		{question: "total sessions by channel", mentioned: true},
+		{question: "how many support tickets were opened"},
+	}
+	metrics := testMetrics()
+	metrics[1].Definition.Measure = MetricMeasure{Aggregation: AggregationAvg, Expression: "duration_seconds"}
+	for _, tt := range tests {
+		t.Run(tt.question, func(t *testing.T) {
+			q, mentioned := ParseMetricQuestion(tt.question, metrics)
+			if mentioned != tt.mentioned {
+				t.Fatalf("expected mentioned %v, got %v", tt.mentioned, mentioned)
+			}
+			if tt.metrics == nil {
+				if q != nil {
+					t.Fatalf("expected no metric query, got %+v", q)
+				}
+				return
+			}
+			if q == nil {
+				t.Fatal("expected a metric query")
+			}
+			if strings.Join(q.Metrics, ",") != strings.Join(tt.metrics, ",") || strings.Join(q.Dimensions, ",") != strings.Join(tt.dimensions, ",") {
+				t.Errorf("expected metrics %v by %v, got %v by %v", tt.metrics, tt.dimensions, q.Metrics, q.Dimensions)
+			}
+			if q.TimeGrain != tt.grain {
+				t.Errorf("expected grain %q, got %q", tt.grain, q.TimeGrain)
+			}
+			preset := ""
+			if q.TimeRange != nil {
+				preset = q.TimeRange.Preset
+			}
+			if preset != tt.preset {
+				t.Errorf("expected time range %q, got %q", tt.preset, preset)
+			}
+		})
+	}
+}
@@ -35,6 +35,7 @@ type Metric struct {
 	Version         string                 `json:"version"`
 	Status          string                 `json:"status"`
 	ApprovalWorkflow map[string]interface{} `json:"approval_workflow,omitempty"`
+	Definition      *MetricDefinition      `json:"semantic_definition,omitempty"`
 	CreatedAt       time.Time              `json:"created_at"`
 	UpdatedAt       time.Time              `json:"updated_at"`
 	ApprovedAt      *time.Time             `json:"approved_at,omitempty"`
@@ -54,20 +55,28 @@ func (s *MetricsService) CreateMetric(ctx context.Context, metric Metric) (*Metr
 		metric.Status = "draft"
 	}
 	
+	var definitionJSON []byte
+	if metric.Definition != nil {
+		if err := ValidateMetricDefinition(metric.Definition); err != nil {
+			return nil, err
+		}
+		definitionJSON, _ = json.Marshal(metric.Definition)
+	}
+	
 	tagsJSON, _ := json.Marshal(metric.Tags)
 	workflowJSON, _ := json.Marshal(metric.ApprovalWorkflow)
 	
 	query := `
 		INSERT INTO neuronip.metric_catalog 
 		(id, name, display_name, description, sql_expression, metric_type, unit, category, 
-		 tags, owner_id, version, status, approval_workflow, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		 tags, owner_id, version, status, approval_workflow, semantic_definition, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 		RETURNING id, created_at, updated_at, approved_at, approved_by`
 	
 	err := s.pool.QueryRow(ctx, query,
 		metric.ID, metric.Name, metric.DisplayName, metric.Description, metric.SQLExpression,
 		metric.MetricType, metric.Unit, metric.Category, tagsJSON, metric.OwnerID,
-		metric.Version, metric.Status, workflowJSON, metric.CreatedAt, metric.UpdatedAt,
+		metric.Version, metric.Status, workflowJSON, definitionJSON, metric.CreatedAt, metric.UpdatedAt,
 	).Scan(&metric.ID, &metric.CreatedAt, &metric.UpdatedAt, &metric.ApprovedAt, &metric.ApprovedBy)
 	
 	if err != nil {
@@ -81,17 +90,17 @@ func (s *MetricsService) CreateMetric(ctx context.Context, metric Metric) (*Metr
 func (s *MetricsService) GetMetric(ctx context.Context, id uuid.UUID) (*Metric, error) {
 	query := `
 		SELECT id, name, display_name, description, sql_expression, metric_type, unit, category,
-		       tags, owner_id, version, status, approval_workflow, created_at, updated_at, approved_at, approved_by
+		       tags, owner_id, version, status, approval_workflow, semantic_definition, created_at, updated_at, approved_at, approved_by
 		FROM neuronip.metric_catalog
 		WHERE id = $1`
 	
 	var metric Metric
-	var tagsJSON, workflowJSON json.RawMessage
+	var tagsJSON, workflowJSON, definitionJSON json.RawMessage
 	
 	err := s.pool.QueryRow(ctx, query, id).Scan(
 		&metric.ID, &metric.Name, &metric.DisplayName, &metric.Description, &metric.SQLExpression,
 		&metric.MetricType, &metric.Unit, &metric.Category, &tagsJSON, &metric.OwnerID,
-		&metric.Version, &metric.Status, &workflowJSON, &metric.CreatedAt, &metric.UpdatedAt,
+		&metric.Version, &metric.Status, &workflowJSON, &definitionJSON, &metric.CreatedAt, &metric.UpdatedAt,
 		&metric.ApprovedAt, &metric.ApprovedBy,
 	)
 	if err != nil {
@@ -104,6 +113,9 @@ func (s *MetricsService) GetMetric(ctx context.Context, id uuid.UUID) (*Metric,
 	if workflowJSON != nil {
 		json.Unmarshal(workflowJSON, &metric.ApprovalWorkflow)
 	}
+	if definitionJSON != nil {
+		json.Unmarshal(definitionJSON, &metric.Definition)
+	}
 	
 	return &metric, nil
 }
@@ -111,7 +123,7 @@ func (s *MetricsService) GetMetric(ctx context.Context, id uuid.UUID) (*Metric,
 /* ListMetrics lists all metrics */
 func (s *MetricsService) ListMetrics(ctx context.Context, status *string, category *string, limit int) ([]Metric, error) {
 	query := `SELECT id, name, display_name, description, sql_expression, metric_type, unit, category,
-		       tags, owner_id, version, status, approval_workflow, created_at, updated_at, approved_at, approved_by
+		       tags, owner_id, version, status, approval_workflow, semantic_definition, created_at, updated_at, approved_at, approved_by
 		FROM neuronip.metric_catalog WHERE 1=1`
 	
 	args := []interface{}{}
@@ -145,12 +157,12 @@ func (s *MetricsService) ListMetrics(ctx context.Context, status *string, catego
 	metrics := make([]Metric, 0)
 	for rows.Next() {
 		var metric Metric
-		var tagsJSON, workflowJSON json.RawMessage
+		var tagsJSON, workflowJSON, definitionJSON json.RawMessage
 		
 		err := rows.Scan(
 			&metric.ID, &metric.Name, &metric.DisplayName, &metric.Description, &metric.SQLExpression,
 			&metric.MetricType, &metric.Unit, &metric.Category, &tagsJSON, &metric.OwnerID,
-			&metric.Version, &metric.Status, &workflowJSON, &metric.CreatedAt, &metric.UpdatedAt,
+			&metric.Version, &metric.Status, &workflowJSON, &definitionJSON, &metric.CreatedAt, &metric.UpdatedAt,
 			&metric.ApprovedAt, &metric.ApprovedBy,
 		)
 		if err != nil {
@@ -163,6 +175,9 @@ func (s *MetricsService) ListMetrics(ctx context.Context, status *string, catego
 		if workflowJSON != nil {
 			json.Unmarshal(workflowJSON, &metric.ApprovalWorkflow)
 		}
+		if definitionJSON != nil {
+			json.Unmarshal(definitionJSON, &metric.Definition)
+		}
 		
 		metrics = append(metrics, metric)
 	}
@@ -240,3 +255,57 @@ func (s *MetricsService) ApproveMetric(ctx context.Context, metricID uuid.UUID,
 	
 	return nil
 }
+
+/* SetMetricDefinition sets the semantic definition used to compile a metric to SQL.
+ * Changing the definition of an approved metric returns it to draft so the new definition is reviewed. */
+func (s *MetricsService) SetMetricDefinition(ctx context.Context, metricID uuid.UUID, def *MetricDefinition) (*Metric, error) {
+	if err := ValidateMetricDefinition(def); err != nil {
+		return nil, err
+	}
+	definitionJSON, _ := json.Marshal(def)
+	
+	query := `
+		UPDATE neuronip.metric_catalog
+		SET semantic_definition = $1,
+		    status = CASE WHEN status = 'approved' THEN 'draft' ELSE status END,
+		    approved_at = CASE WHEN status = 'approved' THEN NULL ELSE approved_at END,
+		    approved_by = CASE WHEN status = 'approved' THEN NULL ELSE approved_by END,
+		    updated_at = NOW()
+		WHERE id = $2`
+	
+	result, err := s.pool.Exec(ctx, query, definitionJSON, metricID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set metric definition: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, fmt.Errorf("metric not found")
+	}
+	
+	return s.GetMetric(ctx, metricID)
+}
+
+/* ListApprovedDefinitions lists approved metrics that have a semantic definition */
+func (s *MetricsService) ListApprovedDefinitions(ctx context.Context) ([]Metric, error) {
+	status := "approved"
+	metrics, err := s.ListMetrics(ctx, &status, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	
+	defined := make([]Metric, 0, len(metrics))
+	for _, metric := range metrics {
+		if metric.Definition != nil {
+			defined = append(defined, metric)
+		}
+	}
+	return defined, nil
+}
+
+/* CompileMetricQuery compiles a metric query against the approved metric definitions */
+func (s *MetricsService) CompileMetricQuery(ctx context.Context, q MetricQuery) (*CompiledMetricQuery, error) {
+	metrics, err := s.ListApprovedDefinitions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metric definitions: %w", err)
+	}
+	return CompileMetrics(metrics, q)
+}
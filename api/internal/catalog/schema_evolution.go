@@ -4,15 +4,21 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/cache"
+	"github.com/neurondb/NeuronIP/api/internal/connectors"
+	"github.com/neurondb/NeuronIP/api/internal/logging"
 )
 
 /* SchemaEvolutionService provides schema evolution tracking functionality */
 type SchemaEvolutionService struct {
-	pool *pgxpool.Pool
+	pool          *pgxpool.Pool
+	tableListener cache.TableChangeListener
 }
 
 /* NewSchemaEvolutionService creates a new schema evolution service */
@@ -20,6 +26,11 @@ func NewSchemaEvolutionService(pool *pgxpool.Pool) *SchemaEvolutionService {
 	return &SchemaEvolutionService{pool: pool}
 }
 
+/* NewSchemaEvolutionServiceWithCacheInvalidation creates a schema evolution service that notifies listener of detected changes */
+func NewSchemaEvolutionServiceWithCacheInvalidation(pool *pgxpool.Pool, listener cache.TableChangeListener) *SchemaEvolutionService {
+	return &SchemaEvolutionService{pool: pool, tableListener: listener}
+}
+
 /* SchemaVersion represents a version of a schema */
 type SchemaVersion struct {
 	ID            uuid.UUID              `json:"id"`
@@ -124,9 +135,68 @@ func (s *SchemaEvolutionService) TrackSchemaEvolution(ctx context.Context,
 		)
 	}
 
+	// Cached results may no longer match the table's shape
+	if len(changes) > 0 && s.tableListener != nil {
+		table := tableName
+		if schemaName != "" {
+			table = schemaName + "." + tableName
+		}
+		if err := s.tableListener.TablesChanged(ctx, cache.TableChange{
+			Tables: []string{table},
+			Reason: cache.TableChangeSchema,
+			Source: connectorID.String(),
+		}); err != nil {
+			logging.Error("Failed to invalidate cached results after a schema change", "table", table, "error", err)
+		}
+	}
+
 	return version, nil
 }
 
+/* TrackSyncedSchema records a new schema version for each table of a connector sync whose columns changed
+ * since its latest version, or that has no version yet. Changed tables invalidate cached results. */
+func (s *SchemaEvolutionService) TrackSyncedSchema(ctx context.Context, connectorID uuid.UUID, schema *connectors.Schema) error {
+	var failed []string
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		current := discoveredSchema(table)
+
+		var prevSchemaJSON []byte
+		err := s.pool.QueryRow(ctx, `
+			SELECT schema_definition
+			FROM neuronip.schema_versions
+			WHERE connector_id = $1 AND schema_name = $2 AND table_name = $3
+			ORDER BY version DESC
+			LIMIT 1`,
+			connectorID, table.SchemaName, table.TableName,
+		).Scan(&prevSchemaJSON)
+		if err != nil && err != pgx.ErrNoRows {
+			failed = append(failed, qualifiedName(table.SchemaName, table.TableName))
+			continue
+		}
+		if err == nil && !s.schemaChanged(prevSchemaJSON, current) {
+			continue
+		}
+
+		if _, err := s.TrackSchemaEvolution(ctx, connectorID, table.SchemaName, table.TableName, current); err != nil {
+			failed = append(failed, qualifiedName(table.SchemaName, table.TableName))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to track schema of %d tables: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+/* schemaChanged reports whether a table's current schema differs from its stored schema definition */
+func (s *SchemaEvolutionService) schemaChanged(prevSchemaJSON []byte, current map[string]interface{}) bool {
+	var prevSchema map[string]interface{}
+	if err := json.Unmarshal(prevSchemaJSON, &prevSchema); err != nil {
+		return true
+	}
+	return len(s.detectChanges(prevSchema, current)) > 0
+}
+
 /* detectChanges detects changes between schema versions */
 func (s *SchemaEvolutionService) detectChanges(prevSchema, currentSchema map[string]interface{}) []SchemaChange {
 	var changes []SchemaChange
@@ -0,0 +1,77 @@
+package catalog
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/neurondb/NeuronIP/api/internal/connectors"
+)
+
+/* TestSyncedSchemaChanged checks that a sync only records a schema version, and invalidates cached results,
+ * when the discovered columns differ from the stored definition */
+func TestSyncedSchemaChanged(t *testing.T) {
+	s := NewSchemaEvolutionService(nil)
+	stored := &connectors.Table{TableName: "orders", Columns: []connectors.Column{
+		{ColumnName: "id", ColumnType: "int8"},
+		{ColumnName: "status", ColumnType: "character varying(32)", IsNullable: true},
+	}}
+	storedJSON, err := json.Marshal(discoveredSchema(stored))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		columns []connectors.Column
+		changed bool
+	}{
+		{
+			name: "same columns",
+			columns: []connectors.Column{
+				{ColumnName: "status", ColumnType: "character varying(32)", IsNullable: true},
+				{ColumnName: "id", ColumnType: "int8"},
+			},
+		},
+		{
+			name: "type alias and length only",
+			columns: []connectors.Column{
+				{ColumnName: "id", ColumnType: "bigint"},
+				{ColumnName: "status", ColumnType: "varchar(64)", IsNullable: true},
+			},
+		},
+		{
+			name: "column added",
+			columns: []connectors.Column{
+				{ColumnName: "id", ColumnType: "int8"},
+				{ColumnName: "status", ColumnType: "varchar", IsNullable: true},
+				{ColumnName: "amount", ColumnType: "numeric", IsNullable: true},
+			},
+			changed: true,
+		},
+		{
+			name:    "column removed",
+			columns: []connectors.Column{{ColumnName: "id", ColumnType: "int8"}},
+			changed: true,
+		},
+		{
+			name: "type changed",
+			columns: []connectors.Column{
+				{ColumnName: "id", ColumnType: "text"},
+				{ColumnName: "status", ColumnType: "varchar", IsNullable: true},
+			},
+			changed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := discoveredSchema(&connectors.Table{TableName: "orders", Columns: tt.columns})
+			if got := s.schemaChanged(storedJSON, current); got != tt.changed {
+				t.Fatalf("expected changed=%v, got %v", tt.changed, got)
+			}
+		})
+	}
+
+	if !s.schemaChanged([]byte("not json"), discoveredSchema(stored)) {
+		t.Error("expected an unreadable stored definition to count as changed")
+	}
+}
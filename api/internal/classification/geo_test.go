@@ -0,0 +1,32 @@
+package classification
+
+import (
+	"testing"
+)
+
+/* TestGeographicRole checks which column names are taken for geographic columns */
+func TestGeographicRole(t *testing.T) {
+	tests := []struct {
+		column string
+		want   string
+	}{
+		{column: "Country", want: GeoCountry},
+		{column: "billing_country", want: GeoCountry},
+		{column: "ship_postal_code", want: GeoPostalCode},
+		{column: "zip", want: GeoPostalCode},
+		{column: "home_zip", want: GeoPostalCode},
+		{column: "pickup_lat", want: GeoLatitude},
+		{column: "pickup_lon", want: GeoLongitude},
+		{column: "long", want: GeoLongitude},
+		{column: "store_long", want: GeoLongitude},
+		{column: "lng", want: GeoLongitude},
+		{column: "status_lng"},
+		{column: "statement"},
+		{column: "amount"},
+	}
+	for _, tt := range tests {
+		if got := GeographicRole(tt.column); got != tt.want {
+			t.Errorf("GeographicRole(%q) = %q, want %q", tt.column, got, tt.want)
+		}
+	}
+}
@@ -236,6 +236,16 @@ func (s *Service) detectPatterns(columnName string, sampleValues []string) *Clas
 		}
 	}
 
+	// Geographic columns stay public but record their role for charting
+	if role := GeographicRole(columnLower); role != "" {
+		return &ClassificationMatch{
+			ClassificationType: "public",
+			Confidence:         0.6,
+			DetectionMethod:    "pattern",
+			DetectedPatterns:   []string{"geo:" + role},
+		}
+	}
+
 	return &ClassificationMatch{
 		ClassificationType: "public",
 		Confidence:         0.5,
@@ -244,6 +254,44 @@ func (s *Service) detectPatterns(columnName string, sampleValues []string) *Clas
 	}
 }
 
+/* Geographic roles a column name can suggest */
+const (
+	GeoCountry    = "country"
+	GeoState      = "state"
+	GeoCity       = "city"
+	GeoPostalCode = "postal_code"
+	GeoLatitude   = "latitude"
+	GeoLongitude  = "longitude"
+)
+
+/* geoNames maps column names and name suffixes to geographic roles */
+var geoNames = map[string]string{
+	"country": GeoCountry, "country_name": GeoCountry, "nation": GeoCountry,
+	"state": GeoState, "state_name": GeoState, "province": GeoState,
+	"city": GeoCity, "town": GeoCity,
+	"zip": GeoPostalCode, "zipcode": GeoPostalCode, "zip_code": GeoPostalCode, "postal_code": GeoPostalCode, "postcode": GeoPostalCode,
+	"lat": GeoLatitude, "latitude": GeoLatitude,
+	"lon": GeoLongitude, "lng": GeoLongitude, "long": GeoLongitude, "longitude": GeoLongitude,
+}
+
+/* geoShortSuffixes are the short names that still mark a geographic column as a suffix, as in pickup_lat */
+var geoShortSuffixes = map[string]bool{"lat": true, "lon": true, "zip": true}
+
+/* GeographicRole returns the geographic role a column name suggests: country, state, city, postal_code, latitude or longitude.
+ * Short names other than lat, lon and zip only match whole, so a name like status_lng is not taken for a place. */
+func GeographicRole(column string) string {
+	name := strings.ToLower(column)
+	if role, ok := geoNames[name]; ok {
+		return role
+	}
+	for suffix, role := range geoNames {
+		if (len(suffix) > 3 || geoShortSuffixes[suffix]) && strings.HasSuffix(name, "_"+suffix) {
+			return role
+		}
+	}
+	return ""
+}
+
 /* matchPattern matches pattern against values */
 func (s *Service) matchPattern(pattern string, values []string) bool {
 	re, err := regexp.Compile(pattern)
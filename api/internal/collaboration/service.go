@@ -4,16 +4,44 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/warehouse"
 )
 
+/* ErrNotFound is returned when an answer card or dashboard does not exist */
+var ErrNotFound = errors.New("not found")
+
+/* ErrNotOwner is returned when a user changes an answer card or dashboard they did not create */
+var ErrNotOwner = errors.New("only the creator can change this item")
+
+/* IsNotFound reports whether err means the answer card or dashboard does not exist */
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+/* IsNotOwner reports whether err means the user does not own the answer card or dashboard */
+func IsNotOwner(err error) bool {
+	return errors.Is(err, ErrNotOwner)
+}
+
+/* ErrNoTemplate is returned when refreshing an answer card that is not backed by a query template */
+var ErrNoTemplate = errors.New("answer card is not backed by a query template")
+
+/* IsNoTemplate reports whether err means the answer card has no query template to run */
+func IsNoTemplate(err error) bool {
+	return errors.Is(err, ErrNoTemplate)
+}
+
 /* CollaborationService provides collaboration features */
 type CollaborationService struct {
-	pool *pgxpool.Pool
+	pool      *pgxpool.Pool
+	templates *warehouse.QueryTemplateService
 }
 
 /* NewCollaborationService creates a new collaboration service */
@@ -21,6 +49,11 @@ func NewCollaborationService(pool *pgxpool.Pool) *CollaborationService {
 	return &CollaborationService{pool: pool}
 }
 
+/* NewCollaborationServiceWithTemplates creates a collaboration service whose answer cards can be backed by query templates */
+func NewCollaborationServiceWithTemplates(pool *pgxpool.Pool, templates *warehouse.QueryTemplateService) *CollaborationService {
+	return &CollaborationService{pool: pool, templates: templates}
+}
+
 /* SharedDashboard represents a shared dashboard */
 type SharedDashboard struct {
 	ID            uuid.UUID              `json:"id"`
@@ -127,28 +160,42 @@ type AnswerCard struct {
 	IsPublic    bool                   `json:"is_public"`
 	SharedWith  []string               `json:"shared_with,omitempty"`
 	Tags        []string               `json:"tags,omitempty"`
+	TemplateID     *uuid.UUID             `json:"template_id,omitempty"`     // Query template that refreshes the card
+	TemplateParams map[string]interface{} `json:"template_params,omitempty"` // Parameter values for the template
+	ChartSpec      map[string]interface{} `json:"chart_spec,omitempty"`      // Vega-Lite spec chosen for the card
 	CreatedAt   time.Time              `json:"created_at"`
 	UpdatedAt   time.Time              `json:"updated_at"`
 }
 
-/* CreateAnswerCard creates a shared answer card */
-func (s *CollaborationService) CreateAnswerCard(ctx context.Context, title string, queryText string, queryResult map[string]interface{}, explanation *string, createdBy string, workspaceID *uuid.UUID, isPublic bool, sharedWith []string, tags []string) (*AnswerCard, error) {
+/* CreateAnswerCard creates a shared answer card. When templateID is set the card is linked to the query template and
+ * parameter values, and its result comes from running the template, which createdBy must be able to see. */
+func (s *CollaborationService) CreateAnswerCard(ctx context.Context, title string, queryText string, queryResult map[string]interface{}, explanation *string, createdBy string, workspaceID *uuid.UUID, isPublic bool, sharedWith []string, tags []string, templateID *uuid.UUID, templateParams map[string]interface{}) (*AnswerCard, error) {
+	if templateID != nil {
+		result, err := s.runCardTemplate(ctx, *templateID, createdBy, templateParams)
+		if err != nil {
+			return nil, err
+		}
+		queryResult = result
+	}
+
 	id := uuid.New()
 	resultJSON, _ := json.Marshal(queryResult)
 	sharedWithJSON, _ := json.Marshal(sharedWith)
 	tagsJSON, _ := json.Marshal(tags)
+	templateParamsJSON, _ := json.Marshal(templateParams)
 
+	// The template link is written with the card so a card is never stored without the template it was created from
 	query := `
 		INSERT INTO neuronip.answer_cards 
-		(id, title, query_text, query_result, explanation, created_by, workspace_id, is_public, shared_with, tags, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+		(id, title, query_text, query_result, explanation, created_by, workspace_id, is_public, shared_with, tags, template_id, template_params, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())
 		RETURNING id, title, query_text, query_result, explanation, created_by, workspace_id, is_public, shared_with, tags, created_at, updated_at`
 
 	var card AnswerCard
 	var expl sql.NullString
 	var sharedWithRaw, tagsRaw json.RawMessage
 
-	err := s.pool.QueryRow(ctx, query, id, title, queryText, resultJSON, explanation, createdBy, workspaceID, isPublic, sharedWithJSON, tagsJSON).Scan(
+	err := s.pool.QueryRow(ctx, query, id, title, queryText, resultJSON, explanation, createdBy, workspaceID, isPublic, sharedWithJSON, tagsJSON, templateID, templateParamsJSON).Scan(
 		&card.ID, &card.Title, &card.QueryText, &card.QueryResult,
 		&expl, &card.CreatedBy, &card.WorkspaceID, &card.IsPublic,
 		&sharedWithRaw, &tagsRaw, &card.CreatedAt, &card.UpdatedAt,
@@ -166,10 +213,156 @@ func (s *CollaborationService) CreateAnswerCard(ctx context.Context, title strin
 	if tagsRaw != nil {
 		json.Unmarshal(tagsRaw, &card.Tags)
 	}
+	if templateID != nil {
+		card.TemplateID = templateID
+		card.TemplateParams = templateParams
+	}
+
+	return &card, nil
+}
+
+/* GetAnswerCard retrieves an answer card visible to userID: created by them, public or shared with them */
+func (s *CollaborationService) GetAnswerCard(ctx context.Context, cardID uuid.UUID, userID string) (*AnswerCard, error) {
+	query := `
+		SELECT id, title, query_text, query_result, explanation, created_by, workspace_id, is_public, shared_with, tags,
+		       template_id, template_params, chart_spec, created_at, updated_at
+		FROM neuronip.answer_cards
+		WHERE id = $1 AND (created_by = $2 OR is_public = true OR $2 = ANY(shared_with))`
+
+	var card AnswerCard
+	var expl sql.NullString
+	var sharedWithRaw, tagsRaw, templateParamsRaw, chartSpecRaw json.RawMessage
+
+	err := s.pool.QueryRow(ctx, query, cardID, userID).Scan(
+		&card.ID, &card.Title, &card.QueryText, &card.QueryResult,
+		&expl, &card.CreatedBy, &card.WorkspaceID, &card.IsPublic,
+		&sharedWithRaw, &tagsRaw, &card.TemplateID, &templateParamsRaw, &chartSpecRaw,
+		&card.CreatedAt, &card.UpdatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get answer card: %w", err)
+	}
+
+	if expl.Valid {
+		card.Explanation = &expl.String
+	}
+	if sharedWithRaw != nil {
+		json.Unmarshal(sharedWithRaw, &card.SharedWith)
+	}
+	if tagsRaw != nil {
+		json.Unmarshal(tagsRaw, &card.Tags)
+	}
+	if card.TemplateID != nil && templateParamsRaw != nil {
+		json.Unmarshal(templateParamsRaw, &card.TemplateParams)
+	}
+	if chartSpecRaw != nil {
+		json.Unmarshal(chartSpecRaw, &card.ChartSpec)
+	}
 
 	return &card, nil
 }
 
+/* RefreshAnswerCard re-runs the query template backing an answer card with its stored parameter values and saves the new result.
+ * The template runs as the card's creator, as template-backed saved searches run as their owner. */
+func (s *CollaborationService) RefreshAnswerCard(ctx context.Context, cardID uuid.UUID, userID string) (*AnswerCard, error) {
+	card, err := s.GetAnswerCard(ctx, cardID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if card.TemplateID == nil {
+		return nil, ErrNoTemplate
+	}
+
+	result, err := s.runCardTemplate(ctx, *card.TemplateID, card.CreatedBy, card.TemplateParams)
+	if err != nil {
+		return nil, err
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	query := `
+		UPDATE neuronip.answer_cards
+		SET query_result = $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING updated_at`
+	if err := s.pool.QueryRow(ctx, query, resultJSON, cardID).Scan(&card.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to save answer card result: %w", err)
+	}
+	card.QueryResult = result
+	return card, nil
+}
+
+/* runCardTemplate runs a card's query template as userID and returns the result stored on the card */
+func (s *CollaborationService) runCardTemplate(ctx context.Context, templateID uuid.UUID, userID string, params map[string]interface{}) (map[string]interface{}, error) {
+	if s.templates == nil {
+		return nil, fmt.Errorf("answer card references a query template but templates are not configured")
+	}
+	result, err := s.templates.ExecuteTemplate(ctx, templateID, &userID, params)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"template_id": result.TemplateID,
+		"sql":         result.SQL,
+		"parameters":  result.Parameters,
+		"columns":     result.Columns,
+		"rows":        result.Rows,
+		"row_count":   result.RowCount,
+	}, nil
+}
+
+/* SetAnswerCardChart saves the Vega-Lite spec an answer card is displayed with; only the card's creator may change it */
+func (s *CollaborationService) SetAnswerCardChart(ctx context.Context, cardID uuid.UUID, userID string, spec map[string]interface{}) error {
+	specJSON, _ := json.Marshal(spec)
+	query := `
+		UPDATE neuronip.answer_cards
+		SET chart_spec = $1, updated_at = NOW()
+		WHERE id = $2 AND created_by = $3`
+	tag, err := s.pool.Exec(ctx, query, specJSON, cardID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to save answer card chart: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return s.unchangedError(ctx, "answer_cards", cardID)
+	}
+	return nil
+}
+
+/* SetDashboardChart saves a Vega-Lite spec under a key in a dashboard's charts; only the dashboard's creator may change it */
+func (s *CollaborationService) SetDashboardChart(ctx context.Context, dashboardID uuid.UUID, userID string, chartKey string, spec map[string]interface{}) error {
+	specJSON, _ := json.Marshal(spec)
+	query := `
+		UPDATE neuronip.shared_dashboards
+		SET dashboard_config = jsonb_set(
+			COALESCE(dashboard_config, '{}'::jsonb), '{charts}',
+			COALESCE(dashboard_config->'charts', '{}'::jsonb) || jsonb_build_object($1::text, $2::jsonb)),
+			updated_at = NOW()
+		WHERE id = $3 AND created_by = $4`
+	tag, err := s.pool.Exec(ctx, query, chartKey, specJSON, dashboardID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to save dashboard chart: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return s.unchangedError(ctx, "shared_dashboards", dashboardID)
+	}
+	return nil
+}
+
+/* unchangedError explains an owner-scoped update that changed no row: the item is missing or owned by someone else */
+func (s *CollaborationService) unchangedError(ctx context.Context, table string, id uuid.UUID) error {
+	var exists bool
+	query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM neuronip.%s WHERE id = $1)`, table)
+	if err := s.pool.QueryRow(ctx, query, id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up %s: %w", table, err)
+	}
+	if exists {
+		return ErrNotOwner
+	}
+	return ErrNotFound
+}
+
 /* SavedQuestion represents a saved question */
 type SavedQuestion struct {
 	ID          uuid.UUID  `json:"id"`
@@ -19,6 +19,12 @@ type Config struct {
 	NeuronMCP     NeuronMCPConfig
 	Observability ObservabilityConfig
 	RateLimit     RateLimitConfig
+	SemanticCache SemanticCacheConfig
+	Groundedness  GroundednessConfig
+	WarehouseResults WarehouseResultsConfig
+	WarehouseAdmission WarehouseAdmissionConfig
+	WarehouseCorrection WarehouseCorrectionConfig
+	Federation    FederationConfig
 }
 
 /* DatabaseConfig holds database configuration */
@@ -136,6 +142,55 @@ type RateLimitConfig struct {
 	Window       time.Duration
 }
 
+/* SemanticCacheConfig holds semantic answer cache configuration */
+type SemanticCacheConfig struct {
+	Enabled             bool
+	SimilarityThreshold float64       // Minimum cosine similarity for a cache hit
+	TTL                 time.Duration
+	ScopeByUser         bool          // Key entries per user (default); false shares answers per tenant and role set
+}
+
+/* GroundednessConfig holds claim-level answer verification configuration */
+type GroundednessConfig struct {
+	Enabled   bool
+	Threshold float64 // Minimum share of supported claims before Action applies
+	Action    string  // "none", "annotate" or "block" for answers below Threshold
+}
+
+/* WarehouseResultsConfig holds default limits for paginated and streamed warehouse results */
+type WarehouseResultsConfig struct {
+	DefaultPageSize   int
+	MaxPageSize       int
+	MaxTotalRows      int           // Row cap for a cursor or stream
+	MaxOpenCursors    int           // Open cursors per user
+	CursorIdleTimeout time.Duration // Cursors unused for this long are closed
+	StatementTimeout  time.Duration
+}
+
+/* WarehouseAdmissionConfig holds EXPLAIN-based admission control settings for warehouse queries */
+type WarehouseAdmissionConfig struct {
+	MaxConcurrent    int           // Queries executing at once across all tenants and roles
+	MaxQueueWait     time.Duration // Queries waiting longer for a slot are rejected
+	ExplainTimeout   time.Duration
+	DefaultCostLimit float64 // Planner cost above which queries run asynchronously
+	AsyncExpiryHours int
+}
+
+/* WarehouseCorrectionConfig holds settings for regenerating NL-to-SQL queries that fail */
+type WarehouseCorrectionConfig struct {
+	MaxAttempts       int  // Generated queries per question, including the first
+	RetryEmptyResults bool // Regenerate queries that return no rows
+}
+
+/* FederationConfig holds limits for queries that join tables across data source connectors */
+type FederationConfig struct {
+	MemoryLimitMB   int    // Rows held in memory by joins and aggregations before spilling to disk
+	SpillDir        string // Empty uses the system temp directory
+	SpillPartitions int
+	MaxResultRows   int
+	QueryTimeout    time.Duration
+}
+
 /* Load loads configuration from environment variables */
 func Load() *Config {
 	return &Config{
@@ -236,6 +291,43 @@ func Load() *Config {
 			MaxConcurrentTools:  getEnvInt("NEURONMCP_MAX_CONCURRENT_TOOLS", 10),
 			CacheResults:        getEnv("NEURONMCP_CACHE_RESULTS", "true") == "true",
 		},
+		SemanticCache: SemanticCacheConfig{
+			Enabled:             getEnv("SEMANTIC_CACHE_ENABLED", "true") == "true",
+			SimilarityThreshold: getEnvFloat("SEMANTIC_CACHE_SIMILARITY_THRESHOLD", 0.95),
+			TTL:                 getEnvDuration("SEMANTIC_CACHE_TTL", 1*time.Hour),
+			ScopeByUser:         getEnv("SEMANTIC_CACHE_SCOPE_BY_USER", "true") == "true",
+		},
+		Groundedness: GroundednessConfig{
+			Enabled:   getEnv("GROUNDEDNESS_ENABLED", "true") == "true",
+			Threshold: getEnvFloat("GROUNDEDNESS_THRESHOLD", 0.6),
+			Action:    getEnv("GROUNDEDNESS_ACTION", "none"),
+		},
+		WarehouseResults: WarehouseResultsConfig{
+			DefaultPageSize:   getEnvInt("WAREHOUSE_DEFAULT_PAGE_SIZE", 1000),
+			MaxPageSize:       getEnvInt("WAREHOUSE_MAX_PAGE_SIZE", 10000),
+			MaxTotalRows:      getEnvInt("WAREHOUSE_MAX_RESULT_ROWS", 1000000),
+			MaxOpenCursors:    getEnvInt("WAREHOUSE_MAX_OPEN_CURSORS", 4),
+			CursorIdleTimeout: getEnvDuration("WAREHOUSE_CURSOR_IDLE_TIMEOUT", 5*time.Minute),
+			StatementTimeout:  getEnvDuration("WAREHOUSE_STATEMENT_TIMEOUT", 5*time.Minute),
+		},
+		WarehouseAdmission: WarehouseAdmissionConfig{
+			MaxConcurrent:    getEnvInt("WAREHOUSE_MAX_CONCURRENT_QUERIES", 8),
+			MaxQueueWait:     getEnvDuration("WAREHOUSE_ADMISSION_MAX_WAIT", 30*time.Second),
+			ExplainTimeout:   getEnvDuration("WAREHOUSE_EXPLAIN_TIMEOUT", 5*time.Second),
+			DefaultCostLimit: getEnvFloat("WAREHOUSE_DEFAULT_COST_LIMIT", 100000),
+			AsyncExpiryHours: getEnvInt("WAREHOUSE_ASYNC_RESULT_HOURS", 24),
+		},
+		WarehouseCorrection: WarehouseCorrectionConfig{
+			MaxAttempts:       getEnvInt("WAREHOUSE_NL_SQL_MAX_ATTEMPTS", 3),
+			RetryEmptyResults: getEnv("WAREHOUSE_NL_SQL_RETRY_EMPTY", "true") == "true",
+		},
+		Federation: FederationConfig{
+			MemoryLimitMB:   getEnvInt("FEDERATION_MEMORY_LIMIT_MB", 256),
+			SpillDir:        getEnv("FEDERATION_SPILL_DIR", ""),
+			SpillPartitions: getEnvInt("FEDERATION_SPILL_PARTITIONS", 16),
+			MaxResultRows:   getEnvInt("FEDERATION_MAX_RESULT_ROWS", 100000),
+			QueryTimeout:    getEnvDuration("FEDERATION_QUERY_TIMEOUT", 5*time.Minute),
+		},
 	}
 }
 
@@ -255,6 +347,15 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
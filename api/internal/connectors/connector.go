@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/logging"
 )
 
 /* ConnectorType represents a data source connector type */
@@ -49,6 +51,24 @@ type DataSourceConnector struct {
 type ConnectorService struct {
 	pool      *pgxpool.Pool
 	registry  *ConnectorRegistry
+	validator SyncValidator
+	tracker   SchemaTracker
+}
+
+/* SchemaTracker records the schema stored by each sync, such as for schema evolution history */
+type SchemaTracker interface {
+	TrackSyncedSchema(ctx context.Context, connectorID uuid.UUID, schema *Schema) error
+}
+
+/* SyncValidator checks the schema discovered by a sync, such as against data contracts, before it is stored */
+type SyncValidator interface {
+	ValidateSync(ctx context.Context, connector *DataSourceConnector, syncHistoryID uuid.UUID, schema *Schema) (*SyncValidation, error)
+}
+
+/* SyncValidation is the outcome of validating a sync */
+type SyncValidation struct {
+	Violations int      `json:"violations"`
+	Blocking   []string `json:"blocking,omitempty"` // Why the sync must not be stored; empty to accept it
 }
 
 /* NewConnectorService creates a new connector service */
@@ -60,6 +80,20 @@ func NewConnectorService(pool *pgxpool.Pool) *ConnectorService {
 	}
 }
 
+/* NewConnectorServiceWithSyncValidator creates a connector service that validates every sync's discovered schema */
+func NewConnectorServiceWithSyncValidator(pool *pgxpool.Pool, validator SyncValidator) *ConnectorService {
+	s := NewConnectorService(pool)
+	s.validator = validator
+	return s
+}
+
+/* NewConnectorServiceWithSchemaTracking creates a connector service that validates every sync and tracks the schema it stores */
+func NewConnectorServiceWithSchemaTracking(pool *pgxpool.Pool, validator SyncValidator, tracker SchemaTracker) *ConnectorService {
+	s := NewConnectorServiceWithSyncValidator(pool, validator)
+	s.tracker = tracker
+	return s
+}
+
 /* CreateConnector creates a new data source connector */
 func (s *ConnectorService) CreateConnector(ctx context.Context, connector DataSourceConnector) (*DataSourceConnector, error) {
 	connector.ID = uuid.New()
@@ -238,18 +272,29 @@ func (s *ConnectorService) SyncConnector(ctx context.Context, connectorID uuid.U
 		return fmt.Errorf("failed to create sync history: %w", err)
 	}
 
-	// Get connector implementation from registry
-	impl, err := s.registry.GetConnector(connector.ConnectorType)
+	// Discover schema
+	schema, err := s.DiscoverSchema(ctx, connector)
 	if err != nil {
 		s.updateSyncError(ctx, connectorID, syncHistoryID, err.Error())
-		return fmt.Errorf("connector type not supported: %w", err)
+		return err
 	}
 
-	// Discover schema
-	schema, err := impl.DiscoverSchema(ctx, connector)
-	if err != nil {
-		s.updateSyncError(ctx, connectorID, syncHistoryID, err.Error())
-		return fmt.Errorf("failed to discover schema: %w", err)
+	// Validate against the sync validator; a blocked sync keeps the previously stored schema
+	violations := 0
+	if s.validator != nil {
+		validation, err := s.validator.ValidateSync(ctx, connector, syncHistoryID, schema)
+		if err != nil {
+			s.updateSyncError(ctx, connectorID, syncHistoryID, err.Error())
+			return fmt.Errorf("failed to validate sync: %w", err)
+		}
+		violations = validation.Violations
+		if len(validation.Blocking) > 0 {
+			s.pool.Exec(ctx, `UPDATE neuronip.connector_sync_history SET contract_violations = $1 WHERE id = $2`,
+				violations, syncHistoryID)
+			msg := "sync blocked: " + strings.Join(validation.Blocking, "; ")
+			s.updateSyncError(ctx, connectorID, syncHistoryID, msg)
+			return fmt.Errorf("%s", msg)
+		}
 	}
 
 	// Store discovered schema
@@ -259,6 +304,13 @@ func (s *ConnectorService) SyncConnector(ctx context.Context, connectorID uuid.U
 		return fmt.Errorf("failed to store schema: %w", err)
 	}
 
+	// Schema history is best effort; the stored schema is already the new one
+	if s.tracker != nil {
+		if err := s.tracker.TrackSyncedSchema(ctx, connectorID, schema); err != nil {
+			logging.Error("Failed to track synced schema", "connector_id", connectorID, "error", err)
+		}
+	}
+
 	// Update sync status to success
 	completedAt := time.Now()
 	durationMs := int(completedAt.Sub(startedAt).Milliseconds())
@@ -274,9 +326,9 @@ func (s *ConnectorService) SyncConnector(ctx context.Context, connectorID uuid.U
 	_, err = s.pool.Exec(ctx, `
 		UPDATE neuronip.connector_sync_history
 		SET status = 'success', completed_at = $1, duration_ms = $2,
-		    tables_discovered = $3, columns_discovered = $4
-		WHERE id = $5`,
-		completedAt, durationMs, tablesCount, columnsCount, syncHistoryID)
+		    tables_discovered = $3, columns_discovered = $4, contract_violations = $5
+		WHERE id = $6`,
+		completedAt, durationMs, tablesCount, columnsCount, violations, syncHistoryID)
 	if err != nil {
 		return fmt.Errorf("failed to update sync history: %w", err)
 	}
@@ -284,6 +336,19 @@ func (s *ConnectorService) SyncConnector(ctx context.Context, connectorID uuid.U
 	return nil
 }
 
+/* DiscoverSchema discovers a connector's current schema without storing it */
+func (s *ConnectorService) DiscoverSchema(ctx context.Context, connector *DataSourceConnector) (*Schema, error) {
+	impl, err := s.registry.GetConnector(connector.ConnectorType)
+	if err != nil {
+		return nil, fmt.Errorf("connector type not supported: %w", err)
+	}
+	schema, err := impl.DiscoverSchema(ctx, connector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover schema: %w", err)
+	}
+	return schema, nil
+}
+
 /* updateSyncError updates sync status with error */
 func (s *ConnectorService) updateSyncError(ctx context.Context, connectorID, syncHistoryID uuid.UUID, errorMsg string) {
 	s.pool.Exec(ctx, `
@@ -0,0 +1,182 @@
+package connectors
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "github.com/denisenkom/go-mssqldb"
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	_ "github.com/snowflakedb/gosnowflake"
+)
+
+/* QueryDialect describes how to write SQL for a connector's database */
+type QueryDialect struct {
+	Driver      string
+	IdentQuote  string // Opening and closing quote for identifiers
+	EscapeSlash bool   // Backslashes in string literals are escape characters
+	ReadOnlyTx  bool   // The driver can begin read-only transactions
+	TopLimit    bool   // Row limits are written SELECT TOP n rather than LIMIT n
+}
+
+/* queryDialects are the connector types that can answer SQL queries */
+var queryDialects = map[ConnectorType]QueryDialect{
+	ConnectorPostgreSQL: {Driver: "postgres", IdentQuote: `"`, ReadOnlyTx: true},
+	ConnectorRedshift:   {Driver: "postgres", IdentQuote: `"`, ReadOnlyTx: true},
+	ConnectorMySQL:      {Driver: "mysql", IdentQuote: "`", EscapeSlash: true, ReadOnlyTx: true},
+	ConnectorSnowflake:  {Driver: "snowflake", IdentQuote: `"`},
+	ConnectorSQLServer:  {Driver: "sqlserver", IdentQuote: `"`, TopLimit: true},
+}
+
+/* QuoteIdent quotes an identifier for the dialect */
+func (d QueryDialect) QuoteIdent(name string) string {
+	return d.IdentQuote + strings.ReplaceAll(name, d.IdentQuote, d.IdentQuote+d.IdentQuote) + d.IdentQuote
+}
+
+/* QuoteString quotes a string literal for the dialect */
+func (d QueryDialect) QuoteString(value string) string {
+	if d.EscapeSlash {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+/* SelectLimited writes a query selecting columns from a table, returning at most limit rows */
+func (d QueryDialect) SelectLimited(columns, from string, limit int) string {
+	if d.TopLimit {
+		return fmt.Sprintf("SELECT TOP %d %s FROM %s", limit, columns, from)
+	}
+	return fmt.Sprintf("SELECT %s FROM %s LIMIT %d", columns, from, limit)
+}
+
+/* GetQueryDialect returns the SQL dialect of a connector type */
+func GetQueryDialect(connectorType ConnectorType) (QueryDialect, error) {
+	dialect, ok := queryDialects[connectorType]
+	if !ok {
+		return QueryDialect{}, fmt.Errorf("connector type %s does not support SQL queries", connectorType)
+	}
+	return dialect, nil
+}
+
+/* OpenQueryDB opens a database handle for running SQL against a connector */
+func OpenQueryDB(ctx context.Context, connector *DataSourceConnector) (*sql.DB, error) {
+	dialect, err := GetQueryDialect(connector.ConnectorType)
+	if err != nil {
+		return nil, err
+	}
+
+	dsn, err := queryDSN(connector)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(dialect.Driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open connection: %w", err)
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(pingCtx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s: %w", connector.Name, err)
+	}
+
+	return db, nil
+}
+
+/* queryDSN builds a driver connection string from the connector's connection string or configuration */
+func queryDSN(connector *DataSourceConnector) (string, error) {
+	if connector.ConnectionString != nil && *connector.ConnectionString != "" {
+		return *connector.ConnectionString, nil
+	}
+
+	config := connector.Configuration
+	get := func(key string) string {
+		if value, ok := config[key].(string); ok {
+			return value
+		}
+		if value, ok := connector.Credentials[key].(string); ok {
+			return value
+		}
+		return ""
+	}
+	port := func(fallback string) string {
+		switch value := config["port"].(type) {
+		case string:
+			if value != "" {
+				return value
+			}
+		case float64:
+			return fmt.Sprintf("%.0f", value)
+		}
+		return fallback
+	}
+
+	switch connector.ConnectorType {
+	case ConnectorPostgreSQL, ConnectorRedshift:
+		return (&PostgreSQLConnector{}).buildConnectionString(connector), nil
+
+	case ConnectorMySQL:
+		cfg := mysql.NewConfig()
+		cfg.User = get("user")
+		cfg.Passwd = get("password")
+		cfg.Net = "tcp"
+		cfg.Addr = get("host") + ":" + port("3306")
+		cfg.DBName = get("database")
+		cfg.ParseTime = true
+		if cfg.User == "" || get("host") == "" {
+			return "", fmt.Errorf("host and user are required")
+		}
+		return cfg.FormatDSN(), nil
+
+	case ConnectorSnowflake:
+		account, user := get("account"), get("user")
+		if account == "" || user == "" || get("database") == "" {
+			return "", fmt.Errorf("account, user and database are required")
+		}
+		params := url.Values{}
+		for _, key := range []string{"warehouse", "schema", "role"} {
+			if value := get(key); value != "" {
+				params.Set(key, value)
+			}
+		}
+		dsn := fmt.Sprintf("%s:%s@%s/%s", url.QueryEscape(user), url.QueryEscape(get("password")), account, get("database"))
+		if len(params) > 0 {
+			dsn += "?" + params.Encode()
+		}
+		return dsn, nil
+
+	case ConnectorSQLServer:
+		if get("host") == "" || get("user") == "" {
+			return "", fmt.Errorf("host and user are required")
+		}
+		query := url.Values{}
+		if database := get("database"); database != "" {
+			query.Set("database", database)
+		}
+		u := &url.URL{
+			Scheme:   "sqlserver",
+			User:     url.UserPassword(get("user"), get("password")),
+			Host:     get("host") + ":" + port("1433"),
+			RawQuery: query.Encode(),
+		}
+		return u.String(), nil
+	}
+
+	return "", fmt.Errorf("connector type %s does not support SQL queries", connector.ConnectorType)
+}
+
+/* GetConnectorByName retrieves a connector by its unique name */
+func (s *ConnectorService) GetConnectorByName(ctx context.Context, name string) (*DataSourceConnector, error) {
+	var id uuid.UUID
+	err := s.pool.QueryRow(ctx, `SELECT id FROM neuronip.data_source_connectors WHERE name = $1`, name).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connector %s: %w", name, err)
+	}
+	return s.GetConnector(ctx, id)
+}
@@ -0,0 +1,903 @@
+package dataquality
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/neurondb/NeuronIP/api/internal/agent"
+	"github.com/neurondb/NeuronIP/api/internal/connectors"
+	"github.com/neurondb/NeuronIP/api/internal/mcp"
+	"github.com/neurondb/NeuronIP/api/internal/neurondb"
+)
+
+/* Consistency rule kinds, set in rule metadata as consistency_type */
+const (
+	ConsistencyReferentialIntegrity = "referential_integrity"
+	ConsistencyCrossColumn          = "cross_column"
+	ConsistencyReconciliation       = "reconciliation"
+)
+
+const (
+	defaultSampleLimit = 10
+	lookupBatchSize    = 500
+	fingerprintBuckets = 256
+	maxLocateBuckets   = 16
+)
+
+/* NewServiceWithConnectors creates a data quality service whose consistency rules can read tables on data source connectors */
+func NewServiceWithConnectors(pool *pgxpool.Pool, neurondbClient *neurondb.Client, mcpClient *mcp.Client, agentClient *agent.Client, connectorService *connectors.ConnectorService) *Service {
+	svc := NewServiceWithMCPAndAgent(pool, neurondbClient, mcpClient, agentClient)
+	svc.connectorService = connectorService
+	return svc
+}
+
+/* executeConsistencyRule executes consistency rule.
+ * Metadata consistency_type selects referential_integrity, cross_column or reconciliation. */
+func (s *Service) executeConsistencyRule(ctx context.Context, rule *QualityRule) (*RuleExecutionResult, error) {
+	if rule.TableName == nil {
+		return nil, fmt.Errorf("table_name required for consistency rule")
+	}
+
+	switch metaString(rule.Metadata, "consistency_type") {
+	case ConsistencyReferentialIntegrity:
+		return s.checkReferentialIntegrity(ctx, rule)
+	case ConsistencyCrossColumn:
+		return s.checkCrossColumn(ctx, rule)
+	case ConsistencyReconciliation:
+		return s.checkReconciliation(ctx, rule)
+	case "":
+		return nil, fmt.Errorf("consistency_type is required in rule metadata")
+	default:
+		return nil, fmt.Errorf("unknown consistency_type: %s", metaString(rule.Metadata, "consistency_type"))
+	}
+}
+
+/* tableSource is a table on the NeuronIP database or on a data source connector */
+type tableSource struct {
+	db          *sql.DB
+	dialect     connectors.QueryDialect
+	connectorID *uuid.UUID
+	schema      string
+	table       string
+}
+
+/* openTable opens the database holding a table.
+ * Without a connector, or when no connector service is configured, the table is read from the NeuronIP database as the other rules do. */
+func (s *Service) openTable(ctx context.Context, connectorID *uuid.UUID, schema, table string) (*tableSource, error) {
+	if connectorID == nil || s.connectorService == nil {
+		if schema == "" {
+			schema = "public"
+		}
+		dialect, _ := connectors.GetQueryDialect(connectors.ConnectorPostgreSQL)
+		return &tableSource{db: stdlib.OpenDBFromPool(s.pool), dialect: dialect, schema: schema, table: table}, nil
+	}
+
+	connector, err := s.connectorService.GetConnector(ctx, *connectorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connector: %w", err)
+	}
+	dialect, err := connectors.GetQueryDialect(connector.ConnectorType)
+	if err != nil {
+		return nil, err
+	}
+	db, err := connectors.OpenQueryDB(ctx, connector)
+	if err != nil {
+		return nil, err
+	}
+	return &tableSource{db: db, dialect: dialect, connectorID: connectorID, schema: schema, table: table}, nil
+}
+
+func (t *tableSource) close() {
+	t.db.Close()
+}
+
+/* qualified returns the quoted table name */
+func (t *tableSource) qualified() string {
+	if t.schema == "" {
+		return t.dialect.QuoteIdent(t.table)
+	}
+	return t.dialect.QuoteIdent(t.schema) + "." + t.dialect.QuoteIdent(t.table)
+}
+
+func (t *tableSource) columns(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = t.dialect.QuoteIdent(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+/* sameDatabase reports whether two tables can be joined in one query */
+func (t *tableSource) sameDatabase(other *tableSource) bool {
+	if t.connectorID == nil || other.connectorID == nil {
+		return t.connectorID == nil && other.connectorID == nil
+	}
+	return *t.connectorID == *other.connectorID
+}
+
+/* scanRows runs a query and calls fn with each row until fn returns false */
+func (t *tableSource) scanRows(ctx context.Context, query string, fn func(values []interface{}) bool) error {
+	return t.scanNamedRows(ctx, query, func(_ []string, values []interface{}) bool {
+		return fn(values)
+	})
+}
+
+/* scanNamedRows is scanRows with the result column names */
+func (t *tableSource) scanNamedRows(ctx context.Context, query string, fn func(columns []string, values []interface{}) bool) error {
+	rows, err := t.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		if !fn(columns, values) {
+			break
+		}
+	}
+	return rows.Err()
+}
+
+/* ruleTable opens the table the rule is defined on */
+func (s *Service) ruleTable(ctx context.Context, rule *QualityRule) (*tableSource, error) {
+	schema := ""
+	if rule.SchemaName != nil {
+		schema = *rule.SchemaName
+	}
+	connectorID := rule.ConnectorID
+	if rule.staged {
+		// Staged batches are on the NeuronIP database whatever connector the rule was written for
+		connectorID = nil
+	}
+	return s.openTable(ctx, connectorID, schema, *rule.TableName)
+}
+
+/* metadataTable opens the table named by the prefix_connector_id, prefix_schema and prefix_table metadata keys.
+ * Without prefix_connector_id the table is on the rule's connector. */
+func (s *Service) metadataTable(ctx context.Context, rule *QualityRule, prefix, defaultTable string) (*tableSource, error) {
+	table := metaString(rule.Metadata, prefix+"_table")
+	if table == "" {
+		table = defaultTable
+	}
+	if table == "" {
+		return nil, fmt.Errorf("%s_table is required in rule metadata", prefix)
+	}
+	connectorID := rule.ConnectorID
+	if id := metaString(rule.Metadata, prefix+"_connector_id"); id != "" {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s_connector_id: %w", prefix, err)
+		}
+		connectorID = &parsed
+	}
+	return s.openTable(ctx, connectorID, metaString(rule.Metadata, prefix+"_schema"), table)
+}
+
+/* sampleCollector keeps the first offending keys of a check */
+type sampleCollector struct {
+	limit int
+	keys  []string
+}
+
+func newSampleCollector(rule *QualityRule) *sampleCollector {
+	limit := int(metaFloat(rule.Metadata, "sample_limit", defaultSampleLimit))
+	if limit <= 0 {
+		limit = defaultSampleLimit
+	}
+	return &sampleCollector{limit: limit}
+}
+
+func (c *sampleCollector) add(key string) {
+	if len(c.keys) < c.limit {
+		c.keys = append(c.keys, key)
+	}
+}
+
+func (c *sampleCollector) full() bool {
+	return len(c.keys) >= c.limit
+}
+
+func (c *sampleCollector) violation(violationType, message, severity string) QualityViolation {
+	violation := QualityViolation{
+		ViolationType:    violationType,
+		ViolationMessage: message,
+		Severity:         severity,
+		SampleKeys:       c.keys,
+	}
+	if len(c.keys) > 0 {
+		violation.RowIdentifier = c.keys[0]
+	}
+	return violation
+}
+
+/* checkReferentialIntegrity finds rows whose key has no match in a reference table, which may live on another connector */
+func (s *Service) checkReferentialIntegrity(ctx context.Context, rule *QualityRule) (*RuleExecutionResult, error) {
+	if rule.ColumnName == nil {
+		return nil, fmt.Errorf("column_name required for referential integrity rule")
+	}
+	referenceColumn := metaString(rule.Metadata, "reference_column")
+	if referenceColumn == "" {
+		referenceColumn = *rule.ColumnName
+	}
+
+	child, err := s.ruleTable(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	defer child.close()
+	parent, err := s.metadataTable(ctx, rule, "reference", "")
+	if err != nil {
+		return nil, err
+	}
+	defer parent.close()
+
+	column := child.dialect.QuoteIdent(*rule.ColumnName)
+	samples := newSampleCollector(rule)
+	var totalCount, orphanRows, orphanKeys int64
+
+	if child.sameDatabase(parent) {
+		// Both tables are on one database, so let it run the anti-join
+		if err := child.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(%s) FROM %s`, column, child.qualified())).Scan(&totalCount); err != nil {
+			return nil, fmt.Errorf("failed to count rows: %w", err)
+		}
+		query := fmt.Sprintf(`SELECT c.%s, COUNT(*) FROM %s c WHERE c.%s IS NOT NULL AND NOT EXISTS (SELECT 1 FROM %s p WHERE p.%s = c.%s) GROUP BY c.%s`,
+			column, child.qualified(), column, parent.qualified(), parent.dialect.QuoteIdent(referenceColumn), column, column)
+		err = child.scanRows(ctx, query, func(values []interface{}) bool {
+			orphanKeys++
+			orphanRows += toInt64(values[1])
+			samples.add(canonicalValue(values[0]))
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to find orphaned keys: %w", err)
+		}
+	} else {
+		// Stream the distinct child keys and look them up on the reference connector in batches. Keys go to the
+		// reference connector as read and are canonicalized only to compare, so text keys such as "007" still match.
+		keys := make([]string, 0, lookupBatchSize)
+		counts := make(map[string]int64, lookupBatchSize)
+		flush := func() error {
+			if len(keys) == 0 {
+				return nil
+			}
+			found, err := lookupKeys(ctx, parent, referenceColumn, keys)
+			if err != nil {
+				return err
+			}
+			for _, key := range keys {
+				if !found[canonicalValue(key)] {
+					orphanKeys++
+					orphanRows += counts[key]
+					samples.add(key)
+				}
+			}
+			keys = keys[:0]
+			clear(counts)
+			return nil
+		}
+
+		var lookupErr error
+		query := fmt.Sprintf(`SELECT %s, COUNT(*) FROM %s WHERE %s IS NOT NULL GROUP BY %s`, column, child.qualified(), column, column)
+		err = child.scanRows(ctx, query, func(values []interface{}) bool {
+			key := literalValue(values[0])
+			count := toInt64(values[1])
+			totalCount += count
+			if _, ok := counts[key]; !ok {
+				keys = append(keys, key)
+			}
+			counts[key] += count
+			if len(keys) >= lookupBatchSize {
+				lookupErr = flush()
+			}
+			return lookupErr == nil
+		})
+		if err == nil {
+			err = lookupErr
+		}
+		if err == nil {
+			err = flush()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to check referenced keys: %w", err)
+		}
+	}
+
+	result := &RuleExecutionResult{
+		PassedCount: totalCount - orphanRows,
+		FailedCount: orphanRows,
+		TotalCount:  totalCount,
+		Violations:  []QualityViolation{},
+	}
+	if orphanRows > 0 {
+		result.Violations = append(result.Violations, samples.violation(ConsistencyReferentialIntegrity,
+			fmt.Sprintf("%d rows reference %d %s values missing from %s.%s", orphanRows, orphanKeys, *rule.ColumnName, parent.qualified(), referenceColumn),
+			"high"))
+	}
+	return result, nil
+}
+
+/* lookupKeys returns the canonical values of the keys that exist in a column */
+func lookupKeys(ctx context.Context, t *tableSource, column string, keys []string) (map[string]bool, error) {
+	literals := make([]string, len(keys))
+	for i, key := range keys {
+		literals[i] = t.dialect.QuoteString(key)
+	}
+	query := fmt.Sprintf(`SELECT DISTINCT %s FROM %s WHERE %s IN (%s)`,
+		t.dialect.QuoteIdent(column), t.qualified(), t.dialect.QuoteIdent(column), strings.Join(literals, ", "))
+
+	found := make(map[string]bool, len(keys))
+	err := t.scanRows(ctx, query, func(values []interface{}) bool {
+		found[canonicalValue(values[0])] = true
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+/* checkCrossColumn counts rows breaking a boolean invariant such as end_date >= start_date */
+func (s *Service) checkCrossColumn(ctx context.Context, rule *QualityRule) (*RuleExecutionResult, error) {
+	return s.checkRowCondition(ctx, rule, ConsistencyCrossColumn)
+}
+
+/* checkRowCondition counts rows for which the rule expression, a boolean SQL condition, is false */
+func (s *Service) checkRowCondition(ctx context.Context, rule *QualityRule, violationType string) (*RuleExecutionResult, error) {
+	t, err := s.ruleTable(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	defer t.close()
+
+	condition, err := rowConditionFailure(rule, t.dialect)
+	if err != nil {
+		return nil, err
+	}
+	expression := strings.TrimSpace(rule.RuleExpression)
+
+	var totalCount, failedCount int64
+	if err := t.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s`, t.qualified())).Scan(&totalCount); err != nil {
+		return nil, fmt.Errorf("failed to count rows: %w", err)
+	}
+	if err := t.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s`, t.qualified(), condition)).Scan(&failedCount); err != nil {
+		return nil, fmt.Errorf("failed to evaluate expression: %w", err)
+	}
+
+	result := &RuleExecutionResult{
+		PassedCount: totalCount - failedCount,
+		FailedCount: failedCount,
+		TotalCount:  totalCount,
+		Violations:  []QualityViolation{},
+	}
+	if failedCount == 0 {
+		return result, nil
+	}
+
+	keyColumns := ruleKeyColumns(rule)
+	selectList := "*"
+	if len(keyColumns) > 0 {
+		selectList = t.columns(keyColumns)
+	}
+	samples := newSampleCollector(rule)
+	err = t.scanNamedRows(ctx, fmt.Sprintf(`SELECT %s FROM %s WHERE %s`, selectList, t.qualified(), condition), func(columns []string, values []interface{}) bool {
+		samples.add(formatKey(columns, values))
+		return !samples.full()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample violating rows: %w", err)
+	}
+
+	result.Violations = append(result.Violations, samples.violation(violationType,
+		fmt.Sprintf("%d of %d rows violate %s", failedCount, totalCount, expression), "medium"))
+	return result, nil
+}
+
+/* rowConditionFailure returns a condition selecting the rows for which the rule expression is false,
+ * written for the dialect of the table it runs on. Rows where the expression is NULL pass unless null_is_violation is set. */
+func rowConditionFailure(rule *QualityRule, dialect connectors.QueryDialect) (string, error) {
+	expression, err := importedExpression(rule, dialect)
+	if err != nil {
+		return "", err
+	}
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return "", fmt.Errorf("rule_expression is required for %s rule", rule.RuleType)
+	}
+	if strings.Contains(expression, ";") {
+		return "", fmt.Errorf("rule_expression must be a single boolean expression")
+	}
+	if metaBool(rule.Metadata, "null_is_violation") {
+		return fmt.Sprintf("CASE WHEN (%s) THEN 0 ELSE 1 END = 1", expression), nil
+	}
+	return fmt.Sprintf("NOT (%s)", expression), nil
+}
+
+/* checkRowCount checks that a table's row count is within min_row_count and max_row_count,
+ * and with row_count_band set, within the band learned from its past counts */
+func (s *Service) checkRowCount(ctx context.Context, rule *QualityRule) (*RuleExecutionResult, error) {
+	t, err := s.ruleTable(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	defer t.close()
+
+	var rows int64
+	err = t.scanRows(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s`, t.qualified()), func(values []interface{}) bool {
+		rows = toInt64(values[0])
+		return false
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rows: %w", err)
+	}
+
+	minRows, hasMin := rule.Metadata["min_row_count"].(float64)
+	maxRows, hasMax := rule.Metadata["max_row_count"].(float64)
+	var message string
+	switch {
+	case hasMin && float64(rows) < minRows:
+		message = fmt.Sprintf("%s has %d rows, fewer than the minimum of %.0f", t.qualified(), rows, minRows)
+	case hasMax && float64(rows) > maxRows:
+		message = fmt.Sprintf("%s has %d rows, more than the maximum of %.0f", t.qualified(), rows, maxRows)
+	case metaBool(rule.Metadata, "row_count_band") && !rule.staged:
+		eval, band, err := s.observeRowCount(ctx, rule, t, rows)
+		if err != nil {
+			return nil, err
+		}
+		if eval == nil || !eval.Outside {
+			return tableCheckResult(nil), nil
+		}
+		message = fmt.Sprintf("%s row count %s. %s", t.qualified(), eval.Describe(), band.Explanation)
+	default:
+		return tableCheckResult(nil), nil
+	}
+	return tableCheckResult(&QualityViolation{
+		ColumnValue:      strconv.FormatInt(rows, 10),
+		ViolationType:    "row_count",
+		ViolationMessage: message,
+		Severity:         "high",
+	}), nil
+}
+
+/* checkNullRows counts the rows whose rule column is null, reading the table through its connector */
+func (s *Service) checkNullRows(ctx context.Context, rule *QualityRule) (*RuleExecutionResult, error) {
+	t, err := s.ruleTable(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	defer t.close()
+
+	counted := "*"
+	if rule.ColumnName != nil {
+		counted = t.dialect.QuoteIdent(*rule.ColumnName)
+	}
+	var totalCount, nonNullCount int64
+	err = t.scanRows(ctx, fmt.Sprintf(`SELECT COUNT(*), COUNT(%s) FROM %s`, counted, t.qualified()), func(values []interface{}) bool {
+		totalCount, nonNullCount = toInt64(values[0]), toInt64(values[1])
+		return false
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count non-null rows: %w", err)
+	}
+	return &RuleExecutionResult{
+		PassedCount: nonNullCount,
+		FailedCount: totalCount - nonNullCount,
+		TotalCount:  totalCount,
+		Violations:  []QualityViolation{},
+	}, nil
+}
+
+/* reconciliationMeasure is one aggregate compared between source and target */
+type reconciliationMeasure struct {
+	kind   string // row_count, sum or checksum
+	column string
+}
+
+func (m reconciliationMeasure) String() string {
+	if m.column == "" {
+		return m.kind
+	}
+	return m.kind + ":" + m.column
+}
+
+/* parseMeasures reads measures such as ["row_count", "sum:amount", "checksum:status"] */
+func parseMeasures(values []string) ([]reconciliationMeasure, error) {
+	if len(values) == 0 {
+		values = []string{"row_count"}
+	}
+	measures := make([]reconciliationMeasure, 0, len(values))
+	for _, value := range values {
+		kind, column, _ := strings.Cut(strings.TrimSpace(value), ":")
+		switch kind {
+		case "row_count":
+			measures = append(measures, reconciliationMeasure{kind: kind})
+		case "sum", "checksum":
+			if column == "" {
+				return nil, fmt.Errorf("measure %s needs a column", value)
+			}
+			measures = append(measures, reconciliationMeasure{kind: kind, column: column})
+		default:
+			return nil, fmt.Errorf("unknown reconciliation measure: %s", value)
+		}
+	}
+	return measures, nil
+}
+
+/* checkReconciliation compares row counts, sums and checksums between a source connector and the ingested target table.
+ * Each measure is one check; counts and sums may differ by the relative tolerance, checksums must match. */
+func (s *Service) checkReconciliation(ctx context.Context, rule *QualityRule) (*RuleExecutionResult, error) {
+	measures, err := parseMeasures(metaStrings(rule.Metadata, "measures"))
+	if err != nil {
+		return nil, err
+	}
+	tolerance := metaFloat(rule.Metadata, "tolerance", 0)
+	keyColumns := ruleKeyColumns(rule)
+
+	target, err := s.ruleTable(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	defer target.close()
+	source, err := s.metadataTable(ctx, rule, "source", *rule.TableName)
+	if err != nil {
+		return nil, err
+	}
+	defer source.close()
+	if source.sameDatabase(target) && source.qualified() == target.qualified() {
+		return nil, fmt.Errorf("reconciliation source and target are the same table")
+	}
+
+	sourceAggregates, err := aggregateMeasures(ctx, source, measures)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate source: %w", err)
+	}
+	targetAggregates, err := aggregateMeasures(ctx, target, measures)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate target: %w", err)
+	}
+
+	// Checksums, and the bucket hashes used to find mismatched keys, need one streaming pass over each side
+	var checksumColumns, sumColumns []string
+	for _, m := range measures {
+		switch m.kind {
+		case "checksum":
+			checksumColumns = append(checksumColumns, m.column)
+		case "sum":
+			sumColumns = append(sumColumns, m.column)
+		}
+	}
+	var sourcePrint, targetPrint *fingerprint
+	if len(checksumColumns) > 0 || len(keyColumns) > 0 {
+		if sourcePrint, err = fingerprintTable(ctx, source, keyColumns, checksumColumns, sumColumns); err != nil {
+			return nil, fmt.Errorf("failed to checksum source: %w", err)
+		}
+		if targetPrint, err = fingerprintTable(ctx, target, keyColumns, checksumColumns, sumColumns); err != nil {
+			return nil, fmt.Errorf("failed to checksum target: %w", err)
+		}
+	}
+
+	type mismatch struct {
+		measure reconciliationMeasure
+		message string
+	}
+	var mismatches []mismatch
+	for i, m := range measures {
+		switch m.kind {
+		case "checksum":
+			if sourcePrint.checksum != targetPrint.checksum {
+				mismatches = append(mismatches, mismatch{m, fmt.Sprintf("checksum of %s differs between source and target", m.column)})
+			}
+		default:
+			sv, tv := sourceAggregates[i], targetAggregates[i]
+			if !withinTolerance(sv, tv, tolerance) {
+				mismatches = append(mismatches, mismatch{m, fmt.Sprintf("%s is %s in source and %s in target (tolerance %g)",
+					m, strconv.FormatFloat(sv, 'f', -1, 64), strconv.FormatFloat(tv, 'f', -1, 64), tolerance)})
+			}
+		}
+	}
+
+	result := &RuleExecutionResult{
+		PassedCount: int64(len(measures) - len(mismatches)),
+		FailedCount: int64(len(mismatches)),
+		TotalCount:  int64(len(measures)),
+		Violations:  []QualityViolation{},
+	}
+	if len(mismatches) == 0 {
+		return result, nil
+	}
+
+	samples := newSampleCollector(rule)
+	if len(keyColumns) > 0 {
+		if err := locateMismatches(ctx, source, target, sourcePrint, targetPrint, keyColumns, checksumColumns, sumColumns, samples); err != nil {
+			return nil, fmt.Errorf("failed to locate mismatched keys: %w", err)
+		}
+	}
+	for _, m := range mismatches {
+		result.Violations = append(result.Violations, samples.violation(ConsistencyReconciliation, m.message, "high"))
+	}
+	return result, nil
+}
+
+/* aggregateMeasures computes row counts and sums in one query; checksum entries are left at zero */
+func aggregateMeasures(ctx context.Context, t *tableSource, measures []reconciliationMeasure) ([]float64, error) {
+	selects := make([]string, 0, len(measures))
+	for _, m := range measures {
+		switch m.kind {
+		case "row_count":
+			selects = append(selects, "COUNT(*)")
+		case "sum":
+			selects = append(selects, fmt.Sprintf("SUM(%s)", t.dialect.QuoteIdent(m.column)))
+		default:
+			selects = append(selects, "0")
+		}
+	}
+
+	values := make([]float64, len(measures))
+	err := t.scanRows(ctx, fmt.Sprintf(`SELECT %s FROM %s`, strings.Join(selects, ", "), t.qualified()), func(row []interface{}) bool {
+		for i, v := range row {
+			values[i] = toFloat64(v)
+		}
+		return false
+	})
+	return values, err
+}
+
+/* withinTolerance compares two aggregates using a tolerance relative to the larger of them */
+func withinTolerance(source, target, tolerance float64) bool {
+	return math.Abs(source-target) <= tolerance*math.Max(math.Abs(source), math.Abs(target))
+}
+
+/* fingerprint is an order-independent hash of a table's rows */
+type fingerprint struct {
+	checksum uint64
+	buckets  []uint64
+}
+
+/* fingerprintTable hashes each row. checksum covers the key and checksum columns;
+ * when keys are given, buckets hold per-key-hash sums over every compared column so mismatches can be located. */
+func fingerprintTable(ctx context.Context, t *tableSource, keyColumns, checksumColumns, sumColumns []string) (*fingerprint, error) {
+	columns := append(append(append([]string{}, keyColumns...), checksumColumns...), sumColumns...)
+	fp := &fingerprint{}
+	if len(keyColumns) > 0 {
+		fp.buckets = make([]uint64, fingerprintBuckets)
+	}
+	checksumWidth := len(keyColumns) + len(checksumColumns)
+
+	err := t.scanRows(ctx, fmt.Sprintf(`SELECT %s FROM %s`, t.columns(columns), t.qualified()), func(values []interface{}) bool {
+		fp.checksum += hashValues(values[:checksumWidth])
+		if fp.buckets != nil {
+			fp.buckets[bucketOf(formatKey(keyColumns, values[:len(keyColumns)]))] += hashValues(values)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fp, nil
+}
+
+/* locateMismatches re-reads the rows of the first mismatched buckets on each side and records the keys that differ */
+func locateMismatches(ctx context.Context, source, target *tableSource, sourcePrint, targetPrint *fingerprint, keyColumns, checksumColumns, sumColumns []string, samples *sampleCollector) error {
+	buckets := make(map[int]bool)
+	for i := range sourcePrint.buckets {
+		if sourcePrint.buckets[i] != targetPrint.buckets[i] && len(buckets) < maxLocateBuckets {
+			buckets[i] = true
+		}
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	columns := append(append(append([]string{}, keyColumns...), checksumColumns...), sumColumns...)
+	collect := func(t *tableSource) (map[string]uint64, error) {
+		rowsByKey := make(map[string]uint64)
+		err := t.scanRows(ctx, fmt.Sprintf(`SELECT %s FROM %s`, t.columns(columns), t.qualified()), func(values []interface{}) bool {
+			key := formatKey(keyColumns, values[:len(keyColumns)])
+			if buckets[bucketOf(key)] {
+				rowsByKey[key] += hashValues(values)
+			}
+			return true
+		})
+		return rowsByKey, err
+	}
+	sourceRows, err := collect(source)
+	if err != nil {
+		return err
+	}
+	targetRows, err := collect(target)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(sourceRows))
+	for key, hash := range sourceRows {
+		if targetHash, ok := targetRows[key]; !ok || targetHash != hash {
+			keys = append(keys, key)
+		}
+	}
+	for key := range targetRows {
+		if _, ok := sourceRows[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		_, inSource := sourceRows[key]
+		_, inTarget := targetRows[key]
+		switch {
+		case !inSource:
+			samples.add(key + " (only in target)")
+		case !inTarget:
+			samples.add(key + " (missing from target)")
+		default:
+			samples.add(key + " (values differ)")
+		}
+	}
+	return nil
+}
+
+func bucketOf(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % fingerprintBuckets)
+}
+
+func hashValues(values []interface{}) uint64 {
+	h := fnv.New64a()
+	for _, v := range values {
+		h.Write([]byte(canonicalValue(v)))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+/* canonicalValue renders a value the same way whichever driver read it, so keys and hashes compare across connectors */
+func canonicalValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		return canonicalNumber(value)
+	case bool:
+		return strconv.FormatBool(value)
+	case time.Time:
+		return value.UTC().Format("2006-01-02 15:04:05.999999")
+	case string:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return strconv.FormatInt(i, 10)
+		}
+		if f, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return canonicalNumber(f)
+		}
+		return value
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+/* literalValue renders a key for a lookup on another connector: text as read, since the canonical form drops
+ * zero padding the other database compares on, and other values in canonical form */
+func literalValue(v interface{}) string {
+	if text, ok := v.(string); ok {
+		return text
+	}
+	return canonicalValue(v)
+}
+
+func canonicalNumber(f float64) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+/* formatKey renders key columns as a sample key, e.g. "order_id=42" */
+func formatKey(columns []string, values []interface{}) string {
+	if len(values) == 1 && len(columns) <= 1 {
+		return canonicalValue(values[0])
+	}
+	parts := make([]string, len(values))
+	for i, v := range values {
+		if i < len(columns) {
+			parts[i] = columns[i] + "=" + canonicalValue(v)
+		} else {
+			parts[i] = canonicalValue(v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+/* ruleKeyColumns returns the key_columns metadata, falling back to the rule column */
+func ruleKeyColumns(rule *QualityRule) []string {
+	if columns := metaStrings(rule.Metadata, "key_columns"); len(columns) > 0 {
+		return columns
+	}
+	if rule.ColumnName != nil {
+		return []string{*rule.ColumnName}
+	}
+	return nil
+}
+
+func toInt64(v interface{}) int64 {
+	return int64(toFloat64(v))
+}
+
+func toFloat64(v interface{}) float64 {
+	switch value := v.(type) {
+	case int64:
+		return float64(value)
+	case float64:
+		return value
+	case string:
+		f, _ := strconv.ParseFloat(value, 64)
+		return f
+	}
+	return 0
+}
+
+func metaString(metadata map[string]interface{}, key string) string {
+	if value, ok := metadata[key].(string); ok {
+		return strings.TrimSpace(value)
+	}
+	return ""
+}
+
+func metaStrings(metadata map[string]interface{}, key string) []string {
+	var values []string
+	switch value := metadata[key].(type) {
+	case []interface{}:
+		for _, item := range value {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				values = append(values, strings.TrimSpace(s))
+			}
+		}
+	case []string:
+		values = value
+	case string:
+		for _, s := range strings.Split(value, ",") {
+			if strings.TrimSpace(s) != "" {
+				values = append(values, strings.TrimSpace(s))
+			}
+		}
+	}
+	return values
+}
+
+func metaFloat(metadata map[string]interface{}, key string, fallback float64) float64 {
+	if value, ok := metadata[key].(float64); ok {
+		return value
+	}
+	return fallback
+}
+
+func metaBool(metadata map[string]interface{}, key string) bool {
+	value, _ := metadata[key].(bool)
+	return value
+}
@@ -0,0 +1,54 @@
+package dataquality
+
+import (
+	"testing"
+	"time"
+)
+
+/* TestCanonicalValue checks that values read by different drivers get equal keys */
+func TestCanonicalValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "null", value: nil, want: "NULL"},
+		{name: "integer", value: int64(42), want: "42"},
+		{name: "integral float", value: float64(42), want: "42"},
+		{name: "numeric text", value: "42", want: "42"},
+		{name: "decimal text", value: "42.50", want: "42.5"},
+		{name: "zero-padded text", value: "007", want: "7"},
+		{name: "text", value: "ACME", want: "ACME"},
+		{name: "bool", value: true, want: "true"},
+		{name: "time in a zone", value: time.Date(2026, 3, 1, 14, 0, 0, 0, time.FixedZone("CET", 3600)), want: "2026-03-01 13:00:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canonicalValue(tt.value); got != tt.want {
+				t.Fatalf("canonicalValue(%#v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+/* TestLiteralValue checks that lookup keys keep text as read, so zero-padded keys match on the reference connector */
+func TestLiteralValue(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{value: "007", want: "007"},
+		{value: " padded", want: " padded"},
+		{value: int64(7), want: "7"},
+		{value: float64(2.5), want: "2.5"},
+	}
+	for _, tt := range tests {
+		if got := literalValue(tt.value); got != tt.want {
+			t.Errorf("literalValue(%#v) = %q, want %q", tt.value, got, tt.want)
+		}
+		// Keys found on the reference connector compare in canonical form
+		if canonicalValue(literalValue(tt.value)) != canonicalValue(tt.value) {
+			t.Errorf("literal and canonical forms of %#v compare differently", tt.value)
+		}
+	}
+}
@@ -0,0 +1,774 @@
+package dataquality
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/neurondb/NeuronIP/api/internal/connectors"
+	"gopkg.in/yaml.v3"
+)
+
+/* Test suite formats that can be imported as quality rules */
+const (
+	ImportFormatDbt               = "dbt"
+	ImportFormatGreatExpectations = "great_expectations"
+	importActionCreated           = "created"
+	importActionUpdated           = "updated"
+	importActionUnchanged         = "unchanged"
+)
+
+/* ImportError is a suite or option that cannot be imported */
+type ImportError struct {
+	Message string
+}
+
+func (e *ImportError) Error() string {
+	return e.Message
+}
+
+func importErrorf(format string, args ...interface{}) error {
+	return &ImportError{Message: fmt.Sprintf(format, args...)}
+}
+
+/* AsImportError returns the import error in err's chain, if any */
+func AsImportError(err error) *ImportError {
+	var importErr *ImportError
+	if errors.As(err, &importErr) {
+		return importErr
+	}
+	return nil
+}
+
+/* ImportOptions says where imported tests run */
+type ImportOptions struct {
+	ConnectorID uuid.UUID `json:"connector_id"`
+	SchemaName  string    `json:"schema_name,omitempty"`
+	TableName   string    `json:"table_name,omitempty"` // Table a Great Expectations suite validates; defaults to the suite name up to the first dot
+	DryRun      bool      `json:"dry_run,omitempty"`
+	CreatedBy   *string   `json:"-"`
+}
+
+/* ImportedRule is a test that became a quality rule */
+type ImportedRule struct {
+	SourceID string     `json:"source_id"`
+	RuleID   *uuid.UUID `json:"rule_id,omitempty"`
+	Name     string     `json:"name"`
+	RuleType string     `json:"rule_type"`
+	Action   string     `json:"action"` // created, updated or unchanged
+}
+
+/* UnmappedTest is a test that has no quality rule equivalent */
+type UnmappedTest struct {
+	SourceID string `json:"source_id"`
+	Test     string `json:"test"`
+	Table    string `json:"table,omitempty"`
+	Column   string `json:"column,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+/* ImportReport lists what an import did with every test in the suite */
+type ImportReport struct {
+	Format    string         `json:"format"`
+	DryRun    bool           `json:"dry_run"`
+	Created   int            `json:"created"`
+	Updated   int            `json:"updated"`
+	Unchanged int            `json:"unchanged"`
+	Rules     []ImportedRule `json:"rules"`
+	Unmapped  []UnmappedTest `json:"unmapped"`
+}
+
+/* suiteTest is one test read from a suite, before mapping */
+type suiteTest struct {
+	sourceID string
+	name     string // Rule name
+	test     string // Expectation or dbt test name, without package prefix
+	schema   string
+	table    string
+	column   string
+	args     map[string]interface{}
+	refs     map[string]string // dbt source name to schema
+}
+
+/* ImportTests translates a dbt schema YAML file or a Great Expectations JSON suite into quality rules.
+ * Rules are keyed on the test's source ID within the connector, so importing the same suite again updates rules in place. */
+func (s *Service) ImportTests(ctx context.Context, format string, content []byte, opts ImportOptions) (*ImportReport, error) {
+	if opts.ConnectorID == uuid.Nil {
+		return nil, importErrorf("connector_id is required")
+	}
+
+	var tests []suiteTest
+	var unmapped []UnmappedTest
+	var err error
+	switch format {
+	case ImportFormatDbt:
+		tests, unmapped, err = parseDbtTests(content, opts)
+	case ImportFormatGreatExpectations:
+		tests, err = parseExpectationSuite(content, opts)
+	default:
+		return nil, importErrorf("unknown import format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dialect, err := s.importDialect(ctx, opts.ConnectorID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{Format: format, DryRun: opts.DryRun, Rules: []ImportedRule{}, Unmapped: unmapped}
+	if report.Unmapped == nil {
+		report.Unmapped = []UnmappedTest{}
+	}
+	seen := make(map[string]bool)
+	for _, test := range tests {
+		if seen[test.sourceID] {
+			report.Unmapped = append(report.Unmapped, test.unmapped("duplicate test ID in suite"))
+			continue
+		}
+		seen[test.sourceID] = true
+
+		rule, reason := mapTest(test, dialect)
+		if rule == nil {
+			report.Unmapped = append(report.Unmapped, test.unmapped(reason))
+			continue
+		}
+		rule.ConnectorID = &opts.ConnectorID
+		rule.CreatedBy = opts.CreatedBy
+		rule.Enabled = true
+		rule.Metadata["import_format"] = format
+		rule.Metadata["import_test_id"] = test.sourceID
+		rule.Metadata["import_test"] = test.test
+		rule.Metadata["import_args"] = importArgs(test.args)
+
+		imported, err := s.upsertImportedRule(ctx, rule, opts.DryRun)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import %s: %w", test.sourceID, err)
+		}
+		imported.SourceID = test.sourceID
+		switch imported.Action {
+		case importActionCreated:
+			report.Created++
+		case importActionUpdated:
+			report.Updated++
+		default:
+			report.Unchanged++
+		}
+		report.Rules = append(report.Rules, *imported)
+	}
+
+	return report, nil
+}
+
+func (t suiteTest) unmapped(reason string) UnmappedTest {
+	return UnmappedTest{SourceID: t.sourceID, Test: t.test, Table: t.table, Column: t.column, Reason: reason}
+}
+
+/* importDialect returns the SQL dialect of the connector imported rules will run against */
+func (s *Service) importDialect(ctx context.Context, connectorID uuid.UUID) (connectors.QueryDialect, error) {
+	if s.connectorService == nil {
+		return connectors.GetQueryDialect(connectors.ConnectorPostgreSQL)
+	}
+	connector, err := s.connectorService.GetConnector(ctx, connectorID)
+	if err != nil {
+		return connectors.QueryDialect{}, fmt.Errorf("failed to get connector: %w", err)
+	}
+	return connectors.GetQueryDialect(connector.ConnectorType)
+}
+
+/* upsertImportedRule creates the rule for a test, or updates the rule a previous import created for it */
+func (s *Service) upsertImportedRule(ctx context.Context, rule *QualityRule, dryRun bool) (*ImportedRule, error) {
+	imported := &ImportedRule{Name: rule.Name, RuleType: rule.RuleType}
+
+	var existingID uuid.UUID
+	err := s.pool.QueryRow(ctx, `
+		SELECT id FROM neuronip.data_quality_rules
+		WHERE connector_id = $1 AND metadata->>'import_test_id' = $2`,
+		rule.ConnectorID, rule.Metadata["import_test_id"],
+	).Scan(&existingID)
+	if err == pgx.ErrNoRows {
+		imported.Action = importActionCreated
+		if dryRun {
+			return imported, nil
+		}
+		created, err := s.CreateRule(ctx, *rule)
+		if err != nil {
+			return nil, err
+		}
+		imported.RuleID = &created.ID
+		return imported, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find imported rule: %w", err)
+	}
+
+	imported.RuleID = &existingID
+	existing, err := s.GetRule(ctx, existingID)
+	if err != nil {
+		return nil, err
+	}
+	if importSignature(existing) == importSignature(rule) {
+		imported.Action = importActionUnchanged
+		return imported, nil
+	}
+
+	imported.Action = importActionUpdated
+	if dryRun {
+		return imported, nil
+	}
+	// Whether the rule is enabled and its schedule stay as set in NeuronIP
+	metadataJSON, _ := json.Marshal(rule.Metadata)
+	_, err = s.pool.Exec(ctx, `
+		UPDATE neuronip.data_quality_rules
+		SET name = $1, description = $2, rule_type = $3, schema_name = $4, table_name = $5, column_name = $6,
+		    rule_expression = $7, threshold = $8, metadata = $9, updated_at = $10
+		WHERE id = $11`,
+		rule.Name, rule.Description, rule.RuleType, rule.SchemaName, rule.TableName, rule.ColumnName,
+		rule.RuleExpression, rule.Threshold, metadataJSON, time.Now(), existingID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update imported rule: %w", err)
+	}
+	return imported, nil
+}
+
+/* importSignature captures the fields an import sets, to tell whether a re-import changed a rule */
+func importSignature(rule *QualityRule) string {
+	signature, _ := json.Marshal([]interface{}{
+		rule.Name, rule.Description, rule.RuleType, rule.SchemaName, rule.TableName, rule.ColumnName,
+		rule.RuleExpression, rule.Threshold, rule.Metadata,
+	})
+	return string(signature)
+}
+
+/* mapTest translates a test into a rule, or explains why it cannot be */
+func mapTest(test suiteTest, dialect connectors.QueryDialect) (*QualityRule, string) {
+	if config, ok := test.args["config"].(map[string]interface{}); ok && config["where"] != nil {
+		return nil, "tests with a where filter are not supported"
+	}
+
+	rule := &QualityRule{
+		Name:     test.name,
+		Metadata: map[string]interface{}{},
+	}
+	if test.schema != "" {
+		rule.SchemaName = &test.schema
+	}
+	if test.table == "" {
+		return nil, "test has no table"
+	}
+	rule.TableName = &test.table
+	if test.column != "" {
+		rule.ColumnName = &test.column
+	}
+	description := fmt.Sprintf("Imported %s test %s", test.test, test.sourceID)
+	rule.Description = &description
+
+	threshold := 1.0
+	if mostly, ok := test.args["mostly"].(float64); ok && mostly > 0 && mostly <= 1 {
+		threshold = mostly
+	}
+	rule.Threshold = &threshold
+
+	column := dialect.QuoteIdent(test.column)
+	needsColumn := func() string {
+		if test.column == "" {
+			return "test needs a column"
+		}
+		return ""
+	}
+
+	switch test.test {
+	case "not_null", "expect_column_values_to_not_be_null":
+		if reason := needsColumn(); reason != "" {
+			return nil, reason
+		}
+		rule.RuleType = "completeness"
+
+	case "unique", "expect_column_values_to_be_unique":
+		if reason := needsColumn(); reason != "" {
+			return nil, reason
+		}
+		rule.RuleType = "uniqueness"
+
+	case "accepted_values", "expect_column_values_to_be_in_set":
+		if reason := needsColumn(); reason != "" {
+			return nil, reason
+		}
+		values, ok := test.args["values"].([]interface{})
+		if !ok {
+			values, ok = test.args["value_set"].([]interface{})
+		}
+		if !ok || len(values) == 0 {
+			return nil, "accepted values are missing"
+		}
+		quote := true
+		if q, ok := test.args["quote"].(bool); ok {
+			quote = q
+		}
+		literals := make([]string, len(values))
+		for i, v := range values {
+			literal, err := sqlLiteral(dialect, v, quote)
+			if err != nil {
+				return nil, err.Error()
+			}
+			literals[i] = literal
+		}
+		rule.RuleType = "validity"
+		rule.RuleExpression = fmt.Sprintf("%s IN (%s)", column, strings.Join(literals, ", "))
+
+	case "accepted_range", "expect_column_values_to_be_between":
+		if reason := needsColumn(); reason != "" {
+			return nil, reason
+		}
+		var conditions []string
+		for _, bound := range []struct{ key, op, strictKey, strictOp string }{
+			{"min_value", ">=", "strict_min", ">"},
+			{"max_value", "<=", "strict_max", "<"},
+		} {
+			value, ok := test.args[bound.key]
+			if !ok || value == nil {
+				continue
+			}
+			literal, err := sqlLiteral(dialect, value, true)
+			if err != nil {
+				return nil, err.Error()
+			}
+			op := bound.op
+			if strict, _ := test.args[bound.strictKey].(bool); strict {
+				op = bound.strictOp
+			}
+			// dbt_utils.accepted_range uses inclusive: false instead of strict bounds
+			if inclusive, ok := test.args["inclusive"].(bool); ok && !inclusive {
+				op = bound.strictOp
+			}
+			conditions = append(conditions, fmt.Sprintf("%s %s %s", column, op, literal))
+		}
+		if len(conditions) == 0 {
+			return nil, "range has no min_value or max_value"
+		}
+		rule.RuleType = "validity"
+		rule.RuleExpression = strings.Join(conditions, " AND ")
+
+	case "expect_column_values_to_match_regex":
+		if reason := needsColumn(); reason != "" {
+			return nil, reason
+		}
+		pattern, _ := test.args["regex"].(string)
+		if pattern == "" {
+			return nil, "regex is missing"
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Sprintf("invalid regex: %v", err)
+		}
+		expression, reason := regexMatch(dialect, column, pattern)
+		if expression == "" {
+			return nil, reason
+		}
+		rule.RuleType = "validity"
+		rule.RuleExpression = expression
+
+	case "relationships":
+		if reason := needsColumn(); reason != "" {
+			return nil, reason
+		}
+		to, _ := test.args["to"].(string)
+		field, _ := test.args["field"].(string)
+		schema, table, ok := parseDbtRelation(to, test.refs)
+		if !ok || field == "" {
+			return nil, "relationships test needs to: ref(...) or source(...) and field"
+		}
+		if schema == "" {
+			schema = test.schema
+		}
+		rule.RuleType = "consistency"
+		rule.Metadata["consistency_type"] = ConsistencyReferentialIntegrity
+		rule.Metadata["reference_schema"] = schema
+		rule.Metadata["reference_table"] = table
+		rule.Metadata["reference_column"] = field
+
+	case "expect_table_row_count_to_be_between", "expect_table_row_count_to_equal":
+		rule.ColumnName = nil
+		if value, ok := test.args["value"].(float64); ok {
+			rule.Metadata["min_row_count"] = value
+			rule.Metadata["max_row_count"] = value
+		}
+		if value, ok := test.args["min_value"].(float64); ok {
+			rule.Metadata["min_row_count"] = value
+		}
+		if value, ok := test.args["max_value"].(float64); ok {
+			rule.Metadata["max_row_count"] = value
+		}
+		if rule.Metadata["min_row_count"] == nil && rule.Metadata["max_row_count"] == nil {
+			return nil, "row count range has no numeric bounds"
+		}
+		rule.RuleType = "completeness"
+
+	default:
+		return nil, "no equivalent quality rule"
+	}
+
+	return rule, ""
+}
+
+/* isImportedRule reports whether a rule was imported from a dbt or Great Expectations suite */
+func isImportedRule(rule *QualityRule) bool {
+	return metaString(rule.Metadata, "import_test") != ""
+}
+
+/* importArgs returns test arguments as stored in rule metadata. Dates become the text sqlLiteral renders them as,
+ * so an expression rendered again from metadata matches the one rendered at import. */
+func importArgs(args map[string]interface{}) map[string]interface{} {
+	stored := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		stored[k] = importArg(v)
+	}
+	return stored
+}
+
+func importArg(value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05")
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = importArg(item)
+		}
+		return converted
+	}
+	return value
+}
+
+/* importedExpression returns the rule expression for the dialect of the table the rule runs on. An imported
+ * test's expression is rendered again from its stored test and arguments, since the stored expression is quoted
+ * for the connector's dialect and a staged batch is on the NeuronIP database. */
+func importedExpression(rule *QualityRule, dialect connectors.QueryDialect) (string, error) {
+	if !isImportedRule(rule) {
+		return rule.RuleExpression, nil
+	}
+	test := suiteTest{test: metaString(rule.Metadata, "import_test")}
+	test.args, _ = rule.Metadata["import_args"].(map[string]interface{})
+	if rule.TableName != nil {
+		test.table = *rule.TableName
+	}
+	if rule.ColumnName != nil {
+		test.column = *rule.ColumnName
+	}
+	mapped, reason := mapTest(test, dialect)
+	if mapped == nil {
+		return "", fmt.Errorf("imported %s test cannot run on %s: %s", test.test, dialect.Driver, reason)
+	}
+	return mapped.RuleExpression, nil
+}
+
+/* sqlLiteral renders a suite value as a SQL literal; unquoted strings are inserted as written, as dbt does with quote: false */
+func sqlLiteral(dialect connectors.QueryDialect, value interface{}, quote bool) (string, error) {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case string:
+		if !quote {
+			return v, nil
+		}
+		return dialect.QuoteString(v), nil
+	case time.Time:
+		return dialect.QuoteString(v.Format("2006-01-02 15:04:05")), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", value)
+}
+
+/* regexMatch renders a regular expression match for the dialect */
+func regexMatch(dialect connectors.QueryDialect, column, pattern string) (string, string) {
+	switch dialect.Driver {
+	case "postgres":
+		return fmt.Sprintf("%s ~ %s", column, dialect.QuoteString(pattern)), ""
+	case "mysql":
+		return fmt.Sprintf("%s REGEXP %s", column, dialect.QuoteString(pattern)), ""
+	case "snowflake":
+		return fmt.Sprintf("REGEXP_LIKE(%s, %s)", column, dialect.QuoteString(pattern)), ""
+	}
+	return "", fmt.Sprintf("regular expressions are not supported by %s", dialect.Driver)
+}
+
+var dbtRelationPattern = regexp.MustCompile(`^\s*(ref|source)\(\s*(.*?)\s*\)\s*$`)
+
+/* parseDbtRelation reads ref('model') or source('source', 'table') into a schema and table */
+func parseDbtRelation(relation string, sourceSchemas map[string]string) (string, string, bool) {
+	match := dbtRelationPattern.FindStringSubmatch(relation)
+	if match == nil {
+		return "", "", false
+	}
+	var args []string
+	for _, arg := range strings.Split(match[2], ",") {
+		if arg = strings.Trim(strings.TrimSpace(arg), `'"`); arg != "" {
+			args = append(args, arg)
+		}
+	}
+	if len(args) == 0 {
+		return "", "", false
+	}
+	if match[1] == "ref" {
+		return "", args[len(args)-1], true
+	}
+	if len(args) != 2 {
+		return "", "", false
+	}
+	schema, ok := sourceSchemas[args[0]]
+	if !ok {
+		schema = args[0]
+	}
+	return schema, args[1], true
+}
+
+/* dbtSchemaFile is the part of a dbt properties YAML file that declares tests */
+type dbtSchemaFile struct {
+	Models    []dbtNode `yaml:"models"`
+	Seeds     []dbtNode `yaml:"seeds"`
+	Snapshots []dbtNode `yaml:"snapshots"`
+	Sources   []struct {
+		Name   string    `yaml:"name"`
+		Schema string    `yaml:"schema"`
+		Tables []dbtNode `yaml:"tables"`
+	} `yaml:"sources"`
+}
+
+type dbtNode struct {
+	Name       string `yaml:"name"`
+	Identifier string `yaml:"identifier"`
+	Config     struct {
+		Alias  string `yaml:"alias"`
+		Schema string `yaml:"schema"`
+	} `yaml:"config"`
+	Tests     []interface{} `yaml:"tests"`
+	DataTests []interface{} `yaml:"data_tests"`
+	Columns   []struct {
+		Name      string        `yaml:"name"`
+		Tests     []interface{} `yaml:"tests"`
+		DataTests []interface{} `yaml:"data_tests"`
+	} `yaml:"columns"`
+}
+
+/* parseDbtTests reads the generic tests of every model, seed, snapshot and source table in a dbt YAML file.
+ * A test's source ID is its name: or test_name-given name when set, otherwise dbt's default <test>_<node>_<column>. */
+func parseDbtTests(content []byte, opts ImportOptions) ([]suiteTest, []UnmappedTest, error) {
+	var file dbtSchemaFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, nil, importErrorf("invalid dbt YAML: %v", err)
+	}
+
+	sourceSchemas := make(map[string]string)
+	for _, source := range file.Sources {
+		schema := source.Schema
+		if schema == "" {
+			schema = source.Name
+		}
+		sourceSchemas[source.Name] = schema
+	}
+
+	var tests []suiteTest
+	var unmapped []UnmappedTest
+	addNode := func(source string, node dbtNode, schema, table string) {
+		if table == "" {
+			table = node.Name
+		}
+		add := func(column string, entries []interface{}) {
+			for _, entry := range entries {
+				test, err := parseDbtTest(entry)
+				if err != nil {
+					unmapped = append(unmapped, UnmappedTest{SourceID: "dbt:" + dbtTestName("", source, node.Name, column), Table: table, Column: column, Reason: err.Error()})
+					continue
+				}
+				test.schema, test.table, test.refs = schema, table, sourceSchemas
+				test.column = column
+				if c, ok := test.args["column_name"].(string); ok && column == "" {
+					test.column = c
+				}
+				if test.name == "" {
+					test.name = dbtTestName(test.test, source, node.Name, test.column)
+				}
+				test.sourceID = "dbt:" + test.name
+				tests = append(tests, test)
+			}
+		}
+		add("", append(node.Tests, node.DataTests...))
+		for _, column := range node.Columns {
+			add(column.Name, append(column.Tests, column.DataTests...))
+		}
+	}
+
+	for _, group := range [][]dbtNode{file.Models, file.Seeds, file.Snapshots} {
+		for _, node := range group {
+			schema := node.Config.Schema
+			if schema == "" {
+				schema = opts.SchemaName
+			}
+			addNode("", node, schema, node.Config.Alias)
+		}
+	}
+	for _, source := range file.Sources {
+		for _, node := range source.Tables {
+			addNode(source.Name, node, sourceSchemas[source.Name], node.Identifier)
+		}
+	}
+
+	return tests, unmapped, nil
+}
+
+/* parseDbtTest reads a test entry: "unique", {accepted_values: {values: [...]}} or {test_name: unique, name: ...} */
+func parseDbtTest(entry interface{}) (suiteTest, error) {
+	switch value := yamlValue(entry).(type) {
+	case string:
+		return suiteTest{test: stripPackage(value), args: map[string]interface{}{}}, nil
+	case map[string]interface{}:
+		if testName, ok := value["test_name"].(string); ok {
+			args := make(map[string]interface{}, len(value))
+			for k, v := range value {
+				args[k] = v
+			}
+			if nested, ok := value["arguments"].(map[string]interface{}); ok {
+				for k, v := range nested {
+					args[k] = v
+				}
+			}
+			name, _ := value["name"].(string)
+			return suiteTest{test: stripPackage(testName), name: name, args: args}, nil
+		}
+		if len(value) != 1 {
+			return suiteTest{}, fmt.Errorf("test entry must have a single test name")
+		}
+		for testName, raw := range value {
+			args, _ := raw.(map[string]interface{})
+			if args == nil {
+				args = map[string]interface{}{}
+			}
+			if nested, ok := args["arguments"].(map[string]interface{}); ok {
+				for k, v := range nested {
+					args[k] = v
+				}
+			}
+			name, _ := args["name"].(string)
+			return suiteTest{test: stripPackage(testName), name: name, args: args}, nil
+		}
+	}
+	return suiteTest{}, fmt.Errorf("unrecognised test entry")
+}
+
+/* yamlValue converts YAML integers to float64, the type JSON-decoded suites and rule metadata use for numbers */
+func yamlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			converted[k] = yamlValue(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = yamlValue(item)
+		}
+		return converted
+	}
+	return value
+}
+
+/* stripPackage drops a dbt package prefix, so dbt_expectations tests map like Great Expectations ones */
+func stripPackage(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+/* dbtTestName is dbt's default name for a generic test, e.g. not_null_orders_id or source_unique_raw_orders_id */
+func dbtTestName(test, source, node, column string) string {
+	parts := []string{test}
+	if source != "" {
+		parts = []string{"source_" + test, source}
+	}
+	parts = append(parts, node)
+	if column != "" {
+		parts = append(parts, column)
+	}
+	return strings.Join(parts, "_")
+}
+
+/* expectationSuite is a Great Expectations suite in either the classic or the 1.x JSON layout */
+type expectationSuite struct {
+	SuiteName    string `json:"expectation_suite_name"`
+	Name         string `json:"name"`
+	Expectations []struct {
+		ID              string                 `json:"id"`
+		ExpectationType string                 `json:"expectation_type"`
+		Type            string                 `json:"type"`
+		Kwargs          map[string]interface{} `json:"kwargs"`
+	} `json:"expectations"`
+}
+
+/* parseExpectationSuite reads the expectations of a suite. A source ID is the expectation's id when GE assigned one,
+ * otherwise the suite, expectation type and arguments, so the same expectation always maps to the same rule. */
+func parseExpectationSuite(content []byte, opts ImportOptions) ([]suiteTest, error) {
+	var suite expectationSuite
+	if err := json.Unmarshal(content, &suite); err != nil {
+		return nil, importErrorf("invalid expectation suite: %v", err)
+	}
+	name := suite.SuiteName
+	if name == "" {
+		name = suite.Name
+	}
+	if name == "" {
+		return nil, importErrorf("expectation suite has no name")
+	}
+	table := opts.TableName
+	if table == "" {
+		table, _, _ = strings.Cut(name, ".")
+	}
+
+	tests := make([]suiteTest, 0, len(suite.Expectations))
+	for _, expectation := range suite.Expectations {
+		kind := expectation.ExpectationType
+		if kind == "" {
+			kind = expectation.Type
+		}
+		args := expectation.Kwargs
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		column, _ := args["column"].(string)
+
+		sourceID := expectation.ID
+		if sourceID == "" {
+			// Map keys marshal in sorted order, so the arguments render the same way every time
+			canonical, _ := json.Marshal(args)
+			sourceID = fmt.Sprintf("%s:%s:%s", name, kind, canonical)
+		}
+		tests = append(tests, suiteTest{
+			sourceID: "ge:" + sourceID,
+			name:     strings.TrimSpace(fmt.Sprintf("%s %s %s", name, kind, column)),
+			test:     kind,
+			schema:   opts.SchemaName,
+			table:    table,
+			column:   column,
+			args:     args,
+		})
+	}
+	return tests, nil
+}
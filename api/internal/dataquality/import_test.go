@@ -0,0 +1,115 @@
+package dataquality
+
+import (
+	"testing"
+
+	"github.com/neurondb/NeuronIP/api/internal/connectors"
+)
+
+func queryDialect(t *testing.T, connectorType connectors.ConnectorType) connectors.QueryDialect {
+	t.Helper()
+	dialect, err := connectors.GetQueryDialect(connectorType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dialect
+}
+
+/* TestMapTest checks how suite tests translate into rules */
+func TestMapTest(t *testing.T) {
+	postgres := queryDialect(t, connectors.ConnectorPostgreSQL)
+
+	tests := []struct {
+		name       string
+		test       suiteTest
+		ruleType   string
+		expression string
+		unmapped   bool
+	}{
+		{name: "not null", test: suiteTest{test: "not_null", table: "orders", column: "id"}, ruleType: "completeness"},
+		{name: "unique", test: suiteTest{test: "expect_column_values_to_be_unique", table: "orders", column: "id"}, ruleType: "uniqueness"},
+		{
+			name:       "accepted values",
+			test:       suiteTest{test: "accepted_values", table: "orders", column: "status", args: map[string]interface{}{"values": []interface{}{"open", "it's shipped"}}},
+			ruleType:   "validity",
+			expression: `"status" IN ('open', 'it''s shipped')`,
+		},
+		{
+			name:       "unquoted accepted values",
+			test:       suiteTest{test: "accepted_values", table: "orders", column: "qty", args: map[string]interface{}{"values": []interface{}{float64(1), float64(2)}, "quote": false}},
+			ruleType:   "validity",
+			expression: `"qty" IN (1, 2)`,
+		},
+		{
+			name:       "strict range",
+			test:       suiteTest{test: "expect_column_values_to_be_between", table: "orders", column: "qty", args: map[string]interface{}{"min_value": float64(0), "max_value": float64(10), "strict_min": true}},
+			ruleType:   "validity",
+			expression: `"qty" > 0 AND "qty" <= 10`,
+		},
+		{
+			name:       "regex",
+			test:       suiteTest{test: "expect_column_values_to_match_regex", table: "orders", column: "code", args: map[string]interface{}{"regex": "^[A-Z]{3}$"}},
+			ruleType:   "validity",
+			expression: `"code" ~ '^[A-Z]{3}$'`,
+		},
+		{name: "where filter", test: suiteTest{test: "not_null", table: "orders", column: "id", args: map[string]interface{}{"config": map[string]interface{}{"where": "id > 0"}}}, unmapped: true},
+		{name: "missing column", test: suiteTest{test: "unique", table: "orders"}, unmapped: true},
+		{name: "unknown test", test: suiteTest{test: "expect_column_kl_divergence_to_be_less_than", table: "orders", column: "id"}, unmapped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, reason := mapTest(tt.test, postgres)
+			if tt.unmapped {
+				if rule != nil {
+					t.Fatalf("expected the test to be unmapped, got %+v", rule)
+				}
+				return
+			}
+			if rule == nil {
+				t.Fatalf("expected a rule, got %q", reason)
+			}
+			if rule.RuleType != tt.ruleType {
+				t.Errorf("expected rule type %s, got %s", tt.ruleType, rule.RuleType)
+			}
+			if rule.RuleExpression != tt.expression {
+				t.Errorf("expected expression %q, got %q", tt.expression, rule.RuleExpression)
+			}
+		})
+	}
+}
+
+/* TestImportedExpression checks that an imported test is rendered for the dialect it runs on, not the one it was imported for */
+func TestImportedExpression(t *testing.T) {
+	mysql := queryDialect(t, connectors.ConnectorMySQL)
+	postgres := queryDialect(t, connectors.ConnectorPostgreSQL)
+
+	test := suiteTest{test: "accepted_values", table: "orders", column: "status", args: map[string]interface{}{"values": []interface{}{"open"}}}
+	rule, reason := mapTest(test, mysql)
+	if rule == nil {
+		t.Fatal(reason)
+	}
+	rule.Metadata["import_test"] = test.test
+	rule.Metadata["import_args"] = importArgs(test.args)
+
+	for _, tc := range []struct {
+		dialect connectors.QueryDialect
+		want    string
+	}{
+		{dialect: mysql, want: "`status` IN ('open')"},
+		{dialect: postgres, want: `"status" IN ('open')`},
+	} {
+		got, err := importedExpression(rule, tc.dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.dialect.Driver, tc.want, got)
+		}
+	}
+
+	// Rules written in NeuronIP keep their expression as written
+	written := &QualityRule{RuleExpression: "end_date >= start_date", Metadata: map[string]interface{}{}}
+	if got, _ := importedExpression(written, postgres); got != written.RuleExpression {
+		t.Errorf("expected the written expression, got %q", got)
+	}
+}
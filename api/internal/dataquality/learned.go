@@ -0,0 +1,236 @@
+package dataquality
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neurondb/NeuronIP/api/internal/anomaly"
+	"github.com/neurondb/NeuronIP/api/internal/freshness"
+)
+
+/* ThresholdModeLearnedBand, set in rule metadata as threshold_mode, grades a rule against a band learned from its history
+ * instead of its static threshold: the check score for most rules, the lag for max lag timeliness rules */
+const ThresholdModeLearnedBand = "learned_band"
+
+const defaultLearnedLagMinutes = 60
+
+func learnedThreshold(rule *QualityRule) bool {
+	return metaString(rule.Metadata, "threshold_mode") == ThresholdModeLearnedBand
+}
+
+/* gradeAgainstBand grades a check score by the band learned from the rule's earlier scores.
+ * Only a score below the band fails; a better than expected score passes. Until there is enough history,
+ * or when the history cannot be read, the static grade stands. */
+func (s *Service) gradeAgainstBand(ctx context.Context, rule *QualityRule, score float64, status string, at time.Time) (string, *anomaly.Band) {
+	if rule.RuleType == "timeliness" {
+		// Timeliness rules band the lag itself; their score is pass or fail
+		return status, nil
+	}
+	opts := anomaly.BandOptionsFromConfig(rule.Metadata).Bounded(0, 100)
+	band, err := s.scoreBand(ctx, rule.ID, at, opts)
+	if err != nil {
+		return status, nil
+	}
+	band.History = nil
+	if band.Evaluate(score).Direction == "below" {
+		return "fail", band
+	}
+	return "pass", band
+}
+
+/* scoreBand learns the band of a rule's check scores for the day of at */
+func (s *Service) scoreBand(ctx context.Context, ruleID uuid.UUID, at time.Time, opts anomaly.BandOptions) (*anomaly.Band, error) {
+	since := at.AddDate(0, 0, -opts.Days())
+	rows, err := s.pool.Query(ctx, `
+		SELECT executed_at, score FROM neuronip.data_quality_checks
+		WHERE rule_id = $1 AND status IN ('pass', 'warning', 'fail') AND executed_at >= $2 AND executed_at < $3
+		ORDER BY executed_at ASC`, ruleID, since, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get score history: %w", err)
+	}
+	defer rows.Close()
+
+	points := []anomaly.Point{}
+	for rows.Next() {
+		var p anomaly.Point
+		if err := rows.Scan(&p.Time, &p.Value); err != nil {
+			continue
+		}
+		points = append(points, p)
+	}
+	return anomaly.LearnBand(points, at, opts)
+}
+
+/* rowCountSeries is the series of a table's row counts */
+func rowCountSeries(t *tableSource) anomaly.Series {
+	database := "neuronip"
+	if t.connectorID != nil {
+		database = t.connectorID.String()
+	}
+	return anomaly.Series{Kind: anomaly.SeriesRowCount, Key: fmt.Sprintf("%s/%s.%s", database, t.schema, t.table)}
+}
+
+/* observeRowCount records a table's row count and judges it against the band learned from earlier days.
+ * It returns no evaluation until the table has enough history. */
+func (s *Service) observeRowCount(ctx context.Context, rule *QualityRule, t *tableSource, rows int64) (*anomaly.Evaluation, *anomaly.Band, error) {
+	opts := anomaly.BandOptionsFromConfig(rule.Metadata).NonNegative()
+	band, eval, err := s.bands.Observe(ctx, rowCountSeries(t), float64(rows), time.Now(), opts)
+	if errors.Is(err, anomaly.ErrInsufficientHistory) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return eval, band, nil
+}
+
+/* checkLagBand judges a monitor's current lag against the band learned from its earlier lags.
+ * Only a lag above the band is a violation. learned is false until the lag has enough history. */
+func (s *Service) checkLagBand(ctx context.Context, rule *QualityRule, monitor *freshness.FreshnessMonitor, latest, now time.Time) (violation *QualityViolation, learned bool, err error) {
+	opts := anomaly.BandOptionsFromConfig(rule.Metadata).NonNegative()
+	band, err := s.bands.LearnBand(ctx, freshness.LagSeries(monitor.ID), now, opts)
+	if errors.Is(err, anomaly.ErrInsufficientHistory) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	eval := band.Evaluate(now.Sub(latest).Minutes())
+	if eval.Direction != "above" {
+		return nil, true, nil
+	}
+	return &QualityViolation{
+		ColumnValue:      latest.UTC().Format(time.RFC3339),
+		ViolationType:    TimelinessMaxLag,
+		ViolationMessage: fmt.Sprintf("Lag of %s in minutes: %s. %s", monitor.TimestampColumn, eval.Describe(), band.Explanation),
+		Severity:         bandSeverity(eval),
+	}, true, nil
+}
+
+/* bandSeverity rates how far outside its band a value is */
+func bandSeverity(eval anomaly.Evaluation) string {
+	if math.Abs(eval.Deviation) >= 6 {
+		return "high"
+	}
+	return "medium"
+}
+
+/* QualityTrendBand is a quality trend with the band learned from it, explaining whether the latest day is expected */
+type QualityTrendBand struct {
+	Level  string              `json:"level"`
+	Trends []QualityTrendPoint `json:"trends"`
+	Band   *anomaly.Band       `json:"band,omitempty"`   // Band for the latest day, learned from the days before it
+	Latest *anomaly.Evaluation `json:"latest,omitempty"` // Latest day's score judged against the band
+	Note   string              `json:"note,omitempty"`
+}
+
+/* GetQualityTrendBand learns the band of a quality trend and judges its latest day against it */
+func (s *Service) GetQualityTrendBand(ctx context.Context, level string, days int, opts anomaly.BandOptions) (*QualityTrendBand, error) {
+	trends, err := s.GetQualityTrends(ctx, level, days)
+	if err != nil {
+		return nil, err
+	}
+	result := &QualityTrendBand{Level: level, Trends: trends}
+	if len(trends) == 0 {
+		result.Note = "No quality scores in the period"
+		return result, nil
+	}
+
+	points := make([]anomaly.Point, len(trends))
+	for i, point := range trends {
+		points[i] = anomaly.Point{Time: point.Date, Value: point.Score}
+	}
+	latest := trends[len(trends)-1]
+	band, err := anomaly.LearnBand(points, latest.Date, opts.Bounded(0, 100))
+	if errors.Is(err, anomaly.ErrInsufficientHistory) {
+		result.Note = err.Error()
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	eval := band.Evaluate(latest.Score)
+	result.Band = band
+	result.Latest = &eval
+	return result, nil
+}
+
+/* RuleBand is the learned band a rule is graded against today */
+type RuleBand struct {
+	RuleID  uuid.UUID     `json:"rule_id"`
+	Measure string        `json:"measure"` // score, row_count or freshness_lag
+	Band    *anomaly.Band `json:"band,omitempty"`
+	Note    string        `json:"note,omitempty"`
+}
+
+/* GetRuleBand returns the learned band of a rule with threshold_mode learned_band or row_count_band, for explaining its grade */
+func (s *Service) GetRuleBand(ctx context.Context, ruleID uuid.UUID) (*RuleBand, error) {
+	rule, err := s.GetRule(ctx, ruleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rule: %w", err)
+	}
+	opts := anomaly.BandOptionsFromConfig(rule.Metadata)
+	now := time.Now()
+	result := &RuleBand{RuleID: rule.ID, Measure: "score"}
+
+	var band *anomaly.Band
+	switch {
+	case rule.RuleType == "completeness" && metaBool(rule.Metadata, "row_count_band") && rule.TableName != nil:
+		result.Measure = anomaly.SeriesRowCount
+		t, openErr := s.ruleTable(ctx, rule)
+		if openErr != nil {
+			return nil, openErr
+		}
+		t.close()
+		band, err = s.bands.LearnBand(ctx, rowCountSeries(t), now, opts.NonNegative())
+	case rule.RuleType == "timeliness" && learnedThreshold(rule):
+		result.Measure = anomaly.SeriesFreshnessLag
+		monitor, findErr := s.ruleMonitor(ctx, rule)
+		if findErr != nil {
+			return nil, findErr
+		}
+		if monitor == nil {
+			result.Note = "The rule has not run yet, so its freshness monitor has no lag history"
+			return result, nil
+		}
+		band, err = s.bands.LearnBand(ctx, freshness.LagSeries(monitor.ID), now, opts.NonNegative())
+	case learnedThreshold(rule):
+		band, err = s.scoreBand(ctx, rule.ID, now, opts.Bounded(0, 100))
+	default:
+		return nil, fmt.Errorf("rule does not use a learned band; set threshold_mode to %s or row_count_band", ThresholdModeLearnedBand)
+	}
+	if errors.Is(err, anomaly.ErrInsufficientHistory) {
+		result.Note = err.Error()
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Band = band
+	return result, nil
+}
+
+/* ruleMonitor finds the freshness monitor a timeliness rule checks, or nil before the rule first creates it */
+func (s *Service) ruleMonitor(ctx context.Context, rule *QualityRule) (*freshness.FreshnessMonitor, error) {
+	if id := metaString(rule.Metadata, "freshness_monitor_id"); id != "" {
+		monitorID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid freshness_monitor_id: %w", err)
+		}
+		return s.freshness.GetMonitor(ctx, monitorID)
+	}
+	if rule.TableName == nil || rule.ColumnName == nil {
+		return nil, fmt.Errorf("table_name and column_name required for max lag rule")
+	}
+	t, err := s.ruleTable(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	t.close()
+	return s.freshness.FindMonitor(ctx, t.connectorID, t.schema, t.table, *rule.ColumnName)
+}
@@ -5,11 +5,15 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/neurondb/NeuronIP/api/internal/agent"
+	"github.com/neurondb/NeuronIP/api/internal/anomaly"
+	"github.com/neurondb/NeuronIP/api/internal/connectors"
+	"github.com/neurondb/NeuronIP/api/internal/freshness"
 	"github.com/neurondb/NeuronIP/api/internal/mcp"
 	"github.com/neurondb/NeuronIP/api/internal/neurondb"
 )
@@ -20,6 +24,9 @@ type Service struct {
 	neurondbClient *neurondb.Client
 	mcpClient      *mcp.Client
 	agentClient    *agent.Client
+	connectorService *connectors.ConnectorService
+	freshness      *freshness.Service
+	bands          *anomaly.Service
 }
 
 /* NewService creates a new data quality service */
@@ -27,6 +34,8 @@ func NewService(pool *pgxpool.Pool, neurondbClient *neurondb.Client) *Service {
 	return &Service{
 		pool:           pool,
 		neurondbClient: neurondbClient,
+		freshness:      freshness.NewService(pool),
+		bands:          anomaly.NewService(pool),
 	}
 }
 
@@ -37,6 +46,8 @@ func NewServiceWithMCPAndAgent(pool *pgxpool.Pool, neurondbClient *neurondb.Clie
 		neurondbClient: neurondbClient,
 		mcpClient:      mcpClient,
 		agentClient:    agentClient,
+		freshness:      freshness.NewService(pool),
+		bands:          anomaly.NewService(pool),
 	}
 }
 
@@ -58,6 +69,9 @@ type QualityRule struct {
 	CreatedBy    *string                `json:"created_by,omitempty"`
 	CreatedAt    time.Time              `json:"created_at"`
 	UpdatedAt    time.Time              `json:"updated_at"`
+
+	staged       bool   // Set on copies retargeted at a staged batch
+	stagedTarget string // Quoted table the staged batch is committed to
 }
 
 /* CreateRule creates a new quality rule */
@@ -172,24 +186,25 @@ func (s *Service) ExecuteRule(ctx context.Context, ruleID uuid.UUID) (*QualityCh
 
 	// Update check with results
 	executionTime := int(time.Since(startTime).Milliseconds())
-	status := "pass"
-	if score < 100 {
-		if rule.Threshold != nil && score < *rule.Threshold*100 {
-			status = "fail"
-		} else {
-			status = "warning"
-		}
+	status := checkStatus(rule, score)
+	var band *anomaly.Band
+	if learnedThreshold(rule) {
+		status, band = s.gradeAgainstBand(ctx, rule, score, status, startTime)
+	}
+	var bandJSON []byte
+	if band != nil {
+		bandJSON, _ = json.Marshal(band)
 	}
 
 	updateQuery := `
 		UPDATE neuronip.data_quality_checks
 		SET status = $1, score = $2, passed_count = $3, failed_count = $4,
-		    total_count = $5, execution_time_ms = $6
-		WHERE id = $7`
+		    total_count = $5, execution_time_ms = $6, band = $7
+		WHERE id = $8`
 
 	_, err = s.pool.Exec(ctx, updateQuery,
 		status, score, result.PassedCount, result.FailedCount,
-		result.TotalCount, executionTime, checkID,
+		result.TotalCount, executionTime, bandJSON, checkID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update check: %w", err)
@@ -213,6 +228,7 @@ func (s *Service) ExecuteRule(ctx context.Context, ruleID uuid.UUID) (*QualityCh
 		TotalCount:    result.TotalCount,
 		ExecutionTimeMs: executionTime,
 		ExecutedAt:    time.Now(),
+		Band:          band,
 	}, nil
 }
 
@@ -231,6 +247,7 @@ type QualityViolation struct {
 	ViolationType  string
 	ViolationMessage string
 	Severity       string
+	SampleKeys     []string // Keys of sample offending rows for rules that check sets of rows
 }
 
 /* executeRuleByType executes rule based on rule type */
@@ -259,6 +276,21 @@ func (s *Service) executeCompletenessRule(ctx context.Context, rule *QualityRule
 		return nil, fmt.Errorf("connector_id and table_name required for completeness rule")
 	}
 
+	// Row count bounds, static or learned, make this a check of the table's volume
+	if _, ok := rule.Metadata["min_row_count"]; ok {
+		return s.checkRowCount(ctx, rule)
+	}
+	if _, ok := rule.Metadata["max_row_count"]; ok {
+		return s.checkRowCount(ctx, rule)
+	}
+	if metaBool(rule.Metadata, "row_count_band") {
+		return s.checkRowCount(ctx, rule)
+	}
+	// Imported tests and staged batches are read through the table's connector
+	if rule.staged || isImportedRule(rule) {
+		return s.checkNullRows(ctx, rule)
+	}
+
 	// Get connector
 	// For now, assume PostgreSQL and execute directly
 	// In production, use connector service to get connection
@@ -383,16 +415,6 @@ func (s *Service) executeAccuracyRule(ctx context.Context, rule *QualityRule) (*
 	}, nil
 }
 
-/* executeConsistencyRule executes consistency rule */
-func (s *Service) executeConsistencyRule(ctx context.Context, rule *QualityRule) (*RuleExecutionResult, error) {
-	return &RuleExecutionResult{
-		PassedCount: 0,
-		FailedCount: 0,
-		TotalCount:  0,
-		Violations:  []QualityViolation{},
-	}, nil
-}
-
 /* executeValidityRule executes validity rule using ML classification if model specified */
 func (s *Service) executeValidityRule(ctx context.Context, rule *QualityRule) (*RuleExecutionResult, error) {
 	if rule.ConnectorID == nil || rule.TableName == nil || rule.ColumnName == nil {
@@ -468,6 +490,11 @@ func (s *Service) executeValidityRule(ctx context.Context, rule *QualityRule) (*
 		}, nil
 	}
 
+	// Without a model, rule_expression is a condition every row must meet
+	if strings.TrimSpace(rule.RuleExpression) != "" {
+		return s.checkRowCondition(ctx, rule, "invalid_value")
+	}
+
 	// Fallback to basic validation without ML
 	return &RuleExecutionResult{
 		PassedCount: 0,
@@ -482,6 +509,9 @@ func (s *Service) executeUniquenessRule(ctx context.Context, rule *QualityRule)
 	if rule.ConnectorID == nil || rule.TableName == nil || rule.ColumnName == nil {
 		return nil, fmt.Errorf("connector_id, table_name, and column_name required for uniqueness rule")
 	}
+	if rule.staged || isImportedRule(rule) {
+		return s.checkDuplicateRows(ctx, rule)
+	}
 
 	schema := "public"
 	if rule.SchemaName != nil {
@@ -515,16 +545,6 @@ func (s *Service) executeUniquenessRule(ctx context.Context, rule *QualityRule)
 	}, nil
 }
 
-/* executeTimelinessRule executes timeliness rule */
-func (s *Service) executeTimelinessRule(ctx context.Context, rule *QualityRule) (*RuleExecutionResult, error) {
-	return &RuleExecutionResult{
-		PassedCount: 0,
-		FailedCount: 0,
-		TotalCount:  0,
-		Violations:  []QualityViolation{},
-	}, nil
-}
-
 /* executeCustomRule executes custom rule */
 func (s *Service) executeCustomRule(ctx context.Context, rule *QualityRule) (*RuleExecutionResult, error) {
 	if rule.RuleExpression == "" {
@@ -621,6 +641,17 @@ func (s *Service) executeCustomRule(ctx context.Context, rule *QualityRule) (*Ru
 	return &result, nil
 }
 
+/* checkStatus grades a score against the rule threshold: below it fails, below 100 warns */
+func checkStatus(rule *QualityRule, score float64) string {
+	if score >= 100 {
+		return "pass"
+	}
+	if rule.Threshold != nil && score < *rule.Threshold*100 {
+		return "fail"
+	}
+	return "warning"
+}
+
 /* calculateScore calculates quality score from result */
 func (s *Service) calculateScore(result *RuleExecutionResult) float64 {
 	if result.TotalCount == 0 {
@@ -643,12 +674,16 @@ func (s *Service) storeViolations(ctx context.Context, checkID, ruleID uuid.UUID
 		query := `
 			INSERT INTO neuronip.data_quality_violations
 			(id, check_id, rule_id, row_identifier, column_value, violation_type,
-			 violation_message, severity, created_at)
-			VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, NOW())`
+			 violation_message, severity, sample_keys, created_at)
+			VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, $8, NOW())`
 
+		sampleKeysJSON, _ := json.Marshal(violation.SampleKeys)
+		if violation.SampleKeys == nil {
+			sampleKeysJSON = []byte("[]")
+		}
 		s.pool.Exec(ctx, query,
 			checkID, ruleID, violation.RowIdentifier, violation.ColumnValue,
-			violation.ViolationType, violation.ViolationMessage, violation.Severity,
+			violation.ViolationType, violation.ViolationMessage, violation.Severity, sampleKeysJSON,
 		)
 	}
 }
@@ -782,6 +817,7 @@ type QualityCheck struct {
 	TotalCount      int64      `json:"total_count"`
 	ExecutionTimeMs int        `json:"execution_time_ms"`
 	ExecutedAt      time.Time  `json:"executed_at"`
+	Band            *anomaly.Band `json:"band,omitempty"` // Learned band the score was graded against
 }
 
 /* QualityDashboard represents aggregated quality metrics */
@@ -1171,11 +1207,10 @@ func (s *Service) ValidateDataQualityWithML(ctx context.Context, tableName strin
 				for _, outlier := range outlierList {
 					if outlierMap, ok := outlier.(map[string]interface{}); ok {
 						violations = append(violations, QualityViolation{
-							RuleID:     uuid.New(),
-							Value:      fmt.Sprintf("%v", outlierMap["value"]),
-							Severity:   "medium",
-							Message:    "MCP outlier detection identified anomaly",
-							DetectedAt: time.Now(),
+							ColumnValue:      fmt.Sprintf("%v", outlierMap["value"]),
+							ViolationType:    "ml_outlier",
+							ViolationMessage: "MCP outlier detection identified anomaly",
+							Severity:         "medium",
 						})
 					}
 				}
@@ -1199,7 +1234,7 @@ func (s *Service) GenerateDataQualityReport(ctx context.Context, ruleID uuid.UUI
 	}
 
 	// Get quality scores for the rule
-	scores, err := s.GetScores(ctx, ruleID, nil, nil, 100)
+	scores, err := s.recentChecks(ctx, ruleID, 100)
 	if err != nil {
 		return "", fmt.Errorf("failed to get scores: %w", err)
 	}
@@ -1228,3 +1263,29 @@ func (s *Service) GenerateDataQualityReport(ctx context.Context, ruleID uuid.UUI
 
 	return report, nil
 }
+
+/* recentChecks returns a rule's latest checks, newest first */
+func (s *Service) recentChecks(ctx context.Context, ruleID uuid.UUID, limit int) ([]QualityCheck, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, rule_id, status, COALESCE(score, 0), COALESCE(passed_count, 0), COALESCE(failed_count, 0),
+		       COALESCE(total_count, 0), COALESCE(execution_time_ms, 0), executed_at
+		FROM neuronip.data_quality_checks
+		WHERE rule_id = $1
+		ORDER BY executed_at DESC
+		LIMIT $2`, ruleID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checks := []QualityCheck{}
+	for rows.Next() {
+		var c QualityCheck
+		if err := rows.Scan(&c.ID, &c.RuleID, &c.Status, &c.Score, &c.PassedCount, &c.FailedCount,
+			&c.TotalCount, &c.ExecutionTimeMs, &c.ExecutedAt); err != nil {
+			continue
+		}
+		checks = append(checks, c)
+	}
+	return checks, nil
+}
@@ -0,0 +1,178 @@
+package dataquality
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+/* StagedBatch is a table on the NeuronIP database holding loaded rows that are not yet committed to their target */
+type StagedBatch struct {
+	SchemaName   string
+	TableName    string
+	TargetSchema string // Table the batch is committed to; uniqueness rules also fail rows whose value it already holds
+	TargetTable  string
+}
+
+/* StagedResult is the outcome of a quality rule run against a staged batch */
+type StagedResult struct {
+	Rule   *QualityRule
+	Result *RuleExecutionResult
+	Score  float64
+	Status string // "pass", "warning" or "fail", graded as for a recorded check
+	// FailingRows is a condition over the staged table selecting the rows that fail the rule.
+	// It is empty for rules that judge the batch as a whole, such as row count bounds.
+	FailingRows string
+}
+
+/* Passed reports whether the batch meets the rule. Without a threshold any failing row fails the batch. */
+func (r *StagedResult) Passed() bool {
+	return r.Status == "pass" || (r.Status == "warning" && r.Rule.Threshold != nil)
+}
+
+/* EvaluateStagedRule runs a rule against a staged batch instead of the table it is defined on.
+ * Reference tables named in the rule metadata are still read from the rule's connector. Nothing is recorded:
+ * the batch is not the rule's table, so its score would distort the table's check history. */
+func (s *Service) EvaluateStagedRule(ctx context.Context, ruleID uuid.UUID, batch StagedBatch) (*StagedResult, error) {
+	rule, err := s.GetRule(ctx, ruleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rule: %w", err)
+	}
+	if !rule.Enabled {
+		return nil, fmt.Errorf("rule is disabled")
+	}
+
+	// Rules comparing the table with its own history or with another copy of it say nothing about one batch
+	if rule.RuleType == "timeliness" {
+		return nil, fmt.Errorf("timeliness rules cannot run against a staged batch")
+	}
+	if rule.RuleType == "consistency" && metaString(rule.Metadata, "consistency_type") == ConsistencyReconciliation {
+		return nil, fmt.Errorf("reconciliation rules cannot run against a staged batch")
+	}
+
+	staged := *rule
+	staged.SchemaName = &batch.SchemaName
+	staged.TableName = &batch.TableName
+	staged.staged = true
+	if batch.TargetTable != "" {
+		staged.stagedTarget = pgx.Identifier{batch.TargetSchema, batch.TargetTable}.Sanitize()
+	}
+
+	result, err := s.executeRuleByType(ctx, &staged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute rule: %w", err)
+	}
+	failingRows, err := s.failingRowCondition(ctx, &staged)
+	if err != nil {
+		return nil, err
+	}
+
+	score := s.calculateScore(result)
+	return &StagedResult{
+		Rule:        rule,
+		Result:      result,
+		Score:       score,
+		Status:      checkStatus(rule, score),
+		FailingRows: failingRows,
+	}, nil
+}
+
+/* failingRowCondition returns a condition over the rule's table selecting the rows that fail it,
+ * or an empty string when the rule has no row-level failures */
+func (s *Service) failingRowCondition(ctx context.Context, rule *QualityRule) (string, error) {
+	t, err := s.ruleTable(ctx, rule)
+	if err != nil {
+		return "", err
+	}
+	defer t.close()
+
+	switch rule.RuleType {
+	case "completeness":
+		_, hasMin := rule.Metadata["min_row_count"]
+		_, hasMax := rule.Metadata["max_row_count"]
+		if rule.ColumnName == nil || hasMin || hasMax || metaBool(rule.Metadata, "row_count_band") {
+			return "", nil
+		}
+		return fmt.Sprintf("%s IS NULL", t.dialect.QuoteIdent(*rule.ColumnName)), nil
+	case "uniqueness":
+		if rule.ColumnName == nil {
+			return "", nil
+		}
+		return duplicateCondition(rule, t), nil
+	case "validity":
+		if metaString(rule.Metadata, "model_path") != "" || rule.RuleExpression == "" {
+			return "", nil
+		}
+		return rowConditionFailure(rule, t.dialect)
+	case "consistency":
+		switch metaString(rule.Metadata, "consistency_type") {
+		case ConsistencyCrossColumn:
+			return rowConditionFailure(rule, t.dialect)
+		case ConsistencyReferentialIntegrity:
+			return s.orphanCondition(ctx, rule, t)
+		}
+	}
+	return "", nil
+}
+
+/* duplicateCondition selects rows whose value repeats in the table, and for a staged batch,
+ * rows whose value is already in the target it will be committed to */
+func duplicateCondition(rule *QualityRule, t *tableSource) string {
+	column := t.dialect.QuoteIdent(*rule.ColumnName)
+	condition := fmt.Sprintf("%s IN (SELECT %s FROM %s GROUP BY %s HAVING COUNT(*) > 1)", column, column, t.qualified(), column)
+	if rule.stagedTarget != "" {
+		condition = fmt.Sprintf("(%s OR %s IN (SELECT %s FROM %s))", condition, column, column, rule.stagedTarget)
+	}
+	return condition
+}
+
+/* checkDuplicateRows counts the rows whose value repeats, reading the table through its connector.
+ * For a staged batch, rows duplicating a row already in the target count too. */
+func (s *Service) checkDuplicateRows(ctx context.Context, rule *QualityRule) (*RuleExecutionResult, error) {
+	t, err := s.ruleTable(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	defer t.close()
+
+	var totalCount, failedCount int64
+	if err := t.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s`, t.qualified())).Scan(&totalCount); err != nil {
+		return nil, fmt.Errorf("failed to count rows: %w", err)
+	}
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s`, t.qualified(), duplicateCondition(rule, t))
+	if err := t.db.QueryRowContext(ctx, query).Scan(&failedCount); err != nil {
+		return nil, fmt.Errorf("failed to count duplicate rows: %w", err)
+	}
+	return &RuleExecutionResult{
+		PassedCount: totalCount - failedCount,
+		FailedCount: failedCount,
+		TotalCount:  totalCount,
+		Violations:  []QualityViolation{},
+	}, nil
+}
+
+/* orphanCondition selects rows whose key is missing from the reference table.
+ * It needs both tables on one database; otherwise the orphans are only known by key and there is no condition. */
+func (s *Service) orphanCondition(ctx context.Context, rule *QualityRule, child *tableSource) (string, error) {
+	if rule.ColumnName == nil {
+		return "", nil
+	}
+	parent, err := s.metadataTable(ctx, rule, "reference", "")
+	if err != nil {
+		return "", err
+	}
+	defer parent.close()
+	if !child.sameDatabase(parent) {
+		return "", nil
+	}
+
+	referenceColumn := metaString(rule.Metadata, "reference_column")
+	if referenceColumn == "" {
+		referenceColumn = *rule.ColumnName
+	}
+	column := child.qualified() + "." + child.dialect.QuoteIdent(*rule.ColumnName)
+	return fmt.Sprintf("%s IS NOT NULL AND NOT EXISTS (SELECT 1 FROM %s p WHERE p.%s = %s)",
+		column, parent.qualified(), parent.dialect.QuoteIdent(referenceColumn), column), nil
+}
@@ -0,0 +1,377 @@
+package dataquality
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neurondb/NeuronIP/api/internal/connectors"
+	"github.com/neurondb/NeuronIP/api/internal/freshness"
+)
+
+/* Timeliness rule kinds, set in rule metadata as timeliness_type */
+const (
+	TimelinessMaxLag        = "max_lag"
+	TimelinessArrivalCutoff = "arrival_cutoff"
+	TimelinessRowLatency    = "row_latency"
+)
+
+const (
+	defaultAlertThresholdMinutes = 60
+	defaultLatencyWindowHours    = 24
+)
+
+/* executeTimelinessRule executes timeliness rule.
+ * Metadata timeliness_type selects max_lag, arrival_cutoff or row_latency. Every kind reads or creates the
+ * freshness monitor of the column it checks and records the latest value it saw, so staleness is tracked in one place. */
+func (s *Service) executeTimelinessRule(ctx context.Context, rule *QualityRule) (*RuleExecutionResult, error) {
+	if rule.TableName == nil && metaString(rule.Metadata, "freshness_monitor_id") == "" {
+		return nil, fmt.Errorf("table_name or freshness_monitor_id required for timeliness rule")
+	}
+
+	switch kind := metaString(rule.Metadata, "timeliness_type"); kind {
+	case TimelinessMaxLag, "":
+		return s.checkMaxLag(ctx, rule)
+	case TimelinessArrivalCutoff:
+		return s.checkArrivalCutoff(ctx, rule)
+	case TimelinessRowLatency:
+		return s.checkRowLatency(ctx, rule)
+	default:
+		return nil, fmt.Errorf("unknown timeliness_type: %s", kind)
+	}
+}
+
+/* monitorFor returns the freshness monitor of a table column, creating it when none exists.
+ * An existing monitor's expected interval and alert threshold take precedence over the rule's. */
+func (s *Service) monitorFor(ctx context.Context, rule *QualityRule, t *tableSource, column string, defaultInterval int) (*freshness.FreshnessMonitor, error) {
+	monitor, err := s.freshness.FindMonitor(ctx, t.connectorID, t.schema, t.table, column)
+	if err != nil {
+		return nil, err
+	}
+	if monitor != nil {
+		return monitor, nil
+	}
+
+	return s.freshness.CreateMonitor(ctx, freshness.FreshnessMonitor{
+		ConnectorID:             t.connectorID,
+		SchemaName:              t.schema,
+		TableName:               t.table,
+		TimestampColumn:         column,
+		ExpectedIntervalMinutes: int(metaFloat(rule.Metadata, "expected_interval_minutes", float64(defaultInterval))),
+		AlertThreshold:          int(metaFloat(rule.Metadata, "alert_threshold_minutes", defaultAlertThresholdMinutes)),
+		Enabled:                 true,
+		Metadata: map[string]interface{}{
+			"created_by_rule": rule.ID.String(),
+		},
+	})
+}
+
+/* latestValue returns MAX(column), or nil when the table has no values */
+func latestValue(ctx context.Context, t *tableSource, column, layout string) (*time.Time, error) {
+	var latest *time.Time
+	err := t.scanRows(ctx, fmt.Sprintf(`SELECT MAX(%s) FROM %s`, t.dialect.QuoteIdent(column), t.qualified()), func(values []interface{}) bool {
+		latest = toTime(values[0], layout)
+		return false
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read latest %s: %w", column, err)
+	}
+	return latest, nil
+}
+
+/* checkMaxLag checks that the newest value of a timestamp column is within the monitor's expected interval of now */
+func (s *Service) checkMaxLag(ctx context.Context, rule *QualityRule) (*RuleExecutionResult, error) {
+	var monitor *freshness.FreshnessMonitor
+	var t *tableSource
+	var err error
+
+	if id := metaString(rule.Metadata, "freshness_monitor_id"); id != "" {
+		monitorID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid freshness_monitor_id: %w", err)
+		}
+		if monitor, err = s.freshness.GetMonitor(ctx, monitorID); err != nil {
+			return nil, err
+		}
+		if t, err = s.openTable(ctx, monitor.ConnectorID, monitor.SchemaName, monitor.TableName); err != nil {
+			return nil, err
+		}
+		defer t.close()
+	} else {
+		if rule.ColumnName == nil {
+			return nil, fmt.Errorf("column_name required for max lag rule")
+		}
+		maxLag := metaFloat(rule.Metadata, "max_lag_minutes", 0)
+		if maxLag <= 0 {
+			if !learnedThreshold(rule) {
+				return nil, fmt.Errorf("max_lag_minutes is required in rule metadata unless threshold_mode is learned_band")
+			}
+			// The learned band judges the lag; the monitor only needs a nominal interval
+			maxLag = defaultLearnedLagMinutes
+		}
+		if t, err = s.ruleTable(ctx, rule); err != nil {
+			return nil, err
+		}
+		defer t.close()
+		if monitor, err = s.monitorFor(ctx, rule, t, *rule.ColumnName, int(maxLag)); err != nil {
+			return nil, err
+		}
+	}
+
+	latest, err := latestValue(ctx, t, monitor.TimestampColumn, "")
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := s.freshness.RecordUpdate(ctx, monitor, latest, now); err != nil {
+		return nil, err
+	}
+
+	if learnedThreshold(rule) && latest != nil {
+		violation, learned, err := s.checkLagBand(ctx, rule, monitor, *latest, now)
+		if err != nil {
+			return nil, err
+		}
+		if learned {
+			return tableCheckResult(violation), nil
+		}
+		// Until the lag has enough history the monitor's static interval applies
+	}
+
+	if monitor.Status == "fresh" {
+		return tableCheckResult(nil), nil
+	}
+	violation := QualityViolation{
+		ViolationType: TimelinessMaxLag,
+		Severity:      freshnessSeverity(monitor.Status),
+	}
+	if latest == nil {
+		violation.ViolationMessage = fmt.Sprintf("%s has no %s values", t.qualified(), monitor.TimestampColumn)
+	} else {
+		violation.ColumnValue = latest.UTC().Format(time.RFC3339)
+		violation.ViolationMessage = fmt.Sprintf("Latest %s is %s old; expected within %d minutes (%s)",
+			monitor.TimestampColumn, now.Sub(*latest).Round(time.Minute), monitor.ExpectedIntervalMinutes, monitor.Status)
+	}
+	return tableCheckResult(&violation), nil
+}
+
+/* checkArrivalCutoff checks that a day's partition has landed by a cutoff, e.g. yesterday's partition by 06:00 UTC.
+ * Before today's cutoff the previous day's expectation is checked, so a rule only fails once a deadline has passed. */
+func (s *Service) checkArrivalCutoff(ctx context.Context, rule *QualityRule) (*RuleExecutionResult, error) {
+	column := metaString(rule.Metadata, "partition_column")
+	if column == "" && rule.ColumnName != nil {
+		column = *rule.ColumnName
+	}
+	if column == "" {
+		return nil, fmt.Errorf("partition_column or column_name required for arrival cutoff rule")
+	}
+
+	location := time.UTC
+	if name := metaString(rule.Metadata, "timezone"); name != "" {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone: %w", err)
+		}
+		location = loc
+	}
+	cutoffText := metaString(rule.Metadata, "arrival_cutoff")
+	if cutoffText == "" {
+		cutoffText = "06:00"
+	}
+	cutoff, err := time.Parse("15:04", cutoffText)
+	if err != nil {
+		return nil, fmt.Errorf("arrival_cutoff must be HH:MM: %w", err)
+	}
+	lagDays := int(metaFloat(rule.Metadata, "partition_lag_days", 1))
+	layout := metaString(rule.Metadata, "partition_format")
+
+	partitionDay, deadline := arrivalDeadline(time.Now().In(location), cutoff, lagDays)
+
+	t, err := s.ruleTable(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	defer t.close()
+
+	// On schedule, the newest partition is never older than the lag plus one day plus the cutoff
+	defaultInterval := (lagDays+1)*24*60 + cutoff.Hour()*60 + cutoff.Minute()
+	monitor, err := s.monitorFor(ctx, rule, t, column, defaultInterval)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := latestValue(ctx, t, column, layout)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.freshness.RecordUpdate(ctx, monitor, latest, time.Now()); err != nil {
+		return nil, err
+	}
+
+	quoted := t.dialect.QuoteIdent(column)
+	var condition string
+	if layout != "" {
+		condition = fmt.Sprintf("%s = %s", quoted, t.dialect.QuoteString(partitionDay.Format(layout)))
+	} else {
+		condition = fmt.Sprintf("%s >= %s AND %s < %s", quoted, t.dialect.QuoteString(partitionDay.Format("2006-01-02 15:04:05")),
+			quoted, t.dialect.QuoteString(partitionDay.AddDate(0, 0, 1).Format("2006-01-02 15:04:05")))
+	}
+	var rows int64
+	err = t.scanRows(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s`, t.qualified(), condition), func(values []interface{}) bool {
+		rows = toInt64(values[0])
+		return false
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check partition: %w", err)
+	}
+
+	if rows > 0 {
+		return tableCheckResult(nil), nil
+	}
+	return tableCheckResult(&QualityViolation{
+		RowIdentifier: partitionDay.Format("2006-01-02"),
+		ViolationType: TimelinessArrivalCutoff,
+		ViolationMessage: fmt.Sprintf("Partition %s of %s had not arrived by %s %s",
+			partitionDay.Format("2006-01-02"), t.qualified(), deadline.Format("2006-01-02 15:04"), location),
+		Severity:   "high",
+		SampleKeys: []string{partitionDay.Format("2006-01-02")},
+	}), nil
+}
+
+/* arrivalDeadline returns the partition day that must have landed by now and the cutoff it was due by.
+ * now carries the rule's time zone; before today's cutoff the deadline is yesterday's. */
+func arrivalDeadline(now, cutoff time.Time, lagDays int) (time.Time, time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	deadline := today.Add(time.Duration(cutoff.Hour())*time.Hour + time.Duration(cutoff.Minute())*time.Minute)
+	if now.Before(deadline) {
+		deadline = deadline.AddDate(0, 0, -1)
+	}
+	partitionDay := time.Date(deadline.Year(), deadline.Month(), deadline.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -lagDays)
+	return partitionDay, deadline
+}
+
+/* checkRowLatency counts rows loaded more than max_latency_minutes after their event time */
+func (s *Service) checkRowLatency(ctx context.Context, rule *QualityRule) (*RuleExecutionResult, error) {
+	eventColumn := metaString(rule.Metadata, "event_column")
+	loadColumn := metaString(rule.Metadata, "load_column")
+	if loadColumn == "" && rule.ColumnName != nil {
+		loadColumn = *rule.ColumnName
+	}
+	if eventColumn == "" || loadColumn == "" {
+		return nil, fmt.Errorf("event_column and load_column required for row latency rule")
+	}
+	maxLatency := int(metaFloat(rule.Metadata, "max_latency_minutes", 0))
+	if maxLatency <= 0 {
+		return nil, fmt.Errorf("max_latency_minutes is required in rule metadata")
+	}
+
+	t, err := s.ruleTable(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	defer t.close()
+
+	monitor, err := s.monitorFor(ctx, rule, t, loadColumn, 24*60)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := latestValue(ctx, t, loadColumn, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.freshness.RecordUpdate(ctx, monitor, latest, time.Now()); err != nil {
+		return nil, err
+	}
+
+	event, load := t.dialect.QuoteIdent(eventColumn), t.dialect.QuoteIdent(loadColumn)
+	scope := fmt.Sprintf("%s IS NOT NULL AND %s IS NOT NULL", event, load)
+	if window := metaFloat(rule.Metadata, "window_hours", defaultLatencyWindowHours); window > 0 {
+		since := time.Now().UTC().Add(-time.Duration(window * float64(time.Hour)))
+		scope += fmt.Sprintf(" AND %s >= %s", load, t.dialect.QuoteString(since.Format("2006-01-02 15:04:05")))
+	}
+	late := fmt.Sprintf("%s AND %s > %s", scope, load, addMinutes(t.dialect, event, maxLatency))
+
+	var totalCount, failedCount int64
+	err = t.scanRows(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s`, t.qualified(), scope), func(values []interface{}) bool {
+		totalCount = toInt64(values[0])
+		return false
+	})
+	if err == nil {
+		err = t.scanRows(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s`, t.qualified(), late), func(values []interface{}) bool {
+			failedCount = toInt64(values[0])
+			return false
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure load latency: %w", err)
+	}
+
+	result := &RuleExecutionResult{
+		PassedCount: totalCount - failedCount,
+		FailedCount: failedCount,
+		TotalCount:  totalCount,
+		Violations:  []QualityViolation{},
+	}
+	if failedCount == 0 {
+		return result, nil
+	}
+
+	columns := append(metaStrings(rule.Metadata, "key_columns"), eventColumn, loadColumn)
+	samples := newSampleCollector(rule)
+	err = t.scanRows(ctx, fmt.Sprintf(`SELECT %s FROM %s WHERE %s`, t.columns(columns), t.qualified(), late), func(values []interface{}) bool {
+		samples.add(formatKey(columns, values))
+		return !samples.full()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample late rows: %w", err)
+	}
+	result.Violations = append(result.Violations, samples.violation(TimelinessRowLatency,
+		fmt.Sprintf("%d of %d rows were loaded more than %d minutes after %s", failedCount, totalCount, maxLatency, eventColumn), "medium"))
+	return result, nil
+}
+
+/* tableCheckResult is the result of a check on a whole table: one check that passes or fails */
+func tableCheckResult(violation *QualityViolation) *RuleExecutionResult {
+	if violation == nil {
+		return &RuleExecutionResult{PassedCount: 1, TotalCount: 1, Violations: []QualityViolation{}}
+	}
+	return &RuleExecutionResult{FailedCount: 1, TotalCount: 1, Violations: []QualityViolation{*violation}}
+}
+
+func freshnessSeverity(status string) string {
+	if status == "critical" {
+		return "high"
+	}
+	return "medium"
+}
+
+/* addMinutes renders a timestamp expression shifted by a number of minutes */
+func addMinutes(dialect connectors.QueryDialect, expr string, minutes int) string {
+	switch dialect.Driver {
+	case "mysql":
+		return fmt.Sprintf("%s + INTERVAL %d MINUTE", expr, minutes)
+	case "sqlserver", "snowflake":
+		return fmt.Sprintf("DATEADD(minute, %d, %s)", minutes, expr)
+	}
+	return fmt.Sprintf("%s + INTERVAL '%d minutes'", expr, minutes)
+}
+
+/* toTime reads a timestamp returned by any driver; layout is tried first for string partition values */
+func toTime(v interface{}, layout string) *time.Time {
+	switch value := v.(type) {
+	case time.Time:
+		return &value
+	case string:
+		layouts := []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999", "2006-01-02"}
+		if layout != "" {
+			layouts = append([]string{layout}, layouts...)
+		}
+		for _, l := range layouts {
+			if parsed, err := time.Parse(l, strings.TrimSpace(value)); err == nil {
+				return &parsed
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package dataquality
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neurondb/NeuronIP/api/internal/connectors"
+	"github.com/neurondb/NeuronIP/api/internal/freshness"
+)
+
+/* TestExecuteTimelinessRuleConfig checks the rule configuration rejected before any table is read */
+func TestExecuteTimelinessRuleConfig(t *testing.T) {
+	table := "orders"
+	tests := []struct {
+		name     string
+		table    *string
+		metadata map[string]interface{}
+		errMsg   string
+	}{
+		{name: "no table or monitor", metadata: map[string]interface{}{}, errMsg: "table_name or freshness_monitor_id"},
+		{name: "unknown kind", table: &table, metadata: map[string]interface{}{"timeliness_type": "sla"}, errMsg: "unknown timeliness_type"},
+		{name: "max lag without column", table: &table, metadata: map[string]interface{}{}, errMsg: "column_name required"},
+		{name: "invalid monitor", metadata: map[string]interface{}{"freshness_monitor_id": "orders"}, errMsg: "invalid freshness_monitor_id"},
+		{
+			name:     "cutoff without column",
+			table:    &table,
+			metadata: map[string]interface{}{"timeliness_type": TimelinessArrivalCutoff},
+			errMsg:   "partition_column or column_name",
+		},
+		{
+			name:     "invalid cutoff",
+			table:    &table,
+			metadata: map[string]interface{}{"timeliness_type": TimelinessArrivalCutoff, "partition_column": "ds", "arrival_cutoff": "6am"},
+			errMsg:   "HH:MM",
+		},
+		{
+			name:     "latency without bound",
+			table:    &table,
+			metadata: map[string]interface{}{"timeliness_type": TimelinessRowLatency, "event_column": "event_at", "load_column": "loaded_at"},
+			errMsg:   "max_latency_minutes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &QualityRule{RuleType: "timeliness", TableName: tt.table, Metadata: tt.metadata}
+			_, err := (&Service{}).executeTimelinessRule(context.Background(), rule)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+/* TestArrivalDeadline checks which partition must have landed before and after the daily cutoff */
+func TestArrivalDeadline(t *testing.T) {
+	cutoff, _ := time.Parse("15:04", "06:00")
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone database unavailable")
+	}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		lagDays   int
+		partition string
+		deadline  string
+	}{
+		{name: "after cutoff", now: time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC), lagDays: 1, partition: "2026-03-09", deadline: "2026-03-10 06:00"},
+		{name: "before cutoff", now: time.Date(2026, 3, 10, 5, 59, 0, 0, time.UTC), lagDays: 1, partition: "2026-03-08", deadline: "2026-03-09 06:00"},
+		{name: "at cutoff", now: time.Date(2026, 3, 10, 6, 0, 0, 0, time.UTC), lagDays: 1, partition: "2026-03-09", deadline: "2026-03-10 06:00"},
+		{name: "same-day partition", now: time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC), lagDays: 0, partition: "2026-03-10", deadline: "2026-03-10 06:00"},
+		{name: "in a time zone", now: time.Date(2026, 3, 10, 5, 30, 0, 0, time.UTC).In(berlin), lagDays: 1, partition: "2026-03-09", deadline: "2026-03-10 06:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			partition, deadline := arrivalDeadline(tt.now, cutoff, tt.lagDays)
+			if got := partition.Format("2006-01-02"); got != tt.partition {
+				t.Errorf("expected partition %s, got %s", tt.partition, got)
+			}
+			if got := deadline.Format("2006-01-02 15:04"); got != tt.deadline {
+				t.Errorf("expected deadline %s, got %s", tt.deadline, got)
+			}
+			if deadline.After(tt.now) {
+				t.Errorf("expected a deadline that has passed, got %s at %s", deadline, tt.now)
+			}
+		})
+	}
+}
+
+/* TestFreshnessStatus checks how the age of the latest value grades against a monitor and the severity it maps to */
+func TestFreshnessStatus(t *testing.T) {
+	monitor := &freshness.FreshnessMonitor{ExpectedIntervalMinutes: 60, AlertThreshold: 30}
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+
+	tests := []struct {
+		name     string
+		latest   *time.Time
+		status   string
+		severity string
+	}{
+		{name: "within interval", latest: at(60 * time.Minute), status: "fresh"},
+		{name: "within threshold", latest: at(90 * time.Minute), status: "stale", severity: "medium"},
+		{name: "past threshold", latest: at(91 * time.Minute), status: "critical", severity: "high"},
+		{name: "no values", status: "critical", severity: "high"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := monitor.StatusAt(tt.latest, now)
+			if status != tt.status {
+				t.Fatalf("expected %s, got %s", tt.status, status)
+			}
+			if tt.severity != "" && freshnessSeverity(status) != tt.severity {
+				t.Errorf("expected severity %s, got %s", tt.severity, freshnessSeverity(status))
+			}
+		})
+	}
+}
+
+/* TestAddMinutes checks the interval arithmetic written for each dialect */
+func TestAddMinutes(t *testing.T) {
+	tests := []struct {
+		connector connectors.ConnectorType
+		want      string
+	}{
+		{connector: connectors.ConnectorPostgreSQL, want: `"event_at" + INTERVAL '15 minutes'`},
+		{connector: connectors.ConnectorMySQL, want: `"event_at" + INTERVAL 15 MINUTE`},
+		{connector: connectors.ConnectorSQLServer, want: `DATEADD(minute, 15, "event_at")`},
+		{connector: connectors.ConnectorSnowflake, want: `DATEADD(minute, 15, "event_at")`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.connector), func(t *testing.T) {
+			dialect, err := connectors.GetQueryDialect(tt.connector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := addMinutes(dialect, `"event_at"`, 15); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+/* TestToTime checks timestamps read back from different drivers and partition formats */
+func TestToTime(t *testing.T) {
+	want := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		value  interface{}
+		layout string
+		want   *time.Time
+	}{
+		{name: "time", value: want, want: &want},
+		{name: "date text", value: "2026-03-09", want: &want},
+		{name: "timestamp text", value: "2026-03-09 00:00:00", want: &want},
+		{name: "RFC 3339 text", value: "2026-03-09T00:00:00Z", want: &want},
+		{name: "partition layout", value: "20260309", layout: "20060102", want: &want},
+		{name: "unparseable", value: "yesterday"},
+		{name: "null", value: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toTime(tt.value, tt.layout)
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+/* TestTableCheckResult checks that whole-table checks count as a single checked row */
+func TestTableCheckResult(t *testing.T) {
+	if result := tableCheckResult(nil); result.PassedCount != 1 || result.TotalCount != 1 || len(result.Violations) != 0 {
+		t.Errorf("expected one passing check, got %+v", result)
+	}
+	violation := &QualityViolation{ViolationType: TimelinessMaxLag}
+	if result := tableCheckResult(violation); result.FailedCount != 1 || result.TotalCount != 1 || len(result.Violations) != 1 {
+		t.Errorf("expected one failing check, got %+v", result)
+	}
+}
@@ -11,6 +11,9 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+/* AsyncQueryJobType is the job queue type of submitted async queries */
+const AsyncQueryJobType = "query"
+
 /* AsyncQueryService provides async query execution functionality */
 type AsyncQueryService struct {
 	pool *pgxpool.Pool
@@ -105,7 +108,7 @@ func (s *AsyncQueryService) SubmitAsyncQuery(ctx context.Context, queryText stri
 
 	// Enqueue for execution
 	jobService := NewJobQueueService(s.pool)
-	_, err = jobService.EnqueueJob(ctx, "query", map[string]interface{}{
+	_, err = jobService.EnqueueJob(ctx, AsyncQueryJobType, map[string]interface{}{
 		"query_id": id.String(),
 		"query_text": queryText,
 		"query_params": queryParams,
@@ -280,3 +283,40 @@ func (s *AsyncQueryService) CancelAsyncQuery(ctx context.Context, queryID uuid.U
 
 	return nil
 }
+
+/* StartAsyncQuery marks a pending query as running; it reports false when the query was cancelled or already taken */
+func (s *AsyncQueryService) StartAsyncQuery(ctx context.Context, queryID uuid.UUID) (bool, error) {
+	result, err := s.pool.Exec(ctx, `
+		UPDATE neuronip.async_queries
+		SET status = 'running', started_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'pending'`, queryID)
+	if err != nil {
+		return false, fmt.Errorf("failed to start async query: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+/* CompleteAsyncQuery records a finished query and where its result was stored */
+func (s *AsyncQueryService) CompleteAsyncQuery(ctx context.Context, queryID uuid.UUID, resultLocation string, rowsReturned int64, executionTimeMs int) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE neuronip.async_queries
+		SET status = 'completed', result_location = $1, rows_returned = $2, execution_time_ms = $3,
+		    completed_at = NOW(), updated_at = NOW()
+		WHERE id = $4 AND status = 'running'`, resultLocation, rowsReturned, executionTimeMs, queryID)
+	if err != nil {
+		return fmt.Errorf("failed to complete async query: %w", err)
+	}
+	return nil
+}
+
+/* FailAsyncQuery records a query that could not run */
+func (s *AsyncQueryService) FailAsyncQuery(ctx context.Context, queryID uuid.UUID, errorMessage string) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE neuronip.async_queries
+		SET status = 'failed', error_message = $1, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $2 AND status IN ('pending', 'running')`, errorMessage, queryID)
+	if err != nil {
+		return fmt.Errorf("failed to fail async query: %w", err)
+	}
+	return nil
+}
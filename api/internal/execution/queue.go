@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -129,7 +130,7 @@ func (s *JobQueueService) DequeueJob(ctx context.Context, workerID string, jobTy
 		&errorMsg, &job.RetryCount, &job.MaxRetries, &job.CreatedAt, &job.UpdatedAt,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == pgx.ErrNoRows {
 			return nil, nil // No jobs available
 		}
 		return nil, fmt.Errorf("failed to dequeue job: %w", err)
@@ -8,17 +8,25 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/anomaly"
 )
 
 /* Service provides data freshness monitoring functionality */
 type Service struct {
-	pool *pgxpool.Pool
+	pool  *pgxpool.Pool
+	bands *anomaly.Service
 }
 
 /* NewService creates a new freshness service */
 func NewService(pool *pgxpool.Pool) *Service {
-	return &Service{pool: pool}
+	return &Service{pool: pool, bands: anomaly.NewService(pool)}
+}
+
+/* LagSeries is the series of a monitor's observed lags in minutes, from which learned bands are built */
+func LagSeries(monitorID uuid.UUID) anomaly.Series {
+	return anomaly.Series{Kind: anomaly.SeriesFreshnessLag, Key: monitorID.String()}
 }
 
 /* FreshnessMonitor represents a freshness monitor */
@@ -62,60 +70,78 @@ func (s *Service) CheckFreshness(ctx context.Context, monitorID uuid.UUID) (*Fre
 		return nil, fmt.Errorf("failed to check freshness: %w", err)
 	}
 
-	monitor.LastCheckAt = &now
-
+	var observed *time.Time
 	if lastUpdate.Valid {
-		monitor.LastUpdateAt = &lastUpdate.Time
-		
-		// Calculate age
-		ageMinutes := int(now.Sub(lastUpdate.Time).Minutes())
-		expectedAge := monitor.ExpectedIntervalMinutes
-		alertThreshold := monitor.AlertThreshold
-		
-		// Determine status
-		if ageMinutes <= expectedAge {
-			monitor.Status = "fresh"
-		} else if ageMinutes <= expectedAge+alertThreshold {
-			monitor.Status = "stale"
-		} else {
-			monitor.Status = "critical"
-		}
+		observed = &lastUpdate.Time
+	}
+	if err := s.RecordUpdate(ctx, monitor, observed, now); err != nil {
+		return nil, err
+	}
 
-		// Update monitor
-		monitor.UpdatedAt = now
-		metadataJSON, _ := json.Marshal(monitor.Metadata)
-		
-		updateQuery := `
-			UPDATE neuronip.freshness_monitors
-			SET last_check_at = $1, last_update_at = $2, status = $3, updated_at = $4, metadata = $5
-			WHERE id = $6`
-
-		_, err = s.pool.Exec(ctx, updateQuery,
-			monitor.LastCheckAt, monitor.LastUpdateAt, monitor.Status,
-			monitor.UpdatedAt, metadataJSON, monitor.ID,
-		)
+	return monitor, nil
+}
 
-		if err != nil {
-			return nil, fmt.Errorf("failed to update monitor: %w", err)
-		}
-	} else {
+/* StatusAt classifies the age of the latest update against the monitor's expected interval and alert threshold */
+func (m *FreshnessMonitor) StatusAt(lastUpdate *time.Time, now time.Time) string {
+	if lastUpdate == nil {
 		// No data found
-		monitor.Status = "critical"
-		monitor.UpdatedAt = now
-		metadataJSON, _ := json.Marshal(monitor.Metadata)
-		
-		updateQuery := `
-			UPDATE neuronip.freshness_monitors
-			SET last_check_at = $1, status = $2, updated_at = $3, metadata = $4
-			WHERE id = $5`
-
-		_, err = s.pool.Exec(ctx, updateQuery,
-			monitor.LastCheckAt, monitor.Status, monitor.UpdatedAt,
-			metadataJSON, monitor.ID,
-		)
+		return "critical"
+	}
+	ageMinutes := int(now.Sub(*lastUpdate).Minutes())
+	if ageMinutes <= m.ExpectedIntervalMinutes {
+		return "fresh"
+	} else if ageMinutes <= m.ExpectedIntervalMinutes+m.AlertThreshold {
+		return "stale"
 	}
+	return "critical"
+}
 
-	return monitor, nil
+/* RecordUpdate stores the latest update time observed for a monitor's table, along with the resulting status.
+ * It lets callers that read the table themselves, such as timeliness quality rules, keep the monitor current. */
+func (s *Service) RecordUpdate(ctx context.Context, monitor *FreshnessMonitor, lastUpdate *time.Time, now time.Time) error {
+	monitor.LastCheckAt = &now
+	monitor.Status = monitor.StatusAt(lastUpdate, now)
+	monitor.UpdatedAt = now
+	if lastUpdate != nil {
+		monitor.LastUpdateAt = lastUpdate
+	}
+	metadataJSON, _ := json.Marshal(monitor.Metadata)
+
+	updateQuery := `
+		UPDATE neuronip.freshness_monitors
+		SET last_check_at = $1, last_update_at = COALESCE($2, last_update_at), status = $3, updated_at = $4, metadata = $5
+		WHERE id = $6`
+
+	_, err := s.pool.Exec(ctx, updateQuery,
+		monitor.LastCheckAt, lastUpdate, monitor.Status,
+		monitor.UpdatedAt, metadataJSON, monitor.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update monitor: %w", err)
+	}
+
+	// Best effort: a missed lag observation only leaves a gap the band interpolates over
+	if monitor.LastUpdateAt != nil {
+		s.bands.Record(ctx, LagSeries(monitor.ID), now.Sub(*monitor.LastUpdateAt).Minutes(), now)
+	}
+	return nil
+}
+
+/* FindMonitor retrieves the monitor of a table's timestamp column, or nil when there is none */
+func (s *Service) FindMonitor(ctx context.Context, connectorID *uuid.UUID, schemaName, tableName, timestampColumn string) (*FreshnessMonitor, error) {
+	var id uuid.UUID
+	err := s.pool.QueryRow(ctx, `
+		SELECT id FROM neuronip.freshness_monitors
+		WHERE connector_id IS NOT DISTINCT FROM $1 AND schema_name = $2 AND table_name = $3 AND timestamp_column = $4`,
+		connectorID, schemaName, tableName, timestampColumn,
+	).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find monitor: %w", err)
+	}
+	return s.GetMonitor(ctx, id)
 }
 
 /* CreateMonitor creates a new freshness monitor */
@@ -205,6 +205,25 @@ func (h *AlertsHandler) UpdateAlertRule(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(rule)
 }
 
+/* GetAlertRuleBand handles requests for the learned band of an anomaly rule */
+func (h *AlertsHandler) GetAlertRuleBand(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ruleID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid rule ID"))
+		return
+	}
+
+	band, err := h.service.GetRuleBand(r.Context(), ruleID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(band)
+}
+
 /* DeleteAlertRule handles alert rule deletion requests */
 func (h *AlertsHandler) DeleteAlertRule(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/neurondb/NeuronIP/api/internal/auth"
+	"github.com/neurondb/NeuronIP/api/internal/errors"
+	"github.com/neurondb/NeuronIP/api/internal/execution"
+)
+
+/* AsyncQueryHandler handles requests for queries running asynchronously */
+type AsyncQueryHandler struct {
+	service *execution.AsyncQueryService
+}
+
+/* NewAsyncQueryHandler creates a new async query handler */
+func NewAsyncQueryHandler(service *execution.AsyncQueryService) *AsyncQueryHandler {
+	return &AsyncQueryHandler{service: service}
+}
+
+/* GetAsyncQuery handles GET /api/v1/warehouse/async-queries/{id} */
+func (h *AsyncQueryHandler) GetAsyncQuery(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid async query ID"))
+		return
+	}
+
+	query, err := h.service.GetAsyncQuery(r.Context(), id)
+	if err != nil || !ownsAsyncQuery(r, query) {
+		WriteErrorResponse(w, errors.NotFound("Async query"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(query)
+}
+
+/* ListAsyncQueries handles GET /api/v1/warehouse/async-queries?status=...&limit=... */
+func (h *AsyncQueryHandler) ListAsyncQueries(w http.ResponseWriter, r *http.Request) {
+	var createdBy, status *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		createdBy = &uid
+	}
+	if s := r.URL.Query().Get("status"); s != "" {
+		status = &s
+	}
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	queries, err := h.service.ListAsyncQueries(r.Context(), createdBy, status, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(queries)
+}
+
+/* CancelAsyncQuery handles DELETE /api/v1/warehouse/async-queries/{id} */
+func (h *AsyncQueryHandler) CancelAsyncQuery(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid async query ID"))
+		return
+	}
+
+	query, err := h.service.GetAsyncQuery(r.Context(), id)
+	if err != nil || !ownsAsyncQuery(r, query) {
+		WriteErrorResponse(w, errors.NotFound("Async query"))
+		return
+	}
+
+	if err := h.service.CancelAsyncQuery(r.Context(), id); err != nil {
+		WriteErrorResponse(w, errors.Conflict(err.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* ownsAsyncQuery reports whether the caller submitted the query; queries without an owner are visible to no one */
+func ownsAsyncQuery(r *http.Request, query *execution.AsyncQuery) bool {
+	if query.CreatedBy == nil {
+		return false
+	}
+	uid, ok := auth.GetUserIDFromContext(r.Context())
+	return ok && uid == *query.CreatedBy
+}
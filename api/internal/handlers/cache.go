@@ -3,7 +3,10 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
+	"github.com/gorilla/mux"
+	"github.com/neurondb/NeuronIP/api/internal/cache"
 	"github.com/neurondb/NeuronIP/api/internal/errors"
 	"github.com/neurondb/NeuronIP/api/internal/warehouse"
 )
@@ -71,3 +74,69 @@ func (h *CacheHandler) GetCacheStats(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(stats)
 }
+
+/* InvalidateTables handles invalidating cached results that read the given tables */
+func (h *CacheHandler) InvalidateTables(w http.ResponseWriter, r *http.Request) {
+	var req cache.TableChange
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+	if len(req.Tables) == 0 {
+		WriteErrorResponse(w, errors.BadRequest("tables is required"))
+		return
+	}
+	switch req.Reason {
+	case "":
+		req.Reason = cache.TableChangeIngestion
+	case cache.TableChangeIngestion, cache.TableChangeCDC, cache.TableChangeSchema:
+	default:
+		WriteErrorResponse(w, errors.BadRequest("reason must be ingestion, cdc or schema_change"))
+		return
+	}
+
+	result, err := h.service.InvalidateTables(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+/* ListTableStaleness handles listing per-table staleness tolerances */
+func (h *CacheHandler) ListTableStaleness(w http.ResponseWriter, r *http.Request) {
+	tolerances, err := h.service.ListTableStaleness(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tolerances)
+}
+
+/* SetTableStaleness handles setting the staleness tolerance of a table */
+func (h *CacheHandler) SetTableStaleness(w http.ResponseWriter, r *http.Request) {
+	table := mux.Vars(r)["table"]
+
+	var req struct {
+		MaxStalenessSeconds int `json:"max_staleness_seconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+	if req.MaxStalenessSeconds < 0 {
+		WriteErrorResponse(w, errors.BadRequest("max_staleness_seconds must not be negative"))
+		return
+	}
+
+	if err := h.service.SetTableStaleness(r.Context(), table, time.Duration(req.MaxStalenessSeconds)*time.Second); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/neurondb/NeuronIP/api/internal/collaboration"
 	"github.com/neurondb/NeuronIP/api/internal/errors"
+	"github.com/neurondb/NeuronIP/api/internal/warehouse"
 )
 
 /* CollaborationHandler handles collaboration requests */
@@ -24,6 +25,13 @@ func NewCollaborationHandler(pool *pgxpool.Pool) *CollaborationHandler {
 	}
 }
 
+/* NewCollaborationHandlerWithTemplates creates a collaboration handler whose answer cards can be backed by query templates */
+func NewCollaborationHandlerWithTemplates(pool *pgxpool.Pool, templates *warehouse.QueryTemplateService) *CollaborationHandler {
+	return &CollaborationHandler{
+		service: collaboration.NewCollaborationServiceWithTemplates(pool, templates),
+	}
+}
+
 /* CreateSharedDashboard handles POST /api/v1/collaboration/dashboards */
 func (h *CollaborationHandler) CreateSharedDashboard(w http.ResponseWriter, r *http.Request) {
 	var req struct {
@@ -157,12 +165,22 @@ func (h *CollaborationHandler) CreateAnswerCard(w http.ResponseWriter, r *http.R
 		IsPublic    bool                   `json:"is_public"`
 		SharedWith  []string               `json:"shared_with,omitempty"`
 		Tags        []string               `json:"tags,omitempty"`
+		TemplateID     *uuid.UUID             `json:"template_id,omitempty"`
+		TemplateParams map[string]interface{} `json:"template_params,omitempty"`
+		ChartSpec      map[string]interface{} `json:"chart_spec,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
 		return
 	}
 
+	if req.ChartSpec != nil {
+		if err := warehouse.ValidateChartSpec(req.ChartSpec); err != nil {
+			WriteErrorResponse(w, errors.ValidationFailed(err.Error(), nil))
+			return
+		}
+	}
+
 	userID := r.Header.Get("X-User-ID")
 	if userID == "" {
 		WriteErrorResponse(w, errors.Unauthorized("User ID required"))
@@ -172,17 +190,173 @@ func (h *CollaborationHandler) CreateAnswerCard(w http.ResponseWriter, r *http.R
 	card, err := h.service.CreateAnswerCard(
 		r.Context(), req.Title, req.QueryText, req.QueryResult, req.Explanation,
 		userID, req.WorkspaceID, req.IsPublic, req.SharedWith, req.Tags,
+		req.TemplateID, req.TemplateParams,
 	)
 	if err != nil {
-		WriteError(w, err)
+		writeAnswerCardError(w, err)
 		return
 	}
 
+	if req.ChartSpec != nil {
+		if err := h.service.SetAnswerCardChart(r.Context(), card.ID, userID, req.ChartSpec); err != nil {
+			WriteError(w, err)
+			return
+		}
+		card.ChartSpec = req.ChartSpec
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(card)
 }
 
+/* GetAnswerCard handles GET /api/v1/collaboration/answer-cards/{id} */
+func (h *CollaborationHandler) GetAnswerCard(w http.ResponseWriter, r *http.Request) {
+	cardID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid answer card ID"))
+		return
+	}
+
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		WriteErrorResponse(w, errors.Unauthorized("User ID required"))
+		return
+	}
+
+	card, err := h.service.GetAnswerCard(r.Context(), cardID, userID)
+	if err != nil {
+		writeAnswerCardError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(card)
+}
+
+/* RefreshAnswerCard handles POST /api/v1/collaboration/answer-cards/{id}/refresh */
+func (h *CollaborationHandler) RefreshAnswerCard(w http.ResponseWriter, r *http.Request) {
+	cardID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid answer card ID"))
+		return
+	}
+
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		WriteErrorResponse(w, errors.Unauthorized("User ID required"))
+		return
+	}
+
+	card, err := h.service.RefreshAnswerCard(r.Context(), cardID, userID)
+	if err != nil {
+		writeAnswerCardError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(card)
+}
+
+/* writeAnswerCardError maps errors from loading, creating or refreshing an answer card to responses */
+func writeAnswerCardError(w http.ResponseWriter, err error) {
+	if collaboration.IsNotFound(err) {
+		WriteErrorResponse(w, errors.NotFound("Answer card"))
+		return
+	}
+	if collaboration.IsNoTemplate(err) {
+		WriteErrorResponse(w, errors.BadRequest(err.Error()))
+		return
+	}
+	writeTemplateError(w, err)
+}
+
+/* chartSpecRequest is a Vega-Lite spec chosen by a user, typically one of a query's recommended charts */
+type chartSpecRequest struct {
+	Spec map[string]interface{} `json:"spec"`
+}
+
+/* SetAnswerCardChart handles PUT /api/v1/collaboration/answer-cards/{id}/chart */
+func (h *CollaborationHandler) SetAnswerCardChart(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	cardID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid answer card ID"))
+		return
+	}
+
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		WriteErrorResponse(w, errors.Unauthorized("User ID required"))
+		return
+	}
+
+	var req chartSpecRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+	if err := warehouse.ValidateChartSpec(req.Spec); err != nil {
+		WriteErrorResponse(w, errors.ValidationFailed(err.Error(), nil))
+		return
+	}
+
+	if err := h.service.SetAnswerCardChart(r.Context(), cardID, userID, req.Spec); err != nil {
+		writeChartError(w, "Answer card", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": cardID, "chart_spec": req.Spec})
+}
+
+/* SetDashboardChart handles PUT /api/v1/collaboration/dashboards/{id}/charts/{key} */
+func (h *CollaborationHandler) SetDashboardChart(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	dashboardID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid dashboard ID"))
+		return
+	}
+
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		WriteErrorResponse(w, errors.Unauthorized("User ID required"))
+		return
+	}
+
+	var req chartSpecRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+	if err := warehouse.ValidateChartSpec(req.Spec); err != nil {
+		WriteErrorResponse(w, errors.ValidationFailed(err.Error(), nil))
+		return
+	}
+
+	if err := h.service.SetDashboardChart(r.Context(), dashboardID, userID, vars["key"], req.Spec); err != nil {
+		writeChartError(w, "Dashboard", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": dashboardID, "key": vars["key"], "spec": req.Spec})
+}
+
+/* writeChartError maps errors from saving a chart to responses */
+func writeChartError(w http.ResponseWriter, resource string, err error) {
+	if collaboration.IsNotFound(err) {
+		WriteErrorResponse(w, errors.NotFound(resource))
+		return
+	}
+	if collaboration.IsNotOwner(err) {
+		WriteErrorResponse(w, errors.Forbidden(err.Error()))
+		return
+	}
+	WriteError(w, err)
+}
+
 /* SaveQuestion handles POST /api/v1/collaboration/saved-questions */
 func (h *CollaborationHandler) SaveQuestion(w http.ResponseWriter, r *http.Request) {
 	var req struct {
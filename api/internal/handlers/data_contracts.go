@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/neurondb/NeuronIP/api/internal/auth"
+	"github.com/neurondb/NeuronIP/api/internal/catalog"
+	"github.com/neurondb/NeuronIP/api/internal/errors"
+)
+
+/* DataContractHandler handles data contract requests */
+type DataContractHandler struct {
+	service *catalog.ContractService
+}
+
+/* NewDataContractHandler creates a new data contract handler */
+func NewDataContractHandler(service *catalog.ContractService) *DataContractHandler {
+	return &DataContractHandler{service: service}
+}
+
+/* CreateContract handles POST /api/v1/catalog/contracts; the caller owns the contract unless owner_id is given */
+func (h *DataContractHandler) CreateContract(w http.ResponseWriter, r *http.Request) {
+	var req catalog.DataContract
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+	if req.OwnerID == uuid.Nil {
+		if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+			req.OwnerID, _ = uuid.Parse(uid)
+		}
+	}
+
+	contract, err := h.service.CreateContract(r.Context(), req)
+	if contractErr := catalog.AsContractError(err); contractErr != nil {
+		WriteErrorResponse(w, errors.ValidationFailed(contractErr.Error(), nil))
+		return
+	}
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(contract)
+}
+
+/* ListContracts handles GET /api/v1/catalog/contracts */
+func (h *DataContractHandler) ListContracts(w http.ResponseWriter, r *http.Request) {
+	var connectorID *uuid.UUID
+	if c := r.URL.Query().Get("connector_id"); c != "" {
+		id, err := uuid.Parse(c)
+		if err != nil {
+			WriteErrorResponse(w, errors.BadRequest("Invalid connector ID"))
+			return
+		}
+		connectorID = &id
+	}
+
+	contracts, err := h.service.ListContracts(r.Context(), connectorID, r.URL.Query().Get("status"))
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contracts)
+}
+
+/* GetContract handles GET /api/v1/catalog/contracts/{id} */
+func (h *DataContractHandler) GetContract(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid contract ID"))
+		return
+	}
+
+	contract, err := h.service.GetContract(r.Context(), id)
+	if err != nil {
+		WriteErrorResponse(w, errors.NotFound("Contract"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contract)
+}
+
+/* GetContractVersions handles GET /api/v1/catalog/contracts/{connector_id}/{schema_name}/{table_name}/versions */
+func (h *DataContractHandler) GetContractVersions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	connectorID, err := uuid.Parse(vars["connector_id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid connector ID"))
+		return
+	}
+
+	versions, err := h.service.GetContractVersions(r.Context(), connectorID, vars["schema_name"], vars["table_name"])
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+/* ActivateContract handles POST /api/v1/catalog/contracts/{id}/activate */
+func (h *DataContractHandler) ActivateContract(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid contract ID"))
+		return
+	}
+
+	userID, ok := contractCaller(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		AcknowledgeBreaking bool `json:"acknowledge_breaking"`
+	}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+			return
+		}
+	}
+
+	contract, err := h.service.ActivateContract(r.Context(), id, userID, req.AcknowledgeBreaking)
+	if err != nil {
+		writeContractStatusError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contract)
+}
+
+/* RetireContract handles POST /api/v1/catalog/contracts/{id}/retire */
+func (h *DataContractHandler) RetireContract(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid contract ID"))
+		return
+	}
+
+	userID, ok := contractCaller(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.RetireContract(r.Context(), id, userID); err != nil {
+		writeContractStatusError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* contractCaller returns the caller's user ID, writing an error response when there is none */
+func contractCaller(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	uid, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteErrorResponse(w, errors.Unauthorized("User not authenticated"))
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(uid)
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid user ID"))
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+/* writeContractStatusError maps errors from activating or retiring a contract to responses */
+func writeContractStatusError(w http.ResponseWriter, err error) {
+	if catalog.IsContractNotFound(err) {
+		WriteErrorResponse(w, errors.NotFound("Contract"))
+		return
+	}
+	if catalog.IsContractNotOwner(err) {
+		WriteErrorResponse(w, errors.Forbidden(err.Error()))
+		return
+	}
+	if contractErr := catalog.AsContractError(err); contractErr != nil {
+		WriteErrorResponse(w, errors.ValidationFailed(contractErr.Error(), nil))
+		return
+	}
+	WriteError(w, err)
+}
+
+/* ListViolations handles GET /api/v1/catalog/contracts/{id}/violations */
+func (h *DataContractHandler) ListViolations(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid contract ID"))
+		return
+	}
+
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	violations, err := h.service.ListViolations(r.Context(), id, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(violations)
+}
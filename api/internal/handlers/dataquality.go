@@ -7,6 +7,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/neurondb/NeuronIP/api/internal/anomaly"
+	"github.com/neurondb/NeuronIP/api/internal/auth"
 	"github.com/neurondb/NeuronIP/api/internal/dataquality"
 	"github.com/neurondb/NeuronIP/api/internal/errors"
 )
@@ -111,3 +113,90 @@ func (h *DataQualityHandler) GetTrends(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(trends)
 }
+
+/* GetTrendBand gets a quality trend with its learned band, explaining whether the latest score is expected */
+func (h *DataQualityHandler) GetTrendBand(w http.ResponseWriter, r *http.Request) {
+	level := r.URL.Query().Get("level")
+	if level == "" {
+		level = "overall"
+	}
+
+	days := 90
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		fmt.Sscanf(daysStr, "%d", &days)
+	}
+	var opts anomaly.BandOptions
+	if sensitivityStr := r.URL.Query().Get("sensitivity"); sensitivityStr != "" {
+		fmt.Sscanf(sensitivityStr, "%g", &opts.Sensitivity)
+	}
+
+	trend, err := h.service.GetQualityTrendBand(r.Context(), level, days, opts)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trend)
+}
+
+/* GetRuleBand gets the learned band a rule is graded against */
+func (h *DataQualityHandler) GetRuleBand(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid rule ID"))
+		return
+	}
+
+	band, err := h.service.GetRuleBand(r.Context(), id)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(band)
+}
+
+/* ImportRules imports a dbt schema YAML file or a Great Expectations suite as quality rules */
+func (h *DataQualityHandler) ImportRules(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		dataquality.ImportOptions
+		Format  string          `json:"format"`  // dbt or great_expectations
+		Content string          `json:"content"` // dbt YAML or suite JSON as text
+		Suite   json.RawMessage `json:"suite"`   // Suite JSON inline, instead of content
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+	content := []byte(req.Content)
+	if len(req.Suite) > 0 {
+		content = req.Suite
+	}
+	if req.Format == "" || len(content) == 0 {
+		WriteErrorResponse(w, errors.BadRequest("format and content are required"))
+		return
+	}
+	if req.ConnectorID == uuid.Nil {
+		WriteErrorResponse(w, errors.BadRequest("connector_id is required"))
+		return
+	}
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		req.CreatedBy = &uid
+	}
+
+	report, err := h.service.ImportTests(r.Context(), req.Format, content, req.ImportOptions)
+	if importErr := dataquality.AsImportError(err); importErr != nil {
+		WriteErrorResponse(w, errors.BadRequest(importErr.Message))
+		return
+	}
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
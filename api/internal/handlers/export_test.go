@@ -0,0 +1,4 @@
+package handlers
+
+/* WriteResultStream exposes writeResultStream to tests that run it behind the middleware package */
+var WriteResultStream = writeResultStream
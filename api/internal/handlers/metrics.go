@@ -314,6 +314,35 @@ func (h *MetricsHandler) ApproveMetric(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+/* SetMetricDefinition handles PUT /api/v1/catalog/metrics/{id}/definition */
+func (h *MetricsHandler) SetMetricDefinition(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid metric ID"))
+		return
+	}
+
+	var req catalog.MetricDefinition
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+
+	metric, err := h.catalogService.SetMetricDefinition(r.Context(), id, &req)
+	if err != nil {
+		if compileErr := catalog.AsMetricCompileError(err); compileErr != nil {
+			WriteErrorResponse(w, errors.ValidationFailed(compileErr.Error(), nil))
+			return
+		}
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(metric)
+}
+
 /* DiscoverMetrics handles POST /api/v1/metrics/discover */
 func (h *MetricsHandler) DiscoverMetrics(w http.ResponseWriter, r *http.Request) {
 	var req metrics.MetricDiscoveryCriteria
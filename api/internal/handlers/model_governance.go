@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -173,23 +172,6 @@ func (h *ModelGovernanceHandler) ListPrompts(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(prompts)
-}
-		if parentID.Valid {
-			pid, _ := uuid.Parse(parentID.String)
-			prompt.ParentTemplateID = &pid
-		}
-		if variablesJSON != nil {
-			json.Unmarshal(variablesJSON, &prompt.Variables)
-		}
-		if metadataJSON != nil {
-			json.Unmarshal(metadataJSON, &prompt.Metadata)
-		}
-		
-		prompts = append(prompts, prompt)
-	}
-	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(prompts)
 }
@@ -3,16 +3,20 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/neurondb/NeuronIP/api/internal/alerts"
+	"github.com/neurondb/NeuronIP/api/internal/auth"
 	"github.com/neurondb/NeuronIP/api/internal/errors"
 	"github.com/neurondb/NeuronIP/api/internal/profiling"
 )
 
 /* ProfilingHandler handles data profiling requests */
 type ProfilingHandler struct {
-	service *profiling.Service
+	service     *profiling.Service
+	driftAlerts *alerts.DriftAlertService
 }
 
 /* NewProfilingHandler creates a new profiling handler */
@@ -20,6 +24,39 @@ func NewProfilingHandler(service *profiling.Service) *ProfilingHandler {
 	return &ProfilingHandler{service: service}
 }
 
+/* NewProfilingHandlerWithDriftAlerts creates a profiling handler whose drift checks can raise alerts */
+func NewProfilingHandlerWithDriftAlerts(service *profiling.Service, driftAlerts *alerts.DriftAlertService) *ProfilingHandler {
+	return &ProfilingHandler{service: service, driftAlerts: driftAlerts}
+}
+
+/* profileOptions are the optional body of a profiling request, choosing the mode and sketch sampling */
+type profileOptions struct {
+	Mode            string   `json:"mode"`
+	SampleMethod    string   `json:"sample_method"`
+	SamplePercent   *float64 `json:"sample_percent"`
+	SampleSize      *int     `json:"sample_size"`
+	PartitionColumn *string  `json:"partition_column"`
+	PartitionValue  *string  `json:"partition_value"`
+}
+
+/* decodeProfileOptions applies the request body's options, if any, to req */
+func decodeProfileOptions(r *http.Request, req *profiling.ProfileRequest) error {
+	if r.ContentLength == 0 {
+		return nil
+	}
+	var opts profileOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		return err
+	}
+	req.Mode = opts.Mode
+	req.SampleMethod = opts.SampleMethod
+	req.SamplePercent = opts.SamplePercent
+	req.SampleSize = opts.SampleSize
+	req.PartitionColumn = opts.PartitionColumn
+	req.PartitionValue = opts.PartitionValue
+	return nil
+}
+
 /* ProfileTable profiles a table */
 func (h *ProfilingHandler) ProfileTable(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -37,6 +74,10 @@ func (h *ProfilingHandler) ProfileTable(w http.ResponseWriter, r *http.Request)
 		SchemaName:  schemaName,
 		TableName:   tableName,
 	}
+	if err := decodeProfileOptions(r, &req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
 
 	result, err := h.service.ProfileTable(r.Context(), req)
 	if err != nil {
@@ -67,6 +108,10 @@ func (h *ProfilingHandler) ProfileColumn(w http.ResponseWriter, r *http.Request)
 		TableName:   tableName,
 		ColumnName:  &columnName,
 	}
+	if err := decodeProfileOptions(r, &req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
 
 	result, err := h.service.ProfileColumn(r.Context(), req)
 	if err != nil {
@@ -77,3 +122,224 @@ func (h *ProfilingHandler) ProfileColumn(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
+
+/* GetSketchProfile handles GET .../columns/{column_name}/sketch, merging the column's stored sketches without
+ * reading the table; the partition_column query parameter selects the partitioned sketches */
+func (h *ProfilingHandler) GetSketchProfile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	connectorID, err := uuid.Parse(vars["connector_id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid connector ID"))
+		return
+	}
+
+	columnName := vars["column_name"]
+	req := profiling.ProfileRequest{
+		ConnectorID: connectorID,
+		SchemaName:  vars["schema_name"],
+		TableName:   vars["table_name"],
+		ColumnName:  &columnName,
+	}
+	if p := r.URL.Query().Get("partition_column"); p != "" {
+		req.PartitionColumn = &p
+	}
+
+	result, err := h.service.GetMergedSketchProfile(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+/* DiscoverStructure handles POST .../tables/{table_name}/structure, finding candidate keys, functional and
+ * inclusion dependencies and correlations from a sample of the table */
+func (h *ProfilingHandler) DiscoverStructure(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	connectorID, err := uuid.Parse(vars["connector_id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid connector ID"))
+		return
+	}
+
+	var req profiling.StructureRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+			return
+		}
+	}
+	req.ConnectorID = connectorID
+	req.SchemaName = vars["schema_name"]
+	req.TableName = vars["table_name"]
+
+	structure, err := h.service.DiscoverStructure(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(structure)
+}
+
+/* GetStructure handles GET .../tables/{table_name}/structure */
+func (h *ProfilingHandler) GetStructure(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	connectorID, err := uuid.Parse(vars["connector_id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid connector ID"))
+		return
+	}
+
+	structure, err := h.service.GetTableStructure(r.Context(), connectorID, vars["schema_name"], vars["table_name"])
+	if err != nil {
+		WriteErrorResponse(w, errors.NotFound("Table structure"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(structure)
+}
+
+/* ListSuggestedJoins handles GET /api/v1/profiling/suggested-joins */
+func (h *ProfilingHandler) ListSuggestedJoins(w http.ResponseWriter, r *http.Request) {
+	var connectorID *uuid.UUID
+	if c := r.URL.Query().Get("connector_id"); c != "" {
+		id, err := uuid.Parse(c)
+		if err != nil {
+			WriteErrorResponse(w, errors.BadRequest("Invalid connector ID"))
+			return
+		}
+		connectorID = &id
+	}
+
+	joins, err := h.service.ListSuggestedJoins(r.Context(), connectorID, r.URL.Query().Get("schema_name"), r.URL.Query().Get("status"))
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(joins)
+}
+
+/* ReviewSuggestedJoin handles PUT /api/v1/profiling/suggested-joins/{id}, accepting or rejecting a join */
+func (h *ProfilingHandler) ReviewSuggestedJoin(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid suggested join ID"))
+		return
+	}
+
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+	if req.Status != profiling.JoinSuggested && req.Status != profiling.JoinAccepted && req.Status != profiling.JoinRejected {
+		WriteErrorResponse(w, errors.BadRequest("status must be suggested, accepted or rejected"))
+		return
+	}
+	reviewer, _ := auth.GetUserIDFromContext(r.Context())
+
+	join, err := h.service.ReviewSuggestedJoin(r.Context(), id, req.Status, reviewer)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(join)
+}
+
+/* ListSnapshots handles GET .../tables/{table_name}/snapshots, newest first */
+func (h *ProfilingHandler) ListSnapshots(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	connectorID, err := uuid.Parse(vars["connector_id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid connector ID"))
+		return
+	}
+
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	snapshots, err := h.service.ListSnapshots(r.Context(), connectorID, vars["schema_name"], vars["table_name"], limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshots)
+}
+
+/* GetSnapshot handles GET /api/v1/profiling/snapshots/{id} */
+func (h *ProfilingHandler) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid snapshot ID"))
+		return
+	}
+
+	snapshot, err := h.service.GetSnapshot(r.Context(), id)
+	if err != nil {
+		WriteErrorResponse(w, errors.NotFound("Profile snapshot"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+/* CompareSnapshots handles POST /api/v1/profiling/drift, comparing baseline_id with current_id, or else the
+ * table's two newest snapshots; with an alert_rule_id, drift past the thresholds raises an alert */
+func (h *ProfilingHandler) CompareSnapshots(w http.ResponseWriter, r *http.Request) {
+	var req alerts.DriftCheck
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+	if (req.BaselineID == nil || req.CurrentID == nil) && (req.ConnectorID == uuid.Nil || req.SchemaName == "" || req.TableName == "") {
+		WriteErrorResponse(w, errors.BadRequest("baseline_id and current_id, or connector_id, schema_name and table_name, are required"))
+		return
+	}
+	if req.AlertRuleID != nil && h.driftAlerts == nil {
+		WriteErrorResponse(w, errors.BadRequest("Drift alerts are not configured"))
+		return
+	}
+
+	var report *profiling.DriftReport
+	var alert *alerts.Alert
+	var err error
+	if h.driftAlerts != nil {
+		report, alert, err = h.driftAlerts.CheckDrift(r.Context(), req)
+	} else if req.BaselineID != nil && req.CurrentID != nil {
+		report, err = h.service.CompareSnapshots(r.Context(), *req.BaselineID, *req.CurrentID, req.Thresholds)
+	} else {
+		report, err = h.service.CompareLatestSnapshots(r.Context(), req.ConnectorID, req.SchemaName, req.TableName, req.Thresholds)
+	}
+	if err != nil && report == nil {
+		WriteError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{"drift": report}
+	if alert != nil {
+		response["alert"] = alert
+	}
+	if err != nil {
+		response["alert_error"] = err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/neurondb/NeuronIP/api/internal/auth"
+	"github.com/neurondb/NeuronIP/api/internal/errors"
+	"github.com/neurondb/NeuronIP/api/internal/warehouse"
+)
+
+/* QueryTemplateHandler handles parameterized query template requests */
+type QueryTemplateHandler struct {
+	service *warehouse.QueryTemplateService
+}
+
+/* NewQueryTemplateHandler creates a new query template handler */
+func NewQueryTemplateHandler(service *warehouse.QueryTemplateService) *QueryTemplateHandler {
+	return &QueryTemplateHandler{service: service}
+}
+
+/* writeTemplateError maps parameter and SQL validation errors to validation failures, unknown or hidden templates to not found,
+ * and templates still in use to conflicts */
+func writeTemplateError(w http.ResponseWriter, err error) {
+	if warehouse.IsTemplateNotFound(err) {
+		WriteErrorResponse(w, errors.NotFound("Query template"))
+		return
+	}
+	if warehouse.IsTemplateInUse(err) {
+		WriteErrorResponse(w, errors.Conflict(err.Error()))
+		return
+	}
+	if paramErr := warehouse.AsTemplateParameterError(err); paramErr != nil {
+		WriteErrorResponse(w, errors.ValidationFailed(paramErr.Error(), paramErr.Violations))
+		return
+	}
+	if validationErr := warehouse.AsSQLValidationError(err); validationErr != nil {
+		WriteErrorResponse(w, errors.ValidationFailed(validationErr.Error(), validationErr.Violations))
+		return
+	}
+	WriteError(w, err)
+}
+
+/* CreateTemplate handles POST /api/v1/warehouse/templates */
+func (h *QueryTemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var template warehouse.QueryTemplate
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+
+	if template.SQL == "" {
+		WriteErrorResponse(w, errors.ValidationFailed("sql is required", nil))
+		return
+	}
+
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		template.OwnerID = &uid
+	}
+
+	created, err := h.service.CreateTemplate(r.Context(), template)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+/* GetTemplate handles GET /api/v1/warehouse/templates/{id} */
+func (h *QueryTemplateHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	templateID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid template ID"))
+		return
+	}
+
+	var userID *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		userID = &uid
+	}
+
+	template, err := h.service.GetTemplate(r.Context(), templateID, userID)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template)
+}
+
+/* ListTemplates handles GET /api/v1/warehouse/templates */
+func (h *QueryTemplateHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	var userID *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		userID = &uid
+	}
+
+	templates, err := h.service.ListTemplates(r.Context(), userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
+/* DeleteTemplate handles DELETE /api/v1/warehouse/templates/{id} */
+func (h *QueryTemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	templateID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid template ID"))
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteErrorResponse(w, errors.Unauthorized("User ID required"))
+		return
+	}
+
+	if err := h.service.DeleteTemplate(r.Context(), templateID, userID); err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* ExecuteTemplate handles POST /api/v1/warehouse/templates/{id}/execute */
+func (h *QueryTemplateHandler) ExecuteTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	templateID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid template ID"))
+		return
+	}
+
+	var req struct {
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+			return
+		}
+	}
+
+	var userID *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		userID = &uid
+	}
+
+	result, err := h.service.ExecuteTemplate(r.Context(), templateID, userID, req.Parameters)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
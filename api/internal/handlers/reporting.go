@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/neurondb/NeuronIP/api/internal/auth"
 	"github.com/neurondb/NeuronIP/api/internal/errors"
 	"github.com/neurondb/NeuronIP/api/internal/reporting"
 )
@@ -29,9 +30,17 @@ func (h *ReportingHandler) CreateReport(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Template sections run with the creator's access, so the creator comes from the session rather than the body
+	report.CreatedBy = uuid.Nil
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		if createdBy, err := uuid.Parse(uid); err == nil {
+			report.CreatedBy = createdBy
+		}
+	}
+
 	created, err := h.reportingService.CreateReport(r.Context(), report)
 	if err != nil {
-		WriteError(w, err)
+		writeTemplateError(w, err)
 		return
 	}
 
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/neurondb/NeuronIP/api/internal/errors"
+	"github.com/neurondb/NeuronIP/api/internal/observability"
+)
+
+/* RetrievalEvaluationHandler handles offline retrieval evaluation requests */
+type RetrievalEvaluationHandler struct {
+	service *observability.RetrievalEvaluationService
+}
+
+/* NewRetrievalEvaluationHandler creates a new retrieval evaluation handler */
+func NewRetrievalEvaluationHandler(service *observability.RetrievalEvaluationService) *RetrievalEvaluationHandler {
+	return &RetrievalEvaluationHandler{service: service}
+}
+
+/* CreateGoldenSet handles POST /api/v1/observability/retrieval/golden-sets */
+func (h *RetrievalEvaluationHandler) CreateGoldenSet(w http.ResponseWriter, r *http.Request) {
+	var set observability.GoldenQuerySet
+	if err := json.NewDecoder(r.Body).Decode(&set); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+
+	if set.Name == "" {
+		WriteErrorResponse(w, errors.ValidationFailed("name is required", nil))
+		return
+	}
+
+	created, err := h.service.CreateGoldenQuerySet(r.Context(), set)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+/* ListGoldenSets handles GET /api/v1/observability/retrieval/golden-sets */
+func (h *RetrievalEvaluationHandler) ListGoldenSets(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	sets, err := h.service.ListGoldenQuerySets(r.Context(), limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sets)
+}
+
+/* GetGoldenSet handles GET /api/v1/observability/retrieval/golden-sets/{id} */
+func (h *RetrievalEvaluationHandler) GetGoldenSet(w http.ResponseWriter, r *http.Request) {
+	setID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid golden set ID"))
+		return
+	}
+
+	set, err := h.service.GetGoldenQuerySet(r.Context(), setID)
+	if err != nil {
+		WriteErrorResponse(w, errors.NotFound("Golden query set"))
+		return
+	}
+
+	queries, err := h.service.ListGoldenQueries(r.Context(), setID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"set":     set,
+		"queries": queries,
+	})
+}
+
+/* AddGoldenQuery handles POST /api/v1/observability/retrieval/golden-sets/{id}/queries */
+func (h *RetrievalEvaluationHandler) AddGoldenQuery(w http.ResponseWriter, r *http.Request) {
+	setID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid golden set ID"))
+		return
+	}
+
+	var query observability.GoldenQuery
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+
+	if query.QueryText == "" || len(query.Judgments) == 0 {
+		WriteErrorResponse(w, errors.ValidationFailed("query_text and judgments are required", nil))
+		return
+	}
+
+	created, err := h.service.AddGoldenQuery(r.Context(), setID, query)
+	if err != nil {
+		WriteErrorResponse(w, errors.ValidationFailed(err.Error(), nil))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+/* DeleteGoldenQuery handles DELETE /api/v1/observability/retrieval/golden-sets/{id}/queries/{query_id} */
+func (h *RetrievalEvaluationHandler) DeleteGoldenQuery(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	setID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid golden set ID"))
+		return
+	}
+	queryID, err := uuid.Parse(vars["query_id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid query ID"))
+		return
+	}
+
+	if err := h.service.DeleteGoldenQuery(r.Context(), setID, queryID); err != nil {
+		WriteErrorResponse(w, errors.NotFound("Golden query"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* RunEvaluation handles POST /api/v1/observability/retrieval/golden-sets/{id}/runs.
+ * A run that regresses against its baseline (passed=false) is returned with 422 so CI can gate on the status code. */
+func (h *RetrievalEvaluationHandler) RunEvaluation(w http.ResponseWriter, r *http.Request) {
+	setID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid golden set ID"))
+		return
+	}
+
+	var req observability.RunEvaluationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+	req.SetID = setID
+
+	run, err := h.service.RunEvaluation(r.Context(), req)
+	if err != nil {
+		if observability.IsInvalidBaseline(err) {
+			WriteErrorResponse(w, errors.ValidationFailed(err.Error(), nil))
+			return
+		}
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if run.Passed != nil && !*run.Passed {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(run)
+}
+
+/* ListEvaluationRuns handles GET /api/v1/observability/retrieval/golden-sets/{id}/runs */
+func (h *RetrievalEvaluationHandler) ListEvaluationRuns(w http.ResponseWriter, r *http.Request) {
+	setID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid golden set ID"))
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	runs, err := h.service.ListEvaluationRuns(r.Context(), setID, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+/* SetBaselineRun handles PUT /api/v1/observability/retrieval/golden-sets/{id}/baseline */
+func (h *RetrievalEvaluationHandler) SetBaselineRun(w http.ResponseWriter, r *http.Request) {
+	setID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid golden set ID"))
+		return
+	}
+
+	var req struct {
+		RunID uuid.UUID `json:"run_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := h.service.SetBaselineRun(r.Context(), setID, req.RunID); err != nil {
+		WriteErrorResponse(w, errors.ValidationFailed(err.Error(), nil))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"set_id":          setID,
+		"baseline_run_id": req.RunID,
+	})
+}
+
+/* GetEvaluationRun handles GET /api/v1/observability/retrieval/evaluation-runs/{id} */
+func (h *RetrievalEvaluationHandler) GetEvaluationRun(w http.ResponseWriter, r *http.Request) {
+	runID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid run ID"))
+		return
+	}
+
+	run, err := h.service.GetEvaluationRun(r.Context(), runID)
+	if err != nil {
+		WriteErrorResponse(w, errors.NotFound("Evaluation run"))
+		return
+	}
+
+	results, err := h.service.GetEvaluationResults(r.Context(), runID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"run":     run,
+		"results": results,
+	})
+}
+
+/* CompareEvaluationRuns handles GET /api/v1/observability/retrieval/evaluation-runs/{id}/compare */
+func (h *RetrievalEvaluationHandler) CompareEvaluationRuns(w http.ResponseWriter, r *http.Request) {
+	runID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid run ID"))
+		return
+	}
+
+	baselineID, err := uuid.Parse(r.URL.Query().Get("baseline_run_id"))
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("baseline_run_id is required"))
+		return
+	}
+
+	comparison, err := h.service.CompareRuns(r.Context(), baselineID, runID)
+	if err != nil {
+		if observability.IsInvalidBaseline(err) {
+			WriteErrorResponse(w, errors.ValidationFailed(err.Error(), nil))
+			return
+		}
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comparison)
+}
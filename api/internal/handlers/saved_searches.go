@@ -91,16 +91,20 @@ func (h *SavedSearchHandler) ExecuteSavedSearch(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	result, err := h.service.ExecuteSavedSearch(r.Context(), searchID, h.warehouseService)
-	if err != nil {
-		WriteError(w, err)
-		return
+	// Template-backed searches accept parameter values overriding the stored ones
+	var req struct {
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+			return
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	result, err := h.service.ExecuteSavedSearchWithParams(r.Context(), searchID, h.warehouseService, req.Parameters)
 	if err != nil {
-		WriteError(w, err)
+		writeTemplateError(w, err)
 		return
 	}
 
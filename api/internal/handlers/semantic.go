@@ -7,6 +7,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/neurondb/NeuronIP/api/internal/auth"
+	"github.com/neurondb/NeuronIP/api/internal/db"
 	"github.com/neurondb/NeuronIP/api/internal/errors"
 	"github.com/neurondb/NeuronIP/api/internal/semantic"
 )
@@ -566,3 +568,238 @@ func (h *SemanticHandler) RemoveMetricOwner(w http.ResponseWriter, r *http.Reque
 
 	w.WriteHeader(http.StatusOK)
 }
+
+/* GetDocumentDuplicates handles GET /api/v1/semantic/documents/{id}/duplicates */
+func (h *SemanticHandler) GetDocumentDuplicates(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid document ID"))
+		return
+	}
+
+	duplicates, err := h.semanticService.ListDocumentDuplicates(r.Context(), id)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"canonical_document_id": id,
+		"duplicates":            duplicates,
+	})
+}
+
+/* AdviseVectorIndex handles POST /api/v1/semantic/indexes/advise */
+func (h *SemanticHandler) AdviseVectorIndex(w http.ResponseWriter, r *http.Request) {
+	var req semantic.AdviseIndexRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+
+	if req.TableName == "" || req.ColumnName == "" {
+		WriteErrorResponse(w, errors.ValidationFailed("table_name and column_name are required", nil))
+		return
+	}
+
+	advisory, err := h.semanticService.AdviseVectorIndex(r.Context(), req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(advisory)
+}
+
+/* GetVectorIndexStatus handles GET /api/v1/semantic/indexes/{name}/status */
+func (h *SemanticHandler) GetVectorIndexStatus(w http.ResponseWriter, r *http.Request) {
+	indexName := mux.Vars(r)["name"]
+
+	status, err := h.semanticService.GetIndexStatus(r.Context(), indexName)
+	if err != nil {
+		WriteErrorResponse(w, errors.NotFound("Vector index"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+/* SemanticSearchRequest is the body of POST /api/v1/semantic/search and /api/v1/semantic/rag */
+type SemanticSearchRequest struct {
+	Query              string     `json:"query"`
+	CollectionID       *uuid.UUID `json:"collection_id,omitempty"`
+	Limit              int        `json:"limit"`
+	Threshold          float64    `json:"threshold"`
+	DistanceMetric     string     `json:"distance_metric,omitempty"`
+	CollapseDuplicates bool       `json:"collapse_duplicates,omitempty"`
+	MaxContext         int        `json:"max_context,omitempty"`
+}
+
+/* Search handles POST /api/v1/semantic/search */
+func (h *SemanticHandler) Search(w http.ResponseWriter, r *http.Request) {
+	var req SemanticSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+	if req.Query == "" {
+		WriteErrorResponse(w, errors.ValidationFailed("query is required", nil))
+		return
+	}
+
+	results, err := h.semanticService.Search(r.Context(), semantic.SearchRequest{
+		Query:              req.Query,
+		CollectionID:       req.CollectionID,
+		Limit:              req.Limit,
+		Threshold:          req.Threshold,
+		DistanceMetric:     req.DistanceMetric,
+		CollapseDuplicates: req.CollapseDuplicates,
+	})
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results": results,
+		"count":   len(results),
+	})
+}
+
+/* RAG handles POST /api/v1/semantic/rag */
+func (h *SemanticHandler) RAG(w http.ResponseWriter, r *http.Request) {
+	var req SemanticSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+	if req.Query == "" {
+		WriteErrorResponse(w, errors.ValidationFailed("query is required", nil))
+		return
+	}
+
+	result, err := h.semanticService.RAG(r.Context(), semantic.RAGRequest{
+		Query:        req.Query,
+		CollectionID: req.CollectionID,
+		Limit:        req.Limit,
+		Threshold:    req.Threshold,
+		MaxContext:   req.MaxContext,
+	})
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+/* DocumentRequest is the body of POST /api/v1/semantic/documents and PUT /api/v1/semantic/documents/{id} */
+type DocumentRequest struct {
+	Document       db.KnowledgeDocument `json:"document"`
+	ChunkingConfig *struct {
+		ChunkSize    int   `json:"chunk_size"`
+		ChunkOverlap int   `json:"chunk_overlap"`
+		Enabled      *bool `json:"enabled,omitempty"`
+	} `json:"chunking_config,omitempty"`
+}
+
+/* chunking returns the request's chunking settings, or nil for the service defaults */
+func (req *DocumentRequest) chunking() *semantic.ChunkingConfig {
+	if req.ChunkingConfig == nil {
+		return nil
+	}
+	config := semantic.DefaultChunkingConfig()
+	if req.ChunkingConfig.ChunkSize > 0 {
+		config.ChunkSize = req.ChunkingConfig.ChunkSize
+	}
+	if req.ChunkingConfig.ChunkOverlap >= 0 {
+		config.ChunkOverlap = req.ChunkingConfig.ChunkOverlap
+	}
+	if req.ChunkingConfig.Enabled != nil {
+		config.EnableChunking = *req.ChunkingConfig.Enabled
+	}
+	return &config
+}
+
+/* CreateDocument handles POST /api/v1/semantic/documents */
+func (h *SemanticHandler) CreateDocument(w http.ResponseWriter, r *http.Request) {
+	var req DocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+	if req.Document.Title == "" || req.Document.Content == "" {
+		WriteErrorResponse(w, errors.ValidationFailed("document title and content are required", nil))
+		return
+	}
+	if req.Document.ContentType == "" {
+		req.Document.ContentType = "document"
+	}
+
+	var userID *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		userID = &uid
+	}
+	if err := h.semanticService.CreateDocument(r.Context(), &req.Document, req.chunking(), userID); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(req.Document)
+}
+
+/* UpdateDocument handles PUT /api/v1/semantic/documents/{id} */
+func (h *SemanticHandler) UpdateDocument(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid document ID"))
+		return
+	}
+
+	var req DocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+
+	var userID *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		userID = &uid
+	}
+	if err := h.semanticService.UpdateDocument(r.Context(), id, &req.Document, req.chunking(), userID); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      id,
+		"updated": true,
+	})
+}
+
+/* GetCollection handles GET /api/v1/semantic/collections/{id} */
+func (h *SemanticHandler) GetCollection(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid collection ID"))
+		return
+	}
+
+	collection, err := h.semanticService.GetCollection(r.Context(), id)
+	if err != nil {
+		WriteErrorResponse(w, errors.NotFound("Collection"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(collection)
+}
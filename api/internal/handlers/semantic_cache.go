@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/neurondb/NeuronIP/api/internal/cache"
+	"github.com/neurondb/NeuronIP/api/internal/errors"
+)
+
+/* SemanticCacheHandler handles semantic answer cache requests */
+type SemanticCacheHandler struct {
+	service *cache.SemanticCacheService
+}
+
+/* NewSemanticCacheHandler creates a new semantic cache handler */
+func NewSemanticCacheHandler(service *cache.SemanticCacheService) *SemanticCacheHandler {
+	return &SemanticCacheHandler{service: service}
+}
+
+/* GetStats handles GET /api/v1/cache/semantic/stats */
+func (h *SemanticCacheHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.GetStats(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled":    h.service.Enabled(),
+		"namespaces": stats,
+	})
+}
+
+/* Invalidate handles POST /api/v1/cache/semantic/invalidate */
+func (h *SemanticCacheHandler) Invalidate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Namespace   string      `json:"namespace,omitempty"`
+		DocumentIDs []uuid.UUID `json:"document_ids,omitempty"`
+		Tables      []string    `json:"tables,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+
+	var invalidated int64
+	switch {
+	case len(req.DocumentIDs) > 0 || len(req.Tables) > 0:
+		count, err := h.service.InvalidateDocuments(r.Context(), req.DocumentIDs)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		invalidated += count
+
+		count, err = h.service.InvalidateTables(r.Context(), req.Tables)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		invalidated += count
+	case req.Namespace == "" || req.Namespace == cache.NamespaceRAG || req.Namespace == cache.NamespaceWarehouse:
+		count, err := h.service.InvalidateNamespace(r.Context(), req.Namespace)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		invalidated = count
+	default:
+		WriteErrorResponse(w, errors.ValidationFailed("namespace must be rag or warehouse", nil))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"invalidated": invalidated,
+	})
+}
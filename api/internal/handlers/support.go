@@ -204,3 +204,22 @@ func (h *SupportHandler) GetSimilarCases(w http.ResponseWriter, r *http.Request)
 		"count":         len(similarCases),
 	})
 }
+
+/* GenerateReply handles POST /api/v1/support/tickets/{id}/reply */
+func (h *SupportHandler) GenerateReply(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ticketID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid ticket ID"))
+		return
+	}
+
+	reply, err := h.service.GenerateVerifiedReply(r.Context(), ticketID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reply)
+}
@@ -60,15 +60,15 @@ func (h *UnifiedAIHandler) GenerateEmbedding(w http.ResponseWriter, r *http.Requ
 	})
 }
 
-/* ExecuteWorkflowRequest represents a workflow execution request */
-type ExecuteWorkflowRequest struct {
+/* UnifiedWorkflowRequest represents a workflow execution request */
+type UnifiedWorkflowRequest struct {
 	WorkflowID string                 `json:"workflow_id"`
 	Input      map[string]interface{} `json:"input"`
 }
 
 /* ExecuteWorkflow handles POST /api/v1/ai/workflow */
 func (h *UnifiedAIHandler) ExecuteWorkflow(w http.ResponseWriter, r *http.Request) {
-	var req ExecuteWorkflowRequest
+	var req UnifiedWorkflowRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
 		return
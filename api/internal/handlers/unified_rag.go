@@ -2,9 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/neurondb/NeuronIP/api/internal/auth"
 	"github.com/neurondb/NeuronIP/api/internal/errors"
 	"github.com/neurondb/NeuronIP/api/internal/rag"
 )
@@ -26,6 +30,8 @@ type PerformRAGRequest struct {
 	Limit        int    `json:"limit,omitempty"`
 	UseReranking bool   `json:"use_reranking,omitempty"`
 	RerankMethod string `json:"rerank_method,omitempty"`
+	BypassCache  bool   `json:"bypass_cache,omitempty"`
+	ConversationID *uuid.UUID `json:"conversation_id,omitempty"`
 }
 
 /* PerformRAG handles POST /api/v1/rag/query */
@@ -46,12 +52,21 @@ func (h *UnifiedRAGHandler) PerformRAG(w http.ResponseWriter, r *http.Request) {
 		req.Limit = 10
 	}
 
+	// Extract userID from context so cached answers stay within the caller's permission scope
+	var userID *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		userID = &uid
+	}
+
 	ragReq := rag.RAGRequest{
 		Query:        req.Query,
 		CollectionID: req.CollectionID,
 		Limit:        req.Limit,
 		UseReranking: req.UseReranking,
 		RerankMethod: req.RerankMethod,
+		UserID:       userID,
+		BypassCache:  req.BypassCache,
+		ConversationID: req.ConversationID,
 	}
 
 	result, err := h.service.ExecuteRAGPipeline(r.Context(), ragReq)
@@ -82,10 +97,11 @@ func (h *UnifiedRAGHandler) PerformRAGStream(w http.ResponseWriter, r *http.Requ
 		req.Limit = 10
 	}
 
-	// Set up streaming response
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
+	// Extract userID from context so cached answers stay within the caller's permission scope
+	var userID *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		userID = &uid
+	}
 
 	ragReq := rag.RAGRequest{
 		Query:        req.Query,
@@ -93,22 +109,113 @@ func (h *UnifiedRAGHandler) PerformRAGStream(w http.ResponseWriter, r *http.Requ
 		Limit:        req.Limit,
 		UseReranking: req.UseReranking,
 		RerankMethod: req.RerankMethod,
+		UserID:       userID,
+		BypassCache:  req.BypassCache,
+		ConversationID: req.ConversationID,
 	}
 
-	// For now, perform regular RAG and stream the result
-	// In a full implementation, this would stream intermediate results
-	result, err := h.service.ExecuteRAGPipeline(r.Context(), ragReq)
+	// Set up streaming response; the controller reaches the connection through middleware writers
+	controller := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	// The request context is cancelled when the client disconnects, which stops generation
+	emit := func(event rag.StreamEvent) error {
+		return writeSSEEvent(w, controller, event.Type, event.Data)
+	}
+	if _, err := h.service.ExecuteRAGPipelineStream(r.Context(), ragReq, emit); err != nil && r.Context().Err() == nil {
+		writeSSEEvent(w, controller, "error", map[string]interface{}{"message": err.Error()})
+	}
+}
+
+/* writeSSEEvent writes one server-sent event and flushes it to the client */
+func writeSSEEvent(w http.ResponseWriter, controller *http.ResponseController, event string, data interface{}) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, jsonData); err != nil {
+		return err
+	}
+	return controller.Flush()
+}
+
+/* CreateConversation handles POST /api/v1/rag/conversations */
+func (h *UnifiedRAGHandler) CreateConversation(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CollectionID *string `json:"collection_id,omitempty"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+			return
+		}
+	}
+
+	var userID *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		userID = &uid
+	}
+
+	conversation, err := h.service.CreateConversation(r.Context(), userID, req.CollectionID)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	// Stream the result as JSON
-	jsonData, _ := json.Marshal(result)
-	w.Write([]byte("data: " + string(jsonData) + "\n\n"))
-	if flusher, ok := w.(http.Flusher); ok {
-		flusher.Flush()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(conversation)
+}
+
+/* GetConversation handles GET /api/v1/rag/conversations/{id} */
+func (h *UnifiedRAGHandler) GetConversation(w http.ResponseWriter, r *http.Request) {
+	conversationID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid conversation ID"))
+		return
+	}
+
+	var userID *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		userID = &uid
+	}
+
+	conversation, err := h.service.GetConversation(r.Context(), conversationID, userID)
+	if err != nil {
+		WriteErrorResponse(w, errors.NotFound("Conversation"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conversation)
+}
+
+/* DeleteConversation handles DELETE /api/v1/rag/conversations/{id} */
+func (h *UnifiedRAGHandler) DeleteConversation(w http.ResponseWriter, r *http.Request) {
+	conversationID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid conversation ID"))
+		return
 	}
+
+	var userID *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		userID = &uid
+	}
+
+	if err := h.service.DeleteConversation(r.Context(), conversationID, userID); err != nil {
+		WriteErrorResponse(w, errors.NotFound("Conversation"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 /* GetRAGStatus handles GET /api/v1/rag/status */
@@ -4,13 +4,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/neurondb/NeuronIP/api/internal/auth"
+	"github.com/neurondb/NeuronIP/api/internal/catalog"
 	"github.com/neurondb/NeuronIP/api/internal/errors"
 	"github.com/neurondb/NeuronIP/api/internal/metrics"
 	"github.com/neurondb/NeuronIP/api/internal/warehouse"
+	"github.com/neurondb/NeuronIP/api/internal/warehouse/federation"
 )
 
 /* WarehouseHandler handles warehouse query requests */
@@ -29,6 +32,8 @@ type QueryRequest struct {
 	SchemaID      *uuid.UUID             `json:"schema_id,omitempty"`
 	SemanticQuery *string                `json:"semantic_query,omitempty"`
 	SQLFilters    map[string]interface{} `json:"sql_filters,omitempty"`
+	BypassCache   bool                   `json:"bypass_cache,omitempty"`
+	PageSize      int                    `json:"page_size,omitempty"` // Rows per page for cursor queries
 }
 
 /* Query handles warehouse query execution requests */
@@ -56,18 +61,28 @@ func (h *WarehouseHandler) Query(w http.ResponseWriter, r *http.Request) {
 		UserID:        userID,
 		SemanticQuery: req.SemanticQuery,
 		SQLFilters:    req.SQLFilters,
+		BypassCache:   req.BypassCache,
 	}
 
 	result, err := h.service.ExecuteQuery(r.Context(), warehouseReq)
 	if err != nil {
-		WriteError(w, err)
+		writeResultError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Queries moved to async execution are accepted rather than completed
+	if result.Admission != nil && result.Admission.Decision == warehouse.AdmissionAsync {
+		metrics.IncrementWarehouseQuery("async")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(result)
 		return
 	}
 
 	// Increment business metric
 	metrics.IncrementWarehouseQuery("completed")
 
-	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
@@ -114,6 +129,7 @@ func (h *WarehouseHandler) ListSchemas(w http.ResponseWriter, r *http.Request) {
 type CreateSchemaRequest struct {
 	SchemaName   string                 `json:"schema_name"`
 	DatabaseName string                 `json:"database_name"`
+	ConnectorID  *uuid.UUID             `json:"connector_id,omitempty"`
 	Description  *string                `json:"description,omitempty"`
 	Tables       []map[string]interface{} `json:"tables,omitempty"`
 }
@@ -135,7 +151,7 @@ func (h *WarehouseHandler) CreateSchema(w http.ResponseWriter, r *http.Request)
 		req.Tables = []map[string]interface{}{}
 	}
 
-	schema, err := h.service.CreateSchema(r.Context(), req.SchemaName, req.DatabaseName, req.Description, req.Tables)
+	schema, err := h.service.CreateSchema(r.Context(), req.SchemaName, req.DatabaseName, req.ConnectorID, req.Description, req.Tables)
 	if err != nil {
 		WriteError(w, err)
 		return
@@ -192,6 +208,46 @@ func (h *WarehouseHandler) GetQueryHistory(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(history)
 }
 
+/* GetQueryAttempts handles GET /api/v1/warehouse/queries/{id}/attempts: the generated SQL attempts behind a query */
+func (h *WarehouseHandler) GetQueryAttempts(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid query ID"))
+		return
+	}
+
+	attempts, err := h.service.GetQueryAttempts(r.Context(), id)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attempts)
+}
+
+/* GetCorrectionStats handles GET /api/v1/warehouse/queries/corrections?days=...: how often NL-to-SQL needed correction per schema */
+func (h *WarehouseHandler) GetCorrectionStats(w http.ResponseWriter, r *http.Request) {
+	days := 7
+	if d := r.URL.Query().Get("days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed <= 0 {
+			WriteErrorResponse(w, errors.BadRequest("days must be a positive integer"))
+			return
+		}
+		days = parsed
+	}
+
+	stats, err := h.service.GetCorrectionStats(r.Context(), time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
 /* GetQueryOptimization handles query optimization suggestion requests */
 func (h *WarehouseHandler) GetQueryOptimization(w http.ResponseWriter, r *http.Request) {
 	var req struct {
@@ -218,4 +274,336 @@ func (h *WarehouseHandler) GetQueryOptimization(w http.ResponseWriter, r *http.R
 		"suggestions": suggestions,
 		"count":       len(suggestions),
 	})
-}
\ No newline at end of file
+}
+
+/* streamFlushRows is how many rows are written between flushes of a streamed result */
+const streamFlushRows = 100
+
+/* decodeQueryRequest reads a query request and attaches the caller's user ID */
+func decodeQueryRequest(w http.ResponseWriter, r *http.Request) (*QueryRequest, *warehouse.QueryRequest, bool) {
+	var req QueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return nil, nil, false
+	}
+
+	if req.Query == "" {
+		WriteErrorResponse(w, errors.ValidationFailed("query is required", nil))
+		return nil, nil, false
+	}
+
+	var userID *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		userID = &uid
+	}
+
+	return &req, &warehouse.QueryRequest{
+		Query:         req.Query,
+		SchemaID:      req.SchemaID,
+		UserID:        userID,
+		SemanticQuery: req.SemanticQuery,
+		SQLFilters:    req.SQLFilters,
+		BypassCache:   req.BypassCache,
+	}, true
+}
+
+/* writeResultError maps SQL correction, validation, metric compilation, admission and cursor errors to client errors */
+func writeResultError(w http.ResponseWriter, err error) {
+	if correctionErr := warehouse.AsSQLCorrectionError(err); correctionErr != nil {
+		WriteErrorResponse(w, errors.ValidationFailed(correctionErr.Error(), correctionErr.Attempts))
+		return
+	}
+	if planErr := federation.AsPlanError(err); planErr != nil {
+		WriteErrorResponse(w, errors.ValidationFailed(planErr.Error(), nil))
+		return
+	}
+	if compileErr := catalog.AsMetricCompileError(err); compileErr != nil {
+		WriteErrorResponse(w, errors.ValidationFailed(compileErr.Error(), nil))
+		return
+	}
+	if validationErr := warehouse.AsSQLValidationError(err); validationErr != nil {
+		WriteErrorResponse(w, errors.ValidationFailed(validationErr.Error(), validationErr.Violations))
+		return
+	}
+	if admissionErr := warehouse.AsAdmissionError(err); admissionErr != nil {
+		if admissionErr.Code == warehouse.AdmissionErrorQueueTimeout {
+			WriteErrorResponse(w, errors.TooManyRequests(admissionErr.Message).WithDetails(admissionErr.Result))
+			return
+		}
+		WriteErrorResponse(w, errors.Forbidden(admissionErr.Message).WithDetails(admissionErr.Result))
+		return
+	}
+	if cursorErr := warehouse.AsCursorError(err); cursorErr != nil {
+		switch cursorErr.Code {
+		case warehouse.CursorErrorInvalidToken:
+			WriteErrorResponse(w, errors.BadRequest(cursorErr.Message))
+		case warehouse.CursorErrorNotFound:
+			WriteErrorResponse(w, errors.NotFound("Cursor"))
+		case warehouse.CursorErrorOtherReplica:
+			WriteErrorResponse(w, errors.Conflict(cursorErr.Message))
+		default:
+			WriteErrorResponse(w, errors.TooManyRequests(cursorErr.Message))
+		}
+		return
+	}
+	WriteError(w, err)
+}
+
+/* QueryCursor handles POST /api/v1/warehouse/query/cursor: runs a query behind a server-side cursor and returns the first page */
+func (h *WarehouseHandler) QueryCursor(w http.ResponseWriter, r *http.Request) {
+	req, warehouseReq, ok := decodeQueryRequest(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.service.OpenCursor(r.Context(), *warehouseReq, req.PageSize)
+	if err != nil {
+		writeResultError(w, err)
+		return
+	}
+
+	metrics.IncrementWarehouseQuery("completed")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+/* GetResultPage handles GET /api/v1/warehouse/query/pages?page_token=...: returns the page a token points at.
+ * Cursors are held by the replica that opened them, so with several replicas page requests need sticky sessions;
+ * a token sent to another replica is rejected with 409. */
+func (h *WarehouseHandler) GetResultPage(w http.ResponseWriter, r *http.Request) {
+	pageToken := r.URL.Query().Get("page_token")
+	if pageToken == "" {
+		WriteErrorResponse(w, errors.ValidationFailed("page_token is required", nil))
+		return
+	}
+
+	var userID *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		userID = &uid
+	}
+
+	page, err := h.service.NextPage(r.Context(), pageToken, userID)
+	if err != nil {
+		writeResultError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+/* CloseResultCursor handles DELETE /api/v1/warehouse/cursors/{id} */
+func (h *WarehouseHandler) CloseResultCursor(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid cursor ID"))
+		return
+	}
+
+	var userID *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		userID = &uid
+	}
+
+	if err := h.service.CloseCursor(r.Context(), id, userID); err != nil {
+		writeResultError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* StreamQuery handles POST /api/v1/warehouse/query/stream: writes result rows as NDJSON while they are fetched.
+ * Lines are typed events: columns first, then one row per line, then end (or error if the query fails mid-stream). */
+func (h *WarehouseHandler) StreamQuery(w http.ResponseWriter, r *http.Request) {
+	_, warehouseReq, ok := decodeQueryRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := writeResultStream(w, func(emit func(warehouse.ResultStreamEvent) error) error {
+		return h.service.StreamQuery(r.Context(), *warehouseReq, emit)
+	})
+	if err == nil {
+		metrics.IncrementWarehouseQuery("completed")
+	}
+}
+
+/* writeResultStream writes the events produced by run as NDJSON, flushing every streamFlushRows rows.
+ * Errors before the first event become an error response; later ones are written as an error line. */
+func writeResultStream(w http.ResponseWriter, run func(emit func(warehouse.ResultStreamEvent) error) error) error {
+	// The controller reaches the connection through middleware writers
+	controller := http.NewResponseController(w)
+	encoder := json.NewEncoder(w)
+	started := false
+	rows := 0
+	err := run(func(event warehouse.ResultStreamEvent) error {
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+		if event.Type == warehouse.ResultEventRow {
+			rows++
+			if rows%streamFlushRows != 0 {
+				return nil
+			}
+		}
+		return controller.Flush()
+	})
+	if err != nil {
+		if !started {
+			writeResultError(w, err)
+			return err
+		}
+		encoder.Encode(warehouse.ResultStreamEvent{Type: warehouse.ResultEventError, Error: err.Error()})
+		controller.Flush()
+	}
+	return err
+}
+
+/* QueryMetrics handles POST /api/v1/warehouse/metrics/query: runs a query over approved catalog metrics */
+func (h *WarehouseHandler) QueryMetrics(w http.ResponseWriter, r *http.Request) {
+	var req catalog.MetricQuery
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+
+	if len(req.Metrics) == 0 {
+		WriteErrorResponse(w, errors.ValidationFailed("metrics is required", nil))
+		return
+	}
+
+	var userID *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		userID = &uid
+	}
+
+	result, err := h.service.ExecuteMetricQuery(r.Context(), req, userID)
+	if err != nil {
+		writeResultError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if result.Admission != nil && result.Admission.Decision == warehouse.AdmissionAsync {
+		metrics.IncrementWarehouseQuery("async")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	metrics.IncrementWarehouseQuery("completed")
+	json.NewEncoder(w).Encode(result)
+}
+
+/* CompileMetrics handles POST /api/v1/warehouse/metrics/compile: returns the SQL for a metric query without running it */
+func (h *WarehouseHandler) CompileMetrics(w http.ResponseWriter, r *http.Request) {
+	var req catalog.MetricQuery
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+
+	if len(req.Metrics) == 0 {
+		WriteErrorResponse(w, errors.ValidationFailed("metrics is required", nil))
+		return
+	}
+
+	compiled, err := h.service.CompileMetricQuery(r.Context(), req)
+	if err != nil {
+		writeResultError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(compiled)
+}
+
+/* federatedQueryRequest is a SQL query over tables named connector.schema.table */
+type federatedQueryRequest struct {
+	SQL string `json:"sql"`
+}
+
+/* FederatedQuery handles POST /api/v1/warehouse/federated/query: joins tables across data source connectors */
+func (h *WarehouseHandler) FederatedQuery(w http.ResponseWriter, r *http.Request) {
+	var req federatedQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+
+	if req.SQL == "" {
+		WriteErrorResponse(w, errors.ValidationFailed("sql is required", nil))
+		return
+	}
+
+	var userID *string
+	if uid, ok := auth.GetUserIDFromContext(r.Context()); ok {
+		userID = &uid
+	}
+
+	result, err := h.service.ExecuteFederatedQuery(r.Context(), req.SQL, userID)
+	if err != nil {
+		writeResultError(w, err)
+		return
+	}
+
+	metrics.IncrementWarehouseQuery("completed")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+/* PlanFederatedQuery handles POST /api/v1/warehouse/federated/plan: shows the per-connector subqueries without running them */
+func (h *WarehouseHandler) PlanFederatedQuery(w http.ResponseWriter, r *http.Request) {
+	var req federatedQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+
+	if req.SQL == "" {
+		WriteErrorResponse(w, errors.ValidationFailed("sql is required", nil))
+		return
+	}
+
+	plan, err := h.service.PlanFederatedQuery(r.Context(), req.SQL)
+	if err != nil {
+		writeResultError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+/* RecommendCharts handles POST /api/v1/warehouse/charts/recommend: ranks Vega-Lite charts for a result set */
+func (h *WarehouseHandler) RecommendCharts(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SQL     string                   `json:"sql,omitempty"` // Query the results came from, used to find classified geographic columns
+		Results []map[string]interface{} `json:"results"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&req); err != nil {
+		WriteErrorResponse(w, errors.BadRequest("Invalid request body"))
+		return
+	}
+
+	if len(req.Results) == 0 {
+		WriteErrorResponse(w, errors.ValidationFailed("results is required", nil))
+		return
+	}
+
+	charts := h.service.SuggestCharts(r.Context(), req.SQL, req.Results)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"charts": charts})
+}
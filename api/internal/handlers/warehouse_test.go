@@ -0,0 +1,88 @@
+package handlers_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/neurondb/NeuronIP/api/internal/handlers"
+	"github.com/neurondb/NeuronIP/api/internal/middleware"
+	"github.com/neurondb/NeuronIP/api/internal/tracing"
+	"github.com/neurondb/NeuronIP/api/internal/warehouse"
+)
+
+/* TestWriteResultStreamThroughMiddleware checks that a result stream flushes through the server's middleware
+ * chain and outlives both the default route timeout and the server write timeout */
+func TestWriteResultStreamThroughMiddleware(t *testing.T) {
+	release := make(chan struct{})
+	router := mux.NewRouter()
+	router.Use(middleware.Recovery)
+	router.Use(middleware.RequestID)
+	router.Use(middleware.HTTPLogging)
+	router.Use(middleware.Tracing(tracing.NewTracerService(true)))
+	apiRouter := router.PathPrefix("/api/v1").Subrouter()
+	config := middleware.DefaultTimeoutConfig()
+	config.Default, config.Query = 50*time.Millisecond, 50*time.Millisecond
+	apiRouter.Use(middleware.TimeoutByRoute(config))
+	apiRouter.HandleFunc("/warehouse/query/stream", func(w http.ResponseWriter, r *http.Request) {
+		handlers.WriteResultStream(w, func(emit func(warehouse.ResultStreamEvent) error) error {
+			if err := emit(warehouse.ResultStreamEvent{Type: warehouse.ResultEventColumns}); err != nil {
+				return err
+			}
+			// Outlast the route timeouts and the server write timeout before finishing
+			<-release
+			time.Sleep(200 * time.Millisecond)
+			if err := emit(warehouse.ResultStreamEvent{Type: warehouse.ResultEventRow, Values: []interface{}{1}}); err != nil {
+				return err
+			}
+			rows := int64(1)
+			return emit(warehouse.ResultStreamEvent{Type: warehouse.ResultEventEnd, RowCount: &rows})
+		})
+	}).Methods("POST")
+
+	server := httptest.NewUnstartedServer(router)
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/v1/warehouse/query/stream", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("expected NDJSON, got %q", ct)
+	}
+
+	// The columns line must arrive while the producer is still blocked
+	reader := bufio.NewReader(resp.Body)
+	var types []string
+	readEvent := func() {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("after %v: %v", types, err)
+		}
+		var event warehouse.ResultStreamEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("bad line %q: %v", line, err)
+		}
+		types = append(types, event.Type)
+	}
+	readEvent()
+	close(release)
+	readEvent()
+	readEvent()
+
+	want := []string{warehouse.ResultEventColumns, warehouse.ResultEventRow, warehouse.ResultEventEnd}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected events %v, got %v", want, types)
+	}
+}
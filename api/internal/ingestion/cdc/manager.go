@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/cache"
+	"github.com/neurondb/NeuronIP/api/internal/logging"
 )
 
 /* CDCManager coordinates CDC operations across different database types */
@@ -12,6 +14,7 @@ type CDCManager struct {
 	postgresCDC *PostgresCDC
 	mysqlCDC    *MySQLCDC
 	pool        *pgxpool.Pool
+	listener    cache.TableChangeListener
 }
 
 /* NewCDCManager creates a new CDC manager */
@@ -23,6 +26,13 @@ func NewCDCManager(pool *pgxpool.Pool) *CDCManager {
 	}
 }
 
+/* NewCDCManagerWithListener creates a CDC manager that notifies listener of the tables each change batch touches */
+func NewCDCManagerWithListener(pool *pgxpool.Pool, listener cache.TableChangeListener) *CDCManager {
+	m := NewCDCManager(pool)
+	m.listener = listener
+	return m
+}
+
 /* StartCDCForDataSource starts CDC for a data source */
 func (m *CDCManager) StartCDCForDataSource(ctx context.Context, dataSourceType string, config map[string]interface{}) error {
 	switch dataSourceType {
@@ -49,6 +59,9 @@ func (m *CDCManager) StopCDCForDataSource(ctx context.Context, dataSourceType st
 
 /* ProcessChanges processes CDC changes and applies them */
 func (m *CDCManager) ProcessChanges(ctx context.Context, dataSourceID string, dataSourceType string, changes []ChangeEvent) error {
+	var changedTables []string
+	seenTables := make(map[string]bool)
+
 	// Process each change event
 	for _, change := range changes {
 		// Save checkpoint
@@ -70,6 +83,10 @@ func (m *CDCManager) ProcessChanges(ctx context.Context, dataSourceID string, da
 		if err := cdc.SaveCheckpoint(ctx, dataSourceID, change.Table, checkpoint); err != nil {
 			return fmt.Errorf("failed to save checkpoint: %w", err)
 		}
+		if change.Table != "" && !seenTables[change.Table] {
+			seenTables[change.Table] = true
+			changedTables = append(changedTables, change.Table)
+		}
 		
 		// In production, would apply changes to target system
 		// This could be:
@@ -77,6 +94,16 @@ func (m *CDCManager) ProcessChanges(ctx context.Context, dataSourceID string, da
 		// 2. Trigger ETL transformations
 		// 3. Update knowledge graph
 	}
+
+	if m.listener != nil && len(changedTables) > 0 {
+		if err := m.listener.TablesChanged(ctx, cache.TableChange{
+			Tables: changedTables,
+			Reason: cache.TableChangeCDC,
+			Source: dataSourceID,
+		}); err != nil {
+			logging.Error("Failed to invalidate cached results after CDC changes", "tables", changedTables, "data_source_id", dataSourceID, "error", err)
+		}
+	}
 	
 	return nil
 }
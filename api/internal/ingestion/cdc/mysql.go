@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/go-mysql-org/go-mysql/client"
 	"github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/go-mysql-org/go-mysql/replication"
 )
@@ -68,7 +69,7 @@ func (m *MySQLCDC) StartCDC(ctx context.Context, config map[string]interface{})
 		Password: password,
 	}
 
-	syncer := replication.NewBinlogSyncer(&cfg)
+	syncer := replication.NewBinlogSyncer(cfg)
 	m.syncer = syncer
 
 	// Get current binlog position (or use provided position)
@@ -85,7 +86,7 @@ func (m *MySQLCDC) StartCDC(ctx context.Context, config map[string]interface{})
 	// If no position provided, start from current position
 	if position.Name == "" {
 		// Connect to MySQL to get current position
-		conn, err := mysql.Connect(fmt.Sprintf("%s:%d", host, uint16(port)), user, password, "")
+		conn, err := client.Connect(fmt.Sprintf("%s:%d", host, uint16(port)), user, password, "")
 		if err != nil {
 			// If we can't get position, start from beginning
 			position.Name = "mysql-bin.000001"
@@ -93,10 +94,10 @@ func (m *MySQLCDC) StartCDC(ctx context.Context, config map[string]interface{})
 		} else {
 			pos, err := conn.Execute("SHOW MASTER STATUS")
 			if err == nil && len(pos.Values) > 0 {
-				if file, ok := pos.Values[0][0].(string); ok {
+				if file, err := pos.GetString(0, 0); err == nil {
 					position.Name = file
 				}
-				if posVal, ok := pos.Values[0][1].(uint64); ok {
+				if posVal, err := pos.GetUint(0, 1); err == nil {
 					position.Pos = uint32(posVal)
 				}
 			}
@@ -258,8 +259,13 @@ func (m *MySQLCDC) convertBinlogEvent(ev *replication.BinlogEvent) (*ChangeEvent
 		return nil, fmt.Errorf("nil binlog event")
 	}
 
+	binlogFile := ""
+	if m.syncer != nil {
+		binlogFile = m.syncer.GetNextPosition().Name
+	}
+
 	change := &ChangeEvent{
-		LSN:       fmt.Sprintf("%s:%d", ev.Header.LogPos, ev.Header.LogPos),
+		LSN:       fmt.Sprintf("%s:%d", binlogFile, ev.Header.LogPos),
 		Timestamp: time.Unix(int64(ev.Header.Timestamp), 0),
 		OldData:   make(map[string]interface{}),
 		NewData:   make(map[string]interface{}),
@@ -278,9 +284,9 @@ func (m *MySQLCDC) convertBinlogEvent(ev *replication.BinlogEvent) (*ChangeEvent
 			// Extract new row data
 			if len(e.Rows) > 0 {
 				row := e.Rows[0]
-				for i, col := range e.Table.Columns {
+				for i, col := range e.Table.ColumnNameString() {
 					if i < len(row) {
-						change.NewData[col.Name] = row[i]
+						change.NewData[col] = row[i]
 					}
 				}
 			}
@@ -291,12 +297,12 @@ func (m *MySQLCDC) convertBinlogEvent(ev *replication.BinlogEvent) (*ChangeEvent
 			if len(e.Rows) >= 2 {
 				oldRow := e.Rows[0]
 				newRow := e.Rows[1]
-				for i, col := range e.Table.Columns {
+				for i, col := range e.Table.ColumnNameString() {
 					if i < len(oldRow) {
-						change.OldData[col.Name] = oldRow[i]
+						change.OldData[col] = oldRow[i]
 					}
 					if i < len(newRow) {
-						change.NewData[col.Name] = newRow[i]
+						change.NewData[col] = newRow[i]
 					}
 				}
 			}
@@ -306,9 +312,9 @@ func (m *MySQLCDC) convertBinlogEvent(ev *replication.BinlogEvent) (*ChangeEvent
 			// Extract old row data
 			if len(e.Rows) > 0 {
 				row := e.Rows[0]
-				for i, col := range e.Table.Columns {
+				for i, col := range e.Table.ColumnNameString() {
 					if i < len(row) {
-						change.OldData[col.Name] = row[i]
+						change.OldData[col] = row[i]
 					}
 				}
 			}
@@ -61,11 +61,8 @@ func (p *PostgresCDC) StartCDC(ctx context.Context, config map[string]interface{
 
 /* establishReplicationConnection establishes a replication connection */
 func (p *PostgresCDC) establishReplicationConnection(ctx context.Context, config map[string]interface{}) error {
-	// Get connection config from pool
-	connConfig := p.pool.Config()
-	
-	// Create a new config for replication connection
-	replConfig := connConfig.Copy()
+	// Create a new config for replication connection from the pool's connection config
+	replConfig := p.pool.Config().ConnConfig.Copy()
 	replConfig.RuntimeParams["replication"] = "database"
 	
 	// Create replication connection
@@ -30,6 +30,18 @@ type Connector interface {
 	GetMetadata() ConnectorMetadata
 }
 
+/* StagingSyncer is implemented by connectors that can land a sync's rows in a staging table.
+ * Jobs with quality gates need it, since the gates check the staged rows before they are committed. */
+type StagingSyncer interface {
+	// SyncToStaging reads the one table in options.Tables and writes its rows to staging
+	SyncToStaging(ctx context.Context, options SyncOptions, staging StagingWriter) (*SyncResult, error)
+}
+
+/* StagingWriter appends rows to the staging table named by SyncOptions.StagingTable */
+type StagingWriter interface {
+	WriteRows(ctx context.Context, columns []string, rows [][]interface{}) error
+}
+
 /* Schema represents the discovered schema of a data source */
 type Schema struct {
 	Tables      []TableSchema `json:"tables"`
@@ -76,6 +88,7 @@ type SyncOptions struct {
 	Since         *time.Time              `json:"since,omitempty"` // For incremental syncs
 	BatchSize     int                     `json:"batch_size,omitempty"`
 	Transformations map[string]interface{} `json:"transformations,omitempty"`
+	StagingTable  string                  `json:"staging_table,omitempty"` // Table SyncToStaging lands rows in for quality gates, instead of the target table
 }
 
 /* SyncMode defines the type of sync */
@@ -229,3 +229,12 @@ func (a *AzureSynapseConnector) Sync(ctx context.Context, options ingestion.Sync
 	result.Duration = time.Since(startTime)
 	return result, nil
 }
+
+func (a *AzureSynapseConnector) SyncToStaging(ctx context.Context, options ingestion.SyncOptions, staging ingestion.StagingWriter) (*ingestion.SyncResult, error) {
+	return syncTableToStaging(ctx, a.db, options, staging, func(tableName string) (string, []interface{}) {
+		if options.Since != nil {
+			return fmt.Sprintf("SELECT * FROM [%s] WHERE updated_at >= @p1", tableName), []interface{}{sql.Named("p1", options.Since)}
+		}
+		return fmt.Sprintf("SELECT * FROM [%s]", tableName), nil
+	})
+}
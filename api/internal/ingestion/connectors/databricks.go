@@ -219,3 +219,12 @@ func (d *DatabricksConnector) Sync(ctx context.Context, options ingestion.SyncOp
 	result.Duration = time.Since(startTime)
 	return result, nil
 }
+
+func (d *DatabricksConnector) SyncToStaging(ctx context.Context, options ingestion.SyncOptions, staging ingestion.StagingWriter) (*ingestion.SyncResult, error) {
+	return syncTableToStaging(ctx, d.db, options, staging, func(tableName string) (string, []interface{}) {
+		if options.Since != nil {
+			return fmt.Sprintf("SELECT * FROM %s WHERE updated_at >= ?", tableName), []interface{}{options.Since}
+		}
+		return fmt.Sprintf("SELECT * FROM %s", tableName), nil
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strings"
 	"time"
 
 	// Optional drivers - uncomment when available:
@@ -96,7 +95,8 @@ func (h *HiveConnector) Connect(ctx context.Context, config map[string]interface
 	
 	db, err = sql.Open("odbc", odbcDSN)
 	if err == nil {
-		if pingErr := db.PingContext(ctx); pingErr == nil {
+		pingErr := db.PingContext(ctx)
+		if pingErr == nil {
 			h.db = db
 			h.BaseConnector.SetConnected(true)
 			return nil
@@ -114,10 +114,6 @@ func (h *HiveConnector) Connect(ctx context.Context, config map[string]interface
 		"2) A Hive JDBC bridge, or "+
 		"3) A native Hive Go client. "+
 		"Last error: %w", lastErr)
-
-	h.db = db
-	h.BaseConnector.SetConnected(true)
-	return nil
 }
 
 /* Disconnect closes the connection */
@@ -268,3 +268,12 @@ func (m *MySQLConnector) Sync(ctx context.Context, options ingestion.SyncOptions
 	result.Duration = time.Since(startTime)
 	return result, nil
 }
+
+func (m *MySQLConnector) SyncToStaging(ctx context.Context, options ingestion.SyncOptions, staging ingestion.StagingWriter) (*ingestion.SyncResult, error) {
+	return syncTableToStaging(ctx, m.db, options, staging, func(tableName string) (string, []interface{}) {
+		if options.Since != nil {
+			return fmt.Sprintf("SELECT * FROM `%s` WHERE updated_at >= ?", tableName), []interface{}{options.Since}
+		}
+		return fmt.Sprintf("SELECT * FROM `%s`", tableName), nil
+	})
+}
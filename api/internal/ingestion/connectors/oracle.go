@@ -247,3 +247,12 @@ func (o *OracleConnector) Sync(ctx context.Context, options ingestion.SyncOption
 	result.Duration = time.Since(startTime)
 	return result, nil
 }
+
+func (o *OracleConnector) SyncToStaging(ctx context.Context, options ingestion.SyncOptions, staging ingestion.StagingWriter) (*ingestion.SyncResult, error) {
+	return syncTableToStaging(ctx, o.db, options, staging, func(tableName string) (string, []interface{}) {
+		if options.Since != nil {
+			return fmt.Sprintf("SELECT * FROM %s WHERE updated_at >= :1", tableName), []interface{}{options.Since}
+		}
+		return fmt.Sprintf("SELECT * FROM %s", tableName), nil
+	})
+}
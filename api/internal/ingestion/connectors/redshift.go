@@ -240,3 +240,12 @@ func (r *RedshiftConnector) Sync(ctx context.Context, options ingestion.SyncOpti
 	result.Duration = time.Since(startTime)
 	return result, nil
 }
+
+func (r *RedshiftConnector) SyncToStaging(ctx context.Context, options ingestion.SyncOptions, staging ingestion.StagingWriter) (*ingestion.SyncResult, error) {
+	return syncTableToStaging(ctx, r.db, options, staging, func(tableName string) (string, []interface{}) {
+		if options.Since != nil {
+			return fmt.Sprintf("SELECT * FROM %s WHERE updated_at >= $1", tableName), []interface{}{options.Since}
+		}
+		return fmt.Sprintf("SELECT * FROM %s", tableName), nil
+	})
+}
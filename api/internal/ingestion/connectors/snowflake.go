@@ -251,3 +251,12 @@ func (s *SnowflakeConnector) Sync(ctx context.Context, options ingestion.SyncOpt
 	result.Duration = time.Since(startTime)
 	return result, nil
 }
+
+func (s *SnowflakeConnector) SyncToStaging(ctx context.Context, options ingestion.SyncOptions, staging ingestion.StagingWriter) (*ingestion.SyncResult, error) {
+	return syncTableToStaging(ctx, s.db, options, staging, func(tableName string) (string, []interface{}) {
+		if options.Since != nil {
+			return fmt.Sprintf("SELECT * FROM %s WHERE updated_at >= ?", tableName), []interface{}{options.Since}
+		}
+		return fmt.Sprintf("SELECT * FROM %s", tableName), nil
+	})
+}
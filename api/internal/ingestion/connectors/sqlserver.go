@@ -269,3 +269,12 @@ func (s *SQLServerConnector) Sync(ctx context.Context, options ingestion.SyncOpt
 	result.Duration = time.Since(startTime)
 	return result, nil
 }
+
+func (s *SQLServerConnector) SyncToStaging(ctx context.Context, options ingestion.SyncOptions, staging ingestion.StagingWriter) (*ingestion.SyncResult, error) {
+	return syncTableToStaging(ctx, s.db, options, staging, func(tableName string) (string, []interface{}) {
+		if options.Since != nil {
+			return fmt.Sprintf("SELECT * FROM [%s] WHERE updated_at >= @p1", tableName), []interface{}{sql.Named("p1", options.Since)}
+		}
+		return fmt.Sprintf("SELECT * FROM [%s]", tableName), nil
+	})
+}
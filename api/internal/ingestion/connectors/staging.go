@@ -0,0 +1,92 @@
+package connectors
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/neurondb/NeuronIP/api/internal/ingestion"
+)
+
+/* defaultStagingBatchSize is how many rows are written to staging at a time when the sync sets no batch size */
+const defaultStagingBatchSize = 1000
+
+/* syncTableToStaging reads the one table of a staged sync from a SQL connector and writes its rows to staging.
+ * selectRows builds the connector's query for the table, with its incremental filter when the sync has one. */
+func syncTableToStaging(ctx context.Context, db *sql.DB, options ingestion.SyncOptions, staging ingestion.StagingWriter,
+	selectRows func(table string) (string, []interface{})) (*ingestion.SyncResult, error) {
+	if db == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+	if len(options.Tables) != 1 {
+		return nil, fmt.Errorf("a staged sync reads exactly one table, got %d", len(options.Tables))
+	}
+
+	startTime := time.Now()
+	table := options.Tables[0]
+	query, args := selectRows(table)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultStagingBatchSize
+	}
+
+	result := &ingestion.SyncResult{
+		TablesSynced: []string{table},
+		Errors:       []ingestion.SyncError{},
+	}
+	batch := make([][]interface{}, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := staging.WriteRows(ctx, columns, batch); err != nil {
+			return err
+		}
+		result.RowsSynced += int64(len(batch))
+		batch = make([][]interface{}, 0, batchSize)
+		return nil
+	}
+
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("failed to scan row of %s: %w", table, err)
+		}
+		// Drivers may reuse byte slices between rows, and staging stores text rather than bytea
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		batch = append(batch, values)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", table, err)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	result.Duration = time.Since(startTime)
+	return result, nil
+}
@@ -0,0 +1,98 @@
+package connectors
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/neurondb/NeuronIP/api/internal/ingestion"
+)
+
+/* stagingTestDriver answers every query with the same rows, so staged syncs can run without a database */
+type stagingTestDriver struct{}
+
+type stagingTestConn struct{}
+
+type stagingTestRows struct{ next int }
+
+var stagingTestData = [][]driver.Value{{int64(1), []byte("pending")}, {int64(2), []byte("paid")}, {int64(3), nil}}
+
+func init() {
+	sql.Register("stagingtest", stagingTestDriver{})
+}
+
+func (stagingTestDriver) Open(name string) (driver.Conn, error) { return stagingTestConn{}, nil }
+
+func (stagingTestConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (stagingTestConn) Close() error                              { return nil }
+func (stagingTestConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+func (stagingTestConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+	return &stagingTestRows{}, nil
+}
+
+func (r *stagingTestRows) Columns() []string { return []string{"id", "status"} }
+func (r *stagingTestRows) Close() error      { return nil }
+func (r *stagingTestRows) Next(dest []driver.Value) error {
+	if r.next == len(stagingTestData) {
+		return io.EOF
+	}
+	copy(dest, stagingTestData[r.next])
+	r.next++
+	return nil
+}
+
+/* recordingWriter keeps the batches written to staging */
+type recordingWriter struct {
+	columns []string
+	batches [][][]interface{}
+}
+
+func (w *recordingWriter) WriteRows(ctx context.Context, columns []string, rows [][]interface{}) error {
+	w.columns = columns
+	w.batches = append(w.batches, rows)
+	return nil
+}
+
+/* TestSyncTableToStaging checks that a staged sync writes the table's rows in batches as text-safe values */
+func TestSyncTableToStaging(t *testing.T) {
+	selectRows := func(table string) (string, []interface{}) { return "SELECT id, status FROM " + table, nil }
+
+	if _, err := syncTableToStaging(context.Background(), nil, ingestion.SyncOptions{Tables: []string{"orders"}}, &recordingWriter{}, selectRows); err == nil {
+		t.Fatal("expected an error without a connection")
+	}
+
+	db, err := sql.Open("stagingtest", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = syncTableToStaging(context.Background(), db, ingestion.SyncOptions{Tables: []string{"orders", "customers"}}, &recordingWriter{}, selectRows)
+	if err == nil || !strings.Contains(err.Error(), "exactly one table") {
+		t.Fatalf("expected a one-table error, got %v", err)
+	}
+
+	writer := &recordingWriter{}
+	result, err := syncTableToStaging(context.Background(), db, ingestion.SyncOptions{Tables: []string{"orders"}, BatchSize: 2}, writer, selectRows)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RowsSynced != 3 || len(result.TablesSynced) != 1 || result.TablesSynced[0] != "orders" {
+		t.Errorf("expected 3 rows synced from orders, got %+v", result)
+	}
+	if len(writer.batches) != 2 || len(writer.batches[0]) != 2 || len(writer.batches[1]) != 1 {
+		t.Fatalf("expected batches of 2 and 1 rows, got %v", writer.batches)
+	}
+	if strings.Join(writer.columns, ",") != "id,status" {
+		t.Errorf("expected columns id,status, got %v", writer.columns)
+	}
+	if status, ok := writer.batches[0][0][1].(string); !ok || status != "pending" {
+		t.Errorf("expected byte values to be staged as strings, got %#v", writer.batches[0][0][1])
+	}
+	if writer.batches[1][0][1] != nil {
+		t.Errorf("expected NULL to stay nil, got %#v", writer.batches[1][0][1])
+	}
+}
@@ -72,7 +72,8 @@ func (t *TeradataConnector) Connect(ctx context.Context, config map[string]inter
 	// Try teradata driver first
 	db, err = sql.Open("teradata", dsn)
 	if err == nil {
-		if pingErr := db.PingContext(ctx); pingErr == nil {
+		pingErr := db.PingContext(ctx)
+		if pingErr == nil {
 			t.db = db
 			t.BaseConnector.SetConnected(true)
 			return nil
@@ -92,7 +93,8 @@ func (t *TeradataConnector) Connect(ctx context.Context, config map[string]inter
 	
 	db, err = sql.Open("odbc", odbcDSN)
 	if err == nil {
-		if pingErr := db.PingContext(ctx); pingErr == nil {
+		pingErr := db.PingContext(ctx)
+		if pingErr == nil {
 			t.db = db
 			t.BaseConnector.SetConnected(true)
 			return nil
@@ -109,10 +111,6 @@ func (t *TeradataConnector) Connect(ctx context.Context, config map[string]inter
 		"1) Official Teradata driver: go get github.com/Teradata/teradata-driver, or "+
 		"2) Configure Teradata ODBC driver and: go get github.com/alexbrainman/odbc. "+
 		"Last error: %w", lastErr)
-
-	t.db = db
-	t.BaseConnector.SetConnected(true)
-	return nil
 }
 
 /* Disconnect closes the connection */
@@ -286,3 +284,12 @@ func (t *TeradataConnector) Sync(ctx context.Context, options ingestion.SyncOpti
 	result.Duration = time.Since(startTime)
 	return result, nil
 }
+
+func (t *TeradataConnector) SyncToStaging(ctx context.Context, options ingestion.SyncOptions, staging ingestion.StagingWriter) (*ingestion.SyncResult, error) {
+	return syncTableToStaging(ctx, t.db, options, staging, func(tableName string) (string, []interface{}) {
+		if options.Since != nil {
+			return fmt.Sprintf("SELECT * FROM %s WHERE updated_at >= ?", tableName), []interface{}{options.Since}
+		}
+		return fmt.Sprintf("SELECT * FROM %s", tableName), nil
+	})
+}
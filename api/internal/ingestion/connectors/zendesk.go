@@ -197,8 +197,6 @@ func (z *ZendeskConnector) Sync(ctx context.Context, options ingestion.SyncOptio
 
 /* syncTable syncs a specific table */
 func (z *ZendeskConnector) syncTable(ctx context.Context, table string, options ingestion.SyncOptions) (int64, error) {
-	var rows int64
-	
 	switch table {
 	case "tickets":
 		return z.syncTickets(ctx, options)
@@ -209,8 +207,6 @@ func (z *ZendeskConnector) syncTable(ctx context.Context, table string, options
 	default:
 		return 0, fmt.Errorf("unknown table: %s", table)
 	}
-	
-	return rows, nil
 }
 
 /* syncTickets syncs tickets from Zendesk */
@@ -0,0 +1,103 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/neurondb/NeuronIP/api/internal/connectors"
+)
+
+/* fakeContractValidator records the validation it was asked for and returns a fixed outcome */
+type fakeContractValidator struct {
+	validation  *connectors.SyncValidation
+	err         error
+	connectorID uuid.UUID
+	tables      []string
+	calls       int
+}
+
+func (f *fakeContractValidator) ValidateIngestion(ctx context.Context, connectorID uuid.UUID, jobID uuid.UUID, tables []string) (*connectors.SyncValidation, error) {
+	f.calls++
+	f.connectorID = connectorID
+	f.tables = tables
+	return f.validation, f.err
+}
+
+/* TestValidateContracts checks which jobs are validated against contracts and when a job is blocked */
+func TestValidateContracts(t *testing.T) {
+	connectorID := uuid.New()
+
+	tests := []struct {
+		name       string
+		config     map[string]interface{}
+		validation *connectors.SyncValidation
+		err        error
+		calls      int
+		violations int
+		errMsg     string
+	}{
+		{name: "no connector", config: map[string]interface{}{}},
+		{name: "invalid connector", config: map[string]interface{}{"connector_id": "orders"}, errMsg: "invalid connector_id"},
+		{
+			name:       "clean",
+			config:     map[string]interface{}{"connector_id": connectorID.String()},
+			validation: &connectors.SyncValidation{},
+			calls:      1,
+		},
+		{
+			name:       "warn violations",
+			config:     map[string]interface{}{"connector_id": connectorID.String()},
+			validation: &connectors.SyncValidation{Violations: 2},
+			calls:      1,
+			violations: 2,
+		},
+		{
+			name:       "blocking violations",
+			config:     map[string]interface{}{"connector_id": connectorID.String()},
+			validation: &connectors.SyncValidation{Violations: 3, Blocking: []string{"contract orders v2 has 3 breaking violations"}},
+			calls:      1,
+			violations: 3,
+			errMsg:     "sync blocked: contract orders v2",
+		},
+		{
+			name:   "validation error",
+			config: map[string]interface{}{"connector_id": connectorID.String()},
+			err:    errors.New("connection refused"),
+			calls:  1,
+			errMsg: "failed to validate data contracts",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &fakeContractValidator{validation: tt.validation, err: tt.err}
+			s := &IngestionService{contracts: validator}
+			job := &IngestionJob{ID: uuid.New(), Config: tt.config}
+
+			violations, err := s.validateContracts(context.Background(), job, []string{"sales.orders"})
+			if tt.errMsg == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)) {
+				t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+			if violations != tt.violations {
+				t.Errorf("expected %d violations, got %d", tt.violations, violations)
+			}
+			if validator.calls != tt.calls {
+				t.Fatalf("expected %d validations, got %d", tt.calls, validator.calls)
+			}
+			if tt.calls > 0 && (validator.connectorID != connectorID || len(validator.tables) != 1) {
+				t.Errorf("expected validation of %s on the job's tables, got %s %v", connectorID, validator.connectorID, validator.tables)
+			}
+		})
+	}
+
+	// A service without a validator never validates
+	if _, err := (&IngestionService{}).validateContracts(context.Background(),
+		&IngestionJob{Config: map[string]interface{}{"connector_id": connectorID.String()}}, nil); err != nil {
+		t.Fatalf("unexpected error without a validator: %v", err)
+	}
+}
@@ -0,0 +1,399 @@
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+/* Duplicate types */
+const (
+	DuplicateExact = "exact"
+	DuplicateNear  = "near"
+)
+
+/* DefaultMaxHammingDistance is the largest SimHash distance (out of 64 bits) treated as a near-duplicate.
+ * Bands are 16 bits wide, so any pair within 3 bits shares at least one band. */
+const DefaultMaxHammingDistance = 3
+
+const (
+	shingleSize = 3
+	bandCount   = 4
+	bandBits    = 16
+)
+
+/* Fingerprint identifies document content for exact and near-duplicate detection */
+type Fingerprint struct {
+	ContentHash string `json:"content_hash"`
+	SimHash     uint64 `json:"simhash"`
+}
+
+/* Match describes an existing document that new content duplicates */
+type Match struct {
+	CanonicalID uuid.UUID `json:"canonical_id"`
+	Type        string    `json:"type"` // "exact" or "near"
+	Distance    int       `json:"distance"`
+	Similarity  float64   `json:"similarity"`
+}
+
+/* Duplicate is a document linked to a canonical document */
+type Duplicate struct {
+	DocumentID uuid.UUID `json:"document_id"`
+	Title      string    `json:"title"`
+	Type       string    `json:"type"`
+	Similarity float64   `json:"similarity"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+/* NormalizeContent lowercases text, drops punctuation and collapses whitespace so formatting-only
+ * differences hash identically */
+func NormalizeContent(content string) string {
+	var b strings.Builder
+	b.Grow(len(content))
+	space := false
+	for _, r := range content {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+/* ComputeFingerprint computes the content hash and SimHash of document content */
+func ComputeFingerprint(content string) Fingerprint {
+	normalized := NormalizeContent(content)
+	hash := sha256.Sum256([]byte(normalized))
+	return Fingerprint{
+		ContentHash: hex.EncodeToString(hash[:]),
+		SimHash:     SimHash(normalized),
+	}
+}
+
+/* SimHash computes a 64-bit SimHash over word shingles of normalized text */
+func SimHash(normalized string) uint64 {
+	words := strings.Fields(normalized)
+	if len(words) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	addFeature := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	if len(words) < shingleSize {
+		addFeature(strings.Join(words, " "))
+	} else {
+		for i := 0; i+shingleSize <= len(words); i++ {
+			addFeature(strings.Join(words[i:i+shingleSize], " "))
+		}
+	}
+
+	var fingerprint uint64
+	for i := 0; i < 64; i++ {
+		if weights[i] > 0 {
+			fingerprint |= 1 << uint(i)
+		}
+	}
+	return fingerprint
+}
+
+/* HammingDistance returns the number of differing bits between two SimHashes */
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+/* simhashBands splits a SimHash into fixed-width bands used as lookup keys */
+func simhashBands(simhash uint64) []int32 {
+	bands := make([]int32, bandCount)
+	for i := 0; i < bandCount; i++ {
+		bands[i] = int32((simhash >> uint(i*bandBits)) & (1<<bandBits - 1))
+	}
+	return bands
+}
+
+/* Service detects and links duplicate knowledge documents */
+type Service struct {
+	pool        *pgxpool.Pool
+	maxDistance int
+}
+
+/* NewService creates a new dedup service */
+func NewService(pool *pgxpool.Pool) *Service {
+	return &Service{pool: pool, maxDistance: DefaultMaxHammingDistance}
+}
+
+/* FindDuplicate finds an existing document in the same collection that the fingerprint duplicates.
+ * Exact content hash matches win over near-duplicates; nil means the content is new. */
+func (s *Service) FindDuplicate(ctx context.Context, collectionID *uuid.UUID, fp Fingerprint, excludeID *uuid.UUID) (*Match, error) {
+	exactQuery := `
+		SELECT COALESCE(canonical_document_id, id)
+		FROM neuronip.knowledge_documents
+		WHERE content_hash = $1
+		  AND collection_id IS NOT DISTINCT FROM $2
+		  AND ($3::uuid IS NULL OR id != $3)
+		ORDER BY created_at
+		LIMIT 1`
+
+	var canonicalID uuid.UUID
+	err := s.pool.QueryRow(ctx, exactQuery, fp.ContentHash, collectionID, excludeID).Scan(&canonicalID)
+	if err == nil {
+		return &Match{CanonicalID: canonicalID, Type: DuplicateExact, Distance: 0, Similarity: 1.0}, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to check exact duplicates: %w", err)
+	}
+
+	if fp.SimHash == 0 {
+		return nil, nil
+	}
+
+	bands := simhashBands(fp.SimHash)
+	nearQuery := `
+		SELECT DISTINCT kd.id, COALESCE(kd.canonical_document_id, kd.id), kd.simhash
+		FROM neuronip.document_simhash_bands b
+		JOIN neuronip.knowledge_documents kd ON kd.id = b.document_id
+		WHERE (b.band_index, b.band_value) IN ((0, $1), (1, $2), (2, $3), (3, $4))
+		  AND kd.collection_id IS NOT DISTINCT FROM $5
+		  AND ($6::uuid IS NULL OR kd.id != $6)
+		  AND kd.simhash IS NOT NULL
+		LIMIT 200`
+
+	rows, err := s.pool.Query(ctx, nearQuery, bands[0], bands[1], bands[2], bands[3], collectionID, excludeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check near duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	var best *Match
+	for rows.Next() {
+		var docID, canonical uuid.UUID
+		var simhash int64
+		if err := rows.Scan(&docID, &canonical, &simhash); err != nil {
+			continue
+		}
+		match := s.nearMatch(canonical, fp.SimHash, uint64(simhash))
+		if match != nil && (best == nil || match.Distance < best.Distance) {
+			best = match
+		}
+	}
+	return best, nil
+}
+
+/* nearMatch links a SimHash to a candidate's canonical document when they are within the distance threshold */
+func (s *Service) nearMatch(canonicalID uuid.UUID, simhash, candidate uint64) *Match {
+	distance := HammingDistance(simhash, candidate)
+	if distance > s.maxDistance {
+		return nil
+	}
+	return &Match{
+		CanonicalID: canonicalID,
+		Type:        DuplicateNear,
+		Distance:    distance,
+		Similarity:  1 - float64(distance)/64,
+	}
+}
+
+/* canonicalLink returns the link columns Record stores; a match on the document itself is no link */
+func canonicalLink(documentID uuid.UUID, match *Match) (*uuid.UUID, *string, *float64) {
+	if match == nil || match.CanonicalID == documentID {
+		return nil, nil, nil
+	}
+	return &match.CanonicalID, &match.Type, &match.Similarity
+}
+
+/* indexesBands reports whether a document's SimHash bands are stored for near-duplicate lookup */
+func indexesBands(fp Fingerprint, match *Match) bool {
+	return fp.SimHash != 0 && (match == nil || match.Type != DuplicateExact)
+}
+
+/* Record stores a document's fingerprint and links it to its canonical document when it is a duplicate */
+func (s *Service) Record(ctx context.Context, documentID uuid.UUID, fp Fingerprint, match *Match) error {
+	canonicalID, duplicateType, similarity := canonicalLink(documentID, match)
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE neuronip.knowledge_documents
+		SET content_hash = $1, simhash = $2, canonical_document_id = $3,
+		    duplicate_type = $4, duplicate_similarity = $5
+		WHERE id = $6`,
+		fp.ContentHash, int64(fp.SimHash), canonicalID, duplicateType, similarity, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to record document fingerprint: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM neuronip.document_simhash_bands WHERE document_id = $1`, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to clear simhash bands: %w", err)
+	}
+
+	// Exact duplicates are never searched for directly; their canonical document carries the bands
+	if indexesBands(fp, match) {
+		for i, band := range simhashBands(fp.SimHash) {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO neuronip.document_simhash_bands (document_id, band_index, band_value)
+				VALUES ($1, $2, $3)`, documentID, i, band)
+			if err != nil {
+				return fmt.Errorf("failed to insert simhash band: %w", err)
+			}
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+/* DetachDuplicates unlinks the duplicates of a document whose content is about to change.
+ * The oldest exact duplicate still holds the old content, so it takes over the canonical embeddings and
+ * the remaining exact duplicates; near duplicates keep their own embeddings and simply lose the link.
+ * Returns the promoted document, or nil when there were no exact duplicates. */
+func (s *Service) DetachDuplicates(ctx context.Context, canonicalID uuid.UUID) (*uuid.UUID, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE neuronip.knowledge_documents
+		SET canonical_document_id = NULL, duplicate_type = NULL, duplicate_similarity = NULL,
+		    metadata = metadata - 'duplicate_of' - 'duplicate_type'
+		WHERE canonical_document_id = $1 AND duplicate_type = 'near'`, canonicalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unlink near duplicates: %w", err)
+	}
+
+	var successorID uuid.UUID
+	var simhash *int64
+	err = tx.QueryRow(ctx, `
+		SELECT id, simhash
+		FROM neuronip.knowledge_documents
+		WHERE canonical_document_id = $1 AND duplicate_type = 'exact'
+		ORDER BY created_at
+		LIMIT 1`, canonicalID).Scan(&successorID, &simhash)
+	if err == pgx.ErrNoRows {
+		return nil, tx.Commit(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find exact duplicates: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE neuronip.knowledge_embeddings SET document_id = $1 WHERE document_id = $2`, successorID, canonicalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hand over embeddings: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE neuronip.knowledge_documents
+		SET canonical_document_id = NULL, duplicate_type = NULL, duplicate_similarity = NULL,
+		    metadata = metadata - 'duplicate_of' - 'duplicate_type'
+		WHERE id = $1`, successorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to promote duplicate: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE neuronip.knowledge_documents
+		SET canonical_document_id = $1, metadata = jsonb_set(metadata, '{duplicate_of}', to_jsonb($1::text))
+		WHERE canonical_document_id = $2`, successorID, canonicalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to relink exact duplicates: %w", err)
+	}
+
+	if simhash != nil && *simhash != 0 {
+		for i, band := range simhashBands(uint64(*simhash)) {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO neuronip.document_simhash_bands (document_id, band_index, band_value)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (document_id, band_index) DO UPDATE SET band_value = EXCLUDED.band_value`, successorID, i, band)
+			if err != nil {
+				return nil, fmt.Errorf("failed to insert simhash band: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit duplicate detach: %w", err)
+	}
+	return &successorID, nil
+}
+
+/* ListDuplicates lists documents linked to a canonical document */
+func (s *Service) ListDuplicates(ctx context.Context, canonicalID uuid.UUID) ([]Duplicate, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, title, duplicate_type, COALESCE(duplicate_similarity, 1), created_at
+		FROM neuronip.knowledge_documents
+		WHERE canonical_document_id = $1
+		ORDER BY created_at`, canonicalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	var duplicates []Duplicate
+	for rows.Next() {
+		var d Duplicate
+		if err := rows.Scan(&d.DocumentID, &d.Title, &d.Type, &d.Similarity, &d.CreatedAt); err != nil {
+			continue
+		}
+		duplicates = append(duplicates, d)
+	}
+	return duplicates, nil
+}
+
+/* CanonicalIDs maps each document ID to its canonical document ID */
+func (s *Service) CanonicalIDs(ctx context.Context, documentIDs []uuid.UUID) (map[uuid.UUID]uuid.UUID, error) {
+	canonical := make(map[uuid.UUID]uuid.UUID, len(documentIDs))
+	if len(documentIDs) == 0 {
+		return canonical, nil
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, COALESCE(canonical_document_id, id)
+		FROM neuronip.knowledge_documents
+		WHERE id = ANY($1)`, documentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve canonical documents: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, canonicalID uuid.UUID
+		if err := rows.Scan(&id, &canonicalID); err != nil {
+			continue
+		}
+		canonical[id] = canonicalID
+	}
+	return canonical, nil
+}
@@ -0,0 +1,119 @@
+package dedup
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+/* TestComputeFingerprintNormalization checks that formatting-only differences produce identical fingerprints */
+func TestComputeFingerprintNormalization(t *testing.T) {
+	a := ComputeFingerprint("Refunds are issued within 14 days of the request.")
+	b := ComputeFingerprint("  refunds ARE issued, within 14 days of the   request!\n")
+	if a != b {
+		t.Fatalf("expected identical fingerprints, got %+v and %+v", a, b)
+	}
+
+	c := ComputeFingerprint("Refunds are issued within 30 days of the request.")
+	if a.ContentHash == c.ContentHash {
+		t.Fatal("expected different content to hash differently")
+	}
+}
+
+/* TestNearMatchThreshold checks that SimHashes within the Hamming threshold link and those beyond it do not */
+func TestNearMatchThreshold(t *testing.T) {
+	s := &Service{maxDistance: DefaultMaxHammingDistance}
+	canonical := uuid.New()
+	base := ComputeFingerprint("the quick brown fox jumps over the lazy dog near the river bank").SimHash
+
+	tests := []struct {
+		name      string
+		candidate uint64
+		matches   bool
+	}{
+		{name: "identical", candidate: base, matches: true},
+		{name: "one bit", candidate: base ^ 1, matches: true},
+		{name: "at threshold", candidate: base ^ 0b111, matches: true},
+		{name: "one past threshold", candidate: base ^ 0b1111, matches: false},
+		{name: "inverted", candidate: ^base, matches: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := s.nearMatch(canonical, base, tt.candidate)
+			if (match != nil) != tt.matches {
+				t.Fatalf("expected match=%v, got %+v", tt.matches, match)
+			}
+			if match == nil {
+				return
+			}
+			distance := HammingDistance(base, tt.candidate)
+			if match.CanonicalID != canonical || match.Type != DuplicateNear || match.Distance != distance {
+				t.Errorf("unexpected match %+v", match)
+			}
+			if want := 1 - float64(distance)/64; match.Similarity != want {
+				t.Errorf("expected similarity %v, got %v", want, match.Similarity)
+			}
+		})
+	}
+}
+
+/* TestSimhashBandsWithinThreshold checks that any pair within the threshold shares a lookup band */
+func TestSimhashBandsWithinThreshold(t *testing.T) {
+	base := ComputeFingerprint("documents within the threshold must be found by the band lookup").SimHash
+	flips := [][]uint{{0}, {0, 16, 32}, {15, 31, 63}, {5, 21, 47}}
+	for _, positions := range flips {
+		candidate := base
+		for _, p := range positions {
+			candidate ^= 1 << p
+		}
+		shared := false
+		for i, band := range simhashBands(candidate) {
+			if simhashBands(base)[i] == band {
+				shared = true
+			}
+		}
+		if !shared {
+			t.Errorf("bits %v flipped: expected a shared band", positions)
+		}
+	}
+}
+
+/* TestCanonicalLink checks which matches link a document to a canonical document and which keep bands */
+func TestCanonicalLink(t *testing.T) {
+	docID := uuid.New()
+	otherID := uuid.New()
+	fp := Fingerprint{ContentHash: "hash", SimHash: 42}
+
+	tests := []struct {
+		name   string
+		match  *Match
+		linked bool
+		bands  bool
+	}{
+		{name: "new content", match: nil, bands: true},
+		{name: "exact duplicate", match: &Match{CanonicalID: otherID, Type: DuplicateExact, Similarity: 1}, linked: true},
+		{name: "near duplicate", match: &Match{CanonicalID: otherID, Type: DuplicateNear, Similarity: 0.97}, linked: true, bands: true},
+		{name: "match on itself", match: &Match{CanonicalID: docID, Type: DuplicateNear, Similarity: 0.98}, bands: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canonicalID, duplicateType, similarity := canonicalLink(docID, tt.match)
+			if (canonicalID != nil) != tt.linked {
+				t.Fatalf("expected linked=%v, got %v", tt.linked, canonicalID)
+			}
+			if tt.linked && (*canonicalID != otherID || *duplicateType != tt.match.Type || *similarity != tt.match.Similarity) {
+				t.Errorf("unexpected link %v %v %v", *canonicalID, *duplicateType, *similarity)
+			}
+			if !tt.linked && (duplicateType != nil || similarity != nil) {
+				t.Errorf("expected no duplicate type or similarity without a link")
+			}
+			if got := indexesBands(fp, tt.match); got != tt.bands {
+				t.Errorf("expected bands=%v, got %v", tt.bands, got)
+			}
+		})
+	}
+
+	if indexesBands(Fingerprint{ContentHash: "empty"}, nil) {
+		t.Error("expected empty content to store no bands")
+	}
+}
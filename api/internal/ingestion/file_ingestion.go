@@ -11,17 +11,22 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/ingestion/dedup"
 	"github.com/neurondb/NeuronIP/api/internal/ingestion/parsers"
 )
 
 /* FileIngestionService provides file ingestion functionality */
 type FileIngestionService struct {
-	pool *pgxpool.Pool
+	pool         *pgxpool.Pool
+	dedupService *dedup.Service
 }
 
 /* NewFileIngestionService creates a new file ingestion service */
 func NewFileIngestionService(pool *pgxpool.Pool) *FileIngestionService {
-	return &FileIngestionService{pool: pool}
+	return &FileIngestionService{
+		pool:         pool,
+		dedupService: dedup.NewService(pool),
+	}
 }
 
 /* FileIngestionJob represents a file ingestion job */
@@ -123,6 +128,7 @@ func (s *FileIngestionService) ProcessFileIngestionJob(ctx context.Context, jobI
 	// Parse file based on type
 	var rowsProcessed int
 	var parseErr error
+	var extractedText string
 
 	switch job.ParserType {
 	case "pdf":
@@ -130,6 +136,7 @@ func (s *FileIngestionService) ProcessFileIngestionJob(ctx context.Context, jobI
 		content, parseErr := pdfParser.Parse(file)
 		if parseErr == nil && content != nil {
 			rowsProcessed = len(content.Pages)
+			extractedText = content.Text
 			job.Metadata = map[string]interface{}{
 				"page_count": len(content.Pages),
 				"extracted_text_length": len(content.Text),
@@ -140,6 +147,7 @@ func (s *FileIngestionService) ProcessFileIngestionJob(ctx context.Context, jobI
 		content, parseErr := officeParser.Parse(file, job.FileType)
 		if parseErr == nil && content != nil {
 			rowsProcessed = len(content.Sections)
+			extractedText = content.Text
 			job.Metadata = map[string]interface{}{
 				"section_count": len(content.Sections),
 				"extracted_text_length": len(content.Text),
@@ -165,6 +173,11 @@ func (s *FileIngestionService) ProcessFileIngestionJob(ctx context.Context, jobI
 		return fmt.Errorf("failed to parse file: %w", parseErr)
 	}
 
+	// Fingerprint extracted text so re-uploads of the same file are flagged as duplicates
+	if extractedText != "" {
+		s.annotateDuplicates(ctx, job, extractedText)
+	}
+
 	// Update job as completed
 	completedAt := time.Now()
 	s.updateFileJobStatus(ctx, jobID, "completed", nil, &completedAt)
@@ -179,6 +192,32 @@ func (s *FileIngestionService) ProcessFileIngestionJob(ctx context.Context, jobI
 	return nil
 }
 
+/* annotateDuplicates records the content fingerprint of a file and any earlier job or document it duplicates */
+func (s *FileIngestionService) annotateDuplicates(ctx context.Context, job *FileIngestionJob, text string) {
+	if job.Metadata == nil {
+		job.Metadata = make(map[string]interface{})
+	}
+
+	fingerprint := dedup.ComputeFingerprint(text)
+	job.Metadata["content_hash"] = fingerprint.ContentHash
+
+	var previousJobID uuid.UUID
+	err := s.pool.QueryRow(ctx, `
+		SELECT id FROM neuronip.file_ingestion_jobs
+		WHERE metadata->>'content_hash' = $1 AND status = 'completed' AND id != $2
+		ORDER BY created_at
+		LIMIT 1`, fingerprint.ContentHash, job.ID).Scan(&previousJobID)
+	if err == nil {
+		job.Metadata["duplicate_of_job"] = previousJobID.String()
+	}
+
+	if match, err := s.dedupService.FindDuplicate(ctx, nil, fingerprint, nil); err == nil && match != nil {
+		job.Metadata["duplicate_of_document"] = match.CanonicalID.String()
+		job.Metadata["duplicate_type"] = match.Type
+		job.Metadata["duplicate_similarity"] = match.Similarity
+	}
+}
+
 /* GetFileIngestionJob retrieves a file ingestion job */
 func (s *FileIngestionService) GetFileIngestionJob(ctx context.Context, jobID uuid.UUID) (*FileIngestionJob, error) {
 	query := `
@@ -0,0 +1,408 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/alerts"
+	"github.com/neurondb/NeuronIP/api/internal/dataquality"
+)
+
+/* Gate severities decide what a failing quality gate does to the load */
+const (
+	GateSeverityBlock      = "block"      // abort the load
+	GateSeverityQuarantine = "quarantine" // move failing rows to the quarantine table and load the rest
+	GateSeverityWarn       = "warn"       // load everything and raise an alert
+)
+
+/* QualityGate attaches a quality rule to an ingestion job */
+type QualityGate struct {
+	RuleID      uuid.UUID  `json:"rule_id"`
+	Severity    string     `json:"severity"`
+	AlertRuleID *uuid.UUID `json:"alert_rule_id,omitempty"` // Alert rule warn gates raise alerts under
+}
+
+/* QualityGateResult is the outcome of one quality gate, stored with the job */
+type QualityGateResult struct {
+	RuleID          uuid.UUID  `json:"rule_id"`
+	RuleName        string     `json:"rule_name,omitempty"`
+	Severity        string     `json:"severity"`
+	Status          string     `json:"status"` // "passed", "failed" or "error"
+	Action          string     `json:"action"` // "none", "aborted", "quarantined" or "alerted"
+	Score           float64    `json:"score"`
+	FailedCount     int64      `json:"failed_count"`
+	TotalCount      int64      `json:"total_count"`
+	QuarantinedRows int64      `json:"quarantined_rows,omitempty"`
+	AlertID         *uuid.UUID `json:"alert_id,omitempty"`
+	Message         string     `json:"message,omitempty"`
+	EvaluatedAt     time.Time  `json:"evaluated_at"`
+}
+
+/* gatedTable is a schema-qualified table on the NeuronIP database */
+type gatedTable struct {
+	schema string
+	table  string
+}
+
+func parseGatedTable(name string) gatedTable {
+	if schema, table, ok := strings.Cut(name, "."); ok {
+		return gatedTable{schema: schema, table: table}
+	}
+	return gatedTable{schema: "public", table: name}
+}
+
+func (t gatedTable) String() string {
+	return t.schema + "." + t.table
+}
+
+func (t gatedTable) quoted() string {
+	return pgx.Identifier{t.schema, t.table}.Sanitize()
+}
+
+/* qualityGatePlan is a job's gates with the tables its load moves through */
+type qualityGatePlan struct {
+	gates      []QualityGate
+	stager     StagingSyncer
+	target     gatedTable
+	staging    gatedTable
+	quarantine gatedTable
+}
+
+/* qualityGatesFor reads the job's quality_gates config, returning nil when the job has none.
+ * The job then needs target_table and exactly one source table, and its connector must be able to stage rows.
+ * Each run stages into its own table, named from staging_table (default <target>_staging) and a run ID,
+ * so concurrent runs of a job do not drop each other's batch; quarantine_table defaults to <target>_quarantine. */
+func (s *IngestionService) qualityGatesFor(job *IngestionJob, connector Connector) (*qualityGatePlan, error) {
+	raw, ok := job.Config["quality_gates"].([]interface{})
+	if !ok || len(raw) == 0 {
+		return nil, nil
+	}
+	if s.quality == nil {
+		return nil, fmt.Errorf("quality gates are not configured")
+	}
+	stager, ok := connector.(StagingSyncer)
+	if !ok {
+		return nil, fmt.Errorf("connector type %s cannot stage rows for quality gates", connector.GetConnectorType())
+	}
+	if tables, _ := job.Config["tables"].([]interface{}); len(tables) != 1 {
+		return nil, fmt.Errorf("quality gates need exactly one source table in tables")
+	}
+
+	target, _ := job.Config["target_table"].(string)
+	if target == "" {
+		return nil, fmt.Errorf("target_table is required for quality gates")
+	}
+	plan := &qualityGatePlan{stager: stager, target: parseGatedTable(target)}
+	plan.staging = gatedTable{schema: plan.target.schema, table: plan.target.table + "_staging"}
+	if name, ok := job.Config["staging_table"].(string); ok && name != "" {
+		plan.staging = parseGatedTable(name)
+	}
+	plan.staging.table += "_" + strings.ReplaceAll(uuid.New().String(), "-", "")[:12]
+	plan.quarantine = gatedTable{schema: plan.target.schema, table: plan.target.table + "_quarantine"}
+	if name, ok := job.Config["quarantine_table"].(string); ok && name != "" {
+		plan.quarantine = parseGatedTable(name)
+	}
+
+	var defaultAlertRule *uuid.UUID
+	if id, ok := job.Config["quality_alert_rule_id"].(string); ok && id != "" {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quality_alert_rule_id: %w", err)
+		}
+		defaultAlertRule = &parsed
+	}
+
+	data, _ := json.Marshal(raw)
+	if err := json.Unmarshal(data, &plan.gates); err != nil {
+		return nil, fmt.Errorf("invalid quality_gates: %w", err)
+	}
+	for i := range plan.gates {
+		gate := &plan.gates[i]
+		if gate.RuleID == uuid.Nil {
+			return nil, fmt.Errorf("quality gate %d has no rule_id", i)
+		}
+		switch gate.Severity {
+		case GateSeverityBlock, GateSeverityQuarantine:
+		case GateSeverityWarn:
+			if gate.AlertRuleID == nil {
+				gate.AlertRuleID = defaultAlertRule
+			}
+			if gate.AlertRuleID == nil || s.alertService == nil {
+				return nil, fmt.Errorf("warn gate for rule %s needs an alert_rule_id or quality_alert_rule_id", gate.RuleID)
+			}
+		default:
+			return nil, fmt.Errorf("quality gate for rule %s has unknown severity: %s", gate.RuleID, gate.Severity)
+		}
+	}
+	return plan, nil
+}
+
+/* prepareStaging recreates the staging table empty with the target's columns */
+func (s *IngestionService) prepareStaging(ctx context.Context, plan *qualityGatePlan) error {
+	if _, err := s.pool.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s`, plan.staging.quoted())); err != nil {
+		return fmt.Errorf("failed to drop staging table: %w", err)
+	}
+	_, err := s.pool.Exec(ctx, fmt.Sprintf(`CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS)`, plan.staging.quoted(), plan.target.quoted()))
+	if err != nil {
+		return fmt.Errorf("failed to create staging table: %w", err)
+	}
+	return nil
+}
+
+/* stagingWriter copies rows a connector syncs into the load's staging table.
+ * Source columns are matched to staging columns by name, ignoring case, since sources such as Oracle
+ * and Snowflake report unquoted names in upper case. */
+type stagingWriter struct {
+	pool    *pgxpool.Pool
+	staging gatedTable
+	columns map[string]string // Lower-case name to staging column name
+}
+
+func (w *stagingWriter) WriteRows(ctx context.Context, columns []string, rows [][]interface{}) error {
+	if w.columns == nil {
+		names, err := w.stagingColumns(ctx)
+		if err != nil {
+			return err
+		}
+		w.columns = names
+	}
+	targetColumns := make([]string, len(columns))
+	for i, column := range columns {
+		name, ok := w.columns[strings.ToLower(column)]
+		if !ok {
+			return fmt.Errorf("source column %s has no column in %s", column, w.staging)
+		}
+		targetColumns[i] = name
+	}
+	_, err := w.pool.CopyFrom(ctx, pgx.Identifier{w.staging.schema, w.staging.table}, targetColumns, pgx.CopyFromRows(rows))
+	if err != nil {
+		return fmt.Errorf("failed to write staged rows: %w", err)
+	}
+	return nil
+}
+
+func (w *stagingWriter) stagingColumns(ctx context.Context) (map[string]string, error) {
+	rows, err := w.pool.Query(ctx, `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = $2`, w.staging.schema, w.staging.table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read staging columns: %w", err)
+	}
+	defer rows.Close()
+
+	names := make(map[string]string)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to read staging columns: %w", err)
+		}
+		names[strings.ToLower(name)] = name
+	}
+	return names, rows.Err()
+}
+
+/* discardStaging drops the staging table of a load that will not be committed */
+func (s *IngestionService) discardStaging(ctx context.Context, plan *qualityGatePlan) {
+	s.pool.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s`, plan.staging.quoted()))
+}
+
+/* gateLoad is the committed outcome of a gated load */
+type gateLoad struct {
+	results     []QualityGateResult
+	loaded      int64
+	quarantined int64
+}
+
+/* runQualityGates evaluates the gates against the staged batch and commits it.
+ * A failing block gate, or a failing quarantine gate whose rule cannot single out rows, aborts the load and returns an error.
+ * Otherwise failing quarantine gates move their rows aside, the remaining rows are loaded into the target in one transaction,
+ * and failing warn gates raise alerts once the load is committed. */
+func (s *IngestionService) runQualityGates(ctx context.Context, job *IngestionJob, plan *qualityGatePlan) (*gateLoad, error) {
+	batch := dataquality.StagedBatch{
+		SchemaName:   plan.staging.schema,
+		TableName:    plan.staging.table,
+		TargetSchema: plan.target.schema,
+		TargetTable:  plan.target.table,
+	}
+	load := &gateLoad{results: make([]QualityGateResult, 0, len(plan.gates))}
+	quarantineConditions := make(map[int]string)
+	var blocking []string
+
+	for _, gate := range plan.gates {
+		staged, err := s.quality.EvaluateStagedRule(ctx, gate.RuleID, batch)
+		result, condition := gateResult(gate, staged, err)
+		if condition != "" {
+			quarantineConditions[len(load.results)] = condition
+		}
+		if result.Action == "aborted" {
+			blocking = append(blocking, gate.RuleID.String())
+		}
+		load.results = append(load.results, result)
+	}
+
+	if len(blocking) > 0 {
+		s.discardStaging(ctx, plan)
+		for i := range load.results {
+			if load.results[i].Status != "passed" {
+				load.results[i].Action = "aborted"
+			}
+		}
+		return load, fmt.Errorf("load blocked by quality gates: %s", strings.Join(blocking, ", "))
+	}
+
+	if err := s.commitStagedBatch(ctx, job, plan, load, quarantineConditions); err != nil {
+		s.discardStaging(ctx, plan)
+		return load, err
+	}
+
+	for i, gate := range plan.gates {
+		if load.results[i].Action == "alerted" {
+			s.raiseGateAlert(ctx, job, plan, gate, &load.results[i])
+		}
+	}
+	return load, nil
+}
+
+/* gateResult grades one gate's evaluation against the staged batch and decides what it does to the load.
+ * It returns the condition selecting the rows to quarantine when the gate quarantines them, or "" otherwise. */
+func gateResult(gate QualityGate, staged *dataquality.StagedResult, err error) (QualityGateResult, string) {
+	result := QualityGateResult{
+		RuleID:      gate.RuleID,
+		Severity:    gate.Severity,
+		Status:      "passed",
+		Action:      "none",
+		EvaluatedAt: time.Now(),
+	}
+	if err != nil {
+		// A gate that cannot be evaluated gives no assurance, so it counts as failed
+		result.Status = "error"
+		result.Message = err.Error()
+		staged = nil
+	} else {
+		result.RuleName = staged.Rule.Name
+		result.Score = staged.Score
+		result.FailedCount = staged.Result.FailedCount
+		result.TotalCount = staged.Result.TotalCount
+		if !staged.Passed() {
+			result.Status = "failed"
+			result.Message = fmt.Sprintf("%d of %d rows failed (score %.1f)", result.FailedCount, result.TotalCount, result.Score)
+			if len(staged.Result.Violations) > 0 {
+				result.Message = staged.Result.Violations[0].ViolationMessage
+			}
+		}
+	}
+	if result.Status == "passed" {
+		return result, ""
+	}
+
+	switch gate.Severity {
+	case GateSeverityQuarantine:
+		if staged != nil && staged.FailingRows != "" {
+			result.Action = "quarantined"
+			return result, staged.FailingRows
+		}
+		result.Message += "; failing rows cannot be singled out, so the load is aborted"
+		fallthrough
+	case GateSeverityBlock:
+		result.Action = "aborted"
+	case GateSeverityWarn:
+		result.Action = "alerted"
+	}
+	return result, ""
+}
+
+/* commitStagedBatch quarantines the rows failing quarantine gates, loads the rest into the target and drops the staging table */
+func (s *IngestionService) commitStagedBatch(ctx context.Context, job *IngestionJob, plan *qualityGatePlan, load *gateLoad, quarantineConditions map[int]string) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin load: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if len(quarantineConditions) > 0 {
+		_, err := tx.Exec(ctx, fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s (
+				LIKE %s INCLUDING DEFAULTS,
+				_ingestion_job_id UUID,
+				_quality_rule_id UUID,
+				_quarantined_at TIMESTAMPTZ
+			)`, plan.quarantine.quoted(), plan.target.quoted()))
+		if err != nil {
+			return fmt.Errorf("failed to create quarantine table: %w", err)
+		}
+	}
+
+	// Gates run in order, so a row failing several quarantine gates is quarantined under the first
+	for i := range load.results {
+		condition, ok := quarantineConditions[i]
+		if !ok {
+			continue
+		}
+		staging := plan.staging.quoted()
+		tag, err := tx.Exec(ctx, fmt.Sprintf(`INSERT INTO %s SELECT %s.*, $1, $2, NOW() FROM %s WHERE %s`,
+			plan.quarantine.quoted(), staging, staging, condition), job.ID, load.results[i].RuleID)
+		if err != nil {
+			return fmt.Errorf("failed to quarantine rows: %w", err)
+		}
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s`, staging, condition)); err != nil {
+			return fmt.Errorf("failed to remove quarantined rows: %w", err)
+		}
+		load.results[i].QuarantinedRows = tag.RowsAffected()
+		load.quarantined += tag.RowsAffected()
+	}
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf(`INSERT INTO %s SELECT * FROM %s`, plan.target.quoted(), plan.staging.quoted()))
+	if err != nil {
+		return fmt.Errorf("failed to load staged rows: %w", err)
+	}
+	load.loaded = tag.RowsAffected()
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`DROP TABLE %s`, plan.staging.quoted())); err != nil {
+		return fmt.Errorf("failed to drop staging table: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit load: %w", err)
+	}
+	return nil
+}
+
+/* raiseGateAlert raises the alert of a failing warn gate. Failing to raise it does not undo the load; the result says so instead. */
+func (s *IngestionService) raiseGateAlert(ctx context.Context, job *IngestionJob, plan *qualityGatePlan, gate QualityGate, result *QualityGateResult) {
+	name := result.RuleName
+	if name == "" {
+		name = gate.RuleID.String()
+	}
+	alert, err := s.alertService.RaiseAlert(ctx, alerts.Alert{
+		RuleID:   *gate.AlertRuleID,
+		Severity: "medium",
+		Message:  fmt.Sprintf("Ingestion job %s loaded %s despite failing quality rule %s: %s", job.ID, plan.target, name, result.Message),
+		Details: map[string]interface{}{
+			"ingestion_job_id": job.ID.String(),
+			"data_source_id":   job.DataSourceID.String(),
+			"target_table":     plan.target.String(),
+			"quality_rule_id":  gate.RuleID.String(),
+			"failed_count":     result.FailedCount,
+			"total_count":      result.TotalCount,
+		},
+	})
+	if err != nil {
+		result.Message += "; alert not raised: " + err.Error()
+		return
+	}
+	result.AlertID = &alert.ID
+}
+
+/* storeQualityGateResults records gate outcomes with the job */
+func (s *IngestionService) storeQualityGateResults(ctx context.Context, jobID uuid.UUID, results []QualityGateResult) {
+	resultsJSON, _ := json.Marshal(results)
+	s.pool.Exec(ctx, `
+		UPDATE neuronip.ingestion_jobs
+		SET quality_gate_results = $1, updated_at = NOW()
+		WHERE id = $2`, resultsJSON, jobID)
+}
@@ -0,0 +1,208 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/neurondb/NeuronIP/api/internal/alerts"
+	"github.com/neurondb/NeuronIP/api/internal/dataquality"
+)
+
+/* fakeConnector is a connector that cannot stage rows */
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(ctx context.Context, config map[string]interface{}) error { return nil }
+func (fakeConnector) Disconnect(ctx context.Context) error                             { return nil }
+func (fakeConnector) TestConnection(ctx context.Context) error                         { return nil }
+func (fakeConnector) DiscoverSchema(ctx context.Context) (*Schema, error)              { return &Schema{}, nil }
+func (fakeConnector) Sync(ctx context.Context, options SyncOptions) (*SyncResult, error) {
+	return &SyncResult{}, nil
+}
+func (fakeConnector) GetConnectorType() string       { return "fake" }
+func (fakeConnector) GetMetadata() ConnectorMetadata { return ConnectorMetadata{} }
+
+/* fakeStagingConnector is a connector that can stage rows */
+type fakeStagingConnector struct{ fakeConnector }
+
+func (fakeStagingConnector) SyncToStaging(ctx context.Context, options SyncOptions, staging StagingWriter) (*SyncResult, error) {
+	return &SyncResult{}, nil
+}
+
+/* TestQualityGatesFor checks the job config a gated load needs and the tables it moves through */
+func TestQualityGatesFor(t *testing.T) {
+	ruleID := uuid.New()
+	alertRuleID := uuid.New()
+	gated := func(gates ...map[string]interface{}) map[string]interface{} {
+		raw := make([]interface{}, len(gates))
+		for i, gate := range gates {
+			raw[i] = gate
+		}
+		return map[string]interface{}{
+			"quality_gates": raw,
+			"tables":        []interface{}{"orders"},
+			"target_table":  "sales.orders",
+		}
+	}
+	gate := func(severity string) map[string]interface{} {
+		return map[string]interface{}{"rule_id": ruleID.String(), "severity": severity}
+	}
+
+	tests := []struct {
+		name      string
+		config    map[string]interface{}
+		connector Connector
+		none      bool
+		errMsg    string
+	}{
+		{name: "no gates", config: map[string]interface{}{}, none: true},
+		{name: "cannot stage", config: gated(gate(GateSeverityBlock)), connector: fakeConnector{}, errMsg: "cannot stage rows"},
+		{
+			name: "several tables",
+			config: func() map[string]interface{} {
+				c := gated(gate(GateSeverityBlock))
+				c["tables"] = []interface{}{"a", "b"}
+				return c
+			}(),
+			errMsg: "exactly one source table",
+		},
+		{
+			name: "no target",
+			config: func() map[string]interface{} {
+				c := gated(gate(GateSeverityBlock))
+				delete(c, "target_table")
+				return c
+			}(),
+			errMsg: "target_table is required",
+		},
+		{name: "no rule", config: gated(map[string]interface{}{"severity": GateSeverityBlock}), errMsg: "has no rule_id"},
+		{name: "unknown severity", config: gated(gate("fatal")), errMsg: "unknown severity"},
+		{name: "warn without alert rule", config: gated(gate(GateSeverityWarn)), errMsg: "needs an alert_rule_id"},
+		{name: "block and quarantine", config: gated(gate(GateSeverityBlock), gate(GateSeverityQuarantine))},
+		{
+			name: "warn with default alert rule",
+			config: func() map[string]interface{} {
+				c := gated(gate(GateSeverityWarn))
+				c["quality_alert_rule_id"] = alertRuleID.String()
+				return c
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &IngestionService{quality: &dataquality.Service{}, alertService: &alerts.Service{}}
+			connector := tt.connector
+			if connector == nil {
+				connector = fakeStagingConnector{}
+			}
+			plan, err := s.qualityGatesFor(&IngestionJob{ID: uuid.New(), Config: tt.config}, connector)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.none {
+				if plan != nil {
+					t.Fatalf("expected no plan, got %+v", plan)
+				}
+				return
+			}
+			if plan.target.String() != "sales.orders" || plan.quarantine.String() != "sales.orders_quarantine" {
+				t.Errorf("unexpected target %s or quarantine %s", plan.target, plan.quarantine)
+			}
+			if plan.staging.schema != "sales" || !strings.HasPrefix(plan.staging.table, "orders_staging_") {
+				t.Errorf("expected a per-run staging table for sales.orders, got %s", plan.staging)
+			}
+			for _, g := range plan.gates {
+				if g.Severity == GateSeverityWarn && (g.AlertRuleID == nil || *g.AlertRuleID != alertRuleID) {
+					t.Errorf("expected warn gate to default to alert rule %s, got %v", alertRuleID, g.AlertRuleID)
+				}
+			}
+		})
+	}
+
+	// Concurrent runs of a job stage into different tables
+	s := &IngestionService{quality: &dataquality.Service{}}
+	first, _ := s.qualityGatesFor(&IngestionJob{Config: gated(gate(GateSeverityBlock))}, fakeStagingConnector{})
+	second, _ := s.qualityGatesFor(&IngestionJob{Config: gated(gate(GateSeverityBlock))}, fakeStagingConnector{})
+	if first.staging == second.staging {
+		t.Errorf("expected separate staging tables, both runs got %s", first.staging)
+	}
+}
+
+/* TestParseGatedTable checks that unqualified table names land in the public schema */
+func TestParseGatedTable(t *testing.T) {
+	if got := parseGatedTable("sales.orders"); got.schema != "sales" || got.table != "orders" {
+		t.Errorf("expected sales.orders, got %s", got)
+	}
+	if got := parseGatedTable("orders"); got.String() != "public.orders" {
+		t.Errorf("expected public.orders, got %s", got)
+	}
+	if got := parseGatedTable(`sales.Order "Lines"`).quoted(); got != `"sales"."Order ""Lines"""` {
+		t.Errorf("expected the name to be quoted, got %s", got)
+	}
+}
+
+/* TestGateResult checks what a failing gate does to the load for each severity */
+func TestGateResult(t *testing.T) {
+	threshold := 95.0
+	rule := &dataquality.QualityRule{Name: "order amount positive"}
+	passed := &dataquality.StagedResult{Rule: rule, Result: &dataquality.RuleExecutionResult{TotalCount: 10}, Score: 100, Status: "pass"}
+	failedRows := &dataquality.StagedResult{
+		Rule:        rule,
+		Result:      &dataquality.RuleExecutionResult{FailedCount: 2, TotalCount: 10},
+		Score:       80,
+		Status:      "fail",
+		FailingRows: `"amount" <= 0`,
+	}
+	failedBatch := &dataquality.StagedResult{Rule: rule, Result: &dataquality.RuleExecutionResult{TotalCount: 3}, Score: 0, Status: "fail"}
+	withinThreshold := &dataquality.StagedResult{
+		Rule:   &dataquality.QualityRule{Name: "order amount positive", Threshold: &threshold},
+		Result: &dataquality.RuleExecutionResult{FailedCount: 1, TotalCount: 100},
+		Score:  99,
+		Status: "warning",
+	}
+
+	tests := []struct {
+		name      string
+		severity  string
+		staged    *dataquality.StagedResult
+		err       error
+		status    string
+		action    string
+		condition string
+	}{
+		{name: "passed", severity: GateSeverityBlock, staged: passed, status: "passed", action: "none"},
+		{name: "within threshold", severity: GateSeverityBlock, staged: withinThreshold, status: "passed", action: "none"},
+		{name: "block", severity: GateSeverityBlock, staged: failedRows, status: "failed", action: "aborted"},
+		{name: "quarantine rows", severity: GateSeverityQuarantine, staged: failedRows, status: "failed", action: "quarantined", condition: `"amount" <= 0`},
+		{name: "quarantine whole batch", severity: GateSeverityQuarantine, staged: failedBatch, status: "failed", action: "aborted"},
+		{name: "quarantine error", severity: GateSeverityQuarantine, err: errors.New("rule is disabled"), status: "error", action: "aborted"},
+		{name: "warn", severity: GateSeverityWarn, staged: failedRows, status: "failed", action: "alerted"},
+		{name: "warn error", severity: GateSeverityWarn, err: errors.New("rule is disabled"), status: "error", action: "alerted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate := QualityGate{RuleID: uuid.New(), Severity: tt.severity}
+			result, condition := gateResult(gate, tt.staged, tt.err)
+			if result.Status != tt.status || result.Action != tt.action {
+				t.Fatalf("expected %s/%s, got %s/%s (%s)", tt.status, tt.action, result.Status, result.Action, result.Message)
+			}
+			if condition != tt.condition {
+				t.Errorf("expected quarantine condition %q, got %q", tt.condition, condition)
+			}
+			if result.RuleID != gate.RuleID || result.Severity != tt.severity {
+				t.Errorf("expected result for gate %s, got %+v", gate.RuleID, result)
+			}
+			if tt.err != nil && !strings.Contains(result.Message, tt.err.Error()) {
+				t.Errorf("expected message to carry the evaluation error, got %q", result.Message)
+			}
+		})
+	}
+}
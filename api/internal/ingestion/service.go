@@ -5,10 +5,16 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/alerts"
+	"github.com/neurondb/NeuronIP/api/internal/cache"
+	"github.com/neurondb/NeuronIP/api/internal/connectors"
+	"github.com/neurondb/NeuronIP/api/internal/logging"
+	"github.com/neurondb/NeuronIP/api/internal/dataquality"
 	"github.com/neurondb/NeuronIP/api/internal/ingestion/cdc"
 	"github.com/neurondb/NeuronIP/api/internal/ingestion/etl"
 	"github.com/neurondb/NeuronIP/api/internal/mcp"
@@ -21,6 +27,15 @@ type IngestionService struct {
 	cdcManager    *cdc.CDCManager
 	etlEngine     *etl.ETLEngine
 	mcpClient     *mcp.Client
+	tableListener cache.TableChangeListener
+	quality       *dataquality.Service
+	alertService  *alerts.Service
+	contracts     ContractValidator
+}
+
+/* ContractValidator checks an ingestion job against the data contracts of the catalog connector it reads from */
+type ContractValidator interface {
+	ValidateIngestion(ctx context.Context, connectorID uuid.UUID, jobID uuid.UUID, tables []string) (*connectors.SyncValidation, error)
 }
 
 /* RegisterConnectorFactory registers a connector factory */
@@ -39,6 +54,42 @@ func NewService(pool *pgxpool.Pool, mcpClient *mcp.Client) *IngestionService {
 	}
 }
 
+/* ServiceOptions holds the optional components of an ingestion service */
+type ServiceOptions struct {
+	TableListener cache.TableChangeListener // Notified of tables changed by syncs and CDC
+	Quality       *dataquality.Service      // Evaluates quality gates against staged batches before commit
+	Alerts        *alerts.Service           // Raises alerts for failing warn gates
+	Contracts     ContractValidator         // Validates jobs against the data contracts of their connector
+}
+
+/* NewServiceWithOptions creates an ingestion service with the given optional components */
+func NewServiceWithOptions(pool *pgxpool.Pool, mcpClient *mcp.Client, opts ServiceOptions) *IngestionService {
+	svc := NewService(pool, mcpClient)
+	if opts.TableListener != nil {
+		svc.cdcManager = cdc.NewCDCManagerWithListener(pool, opts.TableListener)
+	}
+	svc.tableListener = opts.TableListener
+	svc.quality = opts.Quality
+	svc.alertService = opts.Alerts
+	svc.contracts = opts.Contracts
+	return svc
+}
+
+/* notifyTablesChanged tells the table listener that ingestion changed tables */
+func (s *IngestionService) notifyTablesChanged(ctx context.Context, tables []string, source string) {
+	if s.tableListener == nil || len(tables) == 0 {
+		return
+	}
+	// Best effort: entries that miss an invalidation still expire by TTL
+	if err := s.tableListener.TablesChanged(ctx, cache.TableChange{
+		Tables: tables,
+		Reason: cache.TableChangeIngestion,
+		Source: source,
+	}); err != nil {
+		logging.Error("Failed to invalidate cached results after ingestion", "tables", tables, "source", source, "error", err)
+	}
+}
+
 /* CreateIngestionJob creates a new ingestion job */
 func (s *IngestionService) CreateIngestionJob(ctx context.Context, dataSourceID uuid.UUID, jobType string, config map[string]interface{}) (*IngestionJob, error) {
 	jobID := uuid.New()
@@ -50,14 +101,14 @@ func (s *IngestionService) CreateIngestionJob(ctx context.Context, dataSourceID
 		(id, data_source_id, job_type, status, config, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, data_source_id, job_type, status, config, progress, error_message, 
-		          rows_processed, started_at, completed_at, created_at, updated_at`
+		          rows_processed, started_at, completed_at, created_at, updated_at, quality_gate_results`
 	
 	var job IngestionJob
-	var configJSONRaw, progressJSON json.RawMessage
+	var configJSONRaw, progressJSON, gatesJSON json.RawMessage
 	
 	err := s.pool.QueryRow(ctx, query, jobID, dataSourceID, jobType, "pending", configJSON, now, now).Scan(
 		&job.ID, &job.DataSourceID, &job.JobType, &job.Status, &configJSONRaw, &progressJSON,
-		&job.ErrorMessage, &job.RowsProcessed, &job.StartedAt, &job.CompletedAt, &job.CreatedAt, &job.UpdatedAt,
+		&job.ErrorMessage, &job.RowsProcessed, &job.StartedAt, &job.CompletedAt, &job.CreatedAt, &job.UpdatedAt, &gatesJSON,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ingestion job: %w", err)
@@ -67,6 +118,9 @@ func (s *IngestionService) CreateIngestionJob(ctx context.Context, dataSourceID
 	if progressJSON != nil {
 		json.Unmarshal(progressJSON, &job.Progress)
 	}
+	if gatesJSON != nil {
+		json.Unmarshal(gatesJSON, &job.QualityGates)
+	}
 	
 	return &job, nil
 }
@@ -99,6 +153,14 @@ func (s *IngestionService) ExecuteSyncJob(ctx context.Context, jobID uuid.UUID)
 		return fmt.Errorf("failed to get connector: %w", err)
 	}
 	
+	// Jobs with quality gates land rows in a staging table so the gates can check them before commit
+	gates, err := s.qualityGatesFor(job, connector)
+	if err != nil {
+		errMsg := err.Error()
+		s.updateJobStatus(ctx, jobID, "failed", &errMsg)
+		return err
+	}
+
 	// Update job status
 	s.updateJobStatus(ctx, jobID, "running", nil)
 	
@@ -145,9 +207,34 @@ func (s *IngestionService) ExecuteSyncJob(ctx context.Context, jobID uuid.UUID)
 			}
 		}
 	}
+
+	// Active contracts with block enforcement stop the job before anything is loaded
+	violations, err := s.validateContracts(ctx, job, syncOptions.Tables)
+	if err != nil {
+		errMsg := err.Error()
+		s.updateJobStatus(ctx, jobID, "failed", &errMsg)
+		return err
+	}
+
+	if gates != nil {
+		if err := s.prepareStaging(ctx, gates); err != nil {
+			errMsg := err.Error()
+			s.updateJobStatus(ctx, jobID, "failed", &errMsg)
+			return err
+		}
+		syncOptions.StagingTable = gates.staging.String()
+	}
 	
-	result, err := connector.Sync(ctx, syncOptions)
+	var result *SyncResult
+	if gates != nil {
+		result, err = gates.stager.SyncToStaging(ctx, syncOptions, &stagingWriter{pool: s.pool, staging: gates.staging})
+	} else {
+		result, err = connector.Sync(ctx, syncOptions)
+	}
 	if err != nil {
+		if gates != nil {
+			s.discardStaging(ctx, gates)
+		}
 		// Handle retry logic
 		if retryErr := s.handleJobFailure(ctx, jobID, err); retryErr != nil {
 			return retryErr
@@ -156,6 +243,18 @@ func (s *IngestionService) ExecuteSyncJob(ctx context.Context, jobID uuid.UUID)
 		return nil
 	}
 	
+	// Gate the staged batch before anything is committed, so a blocked load leaves the watermark where it was
+	var load *gateLoad
+	if gates != nil {
+		load, err = s.runQualityGates(ctx, job, gates)
+		s.storeQualityGateResults(ctx, jobID, load.results)
+		if err != nil {
+			errMsg := err.Error()
+			s.updateJobStatus(ctx, jobID, "failed", &errMsg)
+			return err
+		}
+	}
+
 	// Update watermark for incremental sync
 	if syncOptions.Mode == SyncModeIncremental {
 		if watermark, ok := job.Config["watermark_column"].(string); ok && watermark != "" {
@@ -174,12 +273,46 @@ func (s *IngestionService) ExecuteSyncJob(ctx context.Context, jobID uuid.UUID)
 		"tables_synced": result.TablesSynced,
 		"duration":      result.Duration.String(),
 	}
+	if violations > 0 {
+		progress["contract_violations"] = violations
+	}
+	if load != nil {
+		progress["rows_loaded"] = load.loaded
+		progress["rows_quarantined"] = load.quarantined
+	}
 	
 	s.updateJobComplete(ctx, jobID, result.RowsSynced, progress)
+
+	changedTables := result.TablesSynced
+	if len(changedTables) == 0 {
+		changedTables = syncOptions.Tables
+	}
+	s.notifyTablesChanged(ctx, changedTables, job.DataSourceID.String())
 	
 	return nil
 }
 
+/* validateContracts validates the job against the contracts of the catalog connector named by its connector_id
+ * config, returning the number of violations; jobs without a connector_id are not covered by contracts */
+func (s *IngestionService) validateContracts(ctx context.Context, job *IngestionJob, tables []string) (int, error) {
+	id, _ := job.Config["connector_id"].(string)
+	if s.contracts == nil || id == "" {
+		return 0, nil
+	}
+	connectorID, err := uuid.Parse(id)
+	if err != nil {
+		return 0, fmt.Errorf("invalid connector_id: %w", err)
+	}
+	validation, err := s.contracts.ValidateIngestion(ctx, connectorID, job.ID, tables)
+	if err != nil {
+		return 0, fmt.Errorf("failed to validate data contracts: %w", err)
+	}
+	if len(validation.Blocking) > 0 {
+		return validation.Violations, fmt.Errorf("sync blocked: %s", strings.Join(validation.Blocking, "; "))
+	}
+	return validation.Violations, nil
+}
+
 /* handleJobFailure handles job failures with retry logic */
 func (s *IngestionService) handleJobFailure(ctx context.Context, jobID uuid.UUID, err error) error {
 	job, jobErr := s.GetIngestionJob(ctx, jobID)
@@ -313,16 +446,16 @@ func (s *IngestionService) updateJobComplete(ctx context.Context, jobID uuid.UUI
 func (s *IngestionService) GetIngestionJob(ctx context.Context, jobID uuid.UUID) (*IngestionJob, error) {
 	query := `
 		SELECT id, data_source_id, job_type, status, config, progress, error_message,
-		       rows_processed, started_at, completed_at, created_at, updated_at
+		       rows_processed, started_at, completed_at, created_at, updated_at, quality_gate_results
 		FROM neuronip.ingestion_jobs
 		WHERE id = $1`
 	
 	var job IngestionJob
-	var configJSON, progressJSON json.RawMessage
+	var configJSON, progressJSON, gatesJSON json.RawMessage
 	
 	err := s.pool.QueryRow(ctx, query, jobID).Scan(
 		&job.ID, &job.DataSourceID, &job.JobType, &job.Status, &configJSON, &progressJSON,
-		&job.ErrorMessage, &job.RowsProcessed, &job.StartedAt, &job.CompletedAt, &job.CreatedAt, &job.UpdatedAt,
+		&job.ErrorMessage, &job.RowsProcessed, &job.StartedAt, &job.CompletedAt, &job.CreatedAt, &job.UpdatedAt, &gatesJSON,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ingestion job: %w", err)
@@ -332,6 +465,9 @@ func (s *IngestionService) GetIngestionJob(ctx context.Context, jobID uuid.UUID)
 	if progressJSON != nil {
 		json.Unmarshal(progressJSON, &job.Progress)
 	}
+	if gatesJSON != nil {
+		json.Unmarshal(gatesJSON, &job.QualityGates)
+	}
 	
 	return &job, nil
 }
@@ -340,7 +476,7 @@ func (s *IngestionService) GetIngestionJob(ctx context.Context, jobID uuid.UUID)
 func (s *IngestionService) ListIngestionJobs(ctx context.Context, dataSourceID *uuid.UUID, limit int) ([]IngestionJob, error) {
 	query := `
 		SELECT id, data_source_id, job_type, status, config, progress, error_message,
-		       rows_processed, started_at, completed_at, created_at, updated_at
+		       rows_processed, started_at, completed_at, created_at, updated_at, quality_gate_results
 		FROM neuronip.ingestion_jobs`
 	
 	args := []interface{}{}
@@ -368,11 +504,11 @@ func (s *IngestionService) ListIngestionJobs(ctx context.Context, dataSourceID *
 	jobs := make([]IngestionJob, 0)
 	for rows.Next() {
 		var job IngestionJob
-		var configJSON, progressJSON json.RawMessage
+		var configJSON, progressJSON, gatesJSON json.RawMessage
 		
 		err := rows.Scan(
 			&job.ID, &job.DataSourceID, &job.JobType, &job.Status, &configJSON, &progressJSON,
-			&job.ErrorMessage, &job.RowsProcessed, &job.StartedAt, &job.CompletedAt, &job.CreatedAt, &job.UpdatedAt,
+			&job.ErrorMessage, &job.RowsProcessed, &job.StartedAt, &job.CompletedAt, &job.CreatedAt, &job.UpdatedAt, &gatesJSON,
 		)
 		if err != nil {
 			continue
@@ -382,6 +518,9 @@ func (s *IngestionService) ListIngestionJobs(ctx context.Context, dataSourceID *
 		if progressJSON != nil {
 			json.Unmarshal(progressJSON, &job.Progress)
 		}
+		if gatesJSON != nil {
+			json.Unmarshal(gatesJSON, &job.QualityGates)
+		}
 		
 		jobs = append(jobs, job)
 	}
@@ -403,6 +542,7 @@ type IngestionJob struct {
 	CompletedAt   *time.Time             `json:"completed_at,omitempty"`
 	CreatedAt     time.Time              `json:"created_at"`
 	UpdatedAt     time.Time              `json:"updated_at"`
+	QualityGates  []QualityGateResult    `json:"quality_gates,omitempty"`
 }
 
 /* LoadDatasetFromSource loads a dataset using MCP LoadDataset tool */
@@ -436,6 +576,7 @@ func (s *IngestionService) LoadDatasetFromSource(ctx context.Context, sourceType
 		if err != nil {
 			// Log error but continue
 		}
+		s.notifyTablesChanged(ctx, []string{targetTable}, sourcePath)
 	}
 
 	return result, nil
@@ -25,7 +25,7 @@ type DiscoveryRule struct {
 	ID           uuid.UUID              `json:"id"`
 	Name         string                 `json:"name"`
 	Description  string                 `json:"description"`
-	SourceType   string                 `json:"source_type"`   // "query_log", "sql_parser", "api_call", "etl_job"
+	SourceType   string                 `json:"source_type"`   // "query_log", "sql_parser", "api_call", "etl_job", "suggested_joins"
 	Pattern      map[string]interface{} `json:"pattern"`       // Pattern matching rules
 	Enabled      bool                   `json:"enabled"`
 	LastRunAt    *time.Time             `json:"last_run_at,omitempty"`
@@ -203,6 +203,14 @@ func (s *DiscoveryService) discoverByRule(ctx context.Context, rule DiscoveryRul
 			return nil, err
 		}
 		discovered = append(discovered, results...)
+
+	case "suggested_joins":
+		// Turn joins suggested by profiling's inclusion dependencies into dependencies between tables
+		results, err := s.discoverFromSuggestedJoins(ctx, rule)
+		if err != nil {
+			return nil, err
+		}
+		discovered = append(discovered, results...)
 	}
 
 	// Save discovered lineage
@@ -291,6 +299,77 @@ func (s *DiscoveryService) discoverFromAPICalls(ctx context.Context, rule Discov
 	return []DiscoveredLineage{}, nil
 }
 
+/* discoverFromSuggestedJoins discovers lineage from suggested joins: the referencing table depends on the
+ * referenced one. Rejected joins and joins already discovered are skipped; pattern "min_confidence" (default
+ * 0.8) filters joins not yet accepted. */
+func (s *DiscoveryService) discoverFromSuggestedJoins(ctx context.Context, rule DiscoveryRule) ([]DiscoveredLineage, error) {
+	minConfidence := 0.8
+	if v, ok := rule.Pattern["min_confidence"].(float64); ok {
+		minConfidence = v
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT sj.id, sj.connector_id, sj.from_schema, sj.from_table, sj.from_column,
+			sj.to_schema, sj.to_table, sj.to_column, sj.confidence, sj.status
+		FROM neuronip.suggested_joins sj
+		WHERE (sj.status = 'accepted' OR (sj.status = 'suggested' AND sj.confidence >= $1))
+			AND NOT EXISTS (
+				SELECT 1 FROM neuronip.discovered_lineage dl
+				WHERE dl.evidence->>'suggested_join_id' = sj.id::text
+			)
+		ORDER BY sj.confidence DESC
+		LIMIT 500`, minConfidence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suggested joins: %w", err)
+	}
+
+	type suggestedJoin struct {
+		id, connectorID                     uuid.UUID
+		fromSchema, fromTable, fromColumn   string
+		toSchema, toTable, toColumn, status string
+		confidence                          float64
+	}
+	var joins []suggestedJoin
+	for rows.Next() {
+		var j suggestedJoin
+		if err := rows.Scan(&j.id, &j.connectorID, &j.fromSchema, &j.fromTable, &j.fromColumn,
+			&j.toSchema, &j.toTable, &j.toColumn, &j.confidence, &j.status); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan suggested join: %w", err)
+		}
+		joins = append(joins, j)
+	}
+	rows.Close()
+
+	var discovered []DiscoveredLineage
+	for _, j := range joins {
+		sourceID, err := findOrCreateTableNode(ctx, s.pool, "connector_id", j.connectorID.String(), j.toSchema+"."+j.toTable)
+		if err != nil {
+			return nil, err
+		}
+		targetID, err := findOrCreateTableNode(ctx, s.pool, "connector_id", j.connectorID.String(), j.fromSchema+"."+j.fromTable)
+		if err != nil {
+			return nil, err
+		}
+		confidence := j.confidence
+		if j.status == "accepted" {
+			confidence = 1
+		}
+		discovered = append(discovered, DiscoveredLineage{
+			SourceNodeID: sourceID,
+			TargetNodeID: targetID,
+			EdgeType:     "depends_on",
+			Confidence:   confidence,
+			Evidence: map[string]interface{}{
+				"suggested_join_id": j.id.String(),
+				"join":              fmt.Sprintf("%s.%s.%s = %s.%s.%s", j.fromSchema, j.fromTable, j.fromColumn, j.toSchema, j.toTable, j.toColumn),
+				"status":            j.status,
+			},
+		})
+	}
+	return discovered, nil
+}
+
 /* VerifyDiscoveredLineage marks discovered lineage as verified and creates actual lineage edges */
 func (s *DiscoveryService) VerifyDiscoveredLineage(ctx context.Context, discoveredID uuid.UUID) error {
 	// Get discovered lineage
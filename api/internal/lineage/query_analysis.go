@@ -298,14 +298,14 @@ func (s *QueryAnalysisService) DiscoverLineageFromQueries(ctx context.Context,
 		// Find or create nodes for source tables
 		var sourceNodeIDs []uuid.UUID
 		for _, tableName := range pattern.SourceTables {
-			nodeID, err := s.findOrCreateNode(ctx, tableName, schemaID)
+			nodeID, err := findOrCreateTableNode(ctx, s.pool, "schema_id", schemaID, tableName)
 			if err == nil {
 				sourceNodeIDs = append(sourceNodeIDs, nodeID)
 			}
 		}
 
 		// Find or create node for target table
-		targetNodeID, err := s.findOrCreateNode(ctx, pattern.TargetTable, schemaID)
+		targetNodeID, err := findOrCreateTableNode(ctx, s.pool, "schema_id", schemaID, pattern.TargetTable)
 		if err != nil {
 			continue
 		}
@@ -331,43 +331,35 @@ func (s *QueryAnalysisService) DiscoverLineageFromQueries(ctx context.Context,
 	return discovered, nil
 }
 
-/* findOrCreateNode finds or creates a lineage node */
-func (s *QueryAnalysisService) findOrCreateNode(ctx context.Context,
-	tableName, schemaID string) (uuid.UUID, error) {
-
-	// Try to find existing node
+/* findOrCreateTableNode returns the lineage node of a table, creating it if needed. Table names are only
+ * unique within a scope, so nodes are keyed on a metadata key naming it, such as schema_id or connector_id. */
+func findOrCreateTableNode(ctx context.Context, pool *pgxpool.Pool, scopeKey, scopeID, tableName string) (uuid.UUID, error) {
 	var nodeID uuid.UUID
-	err := s.pool.QueryRow(ctx, `
+	err := pool.QueryRow(ctx, `
 		SELECT id
 		FROM neuronip.lineage_nodes
 		WHERE node_name = $1
-		AND metadata->>'schema_id' = $2
-		LIMIT 1`, tableName, schemaID,
+		AND metadata->>$2 = $3
+		LIMIT 1`, tableName, scopeKey, scopeID,
 	).Scan(&nodeID)
-
 	if err == nil {
 		return nodeID, nil
 	}
 
-	// Create new node
 	nodeID = uuid.New()
-	metadata := map[string]interface{}{
-		"schema_id":     schemaID,
+	metadataJSON, _ := json.Marshal(map[string]interface{}{
+		scopeKey:        scopeID,
 		"resource_type": "table",
 		"resource_id":   tableName,
-	}
-	metadataJSON, _ := json.Marshal(metadata)
-
-	_, err = s.pool.Exec(ctx, `
+	})
+	_, err = pool.Exec(ctx, `
 		INSERT INTO neuronip.lineage_nodes
 		(id, node_type, node_name, metadata, created_at)
 		VALUES ($1, 'table', $2, $3, NOW())`,
 		nodeID, tableName, metadataJSON,
 	)
-
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("failed to create node: %w", err)
 	}
-
 	return nodeID, nil
 }
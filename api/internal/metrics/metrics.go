@@ -109,6 +109,32 @@ var (
 		},
 	)
 
+	// Semantic answer cache metrics
+	semanticCacheLookupsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semantic_cache_lookups_total",
+			Help: "Total number of semantic answer cache lookups",
+		},
+		[]string{"namespace", "result"},
+	)
+
+	semanticCacheHitSimilarity = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "semantic_cache_hit_similarity",
+			Help:    "Question similarity of semantic answer cache hits",
+			Buckets: []float64{0.85, 0.9, 0.92, 0.94, 0.96, 0.98, 1.0},
+		},
+		[]string{"namespace"},
+	)
+
+	semanticCacheInvalidationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semantic_cache_invalidations_total",
+			Help: "Total number of semantic answer cache entries invalidated",
+		},
+		[]string{"reason"},
+	)
+
 	// Agent execution metrics
 	agentExecutionsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -215,6 +241,21 @@ func RecordWarehouseQueryDuration(duration time.Duration) {
 	warehouseQueryDuration.Observe(duration.Seconds())
 }
 
+/* RecordSemanticCacheLookup records a semantic answer cache hit or miss */
+func RecordSemanticCacheLookup(namespace string, hit bool, similarity float64) {
+	if hit {
+		semanticCacheLookupsTotal.WithLabelValues(namespace, "hit").Inc()
+		semanticCacheHitSimilarity.WithLabelValues(namespace).Observe(similarity)
+		return
+	}
+	semanticCacheLookupsTotal.WithLabelValues(namespace, "miss").Inc()
+}
+
+/* RecordSemanticCacheInvalidations records invalidated semantic answer cache entries */
+func RecordSemanticCacheInvalidations(reason string, count int64) {
+	semanticCacheInvalidationsTotal.WithLabelValues(reason).Add(float64(count))
+}
+
 /* IncrementAgentExecution increments agent execution counter */
 func IncrementAgentExecution(agentID, status string) {
 	agentExecutionsTotal.WithLabelValues(agentID, status).Inc()
@@ -45,6 +45,11 @@ func (rw *responseWriter) Flush() {
 	}
 }
 
+/* Unwrap exposes the underlying writer to http.ResponseController */
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 /* HTTPLogging is a middleware that logs HTTP requests and responses */
 func HTTPLogging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -12,6 +12,7 @@ type TimeoutConfig struct {
 	Query     time.Duration
 	Workflow  time.Duration
 	Ingestion time.Duration
+	Stream    time.Duration // Streaming responses; also extends the server write deadline
 }
 
 /* DefaultTimeoutConfig returns default timeout configuration */
@@ -21,6 +22,7 @@ func DefaultTimeoutConfig() TimeoutConfig {
 		Query:     5 * time.Minute,
 		Workflow:  1 * time.Hour,
 		Ingestion: 10 * time.Minute,
+		Stream:    1 * time.Hour,
 	}
 }
 
@@ -53,6 +55,22 @@ func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
 	}
 }
 
+/* StreamTimeout creates a middleware for streaming routes. The handler runs on the request goroutine,
+ * since a timeout response cannot be raced against a partly written stream, and the server write
+ * deadline is extended so the stream is not cut off at the server-wide WriteTimeout. */
+func StreamTimeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			// Writers without deadline support (e.g. in tests) keep the server default
+			http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
 /* TimeoutByRoute creates a middleware that applies different timeouts based on route */
 func TimeoutByRoute(config TimeoutConfig) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -62,6 +80,9 @@ func TimeoutByRoute(config TimeoutConfig) func(http.Handler) http.Handler {
 			// Determine timeout based on route path
 			path := r.URL.Path
 			switch {
+			case contains(path, "/rag/query/stream") || contains(path, "/warehouse/query/stream"):
+				StreamTimeout(config.Stream)(next).ServeHTTP(w, r)
+				return
 			case contains(path, "/warehouse/query") || contains(path, "/semantic/search") || contains(path, "/semantic/rag"):
 				timeout = config.Query
 			case contains(path, "/workflows/") && contains(path, "/execute"):
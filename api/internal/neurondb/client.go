@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -293,31 +294,33 @@ func (c *Client) HybridSearch(ctx context.Context, queryEmbedding string, keywor
 	return rowsToMapSlice(ctx, rows)
 }
 
-/* CreateVectorIndex creates HNSW or IVF index on a vector column */
+/* CreateVectorIndex creates HNSW or IVF index on a vector column.
+ * Set options["concurrently"] to build without blocking writes. */
 func (c *Client) CreateVectorIndex(ctx context.Context, indexName string, tableName string, columnName string, indexType string, options map[string]interface{}) error {
+	concurrently := ""
+	if options != nil {
+		if cVal, ok := options["concurrently"].(bool); ok && cVal {
+			concurrently = "CONCURRENTLY "
+		}
+	}
+
+	// IF NOT EXISTS would skip an invalid index left behind by an interrupted concurrent build
+	if concurrently != "" {
+		if err := c.dropInvalidIndex(ctx, tableName, indexName); err != nil {
+			return err
+		}
+	}
+
 	var query string
 	if indexType == "hnsw" {
-		m := 16
-		efConstruction := 64
-		if options != nil {
-			if mVal, ok := options["m"].(float64); ok {
-				m = int(mVal)
-			}
-			if efVal, ok := options["ef_construction"].(float64); ok {
-				efConstruction = int(efVal)
-			}
-		}
-		query = fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s USING hnsw (%s vector_cosine_ops) WITH (m = %d, ef_construction = %d)`,
-			indexName, tableName, columnName, m, efConstruction)
+		m := intOption(options, "m", 16)
+		efConstruction := intOption(options, "ef_construction", 64)
+		query = fmt.Sprintf(`CREATE INDEX %sIF NOT EXISTS %s ON %s USING hnsw (%s vector_cosine_ops) WITH (m = %d, ef_construction = %d)`,
+			concurrently, indexName, tableName, columnName, m, efConstruction)
 	} else if indexType == "ivf" {
-		lists := 100
-		if options != nil {
-			if lVal, ok := options["lists"].(float64); ok {
-				lists = int(lVal)
-			}
-		}
-		query = fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s USING ivfflat (%s vector_cosine_ops) WITH (lists = %d)`,
-			indexName, tableName, columnName, lists)
+		lists := intOption(options, "lists", 100)
+		query = fmt.Sprintf(`CREATE INDEX %sIF NOT EXISTS %s ON %s USING ivfflat (%s vector_cosine_ops) WITH (lists = %d)`,
+			concurrently, indexName, tableName, columnName, lists)
 	} else {
 		return fmt.Errorf("unsupported index type: %s (supported: hnsw, ivf)", indexType)
 	}
@@ -329,6 +332,97 @@ func (c *Client) CreateVectorIndex(ctx context.Context, indexName string, tableN
 	return nil
 }
 
+/* RebuildVectorIndex replaces a vector index with new parameters without blocking reads or writes.
+ * The replacement is built concurrently under a temporary name, swapped in by renaming both indexes in one
+ * transaction so the name always refers to a valid index, and the previous index is dropped afterwards. */
+func (c *Client) RebuildVectorIndex(ctx context.Context, indexName string, tableName string, columnName string, indexType string, options map[string]interface{}) error {
+	schema := indexSchema(tableName)
+	tempName := indexName + "_rebuild"
+	oldName := indexName + "_old"
+
+	// Clean up indexes left behind by an interrupted rebuild
+	for _, name := range []string{tempName, oldName} {
+		if _, err := c.pool.Exec(ctx, fmt.Sprintf(`DROP INDEX CONCURRENTLY IF EXISTS %s.%s`, schema, name)); err != nil {
+			return fmt.Errorf("failed to drop stale index %s: %w", name, err)
+		}
+	}
+
+	buildOptions := make(map[string]interface{}, len(options)+1)
+	for k, v := range options {
+		buildOptions[k] = v
+	}
+	buildOptions["concurrently"] = true
+	if err := c.CreateVectorIndex(ctx, tempName, tableName, columnName, indexType, buildOptions); err != nil {
+		return err
+	}
+
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin index swap: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`ALTER INDEX IF EXISTS %s.%s RENAME TO %s`, schema, indexName, oldName)); err != nil {
+		return fmt.Errorf("failed to rename previous vector index: %w", err)
+	}
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`ALTER INDEX %s.%s RENAME TO %s`, schema, tempName, indexName)); err != nil {
+		return fmt.Errorf("failed to rename rebuilt vector index: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to swap vector index: %w", err)
+	}
+
+	if _, err := c.pool.Exec(ctx, fmt.Sprintf(`DROP INDEX CONCURRENTLY IF EXISTS %s.%s`, schema, oldName)); err != nil {
+		return fmt.Errorf("failed to drop previous vector index: %w", err)
+	}
+	return nil
+}
+
+/* dropInvalidIndex drops an index that an interrupted concurrent build left marked invalid */
+func (c *Client) dropInvalidIndex(ctx context.Context, tableName string, indexName string) error {
+	schema := indexSchema(tableName)
+	var invalid bool
+	err := c.pool.QueryRow(ctx, `
+		SELECT NOT x.indisvalid
+		FROM pg_index x
+		JOIN pg_class i ON i.oid = x.indexrelid
+		JOIN pg_namespace n ON n.oid = i.relnamespace
+		WHERE n.nspname = $1 AND i.relname = $2`, schema, indexName).Scan(&invalid)
+	if err == pgx.ErrNoRows || (err == nil && !invalid) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check index validity: %w", err)
+	}
+	if _, err := c.pool.Exec(ctx, fmt.Sprintf(`DROP INDEX CONCURRENTLY IF EXISTS %s.%s`, schema, indexName)); err != nil {
+		return fmt.Errorf("failed to drop invalid index: %w", err)
+	}
+	return nil
+}
+
+/* indexSchema returns the schema of a possibly schema-qualified table name */
+func indexSchema(tableName string) string {
+	if idx := strings.LastIndex(tableName, "."); idx >= 0 {
+		return tableName[:idx]
+	}
+	return "public"
+}
+
+/* intOption reads an integer option that may have been decoded from JSON as float64 */
+func intOption(options map[string]interface{}, key string, defaultValue int) int {
+	if options == nil {
+		return defaultValue
+	}
+	switch v := options[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return defaultValue
+}
+
 // ============================================================================
 // ML Operations
 // ============================================================================
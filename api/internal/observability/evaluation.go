@@ -0,0 +1,1005 @@
+package observability
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/mcp"
+	"github.com/neurondb/NeuronIP/api/internal/neurondb"
+)
+
+/* DefaultRegressionThreshold is the allowed drop for a metric when a golden set has no thresholds configured */
+const DefaultRegressionThreshold = 0.02
+
+/* ErrInvalidBaseline is returned for baseline runs that cannot gate a regression check */
+var ErrInvalidBaseline = errors.New("invalid baseline run")
+
+/* IsInvalidBaseline reports whether err means the baseline run cannot be compared against */
+func IsInvalidBaseline(err error) bool {
+	return errors.Is(err, ErrInvalidBaseline)
+}
+
+/* defaultEvaluationModel is the embedding model used when no pipeline is selected */
+const defaultEvaluationModel = "sentence-transformers/all-MiniLM-L6-v2"
+
+/* RetrievalEvaluationService replays golden query sets against search configurations */
+type RetrievalEvaluationService struct {
+	pool           *pgxpool.Pool
+	neurondbClient *neurondb.Client
+	mcpClient      *mcp.Client
+}
+
+/* NewRetrievalEvaluationService creates a new retrieval evaluation service */
+func NewRetrievalEvaluationService(pool *pgxpool.Pool, neurondbClient *neurondb.Client, mcpClient *mcp.Client) *RetrievalEvaluationService {
+	return &RetrievalEvaluationService{
+		pool:           pool,
+		neurondbClient: neurondbClient,
+		mcpClient:      mcpClient,
+	}
+}
+
+/* GoldenQuerySet represents a named set of judged queries */
+type GoldenQuerySet struct {
+	ID                   uuid.UUID          `json:"id"`
+	Name                 string             `json:"name"`
+	Description          *string            `json:"description,omitempty"`
+	CollectionID         *uuid.UUID         `json:"collection_id,omitempty"`
+	BaselineRunID        *uuid.UUID         `json:"baseline_run_id,omitempty"`
+	RegressionThresholds map[string]float64 `json:"regression_thresholds,omitempty"` // metric -> max allowed drop
+	QueryCount           int                `json:"query_count"`
+	CreatedBy            *string            `json:"created_by,omitempty"`
+	CreatedAt            time.Time          `json:"created_at"`
+	UpdatedAt            time.Time          `json:"updated_at"`
+}
+
+/* RelevanceJudgment grades how relevant a document or chunk is for a query */
+type RelevanceJudgment struct {
+	TargetID   uuid.UUID `json:"target_id"`
+	TargetType string    `json:"target_type"` // document, chunk
+	Grade      int       `json:"grade"`       // 0 = not relevant, higher = more relevant
+}
+
+/* GoldenQuery represents a query with its relevance judgments */
+type GoldenQuery struct {
+	ID        uuid.UUID              `json:"id"`
+	SetID     uuid.UUID              `json:"set_id"`
+	QueryText string                 `json:"query_text"`
+	Judgments []RelevanceJudgment    `json:"judgments"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+/* ChunkingStrategy selects embeddings produced with a given chunking configuration */
+type ChunkingStrategy struct {
+	ChunkSize    int `json:"chunk_size"`
+	ChunkOverlap int `json:"chunk_overlap"`
+}
+
+/* EvaluationConfig describes the search configuration a golden set is replayed against */
+type EvaluationConfig struct {
+	DistanceMetric  string            `json:"distance_metric,omitempty"` // cosine (default), l2, inner_product
+	PipelineID      *uuid.UUID        `json:"pipeline_id,omitempty"`
+	PipelineVersion string            `json:"pipeline_version,omitempty"`
+	Chunking        *ChunkingStrategy `json:"chunking,omitempty"`
+	Reranker        string            `json:"reranker,omitempty"` // "", cross_encoder, llm, cohere, ensemble
+	KValues         []int             `json:"k_values,omitempty"`
+}
+
+/* EvaluationRun represents one replay of a golden set */
+type EvaluationRun struct {
+	ID               uuid.UUID          `json:"id"`
+	SetID            uuid.UUID          `json:"set_id"`
+	Config           EvaluationConfig   `json:"config"`
+	Status           string             `json:"status"` // running, completed, failed
+	Metrics          map[string]float64 `json:"metrics,omitempty"`
+	BaselineRunID    *uuid.UUID         `json:"baseline_run_id,omitempty"`
+	Comparison       *RunComparison     `json:"comparison,omitempty"`
+	Passed           *bool              `json:"passed,omitempty"`
+	QueriesEvaluated int                `json:"queries_evaluated"`
+	ErrorMessage     *string            `json:"error_message,omitempty"`
+	TriggeredBy      *string            `json:"triggered_by,omitempty"`
+	StartedAt        time.Time          `json:"started_at"`
+	CompletedAt      *time.Time         `json:"completed_at,omitempty"`
+}
+
+/* QueryEvaluationResult holds the ranking and metrics for one golden query in a run */
+type QueryEvaluationResult struct {
+	QueryID      uuid.UUID          `json:"query_id"`
+	QueryText    string             `json:"query_text"`
+	RetrievedIDs []string           `json:"retrieved_ids"`
+	Metrics      map[string]float64 `json:"metrics"`
+	LatencyMs    int64              `json:"latency_ms"`
+	ErrorMessage *string            `json:"error_message,omitempty"`
+}
+
+/* MetricRegression describes a metric that dropped more than its threshold */
+type MetricRegression struct {
+	Metric    string  `json:"metric"`
+	Baseline  float64 `json:"baseline"`
+	Current   float64 `json:"current"`
+	Drop      float64 `json:"drop"`
+	Threshold float64 `json:"threshold"`
+	Missing   bool    `json:"missing,omitempty"` // The candidate run did not report the metric
+}
+
+/* RunComparison compares an evaluation run against a baseline run */
+type RunComparison struct {
+	BaselineRunID uuid.UUID          `json:"baseline_run_id"`
+	Deltas        map[string]float64 `json:"deltas"` // current - baseline
+	Regressions   []MetricRegression `json:"regressions"`
+	Passed        bool               `json:"passed"`
+}
+
+/* RunEvaluationRequest represents a request to evaluate a golden set */
+type RunEvaluationRequest struct {
+	SetID         uuid.UUID          `json:"set_id"`
+	Config        EvaluationConfig   `json:"config"`
+	BaselineRunID *uuid.UUID         `json:"baseline_run_id,omitempty"` // defaults to the set's baseline
+	Thresholds    map[string]float64 `json:"thresholds,omitempty"`      // overrides the set's thresholds
+	TriggeredBy   *string            `json:"triggered_by,omitempty"`
+}
+
+/* retrievedItem is one ranked hit from a replayed search */
+type retrievedItem struct {
+	ChunkID    uuid.UUID
+	DocumentID uuid.UUID
+	Content    string
+	Score      float64
+}
+
+/* CreateGoldenQuerySet creates a new golden query set */
+func (s *RetrievalEvaluationService) CreateGoldenQuerySet(ctx context.Context, set GoldenQuerySet) (*GoldenQuerySet, error) {
+	if strings.TrimSpace(set.Name) == "" {
+		return nil, fmt.Errorf("golden query set name is required")
+	}
+
+	set.ID = uuid.New()
+	thresholdsJSON, _ := json.Marshal(set.RegressionThresholds)
+
+	query := `
+		INSERT INTO neuronip.golden_query_sets
+		(id, name, description, collection_id, regression_thresholds, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		RETURNING created_at, updated_at`
+
+	err := s.pool.QueryRow(ctx, query, set.ID, set.Name, set.Description, set.CollectionID,
+		thresholdsJSON, set.CreatedBy).Scan(&set.CreatedAt, &set.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create golden query set: %w", err)
+	}
+
+	return &set, nil
+}
+
+/* GetGoldenQuerySet retrieves a golden query set */
+func (s *RetrievalEvaluationService) GetGoldenQuerySet(ctx context.Context, setID uuid.UUID) (*GoldenQuerySet, error) {
+	query := `
+		SELECT s.id, s.name, s.description, s.collection_id, s.baseline_run_id,
+		       s.regression_thresholds, s.created_by, s.created_at, s.updated_at,
+		       (SELECT COUNT(*) FROM neuronip.golden_queries q WHERE q.set_id = s.id)
+		FROM neuronip.golden_query_sets s
+		WHERE s.id = $1`
+
+	set, err := scanGoldenQuerySet(s.pool.QueryRow(ctx, query, setID))
+	if err != nil {
+		return nil, fmt.Errorf("golden query set not found: %w", err)
+	}
+	return set, nil
+}
+
+/* ListGoldenQuerySets lists golden query sets */
+func (s *RetrievalEvaluationService) ListGoldenQuerySets(ctx context.Context, limit int) ([]GoldenQuerySet, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	query := `
+		SELECT s.id, s.name, s.description, s.collection_id, s.baseline_run_id,
+		       s.regression_thresholds, s.created_by, s.created_at, s.updated_at,
+		       (SELECT COUNT(*) FROM neuronip.golden_queries q WHERE q.set_id = s.id)
+		FROM neuronip.golden_query_sets s
+		ORDER BY s.created_at DESC
+		LIMIT $1`
+
+	rows, err := s.pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list golden query sets: %w", err)
+	}
+	defer rows.Close()
+
+	var sets []GoldenQuerySet
+	for rows.Next() {
+		set, err := scanGoldenQuerySet(rows)
+		if err != nil {
+			continue
+		}
+		sets = append(sets, *set)
+	}
+
+	return sets, nil
+}
+
+/* scanGoldenQuerySet scans a golden query set row */
+func scanGoldenQuerySet(row pgx.Row) (*GoldenQuerySet, error) {
+	var set GoldenQuerySet
+	var collectionIDVal, baselineRunIDVal sql.NullString
+	var thresholdsJSON json.RawMessage
+
+	err := row.Scan(&set.ID, &set.Name, &set.Description, &collectionIDVal, &baselineRunIDVal,
+		&thresholdsJSON, &set.CreatedBy, &set.CreatedAt, &set.UpdatedAt, &set.QueryCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if collectionIDVal.Valid {
+		cID, _ := uuid.Parse(collectionIDVal.String)
+		set.CollectionID = &cID
+	}
+	if baselineRunIDVal.Valid {
+		bID, _ := uuid.Parse(baselineRunIDVal.String)
+		set.BaselineRunID = &bID
+	}
+	if thresholdsJSON != nil {
+		json.Unmarshal(thresholdsJSON, &set.RegressionThresholds)
+	}
+
+	return &set, nil
+}
+
+/* AddGoldenQuery adds a judged query to a golden set */
+func (s *RetrievalEvaluationService) AddGoldenQuery(ctx context.Context, setID uuid.UUID, q GoldenQuery) (*GoldenQuery, error) {
+	if strings.TrimSpace(q.QueryText) == "" {
+		return nil, fmt.Errorf("query text is required")
+	}
+	if len(q.Judgments) == 0 {
+		return nil, fmt.Errorf("at least one relevance judgment is required")
+	}
+	for i := range q.Judgments {
+		if q.Judgments[i].TargetType == "" {
+			q.Judgments[i].TargetType = "document"
+		}
+		if q.Judgments[i].TargetType != "document" && q.Judgments[i].TargetType != "chunk" {
+			return nil, fmt.Errorf("invalid judgment target type: %s", q.Judgments[i].TargetType)
+		}
+		if q.Judgments[i].Grade < 0 {
+			return nil, fmt.Errorf("relevance grade must not be negative")
+		}
+	}
+
+	q.ID = uuid.New()
+	q.SetID = setID
+	judgmentsJSON, _ := json.Marshal(q.Judgments)
+	metadataJSON, _ := json.Marshal(q.Metadata)
+
+	query := `
+		INSERT INTO neuronip.golden_queries (id, set_id, query_text, judgments, metadata, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING created_at`
+
+	err := s.pool.QueryRow(ctx, query, q.ID, setID, q.QueryText, judgmentsJSON, metadataJSON).Scan(&q.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add golden query: %w", err)
+	}
+
+	return &q, nil
+}
+
+/* ListGoldenQueries lists the queries of a golden set */
+func (s *RetrievalEvaluationService) ListGoldenQueries(ctx context.Context, setID uuid.UUID) ([]GoldenQuery, error) {
+	query := `
+		SELECT id, set_id, query_text, judgments, metadata, created_at
+		FROM neuronip.golden_queries
+		WHERE set_id = $1
+		ORDER BY created_at`
+
+	rows, err := s.pool.Query(ctx, query, setID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list golden queries: %w", err)
+	}
+	defer rows.Close()
+
+	var queries []GoldenQuery
+	for rows.Next() {
+		var q GoldenQuery
+		var judgmentsJSON, metadataJSON json.RawMessage
+		if err := rows.Scan(&q.ID, &q.SetID, &q.QueryText, &judgmentsJSON, &metadataJSON, &q.CreatedAt); err != nil {
+			continue
+		}
+		if judgmentsJSON != nil {
+			json.Unmarshal(judgmentsJSON, &q.Judgments)
+		}
+		if metadataJSON != nil {
+			json.Unmarshal(metadataJSON, &q.Metadata)
+		}
+		queries = append(queries, q)
+	}
+
+	return queries, nil
+}
+
+/* DeleteGoldenQuery removes a query from a golden set */
+func (s *RetrievalEvaluationService) DeleteGoldenQuery(ctx context.Context, setID uuid.UUID, queryID uuid.UUID) error {
+	result, err := s.pool.Exec(ctx, `DELETE FROM neuronip.golden_queries WHERE id = $1 AND set_id = $2`, queryID, setID)
+	if err != nil {
+		return fmt.Errorf("failed to delete golden query: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("golden query not found: %s", queryID)
+	}
+	return nil
+}
+
+/* RunEvaluation replays a golden set against a search configuration and compares it with a baseline */
+func (s *RetrievalEvaluationService) RunEvaluation(ctx context.Context, req RunEvaluationRequest) (*EvaluationRun, error) {
+	set, err := s.GetGoldenQuerySet(ctx, req.SetID)
+	if err != nil {
+		return nil, err
+	}
+
+	queries, err := s.ListGoldenQueries(ctx, req.SetID)
+	if err != nil {
+		return nil, err
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("golden query set %s has no queries", req.SetID)
+	}
+
+	config := normalizeEvaluationConfig(req.Config)
+	baselineRunID := req.BaselineRunID
+	if baselineRunID == nil {
+		baselineRunID = set.BaselineRunID
+	}
+
+	// Check the baseline before running so a regression gate never compares against nothing
+	var baseline *EvaluationRun
+	if baselineRunID != nil {
+		baseline, err = s.GetEvaluationRun(ctx, *baselineRunID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBaseline, *baselineRunID, err)
+		}
+		if err := validateBaseline(baseline, req.SetID); err != nil {
+			return nil, err
+		}
+	}
+
+	run := &EvaluationRun{
+		ID:            uuid.New(),
+		SetID:         req.SetID,
+		Config:        config,
+		Status:        "running",
+		BaselineRunID: baselineRunID,
+		TriggeredBy:   req.TriggeredBy,
+		StartedAt:     time.Now(),
+	}
+
+	configJSON, _ := json.Marshal(config)
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO neuronip.retrieval_evaluation_runs
+		(id, set_id, config, status, baseline_run_id, triggered_by, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		run.ID, run.SetID, configJSON, run.Status, baselineRunID, req.TriggeredBy, run.StartedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create evaluation run: %w", err)
+	}
+
+	embeddingModel, err := s.resolveEmbeddingModel(ctx, config)
+	if err != nil {
+		return s.failRun(ctx, run, err)
+	}
+
+	maxK := config.KValues[len(config.KValues)-1]
+	totals := make(map[string]float64)
+	evaluated := 0
+
+	for _, q := range queries {
+		start := time.Now()
+		items, err := s.retrieve(ctx, q.QueryText, set.CollectionID, config, embeddingModel, maxK)
+		latencyMs := time.Since(start).Milliseconds()
+
+		result := QueryEvaluationResult{
+			QueryID:   q.ID,
+			QueryText: q.QueryText,
+			LatencyMs: latencyMs,
+		}
+		if err != nil {
+			msg := err.Error()
+			result.ErrorMessage = &msg
+			result.Metrics = map[string]float64{}
+		} else {
+			ranked, grades := rankForJudgments(items, q.Judgments)
+			result.RetrievedIDs = ranked
+			result.Metrics = computeRankingMetrics(ranked, grades, config.KValues)
+		}
+
+		// Failed queries count as zero on every metric so a broken config cannot look better
+		for _, name := range metricNames(config.KValues) {
+			totals[name] += result.Metrics[name]
+		}
+		evaluated++
+
+		retrievedJSON, _ := json.Marshal(result.RetrievedIDs)
+		metricsJSON, _ := json.Marshal(result.Metrics)
+		s.pool.Exec(ctx, `
+			INSERT INTO neuronip.retrieval_evaluation_results
+			(run_id, query_id, retrieved_ids, metrics, latency_ms, error_message, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NOW())`,
+			run.ID, q.ID, retrievedJSON, metricsJSON, latencyMs, result.ErrorMessage)
+	}
+
+	run.Metrics = make(map[string]float64, len(totals))
+	for name, total := range totals {
+		run.Metrics[name] = total / float64(evaluated)
+	}
+	run.QueriesEvaluated = evaluated
+
+	if baseline != nil {
+		thresholds := req.Thresholds
+		if len(thresholds) == 0 {
+			thresholds = set.RegressionThresholds
+		}
+		comparison := compareRunMetrics(*baselineRunID, baseline.Metrics, run.Metrics, thresholds)
+		run.Comparison = &comparison
+		run.Passed = &comparison.Passed
+	}
+
+	now := time.Now()
+	run.Status = "completed"
+	run.CompletedAt = &now
+
+	metricsJSON, _ := json.Marshal(run.Metrics)
+	var comparisonJSON []byte
+	if run.Comparison != nil {
+		comparisonJSON, _ = json.Marshal(run.Comparison)
+	}
+	_, err = s.pool.Exec(ctx, `
+		UPDATE neuronip.retrieval_evaluation_runs
+		SET status = $1, metrics = $2, comparison = $3, passed = $4, queries_evaluated = $5, completed_at = $6
+		WHERE id = $7`,
+		run.Status, metricsJSON, comparisonJSON, run.Passed, run.QueriesEvaluated, now, run.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete evaluation run: %w", err)
+	}
+
+	return run, nil
+}
+
+/* failRun marks an evaluation run as failed */
+func (s *RetrievalEvaluationService) failRun(ctx context.Context, run *EvaluationRun, cause error) (*EvaluationRun, error) {
+	msg := cause.Error()
+	now := time.Now()
+	run.Status = "failed"
+	run.ErrorMessage = &msg
+	run.CompletedAt = &now
+	s.pool.Exec(ctx, `
+		UPDATE neuronip.retrieval_evaluation_runs
+		SET status = $1, error_message = $2, completed_at = $3
+		WHERE id = $4`, run.Status, msg, now, run.ID)
+	return nil, fmt.Errorf("evaluation run failed: %w", cause)
+}
+
+/* GetEvaluationRun retrieves an evaluation run */
+func (s *RetrievalEvaluationService) GetEvaluationRun(ctx context.Context, runID uuid.UUID) (*EvaluationRun, error) {
+	query := `
+		SELECT id, set_id, config, status, metrics, baseline_run_id, comparison, passed,
+		       queries_evaluated, error_message, triggered_by, started_at, completed_at
+		FROM neuronip.retrieval_evaluation_runs
+		WHERE id = $1`
+
+	run, err := scanEvaluationRun(s.pool.QueryRow(ctx, query, runID))
+	if err != nil {
+		return nil, fmt.Errorf("evaluation run not found: %w", err)
+	}
+	return run, nil
+}
+
+/* ListEvaluationRuns lists evaluation runs for a golden set */
+func (s *RetrievalEvaluationService) ListEvaluationRuns(ctx context.Context, setID uuid.UUID, limit int) ([]EvaluationRun, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := `
+		SELECT id, set_id, config, status, metrics, baseline_run_id, comparison, passed,
+		       queries_evaluated, error_message, triggered_by, started_at, completed_at
+		FROM neuronip.retrieval_evaluation_runs
+		WHERE set_id = $1
+		ORDER BY started_at DESC
+		LIMIT $2`
+
+	rows, err := s.pool.Query(ctx, query, setID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list evaluation runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []EvaluationRun
+	for rows.Next() {
+		run, err := scanEvaluationRun(rows)
+		if err != nil {
+			continue
+		}
+		runs = append(runs, *run)
+	}
+
+	return runs, nil
+}
+
+/* scanEvaluationRun scans an evaluation run row */
+func scanEvaluationRun(row pgx.Row) (*EvaluationRun, error) {
+	var run EvaluationRun
+	var configJSON, metricsJSON, comparisonJSON json.RawMessage
+	var baselineRunIDVal sql.NullString
+
+	err := row.Scan(&run.ID, &run.SetID, &configJSON, &run.Status, &metricsJSON, &baselineRunIDVal,
+		&comparisonJSON, &run.Passed, &run.QueriesEvaluated, &run.ErrorMessage, &run.TriggeredBy,
+		&run.StartedAt, &run.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if configJSON != nil {
+		json.Unmarshal(configJSON, &run.Config)
+	}
+	if metricsJSON != nil {
+		json.Unmarshal(metricsJSON, &run.Metrics)
+	}
+	if baselineRunIDVal.Valid {
+		bID, _ := uuid.Parse(baselineRunIDVal.String)
+		run.BaselineRunID = &bID
+	}
+	if comparisonJSON != nil {
+		var comparison RunComparison
+		if err := json.Unmarshal(comparisonJSON, &comparison); err == nil {
+			run.Comparison = &comparison
+		}
+	}
+
+	return &run, nil
+}
+
+/* GetEvaluationResults retrieves per-query results of an evaluation run */
+func (s *RetrievalEvaluationService) GetEvaluationResults(ctx context.Context, runID uuid.UUID) ([]QueryEvaluationResult, error) {
+	query := `
+		SELECT r.query_id, q.query_text, r.retrieved_ids, r.metrics, r.latency_ms, r.error_message
+		FROM neuronip.retrieval_evaluation_results r
+		JOIN neuronip.golden_queries q ON q.id = r.query_id
+		WHERE r.run_id = $1
+		ORDER BY r.created_at`
+
+	rows, err := s.pool.Query(ctx, query, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get evaluation results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []QueryEvaluationResult
+	for rows.Next() {
+		var result QueryEvaluationResult
+		var retrievedJSON, metricsJSON json.RawMessage
+		if err := rows.Scan(&result.QueryID, &result.QueryText, &retrievedJSON, &metricsJSON,
+			&result.LatencyMs, &result.ErrorMessage); err != nil {
+			continue
+		}
+		if retrievedJSON != nil {
+			json.Unmarshal(retrievedJSON, &result.RetrievedIDs)
+		}
+		if metricsJSON != nil {
+			json.Unmarshal(metricsJSON, &result.Metrics)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+/* SetBaselineRun promotes a completed run to be the baseline of its golden set */
+func (s *RetrievalEvaluationService) SetBaselineRun(ctx context.Context, setID uuid.UUID, runID uuid.UUID) error {
+	run, err := s.GetEvaluationRun(ctx, runID)
+	if err != nil {
+		return err
+	}
+	if err := validateBaseline(run, setID); err != nil {
+		return err
+	}
+
+	_, err = s.pool.Exec(ctx, `UPDATE neuronip.golden_query_sets SET baseline_run_id = $1 WHERE id = $2`, runID, setID)
+	if err != nil {
+		return fmt.Errorf("failed to set baseline run: %w", err)
+	}
+	return nil
+}
+
+/* CompareRuns compares two completed runs using the thresholds of the candidate's golden set */
+func (s *RetrievalEvaluationService) CompareRuns(ctx context.Context, baselineRunID uuid.UUID, candidateRunID uuid.UUID) (*RunComparison, error) {
+	baseline, err := s.GetEvaluationRun(ctx, baselineRunID)
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := s.GetEvaluationRun(ctx, candidateRunID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBaseline(baseline, candidate.SetID); err != nil {
+		return nil, err
+	}
+	set, err := s.GetGoldenQuerySet(ctx, candidate.SetID)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := compareRunMetrics(baselineRunID, baseline.Metrics, candidate.Metrics, set.RegressionThresholds)
+	return &comparison, nil
+}
+
+/* normalizeEvaluationConfig fills in defaults for an evaluation config */
+func normalizeEvaluationConfig(config EvaluationConfig) EvaluationConfig {
+	if config.DistanceMetric == "" {
+		config.DistanceMetric = "cosine"
+	}
+	ks := make([]int, 0, len(config.KValues))
+	seen := make(map[int]bool)
+	for _, k := range config.KValues {
+		if k > 0 && !seen[k] {
+			seen[k] = true
+			ks = append(ks, k)
+		}
+	}
+	if len(ks) == 0 {
+		ks = []int{1, 3, 5, 10}
+	}
+	sort.Ints(ks)
+	config.KValues = ks
+	return config
+}
+
+/* resolveEmbeddingModel returns the embedding model of the selected pipeline version */
+func (s *RetrievalEvaluationService) resolveEmbeddingModel(ctx context.Context, config EvaluationConfig) (string, error) {
+	if config.PipelineID == nil {
+		return defaultEvaluationModel, nil
+	}
+
+	query := `SELECT embedding_model FROM neuronip.pipelines WHERE id = $1`
+	args := []interface{}{*config.PipelineID}
+	if config.PipelineVersion != "" {
+		query += ` AND version = $2`
+		args = append(args, config.PipelineVersion)
+	}
+	query += ` ORDER BY created_at DESC LIMIT 1`
+
+	var model string
+	if err := s.pool.QueryRow(ctx, query, args...).Scan(&model); err != nil {
+		return "", fmt.Errorf("pipeline not found: %w", err)
+	}
+	return model, nil
+}
+
+/* retrieve runs one search against the configured metric, pipeline, chunking and reranker */
+func (s *RetrievalEvaluationService) retrieve(ctx context.Context, queryText string, collectionID *uuid.UUID, config EvaluationConfig, embeddingModel string, k int) ([]retrievedItem, error) {
+	if s.neurondbClient == nil {
+		return nil, fmt.Errorf("NeuronDB client not configured")
+	}
+
+	queryEmbedding, err := s.neurondbClient.GenerateEmbedding(ctx, queryText, embeddingModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+
+	var operator, scoreExpr string
+	switch config.DistanceMetric {
+	case "l2":
+		operator = "<->"
+		scoreExpr = "1.0 / (1.0 + (e.embedding <-> $1::vector))"
+	case "inner_product":
+		operator = "<#>"
+		scoreExpr = "-(e.embedding <#> $1::vector)"
+	default:
+		operator = "<=>"
+		scoreExpr = "1 - (e.embedding <=> $1::vector)"
+	}
+
+	candidates := k
+	if config.Reranker != "" {
+		candidates = k * 2
+	}
+
+	query := fmt.Sprintf(`
+		SELECT e.id, e.document_id, COALESCE(e.chunk_text, d.content), %s AS score
+		FROM neuronip.knowledge_embeddings e
+		JOIN neuronip.knowledge_documents d ON d.id = e.document_id
+		LEFT JOIN neuronip.pipelines p ON p.id = e.pipeline_id AND p.version = e.pipeline_version
+		WHERE e.embedding IS NOT NULL`, scoreExpr)
+	args := []interface{}{queryEmbedding}
+	argIndex := 2
+
+	if collectionID != nil {
+		query += fmt.Sprintf(" AND d.collection_id = $%d", argIndex)
+		args = append(args, *collectionID)
+		argIndex++
+	}
+	if config.PipelineID != nil {
+		query += fmt.Sprintf(" AND e.pipeline_id = $%d", argIndex)
+		args = append(args, *config.PipelineID)
+		argIndex++
+	}
+	if config.PipelineVersion != "" {
+		query += fmt.Sprintf(" AND e.pipeline_version = $%d", argIndex)
+		args = append(args, config.PipelineVersion)
+		argIndex++
+	}
+	if config.Chunking != nil {
+		query += fmt.Sprintf(" AND (p.chunking_config->>'ChunkSize')::int = $%d AND (p.chunking_config->>'ChunkOverlap')::int = $%d",
+			argIndex, argIndex+1)
+		args = append(args, config.Chunking.ChunkSize, config.Chunking.ChunkOverlap)
+		argIndex += 2
+	}
+
+	query += fmt.Sprintf(" ORDER BY e.embedding %s $1::vector LIMIT $%d", operator, argIndex)
+	args = append(args, candidates)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform vector search: %w", err)
+	}
+	defer rows.Close()
+
+	var items []retrievedItem
+	for rows.Next() {
+		var item retrievedItem
+		if err := rows.Scan(&item.ChunkID, &item.DocumentID, &item.Content, &item.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read search results: %w", err)
+	}
+
+	if config.Reranker != "" && len(items) > 0 {
+		items, err = s.rerank(ctx, queryText, items, config.Reranker, k)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(items) > k {
+		items = items[:k]
+	}
+	return items, nil
+}
+
+/* rerank reorders candidates with the configured MCP reranker */
+func (s *RetrievalEvaluationService) rerank(ctx context.Context, queryText string, items []retrievedItem, method string, k int) ([]retrievedItem, error) {
+	if s.mcpClient == nil {
+		return nil, fmt.Errorf("reranker %q requires the MCP client", method)
+	}
+
+	documents := make([]string, len(items))
+	for i, item := range items {
+		documents[i] = item.Content
+	}
+
+	var result map[string]interface{}
+	var err error
+	switch method {
+	case "cross_encoder":
+		result, err = s.mcpClient.RerankCrossEncoder(ctx, queryText, documents, k)
+	case "llm":
+		result, err = s.mcpClient.RerankLLM(ctx, queryText, documents, k, "")
+	case "cohere":
+		result, err = s.mcpClient.RerankCohere(ctx, queryText, documents, k)
+	case "ensemble":
+		result, err = s.mcpClient.RerankEnsemble(ctx, queryText, documents, k, []string{"cross_encoder", "llm"}, nil)
+	default:
+		return nil, fmt.Errorf("unsupported reranker: %s", method)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rerank results: %w", err)
+	}
+
+	rerankedDocs, ok := result["documents"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("reranker returned no documents")
+	}
+
+	used := make([]bool, len(items))
+	reranked := make([]retrievedItem, 0, len(rerankedDocs))
+	for _, doc := range rerankedDocs {
+		docMap, ok := doc.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// Prefer the original index; fall back to matching content
+		if idx, ok := docMap["index"].(float64); ok && int(idx) >= 0 && int(idx) < len(items) && !used[int(idx)] {
+			used[int(idx)] = true
+			reranked = append(reranked, items[int(idx)])
+			continue
+		}
+		content, _ := docMap["content"].(string)
+		for i, item := range items {
+			if !used[i] && item.Content == content {
+				used[i] = true
+				reranked = append(reranked, item)
+				break
+			}
+		}
+	}
+
+	return reranked, nil
+}
+
+/* rankForJudgments maps retrieved chunks onto the granularity used by the judgments */
+func rankForJudgments(items []retrievedItem, judgments []RelevanceJudgment) ([]string, map[string]int) {
+	grades := make(map[string]int, len(judgments))
+	for _, j := range judgments {
+		grades[j.TargetType+":"+j.TargetID.String()] = j.Grade
+	}
+
+	// A chunk judged on its own is matched as a chunk; otherwise the chunk stands for its document.
+	// Several chunks of one document only count once, at the rank of the best chunk.
+	ranked := make([]string, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		key := "chunk:" + item.ChunkID.String()
+		if _, ok := grades[key]; !ok {
+			key = "document:" + item.DocumentID.String()
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		ranked = append(ranked, key)
+	}
+
+	return ranked, grades
+}
+
+/* metricNames lists the metric keys produced for a set of cutoffs */
+func metricNames(ks []int) []string {
+	names := []string{"mrr"}
+	for _, k := range ks {
+		names = append(names,
+			fmt.Sprintf("recall@%d", k),
+			fmt.Sprintf("precision@%d", k),
+			fmt.Sprintf("ndcg@%d", k))
+	}
+	return names
+}
+
+/* computeRankingMetrics computes recall@k, precision@k, MRR and nDCG@k for one ranking */
+func computeRankingMetrics(ranked []string, grades map[string]int, ks []int) map[string]float64 {
+	metrics := make(map[string]float64)
+
+	relevantTotal := 0
+	var idealGrades []int
+	for _, grade := range grades {
+		if grade > 0 {
+			relevantTotal++
+			idealGrades = append(idealGrades, grade)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(idealGrades)))
+
+	metrics["mrr"] = 0
+	for i, key := range ranked {
+		if grades[key] > 0 {
+			metrics["mrr"] = 1.0 / float64(i+1)
+			break
+		}
+	}
+
+	for _, k := range ks {
+		hits := 0
+		dcg := 0.0
+		for i := 0; i < k && i < len(ranked); i++ {
+			grade := grades[ranked[i]]
+			if grade > 0 {
+				hits++
+				dcg += (math.Pow(2, float64(grade)) - 1) / math.Log2(float64(i+2))
+			}
+		}
+
+		idcg := 0.0
+		for i := 0; i < k && i < len(idealGrades); i++ {
+			idcg += (math.Pow(2, float64(idealGrades[i])) - 1) / math.Log2(float64(i+2))
+		}
+
+		recall := 0.0
+		if relevantTotal > 0 {
+			recall = float64(hits) / float64(relevantTotal)
+		}
+		ndcg := 0.0
+		if idcg > 0 {
+			ndcg = dcg / idcg
+		}
+
+		metrics[fmt.Sprintf("recall@%d", k)] = recall
+		metrics[fmt.Sprintf("precision@%d", k)] = float64(hits) / float64(k)
+		metrics[fmt.Sprintf("ndcg@%d", k)] = ndcg
+	}
+
+	return metrics
+}
+
+/* validateBaseline checks that a run can serve as the baseline for a golden set: it must belong to the set
+ * and have completed with metrics, or every comparison against it would pass */
+func validateBaseline(baseline *EvaluationRun, setID uuid.UUID) error {
+	if baseline.SetID != setID {
+		return fmt.Errorf("%w: run %s does not belong to golden query set %s", ErrInvalidBaseline, baseline.ID, setID)
+	}
+	if baseline.Status != "completed" {
+		return fmt.Errorf("%w: run %s is %s, only completed runs can be used as a baseline", ErrInvalidBaseline, baseline.ID, baseline.Status)
+	}
+	if len(baseline.Metrics) == 0 {
+		return fmt.Errorf("%w: run %s has no metrics", ErrInvalidBaseline, baseline.ID)
+	}
+	return nil
+}
+
+/* compareRunMetrics compares candidate metrics with a baseline and applies regression thresholds */
+func compareRunMetrics(baselineRunID uuid.UUID, baseline map[string]float64, current map[string]float64, thresholds map[string]float64) RunComparison {
+	comparison := RunComparison{
+		BaselineRunID: baselineRunID,
+		Deltas:        make(map[string]float64),
+		Regressions:   []MetricRegression{},
+		Passed:        true,
+	}
+
+	names := make([]string, 0, len(baseline))
+	for name := range baseline {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		baselineValue := baseline[name]
+
+		// With explicit thresholds only the listed metrics gate; otherwise every metric uses the default
+		threshold, gated := DefaultRegressionThreshold, true
+		if len(thresholds) > 0 {
+			threshold, gated = thresholds[name]
+		}
+
+		currentValue, ok := current[name]
+		if !ok {
+			// A gated metric the candidate did not report, e.g. after changing k_values, cannot show it held up
+			if gated {
+				comparison.Regressions = append(comparison.Regressions, MetricRegression{
+					Metric:    name,
+					Baseline:  baselineValue,
+					Threshold: threshold,
+					Missing:   true,
+				})
+				comparison.Passed = false
+			}
+			continue
+		}
+		comparison.Deltas[name] = currentValue - baselineValue
+		if !gated {
+			continue
+		}
+
+		drop := baselineValue - currentValue
+		if drop > threshold {
+			comparison.Regressions = append(comparison.Regressions, MetricRegression{
+				Metric:    name,
+				Baseline:  baselineValue,
+				Current:   currentValue,
+				Drop:      drop,
+				Threshold: threshold,
+			})
+			comparison.Passed = false
+		}
+	}
+
+	return comparison
+}
@@ -0,0 +1,193 @@
+package observability
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+/* TestComputeRankingMetrics checks recall@k, precision@k, MRR and nDCG@k against hand-computed rankings */
+func TestComputeRankingMetrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranked []string
+		grades map[string]int
+		ks     []int
+		want   map[string]float64
+	}{
+		{
+			name:   "perfect ranking",
+			ranked: []string{"a", "b", "c"},
+			grades: map[string]int{"a": 1, "b": 1},
+			ks:     []int{2},
+			want:   map[string]float64{"mrr": 1, "recall@2": 1, "precision@2": 1, "ndcg@2": 1},
+		},
+		{
+			name:   "first relevant at rank three",
+			ranked: []string{"x", "y", "a", "z"},
+			grades: map[string]int{"a": 1},
+			ks:     []int{1, 3},
+			want: map[string]float64{
+				"mrr":         1.0 / 3,
+				"recall@1":    0,
+				"precision@1": 0,
+				"ndcg@1":      0,
+				"recall@3":    1,
+				"precision@3": 1.0 / 3,
+				"ndcg@3":      1 / math.Log2(4),
+			},
+		},
+		{
+			name:   "graded relevance out of order",
+			ranked: []string{"b", "a"},
+			grades: map[string]int{"a": 2, "b": 1},
+			ks:     []int{2},
+			want: map[string]float64{
+				"mrr":         1,
+				"recall@2":    1,
+				"precision@2": 1,
+				"ndcg@2":      (1 + 3/math.Log2(3)) / (3 + 1/math.Log2(3)),
+			},
+		},
+		{
+			name:   "relevant document not retrieved",
+			ranked: []string{"a", "x"},
+			grades: map[string]int{"a": 1, "b": 1, "x": 0},
+			ks:     []int{2},
+			want:   map[string]float64{"mrr": 1, "recall@2": 0.5, "precision@2": 0.5, "ndcg@2": 1 / (1 + 1/math.Log2(3))},
+		},
+		{
+			name:   "nothing retrieved",
+			ranked: nil,
+			grades: map[string]int{"a": 1},
+			ks:     []int{5},
+			want:   map[string]float64{"mrr": 0, "recall@5": 0, "precision@5": 0, "ndcg@5": 0},
+		},
+		{
+			name:   "no relevant judgments",
+			ranked: []string{"a"},
+			grades: map[string]int{"a": 0},
+			ks:     []int{1},
+			want:   map[string]float64{"mrr": 0, "recall@1": 0, "precision@1": 0, "ndcg@1": 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeRankingMetrics(tt.ranked, tt.grades, tt.ks)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected metrics %v, got %v", tt.want, got)
+			}
+			for name, want := range tt.want {
+				if math.Abs(got[name]-want) > 1e-9 {
+					t.Errorf("%s: expected %v, got %v", name, want, got[name])
+				}
+			}
+		})
+	}
+}
+
+/* TestCompareRunMetrics checks that drops beyond the threshold fail the comparison and only gated metrics count */
+func TestCompareRunMetrics(t *testing.T) {
+	baseline := map[string]float64{"mrr": 0.8, "recall@10": 0.9}
+
+	tests := []struct {
+		name        string
+		current     map[string]float64
+		thresholds  map[string]float64
+		passed      bool
+		regressions []string
+	}{
+		{name: "unchanged", current: map[string]float64{"mrr": 0.8, "recall@10": 0.9}, passed: true},
+		{name: "improved", current: map[string]float64{"mrr": 0.9, "recall@10": 0.95}, passed: true},
+		{name: "drop within default threshold", current: map[string]float64{"mrr": 0.79, "recall@10": 0.9}, passed: true},
+		{name: "drop beyond default threshold", current: map[string]float64{"mrr": 0.7, "recall@10": 0.9}, regressions: []string{"mrr"}},
+		{
+			name:       "ungated metric drops",
+			current:    map[string]float64{"mrr": 0.5, "recall@10": 0.9},
+			thresholds: map[string]float64{"recall@10": 0.01},
+			passed:     true,
+		},
+		{
+			name:        "gated metric drops",
+			current:     map[string]float64{"mrr": 0.8, "recall@10": 0.85},
+			thresholds:  map[string]float64{"recall@10": 0.01},
+			regressions: []string{"recall@10"},
+		},
+		{
+			name:        "every metric drops",
+			current:     map[string]float64{"mrr": 0.1, "recall@10": 0.1},
+			regressions: []string{"mrr", "recall@10"},
+		},
+		{name: "metric missing", current: map[string]float64{"mrr": 0.8, "recall@5": 0.95}, regressions: []string{"recall@10"}},
+		{
+			name:        "gated metric missing",
+			current:     map[string]float64{"mrr": 0.8},
+			thresholds:  map[string]float64{"recall@10": 0.01},
+			regressions: []string{"recall@10"},
+		},
+		{
+			name:       "ungated metric missing",
+			current:    map[string]float64{"recall@10": 0.9},
+			thresholds: map[string]float64{"recall@10": 0.01},
+			passed:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comparison := compareRunMetrics(uuid.New(), baseline, tt.current, tt.thresholds)
+			if comparison.Passed != tt.passed {
+				t.Fatalf("expected passed=%v, got %v", tt.passed, comparison.Passed)
+			}
+			if len(comparison.Regressions) != len(tt.regressions) {
+				t.Fatalf("expected regressions %v, got %v", tt.regressions, comparison.Regressions)
+			}
+			for i, metric := range tt.regressions {
+				if comparison.Regressions[i].Metric != metric {
+					t.Errorf("expected regression %d on %s, got %s", i, metric, comparison.Regressions[i].Metric)
+				}
+			}
+			for metric := range baseline {
+				_, reported := tt.current[metric]
+				if _, ok := comparison.Deltas[metric]; ok != reported {
+					t.Errorf("expected a delta for every baseline metric the candidate reported, got %v", comparison.Deltas)
+				}
+			}
+			for _, regression := range comparison.Regressions {
+				if _, reported := tt.current[regression.Metric]; regression.Missing == reported {
+					t.Errorf("expected missing=%v for %s", !reported, regression.Metric)
+				}
+			}
+		})
+	}
+}
+
+/* TestValidateBaseline checks that only completed runs of the same golden set with metrics can be baselines */
+func TestValidateBaseline(t *testing.T) {
+	setID := uuid.New()
+	metrics := map[string]float64{"mrr": 0.8}
+
+	tests := []struct {
+		name    string
+		run     EvaluationRun
+		allowed bool
+	}{
+		{name: "completed run of the set", run: EvaluationRun{SetID: setID, Status: "completed", Metrics: metrics}, allowed: true},
+		{name: "run of another set", run: EvaluationRun{SetID: uuid.New(), Status: "completed", Metrics: metrics}},
+		{name: "failed run", run: EvaluationRun{SetID: setID, Status: "failed"}},
+		{name: "running run", run: EvaluationRun{SetID: setID, Status: "running"}},
+		{name: "completed run without metrics", run: EvaluationRun{SetID: setID, Status: "completed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run.ID = uuid.New()
+			err := validateBaseline(&tt.run, setID)
+			if tt.allowed && err != nil {
+				t.Fatalf("expected baseline to be accepted, got %v", err)
+			}
+			if !tt.allowed && !IsInvalidBaseline(err) {
+				t.Fatalf("expected baseline to be rejected, got %v", err)
+			}
+		})
+	}
+}
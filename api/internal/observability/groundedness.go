@@ -0,0 +1,509 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/config"
+	"github.com/neurondb/NeuronIP/api/internal/mcp"
+	"github.com/neurondb/NeuronIP/api/internal/neurondb"
+)
+
+/* Groundedness actions applied to answers below the threshold */
+const (
+	GroundednessActionNone     = "none"
+	GroundednessActionAnnotate = "annotate"
+	GroundednessActionBlock    = "block"
+)
+
+/* Claim scoring methods, in order of preference */
+const (
+	ClaimMethodEntailment = "entailment"
+	ClaimMethodSimilarity = "similarity"
+	ClaimMethodLexical    = "lexical"
+)
+
+/* Per-method score a claim needs against its best source to count as supported */
+var claimSupportThresholds = map[string]float64{
+	ClaimMethodEntailment: 0.5,
+	ClaimMethodSimilarity: 0.75,
+	ClaimMethodLexical:    0.6,
+}
+
+/* BlockedAnswerMessage replaces answers blocked for insufficient grounding */
+const BlockedAnswerMessage = "I could not verify this answer against the available sources, so it has been withheld."
+
+/* maxSourceChars bounds the source text embedded or sent for entailment */
+const maxSourceChars = 4000
+
+/* nonClaimPrefixes mark sentences that carry no verifiable facts */
+var nonClaimPrefixes = []string{
+	"thank", "thanks", "hope this helps", "let me know", "please let", "please feel", "feel free",
+	"i'm sorry", "i am sorry", "sorry", "hi ", "hello", "dear ", "best regards", "regards", "sincerely",
+	"i hope", "happy to help", "if you have any",
+}
+
+/* GroundingSource is a passage an answer may draw on */
+type GroundingSource struct {
+	ID   string `json:"id,omitempty"`
+	Text string `json:"text"`
+}
+
+/* Claim is an atomic statement extracted from an answer; offsets are rune offsets into the answer */
+type Claim struct {
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+/* ClaimVerdict is the verification result of one claim */
+type ClaimVerdict struct {
+	Claim
+	Supported   bool    `json:"supported"`
+	Score       float64 `json:"score"`
+	Method      string  `json:"method"`
+	SourceIndex int     `json:"source_index"` // -1 when no source supports the claim
+	SourceID    string  `json:"source_id,omitempty"`
+	Evidence    string  `json:"evidence,omitempty"` // Best-matching source sentence
+}
+
+/* GroundednessReport is the verification result of an answer */
+type GroundednessReport struct {
+	Score             float64        `json:"score"` // Share of claims supported by the sources
+	Claims            []ClaimVerdict `json:"claims"`
+	UnsupportedClaims int            `json:"unsupported_claims"`
+	Threshold         float64        `json:"threshold"`
+	Action            string         `json:"action"` // Action taken: none, annotate or block
+	SignalID          *uuid.UUID     `json:"signal_id,omitempty"`
+	Error             string         `json:"error,omitempty"` // Set when the answer could not be verified
+}
+
+/* GroundednessService verifies generated answers claim by claim against their sources */
+type GroundednessService struct {
+	neurondbClient *neurondb.Client
+	mcpClient      *mcp.Client
+	hallucination  *HallucinationDetectionService
+	config         config.GroundednessConfig
+}
+
+/* NewGroundednessService creates a new groundedness verification service */
+func NewGroundednessService(pool *pgxpool.Pool, neurondbClient *neurondb.Client, mcpClient *mcp.Client, cfg config.GroundednessConfig) *GroundednessService {
+	if cfg.Action == "" {
+		cfg.Action = GroundednessActionNone
+	}
+	return &GroundednessService{
+		neurondbClient: neurondbClient,
+		mcpClient:      mcpClient,
+		hallucination:  NewHallucinationDetectionService(pool),
+		config:         cfg,
+	}
+}
+
+/* Enabled reports whether answers should be verified */
+func (s *GroundednessService) Enabled() bool {
+	return s != nil && s.config.Enabled
+}
+
+/* Blocking reports whether answers below the threshold are withheld, so they must be verified before release */
+func (s *GroundednessService) Blocking() bool {
+	return s.Enabled() && s.config.Action == GroundednessActionBlock
+}
+
+/* FailedReport is the report of an answer that could not be verified. It scores zero, so Enforce
+ * withholds the answer in block mode instead of releasing it unverified. */
+func (s *GroundednessService) FailedReport(err error) *GroundednessReport {
+	return &GroundednessReport{Score: 0, Threshold: s.config.Threshold, Action: GroundednessActionNone, Error: err.Error()}
+}
+
+/* SplitClaims splits an answer into atomic claims: sentences, further split at clause boundaries
+ * joining independent statements. Greetings, questions and pleasantries are skipped. */
+func SplitClaims(answer string) []Claim {
+	runes := []rune(answer)
+	var claims []Claim
+
+	addClaim := func(start, end int) {
+		for start < end && (unicode.IsSpace(runes[start]) || runes[start] == '-' || runes[start] == '*') {
+			start++
+		}
+		for end > start && unicode.IsSpace(runes[end-1]) {
+			end--
+		}
+		if end <= start {
+			return
+		}
+		text := string(runes[start:end])
+		lower := strings.ToLower(text)
+		if strings.HasSuffix(text, "?") || len(ContentTerms(text)) < 3 {
+			return
+		}
+		for _, prefix := range nonClaimPrefixes {
+			if strings.HasPrefix(lower, prefix) {
+				return
+			}
+		}
+		claims = append(claims, Claim{Text: text, Start: start, End: end})
+	}
+
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		last := i+1 == len(runes)
+		sentenceEnd := runes[i] == '\n' || (strings.ContainsRune(".!?", runes[i]) && (last || unicode.IsSpace(runes[i+1])))
+		if !sentenceEnd && !last {
+			continue
+		}
+		for _, clause := range splitClauses(runes, start, i+1) {
+			addClaim(clause[0], clause[1])
+		}
+		start = i + 1
+	}
+	return claims
+}
+
+/* clauseSeparators join independent statements within a sentence */
+var clauseSeparators = []string{"; ", ", and ", ", but ", ", while "}
+
+/* splitClauses splits runes[start:end] at clause separators when both sides read as statements */
+func splitClauses(runes []rune, start, end int) [][2]int {
+	lower := []rune(strings.ToLower(string(runes[start:end])))
+	if len(lower) != end-start {
+		return [][2]int{{start, end}}
+	}
+
+	var clauses [][2]int
+	clauseStart := 0
+	for i := 0; i < len(lower); i++ {
+		for _, sep := range clauseSeparators {
+			sepRunes := []rune(sep)
+			if i+len(sepRunes) > len(lower) || string(lower[i:i+len(sepRunes)]) != sep {
+				continue
+			}
+			before := string(lower[clauseStart:i])
+			after := string(lower[i+len(sepRunes):])
+			if len(strings.Fields(before)) >= 4 && len(strings.Fields(after)) >= 4 {
+				clauses = append(clauses, [2]int{start + clauseStart, start + i})
+				clauseStart = i + len(sepRunes)
+			}
+			break
+		}
+	}
+	return append(clauses, [2]int{start + clauseStart, end})
+}
+
+/* Verify checks each claim of the answer against the sources */
+func (s *GroundednessService) Verify(ctx context.Context, answer string, sources []GroundingSource) (*GroundednessReport, error) {
+	report := &GroundednessReport{Score: 1.0, Threshold: s.config.Threshold, Action: GroundednessActionNone}
+	claims := SplitClaims(answer)
+	if len(claims) == 0 {
+		return report, nil
+	}
+
+	texts := make([]string, len(sources))
+	for i, source := range sources {
+		texts[i] = truncateRunes(source.Text, maxSourceChars)
+	}
+
+	scorer := &claimScorer{service: s, sources: texts}
+	supported := 0
+	for _, claim := range claims {
+		verdict := ClaimVerdict{Claim: claim, SourceIndex: -1}
+		if len(texts) > 0 {
+			index, score, method := scorer.score(ctx, claim.Text)
+			verdict.Method = method
+			verdict.Score = score
+			if index >= 0 {
+				verdict.SourceIndex = index
+				verdict.SourceID = sources[index].ID
+				verdict.Evidence = bestEvidenceSentence(claim.Text, texts[index])
+			}
+			verdict.Supported = index >= 0 && score >= claimSupportThresholds[method]
+		}
+		if verdict.Supported {
+			supported++
+		} else {
+			report.UnsupportedClaims++
+		}
+		report.Claims = append(report.Claims, verdict)
+	}
+	report.Score = float64(supported) / float64(len(claims))
+	return report, nil
+}
+
+/* VerifyAndRecord verifies an answer and records the groundedness score and per-claim evidence as a hallucination signal */
+func (s *GroundednessService) VerifyAndRecord(ctx context.Context, queryID *uuid.UUID, responseID *uuid.UUID, answer string, sources []GroundingSource, metadata map[string]interface{}) (*GroundednessReport, error) {
+	report, err := s.Verify(ctx, answer, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	var flags []string
+	if len(sources) == 0 {
+		flags = append(flags, "no_sources")
+	}
+	if report.UnsupportedClaims > 0 {
+		flags = append(flags, "unsupported_claims")
+	}
+	if report.Score < s.config.Threshold {
+		flags = append(flags, "low_groundedness")
+	}
+
+	var evidenceTotal float64
+	var evidenceCount int
+	for _, claim := range report.Claims {
+		if claim.Supported {
+			evidenceTotal += claim.Score
+			evidenceCount++
+		}
+	}
+	evidenceStrength := 0.0
+	if evidenceCount > 0 {
+		evidenceStrength = math.Min(1, evidenceTotal/float64(evidenceCount))
+	}
+
+	signalMetadata := map[string]interface{}{}
+	for k, v := range metadata {
+		signalMetadata[k] = v
+	}
+	signalMetadata["groundedness"] = report.Score
+	signalMetadata["claims"] = report.Claims
+
+	signal, err := s.hallucination.RecordHallucinationSignal(ctx, queryID, nil, responseID, report.Score,
+		report.Score, evidenceStrength, flags, signalMetadata)
+	if err != nil {
+		return report, err
+	}
+	report.SignalID = &signal.ID
+	return report, nil
+}
+
+/* Enforce applies the configured action to an answer below the threshold and returns the answer to show */
+func (s *GroundednessService) Enforce(answer string, report *GroundednessReport) string {
+	if report == nil || report.Score >= s.config.Threshold {
+		return answer
+	}
+
+	switch s.config.Action {
+	case GroundednessActionBlock:
+		report.Action = GroundednessActionBlock
+		return BlockedAnswerMessage
+	case GroundednessActionAnnotate:
+		report.Action = GroundednessActionAnnotate
+		return AnnotateUnsupportedClaims(answer, report)
+	}
+	return answer
+}
+
+/* AnnotateUnsupportedClaims marks each unsupported claim in the answer with [unverified] */
+func AnnotateUnsupportedClaims(answer string, report *GroundednessReport) string {
+	runes := []rune(answer)
+	var b strings.Builder
+	last := 0
+	for _, claim := range report.Claims {
+		if claim.Supported || claim.End > len(runes) || claim.End < last {
+			continue
+		}
+		b.WriteString(string(runes[last:claim.End]))
+		b.WriteString(" [unverified]")
+		last = claim.End
+	}
+	b.WriteString(string(runes[last:]))
+	return b.String()
+}
+
+/* claimScorer scores claims against sources, degrading from entailment to embedding similarity to term overlap */
+type claimScorer struct {
+	service          *GroundednessService
+	sources          []string
+	noEntailment     bool
+	noSimilarity     bool
+	sourceEmbeddings [][]float64
+}
+
+/* score returns the best supporting source, its score and the method used */
+func (c *claimScorer) score(ctx context.Context, claim string) (int, float64, string) {
+	if !c.noEntailment && c.service.mcpClient != nil {
+		if index, score, err := c.entailment(ctx, claim); err == nil {
+			return index, score, ClaimMethodEntailment
+		}
+		c.noEntailment = true
+	}
+	if !c.noSimilarity && c.service.neurondbClient != nil {
+		if index, score, err := c.similarity(ctx, claim); err == nil {
+			return index, score, ClaimMethodSimilarity
+		}
+		c.noSimilarity = true
+	}
+	index, score := c.lexical(claim)
+	return index, score, ClaimMethodLexical
+}
+
+/* entailment scores the claim as a hypothesis against each source as premise using an NLI model */
+func (c *claimScorer) entailment(ctx context.Context, claim string) (int, float64, error) {
+	result, err := c.service.mcpClient.ExecuteTool(ctx, "check_entailment", map[string]interface{}{
+		"premises":   c.sources,
+		"hypothesis": claim,
+	})
+	if err != nil {
+		return -1, 0, err
+	}
+	scores, ok := result["scores"].([]interface{})
+	if !ok || len(scores) != len(c.sources) {
+		return -1, 0, fmt.Errorf("invalid entailment response")
+	}
+	best, bestScore := -1, 0.0
+	for i, raw := range scores {
+		if score, ok := raw.(float64); ok && score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best, bestScore, nil
+}
+
+/* similarity scores the claim by cosine similarity between its embedding and each source embedding */
+func (c *claimScorer) similarity(ctx context.Context, claim string) (int, float64, error) {
+	model := "sentence-transformers/all-MiniLM-L6-v2"
+	if c.sourceEmbeddings == nil {
+		embeddings := make([][]float64, len(c.sources))
+		for i, source := range c.sources {
+			embedding, err := c.service.neurondbClient.GenerateEmbedding(ctx, source, model)
+			if err != nil {
+				return -1, 0, err
+			}
+			if embeddings[i], err = parseVector(embedding); err != nil {
+				return -1, 0, err
+			}
+		}
+		c.sourceEmbeddings = embeddings
+	}
+
+	embedding, err := c.service.neurondbClient.GenerateEmbedding(ctx, claim, model)
+	if err != nil {
+		return -1, 0, err
+	}
+	claimVector, err := parseVector(embedding)
+	if err != nil {
+		return -1, 0, err
+	}
+
+	best, bestScore := -1, 0.0
+	for i, sourceVector := range c.sourceEmbeddings {
+		if score := cosineSimilarity(claimVector, sourceVector); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best, bestScore, nil
+}
+
+/* lexical scores the claim by the share of its content terms found in each source */
+func (c *claimScorer) lexical(claim string) (int, float64) {
+	terms := ContentTerms(claim)
+	best, bestScore := -1, 0.0
+	for i, source := range c.sources {
+		if score := termOverlap(terms, source); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best, bestScore
+}
+
+/* bestEvidenceSentence returns the source sentence sharing the most terms with the claim */
+func bestEvidenceSentence(claim string, source string) string {
+	terms := ContentTerms(claim)
+	var best string
+	bestScore := -1.0
+	for _, sentence := range strings.FieldsFunc(source, func(r rune) bool { return r == '.' || r == '\n' || r == '!' || r == '?' }) {
+		if score := termOverlap(terms, sentence); score > bestScore {
+			best, bestScore = strings.TrimSpace(sentence), score
+		}
+	}
+	return truncateRunes(best, 300)
+}
+
+/* termOverlap returns the share of terms present in text */
+func termOverlap(terms []string, text string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	present := make(map[string]bool)
+	for _, term := range ContentTerms(text) {
+		present[term] = true
+	}
+	matched := 0
+	for _, term := range terms {
+		if present[term] {
+			matched++
+		}
+	}
+	return float64(matched) / float64(len(terms))
+}
+
+/* contentStopwords are common words ignored when comparing answer text with sources */
+var contentStopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "was": true, "were": true, "with": true,
+	"that": true, "this": true, "from": true, "has": true, "have": true, "not": true, "but": true,
+	"its": true, "can": true, "which": true, "their": true, "they": true, "also": true, "you": true,
+	"your": true, "will": true, "been": true, "there": true,
+}
+
+/* ContentTerms returns the lowercased content words of text, the terms claims and citations are matched on */
+func ContentTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := words[:0]
+	for _, word := range words {
+		if len([]rune(word)) >= 3 && !contentStopwords[word] {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+/* parseVector parses a pgvector text representation such as [0.1,0.2] */
+func parseVector(text string) ([]float64, error) {
+	text = strings.Trim(strings.TrimSpace(text), "[]")
+	if text == "" {
+		return nil, fmt.Errorf("empty vector")
+	}
+	parts := strings.Split(text, ",")
+	vector := make([]float64, len(parts))
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse vector: %w", err)
+		}
+		vector[i] = value
+	}
+	return vector, nil
+}
+
+/* cosineSimilarity returns the cosine similarity of two vectors of equal length */
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+/* truncateRunes shortens text to at most limit characters */
+func truncateRunes(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit])
+}
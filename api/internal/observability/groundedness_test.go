@@ -0,0 +1,245 @@
+package observability
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/neurondb/NeuronIP/api/internal/config"
+)
+
+/* TestSplitClaims checks which parts of an answer become claims and that offsets point back into the answer */
+func TestSplitClaims(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer string
+		claims []string
+	}{
+		{
+			name:   "sentences",
+			answer: "Revenue grew twelve percent in March. Churn fell below two percent.",
+			claims: []string{"Revenue grew twelve percent in March.", "Churn fell below two percent."},
+		},
+		{
+			name:   "questions and pleasantries",
+			answer: "Thanks for asking! The warehouse stores daily order snapshots. Do you need hourly snapshots? Let me know if anything else helps.",
+			claims: []string{"The warehouse stores daily order snapshots."},
+		},
+		{
+			name:   "too few content terms",
+			answer: "Yes. Orders ship from the Berlin warehouse daily.",
+			claims: []string{"Orders ship from the Berlin warehouse daily."},
+		},
+		{
+			name:   "decimal point",
+			answer: "Average latency was 3.5 seconds last week.",
+			claims: []string{"Average latency was 3.5 seconds last week."},
+		},
+		{
+			name:   "bullet list",
+			answer: "- Orders ship within two days\n- Returns are accepted for thirty days",
+			claims: []string{"Orders ship within two days", "Returns are accepted for thirty days"},
+		},
+		{
+			name:   "independent clauses",
+			answer: "The pipeline loads orders every hour; the dashboard refreshes every morning at six.",
+			claims: []string{"The pipeline loads orders every hour", "the dashboard refreshes every morning at six."},
+		},
+		{
+			name:   "short clause stays attached",
+			answer: "Sales rose in Europe, and Asia.",
+			claims: []string{"Sales rose in Europe, and Asia."},
+		},
+		{
+			name:   "non-ASCII text",
+			answer: "Der Umsatz stieg im März deutlich. Die Kosten blieben über das Jahr stabil.",
+			claims: []string{"Der Umsatz stieg im März deutlich.", "Die Kosten blieben über das Jahr stabil."},
+		},
+		{name: "empty", answer: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := SplitClaims(tt.answer)
+			texts := make([]string, len(claims))
+			runes := []rune(tt.answer)
+			for i, claim := range claims {
+				texts[i] = claim.Text
+				if got := string(runes[claim.Start:claim.End]); got != claim.Text {
+					t.Errorf("expected offsets of %q to select it, got %q", claim.Text, got)
+				}
+			}
+			if len(texts) != len(tt.claims) || (len(texts) > 0 && !reflect.DeepEqual(texts, tt.claims)) {
+				t.Errorf("expected claims %q, got %q", tt.claims, texts)
+			}
+		})
+	}
+}
+
+/* TestSplitClauses checks that sentences split only where both sides read as statements */
+func TestSplitClauses(t *testing.T) {
+	tests := []struct {
+		name     string
+		sentence string
+		start    int
+		clauses  []string
+	}{
+		{
+			name:     "but",
+			sentence: "Prices rose sharply in May, but volumes stayed flat all year",
+			clauses:  []string{"Prices rose sharply in May", "volumes stayed flat all year"},
+		},
+		{
+			name:     "while",
+			sentence: "Europe grew by ten percent, while Asia shrank by two percent",
+			clauses:  []string{"Europe grew by ten percent", "Asia shrank by two percent"},
+		},
+		{
+			name:     "several separators",
+			sentence: "Orders are loaded every hour; refunds are loaded every day, and returns are loaded every week",
+			clauses:  []string{"Orders are loaded every hour", "refunds are loaded every day", "returns are loaded every week"},
+		},
+		{
+			name:     "short side",
+			sentence: "Prices rose sharply in May, but not June",
+			clauses:  []string{"Prices rose sharply in May, but not June"},
+		},
+		{
+			name:     "offset into the answer",
+			sentence: "Note. Prices rose sharply in May; volumes stayed flat all year",
+			start:    6,
+			clauses:  []string{"Prices rose sharply in May", "volumes stayed flat all year"},
+		},
+		{
+			name:     "non-ASCII text",
+			sentence: "Die Preise in München stiegen stark; die Mengen blieben über das Jahr gleich",
+			clauses:  []string{"Die Preise in München stiegen stark", "die Mengen blieben über das Jahr gleich"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runes := []rune(tt.sentence)
+			clauses := splitClauses(runes, tt.start, len(runes))
+			texts := make([]string, len(clauses))
+			for i, clause := range clauses {
+				texts[i] = string(runes[clause[0]:clause[1]])
+			}
+			if !reflect.DeepEqual(texts, tt.clauses) {
+				t.Errorf("expected clauses %q, got %q", tt.clauses, texts)
+			}
+		})
+	}
+}
+
+/* reportFor builds a report over the claims of answer with the given claims supported */
+func reportFor(answer string, supported ...bool) *GroundednessReport {
+	report := &GroundednessReport{}
+	for i, claim := range SplitClaims(answer) {
+		verdict := ClaimVerdict{Claim: claim, Supported: i < len(supported) && supported[i]}
+		report.Claims = append(report.Claims, verdict)
+		if !verdict.Supported {
+			report.UnsupportedClaims++
+		}
+	}
+	report.Score = float64(len(report.Claims)-report.UnsupportedClaims) / float64(len(report.Claims))
+	return report
+}
+
+/* TestAnnotateUnsupportedClaims checks where unsupported claims are marked */
+func TestAnnotateUnsupportedClaims(t *testing.T) {
+	answer := "Revenue grew in March. Churn fell in April. Costs held steady."
+
+	tests := []struct {
+		name   string
+		report *GroundednessReport
+		want   string
+	}{
+		{name: "all supported", report: reportFor(answer, true, true, true), want: answer},
+		{
+			name:   "one unsupported",
+			report: reportFor(answer, true, false, true),
+			want:   "Revenue grew in March. Churn fell in April. [unverified] Costs held steady.",
+		},
+		{
+			name:   "none supported",
+			report: reportFor(answer),
+			want:   "Revenue grew in March. [unverified] Churn fell in April. [unverified] Costs held steady. [unverified]",
+		},
+		{
+			name:   "claim past the answer",
+			report: &GroundednessReport{Claims: []ClaimVerdict{{Claim: Claim{Text: "Revenue grew", Start: 0, End: 500}}}},
+			want:   answer,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AnnotateUnsupportedClaims(answer, tt.report); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+/* TestEnforce checks the answer shown and the action recorded for answers above and below the threshold */
+func TestEnforce(t *testing.T) {
+	answer := "Revenue grew in March. Churn fell in April. Costs held steady."
+	annotated := "Revenue grew in March. Churn fell in April. [unverified] Costs held steady."
+
+	tests := []struct {
+		name   string
+		action string
+		report *GroundednessReport
+		want   string
+		taken  string
+	}{
+		{name: "no report", action: GroundednessActionBlock, want: answer},
+		{name: "above threshold", action: GroundednessActionBlock, report: reportFor(answer, true, true, true), want: answer, taken: GroundednessActionNone},
+		{name: "below threshold without action", action: GroundednessActionNone, report: reportFor(answer, true, false, true), want: answer, taken: GroundednessActionNone},
+		{name: "annotate", action: GroundednessActionAnnotate, report: reportFor(answer, true, false, true), want: annotated, taken: GroundednessActionAnnotate},
+		{name: "block", action: GroundednessActionBlock, report: reportFor(answer, true, false, true), want: BlockedAnswerMessage, taken: GroundednessActionBlock},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &GroundednessService{config: config.GroundednessConfig{Enabled: true, Threshold: 0.8, Action: tt.action}}
+			if tt.report != nil {
+				tt.report.Action = GroundednessActionNone
+			}
+			if got := s.Enforce(answer, tt.report); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+			if tt.report != nil && tt.report.Action != tt.taken {
+				t.Errorf("expected action %s, got %s", tt.taken, tt.report.Action)
+			}
+		})
+	}
+}
+
+/* TestFailedReport checks that an answer that could not be verified scores zero and is withheld when blocking */
+func TestFailedReport(t *testing.T) {
+	answer := "Revenue grew twelve percent in March."
+	for _, action := range []string{GroundednessActionNone, GroundednessActionAnnotate, GroundednessActionBlock} {
+		t.Run(action, func(t *testing.T) {
+			s := &GroundednessService{config: config.GroundednessConfig{Enabled: true, Threshold: 0.5, Action: action}}
+			report := s.FailedReport(errors.New("entailment model unavailable"))
+			if report.Score != 0 || report.Threshold != 0.5 || report.Error != "entailment model unavailable" {
+				t.Fatalf("expected a zero score carrying the error, got %+v", report)
+			}
+
+			got := s.Enforce(answer, report)
+			switch action {
+			case GroundednessActionBlock:
+				if got != BlockedAnswerMessage || report.Action != GroundednessActionBlock {
+					t.Errorf("expected the answer to be withheld, got %q (%s)", got, report.Action)
+				}
+			case GroundednessActionAnnotate:
+				// No claims were checked, so there is nothing to mark
+				if got != answer || report.Action != GroundednessActionAnnotate {
+					t.Errorf("expected the answer unchanged, got %q (%s)", got, report.Action)
+				}
+			default:
+				if got != answer || report.Action != GroundednessActionNone {
+					t.Errorf("expected the answer unchanged, got %q (%s)", got, report.Action)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,436 @@
+package profiling
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"strconv"
+)
+
+/* Structure discovery works on a sample held in memory. Values are dictionary encoded per column,
+ * nulls as -1, so keys and dependencies are found by grouping integer codes. */
+
+const (
+	maxCategories       = 50 // Columns with more distinct values are not categorical for Cramér's V
+	maxCorrelationCols  = 50
+	maxPairDeterminants = 15 // Two-column determinants are only searched on tables this narrow
+	maxTripleKeyColumns = 24 // Three-column keys are only searched on tables this narrow
+)
+
+/* sampleColumn is a dictionary-encoded column of the sample */
+type sampleColumn struct {
+	name     string
+	codes    []int32 // -1 for null
+	values   []string
+	nulls    int
+	numeric  []float64 // Parsed values when every non-null value is numeric, NaN for nulls
+	distinct int
+}
+
+func encodeColumn(name string, raw []sql.NullString) *sampleColumn {
+	c := &sampleColumn{name: name, codes: make([]int32, len(raw))}
+	dict := map[string]int32{}
+	numeric := make([]float64, len(raw))
+	isNumeric := true
+	for i, v := range raw {
+		if !v.Valid {
+			c.codes[i] = -1
+			c.nulls++
+			numeric[i] = math.NaN()
+			continue
+		}
+		code, ok := dict[v.String]
+		if !ok {
+			code = int32(len(c.values))
+			dict[v.String] = code
+			c.values = append(c.values, v.String)
+		}
+		c.codes[i] = code
+		if isNumeric {
+			f, err := strconv.ParseFloat(v.String, 64)
+			if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+				isNumeric = false
+			}
+			numeric[i] = f
+		}
+	}
+	c.distinct = len(c.values)
+	if isNumeric && c.distinct > 2 {
+		c.numeric = numeric
+	}
+	return c
+}
+
+func (c *sampleColumn) constant() bool {
+	return c.distinct <= 1
+}
+
+/* groupIDs numbers the distinct combinations of the columns' codes, nulls included */
+func groupIDs(cols []*sampleColumn, rows int) ([]int32, int) {
+	ids := make([]int32, rows)
+	if len(cols) == 1 {
+		next := int32(0)
+		remap := map[int32]int32{}
+		for i, code := range cols[0].codes {
+			id, ok := remap[code]
+			if !ok {
+				id = next
+				remap[code] = id
+				next++
+			}
+			ids[i] = id
+		}
+		return ids, int(next)
+	}
+	type key [3]int32
+	groups := map[key]int32{}
+	for i := 0; i < rows; i++ {
+		var k key
+		for j, c := range cols {
+			k[j] = c.codes[i]
+		}
+		id, ok := groups[k]
+		if !ok {
+			id = int32(len(groups))
+			groups[k] = id
+		}
+		ids[i] = id
+	}
+	return ids, len(groups)
+}
+
+/* findCandidateKeys finds the minimal column sets of up to maxSize columns that are unique and non-null in the sample */
+func findCandidateKeys(cols []*sampleColumn, rows, maxSize int) [][]string {
+	var eligible []*sampleColumn
+	for _, c := range cols {
+		if c.nulls == 0 && !c.constant() {
+			eligible = append(eligible, c)
+		}
+	}
+
+	var keys [][]*sampleColumn
+	isKey := func(set []*sampleColumn) bool {
+		// A set whose distinct counts multiply to fewer than the rows cannot be unique
+		product := 1.0
+		for _, c := range set {
+			product *= float64(c.distinct)
+		}
+		if product < float64(rows) {
+			return false
+		}
+		_, groups := groupIDs(set, rows)
+		return groups == rows
+	}
+	containsKey := func(set []*sampleColumn) bool {
+		for _, key := range keys {
+			if subset(key, set) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for size := 1; size <= maxSize && size <= 3; size++ {
+		if size == 3 && len(eligible) > maxTripleKeyColumns {
+			break
+		}
+		combinations(len(eligible), size, func(idx []int) {
+			set := make([]*sampleColumn, size)
+			for i, j := range idx {
+				set[i] = eligible[j]
+			}
+			if !containsKey(set) && isKey(set) {
+				keys = append(keys, set)
+			}
+		})
+	}
+
+	names := make([][]string, len(keys))
+	for i, key := range keys {
+		for _, c := range key {
+			names[i] = append(names[i], c.name)
+		}
+	}
+	return names
+}
+
+func subset(small, large []*sampleColumn) bool {
+	for _, s := range small {
+		found := false
+		for _, l := range large {
+			if s == l {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+/* combinations calls fn with each increasing index set of size k out of n */
+func combinations(n, k int, fn func([]int)) {
+	idx := make([]int, k)
+	var rec func(pos, start int)
+	rec = func(pos, start int) {
+		if pos == k {
+			fn(idx)
+			return
+		}
+		for i := start; i < n; i++ {
+			idx[pos] = i
+			rec(pos+1, i+1)
+		}
+	}
+	rec(0, 0)
+}
+
+/* dependencyError is the g3 error of determinant → dependent: the fraction of rows that would have to be
+ * removed for the dependency to hold exactly */
+func dependencyError(determinant []int32, groups int, dependent *sampleColumn) float64 {
+	rows := len(determinant)
+	counts := make(map[int64]int, groups)
+	best := make([]int, groups)
+	width := int64(dependent.distinct + 1)
+	for i, g := range determinant {
+		k := int64(g)*width + int64(dependent.codes[i]+1)
+		counts[k]++
+		if counts[k] > best[g] {
+			best[g] = counts[k]
+		}
+	}
+	kept := 0
+	for _, b := range best {
+		kept += b
+	}
+	return float64(rows-kept) / float64(rows)
+}
+
+/* findDependencies finds minimal functional dependencies with one- or two-column determinants whose g3 error
+ * is at most maxError. Keys and constant columns are left out: keys determine everything and constants are
+ * determined by anything. */
+func findDependencies(cols []*sampleColumn, rows int, keys [][]string, maxError float64) []FunctionalDependency {
+	keyColumn := map[string]bool{}
+	for _, key := range keys {
+		if len(key) == 1 {
+			keyColumn[key[0]] = true
+		}
+	}
+	var candidates []*sampleColumn
+	for _, c := range cols {
+		if !c.constant() {
+			candidates = append(candidates, c)
+		}
+	}
+
+	var deps []FunctionalDependency
+	determined := map[string]map[string]bool{} // dependent → single determinants that already hold
+	for _, x := range candidates {
+		if keyColumn[x.name] {
+			continue
+		}
+		ids, groups := groupIDs([]*sampleColumn{x}, rows)
+		if nearlyUnique(groups, rows, maxError) {
+			continue
+		}
+		for _, a := range candidates {
+			if a == x {
+				continue
+			}
+			if e := dependencyError(ids, groups, a); e <= maxError {
+				deps = append(deps, FunctionalDependency{Determinant: []string{x.name}, Dependent: a.name, Error: e, Exact: e == 0})
+				if determined[a.name] == nil {
+					determined[a.name] = map[string]bool{}
+				}
+				determined[a.name][x.name] = true
+			}
+		}
+	}
+
+	if len(candidates) > maxPairDeterminants {
+		return deps
+	}
+	combinations(len(candidates), 2, func(idx []int) {
+		x, y := candidates[idx[0]], candidates[idx[1]]
+		if keyColumn[x.name] || keyColumn[y.name] {
+			return
+		}
+		pair := []*sampleColumn{x, y}
+		ids, groups := groupIDs(pair, rows)
+		if nearlyUnique(groups, rows, maxError) {
+			return
+		}
+		for _, a := range candidates {
+			if a == x || a == y || determined[a.name][x.name] || determined[a.name][y.name] {
+				continue
+			}
+			if e := dependencyError(ids, groups, a); e <= maxError {
+				deps = append(deps, FunctionalDependency{Determinant: []string{x.name, y.name}, Dependent: a.name, Error: e, Exact: e == 0})
+			}
+		}
+	})
+	return deps
+}
+
+/* nearlyUnique reports whether a determinant has so many groups that every dependency from it is within
+ * maxError, as g3 is at most (rows - groups) / rows */
+func nearlyUnique(groups, rows int, maxError float64) bool {
+	return float64(rows-groups)/float64(rows) <= maxError
+}
+
+/* correlationMatrices computes Pearson and Spearman matrices over the numeric columns and Cramér's V over
+ * the categorical ones, each pair over the rows where both values are present */
+func correlationMatrices(cols []*sampleColumn) []CorrelationMatrix {
+	var numeric, categorical []*sampleColumn
+	for _, c := range cols {
+		switch {
+		case c.numeric != nil && len(numeric) < maxCorrelationCols:
+			numeric = append(numeric, c)
+		case c.numeric == nil && c.distinct >= 2 && c.distinct <= maxCategories && len(categorical) < maxCorrelationCols:
+			categorical = append(categorical, c)
+		}
+	}
+
+	var matrices []CorrelationMatrix
+	if len(numeric) >= 2 {
+		ranks := make([][]float64, len(numeric))
+		for i, c := range numeric {
+			ranks[i] = rankValues(c.numeric)
+		}
+		matrices = append(matrices,
+			pairwiseMatrix("pearson", numeric, func(i, j int) *float64 { return pearson(numeric[i].numeric, numeric[j].numeric) }),
+			pairwiseMatrix("spearman", numeric, func(i, j int) *float64 { return pearson(ranks[i], ranks[j]) }))
+	}
+	if len(categorical) >= 2 {
+		matrices = append(matrices,
+			pairwiseMatrix("cramers_v", categorical, func(i, j int) *float64 { return cramersV(categorical[i], categorical[j]) }))
+	}
+	return matrices
+}
+
+func pairwiseMatrix(method string, cols []*sampleColumn, fn func(i, j int) *float64) CorrelationMatrix {
+	m := CorrelationMatrix{Method: method, Columns: make([]string, len(cols)), Values: make([][]*float64, len(cols))}
+	one := 1.0
+	for i, c := range cols {
+		m.Columns[i] = c.name
+		m.Values[i] = make([]*float64, len(cols))
+		m.Values[i][i] = &one
+	}
+	for i := range cols {
+		for j := i + 1; j < len(cols); j++ {
+			v := fn(i, j)
+			m.Values[i][j], m.Values[j][i] = v, v
+		}
+	}
+	return m
+}
+
+/* pearson is the correlation of x and y over rows where both are present, nil when undefined */
+func pearson(x, y []float64) *float64 {
+	var n, sx, sy float64
+	for i := range x {
+		if math.IsNaN(x[i]) || math.IsNaN(y[i]) {
+			continue
+		}
+		n++
+		sx += x[i]
+		sy += y[i]
+	}
+	if n < 3 {
+		return nil
+	}
+	mx, my := sx/n, sy/n
+	var cov, vx, vy float64
+	for i := range x {
+		if math.IsNaN(x[i]) || math.IsNaN(y[i]) {
+			continue
+		}
+		dx, dy := x[i]-mx, y[i]-my
+		cov += dx * dy
+		vx += dx * dx
+		vy += dy * dy
+	}
+	if vx == 0 || vy == 0 {
+		return nil
+	}
+	r := cov / math.Sqrt(vx*vy)
+	return &r
+}
+
+/* rankValues replaces values by their ranks, ties sharing their average rank and nulls staying NaN */
+func rankValues(values []float64) []float64 {
+	idx := make([]int, 0, len(values))
+	for i, v := range values {
+		if !math.IsNaN(v) {
+			idx = append(idx, i)
+		}
+	}
+	sort.Slice(idx, func(a, b int) bool { return values[idx[a]] < values[idx[b]] })
+	ranks := make([]float64, len(values))
+	for i := range ranks {
+		ranks[i] = math.NaN()
+	}
+	for start := 0; start < len(idx); {
+		end := start
+		for end+1 < len(idx) && values[idx[end+1]] == values[idx[start]] {
+			end++
+		}
+		rank := float64(start+end)/2 + 1
+		for k := start; k <= end; k++ {
+			ranks[idx[k]] = rank
+		}
+		start = end + 1
+	}
+	return ranks
+}
+
+/* cramersV is the association of two categorical columns over rows where both are present, nil when undefined */
+func cramersV(a, b *sampleColumn) *float64 {
+	table := make([][]float64, a.distinct)
+	for i := range table {
+		table[i] = make([]float64, b.distinct)
+	}
+	rowTotals := make([]float64, a.distinct)
+	colTotals := make([]float64, b.distinct)
+	n := 0.0
+	for i := range a.codes {
+		ca, cb := a.codes[i], b.codes[i]
+		if ca < 0 || cb < 0 {
+			continue
+		}
+		table[ca][cb]++
+		rowTotals[ca]++
+		colTotals[cb]++
+		n++
+	}
+
+	r, k := 0, 0
+	for _, t := range rowTotals {
+		if t > 0 {
+			r++
+		}
+	}
+	for _, t := range colTotals {
+		if t > 0 {
+			k++
+		}
+	}
+	if n == 0 || r < 2 || k < 2 {
+		return nil
+	}
+	chi2 := 0.0
+	for i := range table {
+		for j := range table[i] {
+			if rowTotals[i] == 0 || colTotals[j] == 0 {
+				continue
+			}
+			expected := rowTotals[i] * colTotals[j] / n
+			d := table[i][j] - expected
+			chi2 += d * d / expected
+		}
+	}
+	v := math.Sqrt(chi2 / n / float64(min(r, k)-1))
+	return &v
+}
@@ -0,0 +1,112 @@
+package profiling
+
+import (
+	"database/sql"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/* sampleOf encodes a column from space-separated values, with _ for null */
+func sampleOf(name, values string) *sampleColumn {
+	var raw []sql.NullString
+	for _, v := range strings.Fields(values) {
+		raw = append(raw, sql.NullString{String: v, Valid: v != "_"})
+	}
+	return encodeColumn(name, raw)
+}
+
+/* TestFindCandidateKeys checks that only minimal, non-null unique column sets are reported */
+func TestFindCandidateKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []*sampleColumn
+		maxSize int
+		want    [][]string
+	}{
+		{
+			name:    "single key",
+			columns: []*sampleColumn{sampleOf("id", "1 2 3 4"), sampleOf("status", "a b a b")},
+			maxSize: 3,
+			want:    [][]string{{"id"}},
+		},
+		{
+			name:    "composite key",
+			columns: []*sampleColumn{sampleOf("order_id", "1 1 2 2"), sampleOf("line", "1 2 1 2"), sampleOf("qty", "5 5 5 6")},
+			maxSize: 3,
+			want:    [][]string{{"order_id", "line"}},
+		},
+		{
+			name:    "supersets of a key are not keys",
+			columns: []*sampleColumn{sampleOf("id", "1 2 3 4"), sampleOf("code", "a a b b"), sampleOf("region", "x y x y")},
+			maxSize: 3,
+			want:    [][]string{{"id"}, {"code", "region"}},
+		},
+		{
+			name:    "columns with nulls are not keys",
+			columns: []*sampleColumn{sampleOf("email", "a b _ d"), sampleOf("status", "a b a b")},
+			maxSize: 3,
+		},
+		{
+			name:    "size limit",
+			columns: []*sampleColumn{sampleOf("order_id", "1 1 2 2"), sampleOf("line", "1 2 1 2")},
+			maxSize: 1,
+		},
+		{
+			name:    "constant columns are never part of a key",
+			columns: []*sampleColumn{sampleOf("tenant", "t t t t"), sampleOf("id", "1 2 3 4")},
+			maxSize: 3,
+			want:    [][]string{{"id"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findCandidateKeys(tt.columns, len(tt.columns[0].codes), tt.maxSize)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected keys %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+/* TestCramersV checks the association measure between categorical columns */
+func TestCramersV(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b *sampleColumn
+		want *float64 // nil when the measure is undefined
+	}{
+		{name: "one determines the other", a: sampleOf("country", "de de fr fr us us"), b: sampleOf("currency", "eur eur eur eur usd usd"), want: floatPtr(1)},
+		{name: "same partition", a: sampleOf("a", "x x y y"), b: sampleOf("b", "p p q q"), want: floatPtr(1)},
+		{name: "independent", a: sampleOf("a", "x x y y"), b: sampleOf("b", "p q p q"), want: floatPtr(0)},
+		{name: "partial", a: sampleOf("a", "x x x y y y"), b: sampleOf("b", "p p q q q q"), want: floatPtr(math.Sqrt(0.5))},
+		{name: "nulls are skipped", a: sampleOf("a", "x x y y _ x"), b: sampleOf("b", "p p q q p _"), want: floatPtr(1)},
+		{name: "constant column", a: sampleOf("a", "x x y y"), b: sampleOf("b", "p p p p")},
+		{name: "all null", a: sampleOf("a", "_ _ _"), b: sampleOf("b", "p q r")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cramersV(tt.a, tt.b)
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("expected no value, got %v", *got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("expected %v, got no value", *tt.want)
+			}
+			if math.Abs(*got-*tt.want) > 1e-9 {
+				t.Fatalf("expected %v, got %v", *tt.want, *got)
+			}
+		})
+	}
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
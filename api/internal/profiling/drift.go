@@ -0,0 +1,327 @@
+package profiling
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	driftBins    = 10     // PSI bins numeric columns by the baseline's deciles
+	driftEpsilon = 0.0001 // Floor of a bin's share, so empty bins keep PSI finite
+)
+
+/* DriftThresholds are the changes that count as drift; zero values take the defaults */
+type DriftThresholds struct {
+	PSI             float64 `json:"psi"`              // Default 0.2, the usual line for a significant shift
+	KS              float64 `json:"ks"`               // Default 0.1
+	NullRatio       float64 `json:"null_ratio"`       // Absolute change, default 0.05
+	DistinctRatio   float64 `json:"distinct_ratio"`   // Absolute change, default 0.1
+	PatternShift    float64 `json:"pattern_shift"`    // Change in match percentage points, default 10
+	CategoryChanges int     `json:"category_changes"` // New plus disappeared categories, default 1
+}
+
+func (t DriftThresholds) withDefaults() DriftThresholds {
+	if t.PSI <= 0 {
+		t.PSI = 0.2
+	}
+	if t.KS <= 0 {
+		t.KS = 0.1
+	}
+	if t.NullRatio <= 0 {
+		t.NullRatio = 0.05
+	}
+	if t.DistinctRatio <= 0 {
+		t.DistinctRatio = 0.1
+	}
+	if t.PatternShift <= 0 {
+		t.PatternShift = 10
+	}
+	if t.CategoryChanges <= 0 {
+		t.CategoryChanges = 1
+	}
+	return t
+}
+
+/* DriftReport compares two profile snapshots of a table */
+type DriftReport struct {
+	ConnectorID    uuid.UUID       `json:"connector_id"`
+	SchemaName     string          `json:"schema_name"`
+	TableName      string          `json:"table_name"`
+	BaselineID     uuid.UUID       `json:"baseline_id"`
+	CurrentID      uuid.UUID       `json:"current_id"`
+	BaselineAt     time.Time       `json:"baseline_at"`
+	CurrentAt      time.Time       `json:"current_at"`
+	RowCountBefore int64           `json:"row_count_before"`
+	RowCountAfter  int64           `json:"row_count_after"`
+	Columns        []ColumnDrift   `json:"columns"`
+	Thresholds     DriftThresholds `json:"thresholds"`
+	Drifted        bool            `json:"drifted"` // Some column crossed a threshold
+	MaxPSI         float64         `json:"max_psi"`
+	MaxKS          float64         `json:"max_ks"`
+}
+
+/* ColumnDrift is how a column changed between two snapshots */
+type ColumnDrift struct {
+	ColumnName            string         `json:"column_name"`
+	Status                string         `json:"status"` // "present", "added", "removed" or "not_compared" when a run failed to profile it
+	PSI                   *float64       `json:"psi,omitempty"`
+	KS                    *float64       `json:"ks,omitempty"` // Numeric columns only
+	NullRatioBefore       float64        `json:"null_ratio_before"`
+	NullRatioAfter        float64        `json:"null_ratio_after"`
+	DistinctRatioBefore   float64        `json:"distinct_ratio_before"`
+	DistinctRatioAfter    float64        `json:"distinct_ratio_after"`
+	NewCategories         []string       `json:"new_categories,omitempty"`
+	DisappearedCategories []string       `json:"disappeared_categories,omitempty"`
+	TypeBefore            string         `json:"type_before,omitempty"`
+	TypeAfter             string         `json:"type_after,omitempty"`
+	PatternShifts         []PatternShift `json:"pattern_shifts,omitempty"`
+	Exceeded              []string       `json:"exceeded,omitempty"` // Thresholds crossed: psi, ks, null_ratio, distinct_ratio, categories, type, pattern, schema
+}
+
+/* PatternShift is a change in a detected pattern's match percentage; 0 when the pattern was not detected */
+type PatternShift struct {
+	PatternType string  `json:"pattern_type"`
+	Before      float64 `json:"before"`
+	After       float64 `json:"after"`
+}
+
+/* CompareSnapshots reports the drift from a baseline snapshot to a current one of the same table */
+func (s *Service) CompareSnapshots(ctx context.Context, baselineID, currentID uuid.UUID, thresholds DriftThresholds) (*DriftReport, error) {
+	baseline, err := s.GetSnapshot(ctx, baselineID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.GetSnapshot(ctx, currentID)
+	if err != nil {
+		return nil, err
+	}
+	if baseline.ConnectorID != current.ConnectorID || baseline.SchemaName != current.SchemaName || baseline.TableName != current.TableName {
+		return nil, fmt.Errorf("snapshots %s and %s are of different tables", baselineID, currentID)
+	}
+	return compareSnapshots(baseline, current, thresholds.withDefaults()), nil
+}
+
+/* CompareLatestSnapshots reports the drift between a table's two newest snapshots */
+func (s *Service) CompareLatestSnapshots(ctx context.Context, connectorID uuid.UUID, schemaName, tableName string, thresholds DriftThresholds) (*DriftReport, error) {
+	baselineID, currentID, err := s.latestSnapshotIDs(ctx, connectorID, schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	return s.CompareSnapshots(ctx, baselineID, currentID, thresholds)
+}
+
+func compareSnapshots(baseline, current *ProfileSnapshot, t DriftThresholds) *DriftReport {
+	report := &DriftReport{
+		ConnectorID:    current.ConnectorID,
+		SchemaName:     current.SchemaName,
+		TableName:      current.TableName,
+		BaselineID:     baseline.ID,
+		CurrentID:      current.ID,
+		BaselineAt:     baseline.CreatedAt,
+		CurrentAt:      current.CreatedAt,
+		RowCountBefore: baseline.RowCount,
+		RowCountAfter:  current.RowCount,
+		Thresholds:     t,
+	}
+
+	before := map[string]ColumnSnapshot{}
+	for _, c := range baseline.Columns {
+		before[c.ColumnName] = c
+	}
+	for _, c := range current.Columns {
+		b, ok := before[c.ColumnName]
+		if !ok {
+			report.Columns = append(report.Columns, ColumnDrift{ColumnName: c.ColumnName, Status: "added",
+				NullRatioAfter: c.NullRatio, DistinctRatioAfter: c.DistinctRatio, TypeAfter: c.DataType, Exceeded: []string{"schema"}})
+			continue
+		}
+		delete(before, c.ColumnName)
+		if b.Failed || c.Failed {
+			report.Columns = append(report.Columns, ColumnDrift{ColumnName: c.ColumnName, Status: "not_compared"})
+			continue
+		}
+		report.Columns = append(report.Columns, compareColumn(b, c, t))
+	}
+	for _, b := range baseline.Columns {
+		if _, removed := before[b.ColumnName]; removed {
+			report.Columns = append(report.Columns, ColumnDrift{ColumnName: b.ColumnName, Status: "removed",
+				NullRatioBefore: b.NullRatio, DistinctRatioBefore: b.DistinctRatio, TypeBefore: b.DataType, Exceeded: []string{"schema"}})
+		}
+	}
+
+	for _, c := range report.Columns {
+		if len(c.Exceeded) > 0 {
+			report.Drifted = true
+		}
+		if c.PSI != nil {
+			report.MaxPSI = math.Max(report.MaxPSI, *c.PSI)
+		}
+		if c.KS != nil {
+			report.MaxKS = math.Max(report.MaxKS, *c.KS)
+		}
+	}
+	return report
+}
+
+func compareColumn(b, c ColumnSnapshot, t DriftThresholds) ColumnDrift {
+	drift := ColumnDrift{
+		ColumnName:          c.ColumnName,
+		Status:              "present",
+		NullRatioBefore:     b.NullRatio,
+		NullRatioAfter:      c.NullRatio,
+		DistinctRatioBefore: b.DistinctRatio,
+		DistinctRatioAfter:  c.DistinctRatio,
+		TypeBefore:          b.DataType,
+		TypeAfter:           c.DataType,
+	}
+
+	switch {
+	case len(b.Percentiles) > 1 && len(c.Percentiles) > 1:
+		psi := numericPSI(b.Percentiles, c.Percentiles)
+		ks := ksStatistic(b.Percentiles, c.Percentiles)
+		drift.PSI, drift.KS = &psi, &ks
+	case b.Categories != nil && c.Categories != nil:
+		psi := categoricalPSI(b, c)
+		drift.PSI = &psi
+	}
+	if b.Categories != nil && c.Categories != nil {
+		// A value missing from a capped list may only have fallen below the cap
+		for value := range c.Categories {
+			if _, ok := b.Categories[value]; !ok && !b.CategoriesCapped {
+				drift.NewCategories = append(drift.NewCategories, value)
+			}
+		}
+		for value := range b.Categories {
+			if _, ok := c.Categories[value]; !ok && !c.CategoriesCapped {
+				drift.DisappearedCategories = append(drift.DisappearedCategories, value)
+			}
+		}
+		sort.Strings(drift.NewCategories)
+		sort.Strings(drift.DisappearedCategories)
+	}
+
+	patterns := map[string]bool{}
+	for p := range b.Patterns {
+		patterns[p] = true
+	}
+	for p := range c.Patterns {
+		patterns[p] = true
+	}
+	for p := range patterns {
+		if math.Abs(c.Patterns[p]-b.Patterns[p]) >= t.PatternShift {
+			drift.PatternShifts = append(drift.PatternShifts, PatternShift{PatternType: p, Before: b.Patterns[p], After: c.Patterns[p]})
+		}
+	}
+	sort.Slice(drift.PatternShifts, func(i, j int) bool { return drift.PatternShifts[i].PatternType < drift.PatternShifts[j].PatternType })
+
+	if drift.PSI != nil && *drift.PSI >= t.PSI {
+		drift.Exceeded = append(drift.Exceeded, "psi")
+	}
+	if drift.KS != nil && *drift.KS >= t.KS {
+		drift.Exceeded = append(drift.Exceeded, "ks")
+	}
+	if math.Abs(c.NullRatio-b.NullRatio) >= t.NullRatio {
+		drift.Exceeded = append(drift.Exceeded, "null_ratio")
+	}
+	if math.Abs(c.DistinctRatio-b.DistinctRatio) >= t.DistinctRatio {
+		drift.Exceeded = append(drift.Exceeded, "distinct_ratio")
+	}
+	if len(drift.NewCategories)+len(drift.DisappearedCategories) >= t.CategoryChanges {
+		drift.Exceeded = append(drift.Exceeded, "categories")
+	}
+	if b.DataType != c.DataType {
+		drift.Exceeded = append(drift.Exceeded, "type")
+	}
+	if len(drift.PatternShifts) > 0 {
+		drift.Exceeded = append(drift.Exceeded, "pattern")
+	}
+	return drift
+}
+
+/* percentileCDF is the share of values at or below x, from evenly spaced percentiles interpolated linearly */
+func percentileCDF(percentiles []float64, x float64) float64 {
+	n := len(percentiles) - 1
+	if x < percentiles[0] {
+		return 0
+	}
+	if x >= percentiles[n] {
+		return 1
+	}
+	i := sort.Search(len(percentiles), func(i int) bool { return percentiles[i] > x }) - 1
+	f := float64(i)
+	if gap := percentiles[i+1] - percentiles[i]; gap > 0 {
+		f += (x - percentiles[i]) / gap
+	}
+	return f / float64(n)
+}
+
+/* numericPSI bins both distributions by the baseline's deciles and sums (a - e) ln(a / e) over the bins */
+func numericPSI(baseline, current []float64) float64 {
+	var edges []float64
+	n := len(baseline) - 1
+	for i := 1; i < driftBins; i++ {
+		edge := baseline[i*n/driftBins]
+		if len(edges) == 0 || edge > edges[len(edges)-1] {
+			edges = append(edges, edge)
+		}
+	}
+
+	psi := 0.0
+	prevE, prevA := 0.0, 0.0
+	for i := 0; i <= len(edges); i++ {
+		e, a := 1.0, 1.0
+		if i < len(edges) {
+			e, a = percentileCDF(baseline, edges[i]), percentileCDF(current, edges[i])
+		}
+		psi += psiTerm(e-prevE, a-prevA)
+		prevE, prevA = e, a
+	}
+	return psi
+}
+
+/* categoricalPSI treats each category as a bin. Categories whose share is unknown on one side, because they are
+ * missing from its capped list, join the remaining values in one further bin. */
+func categoricalPSI(baseline, current ColumnSnapshot) float64 {
+	psi := 0.0
+	restE, restA := 1.0, 1.0
+	bin := func(e, a float64) {
+		psi += psiTerm(e, a)
+		restE -= e
+		restA -= a
+	}
+	for value, e := range baseline.Categories {
+		a, ok := current.Categories[value]
+		if !ok && current.CategoriesCapped {
+			continue
+		}
+		bin(e, a)
+	}
+	for value, a := range current.Categories {
+		if _, ok := baseline.Categories[value]; !ok && !baseline.CategoriesCapped {
+			bin(0, a)
+		}
+	}
+	return psi + psiTerm(math.Max(restE, 0), math.Max(restA, 0))
+}
+
+func psiTerm(expected, actual float64) float64 {
+	expected = math.Max(expected, driftEpsilon)
+	actual = math.Max(actual, driftEpsilon)
+	return (actual - expected) * math.Log(actual/expected)
+}
+
+/* ksStatistic is the largest gap between the two distributions' CDFs, checked at every percentile of either */
+func ksStatistic(baseline, current []float64) float64 {
+	ks := 0.0
+	for _, points := range [][]float64{baseline, current} {
+		for _, x := range points {
+			ks = math.Max(ks, math.Abs(percentileCDF(baseline, x)-percentileCDF(current, x)))
+		}
+	}
+	return ks
+}
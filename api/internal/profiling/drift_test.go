@@ -0,0 +1,78 @@
+package profiling
+
+import (
+	"math"
+	"testing"
+)
+
+/* uniformPercentiles returns the percentiles 0 to 100 of a uniform distribution over [lo, hi] */
+func uniformPercentiles(lo, hi float64) []float64 {
+	percentiles := make([]float64, snapshotPercentiles+1)
+	for i := range percentiles {
+		percentiles[i] = lo + (hi-lo)*float64(i)/snapshotPercentiles
+	}
+	return percentiles
+}
+
+/* TestNumericDrift checks PSI and the KS statistic computed from percentiles */
+func TestNumericDrift(t *testing.T) {
+	constant := make([]float64, snapshotPercentiles+1)
+	for i := range constant {
+		constant[i] = 5
+	}
+
+	tests := []struct {
+		name     string
+		baseline []float64
+		current  []float64
+		psi      float64
+		ks       float64
+	}{
+		{name: "unchanged", baseline: uniformPercentiles(0, 100), current: uniformPercentiles(0, 100)},
+		// One baseline decile empties and the top one doubles
+		{name: "shifted", baseline: uniformPercentiles(0, 100), current: uniformPercentiles(10, 110),
+			psi: (driftEpsilon-0.1)*math.Log(driftEpsilon/0.1) + 0.1*math.Log(2), ks: 0.1},
+		// Each baseline decile halves and the top one takes the rest
+		{name: "spread", baseline: uniformPercentiles(0, 100), current: uniformPercentiles(0, 200),
+			psi: 9*(0.05-0.1)*math.Log(0.5) + 0.45*math.Log(5.5), ks: 0.5},
+		{name: "constant unchanged", baseline: constant, current: constant},
+		// Repeated baseline edges collapse into a single bin at or below the constant
+		{name: "constant to uniform", baseline: constant, current: uniformPercentiles(0, 10),
+			psi: -0.5*math.Log(0.5) + (0.5-driftEpsilon)*math.Log(0.5/driftEpsilon), ks: 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := numericPSI(tt.baseline, tt.current); math.Abs(got-tt.psi) > 1e-6 {
+				t.Errorf("expected PSI %.6f, got %.6f", tt.psi, got)
+			}
+			if got := ksStatistic(tt.baseline, tt.current); math.Abs(got-tt.ks) > 1e-6 {
+				t.Errorf("expected KS %.6f, got %.6f", tt.ks, got)
+			}
+		})
+	}
+}
+
+/* TestCategoricalPSI checks that categories missing from a capped list are compared as one remaining bin */
+func TestCategoricalPSI(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseline ColumnSnapshot
+		current  ColumnSnapshot
+		psi      float64
+	}{
+		{name: "unchanged", baseline: ColumnSnapshot{Categories: map[string]float64{"a": 0.6, "b": 0.4}}, current: ColumnSnapshot{Categories: map[string]float64{"a": 0.6, "b": 0.4}}},
+		{name: "new category", baseline: ColumnSnapshot{Categories: map[string]float64{"a": 1}}, current: ColumnSnapshot{Categories: map[string]float64{"a": 0.5, "b": 0.5}},
+			psi: -0.5*math.Log(0.5) + (0.5-driftEpsilon)*math.Log(0.5/driftEpsilon)},
+		// b may still be present beyond the current list's cap, so it is compared within the remaining values
+		{name: "capped current", baseline: ColumnSnapshot{Categories: map[string]float64{"a": 0.5, "b": 0.5}}, current: ColumnSnapshot{Categories: map[string]float64{"a": 0.5}, CategoriesCapped: true}},
+		{name: "capped baseline", baseline: ColumnSnapshot{Categories: map[string]float64{"a": 0.7}, CategoriesCapped: true}, current: ColumnSnapshot{Categories: map[string]float64{"a": 0.4, "c": 0.6}},
+			psi: -0.3*math.Log(0.4/0.7) + 0.3*math.Log(0.6/0.3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := categoricalPSI(tt.baseline, tt.current); math.Abs(got-tt.psi) > 1e-6 {
+				t.Errorf("expected PSI %.6f, got %.6f", tt.psi, got)
+			}
+		})
+	}
+}
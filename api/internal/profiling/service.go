@@ -38,6 +38,13 @@ type ProfileRequest struct {
 	TableName   string
 	ColumnName  *string
 	SampleSize  *int
+
+	// Mode is exact (default), sketch or auto. The rest apply to sketch mode; SampleSize is the target sample rows.
+	Mode            string
+	SampleMethod    string   // system (default), bernoulli or reservoir
+	SamplePercent   *float64 // Overrides SampleSize
+	PartitionColumn *string  // With PartitionValue, sketches one partition and merges it with the stored others
+	PartitionValue  *string
 }
 
 /* ProfileResult represents profiling results */
@@ -74,8 +81,21 @@ type DetectedPattern struct {
 
 /* ProfileTable profiles a table */
 func (s *Service) ProfileTable(ctx context.Context, req ProfileRequest) (*ProfileResult, error) {
+	// Resolve auto mode once for every column; sketch mode takes the planner's row estimate instead of counting
+	sketch := s.useSketch(ctx, req)
+	req.Mode = ProfileModeExact
+	if sketch {
+		req.Mode = ProfileModeSketch
+	}
+
 	// Get table statistics
-	stats, err := s.getTableStatistics(ctx, req)
+	var stats *TableStatistics
+	var err error
+	if sketch {
+		stats, err = s.estimateTableStatistics(ctx, req)
+	} else {
+		stats, err = s.getTableStatistics(ctx, req)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get table statistics: %w", err)
 	}
@@ -86,17 +106,40 @@ func (s *Service) ProfileTable(ctx context.Context, req ProfileRequest) (*Profil
 		return nil, fmt.Errorf("failed to get columns: %w", err)
 	}
 
+	// Sketch mode reads every column in one sample scan
+	var sketches []*ColumnSketch
+	if sketch {
+		sketches, err = s.sketchColumns(ctx, req, columns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sketch columns: %w", err)
+		}
+	}
+
 	columnProfiles := []map[string]interface{}{}
-	for _, col := range columns {
+	var columnSnapshots []ColumnSnapshot
+	for i, col := range columns {
 		colReq := req
 		colReq.ColumnName = &col
-		colProfile, err := s.ProfileColumn(ctx, colReq)
-		if err == nil {
+		var colProfile *ProfileResult
+		if sketch {
+			colProfile, err = s.profileFromSketch(ctx, colReq, sketches[i])
+		} else {
+			colProfile, err = s.ProfileColumn(ctx, colReq)
+		}
+		if err != nil {
+			// Record the failure, so drift comparison skips the column rather than reporting it removed
 			columnProfiles = append(columnProfiles, map[string]interface{}{
 				"column_name": col,
-				"profile":     colProfile,
+				"error":       err.Error(),
 			})
+			columnSnapshots = append(columnSnapshots, ColumnSnapshot{ColumnName: col, Failed: true, Error: err.Error()})
+			continue
 		}
+		columnProfiles = append(columnProfiles, map[string]interface{}{
+			"column_name": col,
+			"profile":     colProfile,
+		})
+		columnSnapshots = append(columnSnapshots, s.columnSnapshot(ctx, colReq, colProfile))
 	}
 
 	result := &ProfileResult{
@@ -108,16 +151,32 @@ func (s *Service) ProfileTable(ctx context.Context, req ProfileRequest) (*Profil
 		Statistics: map[string]interface{}{
 			"row_count":      stats.RowCount,
 			"column_count":   len(columns),
+			"mode":           req.Mode,
 			"size_bytes":     stats.SizeBytes,
 			"column_profiles": columnProfiles,
 		},
 		ProfiledAt: time.Now(),
 	}
 
-	// Store profile
+	// Store profile, and the run's snapshot for drift comparisons
+	snapshot := &ProfileSnapshot{
+		ID:          uuid.New(),
+		ProfileID:   result.ID,
+		ConnectorID: req.ConnectorID,
+		SchemaName:  req.SchemaName,
+		TableName:   req.TableName,
+		Mode:        req.Mode,
+		RowCount:    stats.RowCount,
+		Columns:     columnSnapshots,
+		CreatedAt:   result.ProfiledAt,
+	}
+	result.Statistics["snapshot_id"] = snapshot.ID
 	if err := s.storeProfile(ctx, result); err != nil {
 		return nil, fmt.Errorf("failed to store profile: %w", err)
 	}
+	if err := s.storeSnapshot(ctx, snapshot); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
@@ -127,6 +186,9 @@ func (s *Service) ProfileColumn(ctx context.Context, req ProfileRequest) (*Profi
 	if req.ColumnName == nil {
 		return nil, fmt.Errorf("column_name required for column profiling")
 	}
+	if s.useSketch(ctx, req) {
+		return s.profileColumnSketch(ctx, req)
+	}
 
 	// Get column statistics
 	stats, err := s.getColumnStatistics(ctx, req)
@@ -285,10 +347,11 @@ func (s *Service) getTableStatistics(ctx context.Context, req ProfileRequest) (*
 /* getTableColumns gets column names for a table */
 func (s *Service) getTableColumns(ctx context.Context, req ProfileRequest) ([]string, error) {
 	query := `
-		SELECT column_name
-		FROM neuronip.catalog_columns
-		WHERE connector_id = $1 AND schema_name = $2 AND table_name = $3
-		ORDER BY ordinal_position`
+		SELECT cc.column_name
+		FROM neuronip.catalog_columns cc
+		JOIN neuronip.catalog_tables ct ON ct.id = cc.table_id
+		WHERE ct.connector_id = $1 AND ct.schema_name = $2 AND ct.table_name = $3
+		ORDER BY cc.ordinal_position`
 
 	rows, err := s.pool.Query(ctx, query, req.ConnectorID, req.SchemaName, req.TableName)
 	if err != nil {
@@ -514,6 +577,12 @@ func (s *Service) detectPatterns(ctx context.Context, req ProfileRequest, stats
 		}
 	}
 
+	return matchPatterns(sampleValues)
+}
+
+/* matchPatterns detects patterns in sample values */
+func matchPatterns(sampleValues []string) []DetectedPattern {
+	patterns := []DetectedPattern{}
 	if len(sampleValues) == 0 {
 		return patterns
 	}
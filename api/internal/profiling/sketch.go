@@ -0,0 +1,452 @@
+package profiling
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+/* Sketches summarise a column in bounded memory and merge without revisiting the rows they saw,
+ * so a profile can be built from per-partition sketches as partitions arrive. */
+
+const (
+	hllPrecision       = 14
+	hllRegisters       = 1 << hllPrecision
+	defaultCompression = 100
+	defaultTopK        = 100
+)
+
+/* HyperLogLog estimates distinct counts with a relative standard error of 1.04/sqrt(registers), about 0.8% */
+type HyperLogLog struct {
+	Registers []byte `json:"registers"`
+}
+
+/* NewHyperLogLog creates an empty HyperLogLog */
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{Registers: make([]byte, hllRegisters)}
+}
+
+/* Add adds a value */
+func (h *HyperLogLog) Add(value string) {
+	x := hash64(value)
+	idx := x >> (64 - hllPrecision)
+	rank := byte(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank > h.Registers[idx] {
+		h.Registers[idx] = rank
+	}
+}
+
+/* Merge folds another HyperLogLog into h, as if h had seen its values */
+func (h *HyperLogLog) Merge(other *HyperLogLog) error {
+	if len(other.Registers) != len(h.Registers) {
+		return fmt.Errorf("cannot merge HyperLogLogs of %d and %d registers", len(h.Registers), len(other.Registers))
+	}
+	for i, r := range other.Registers {
+		if r > h.Registers[i] {
+			h.Registers[i] = r
+		}
+	}
+	return nil
+}
+
+/* Estimate returns the estimated number of distinct values with Ertl's improved estimator, which needs
+ * no bias correction between the small and large ranges */
+func (h *HyperLogLog) Estimate() float64 {
+	m := float64(len(h.Registers))
+	q := 64 - hllPrecision
+	counts := make([]float64, q+2)
+	for _, r := range h.Registers {
+		counts[r]++
+	}
+	if counts[0] == m {
+		return 0
+	}
+	z := m * hllTau(1-counts[q+1]/m)
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + counts[k])
+	}
+	z += m * hllSigma(counts[0]/m)
+	return m * m / (2 * math.Ln2 * z)
+}
+
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		previous := z
+		z += x * y
+		y *= 2
+		if z == previous {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		previous := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if z == previous {
+			return z / 3
+		}
+	}
+}
+
+/* RelativeError is the estimate's relative standard error */
+func (h *HyperLogLog) RelativeError() float64 {
+	return 1.04 / math.Sqrt(float64(len(h.Registers)))
+}
+
+/* hash64 is FNV-1a finished with the murmur3 mixer, so that similar values spread over all bits.
+ * It must stay stable: stored sketches are merged with new ones. */
+func hash64(value string) uint64 {
+	f := fnv.New64a()
+	f.Write([]byte(value))
+	x := f.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+/* Centroid is a cluster of values in a t-digest */
+type Centroid struct {
+	Mean  float64 `json:"m"`
+	Count float64 `json:"c"`
+}
+
+/* TDigest estimates quantiles and the CDF of numeric values. Centroids are small near the tails,
+ * so extreme quantiles are more accurate than the median. */
+type TDigest struct {
+	Compression float64    `json:"compression"`
+	Centroids   []Centroid `json:"centroids"`
+	Count       float64    `json:"count"`
+	Min         float64    `json:"min"`
+	Max         float64    `json:"max"`
+	buffer      []Centroid
+}
+
+/* NewTDigest creates an empty t-digest; higher compression keeps more centroids and is more accurate */
+func NewTDigest(compression float64) *TDigest {
+	if compression <= 0 {
+		compression = defaultCompression
+	}
+	return &TDigest{Compression: compression, Min: math.Inf(1), Max: math.Inf(-1)}
+}
+
+/* Add adds a value */
+func (t *TDigest) Add(x float64) {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return
+	}
+	t.buffer = append(t.buffer, Centroid{Mean: x, Count: 1})
+	t.Count++
+	t.Min = math.Min(t.Min, x)
+	t.Max = math.Max(t.Max, x)
+	if len(t.buffer) >= int(10*t.Compression) {
+		t.flush()
+	}
+}
+
+/* Merge folds another t-digest into t */
+func (t *TDigest) Merge(other *TDigest) {
+	other.flush()
+	if other.Count == 0 {
+		return
+	}
+	t.buffer = append(t.buffer, other.Centroids...)
+	t.Count += other.Count
+	t.Min = math.Min(t.Min, other.Min)
+	t.Max = math.Max(t.Max, other.Max)
+	t.flush()
+}
+
+/* flush merges buffered values into the centroids. A centroid may grow while it spans at most one unit of
+ * the scale k(q) = compression/2π·asin(2q-1). */
+func (t *TDigest) flush() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	all := append(t.Centroids, t.buffer...)
+	t.buffer = nil
+	sort.Slice(all, func(i, j int) bool { return all[i].Mean < all[j].Mean })
+
+	total := 0.0
+	for _, c := range all {
+		total += c.Count
+	}
+	merged := make([]Centroid, 0, int(t.Compression))
+	current := all[0]
+	before := 0.0
+	limit := t.qLimit(before / total)
+	for _, c := range all[1:] {
+		if (before+current.Count+c.Count)/total <= limit {
+			current.Mean += (c.Mean - current.Mean) * c.Count / (current.Count + c.Count)
+			current.Count += c.Count
+			continue
+		}
+		merged = append(merged, current)
+		before += current.Count
+		limit = t.qLimit(before / total)
+		current = c
+	}
+	t.Centroids = append(merged, current)
+}
+
+/* qLimit is the quantile a centroid starting at q may grow to */
+func (t *TDigest) qLimit(q float64) float64 {
+	k := t.Compression / (2 * math.Pi) * math.Asin(2*q-1)
+	k++
+	if k >= t.Compression/4 {
+		return 1
+	}
+	return (math.Sin(k*2*math.Pi/t.Compression) + 1) / 2
+}
+
+/* Quantile returns the estimated value at quantile q */
+func (t *TDigest) Quantile(q float64) float64 {
+	t.flush()
+	if t.Count == 0 {
+		return math.NaN()
+	}
+	q = math.Max(0, math.Min(1, q))
+	cs := t.Centroids
+	if len(cs) == 1 {
+		return cs[0].Mean
+	}
+	target := q * t.Count
+	if target < cs[0].Count/2 {
+		return interpolate(t.Min, cs[0].Mean, target/(cs[0].Count/2))
+	}
+	last := cs[len(cs)-1]
+	if target > t.Count-last.Count/2 {
+		return interpolate(last.Mean, t.Max, (target-(t.Count-last.Count/2))/(last.Count/2))
+	}
+
+	cumulative := 0.0
+	for i := 0; i < len(cs)-1; i++ {
+		left := cumulative + cs[i].Count/2
+		right := cumulative + cs[i].Count + cs[i+1].Count/2
+		if target <= right {
+			return interpolate(cs[i].Mean, cs[i+1].Mean, (target-left)/(right-left))
+		}
+		cumulative += cs[i].Count
+	}
+	return last.Mean
+}
+
+/* CDF returns the estimated fraction of values at or below x */
+func (t *TDigest) CDF(x float64) float64 {
+	t.flush()
+	if t.Count == 0 {
+		return math.NaN()
+	}
+	if x < t.Min {
+		return 0
+	}
+	if x >= t.Max {
+		return 1
+	}
+	cs := t.Centroids
+	first, last := cs[0], cs[len(cs)-1]
+	if x < first.Mean {
+		return fraction(x-t.Min, first.Mean-t.Min) * first.Count / 2 / t.Count
+	}
+	if x >= last.Mean {
+		return (t.Count - last.Count/2 + fraction(x-last.Mean, t.Max-last.Mean)*last.Count/2) / t.Count
+	}
+
+	cumulative := 0.0
+	for i := 0; i < len(cs)-1; i++ {
+		if x < cs[i+1].Mean {
+			left := cumulative + cs[i].Count/2
+			right := cumulative + cs[i].Count + cs[i+1].Count/2
+			return (left + fraction(x-cs[i].Mean, cs[i+1].Mean-cs[i].Mean)*(right-left)) / t.Count
+		}
+		cumulative += cs[i].Count
+	}
+	return 1
+}
+
+/* RankError is the approximate largest error, as a fraction of the count, of the rank of quantile q */
+func (t *TDigest) RankError(q float64) float64 {
+	return math.Pi / t.Compression * math.Sqrt(q*(1-q))
+}
+
+/* Histogram splits [Min, Max] into equal-width bins with their estimated counts */
+func (t *TDigest) Histogram(bins int) []HistogramBin {
+	t.flush()
+	if t.Count == 0 || bins <= 0 {
+		return nil
+	}
+	if t.Min == t.Max {
+		return []HistogramBin{{Lower: t.Min, Upper: t.Max, Count: t.Count}}
+	}
+	width := (t.Max - t.Min) / float64(bins)
+	histogram := make([]HistogramBin, bins)
+	previous := 0.0
+	for i := range histogram {
+		upper := t.Min + width*float64(i+1)
+		cdf := t.CDF(upper)
+		if i == bins-1 {
+			upper, cdf = t.Max, 1
+		}
+		histogram[i] = HistogramBin{Lower: t.Min + width*float64(i), Upper: upper, Count: (cdf - previous) * t.Count}
+		previous = cdf
+	}
+	return histogram
+}
+
+/* HistogramBin is a bin of an equal-width histogram */
+type HistogramBin struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count float64 `json:"count"`
+}
+
+func interpolate(a, b, f float64) float64 {
+	return a + (b-a)*math.Max(0, math.Min(1, f))
+}
+
+func fraction(n, d float64) float64 {
+	if d <= 0 {
+		return 0.5
+	}
+	return n / d
+}
+
+/* HeavyHitter is a frequent value. Its count may overstate the truth by at most Error. */
+type HeavyHitter struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+	Error int64  `json:"error"`
+}
+
+/* TopK finds the most frequent values with the Space-Saving algorithm. Any value more frequent than
+ * Total/Capacity is guaranteed to be kept. */
+type TopK struct {
+	Capacity int           `json:"capacity"`
+	Total    int64         `json:"total"`
+	Items    []HeavyHitter `json:"items"`
+	index    map[string]int
+}
+
+/* NewTopK creates an empty top-k summary keeping capacity counters */
+func NewTopK(capacity int) *TopK {
+	if capacity <= 0 {
+		capacity = defaultTopK
+	}
+	return &TopK{Capacity: capacity}
+}
+
+func (k *TopK) ensureIndex() {
+	if k.index != nil {
+		return
+	}
+	k.index = make(map[string]int, len(k.Items))
+	for i, item := range k.Items {
+		k.index[item.Value] = i
+	}
+}
+
+/* Add counts a value, replacing the least counted value when the summary is full */
+func (k *TopK) Add(value string) {
+	k.ensureIndex()
+	k.Total++
+	if i, ok := k.index[value]; ok {
+		k.Items[i].Count++
+		return
+	}
+	if len(k.Items) < k.Capacity {
+		k.index[value] = len(k.Items)
+		k.Items = append(k.Items, HeavyHitter{Value: value, Count: 1})
+		return
+	}
+	minIdx := 0
+	for i, item := range k.Items {
+		if item.Count < k.Items[minIdx].Count {
+			minIdx = i
+		}
+	}
+	evicted := k.Items[minIdx]
+	delete(k.index, evicted.Value)
+	k.index[value] = minIdx
+	k.Items[minIdx] = HeavyHitter{Value: value, Count: evicted.Count + 1, Error: evicted.Count}
+}
+
+/* floor is the count a value missing from a full summary may have had */
+func (k *TopK) floor() int64 {
+	if len(k.Items) < k.Capacity {
+		return 0
+	}
+	floor := k.Items[0].Count
+	for _, item := range k.Items {
+		if item.Count < floor {
+			floor = item.Count
+		}
+	}
+	return floor
+}
+
+/* Merge folds another summary into k. A value missing from one side is credited with that side's floor
+ * as both count and error, which keeps the error guarantee. */
+func (k *TopK) Merge(other *TopK) {
+	floorK, floorOther := k.floor(), other.floor()
+	counts := make(map[string]HeavyHitter, len(k.Items)+len(other.Items))
+	for _, item := range k.Items {
+		counts[item.Value] = HeavyHitter{Value: item.Value, Count: item.Count + floorOther, Error: item.Error + floorOther}
+	}
+	for _, item := range other.Items {
+		if merged, ok := counts[item.Value]; ok {
+			merged.Count += item.Count - floorOther
+			merged.Error += item.Error - floorOther
+			counts[item.Value] = merged
+			continue
+		}
+		counts[item.Value] = HeavyHitter{Value: item.Value, Count: item.Count + floorK, Error: item.Error + floorK}
+	}
+
+	items := make([]HeavyHitter, 0, len(counts))
+	for _, item := range counts {
+		items = append(items, item)
+	}
+	sortHeavyHitters(items)
+	if len(items) > k.Capacity {
+		items = items[:k.Capacity]
+	}
+	k.Items = items
+	k.Total += other.Total
+	k.index = nil
+}
+
+/* Top returns the n most frequent values */
+func (k *TopK) Top(n int) []HeavyHitter {
+	items := append([]HeavyHitter(nil), k.Items...)
+	sortHeavyHitters(items)
+	if n > 0 && len(items) > n {
+		items = items[:n]
+	}
+	return items
+}
+
+func sortHeavyHitters(items []HeavyHitter) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Value < items[j].Value
+	})
+}
@@ -0,0 +1,661 @@
+package profiling
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+/* Profiling modes. Exact runs whole-table aggregates; sketch reads a sample, or streams the table once,
+ * into mergeable sketches; auto sketches tables estimated at autoSketchRows rows or more. */
+const (
+	ProfileModeExact  = "exact"
+	ProfileModeSketch = "sketch"
+	ProfileModeAuto   = "auto"
+)
+
+/* Sample methods of sketch mode. System samples pages, bernoulli samples rows, and reservoir streams every row
+ * through the sketches, keeping a reservoir sample for patterns, so only sketch error remains. */
+const (
+	SampleSystem    = "system"
+	SampleBernoulli = "bernoulli"
+	SampleReservoir = "reservoir"
+)
+
+const (
+	autoSketchRows      = 10_000_000
+	defaultSketchSample = 1_000_000 // Target sample rows when no sample percent is given
+	reservoirSize       = 100
+	sketchHistogramBins = 20
+	sketchTopValues     = 10
+	confidenceZ         = 1.96 // Error bounds are 95% intervals
+)
+
+var sketchQuantiles = []float64{0.01, 0.05, 0.25, 0.5, 0.75, 0.95, 0.99}
+
+/* ColumnSketch is the stored summary of a column, or of one partition of it */
+type ColumnSketch struct {
+	ID              uuid.UUID    `json:"id"`
+	ConnectorID     uuid.UUID    `json:"connector_id"`
+	SchemaName      string       `json:"schema_name"`
+	TableName       string       `json:"table_name"`
+	ColumnName      string       `json:"column_name"`
+	PartitionColumn string       `json:"partition_column,omitempty"`
+	PartitionValue  string       `json:"partition_value,omitempty"`
+	SampleMethod    string       `json:"sample_method"`
+	SampleFraction  float64      `json:"sample_fraction"` // Fraction of rows read; 1 when every row was read
+	RowsRead        int64        `json:"rows_read"`
+	NullCount       int64        `json:"null_count"`
+	NumericCount    int64        `json:"numeric_count"`
+	MinValue        *string      `json:"min_value,omitempty"`
+	MaxValue        *string      `json:"max_value,omitempty"`
+	Distinct        *HyperLogLog `json:"-"`
+	Quantiles       *TDigest     `json:"quantiles,omitempty"` // Nil unless every non-null value is numeric
+	TopK            *TopK        `json:"top_k"`
+	Reservoir       []string     `json:"reservoir,omitempty"`
+	Partitions      int          `json:"partitions"`
+	ProfiledAt      time.Time    `json:"profiled_at"`
+}
+
+func newColumnSketch(req ProfileRequest) *ColumnSketch {
+	sketch := &ColumnSketch{
+		ID:          uuid.New(),
+		ConnectorID: req.ConnectorID,
+		SchemaName:  req.SchemaName,
+		TableName:   req.TableName,
+		ColumnName:  *req.ColumnName,
+		Distinct:    NewHyperLogLog(),
+		Quantiles:   NewTDigest(defaultCompression),
+		TopK:        NewTopK(defaultTopK),
+		Partitions:  1,
+		ProfiledAt:  time.Now(),
+	}
+	if req.PartitionColumn != nil && req.PartitionValue != nil {
+		sketch.PartitionColumn = *req.PartitionColumn
+		sketch.PartitionValue = *req.PartitionValue
+	}
+	return sketch
+}
+
+/* add adds a read value; rng drives the reservoir */
+func (c *ColumnSketch) add(value sql.NullString, rng *rand.Rand) {
+	c.RowsRead++
+	if !value.Valid {
+		c.NullCount++
+		return
+	}
+	v := value.String
+	c.Distinct.Add(v)
+	c.TopK.Add(v)
+	if c.MinValue == nil || v < *c.MinValue {
+		c.MinValue = &v
+	}
+	if c.MaxValue == nil || v > *c.MaxValue {
+		c.MaxValue = &v
+	}
+	if c.Quantiles != nil {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			c.Quantiles.Add(f)
+			c.NumericCount++
+		} else {
+			c.Quantiles = nil // Not a numeric column
+		}
+	}
+
+	seen := c.RowsRead - c.NullCount
+	if len(c.Reservoir) < reservoirSize {
+		c.Reservoir = append(c.Reservoir, v)
+	} else if j := rng.Int63n(seen); j < reservoirSize {
+		c.Reservoir[j] = v
+	}
+}
+
+/* estimatedRows is the number of rows the sketch stands for */
+func (c *ColumnSketch) estimatedRows() float64 {
+	if c.SampleFraction <= 0 {
+		return float64(c.RowsRead)
+	}
+	return float64(c.RowsRead) / c.SampleFraction
+}
+
+/* Merge folds the sketch of another partition into c */
+func (c *ColumnSketch) Merge(other *ColumnSketch) error {
+	if err := c.Distinct.Merge(other.Distinct); err != nil {
+		return err
+	}
+	estimated := c.estimatedRows() + other.estimatedRows()
+
+	// Reservoirs are combined in proportion to the rows each stands for
+	if len(other.Reservoir) > 0 {
+		rng := rand.New(rand.NewSource(int64(hash64(other.PartitionValue))))
+		weight := other.estimatedRows() / estimated
+		for i := range c.Reservoir {
+			if rng.Float64() < weight {
+				c.Reservoir[i] = other.Reservoir[rng.Intn(len(other.Reservoir))]
+			}
+		}
+		for len(c.Reservoir) < reservoirSize && len(c.Reservoir) < len(other.Reservoir) {
+			c.Reservoir = append(c.Reservoir, other.Reservoir[len(c.Reservoir)])
+		}
+	}
+
+	c.TopK.Merge(other.TopK)
+	switch {
+	case c.Quantiles != nil && other.Quantiles != nil:
+		c.Quantiles.Merge(other.Quantiles)
+	case c.RowsRead == c.NullCount:
+		c.Quantiles = other.Quantiles // No values yet to disagree with
+	case other.RowsRead > other.NullCount:
+		c.Quantiles = nil // Other partition has non-numeric values
+	}
+	if other.MinValue != nil && (c.MinValue == nil || *other.MinValue < *c.MinValue) {
+		c.MinValue = other.MinValue
+	}
+	if other.MaxValue != nil && (c.MaxValue == nil || *other.MaxValue > *c.MaxValue) {
+		c.MaxValue = other.MaxValue
+	}
+	c.RowsRead += other.RowsRead
+	c.NullCount += other.NullCount
+	c.NumericCount += other.NumericCount
+	if estimated > 0 {
+		c.SampleFraction = float64(c.RowsRead) / estimated
+	}
+	if other.SampleMethod != c.SampleMethod {
+		c.SampleMethod = "mixed"
+	}
+	c.Partitions += other.Partitions
+	if other.ProfiledAt.After(c.ProfiledAt) {
+		c.ProfiledAt = other.ProfiledAt
+	}
+	return nil
+}
+
+/* Bound is an estimate with its 95% interval */
+type Bound struct {
+	Estimate float64 `json:"estimate"`
+	Low      float64 `json:"low"`
+	High     float64 `json:"high"`
+}
+
+/* QuantileEstimate is a quantile with the values its rank error could reach */
+type QuantileEstimate struct {
+	Quantile float64 `json:"quantile"`
+	Bound
+	RankError float64 `json:"rank_error"` // Fraction of rows the true rank may be off by
+}
+
+/* HeavyHitterEstimate is a frequent value with its estimated count in the whole table */
+type HeavyHitterEstimate struct {
+	Value string `json:"value"`
+	Bound
+}
+
+/* SketchErrorBounds are the 95% intervals of a sketch profile's estimates */
+type SketchErrorBounds struct {
+	Confidence    float64 `json:"confidence"`
+	RowCount      Bound   `json:"row_count"`
+	NullFraction  Bound   `json:"null_fraction"`
+	DistinctCount Bound   `json:"distinct_count"`
+	Note          string  `json:"note,omitempty"`
+}
+
+/* errorBounds derives error bounds from the sketches and, when sampled, the sample size */
+func (c *ColumnSketch) errorBounds() SketchErrorBounds {
+	n := float64(c.RowsRead)
+	f := math.Min(1, math.Max(c.SampleFraction, 1e-12))
+	bounds := SketchErrorBounds{Confidence: 0.95}
+
+	rows := c.estimatedRows()
+	rowsMargin := 0.0
+	if f < 1 {
+		rowsMargin = confidenceZ * math.Sqrt(n*(1-f)) / f
+	}
+	bounds.RowCount = Bound{Estimate: rows, Low: math.Max(n, rows-rowsMargin), High: rows + rowsMargin}
+
+	if n > 0 {
+		p := float64(c.NullCount) / n
+		margin := confidenceZ * math.Sqrt(p*(1-p)/n*(1-f))
+		bounds.NullFraction = Bound{Estimate: p, Low: math.Max(0, p-margin), High: math.Min(1, p+margin)}
+	}
+
+	distinct := c.Distinct.Estimate()
+	relative := confidenceZ * c.Distinct.RelativeError()
+	bounds.DistinctCount = Bound{Estimate: distinct, Low: distinct * (1 - relative), High: distinct * (1 + relative)}
+	if f < 1 {
+		// Values in unread rows may all be new, so a sample only bounds the distinct count from below
+		bounds.DistinctCount.High += bounds.RowCount.High - n
+		bounds.Note = "Sampled: the distinct count estimate counts values in the sample; the interval allows for unread rows"
+		if c.SampleMethod == SampleSystem || c.SampleMethod == "mixed" {
+			bounds.Note += ". Page sampling is clustered, so the intervals are optimistic for data ordered on disk"
+		}
+	}
+	return bounds
+}
+
+/* quantileEstimates reads quantiles off the digest, widening their rank error by the sampling error */
+func (c *ColumnSketch) quantileEstimates() []QuantileEstimate {
+	if c.Quantiles == nil || c.Quantiles.Count == 0 {
+		return nil
+	}
+	n := c.Quantiles.Count
+	f := math.Min(1, c.SampleFraction)
+	estimates := make([]QuantileEstimate, len(sketchQuantiles))
+	for i, q := range sketchQuantiles {
+		rankErr := c.Quantiles.RankError(q)
+		if f < 1 {
+			rankErr += confidenceZ * math.Sqrt(q*(1-q)/n*(1-f))
+		}
+		estimates[i] = QuantileEstimate{
+			Quantile: q,
+			Bound: Bound{
+				Estimate: c.Quantiles.Quantile(q),
+				Low:      c.Quantiles.Quantile(q - rankErr),
+				High:     c.Quantiles.Quantile(q + rankErr),
+			},
+			RankError: rankErr,
+		}
+	}
+	return estimates
+}
+
+/* heavyHitters scales the top values' counts to the whole table. Space-Saving counts are upper bounds
+ * that may overstate by their error; sampling adds a Poisson margin. */
+func (c *ColumnSketch) heavyHitters() []HeavyHitterEstimate {
+	f := math.Min(1, math.Max(c.SampleFraction, 1e-12))
+	top := c.TopK.Top(sketchTopValues)
+	estimates := make([]HeavyHitterEstimate, len(top))
+	for i, item := range top {
+		margin := 0.0
+		if f < 1 {
+			margin = confidenceZ * math.Sqrt(float64(item.Count)*(1-f)) / f
+		}
+		estimates[i] = HeavyHitterEstimate{
+			Value: item.Value,
+			Bound: Bound{
+				Estimate: float64(item.Count-item.Error/2) / f,
+				Low:      math.Max(0, float64(item.Count-item.Error)/f-margin),
+				High:     float64(item.Count)/f + margin,
+			},
+		}
+	}
+	return estimates
+}
+
+/* useSketch resolves the request's mode; auto sketches tables estimated at autoSketchRows rows or more */
+func (s *Service) useSketch(ctx context.Context, req ProfileRequest) bool {
+	switch req.Mode {
+	case ProfileModeSketch:
+		return true
+	case ProfileModeAuto:
+		rows, err := s.estimateRows(ctx, req)
+		return err == nil && rows >= autoSketchRows
+	default:
+		return false
+	}
+}
+
+/* estimateRows reads the planner's row estimate, which costs nothing on large tables */
+func (s *Service) estimateRows(ctx context.Context, req ProfileRequest) (float64, error) {
+	var rows float64
+	err := s.pool.QueryRow(ctx, `SELECT reltuples::float8 FROM pg_class WHERE oid = $1::text::regclass`,
+		pgx.Identifier{req.SchemaName, req.TableName}.Sanitize()).Scan(&rows)
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate row count: %w", err)
+	}
+	return math.Max(rows, 0), nil
+}
+
+/* estimateTableStatistics is getTableStatistics from the planner's estimate, for sketch mode */
+func (s *Service) estimateTableStatistics(ctx context.Context, req ProfileRequest) (*TableStatistics, error) {
+	rows, err := s.estimateRows(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	stats := &TableStatistics{RowCount: int64(rows)}
+	var sizeBytes sql.NullInt64
+	err = s.pool.QueryRow(ctx, `SELECT pg_total_relation_size($1::text::regclass)`,
+		pgx.Identifier{req.SchemaName, req.TableName}.Sanitize()).Scan(&sizeBytes)
+	if err == nil && sizeBytes.Valid {
+		stats.SizeBytes = &sizeBytes.Int64
+	}
+	return stats, nil
+}
+
+/* samplePlan chooses the sample method and percent of rows to read */
+func (s *Service) samplePlan(ctx context.Context, req ProfileRequest) (string, float64, error) {
+	method := req.SampleMethod
+	switch method {
+	case "":
+		method = SampleSystem
+	case SampleSystem, SampleBernoulli, SampleReservoir:
+	default:
+		return "", 0, fmt.Errorf("sample_method must be %s, %s or %s", SampleSystem, SampleBernoulli, SampleReservoir)
+	}
+	if method == SampleReservoir {
+		return method, 100, nil
+	}
+	if req.SamplePercent != nil {
+		if *req.SamplePercent <= 0 || *req.SamplePercent > 100 {
+			return "", 0, fmt.Errorf("sample_percent must be in (0, 100]")
+		}
+		return method, *req.SamplePercent, nil
+	}
+
+	target := float64(defaultSketchSample)
+	if req.SampleSize != nil && *req.SampleSize > 0 {
+		target = float64(*req.SampleSize)
+	}
+	rows, err := s.estimateRows(ctx, req)
+	if err != nil {
+		return "", 0, err
+	}
+	if rows <= target {
+		return method, 100, nil
+	}
+	return method, target / rows * 100, nil
+}
+
+/* sketchColumn reads a column, or one partition of it, into a sketch */
+func (s *Service) sketchColumn(ctx context.Context, req ProfileRequest) (*ColumnSketch, error) {
+	sketches, err := s.sketchColumns(ctx, req, []string{*req.ColumnName})
+	if err != nil {
+		return nil, err
+	}
+	return sketches[0], nil
+}
+
+/* sketchColumns reads columns of a table, or of one partition of it, into one sketch each. A single sample scan
+ * feeds every sketch, so profiling a table reads it once rather than once per column. */
+func (s *Service) sketchColumns(ctx context.Context, req ProfileRequest, columns []string) ([]*ColumnSketch, error) {
+	method, percent, err := s.samplePlan(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	selects := make([]string, len(columns))
+	for i, column := range columns {
+		selects[i] = pgx.Identifier{column}.Sanitize() + "::text"
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s`, strings.Join(selects, ", "), pgx.Identifier{req.SchemaName, req.TableName}.Sanitize())
+	args := []interface{}{}
+	if percent < 100 {
+		args = append(args, percent)
+		query += fmt.Sprintf(" TABLESAMPLE %s ($1)", map[string]string{SampleSystem: "SYSTEM", SampleBernoulli: "BERNOULLI"}[method])
+	}
+	if req.PartitionColumn != nil && req.PartitionValue != nil {
+		args = append(args, *req.PartitionValue)
+		query += fmt.Sprintf(" WHERE %s::text = $%d", pgx.Identifier{*req.PartitionColumn}.Sanitize(), len(args))
+	}
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	defer rows.Close()
+
+	sketches := make([]*ColumnSketch, len(columns))
+	for i := range columns {
+		colReq := req
+		colReq.ColumnName = &columns[i]
+		sketches[i] = newColumnSketch(colReq)
+		sketches[i].SampleMethod = method
+		sketches[i].SampleFraction = percent / 100
+	}
+	values := make([]sql.NullString, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("failed to read column values: %w", err)
+		}
+		for i, sketch := range sketches {
+			sketch.add(values[i], rng)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	for _, sketch := range sketches {
+		if sketch.Quantiles != nil && sketch.Quantiles.Count == 0 {
+			sketch.Quantiles = nil
+		}
+	}
+	return sketches, nil
+}
+
+/* profileColumnSketch profiles a column from sketches. A partition's sketch replaces that partition's
+ * stored sketch and the result merges every stored partition, so the profile grows as partitions arrive. */
+func (s *Service) profileColumnSketch(ctx context.Context, req ProfileRequest) (*ProfileResult, error) {
+	sketch, err := s.sketchColumn(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.profileFromSketch(ctx, req, sketch)
+}
+
+/* profileFromSketch stores a freshly read sketch and profiles the column from it, merged with the column's
+ * other partitions when the sketch is of one partition */
+func (s *Service) profileFromSketch(ctx context.Context, req ProfileRequest, sketch *ColumnSketch) (*ProfileResult, error) {
+	if err := s.storeSketch(ctx, sketch); err != nil {
+		return nil, err
+	}
+
+	if sketch.PartitionColumn != "" {
+		merged, err := s.mergedSketch(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		sketch = merged
+	}
+
+	result := s.sketchProfile(ctx, req, sketch)
+	if err := s.storeProfile(ctx, result); err != nil {
+		return nil, fmt.Errorf("failed to store profile: %w", err)
+	}
+	if err := s.storePatterns(ctx, result.ID, result.Patterns); err != nil {
+		return nil, fmt.Errorf("failed to store patterns: %w", err)
+	}
+	return result, nil
+}
+
+/* GetMergedSketchProfile profiles a column from its stored sketches without reading the table: the sketches
+ * of every partition of req.PartitionColumn, or the whole-table sketch when it is not given */
+func (s *Service) GetMergedSketchProfile(ctx context.Context, req ProfileRequest) (*ProfileResult, error) {
+	if req.ColumnName == nil {
+		return nil, fmt.Errorf("column_name required for column profiling")
+	}
+	sketch, err := s.mergedSketch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.sketchProfile(ctx, req, sketch), nil
+}
+
+/* sketchProfile builds a column profile from a sketch */
+func (s *Service) sketchProfile(ctx context.Context, req ProfileRequest, sketch *ColumnSketch) *ProfileResult {
+	bounds := sketch.errorBounds()
+	nullCount := int64(math.Round(bounds.NullFraction.Estimate * bounds.RowCount.Estimate))
+	nonNullCount := int64(math.Round(bounds.RowCount.Estimate)) - nullCount
+	distinct := int64(math.Round(bounds.DistinctCount.Estimate))
+	// The distinct count is of the values read, so its ratio is to the non-null rows read rather than the scaled-up count
+	sampleNonNull := sketch.RowsRead - sketch.NullCount
+
+	statistics := map[string]interface{}{
+		"mode":            ProfileModeSketch,
+		"sample_method":   sketch.SampleMethod,
+		"sample_fraction": sketch.SampleFraction,
+		"rows_read":       sketch.RowsRead,
+		"partitions":      sketch.Partitions,
+		"null_percentage": bounds.NullFraction.Estimate * 100,
+		"top_values":      sketch.heavyHitters(),
+		"error_bounds":    bounds,
+	}
+	if sampleNonNull > 0 {
+		statistics["distinct_percentage"] = math.Min(100, float64(distinct)/float64(sampleNonNull)*100)
+	}
+	if sketch.PartitionColumn != "" {
+		statistics["partition_column"] = sketch.PartitionColumn
+	}
+
+	stats := &ColumnStatistics{
+		Statistics:    statistics,
+		NullCount:     nullCount,
+		NonNullCount:  nonNullCount,
+		DistinctCount: &distinct,
+		MinValue:      sketch.MinValue,
+		MaxValue:      sketch.MaxValue,
+	}
+	if quantiles := sketch.quantileEstimates(); quantiles != nil {
+		statistics["quantiles"] = quantiles
+		statistics["histogram"] = sketch.Quantiles.Histogram(sketchHistogramBins)
+		median := sketch.Quantiles.Quantile(0.5)
+		stats.MedianValue = &median
+		mean := 0.0
+		for _, c := range sketch.Quantiles.Centroids {
+			mean += c.Mean * c.Count
+		}
+		mean /= sketch.Quantiles.Count
+		stats.AvgValue = &mean
+		// Sketch minimum and maximum are numeric, not the lexical text order
+		minValue := strconv.FormatFloat(sketch.Quantiles.Min, 'g', -1, 64)
+		maxValue := strconv.FormatFloat(sketch.Quantiles.Max, 'g', -1, 64)
+		stats.MinValue, stats.MaxValue = &minValue, &maxValue
+	}
+
+	patterns := matchPatterns(sketch.Reservoir)
+	dataType := s.detectDataType(ctx, req, stats, patterns)
+	samples := sketch.Reservoir
+	if len(samples) > 10 {
+		samples = samples[:10]
+	}
+
+	return &ProfileResult{
+		ID:            uuid.New(),
+		ConnectorID:   req.ConnectorID,
+		SchemaName:    req.SchemaName,
+		TableName:     req.TableName,
+		ColumnName:    req.ColumnName,
+		ProfileType:   "column",
+		Statistics:    stats.Statistics,
+		DataType:      &dataType,
+		NullCount:     stats.NullCount,
+		NonNullCount:  stats.NonNullCount,
+		DistinctCount: stats.DistinctCount,
+		MinValue:      stats.MinValue,
+		MaxValue:      stats.MaxValue,
+		AvgValue:      stats.AvgValue,
+		MedianValue:   stats.MedianValue,
+		Patterns:      patterns,
+		SampleValues:  samples,
+		ProfiledAt:    sketch.ProfiledAt,
+	}
+}
+
+/* storeSketch stores a sketch, replacing the stored sketch of the same column and partition */
+func (s *Service) storeSketch(ctx context.Context, sketch *ColumnSketch) error {
+	if sketch.Quantiles != nil {
+		sketch.Quantiles.flush()
+	}
+	quantilesJSON, _ := json.Marshal(sketch.Quantiles)
+	topKJSON, _ := json.Marshal(sketch.TopK)
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO neuronip.profile_sketches
+		(id, connector_id, schema_name, table_name, column_name, partition_column, partition_value,
+		 sample_method, sample_fraction, rows_read, null_count, numeric_count, min_value, max_value,
+		 hll, tdigest, top_k, reservoir, profiled_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		ON CONFLICT (connector_id, schema_name, table_name, column_name, partition_column, partition_value)
+		DO UPDATE SET
+			sample_method = EXCLUDED.sample_method,
+			sample_fraction = EXCLUDED.sample_fraction,
+			rows_read = EXCLUDED.rows_read,
+			null_count = EXCLUDED.null_count,
+			numeric_count = EXCLUDED.numeric_count,
+			min_value = EXCLUDED.min_value,
+			max_value = EXCLUDED.max_value,
+			hll = EXCLUDED.hll,
+			tdigest = EXCLUDED.tdigest,
+			top_k = EXCLUDED.top_k,
+			reservoir = EXCLUDED.reservoir,
+			profiled_at = EXCLUDED.profiled_at`,
+		sketch.ID, sketch.ConnectorID, sketch.SchemaName, sketch.TableName, sketch.ColumnName,
+		sketch.PartitionColumn, sketch.PartitionValue, sketch.SampleMethod, sketch.SampleFraction,
+		sketch.RowsRead, sketch.NullCount, sketch.NumericCount, sketch.MinValue, sketch.MaxValue,
+		sketch.Distinct.Registers, quantilesJSON, topKJSON, sketch.Reservoir, sketch.ProfiledAt)
+	if err != nil {
+		return fmt.Errorf("failed to store sketch: %w", err)
+	}
+	return nil
+}
+
+/* mergedSketch loads and merges the stored sketches of a column for the request's partition column */
+func (s *Service) mergedSketch(ctx context.Context, req ProfileRequest) (*ColumnSketch, error) {
+	partitionColumn := ""
+	if req.PartitionColumn != nil {
+		partitionColumn = *req.PartitionColumn
+	}
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, partition_value, sample_method, sample_fraction, rows_read, null_count, numeric_count,
+		       min_value, max_value, hll, tdigest, top_k, reservoir, profiled_at
+		FROM neuronip.profile_sketches
+		WHERE connector_id = $1 AND schema_name = $2 AND table_name = $3 AND column_name = $4 AND partition_column = $5
+		ORDER BY partition_value`,
+		req.ConnectorID, req.SchemaName, req.TableName, *req.ColumnName, partitionColumn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sketches: %w", err)
+	}
+	defer rows.Close()
+
+	var merged *ColumnSketch
+	for rows.Next() {
+		sketch := &ColumnSketch{
+			ConnectorID:     req.ConnectorID,
+			SchemaName:      req.SchemaName,
+			TableName:       req.TableName,
+			ColumnName:      *req.ColumnName,
+			PartitionColumn: partitionColumn,
+			Distinct:        &HyperLogLog{},
+			TopK:            &TopK{},
+			Partitions:      1,
+		}
+		var quantilesJSON, topKJSON []byte
+		err := rows.Scan(&sketch.ID, &sketch.PartitionValue, &sketch.SampleMethod, &sketch.SampleFraction,
+			&sketch.RowsRead, &sketch.NullCount, &sketch.NumericCount, &sketch.MinValue, &sketch.MaxValue,
+			&sketch.Distinct.Registers, &quantilesJSON, &topKJSON, &sketch.Reservoir, &sketch.ProfiledAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sketch: %w", err)
+		}
+		if quantilesJSON != nil {
+			if err := json.Unmarshal(quantilesJSON, &sketch.Quantiles); err != nil {
+				return nil, fmt.Errorf("failed to decode quantile sketch: %w", err)
+			}
+		}
+		if err := json.Unmarshal(topKJSON, sketch.TopK); err != nil {
+			return nil, fmt.Errorf("failed to decode top-k sketch: %w", err)
+		}
+
+		if merged == nil {
+			merged = sketch
+			continue
+		}
+		if err := merged.Merge(sketch); err != nil {
+			return nil, fmt.Errorf("failed to merge sketch of partition %s: %w", sketch.PartitionValue, err)
+		}
+	}
+	if merged == nil {
+		return nil, fmt.Errorf("no sketches stored for %s.%s.%s", req.SchemaName, req.TableName, *req.ColumnName)
+	}
+	return merged, nil
+}
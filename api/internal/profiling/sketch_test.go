@@ -0,0 +1,210 @@
+package profiling
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+/* TestHyperLogLog checks distinct estimates against the sketch's stated error */
+func TestHyperLogLog(t *testing.T) {
+	tests := []struct {
+		distinct int
+		repeats  int
+	}{
+		{distinct: 0},
+		{distinct: 1, repeats: 5},
+		{distinct: 100, repeats: 3},
+		{distinct: 10000, repeats: 2},
+		{distinct: 200000, repeats: 1},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.distinct), func(t *testing.T) {
+			h := NewHyperLogLog()
+			for r := 0; r < tt.repeats; r++ {
+				for i := 0; i < tt.distinct; i++ {
+					h.Add(fmt.Sprintf("value-%d", i))
+				}
+			}
+			got := h.Estimate()
+			// Four standard errors, with a little slack for very small counts
+			tolerance := 4*h.RelativeError()*float64(tt.distinct) + 1
+			if math.Abs(got-float64(tt.distinct)) > tolerance {
+				t.Fatalf("expected about %d distinct values, got %.0f", tt.distinct, got)
+			}
+		})
+	}
+}
+
+/* TestHyperLogLogMerge checks that merged sketches estimate the union */
+func TestHyperLogLogMerge(t *testing.T) {
+	a, b := NewHyperLogLog(), NewHyperLogLog()
+	for i := 0; i < 30000; i++ {
+		a.Add(fmt.Sprint(i))
+	}
+	for i := 20000; i < 50000; i++ {
+		b.Add(fmt.Sprint(i))
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Estimate(); math.Abs(got-50000) > 4*a.RelativeError()*50000 {
+		t.Fatalf("expected about 50000 distinct values in the union, got %.0f", got)
+	}
+	if err := a.Merge(&HyperLogLog{Registers: make([]byte, 16)}); err == nil {
+		t.Fatal("expected sketches of different precision not to merge")
+	}
+}
+
+/* TestTDigest checks quantiles and the CDF on known distributions, whole and merged from parts */
+func TestTDigest(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	uniform := make([]float64, 100000)
+	for i := range uniform {
+		uniform[i] = float64(i + 1)
+	}
+	rng.Shuffle(len(uniform), func(i, j int) { uniform[i], uniform[j] = uniform[j], uniform[i] })
+	normal := make([]float64, 100000)
+	for i := range normal {
+		normal[i] = rng.NormFloat64()*10 + 50
+	}
+
+	tests := []struct {
+		name   string
+		values []float64
+		parts  int
+		// The exact quantile function and CDF of the distribution
+		quantile func(q float64) float64
+		cdf      func(x float64) float64
+	}{
+		{name: "uniform", values: uniform, parts: 1, quantile: func(q float64) float64 { return q * 100000 }, cdf: func(x float64) float64 { return x / 100000 }},
+		{name: "uniform merged", values: uniform, parts: 8, quantile: func(q float64) float64 { return q * 100000 }, cdf: func(x float64) float64 { return x / 100000 }},
+		{name: "normal", values: normal, parts: 1, quantile: func(q float64) float64 { return 50 + 10*math.Sqrt2*math.Erfinv(2*q-1) }, cdf: func(x float64) float64 { return 0.5 * (1 + math.Erf((x-50)/(10*math.Sqrt2))) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			digest := NewTDigest(0)
+			size := len(tt.values) / tt.parts
+			for p := 0; p < tt.parts; p++ {
+				part := NewTDigest(0)
+				for _, v := range tt.values[p*size : (p+1)*size] {
+					part.Add(v)
+				}
+				digest.Merge(part)
+			}
+			if digest.Count != float64(len(tt.values)) {
+				t.Fatalf("expected %d values, got %.0f", len(tt.values), digest.Count)
+			}
+
+			for _, q := range []float64{0.001, 0.01, 0.25, 0.5, 0.75, 0.99, 0.999} {
+				got := digest.Quantile(q)
+				// Compare ranks rather than values, allowing twice the digest's stated rank error
+				rank := tt.cdf(got)
+				if allowed := 2*digest.RankError(q) + 0.0005; math.Abs(rank-q) > allowed {
+					t.Errorf("quantile %v: got %.4g (rank %.4f), want %.4g", q, got, rank, tt.quantile(q))
+				}
+				if cdf := digest.CDF(tt.quantile(q)); math.Abs(cdf-q) > 2*digest.RankError(q)+0.0005 {
+					t.Errorf("CDF at quantile %v: got %.4f", q, cdf)
+				}
+			}
+			if len(digest.Centroids) > int(digest.Compression) {
+				t.Errorf("expected at most %.0f centroids, got %d", digest.Compression, len(digest.Centroids))
+			}
+
+			total := 0.0
+			for _, bin := range digest.Histogram(10) {
+				total += bin.Count
+			}
+			if math.Abs(total-digest.Count) > 1e-6*digest.Count {
+				t.Errorf("expected histogram bins to add up to %.0f, got %.1f", digest.Count, total)
+			}
+		})
+	}
+}
+
+/* TestTDigestEdges checks empty and single-valued digests */
+func TestTDigestEdges(t *testing.T) {
+	empty := NewTDigest(0)
+	if !math.IsNaN(empty.Quantile(0.5)) || empty.Histogram(10) != nil {
+		t.Error("expected an empty digest to have no quantiles or histogram")
+	}
+	empty.Add(math.NaN())
+	empty.Add(math.Inf(1))
+	if empty.Count != 0 {
+		t.Errorf("expected NaN and infinite values to be skipped, got %.0f values", empty.Count)
+	}
+
+	single := NewTDigest(0)
+	for i := 0; i < 10; i++ {
+		single.Add(7)
+	}
+	if got := single.Quantile(0.9); got != 7 {
+		t.Errorf("expected 7, got %v", got)
+	}
+	if bins := single.Histogram(10); len(bins) != 1 || bins[0].Count != 10 {
+		t.Errorf("expected one bin of 10 values, got %+v", bins)
+	}
+}
+
+/* TestTopK checks that frequent values are found with counts that overstate by at most their error */
+func TestTopK(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	// A skewed stream where v0 is the most frequent value and frequency falls off as a power of rank
+	zipf := rand.NewZipf(rng, 1.2, 1, 9999)
+	stream := make([]string, 100000)
+	for i := range stream {
+		stream[i] = fmt.Sprintf("v%d", zipf.Uint64())
+	}
+	exact := make(map[string]int64)
+	for _, v := range stream {
+		exact[v]++
+	}
+
+	tests := []struct {
+		name  string
+		parts int
+	}{
+		{name: "single pass", parts: 1},
+		{name: "merged", parts: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			top := NewTopK(50)
+			size := len(stream) / tt.parts
+			for p := 0; p < tt.parts; p++ {
+				part := NewTopK(50)
+				for _, v := range stream[p*size : (p+1)*size] {
+					part.Add(v)
+				}
+				if p == 0 {
+					top = part
+				} else {
+					top.Merge(part)
+				}
+			}
+			if top.Total != int64(len(stream)) {
+				t.Fatalf("expected %d values, got %d", len(stream), top.Total)
+			}
+
+			kept := make(map[string]HeavyHitter)
+			for _, item := range top.Top(0) {
+				kept[item.Value] = item
+				if item.Count < exact[item.Value] || item.Count-item.Error > exact[item.Value] {
+					t.Errorf("%s: count %d with error %d does not bound the true count %d", item.Value, item.Count, item.Error, exact[item.Value])
+				}
+			}
+			// Values more frequent than Total/Capacity are guaranteed to be kept
+			for value, count := range exact {
+				if count > top.Total/int64(top.Capacity) {
+					if _, ok := kept[value]; !ok {
+						t.Errorf("%s appears %d times but was not kept", value, count)
+					}
+				}
+			}
+			if first := top.Top(1); len(first) != 1 || first[0].Value != "v0" {
+				t.Errorf("expected v0 to be the most frequent value, got %+v", first)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package profiling
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	snapshotPercentiles = 100 // Numeric columns keep the percentiles 0 to 100
+	snapshotCategories  = 50  // Columns keep the shares of at most this many of their most frequent values
+)
+
+/* ProfileSnapshot is what one ProfileTable run saw of a table, kept so runs can be compared for drift */
+type ProfileSnapshot struct {
+	ID          uuid.UUID        `json:"id"`
+	ProfileID   uuid.UUID        `json:"profile_id"`
+	ConnectorID uuid.UUID        `json:"connector_id"`
+	SchemaName  string           `json:"schema_name"`
+	TableName   string           `json:"table_name"`
+	Mode        string           `json:"mode"`
+	RowCount    int64            `json:"row_count"`
+	Columns     []ColumnSnapshot `json:"columns"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+/* ColumnSnapshot is a column's profile in a snapshot */
+type ColumnSnapshot struct {
+	ColumnName       string             `json:"column_name"`
+	DataType         string             `json:"data_type"`
+	NullRatio        float64            `json:"null_ratio"`
+	DistinctRatio    float64            `json:"distinct_ratio"`              // Distinct values over non-null rows
+	Patterns         map[string]float64 `json:"patterns,omitempty"`          // Match percentage per detected pattern type
+	Percentiles      []float64          `json:"percentiles,omitempty"`       // Percentiles 0 to 100 of numeric columns
+	Categories       map[string]float64 `json:"categories,omitempty"`        // Share of non-null rows of each of the most frequent values
+	CategoriesCapped bool               `json:"categories_capped,omitempty"` // Categories holds only the snapshotCategories most frequent values
+	Failed           bool               `json:"failed,omitempty"`            // The column could not be profiled in this run, so it is not compared
+	Error            string             `json:"error,omitempty"`
+}
+
+/* columnSnapshot captures a profiled column with its distribution; sketch mode reads the stored sketches
+ * the profile was just built from, exact mode queries the table */
+func (s *Service) columnSnapshot(ctx context.Context, req ProfileRequest, profile *ProfileResult) ColumnSnapshot {
+	snapshot := ColumnSnapshot{ColumnName: *req.ColumnName}
+	if profile.DataType != nil {
+		snapshot.DataType = *profile.DataType
+	}
+	if rows := profile.NullCount + profile.NonNullCount; rows > 0 {
+		snapshot.NullRatio = float64(profile.NullCount) / float64(rows)
+	}
+	// Take the profile's distinct percentage, which sketch mode computes against the rows it read
+	if pct, ok := profile.Statistics["distinct_percentage"].(float64); ok && !math.IsNaN(pct) && !math.IsInf(pct, 0) {
+		snapshot.DistinctRatio = pct / 100
+	} else if profile.DistinctCount != nil && profile.NonNullCount > 0 {
+		snapshot.DistinctRatio = float64(*profile.DistinctCount) / float64(profile.NonNullCount)
+	}
+	if len(profile.Patterns) > 0 {
+		snapshot.Patterns = map[string]float64{}
+		for _, p := range profile.Patterns {
+			snapshot.Patterns[p.PatternType] = p.MatchPercentage
+		}
+	}
+
+	if req.Mode == ProfileModeSketch {
+		sketch, err := s.mergedSketch(ctx, req)
+		if err != nil {
+			return snapshot
+		}
+		if sketch.Quantiles != nil && sketch.Quantiles.Count > 0 {
+			snapshot.Percentiles = make([]float64, snapshotPercentiles+1)
+			for i := range snapshot.Percentiles {
+				snapshot.Percentiles[i] = sketch.Quantiles.Quantile(float64(i) / snapshotPercentiles)
+			}
+		}
+		if nonNull := sketch.RowsRead - sketch.NullCount; nonNull > 0 {
+			snapshot.Categories = map[string]float64{}
+			for _, item := range sketch.TopK.Top(snapshotCategories) {
+				snapshot.Categories[item.Value] = float64(item.Count) / float64(nonNull)
+			}
+			snapshot.CategoriesCapped = profile.DistinctCount != nil && *profile.DistinctCount > snapshotCategories
+		}
+		return snapshot
+	}
+
+	column := pgx.Identifier{*req.ColumnName}.Sanitize()
+	table := pgx.Identifier{req.SchemaName, req.TableName}.Sanitize()
+	if profile.AvgValue != nil {
+		points := make([]float64, snapshotPercentiles+1)
+		for i := range points {
+			points[i] = float64(i) / snapshotPercentiles
+		}
+		var percentiles []float64
+		query := fmt.Sprintf(`SELECT percentile_cont($1::float8[]) WITHIN GROUP (ORDER BY %s::float8) FROM %s WHERE %s IS NOT NULL`,
+			column, table, column)
+		if err := s.pool.QueryRow(ctx, query, points).Scan(&percentiles); err == nil && len(percentiles) == len(points) {
+			snapshot.Percentiles = percentiles
+		}
+	}
+	if profile.NonNullCount > 0 {
+		// One value past the cap tells whether the list was cut
+		rows, err := s.pool.Query(ctx, fmt.Sprintf(`SELECT %s::text, COUNT(*) FROM %s WHERE %s IS NOT NULL GROUP BY 1 ORDER BY 2 DESC, 1 LIMIT %d`,
+			column, table, column, snapshotCategories+1))
+		if err == nil {
+			snapshot.Categories = map[string]float64{}
+			for rows.Next() {
+				var value string
+				var count int64
+				if rows.Scan(&value, &count) != nil {
+					continue
+				}
+				if len(snapshot.Categories) == snapshotCategories {
+					snapshot.CategoriesCapped = true
+					continue
+				}
+				snapshot.Categories[value] = float64(count) / float64(profile.NonNullCount)
+			}
+			rows.Close()
+		}
+	}
+	return snapshot
+}
+
+/* storeSnapshot records a ProfileTable run's snapshot */
+func (s *Service) storeSnapshot(ctx context.Context, snapshot *ProfileSnapshot) error {
+	columnsJSON, err := json.Marshal(snapshot.Columns)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot columns: %w", err)
+	}
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO neuronip.profile_snapshots
+		(id, profile_id, connector_id, schema_name, table_name, mode, row_count, columns, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		snapshot.ID, snapshot.ProfileID, snapshot.ConnectorID, snapshot.SchemaName, snapshot.TableName,
+		snapshot.Mode, snapshot.RowCount, columnsJSON, snapshot.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to store profile snapshot: %w", err)
+	}
+	return nil
+}
+
+/* GetSnapshot returns a profile snapshot */
+func (s *Service) GetSnapshot(ctx context.Context, id uuid.UUID) (*ProfileSnapshot, error) {
+	var snapshot ProfileSnapshot
+	var columnsJSON []byte
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, profile_id, connector_id, schema_name, table_name, mode, row_count, columns, created_at
+		FROM neuronip.profile_snapshots
+		WHERE id = $1`, id).Scan(&snapshot.ID, &snapshot.ProfileID, &snapshot.ConnectorID, &snapshot.SchemaName,
+		&snapshot.TableName, &snapshot.Mode, &snapshot.RowCount, &columnsJSON, &snapshot.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile snapshot: %w", err)
+	}
+	if err := json.Unmarshal(columnsJSON, &snapshot.Columns); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot columns: %w", err)
+	}
+	return &snapshot, nil
+}
+
+/* ListSnapshots lists a table's snapshots, newest first, without their columns */
+func (s *Service) ListSnapshots(ctx context.Context, connectorID uuid.UUID, schemaName, tableName string, limit int) ([]ProfileSnapshot, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, profile_id, connector_id, schema_name, table_name, mode, row_count, created_at
+		FROM neuronip.profile_snapshots
+		WHERE connector_id = $1 AND schema_name = $2 AND table_name = $3
+		ORDER BY created_at DESC
+		LIMIT $4`, connectorID, schemaName, tableName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list profile snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []ProfileSnapshot
+	for rows.Next() {
+		var snapshot ProfileSnapshot
+		if err := rows.Scan(&snapshot.ID, &snapshot.ProfileID, &snapshot.ConnectorID, &snapshot.SchemaName,
+			&snapshot.TableName, &snapshot.Mode, &snapshot.RowCount, &snapshot.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan profile snapshot: %w", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, rows.Err()
+}
+
+/* latestSnapshotIDs returns the IDs of a table's two newest snapshots, older first */
+func (s *Service) latestSnapshotIDs(ctx context.Context, connectorID uuid.UUID, schemaName, tableName string) (uuid.UUID, uuid.UUID, error) {
+	snapshots, err := s.ListSnapshots(ctx, connectorID, schemaName, tableName, 2)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	if len(snapshots) < 2 {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("%s.%s needs two profile snapshots to compare, has %d", schemaName, tableName, len(snapshots))
+	}
+	return snapshots[1].ID, snapshots[0].ID, nil
+}
@@ -0,0 +1,657 @@
+package profiling
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultStructureSample = 50_000
+	defaultDependencyError = 0.01 // Largest g3 error of an approximate functional dependency
+	defaultMinCoverage     = 0.99 // Smallest share of a column's values found in a key to suggest a join
+	maxInclusionValues     = 10_000
+	maxInclusionChecks     = 200
+)
+
+/* Suggested join review statuses */
+const (
+	JoinSuggested = "suggested"
+	JoinAccepted  = "accepted"
+	JoinRejected  = "rejected"
+)
+
+/* StructureRequest selects a table for structure discovery; zero values take the defaults */
+type StructureRequest struct {
+	ConnectorID        uuid.UUID
+	SchemaName         string
+	TableName          string
+	SampleSize         int     `json:"sample_size"`
+	MaxKeySize         int     `json:"max_key_size"` // 1 to 3 columns, default 3
+	MaxDependencyError float64 `json:"max_dependency_error"`
+	MinCoverage        float64 `json:"min_coverage"`
+}
+
+/* CandidateKey is a minimal column set unique and non-null in the sample */
+type CandidateKey struct {
+	Columns  []string `json:"columns"`
+	Verified bool     `json:"verified"` // Unique and non-null over the whole table, not only the sample
+	Declared bool     `json:"declared"` // The catalog's primary key
+}
+
+/* FunctionalDependency is Determinant → Dependent holding on all but Error of the sample rows */
+type FunctionalDependency struct {
+	Determinant []string `json:"determinant"`
+	Dependent   string   `json:"dependent"`
+	Error       float64  `json:"error"`
+	Exact       bool     `json:"exact"`
+}
+
+/* InclusionDependency is a column whose values are found in another table's key, suggesting a foreign key */
+type InclusionDependency struct {
+	Column           string  `json:"column"`
+	ReferencedSchema string  `json:"referenced_schema"`
+	ReferencedTable  string  `json:"referenced_table"`
+	ReferencedColumn string  `json:"referenced_column"`
+	Coverage         float64 `json:"coverage"`
+	DistinctValues   int     `json:"distinct_values"` // Distinct values of the column checked
+	Confidence       float64 `json:"confidence"`
+}
+
+/* CorrelationMatrix holds pairwise pearson, spearman or cramers_v values; nil where undefined */
+type CorrelationMatrix struct {
+	Method  string       `json:"method"`
+	Columns []string     `json:"columns"`
+	Values  [][]*float64 `json:"values"`
+}
+
+/* TableStructure is the result of structure discovery on a table */
+type TableStructure struct {
+	ID                     uuid.UUID              `json:"id"`
+	ConnectorID            uuid.UUID              `json:"connector_id"`
+	SchemaName             string                 `json:"schema_name"`
+	TableName              string                 `json:"table_name"`
+	SampleRows             int                    `json:"sample_rows"`
+	SampleFraction         float64                `json:"sample_fraction"`
+	CandidateKeys          []CandidateKey         `json:"candidate_keys"`
+	FunctionalDependencies []FunctionalDependency `json:"functional_dependencies"`
+	InclusionDependencies  []InclusionDependency  `json:"inclusion_dependencies"`
+	Correlations           []CorrelationMatrix    `json:"correlations"`
+	ConstantColumns        []string               `json:"constant_columns"`
+	DiscoveredAt           time.Time              `json:"discovered_at"`
+}
+
+/* SuggestedJoin is a join path suggested by an inclusion dependency */
+type SuggestedJoin struct {
+	ID           uuid.UUID              `json:"id"`
+	ConnectorID  uuid.UUID              `json:"connector_id"`
+	FromSchema   string                 `json:"from_schema"`
+	FromTable    string                 `json:"from_table"`
+	FromColumn   string                 `json:"from_column"`
+	ToSchema     string                 `json:"to_schema"`
+	ToTable      string                 `json:"to_table"`
+	ToColumn     string                 `json:"to_column"`
+	Coverage     float64                `json:"coverage"`
+	Confidence   float64                `json:"confidence"`
+	Status       string                 `json:"status"`
+	Evidence     map[string]interface{} `json:"evidence,omitempty"`
+	ReviewedBy   *string                `json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time             `json:"reviewed_at,omitempty"`
+	DiscoveredAt time.Time              `json:"discovered_at"`
+}
+
+/* DiscoverStructure samples a table and finds its candidate keys, functional dependencies, inclusion
+ * dependencies into other tables' keys, and correlations. The result replaces the table's stored structure;
+ * suggested keys go to the catalog table's metadata and inclusion dependencies become suggested joins. */
+func (s *Service) DiscoverStructure(ctx context.Context, req StructureRequest) (*TableStructure, error) {
+	if req.SampleSize <= 0 {
+		req.SampleSize = defaultStructureSample
+	}
+	if req.MaxKeySize <= 0 || req.MaxKeySize > 3 {
+		req.MaxKeySize = 3
+	}
+	if req.MaxDependencyError <= 0 {
+		req.MaxDependencyError = defaultDependencyError
+	}
+	if req.MinCoverage <= 0 || req.MinCoverage > 1 {
+		req.MinCoverage = defaultMinCoverage
+	}
+
+	columnTypes, err := s.relationColumns(ctx, req.SchemaName, req.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if len(columnTypes) == 0 {
+		return nil, fmt.Errorf("table %s.%s has no columns", req.SchemaName, req.TableName)
+	}
+	cols, rows, fraction, err := s.loadStructureSample(ctx, req, columnTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	structure := &TableStructure{
+		ID:             uuid.New(),
+		ConnectorID:    req.ConnectorID,
+		SchemaName:     req.SchemaName,
+		TableName:      req.TableName,
+		SampleRows:     rows,
+		SampleFraction: fraction,
+		DiscoveredAt:   time.Now(),
+	}
+	for _, c := range cols {
+		if c.constant() {
+			structure.ConstantColumns = append(structure.ConstantColumns, c.name)
+		}
+	}
+
+	if rows > 0 {
+		keys := findCandidateKeys(cols, rows, req.MaxKeySize)
+		declared := s.declaredKey(ctx, req.ConnectorID, req.SchemaName, req.TableName)
+		for _, key := range keys {
+			structure.CandidateKeys = append(structure.CandidateKeys, CandidateKey{
+				Columns:  key,
+				Verified: fraction >= 1 || s.verifyKey(ctx, req, key),
+				Declared: sameColumns(key, declared),
+			})
+		}
+		structure.FunctionalDependencies = findDependencies(cols, rows, keys, req.MaxDependencyError)
+		structure.Correlations = correlationMatrices(cols)
+		structure.InclusionDependencies = s.findInclusionDependencies(ctx, req, cols, columnTypes)
+	}
+
+	if err := s.storeStructure(ctx, structure); err != nil {
+		return nil, err
+	}
+	return structure, nil
+}
+
+/* relationColumn is a column of a table with its SQL type */
+type relationColumn struct {
+	name     string
+	dataType string
+}
+
+/* relationColumns reads a table's columns and types from the database catalog */
+func (s *Service) relationColumns(ctx context.Context, schemaName, tableName string) ([]relationColumn, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT attname, format_type(atttypid, atttypmod)
+		FROM pg_attribute
+		WHERE attrelid = $1::text::regclass AND attnum > 0 AND NOT attisdropped
+		ORDER BY attnum`, pgx.Identifier{schemaName, tableName}.Sanitize())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []relationColumn
+	for rows.Next() {
+		var c relationColumn
+		if err := rows.Scan(&c.name, &c.dataType); err != nil {
+			return nil, fmt.Errorf("failed to scan table column: %w", err)
+		}
+		columns = append(columns, c)
+	}
+	return columns, rows.Err()
+}
+
+/* loadStructureSample reads up to SampleSize rows, a bernoulli sample when the table is estimated larger,
+ * and returns the encoded columns, the rows read and the fraction of the table they are */
+func (s *Service) loadStructureSample(ctx context.Context, req StructureRequest, columnTypes []relationColumn) ([]*sampleColumn, int, float64, error) {
+	selects := make([]string, len(columnTypes))
+	for i, c := range columnTypes {
+		selects[i] = pgx.Identifier{c.name}.Sanitize() + "::text"
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s`, strings.Join(selects, ", "), pgx.Identifier{req.SchemaName, req.TableName}.Sanitize())
+
+	estimate, err := s.estimateRows(ctx, ProfileRequest{SchemaName: req.SchemaName, TableName: req.TableName})
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	sampled := estimate > float64(req.SampleSize)
+	args := []interface{}{}
+	if sampled {
+		// Oversample slightly so the limit, not the sample, decides the row count
+		args = append(args, math.Min(100, float64(req.SampleSize)/estimate*110))
+		query += " TABLESAMPLE BERNOULLI ($1)"
+	}
+	args = append(args, req.SampleSize+1)
+	query += fmt.Sprintf(" LIMIT $%d", len(args))
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to sample table: %w", err)
+	}
+	defer rows.Close()
+
+	raw := make([][]sql.NullString, len(columnTypes))
+	n := 0
+	for rows.Next() {
+		if n == req.SampleSize {
+			sampled = true
+			break
+		}
+		values := make([]sql.NullString, len(columnTypes))
+		dest := make([]interface{}, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to read sample row: %w", err)
+		}
+		for i, v := range values {
+			raw[i] = append(raw[i], v)
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to sample table: %w", err)
+	}
+
+	cols := make([]*sampleColumn, len(columnTypes))
+	for i, c := range columnTypes {
+		cols[i] = encodeColumn(c.name, raw[i])
+	}
+	fraction := 1.0
+	if sampled && estimate > 0 {
+		fraction = math.Min(1, float64(n)/estimate)
+	}
+	if sampled && fraction >= 1 {
+		fraction = math.Nextafter(1, 0) // Cut short by the limit, so not the whole table
+	}
+	return cols, n, fraction, nil
+}
+
+/* verifyKey checks a sample key over the whole table, on tables small enough to scan */
+func (s *Service) verifyKey(ctx context.Context, req StructureRequest, key []string) bool {
+	rows, err := s.estimateRows(ctx, ProfileRequest{SchemaName: req.SchemaName, TableName: req.TableName})
+	if err != nil || rows >= autoSketchRows {
+		return false
+	}
+	columns := make([]string, len(key))
+	nulls := make([]string, len(key))
+	for i, k := range key {
+		columns[i] = pgx.Identifier{k}.Sanitize()
+		nulls[i] = columns[i] + " IS NULL"
+	}
+	table := pgx.Identifier{req.SchemaName, req.TableName}.Sanitize()
+	query := fmt.Sprintf(`
+		SELECT NOT EXISTS (SELECT 1 FROM %s WHERE %s)
+			AND NOT EXISTS (SELECT 1 FROM %s GROUP BY %s HAVING COUNT(*) > 1)`,
+		table, strings.Join(nulls, " OR "), table, strings.Join(columns, ", "))
+	var unique bool
+	if err := s.pool.QueryRow(ctx, query).Scan(&unique); err != nil {
+		return false
+	}
+	return unique
+}
+
+/* declaredKey returns the catalog's primary key columns of a table */
+func (s *Service) declaredKey(ctx context.Context, connectorID uuid.UUID, schemaName, tableName string) []string {
+	rows, err := s.pool.Query(ctx, `
+		SELECT cc.column_name
+		FROM neuronip.catalog_columns cc
+		JOIN neuronip.catalog_tables ct ON ct.id = cc.table_id
+		WHERE ct.connector_id = $1 AND ct.schema_name = $2 AND ct.table_name = $3 AND cc.is_primary_key
+		ORDER BY cc.ordinal_position`, connectorID, schemaName, tableName)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err == nil {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := map[string]bool{}
+	for _, c := range a {
+		set[c] = true
+	}
+	for _, c := range b {
+		if !set[c] {
+			return false
+		}
+	}
+	return true
+}
+
+/* referencedKey is a single-column key of a table that other tables' columns may reference */
+type referencedKey struct {
+	schemaName string
+	tableName  string
+	column     string
+	dataType   string
+}
+
+/* referencedKeys lists the connector's single-column keys: declared primary keys and keys found by earlier
+ * structure discovery, when verified over the whole table rather than only unique in a sample.
+ * A table's columns can only be matched to keys of tables already discovered or declared. */
+func (s *Service) referencedKeys(ctx context.Context, connectorID uuid.UUID) ([]referencedKey, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT ct.schema_name, ct.table_name, MIN(cc.column_name)
+		FROM neuronip.catalog_columns cc
+		JOIN neuronip.catalog_tables ct ON ct.id = cc.table_id
+		WHERE ct.connector_id = $1 AND cc.is_primary_key
+		GROUP BY ct.schema_name, ct.table_name
+		HAVING COUNT(*) = 1
+		UNION
+		SELECT ts.schema_name, ts.table_name, k->'columns'->>0
+		FROM neuronip.table_structures ts, jsonb_array_elements(ts.candidate_keys) k
+		WHERE ts.connector_id = $1 AND jsonb_array_length(k->'columns') = 1
+		  AND ((k->>'verified')::boolean OR (k->>'declared')::boolean)`, connectorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list table keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []referencedKey
+	for rows.Next() {
+		var k referencedKey
+		if err := rows.Scan(&k.schemaName, &k.tableName, &k.column); err != nil {
+			return nil, fmt.Errorf("failed to scan table key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list table keys: %w", err)
+	}
+
+	// Types come from the database, since catalog column types are the source system's names
+	types := map[string][]relationColumn{}
+	typed := keys[:0]
+	for _, k := range keys {
+		table := k.schemaName + "." + k.tableName
+		if _, ok := types[table]; !ok {
+			types[table], _ = s.relationColumns(ctx, k.schemaName, k.tableName)
+		}
+		for _, c := range types[table] {
+			if c.name == k.column {
+				k.dataType = c.dataType
+				typed = append(typed, k)
+				break
+			}
+		}
+	}
+	return typed, nil
+}
+
+/* typeFamily groups SQL types whose values can be compared */
+func typeFamily(dataType string) string {
+	t := strings.ToLower(dataType)
+	switch {
+	case t == "smallint" || t == "integer" || t == "bigint":
+		return "integer"
+	case strings.HasPrefix(t, "numeric"):
+		return "numeric"
+	case t == "text" || strings.HasPrefix(t, "character"):
+		return "text"
+	default:
+		return t
+	}
+}
+
+/* findInclusionDependencies checks each column's sampled distinct values against the connector's keys of
+ * the same type family; columns found in a key at MinCoverage or more suggest a foreign key */
+func (s *Service) findInclusionDependencies(ctx context.Context, req StructureRequest, cols []*sampleColumn, columnTypes []relationColumn) []InclusionDependency {
+	keys, err := s.referencedKeys(ctx, req.ConnectorID)
+	if err != nil {
+		return nil
+	}
+
+	var deps []InclusionDependency
+	checks := 0
+	for i, c := range cols {
+		if c.distinct < 2 {
+			continue
+		}
+		family := typeFamily(columnTypes[i].dataType)
+		values := c.values
+		if len(values) > maxInclusionValues {
+			values = values[:maxInclusionValues]
+		}
+		for _, k := range keys {
+			if k.schemaName == req.SchemaName && k.tableName == req.TableName && k.column == c.name {
+				continue
+			}
+			if typeFamily(k.dataType) != family {
+				continue
+			}
+			if checks == maxInclusionChecks {
+				return deps
+			}
+			checks++
+
+			var missing int
+			query := fmt.Sprintf(`
+				SELECT COUNT(*) FROM unnest($1::text[]) AS v(value)
+				WHERE NOT EXISTS (SELECT 1 FROM %s r WHERE r.%s = v.value::%s)`,
+				pgx.Identifier{k.schemaName, k.tableName}.Sanitize(), pgx.Identifier{k.column}.Sanitize(), k.dataType)
+			if err := s.pool.QueryRow(ctx, query, values).Scan(&missing); err != nil {
+				continue
+			}
+			coverage := 1 - float64(missing)/float64(len(values))
+			if coverage < req.MinCoverage {
+				continue
+			}
+			deps = append(deps, InclusionDependency{
+				Column:           c.name,
+				ReferencedSchema: k.schemaName,
+				ReferencedTable:  k.tableName,
+				ReferencedColumn: k.column,
+				Coverage:         coverage,
+				DistinctValues:   len(values),
+				Confidence:       joinConfidence(c.name, k, coverage, len(values)),
+			})
+		}
+	}
+	return deps
+}
+
+/* joinConfidence discounts coverage when the names don't suggest a reference, since small integer columns
+ * are included in many keys by chance, and when there are few distinct values to go on */
+func joinConfidence(column string, key referencedKey, coverage float64, distinct int) float64 {
+	confidence := coverage
+	col := strings.ToLower(column)
+	table := strings.TrimSuffix(strings.ToLower(key.tableName), "s")
+	ref := strings.ToLower(key.column)
+	if col != ref && col != table+"_"+ref && col != table+ref {
+		confidence *= 0.7
+	}
+	if distinct < 20 {
+		confidence *= float64(distinct) / 20
+	}
+	return confidence
+}
+
+/* storeStructure replaces the table's stored structure and suggested joins, keeping joins already reviewed,
+ * and records suggested keys and references in the catalog's metadata */
+func (s *Service) storeStructure(ctx context.Context, structure *TableStructure) error {
+	keysJSON, _ := json.Marshal(structure.CandidateKeys)
+	depsJSON, _ := json.Marshal(structure.FunctionalDependencies)
+	inclusionJSON, _ := json.Marshal(structure.InclusionDependencies)
+	correlationsJSON, _ := json.Marshal(structure.Correlations)
+	constants := structure.ConstantColumns
+	if constants == nil {
+		constants = []string{}
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO neuronip.table_structures
+		(id, connector_id, schema_name, table_name, sample_rows, sample_fraction, candidate_keys,
+		 functional_dependencies, inclusion_dependencies, correlations, constant_columns, discovered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (connector_id, schema_name, table_name) DO UPDATE SET
+			sample_rows = EXCLUDED.sample_rows,
+			sample_fraction = EXCLUDED.sample_fraction,
+			candidate_keys = EXCLUDED.candidate_keys,
+			functional_dependencies = EXCLUDED.functional_dependencies,
+			inclusion_dependencies = EXCLUDED.inclusion_dependencies,
+			correlations = EXCLUDED.correlations,
+			constant_columns = EXCLUDED.constant_columns,
+			discovered_at = EXCLUDED.discovered_at
+		RETURNING id`,
+		structure.ID, structure.ConnectorID, structure.SchemaName, structure.TableName, structure.SampleRows,
+		structure.SampleFraction, keysJSON, depsJSON, inclusionJSON, correlationsJSON, constants,
+		structure.DiscoveredAt).Scan(&structure.ID)
+	if err != nil {
+		return fmt.Errorf("failed to store table structure: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM neuronip.suggested_joins
+		WHERE connector_id = $1 AND from_schema = $2 AND from_table = $3 AND status = $4`,
+		structure.ConnectorID, structure.SchemaName, structure.TableName, JoinSuggested)
+	if err != nil {
+		return fmt.Errorf("failed to clear suggested joins: %w", err)
+	}
+	for _, dep := range structure.InclusionDependencies {
+		evidence, _ := json.Marshal(map[string]interface{}{
+			"structure_id":    structure.ID,
+			"distinct_values": dep.DistinctValues,
+			"sample_rows":     structure.SampleRows,
+		})
+		_, err = tx.Exec(ctx, `
+			INSERT INTO neuronip.suggested_joins
+			(connector_id, from_schema, from_table, from_column, to_schema, to_table, to_column, coverage, confidence, evidence)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT (connector_id, from_schema, from_table, from_column, to_schema, to_table, to_column) DO UPDATE SET
+				coverage = EXCLUDED.coverage,
+				confidence = EXCLUDED.confidence,
+				evidence = EXCLUDED.evidence,
+				discovered_at = NOW()`,
+			structure.ConnectorID, structure.SchemaName, structure.TableName, dep.Column,
+			dep.ReferencedSchema, dep.ReferencedTable, dep.ReferencedColumn, dep.Coverage, dep.Confidence, evidence)
+		if err != nil {
+			return fmt.Errorf("failed to store suggested join: %w", err)
+		}
+	}
+
+	// Catalog entries may not exist for tables outside a connector sync, so these update nothing then
+	_, err = tx.Exec(ctx, `
+		UPDATE neuronip.catalog_tables
+		SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('suggested_keys', $4::jsonb)
+		WHERE connector_id = $1 AND schema_name = $2 AND table_name = $3`,
+		structure.ConnectorID, structure.SchemaName, structure.TableName, keysJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update catalog table: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE neuronip.catalog_columns cc
+		SET metadata = COALESCE(cc.metadata, '{}'::jsonb) - 'suggested_references' || COALESCE((
+			SELECT jsonb_build_object('suggested_references', jsonb_agg(jsonb_build_object(
+				'schema', sj.to_schema, 'table', sj.to_table, 'column', sj.to_column,
+				'confidence', sj.confidence, 'status', sj.status)))
+			FROM neuronip.suggested_joins sj
+			WHERE sj.connector_id = ct.connector_id AND sj.from_schema = ct.schema_name
+				AND sj.from_table = ct.table_name AND sj.from_column = cc.column_name AND sj.status <> $4
+		), '{}'::jsonb)
+		FROM neuronip.catalog_tables ct
+		WHERE ct.id = cc.table_id AND ct.connector_id = $1 AND ct.schema_name = $2 AND ct.table_name = $3`,
+		structure.ConnectorID, structure.SchemaName, structure.TableName, JoinRejected)
+	if err != nil {
+		return fmt.Errorf("failed to update catalog columns: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+/* GetTableStructure returns a table's stored structure */
+func (s *Service) GetTableStructure(ctx context.Context, connectorID uuid.UUID, schemaName, tableName string) (*TableStructure, error) {
+	var structure TableStructure
+	var keysJSON, depsJSON, inclusionJSON, correlationsJSON []byte
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, connector_id, schema_name, table_name, sample_rows, sample_fraction, candidate_keys,
+			functional_dependencies, inclusion_dependencies, correlations, constant_columns, discovered_at
+		FROM neuronip.table_structures
+		WHERE connector_id = $1 AND schema_name = $2 AND table_name = $3`,
+		connectorID, schemaName, tableName).Scan(&structure.ID, &structure.ConnectorID, &structure.SchemaName,
+		&structure.TableName, &structure.SampleRows, &structure.SampleFraction, &keysJSON, &depsJSON,
+		&inclusionJSON, &correlationsJSON, &structure.ConstantColumns, &structure.DiscoveredAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table structure: %w", err)
+	}
+	json.Unmarshal(keysJSON, &structure.CandidateKeys)
+	json.Unmarshal(depsJSON, &structure.FunctionalDependencies)
+	json.Unmarshal(inclusionJSON, &structure.InclusionDependencies)
+	json.Unmarshal(correlationsJSON, &structure.Correlations)
+	return &structure, nil
+}
+
+/* ListSuggestedJoins lists suggested joins, optionally of one connector, touching one schema, or in one status */
+func (s *Service) ListSuggestedJoins(ctx context.Context, connectorID *uuid.UUID, schemaName, status string) ([]SuggestedJoin, error) {
+	query := `
+		SELECT id, connector_id, from_schema, from_table, from_column, to_schema, to_table, to_column,
+			coverage, confidence, status, evidence, reviewed_by, reviewed_at, discovered_at
+		FROM neuronip.suggested_joins
+		WHERE ($1::uuid IS NULL OR connector_id = $1)
+			AND ($2 = '' OR from_schema = $2 OR to_schema = $2)
+			AND ($3 = '' OR status = $3)
+		ORDER BY confidence DESC, from_schema, from_table, from_column`
+
+	rows, err := s.pool.Query(ctx, query, connectorID, schemaName, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suggested joins: %w", err)
+	}
+	defer rows.Close()
+
+	var joins []SuggestedJoin
+	for rows.Next() {
+		var j SuggestedJoin
+		var evidenceJSON []byte
+		err := rows.Scan(&j.ID, &j.ConnectorID, &j.FromSchema, &j.FromTable, &j.FromColumn, &j.ToSchema,
+			&j.ToTable, &j.ToColumn, &j.Coverage, &j.Confidence, &j.Status, &evidenceJSON, &j.ReviewedBy,
+			&j.ReviewedAt, &j.DiscoveredAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan suggested join: %w", err)
+		}
+		json.Unmarshal(evidenceJSON, &j.Evidence)
+		joins = append(joins, j)
+	}
+	return joins, rows.Err()
+}
+
+/* ReviewSuggestedJoin accepts or rejects a suggested join; rejected joins are kept out of later suggestions */
+func (s *Service) ReviewSuggestedJoin(ctx context.Context, id uuid.UUID, status, reviewer string) (*SuggestedJoin, error) {
+	if status != JoinAccepted && status != JoinRejected && status != JoinSuggested {
+		return nil, fmt.Errorf("status must be %s, %s or %s", JoinSuggested, JoinAccepted, JoinRejected)
+	}
+
+	var j SuggestedJoin
+	var evidenceJSON []byte
+	err := s.pool.QueryRow(ctx, `
+		UPDATE neuronip.suggested_joins
+		SET status = $2, reviewed_by = NULLIF($3, ''), reviewed_at = NOW()
+		WHERE id = $1
+		RETURNING id, connector_id, from_schema, from_table, from_column, to_schema, to_table, to_column,
+			coverage, confidence, status, evidence, reviewed_by, reviewed_at, discovered_at`,
+		id, status, reviewer).Scan(&j.ID, &j.ConnectorID, &j.FromSchema, &j.FromTable, &j.FromColumn,
+		&j.ToSchema, &j.ToTable, &j.ToColumn, &j.Coverage, &j.Confidence, &j.Status, &evidenceJSON,
+		&j.ReviewedBy, &j.ReviewedAt, &j.DiscoveredAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to review suggested join: %w", err)
+	}
+	json.Unmarshal(evidenceJSON, &j.Evidence)
+	return &j, nil
+}
@@ -0,0 +1,408 @@
+package rag
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/neurondb/NeuronIP/api/internal/logging"
+)
+
+const (
+	/* conversationHistoryBudget is the size in characters of conversation history sent to the model */
+	conversationHistoryBudget = 6000
+	/* recentTurnsKept is the number of latest turns kept verbatim; older turns are folded into the summary */
+	recentTurnsKept = 4
+	/* turnAnswerExcerpt is the number of characters of each prior answer included in the history */
+	turnAnswerExcerpt = 600
+	/* maxTurnInsertAttempts bounds the retries of a turn insert that raced another turn for its index */
+	maxTurnInsertAttempts = 3
+)
+
+/* Conversation is a multi-turn RAG session */
+type Conversation struct {
+	ID              uuid.UUID          `json:"id"`
+	UserID          *string            `json:"user_id,omitempty"`
+	CollectionID    *string            `json:"collection_id,omitempty"`
+	Summary         string             `json:"summary,omitempty"`
+	SummarizedTurns int                `json:"summarized_turns"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+	Turns           []ConversationTurn `json:"turns,omitempty"`
+}
+
+/* ConversationTurn is one question and answer of a conversation */
+type ConversationTurn struct {
+	ID                uuid.UUID   `json:"id"`
+	TurnIndex         int         `json:"turn_index"`
+	Question          string      `json:"question"`
+	RewrittenQuery    string      `json:"rewritten_query"`
+	Answer            string      `json:"answer"`
+	SourceDocumentIDs []uuid.UUID `json:"source_document_ids,omitempty"`
+	ContextKeys       []string    `json:"-"`
+	CreatedAt         time.Time   `json:"created_at"`
+}
+
+/* conversationTurn carries conversation state through one pipeline execution */
+type conversationTurn struct {
+	conversation *Conversation
+	question     string
+	rewritten    string
+	seenContext  map[string]bool
+	contextKeys  []string
+}
+
+/* CreateConversation starts a new RAG conversation */
+func (s *UnifiedRAGService) CreateConversation(ctx context.Context, userID *string, collectionID *string) (*Conversation, error) {
+	if s.pool == nil {
+		return nil, fmt.Errorf("conversations are not enabled")
+	}
+
+	var conv Conversation
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO neuronip.rag_conversations (user_id, collection_id)
+		VALUES ($1, $2)
+		RETURNING id, user_id, collection_id, summary, summarized_turns, created_at, updated_at`,
+		userID, collectionID).Scan(&conv.ID, &conv.UserID, &conv.CollectionID, &conv.Summary,
+		&conv.SummarizedTurns, &conv.CreatedAt, &conv.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create conversation: %w", err)
+	}
+	return &conv, nil
+}
+
+/* GetConversation loads a conversation with its turns; conversations owned by another user are not found */
+func (s *UnifiedRAGService) GetConversation(ctx context.Context, conversationID uuid.UUID, userID *string) (*Conversation, error) {
+	if s.pool == nil {
+		return nil, fmt.Errorf("conversations are not enabled")
+	}
+
+	var conv Conversation
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, user_id, collection_id, summary, summarized_turns, created_at, updated_at
+		FROM neuronip.rag_conversations
+		WHERE id = $1`, conversationID).Scan(&conv.ID, &conv.UserID, &conv.CollectionID, &conv.Summary,
+		&conv.SummarizedTurns, &conv.CreatedAt, &conv.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("conversation not found: %s", conversationID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	if conv.UserID != nil && (userID == nil || *userID != *conv.UserID) {
+		return nil, fmt.Errorf("conversation not found: %s", conversationID)
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, turn_index, question, rewritten_query, answer,
+		       COALESCE(source_document_ids, '{}'), COALESCE(context_keys, '{}'), created_at
+		FROM neuronip.rag_conversation_turns
+		WHERE conversation_id = $1
+		ORDER BY turn_index`, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation turns: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var turn ConversationTurn
+		if err := rows.Scan(&turn.ID, &turn.TurnIndex, &turn.Question, &turn.RewrittenQuery, &turn.Answer,
+			&turn.SourceDocumentIDs, &turn.ContextKeys, &turn.CreatedAt); err != nil {
+			continue
+		}
+		conv.Turns = append(conv.Turns, turn)
+	}
+	return &conv, nil
+}
+
+/* DeleteConversation deletes a conversation and its turns */
+func (s *UnifiedRAGService) DeleteConversation(ctx context.Context, conversationID uuid.UUID, userID *string) error {
+	if _, err := s.GetConversation(ctx, conversationID, userID); err != nil {
+		return err
+	}
+	_, err := s.pool.Exec(ctx, `DELETE FROM neuronip.rag_conversations WHERE id = $1`, conversationID)
+	if err != nil {
+		return fmt.Errorf("failed to delete conversation: %w", err)
+	}
+	return nil
+}
+
+/* beginTurn loads the request's conversation and rewrites a follow-up into a standalone query.
+ * Returns nil when the request is not part of a conversation. */
+func (s *UnifiedRAGService) beginTurn(ctx context.Context, req *RAGRequest) (*conversationTurn, error) {
+	if req.ConversationID == nil || s.pool == nil {
+		return nil, nil
+	}
+
+	conv, err := s.GetConversation(ctx, *req.ConversationID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if req.CollectionID == nil {
+		req.CollectionID = conv.CollectionID
+	}
+
+	turn := &conversationTurn{
+		conversation: conv,
+		question:     req.Query,
+		rewritten:    req.Query,
+		seenContext:  make(map[string]bool),
+	}
+	for _, prior := range conv.Turns {
+		for _, key := range prior.ContextKeys {
+			turn.seenContext[key] = true
+		}
+	}
+
+	if len(conv.Turns) > 0 {
+		turn.rewritten = s.rewriteQuery(ctx, conv, req.Query)
+	}
+	return turn, nil
+}
+
+/* rewriteQuery rewrites a follow-up question into a standalone query using the conversation history */
+func (s *UnifiedRAGService) rewriteQuery(ctx context.Context, conv *Conversation, question string) string {
+	prompt := "Rewrite the follow-up question as a standalone search query that can be understood without the conversation. " +
+		"Resolve pronouns and relative references such as \"last year\" or \"that one\". Return only the query.\n\n" +
+		"Conversation:\n" + conversationHistory(conv) + "\n\nFollow-up question: " + question
+
+	rewritten, err := s.generateText(ctx, prompt)
+	if err == nil {
+		rewritten = strings.TrimSpace(strings.SplitN(strings.TrimSpace(rewritten), "\n", 2)[0])
+		rewritten = strings.Trim(rewritten, "\"'` ")
+		rewritten = strings.TrimPrefix(rewritten, "Query: ")
+		if rewritten != "" && len(rewritten) <= 4*len(question)+200 {
+			return rewritten
+		}
+	}
+
+	// Without a model, anchor the follow-up to the previous standalone query
+	previous := conv.Turns[len(conv.Turns)-1].RewrittenQuery
+	return previous + " " + question
+}
+
+/* generateText runs a free-form prompt through the agent, falling back to NeuronDB */
+func (s *UnifiedRAGService) generateText(ctx context.Context, prompt string) (string, error) {
+	if s.agentClient != nil {
+		reply, err := s.agentClient.GenerateReply(ctx, nil, prompt)
+		if err == nil && strings.TrimSpace(reply) != "" {
+			return reply, nil
+		}
+	}
+	if s.neurondbClient == nil {
+		return "", fmt.Errorf("no generation backend available")
+	}
+	return s.neurondbClient.GenerateResponse(ctx, prompt, nil, "sentence-transformers/all-MiniLM-L6-v2")
+}
+
+/* conversationHistory renders the summary and recent turns within the history budget */
+func conversationHistory(conv *Conversation) string {
+	var b strings.Builder
+	if conv.Summary != "" {
+		b.WriteString("Summary of earlier conversation: " + conv.Summary + "\n")
+	}
+
+	start := conv.SummarizedTurns
+	if start > len(conv.Turns) {
+		start = len(conv.Turns)
+	}
+	var turns []string
+	for _, turn := range conv.Turns[start:] {
+		turns = append(turns, "User: "+turn.Question+"\nAssistant: "+truncateText(turn.Answer, turnAnswerExcerpt))
+	}
+
+	// Keep the newest turns when the history is over budget
+	remaining := conversationHistoryBudget - b.Len()
+	first := len(turns)
+	for first > 0 && remaining-len(turns[first-1]) >= 0 {
+		first--
+		remaining -= len(turns[first]) + 1
+	}
+	b.WriteString(strings.Join(turns[first:], "\n"))
+	return b.String()
+}
+
+/* filterSeen drops context passages already shown in earlier turns, keeping all of them if nothing new was found */
+func (t *conversationTurn) filterSeen(documents []string, sources []map[string]interface{}) ([]string, []map[string]interface{}) {
+	if t == nil {
+		return documents, sources
+	}
+
+	var freshDocs []string
+	var freshSources []map[string]interface{}
+	for i, doc := range documents {
+		if t.seenContext[contextKey(doc)] {
+			continue
+		}
+		freshDocs = append(freshDocs, doc)
+		if i < len(sources) {
+			freshSources = append(freshSources, sources[i])
+		}
+	}
+	if len(freshDocs) == 0 {
+		freshDocs, freshSources = documents, sources
+	}
+
+	for _, doc := range freshDocs {
+		t.contextKeys = append(t.contextKeys, contextKey(doc))
+	}
+	return freshDocs, freshSources
+}
+
+/* generationContext prepends the conversation history to the sources passed to the agent */
+func (t *conversationTurn) generationContext(sources []map[string]interface{}) []map[string]interface{} {
+	if t == nil || len(t.conversation.Turns) == 0 {
+		return convertToStringMaps(sources)
+	}
+	history := map[string]interface{}{
+		"type":    "conversation_history",
+		"content": conversationHistory(t.conversation),
+	}
+	return append([]map[string]interface{}{history}, convertToStringMaps(sources)...)
+}
+
+/* finishTurn records the turn on the result and in the conversation. Storing is best-effort:
+ * a failure is logged and the answer is still returned. */
+func (s *UnifiedRAGService) finishTurn(ctx context.Context, turn *conversationTurn, result *RAGResult) {
+	if turn == nil {
+		return
+	}
+	result.ConversationID = &turn.conversation.ID
+	result.RewrittenQuery = turn.rewritten
+	if err := s.storeTurn(ctx, turn, result); err != nil {
+		logging.Error("Failed to store conversation turn", "conversation_id", turn.conversation.ID, "error", err)
+	}
+}
+
+/* storeTurn stores the turn with both the original and rewritten query and refreshes the rolling summary */
+func (s *UnifiedRAGService) storeTurn(ctx context.Context, turn *conversationTurn, result *RAGResult) error {
+	conv := turn.conversation
+
+	stored := ConversationTurn{
+		Question:          turn.question,
+		RewrittenQuery:    turn.rewritten,
+		Answer:            result.Answer,
+		SourceDocumentIDs: sourceDocumentIDs(result.Sources),
+		ContextKeys:       turn.contextKeys,
+	}
+	if stored.SourceDocumentIDs == nil {
+		stored.SourceDocumentIDs = []uuid.UUID{}
+	}
+	if stored.ContextKeys == nil {
+		stored.ContextKeys = []string{}
+	}
+
+	// The turn index is assigned by the insert; a concurrent turn taking the same index violates the
+	// unique constraint, and the insert is retried with the next index
+	var err error
+	for attempt := 0; attempt < maxTurnInsertAttempts; attempt++ {
+		err = s.pool.QueryRow(ctx, `
+			INSERT INTO neuronip.rag_conversation_turns
+			(conversation_id, turn_index, question, rewritten_query, answer, source_document_ids, context_keys)
+			SELECT $1, COALESCE(MAX(turn_index) + 1, 0), $2, $3, $4, $5, $6
+			FROM neuronip.rag_conversation_turns
+			WHERE conversation_id = $1
+			RETURNING id, turn_index, created_at`,
+			conv.ID, stored.Question, stored.RewrittenQuery, stored.Answer,
+			stored.SourceDocumentIDs, stored.ContextKeys).Scan(&stored.ID, &stored.TurnIndex, &stored.CreatedAt)
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != "23505" { // unique_violation
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store conversation turn: %w", err)
+	}
+	conv.Turns = append(conv.Turns, stored)
+
+	if _, err := s.pool.Exec(ctx, `UPDATE neuronip.rag_conversations SET updated_at = NOW() WHERE id = $1`, conv.ID); err != nil {
+		return fmt.Errorf("failed to update conversation: %w", err)
+	}
+
+	result.TurnIndex = stored.TurnIndex
+
+	return s.summarizeConversation(ctx, conv)
+}
+
+/* summarizeConversation folds turns older than the recent window into the summary once the history exceeds its budget */
+func (s *UnifiedRAGService) summarizeConversation(ctx context.Context, conv *Conversation) error {
+	foldUpTo, ok := summaryFoldPoint(conv)
+	if !ok {
+		return nil
+	}
+
+	var folded strings.Builder
+	for _, turn := range conv.Turns[conv.SummarizedTurns:foldUpTo] {
+		folded.WriteString("User: " + turn.Question + "\nAssistant: " + truncateText(turn.Answer, turnAnswerExcerpt) + "\n")
+	}
+
+	prompt := "Summarize this conversation in a short paragraph, keeping the entities, time ranges and facts " +
+		"that later questions may refer to.\n\n"
+	if conv.Summary != "" {
+		prompt += "Earlier summary: " + conv.Summary + "\n\n"
+	}
+	prompt += folded.String()
+
+	summary, err := s.generateText(ctx, prompt)
+	summary = strings.TrimSpace(summary)
+	if err != nil || summary == "" {
+		// Fall back to an extractive summary of the folded questions
+		var questions []string
+		for _, turn := range conv.Turns[conv.SummarizedTurns:foldUpTo] {
+			questions = append(questions, turn.RewrittenQuery)
+		}
+		summary = strings.TrimSpace(conv.Summary + " Earlier questions: " + strings.Join(questions, "; "))
+	}
+	summary = truncateText(summary, conversationHistoryBudget/3)
+
+	_, err = s.pool.Exec(ctx, `
+		UPDATE neuronip.rag_conversations
+		SET summary = $1, summarized_turns = $2, updated_at = NOW()
+		WHERE id = $3`, summary, foldUpTo, conv.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update conversation summary: %w", err)
+	}
+	conv.Summary = summary
+	conv.SummarizedTurns = foldUpTo
+	return nil
+}
+
+/* summaryFoldPoint returns the number of turns the summary should cover once the unsummarized history
+ * exceeds its budget; the latest recentTurnsKept turns are never folded */
+func summaryFoldPoint(conv *Conversation) (int, bool) {
+	foldUpTo := len(conv.Turns) - recentTurnsKept
+	if foldUpTo <= conv.SummarizedTurns {
+		return 0, false
+	}
+
+	size := len(conv.Summary)
+	for _, turn := range conv.Turns[conv.SummarizedTurns:] {
+		size += len(turn.Question) + len(truncateText(turn.Answer, turnAnswerExcerpt))
+	}
+	if size <= conversationHistoryBudget {
+		return 0, false
+	}
+	return foldUpTo, true
+}
+
+/* contextKey identifies a context passage across turns */
+func contextKey(content string) string {
+	hash := sha256.Sum256([]byte(strings.TrimSpace(content)))
+	return hex.EncodeToString(hash[:8])
+}
+
+/* truncateText shortens text to at most limit characters */
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "..."
+}
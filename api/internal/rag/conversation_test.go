@@ -0,0 +1,195 @@
+package rag
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/* TestTruncateText checks that text is cut by characters, not bytes */
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  string
+	}{
+		{name: "short", text: "orders", limit: 10, want: "orders"},
+		{name: "at the limit", text: "orders", limit: 6, want: "orders"},
+		{name: "over the limit", text: "orders shipped", limit: 6, want: "orders..."},
+		{name: "multi-byte characters", text: "Grüße aus München", limit: 5, want: "Grüße..."},
+		{name: "empty", text: "", limit: 0, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateText(tt.text, tt.limit); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+/* TestFilterSeen checks that passages shown in earlier turns are dropped with their sources and new ones remembered */
+func TestFilterSeen(t *testing.T) {
+	source := func(id string) map[string]interface{} { return map[string]interface{}{"id": id} }
+	seen := map[string]bool{contextKey("doc a"): true}
+
+	tests := []struct {
+		name        string
+		documents   []string
+		sources     []map[string]interface{}
+		wantDocs    []string
+		wantSources []map[string]interface{}
+	}{
+		{
+			name:        "drops seen passages",
+			documents:   []string{"doc a\n", "doc b", "doc c"},
+			sources:     []map[string]interface{}{source("a"), source("b"), source("c")},
+			wantDocs:    []string{"doc b", "doc c"},
+			wantSources: []map[string]interface{}{source("b"), source("c")},
+		},
+		{
+			name:        "keeps everything when nothing is new",
+			documents:   []string{"doc a"},
+			sources:     []map[string]interface{}{source("a")},
+			wantDocs:    []string{"doc a"},
+			wantSources: []map[string]interface{}{source("a")},
+		},
+		{
+			name:        "fewer sources than passages",
+			documents:   []string{"doc a", "doc b", "doc c"},
+			sources:     []map[string]interface{}{source("a"), source("b")},
+			wantDocs:    []string{"doc b", "doc c"},
+			wantSources: []map[string]interface{}{source("b")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			turn := &conversationTurn{seenContext: seen}
+			docs, sources := turn.filterSeen(tt.documents, tt.sources)
+			if !reflect.DeepEqual(docs, tt.wantDocs) || !reflect.DeepEqual(sources, tt.wantSources) {
+				t.Fatalf("expected %q with %v, got %q with %v", tt.wantDocs, tt.wantSources, docs, sources)
+			}
+			keys := make([]string, len(tt.wantDocs))
+			for i, doc := range tt.wantDocs {
+				keys[i] = contextKey(doc)
+			}
+			if !reflect.DeepEqual(turn.contextKeys, keys) {
+				t.Errorf("expected the passages sent to be remembered, got %v", turn.contextKeys)
+			}
+		})
+	}
+
+	// Requests outside a conversation pass through
+	var none *conversationTurn
+	docs, sources := none.filterSeen([]string{"doc a"}, []map[string]interface{}{source("a")})
+	if len(docs) != 1 || len(sources) != 1 {
+		t.Errorf("expected passages outside a conversation to pass through, got %q", docs)
+	}
+}
+
+/* historyTurns builds n turns with numbered questions of 100 characters and answers of answerLen characters */
+func historyTurns(n, answerLen int) []ConversationTurn {
+	turns := make([]ConversationTurn, n)
+	for i := range turns {
+		turns[i] = ConversationTurn{
+			TurnIndex:      i,
+			Question:       fmt.Sprintf("%03d", i) + strings.Repeat("q", 97),
+			RewrittenQuery: fmt.Sprintf("query %d", i),
+			Answer:         strings.Repeat("a", answerLen),
+		}
+	}
+	return turns
+}
+
+/* TestConversationHistory checks the summary, the turns after it, and that the newest turns win when over budget */
+func TestConversationHistory(t *testing.T) {
+	short := []ConversationTurn{{Question: "Who owns orders?", Answer: "The sales team."}, {Question: "Since when?", Answer: "Since 2024."}}
+
+	tests := []struct {
+		name string
+		conv *Conversation
+		want string
+	}{
+		{
+			name: "turns",
+			conv: &Conversation{Turns: short},
+			want: "User: Who owns orders?\nAssistant: The sales team.\nUser: Since when?\nAssistant: Since 2024.",
+		},
+		{
+			name: "summary replaces summarized turns",
+			conv: &Conversation{Summary: "Orders are owned by sales.", SummarizedTurns: 1, Turns: short},
+			want: "Summary of earlier conversation: Orders are owned by sales.\nUser: Since when?\nAssistant: Since 2024.",
+		},
+		{
+			name: "summary past the turns",
+			conv: &Conversation{Summary: "Orders are owned by sales.", SummarizedTurns: 5, Turns: short},
+			want: "Summary of earlier conversation: Orders are owned by sales.\n",
+		},
+		{
+			name: "long answer",
+			conv: &Conversation{Turns: []ConversationTurn{{Question: "Describe orders", Answer: strings.Repeat("a", 700)}}},
+			want: "User: Describe orders\nAssistant: " + strings.Repeat("a", turnAnswerExcerpt) + "...",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := conversationHistory(tt.conv); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	// Each of these turns renders to 721 characters, so 8 of the 12 fit the budget
+	history := conversationHistory(&Conversation{Turns: historyTurns(12, 700)})
+	if len(history) > conversationHistoryBudget {
+		t.Errorf("expected history within %d characters, got %d", conversationHistoryBudget, len(history))
+	}
+	if count := strings.Count(history, "User: "); count != 8 {
+		t.Errorf("expected the 8 newest turns, got %d", count)
+	}
+	if !strings.HasPrefix(history, "User: 004") || !strings.Contains(history, "User: 011") {
+		t.Errorf("expected turns 4 to 11, got %q...", history[:20])
+	}
+}
+
+/* TestSummaryFoldPoint checks when the history is summarized and which turns are folded */
+func TestSummaryFoldPoint(t *testing.T) {
+	tests := []struct {
+		name   string
+		conv   *Conversation
+		foldTo int
+		fold   bool
+	}{
+		{name: "only recent turns", conv: &Conversation{Turns: historyTurns(recentTurnsKept, 5000)}},
+		{name: "within budget", conv: &Conversation{Turns: historyTurns(6, 500)}},
+		{name: "over budget", conv: &Conversation{Turns: historyTurns(12, 500)}, foldTo: 8, fold: true},
+		{
+			// Answers count only up to the excerpt sent to the model: 8 turns of 703 characters fit, 9 do not
+			name: "long answers within budget",
+			conv: &Conversation{Turns: historyTurns(8, 5000)},
+		},
+		{name: "long answers over budget", conv: &Conversation{Turns: historyTurns(9, 5000)}, foldTo: 5, fold: true},
+		{name: "already summarized", conv: &Conversation{Summary: "Earlier questions.", SummarizedTurns: 8, Turns: historyTurns(12, 500)}},
+		{
+			// 10 unsummarized turns of 600 characters exactly fill the budget
+			name: "unsummarized turns at the budget",
+			conv: &Conversation{SummarizedTurns: 2, Turns: historyTurns(12, 500)},
+		},
+		{
+			name:   "summary counts toward the budget",
+			conv:   &Conversation{Summary: "s", SummarizedTurns: 2, Turns: historyTurns(12, 500)},
+			foldTo: 8,
+			fold:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			foldTo, fold := summaryFoldPoint(tt.conv)
+			if fold != tt.fold || foldTo != tt.foldTo {
+				t.Errorf("expected fold=%v up to %d, got fold=%v up to %d", tt.fold, tt.foldTo, fold, foldTo)
+			}
+		})
+	}
+}
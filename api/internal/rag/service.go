@@ -4,16 +4,27 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/neurondb/NeuronIP/api/internal/agent"
+	"github.com/neurondb/NeuronIP/api/internal/cache"
+	"github.com/neurondb/NeuronIP/api/internal/logging"
 	"github.com/neurondb/NeuronIP/api/internal/mcp"
 	"github.com/neurondb/NeuronIP/api/internal/neurondb"
+	"github.com/neurondb/NeuronIP/api/internal/observability"
 )
 
 /* UnifiedRAGService provides unified RAG pipeline using all three components */
 type UnifiedRAGService struct {
+	pool           *pgxpool.Pool // Conversation storage; nil disables conversations
 	neurondbClient *neurondb.Client
 	mcpClient      *mcp.Client
 	agentClient    *agent.Client
+	semanticCache  *cache.SemanticCacheService
+
+	retrievalMetrics     *observability.RetrievalMetricsService
+	hallucinationService *observability.HallucinationDetectionService
+	groundedness         *observability.GroundednessService
 }
 
 /* NewUnifiedRAGService creates a new unified RAG service */
@@ -25,6 +36,26 @@ func NewUnifiedRAGService(neurondbClient *neurondb.Client, mcpClient *mcp.Client
 	}
 }
 
+/* ServiceOptions configures the optional components of a unified RAG service */
+type ServiceOptions struct {
+	Pool                 *pgxpool.Pool                               // Stores multi-turn conversations; nil disables them
+	SemanticCache        *cache.SemanticCacheService                 // Serves repeated questions from the semantic answer cache
+	RetrievalMetrics     *observability.RetrievalMetricsService      // Records retrieval metrics for streamed answers
+	HallucinationService *observability.HallucinationDetectionService // Records hallucination signals for streamed answers
+	Groundedness         *observability.GroundednessService          // Verifies answers claim by claim against their sources
+}
+
+/* NewUnifiedRAGServiceWithOptions creates a unified RAG service with the given optional components */
+func NewUnifiedRAGServiceWithOptions(neurondbClient *neurondb.Client, mcpClient *mcp.Client, agentClient *agent.Client, opts ServiceOptions) *UnifiedRAGService {
+	service := NewUnifiedRAGService(neurondbClient, mcpClient, agentClient)
+	service.pool = opts.Pool
+	service.semanticCache = opts.SemanticCache
+	service.retrievalMetrics = opts.RetrievalMetrics
+	service.hallucinationService = opts.HallucinationService
+	service.groundedness = opts.Groundedness
+	return service
+}
+
 /* RAGRequest represents a RAG pipeline request */
 type RAGRequest struct {
 	Query        string
@@ -34,15 +65,24 @@ type RAGRequest struct {
 	RerankMethod string // "cross_encoder", "llm", "cohere", "ensemble"
 	DistanceMetric string // "cosine", "l2", "inner_product"
 	Threshold   float64
+	UserID      *string // Caller, used to scope cached answers to the caller's permissions
+	BypassCache bool
+	ConversationID *uuid.UUID // Follow-up turn of this conversation, rewritten against its history
 }
 
 /* RAGResult represents a RAG pipeline result */
 type RAGResult struct {
-	Answer     string
-	Context    []string
-	Sources    []map[string]interface{}
-	Citations  []string
-	Confidence float64
+	Answer         string
+	Context        []string
+	Sources        []map[string]interface{}
+	Citations      []string
+	Confidence     float64
+	Cache          *cache.HitInfo                    // Set when the answer was served from the semantic cache
+	CitationSpans  []CitationSpan                    // Answer spans mapped to sources, set by streaming
+	ConversationID *uuid.UUID                        // Set when the answer is a turn of a conversation
+	RewrittenQuery string                            // Standalone query used for retrieval in a conversation
+	TurnIndex      int
+	Groundedness   *observability.GroundednessReport // Claim-level verification of the answer
 }
 
 /* ExecuteRAGPipeline executes the unified RAG pipeline */
@@ -51,14 +91,14 @@ func (s *UnifiedRAGService) ExecuteRAGPipeline(ctx context.Context, req RAGReque
 		req.Limit = 10
 	}
 
-	// Step 1: NeuronAgent - Understand query intent
-	var enhancedQuery string
-	if s.agentClient != nil {
-		// Use agent to enhance/understand the query
-		enhancedQuery = req.Query // For now, use as-is
-		// In production, could use agent to expand/refine query
-	} else {
-		enhancedQuery = req.Query
+	// Step 1: NeuronAgent - Understand query intent, rewriting conversation follow-ups into standalone queries
+	turn, err := s.beginTurn(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	enhancedQuery := req.Query
+	if turn != nil {
+		enhancedQuery = turn.rewritten
 	}
 
 	// Step 2: Generate query embedding using NeuronDB
@@ -67,6 +107,106 @@ func (s *UnifiedRAGService) ExecuteRAGPipeline(ctx context.Context, req RAGReque
 		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
 
+	// Serve semantically equivalent questions from the cache within the caller's permission scope
+	useCache := s.semanticCache.Enabled() && !req.BypassCache
+	var scopeKey, partition string
+	if useCache {
+		// An unresolved permission scope skips the cache instead of sharing a looser key
+		scopeKey, err = s.semanticCache.ScopeKey(ctx, req.UserID)
+		useCache = err == nil
+		partition = cachePartition(req)
+	}
+	if useCache {
+		var cached RAGResult
+		hit, err := s.semanticCache.Lookup(ctx, cache.NamespaceRAG, scopeKey, partition, queryEmbedding, &cached)
+		if err == nil && hit != nil {
+			cached.Cache = hit
+			cached.Context, cached.Sources = turn.filterSeen(cached.Context, cached.Sources)
+			s.finishTurn(ctx, turn, &cached)
+			return &cached, nil
+		}
+	}
+
+	// Steps 3-4: NeuronMCP/NeuronDB - Vector search with reranking
+	documents, sources, err := s.retrieve(ctx, req, enhancedQuery, queryEmbedding)
+	if err != nil {
+		return nil, err
+	}
+	documents, sources = turn.filterSeen(documents, sources)
+
+	// Step 5: NeuronDB - Retrieve context
+	context := documents
+
+	// Step 6: NeuronAgent - Generate response
+	var answer string
+	var citations []string
+
+	if s.agentClient != nil {
+		// Use agent to generate response with context
+		reply, err := s.agentClient.GenerateReply(ctx, turn.generationContext(sources), enhancedQuery)
+		if err == nil {
+			answer = reply
+		}
+	}
+
+	// Fallback to NeuronDB if agent fails
+	if answer == "" {
+		answer, err = s.neurondbClient.GenerateResponse(ctx, enhancedQuery, context, "sentence-transformers/all-MiniLM-L6-v2")
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate response: %w", err)
+		}
+	}
+
+	// Step 7: NeuronMCP - Generate citations
+	if s.mcpClient != nil && len(sources) > 0 {
+		citationResult, err := s.mcpClient.AnswerWithCitations(ctx, enhancedQuery, sources, "sentence-transformers/all-MiniLM-L6-v2")
+		if err == nil {
+			if cites, ok := citationResult["citations"].([]interface{}); ok {
+				for _, cite := range cites {
+					if citeStr, ok := cite.(string); ok {
+						citations = append(citations, citeStr)
+					}
+				}
+			}
+		}
+	}
+
+	result := &RAGResult{
+		Answer:     answer,
+		Context:    context,
+		Sources:    sources,
+		Citations:  citations,
+		Confidence: 0.8, // Could be calculated from similarity scores
+	}
+
+	// Verify the answer claim by claim against the retrieved context
+	if s.groundedness.Enabled() {
+		queryID := uuid.New()
+		report, err := s.groundedness.VerifyAndRecord(ctx, &queryID, nil, answer, groundingSources(context, sources),
+			map[string]interface{}{"query": req.Query, "answer": answer})
+		if err != nil {
+			logging.Error("Groundedness verification failed", "query_id", queryID, "error", err)
+			if report == nil {
+				report = s.groundedness.FailedReport(err)
+			}
+		}
+		result.Groundedness = report
+		result.Answer = s.groundedness.Enforce(answer, report)
+	}
+
+	if useCache && !isBlocked(result) {
+		// Caching is best effort; failures must not fail the request
+		s.semanticCache.Store(ctx, cache.NamespaceRAG, scopeKey, partition, enhancedQuery, queryEmbedding, result,
+			cache.CacheEntrySources{DocumentIDs: sourceDocumentIDs(sources)})
+	}
+
+	s.finishTurn(ctx, turn, result)
+
+	return result, nil
+}
+
+/* retrieve searches the knowledge base for the query and reranks the results */
+func (s *UnifiedRAGService) retrieve(ctx context.Context, req RAGRequest, enhancedQuery string, queryEmbedding string) ([]string, []map[string]interface{}, error) {
 	// Step 3: NeuronMCP/NeuronDB - Vector search with reranking
 	var documents []string
 	var sources []map[string]interface{}
@@ -80,12 +220,13 @@ func (s *UnifiedRAGService) ExecuteRAGPipeline(ctx context.Context, req RAGReque
 	// Use appropriate search method
 	if s.mcpClient != nil {
 		// Try MCP hybrid search first
-		result, err := s.mcpClient.HybridSearch(ctx, enhancedQuery, "neuronip.knowledge_documents", "embedding", "content", req.Limit*2, nil)
+		result, err := s.mcpClient.HybridSearch(ctx, enhancedQuery, "neuronip."+documentsTable, "embedding", "content", req.Limit*2, nil)
 		if err == nil {
 			if docs, ok := result["documents"].([]interface{}); ok {
 				for _, doc := range docs {
 					if docMap, ok := doc.(map[string]interface{}); ok {
 						if content, ok := docMap["content"].(string); ok {
+							docMap["source_table"] = documentsTable
 							documents = append(documents, content)
 							sources = append(sources, docMap)
 						}
@@ -110,7 +251,7 @@ func (s *UnifiedRAGService) ExecuteRAGPipeline(ctx context.Context, req RAGReque
 		}
 
 		if err != nil {
-			return nil, fmt.Errorf("failed to perform vector search: %w", err)
+			return nil, nil, fmt.Errorf("failed to perform vector search: %w", err)
 		}
 
 		for _, result := range results {
@@ -165,53 +306,74 @@ func (s *UnifiedRAGService) ExecuteRAGPipeline(ctx context.Context, req RAGReque
 		sources = sources[:req.Limit]
 	}
 
-	// Step 5: NeuronDB - Retrieve context
-	context := documents
-
-	// Step 6: NeuronAgent - Generate response
-	var answer string
-	var citations []string
+	return documents, sources, nil
+}
 
-	if s.agentClient != nil {
-		// Use agent to generate response with context
-		reply, err := s.agentClient.GenerateReply(ctx, convertToStringMaps(sources), enhancedQuery)
-		if err == nil {
-			answer = reply
+/* groundingSources pairs retrieved passages with their document IDs for answer verification */
+func groundingSources(documents []string, sources []map[string]interface{}) []observability.GroundingSource {
+	grounding := make([]observability.GroundingSource, len(documents))
+	for i, doc := range documents {
+		grounding[i].Text = doc
+		if i < len(sources) {
+			for _, key := range []string{"document_id", "doc_id", "id"} {
+				if v, ok := sources[i][key]; ok && v != nil {
+					grounding[i].ID = fmt.Sprint(v)
+					break
+				}
+			}
 		}
 	}
+	return grounding
+}
 
-	// Fallback to NeuronDB if agent fails
-	if answer == "" {
-		answer, err = s.neurondbClient.GenerateResponse(ctx, enhancedQuery, context, "sentence-transformers/all-MiniLM-L6-v2")
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate response: %w", err)
-		}
+/* documentsTable is the table hybrid search reads; its rows' id is the document ID, so results from it are tagged source_table */
+const documentsTable = "knowledge_documents"
+
+/* retrievalTable is the table RAG searches, recorded with retrieval metrics so index advice counts its queries */
+const retrievalTable = "knowledge_embeddings"
+
+/* isBlocked reports whether the answer was withheld for insufficient grounding; blocked answers are not cached */
+func isBlocked(result *RAGResult) bool {
+	return result.Groundedness != nil && result.Groundedness.Action == observability.GroundednessActionBlock
+}
+
+/* cachePartition returns the semantic cache partition for a request */
+func cachePartition(req RAGRequest) string {
+	if req.CollectionID != nil {
+		return *req.CollectionID
 	}
+	return ""
+}
 
-	// Step 7: NeuronMCP - Generate citations
-	if s.mcpClient != nil && len(sources) > 0 {
-		citationResult, err := s.mcpClient.AnswerWithCitations(ctx, enhancedQuery, sources, "sentence-transformers/all-MiniLM-L6-v2")
-		if err == nil {
-			if cites, ok := citationResult["citations"].([]interface{}); ok {
-				for _, cite := range cites {
-					if citeStr, ok := cite.(string); ok {
-						citations = append(citations, citeStr)
-					}
-				}
-			} else if answer, ok := citationResult["answer"].(string); ok {
-				// If citations not in expected format, extract from answer
-				answer = answer // Could parse citations from answer text
+/* sourceDocumentIDs extracts the knowledge document IDs referenced by search results.
+ * Embedding rows name their document in document_id; rows of knowledge_documents itself carry it as id. */
+func sourceDocumentIDs(sources []map[string]interface{}) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool)
+	var ids []uuid.UUID
+	for _, source := range sources {
+		keys := []string{"document_id", "doc_id"}
+		if source["source_table"] == documentsTable {
+			keys = append(keys, "id")
+		}
+		for _, key := range keys {
+			var id uuid.UUID
+			var ok bool
+			switch v := source[key].(type) {
+			case uuid.UUID:
+				id, ok = v, true
+			case [16]byte:
+				id, ok = uuid.UUID(v), true
+			case string:
+				parsed, err := uuid.Parse(v)
+				id, ok = parsed, err == nil
+			}
+			if ok && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
 			}
 		}
 	}
-
-	return &RAGResult{
-		Answer:     answer,
-		Context:    context,
-		Sources:    sources,
-		Citations:  citations,
-		Confidence: 0.8, // Could be calculated from similarity scores
-	}, nil
+	return ids
 }
 
 /* convertToStringMaps converts []map[string]interface{} to format expected by agent */
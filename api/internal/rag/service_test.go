@@ -0,0 +1,62 @@
+package rag
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+/* TestSourceDocumentIDs checks that cached answers record the documents behind every kind of search result */
+func TestSourceDocumentIDs(t *testing.T) {
+	docA, docB := uuid.New(), uuid.New()
+	embeddingID := uuid.New()
+
+	tests := []struct {
+		name    string
+		sources []map[string]interface{}
+		want    []uuid.UUID
+	}{
+		{
+			name:    "embedding rows",
+			sources: []map[string]interface{}{{"id": embeddingID.String(), "document_id": docA}, {"doc_id": docB.String()}},
+			want:    []uuid.UUID{docA, docB},
+		},
+		{
+			name:    "embedding row id is not a document",
+			sources: []map[string]interface{}{{"id": embeddingID.String()}},
+		},
+		{
+			name: "knowledge documents",
+			sources: []map[string]interface{}{
+				{"id": docA.String(), "source_table": documentsTable},
+				{"id": [16]byte(docB), "source_table": documentsTable},
+			},
+			want: []uuid.UUID{docA, docB},
+		},
+		{
+			name: "repeated document",
+			sources: []map[string]interface{}{
+				{"document_id": docA.String()},
+				{"id": docA.String(), "source_table": documentsTable},
+			},
+			want: []uuid.UUID{docA},
+		},
+		{
+			name:    "unparseable id",
+			sources: []map[string]interface{}{{"id": "doc-1", "source_table": documentsTable}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sourceDocumentIDs(tt.sources)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected document %d to be %s, got %s", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,499 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/neurondb/NeuronIP/api/internal/cache"
+	"github.com/neurondb/NeuronIP/api/internal/logging"
+	"github.com/neurondb/NeuronIP/api/internal/observability"
+)
+
+/* Stream event types, sent in this order: retrieval, delta and citation events interleaved, groundedness, then done.
+ * When groundedness is enforced in block mode the deltas and citations are only sent once the answer passes. */
+const (
+	StreamEventRetrieval    = "retrieval"
+	StreamEventDelta        = "delta"
+	StreamEventCitation     = "citation"
+	StreamEventGroundedness = "groundedness"
+	StreamEventDone         = "done"
+)
+
+/* minCitationOverlap is the share of a sentence's terms a source must contain to be cited for it */
+const minCitationOverlap = 0.5
+
+/* StreamEvent is one event of a streaming RAG answer */
+type StreamEvent struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+/* CitationSpan maps a span of the answer to the source that supports it.
+ * Start and End are character (rune) offsets into the answer, End exclusive. */
+type CitationSpan struct {
+	Start       int     `json:"start"`
+	End         int     `json:"end"`
+	Text        string  `json:"text"`
+	SourceIndex int     `json:"source_index"`
+	SourceID    string  `json:"source_id,omitempty"`
+	Score       float64 `json:"score"`
+}
+
+/* ExecuteRAGPipelineStream executes the RAG pipeline, emitting retrieval results, answer token deltas and
+ * citation spans as they become available. Cancelling ctx stops generation; the partial answer is still
+ * recorded in the retrieval and hallucination metrics and returned alongside ctx's error. */
+func (s *UnifiedRAGService) ExecuteRAGPipelineStream(ctx context.Context, req RAGRequest, emit func(StreamEvent) error) (*RAGResult, error) {
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+	started := time.Now()
+	queryID := uuid.New()
+
+	turn, err := s.beginTurn(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	enhancedQuery := req.Query
+	if turn != nil {
+		enhancedQuery = turn.rewritten
+	}
+
+	var queryEmbedding string
+	queryEmbedding, err = s.neurondbClient.GenerateEmbedding(ctx, enhancedQuery, "sentence-transformers/all-MiniLM-L6-v2")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+
+	useCache := s.semanticCache.Enabled() && !req.BypassCache
+	var scopeKey, partition string
+	if useCache {
+		// An unresolved permission scope skips the cache instead of sharing a looser key
+		scopeKey, err = s.semanticCache.ScopeKey(ctx, req.UserID)
+		useCache = err == nil
+		partition = cachePartition(req)
+	}
+	if useCache {
+		var cached RAGResult
+		hit, err := s.semanticCache.Lookup(ctx, cache.NamespaceRAG, scopeKey, partition, queryEmbedding, &cached)
+		if err == nil && hit != nil {
+			cached.Cache = hit
+			cached.Context, cached.Sources = turn.filterSeen(cached.Context, cached.Sources)
+			s.finishTurn(ctx, turn, &cached)
+			return &cached, s.replayCached(ctx, queryID, req, enhancedQuery, &cached, started, emit)
+		}
+	}
+
+	documents, sources, err := s.retrieve(ctx, req, enhancedQuery, queryEmbedding)
+	if err != nil {
+		return nil, err
+	}
+	documents, sources = turn.filterSeen(documents, sources)
+
+	// In block mode an unverified answer must not reach the client, so deltas and citations are held until it passes
+	stream := newAnswerStream(emit, s.groundedness.Blocking(), sources, documents)
+
+	genErr := emit(StreamEvent{Type: StreamEventRetrieval, Data: map[string]interface{}{
+		"query_id":        queryID,
+		"rewritten_query": enhancedQuery,
+		"sources":         sources,
+	}})
+
+	if genErr == nil && s.agentClient != nil {
+		_, streamErr := s.agentClient.StreamReply(ctx, turn.generationContext(sources), enhancedQuery, stream.delta)
+		if streamErr != nil && ctx.Err() == nil {
+			genErr = stream.interrupted(streamErr)
+		}
+	}
+
+	// Fall back to blocking generation when the agent cannot stream, emitting the reply as one delta
+	if genErr == nil && stream.answer.Len() == 0 && ctx.Err() == nil {
+		var reply string
+		if s.agentClient != nil {
+			reply, _ = s.agentClient.GenerateReply(ctx, turn.generationContext(sources), enhancedQuery)
+		}
+		if reply == "" {
+			reply, genErr = s.neurondbClient.GenerateResponse(ctx, enhancedQuery, documents, "sentence-transformers/all-MiniLM-L6-v2")
+		}
+		if genErr == nil {
+			genErr = stream.delta(reply)
+		}
+	}
+
+	partial := genErr != nil || ctx.Err() != nil
+	if !partial {
+		genErr = stream.finish()
+		partial = genErr != nil
+	}
+
+	result := &RAGResult{
+		Answer:        stream.answer.String(),
+		Context:       documents,
+		Sources:       sources,
+		Citations:     stream.tracker.citedSourceIDs(),
+		Confidence:    0.8,
+		CitationSpans: stream.tracker.spans,
+	}
+
+	// Record metrics even when the client went away so partial answers are still evaluated
+	result.Groundedness = s.recordStreamMetrics(context.WithoutCancel(ctx), queryID, req, result, stream.tracker, time.Since(started), partial)
+	s.finishTurn(context.WithoutCancel(ctx), turn, result)
+
+	if partial {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		return result, fmt.Errorf("failed to generate response: %w", genErr)
+	}
+
+	// Outside block mode deltas already reached the client, so an annotated answer is sent as a replacement
+	if result.Groundedness != nil {
+		result.Answer = s.groundedness.Enforce(result.Answer, result.Groundedness)
+		if !isBlocked(result) {
+			for _, event := range stream.held {
+				if err := emit(event); err != nil {
+					return result, err
+				}
+			}
+		}
+		if err := emit(StreamEvent{Type: StreamEventGroundedness, Data: map[string]interface{}{
+			"report": result.Groundedness,
+			"answer": result.Answer,
+		}}); err != nil {
+			return result, err
+		}
+	}
+
+	if useCache && !isBlocked(result) {
+		cached := *result
+		cached.ConversationID, cached.RewrittenQuery, cached.TurnIndex = nil, "", 0
+		s.semanticCache.Store(ctx, cache.NamespaceRAG, scopeKey, partition, enhancedQuery, queryEmbedding, &cached,
+			cache.CacheEntrySources{DocumentIDs: sourceDocumentIDs(sources)})
+	}
+
+	return result, emit(doneEvent(queryID, result))
+}
+
+/* doneEvent is the final event of a stream, generated or replayed from the cache */
+func doneEvent(queryID uuid.UUID, result *RAGResult) StreamEvent {
+	return StreamEvent{Type: StreamEventDone, Data: map[string]interface{}{
+		"query_id":        queryID,
+		"conversation_id": result.ConversationID,
+		"turn_index":      result.TurnIndex,
+		"answer":          result.Answer,
+		"citations":       result.CitationSpans,
+		"confidence":      result.Confidence,
+		"groundedness":    result.Groundedness,
+		"cache":           result.Cache,
+	}}
+}
+
+/* replayCached emits a cached answer using the same event sequence as a generated one
+ * and records the hit in the retrieval metrics */
+func (s *UnifiedRAGService) replayCached(ctx context.Context, queryID uuid.UUID, req RAGRequest, enhancedQuery string, cached *RAGResult, started time.Time, emit func(StreamEvent) error) error {
+	if err := emit(StreamEvent{Type: StreamEventRetrieval, Data: map[string]interface{}{
+		"query_id":        queryID,
+		"rewritten_query": enhancedQuery,
+		"sources":         cached.Sources,
+	}}); err != nil {
+		return err
+	}
+	if err := emit(StreamEvent{Type: StreamEventDelta, Data: map[string]interface{}{"text": cached.Answer}}); err != nil {
+		return err
+	}
+	if err := emitCitations(emit, cached.CitationSpans); err != nil {
+		return err
+	}
+
+	if s.retrievalMetrics != nil {
+		s.retrievalMetrics.RecordRetrieval(context.WithoutCancel(ctx), &queryID, nil, "rag_stream", len(cached.Sources), len(cached.Citations),
+			averageSimilarity(cached.Sources), time.Since(started).Milliseconds(), map[string]interface{}{
+				"query":         req.Query,
+				"answer":        cached.Answer,
+				"streamed":      true,
+				"partial":       false,
+				"cache_hit":     true,
+				"table":         retrievalTable,
+				"collection_id": cachePartition(req),
+			})
+	}
+
+	if cached.Groundedness != nil {
+		if err := emit(StreamEvent{Type: StreamEventGroundedness, Data: map[string]interface{}{
+			"report": cached.Groundedness,
+			"answer": cached.Answer,
+		}}); err != nil {
+			return err
+		}
+	}
+	return emit(doneEvent(queryID, cached))
+}
+
+/* answerStream accumulates a generated answer, sending deltas and their citations as they arrive,
+ * or holding them back when the answer must pass groundedness verification first */
+type answerStream struct {
+	emit      func(StreamEvent) error
+	hold      bool
+	held      []StreamEvent
+	answer    strings.Builder
+	tracker   *citationTracker
+	sources   []map[string]interface{}
+	documents []string
+}
+
+/* newAnswerStream creates an answer stream citing the retrieved sources */
+func newAnswerStream(emit func(StreamEvent) error, hold bool, sources []map[string]interface{}, documents []string) *answerStream {
+	return &answerStream{
+		emit:      emit,
+		hold:      hold,
+		tracker:   newCitationTracker(sources, documents),
+		sources:   sources,
+		documents: documents,
+	}
+}
+
+/* send emits an answer event, or holds it until the answer passes */
+func (a *answerStream) send(event StreamEvent) error {
+	if a.hold {
+		a.held = append(a.held, event)
+		return nil
+	}
+	return a.emit(event)
+}
+
+/* delta appends generated text and sends it with the citations of sentences it completed */
+func (a *answerStream) delta(text string) error {
+	a.answer.WriteString(text)
+	if err := a.send(StreamEvent{Type: StreamEventDelta, Data: map[string]interface{}{"text": text}}); err != nil {
+		return err
+	}
+	return emitCitations(a.send, a.tracker.advance(a.answer.String(), false))
+}
+
+/* finish sends the citation of the trailing sentence once generation is complete */
+func (a *answerStream) finish() error {
+	return emitCitations(a.send, a.tracker.advance(a.answer.String(), true))
+}
+
+/* interrupted handles generation that failed midway and returns nil when it may fall back to blocking generation.
+ * Deltas already sent cannot be taken back, so their error stands; held deltas never reached the client,
+ * so the partial answer is dropped instead. */
+func (a *answerStream) interrupted(err error) error {
+	if a.answer.Len() == 0 {
+		return nil
+	}
+	if !a.hold {
+		return err
+	}
+	a.answer.Reset()
+	a.held = nil
+	a.tracker = newCitationTracker(a.sources, a.documents)
+	return nil
+}
+
+/* emitCitations emits one citation event per span */
+func emitCitations(emit func(StreamEvent) error, spans []CitationSpan) error {
+	for _, span := range spans {
+		if err := emit(StreamEvent{Type: StreamEventCitation, Data: span}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* recordStreamMetrics logs retrieval and hallucination metrics for a streamed, possibly partial, answer.
+ * With groundedness verification enabled the hallucination signal carries per-claim evidence and the report is returned. */
+func (s *UnifiedRAGService) recordStreamMetrics(ctx context.Context, queryID uuid.UUID, req RAGRequest, result *RAGResult, tracker *citationTracker, latency time.Duration, partial bool) *observability.GroundednessReport {
+	metadata := map[string]interface{}{
+		"query":         req.Query,
+		"answer":        result.Answer,
+		"streamed":      true,
+		"partial":       partial,
+		"table":         retrievalTable,
+		"collection_id": cachePartition(req),
+	}
+
+	if s.retrievalMetrics != nil {
+		s.retrievalMetrics.RecordRetrieval(ctx, &queryID, nil, "rag_stream", len(result.Sources), len(result.Citations),
+			averageSimilarity(result.Sources), latency.Milliseconds(), metadata)
+	}
+
+	if s.groundedness.Enabled() {
+		report, err := s.groundedness.VerifyAndRecord(ctx, &queryID, nil, result.Answer,
+			groundingSources(result.Context, result.Sources), metadata)
+		if err != nil {
+			logging.Error("Groundedness verification failed", "query_id", queryID, "error", err)
+			if report == nil {
+				report = s.groundedness.FailedReport(err)
+			}
+		}
+		return report
+	}
+
+	if s.hallucinationService != nil {
+		citationAccuracy, evidenceStrength := tracker.coverage()
+		var flags []string
+		if partial {
+			flags = append(flags, "partial_answer")
+		}
+		if len(result.Sources) == 0 {
+			flags = append(flags, "no_sources")
+		}
+		if citationAccuracy < 1 {
+			flags = append(flags, "uncited_spans")
+		}
+		s.hallucinationService.RecordHallucinationSignal(ctx, &queryID, nil, nil, result.Confidence,
+			citationAccuracy, evidenceStrength, flags, metadata)
+	}
+	return nil
+}
+
+/* averageSimilarity averages the similarity scores reported by search results */
+func averageSimilarity(sources []map[string]interface{}) float64 {
+	var total float64
+	var count int
+	for _, source := range sources {
+		for _, key := range []string{"similarity", "score"} {
+			if v, ok := source[key].(float64); ok {
+				total += v
+				count++
+				break
+			}
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
+
+/* citationTracker splits a growing answer into sentences and attributes each to its best supporting source */
+type citationTracker struct {
+	sourceTerms []map[string]bool
+	sourceIDs   []string
+	offset      int // Rune offset of the first sentence not yet attributed
+	sentences   int
+	spans       []CitationSpan
+}
+
+/* newCitationTracker creates a tracker over the retrieved sources and their text */
+func newCitationTracker(sources []map[string]interface{}, documents []string) *citationTracker {
+	t := &citationTracker{}
+	for i, doc := range documents {
+		terms := make(map[string]bool)
+		for _, term := range observability.ContentTerms(doc) {
+			terms[term] = true
+		}
+		t.sourceTerms = append(t.sourceTerms, terms)
+
+		var id string
+		if i < len(sources) {
+			for _, key := range []string{"document_id", "doc_id", "id"} {
+				if v, ok := sources[i][key]; ok && v != nil {
+					id = fmt.Sprint(v)
+					break
+				}
+			}
+		}
+		t.sourceIDs = append(t.sourceIDs, id)
+	}
+	return t
+}
+
+/* advance attributes sentences completed since the last call, or the trailing text too when final */
+func (t *citationTracker) advance(answer string, final bool) []CitationSpan {
+	runes := []rune(answer)
+	var spans []CitationSpan
+	for i := t.offset; i < len(runes); i++ {
+		last := i+1 == len(runes)
+		boundary := strings.ContainsRune(".!?\n", runes[i]) && !last && unicode.IsSpace(runes[i+1])
+		// The trailing sentence may still grow until the stream is over
+		if !boundary && !(final && last) {
+			continue
+		}
+		if span, ok := t.attribute(runes, t.offset, i+1); ok {
+			spans = append(spans, span)
+		}
+		t.offset = i + 1
+	}
+	t.spans = append(t.spans, spans...)
+	return spans
+}
+
+/* attribute matches the sentence in runes[start:end] against the sources */
+func (t *citationTracker) attribute(runes []rune, start, end int) (CitationSpan, bool) {
+	for start < end && unicode.IsSpace(runes[start]) {
+		start++
+	}
+	for end > start && unicode.IsSpace(runes[end-1]) {
+		end--
+	}
+	terms := observability.ContentTerms(string(runes[start:end]))
+	if len(terms) < 3 {
+		return CitationSpan{}, false
+	}
+	t.sentences++
+
+	best, bestScore := -1, 0.0
+	for i, sourceTerms := range t.sourceTerms {
+		matched := 0
+		for _, term := range terms {
+			if sourceTerms[term] {
+				matched++
+			}
+		}
+		score := float64(matched) / float64(len(terms))
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 || bestScore < minCitationOverlap {
+		return CitationSpan{}, false
+	}
+
+	return CitationSpan{
+		Start:       start,
+		End:         end,
+		Text:        string(runes[start:end]),
+		SourceIndex: best,
+		SourceID:    t.sourceIDs[best],
+		Score:       bestScore,
+	}, true
+}
+
+/* coverage returns the share of answer sentences with a citation and their mean support score */
+func (t *citationTracker) coverage() (float64, float64) {
+	if t.sentences == 0 {
+		return 0, 0
+	}
+	var total float64
+	for _, span := range t.spans {
+		total += span.Score
+	}
+	accuracy := float64(len(t.spans)) / float64(t.sentences)
+	if len(t.spans) == 0 {
+		return accuracy, 0
+	}
+	return accuracy, total / float64(len(t.spans))
+}
+
+/* citedSourceIDs lists the distinct sources cited by the answer, in citation order */
+func (t *citationTracker) citedSourceIDs() []string {
+	seen := make(map[int]bool)
+	var ids []string
+	for _, span := range t.spans {
+		if seen[span.SourceIndex] {
+			continue
+		}
+		seen[span.SourceIndex] = true
+		id := span.SourceID
+		if id == "" {
+			id = fmt.Sprintf("source_%d", span.SourceIndex)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,281 @@
+package rag
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+/* streamDocuments are the retrieved texts the stream tests cite */
+var streamDocuments = []string{
+	"Orders ship from the Berlin warehouse within two days.",
+	"Refunds are processed by the finance team every Friday.",
+}
+
+/* TestCitationTracker checks which sentences of a growing answer are cited, when, and how coverage is scored */
+func TestCitationTracker(t *testing.T) {
+	sources := []map[string]interface{}{{"document_id": "doc-a"}, {"id": 7}}
+
+	type span struct {
+		text   string
+		source int
+	}
+	tests := []struct {
+		name     string
+		chunks   []string
+		sources  []map[string]interface{}
+		streamed int // Spans returned before the final call
+		spans    []span
+		accuracy float64
+		evidence float64
+		cited    []string
+	}{
+		{
+			name:     "sentences as they complete",
+			chunks:   []string{"Orders ship from Berlin wi", "thin two days. Refunds are processed every Friday by finance."},
+			sources:  sources,
+			streamed: 1,
+			spans: []span{
+				{text: "Orders ship from Berlin within two days.", source: 0},
+				{text: "Refunds are processed every Friday by finance.", source: 1},
+			},
+			accuracy: 1,
+			evidence: 1,
+			cited:    []string{"doc-a", "7"},
+		},
+		{
+			name:     "trailing sentence waits for the end",
+			chunks:   []string{"Orders ship from Berlin within two days."},
+			sources:  sources,
+			spans:    []span{{text: "Orders ship from Berlin within two days.", source: 0}},
+			accuracy: 1,
+			evidence: 1,
+			cited:    []string{"doc-a"},
+		},
+		{
+			name:     "unsupported sentence",
+			chunks:   []string{"Orders ship from Berlin within two days. Payroll closes on the last Tuesday."},
+			sources:  sources,
+			streamed: 1,
+			spans:    []span{{text: "Orders ship from Berlin within two days.", source: 0}},
+			accuracy: 0.5,
+			evidence: 1,
+			cited:    []string{"doc-a"},
+		},
+		{
+			name:     "overlap below the minimum",
+			chunks:   []string{"Orders ship overnight via courier services."},
+			sources:  sources,
+			accuracy: 0,
+		},
+		{
+			name:     "short sentences are not counted",
+			chunks:   []string{"Yes. Orders ship from Berlin within two days."},
+			sources:  sources,
+			spans:    []span{{text: "Orders ship from Berlin within two days.", source: 0}},
+			accuracy: 1,
+			evidence: 1,
+			cited:    []string{"doc-a"},
+		},
+		{
+			name:     "repeated source",
+			chunks:   []string{"Orders ship from Berlin.\nThe Berlin warehouse ships orders within two days."},
+			sources:  sources,
+			streamed: 1,
+			spans: []span{
+				{text: "Orders ship from Berlin.", source: 0},
+				{text: "The Berlin warehouse ships orders within two days.", source: 0},
+			},
+			accuracy: 1,
+			evidence: (1 + 6.0/7) / 2,
+			cited:    []string{"doc-a"},
+		},
+		{
+			name:     "source without an id",
+			chunks:   []string{"Refunds are processed every Friday by finance."},
+			sources:  sources[:1],
+			spans:    []span{{text: "Refunds are processed every Friday by finance.", source: 1}},
+			accuracy: 1,
+			evidence: 1,
+			cited:    []string{"source_1"},
+		},
+		{
+			name:     "offsets after non-ASCII text",
+			chunks:   []string{"Grüße! Müller said orders ship from Berlin within two days."},
+			sources:  sources,
+			spans:    []span{{text: "Müller said orders ship from Berlin within two days.", source: 0}},
+			accuracy: 1,
+			evidence: 0.75,
+			cited:    []string{"doc-a"},
+		},
+		{name: "no answer", sources: sources},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newCitationTracker(tt.sources, streamDocuments)
+			var answer string
+			var got []CitationSpan
+			for _, chunk := range tt.chunks {
+				answer += chunk
+				got = append(got, tracker.advance(answer, false)...)
+			}
+			if len(got) != tt.streamed {
+				t.Errorf("expected %d spans before the end, got %d", tt.streamed, len(got))
+			}
+			got = append(got, tracker.advance(answer, true)...)
+
+			if len(got) != len(tt.spans) {
+				t.Fatalf("expected spans %v, got %+v", tt.spans, got)
+			}
+			runes := []rune(answer)
+			for i, want := range tt.spans {
+				if got[i].Text != want.text || got[i].SourceIndex != want.source {
+					t.Errorf("expected span %d to cite %q from source %d, got %q from %d", i, want.text, want.source, got[i].Text, got[i].SourceIndex)
+				}
+				if text := string(runes[got[i].Start:got[i].End]); text != got[i].Text {
+					t.Errorf("expected offsets of span %d to select %q, got %q", i, got[i].Text, text)
+				}
+			}
+			if len(tracker.spans) != len(got) {
+				t.Errorf("expected the tracker to keep every span, got %d of %d", len(tracker.spans), len(got))
+			}
+
+			accuracy, evidence := tracker.coverage()
+			if !approxEqual(accuracy, tt.accuracy) || !approxEqual(evidence, tt.evidence) {
+				t.Errorf("expected coverage %.2f/%.2f, got %.2f/%.2f", tt.accuracy, tt.evidence, accuracy, evidence)
+			}
+			if cited := tracker.citedSourceIDs(); len(cited) != len(tt.cited) || (len(cited) > 0 && !reflect.DeepEqual(cited, tt.cited)) {
+				t.Errorf("expected cited sources %v, got %v", tt.cited, cited)
+			}
+		})
+	}
+}
+
+/* approxEqual compares scores that went through division */
+func approxEqual(a, b float64) bool {
+	d := a - b
+	return d < 1e-9 && d > -1e-9
+}
+
+/* eventTypes lists the types of stream events in order */
+func eventTypes(events []StreamEvent) []string {
+	types := []string{}
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+/* TestAnswerStream checks that deltas and citations reach the client as they arrive, or are all held in block mode */
+func TestAnswerStream(t *testing.T) {
+	sources := []map[string]interface{}{{"document_id": "doc-a"}, {"document_id": "doc-b"}}
+	want := []string{StreamEventDelta, StreamEventCitation, StreamEventDelta, StreamEventCitation}
+
+	for _, hold := range []bool{false, true} {
+		name := "streaming"
+		if hold {
+			name = "holding"
+		}
+		t.Run(name, func(t *testing.T) {
+			var emitted []StreamEvent
+			stream := newAnswerStream(func(event StreamEvent) error {
+				emitted = append(emitted, event)
+				return nil
+			}, hold, sources, streamDocuments)
+
+			for _, delta := range []string{"Orders ship from Berlin within two days. ", "Refunds are processed every Friday by finance."} {
+				if err := stream.delta(delta); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := stream.finish(); err != nil {
+				t.Fatal(err)
+			}
+
+			sent, held := eventTypes(emitted), eventTypes(stream.held)
+			if hold {
+				sent, held = held, sent
+			}
+			if !reflect.DeepEqual(sent, want) || len(held) != 0 {
+				t.Errorf("expected %v sent and nothing else, got %v and %v", want, sent, held)
+			}
+		})
+	}
+}
+
+/* TestAnswerStreamInterrupted checks when generation that failed midway may fall back to blocking generation */
+func TestAnswerStreamInterrupted(t *testing.T) {
+	streamErr := errors.New("agent stream closed")
+	tests := []struct {
+		name     string
+		hold     bool
+		deltas   []string
+		err      error
+		answer   string
+		emitted  int
+		fallback bool
+	}{
+		{name: "nothing generated", fallback: true},
+		{name: "nothing generated while holding", hold: true, fallback: true},
+		{
+			name:    "deltas sent",
+			deltas:  []string{"Orders ship from Berlin within two days. Refunds are"},
+			err:     streamErr,
+			answer:  "Orders ship from Berlin within two days. Refunds are",
+			emitted: 2,
+		},
+		{
+			// The held deltas never reached the client, so the answer is regenerated rather than left partial
+			name:     "deltas held",
+			hold:     true,
+			deltas:   []string{"Orders ship from Berlin within two days. Refunds are"},
+			fallback: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var emitted []StreamEvent
+			stream := newAnswerStream(func(event StreamEvent) error {
+				emitted = append(emitted, event)
+				return nil
+			}, tt.hold, []map[string]interface{}{{"document_id": "doc-a"}}, streamDocuments)
+			for _, delta := range tt.deltas {
+				if err := stream.delta(delta); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := stream.interrupted(streamErr)
+			if err != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if got := stream.answer.String(); got != tt.answer {
+				t.Errorf("expected answer %q, got %q", tt.answer, got)
+			}
+			if len(emitted) != tt.emitted {
+				t.Errorf("expected %d events sent, got %v", tt.emitted, eventTypes(emitted))
+			}
+			if !tt.fallback {
+				return
+			}
+
+			// The fallback reply is cited on its own, without spans of the dropped answer
+			if err := stream.delta("Refunds are processed every Friday by finance."); err != nil {
+				t.Fatal(err)
+			}
+			if err := stream.finish(); err != nil {
+				t.Fatal(err)
+			}
+			if len(stream.tracker.spans) != 1 || stream.tracker.spans[0].SourceIndex != 1 || stream.tracker.spans[0].Start != 0 {
+				t.Errorf("expected one citation of the fallback reply, got %+v", stream.tracker.spans)
+			}
+			events := emitted
+			if tt.hold {
+				events = stream.held
+			}
+			if types := eventTypes(events); !reflect.DeepEqual(types, []string{StreamEventDelta, StreamEventCitation}) {
+				t.Errorf("expected only the fallback reply and its citation, got %v", types)
+			}
+		})
+	}
+}
@@ -7,12 +7,15 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/warehouse"
 )
 
 /* ReportingService provides advanced reporting functionality */
 type ReportingService struct {
-	pool *pgxpool.Pool
+	pool      *pgxpool.Pool
+	templates *warehouse.QueryTemplateService
 }
 
 /* NewReportingService creates a new reporting service */
@@ -20,6 +23,11 @@ func NewReportingService(pool *pgxpool.Pool) *ReportingService {
 	return &ReportingService{pool: pool}
 }
 
+/* NewReportingServiceWithTemplates creates a reporting service whose sections can run query templates */
+func NewReportingServiceWithTemplates(pool *pgxpool.Pool, templates *warehouse.QueryTemplateService) *ReportingService {
+	return &ReportingService{pool: pool, templates: templates}
+}
+
 /* Report represents a custom report */
 type Report struct {
 	ID            uuid.UUID              `json:"id"`
@@ -50,6 +58,8 @@ type ReportSection struct {
 	Title       string                 `json:"title"`
 	Type        string                 `json:"type"` // "table", "chart", "summary", "custom"
 	DataSource  string                 `json:"data_source"` // SQL query, API endpoint, etc.
+	TemplateID     *uuid.UUID             `json:"template_id,omitempty"`     // Query template used instead of DataSource
+	TemplateParams map[string]interface{} `json:"template_params,omitempty"` // Parameter values for the template
 	Columns     []string               `json:"columns,omitempty"`
 	Config      map[string]interface{} `json:"config,omitempty"`
 }
@@ -87,8 +97,19 @@ type ReportSchedule struct {
 	Recipients []string  `json:"recipients,omitempty"`
 }
 
-/* CreateReport creates a new report */
+/* CreateReport creates a new report once every section's SQL validates and its templates are visible to the creator */
 func (s *ReportingService) CreateReport(ctx context.Context, report Report) (*Report, error) {
+	var ownerID *string
+	if report.CreatedBy != uuid.Nil {
+		owner := report.CreatedBy.String()
+		ownerID = &owner
+	}
+	for _, section := range report.Definition.Sections {
+		if err := s.checkSection(ctx, section, ownerID); err != nil {
+			return nil, err
+		}
+	}
+
 	report.ID = uuid.New()
 	report.CreatedAt = time.Now()
 	report.UpdatedAt = time.Now()
@@ -114,6 +135,23 @@ func (s *ReportingService) CreateReport(ctx context.Context, report Report) (*Re
 	return &report, nil
 }
 
+/* checkSection rejects section SQL the warehouse would refuse and templates the owner cannot run */
+func (s *ReportingService) checkSection(ctx context.Context, section ReportSection, ownerID *string) error {
+	if section.TemplateID != nil {
+		if s.templates == nil {
+			return fmt.Errorf("section %s references a query template but templates are not configured", section.ID)
+		}
+		_, err := s.templates.GetTemplate(ctx, *section.TemplateID, ownerID)
+		return err
+	}
+	if section.DataSource != "" {
+		if _, err := warehouse.ValidateSQL(section.DataSource, warehouse.DefaultSQLPolicy()); err != nil {
+			return fmt.Errorf("invalid SQL in section %s: %w", section.ID, err)
+		}
+	}
+	return nil
+}
+
 /* GenerateReport generates a report and returns the result */
 func (s *ReportingService) GenerateReport(ctx context.Context, reportID uuid.UUID) (*ReportResult, error) {
 	report, err := s.GetReport(ctx, reportID)
@@ -128,10 +166,17 @@ func (s *ReportingService) GenerateReport(ctx context.Context, reportID uuid.UUI
 		Status:     "completed",
 	}
 
+	// Template sections run with the report owner's access to templates
+	var ownerID *string
+	if report.CreatedBy != uuid.Nil {
+		owner := report.CreatedBy.String()
+		ownerID = &owner
+	}
+
 	// Generate report sections
 	sections := []ReportSectionResult{}
 	for _, section := range report.Definition.Sections {
-		sectionResult, err := s.generateSection(ctx, section, report.Definition.Filters)
+		sectionResult, err := s.generateSection(ctx, section, report.Definition.Filters, ownerID)
 		if err != nil {
 			result.Status = "partial"
 			continue
@@ -155,7 +200,7 @@ func (s *ReportingService) GenerateReport(ctx context.Context, reportID uuid.UUI
 
 /* generateSection generates a single report section */
 func (s *ReportingService) generateSection(ctx context.Context,
-	section ReportSection, filters []ReportFilter) (ReportSectionResult, error) {
+	section ReportSection, filters []ReportFilter, ownerID *string) (ReportSectionResult, error) {
 
 	result := ReportSectionResult{
 		SectionID: section.ID,
@@ -163,9 +208,33 @@ func (s *ReportingService) generateSection(ctx context.Context,
 		Type:      section.Type,
 	}
 
-	// Execute data source query
+	// Template sections bind their parameter values server-side
+	if section.TemplateID != nil {
+		if s.templates == nil {
+			return result, fmt.Errorf("section %s references a query template but templates are not configured", section.ID)
+		}
+		templateResult, err := s.templates.ExecuteTemplate(ctx, *section.TemplateID, ownerID, section.TemplateParams)
+		if err != nil {
+			return result, fmt.Errorf("failed to execute template: %w", err)
+		}
+		result.Data = templateResult.Rows
+		return result, nil
+	}
+
+	// Execute data source query under the same rules as warehouse queries
 	if section.DataSource != "" {
-		rows, err := s.pool.Query(ctx, section.DataSource)
+		validated, err := warehouse.ValidateSQL(section.DataSource, warehouse.DefaultSQLPolicy())
+		if err != nil {
+			return result, fmt.Errorf("invalid SQL in section %s: %w", section.ID, err)
+		}
+
+		tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+		if err != nil {
+			return result, fmt.Errorf("failed to begin section transaction: %w", err)
+		}
+		defer tx.Rollback(ctx)
+
+		rows, err := tx.Query(ctx, validated.SQL)
 		if err != nil {
 			return result, fmt.Errorf("failed to execute query: %w", err)
 		}
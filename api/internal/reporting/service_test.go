@@ -0,0 +1,43 @@
+package reporting
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/neurondb/NeuronIP/api/internal/warehouse"
+)
+
+/* TestCheckSection checks the sections a report is refused for before anything runs */
+func TestCheckSection(t *testing.T) {
+	templateID := uuid.New()
+	tests := []struct {
+		name    string
+		section ReportSection
+		errMsg  string
+	}{
+		{name: "select", section: ReportSection{ID: "orders", DataSource: "SELECT id FROM orders"}},
+		{name: "no data source", section: ReportSection{ID: "notes", Type: "summary"}},
+		{name: "write", section: ReportSection{ID: "wipe", DataSource: "DELETE FROM orders"}, errMsg: "only SELECT"},
+		{name: "catalog read", section: ReportSection{ID: "roles", DataSource: "SELECT * FROM pg_catalog.pg_authid"}, errMsg: "invalid SQL in section roles"},
+		{name: "template without templates", section: ReportSection{ID: "sales", TemplateID: &templateID}, errMsg: "templates are not configured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&ReportingService{}).checkSection(context.Background(), tt.section, nil)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+			if tt.section.DataSource != "" && warehouse.AsSQLValidationError(err) == nil {
+				t.Errorf("expected a SQL validation error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,576 @@
+package semantic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/neurondb"
+)
+
+/* Index advisor thresholds */
+const (
+	exactSearchMaxRows    = 5000    // Below this, exact scans are fast enough that an index only costs recall
+	ivfMinRows            = 1000000 // IVF is only preferred for very large, lightly queried tables
+	ivfMaxDailyQueries    = 1000    // Above this, HNSW's query speed outweighs its build cost
+	maxIndexedDimensions  = 2000    // pgvector HNSW and IVFFlat limit for vector columns
+	TargetIndexRecall     = 0.95    // Recall@k the advisor tunes search parameters towards
+	maxRecallTuningRounds = 3
+)
+
+var (
+	vectorIdentifierPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	indexParamPattern       = regexp.MustCompile(`(\w+)\s*=\s*'?(\d+)'?`)
+)
+
+/* IndexAdvisorService recommends, builds and benchmarks vector indexes */
+type IndexAdvisorService struct {
+	pool           *pgxpool.Pool
+	neurondbClient *neurondb.Client
+}
+
+/* NewIndexAdvisorService creates a new vector index advisor */
+func NewIndexAdvisorService(pool *pgxpool.Pool, neurondbClient *neurondb.Client) *IndexAdvisorService {
+	return &IndexAdvisorService{pool: pool, neurondbClient: neurondbClient}
+}
+
+/* VectorIndexInfo describes an existing vector index */
+type VectorIndexInfo struct {
+	Name      string         `json:"name"`
+	Type      string         `json:"type"` // hnsw, ivf
+	Params    map[string]int `json:"params"`
+	SizeBytes int64          `json:"size_bytes"`
+}
+
+/* VectorIndexStats describes the vector column an index is built on */
+type VectorIndexStats struct {
+	TableName    string           `json:"table_name"`
+	ColumnName   string           `json:"column_name"`
+	RowCount     int64            `json:"row_count"`
+	Dimensions   int              `json:"dimensions"`
+	DailyQueries float64          `json:"daily_queries"`
+	CurrentIndex *VectorIndexInfo `json:"current_index,omitempty"`
+}
+
+/* IndexRecommendation is the advisor's choice of index type and parameters */
+type IndexRecommendation struct {
+	IndexType string         `json:"index_type"` // hnsw, ivf, none
+	Params    map[string]int `json:"params"`     // m, ef_construction, ef_search or lists, probes
+	Reason    string         `json:"reason"`
+}
+
+/* IndexBenchmark compares index search against exact search on sampled queries */
+type IndexBenchmark struct {
+	SampleSize     int            `json:"sample_size"`
+	K              int            `json:"k"`
+	Recall         float64        `json:"recall"`
+	ExactLatencyMs float64        `json:"exact_latency_ms"`
+	IndexLatencyMs float64        `json:"index_latency_ms"`
+	SearchParams   map[string]int `json:"search_params"`
+}
+
+/* IndexAdvisory is a recorded analysis, recommendation and optional build of a vector index */
+type IndexAdvisory struct {
+	ID             uuid.UUID           `json:"id"`
+	IndexName      string              `json:"index_name"`
+	Stats          VectorIndexStats    `json:"stats"`
+	Recommendation IndexRecommendation `json:"recommendation"`
+	Status         string              `json:"status"` // recommended, building, built, unchanged, failed
+	Benchmark      *IndexBenchmark     `json:"benchmark,omitempty"`
+	ErrorMessage   *string             `json:"error_message,omitempty"`
+	CreatedAt      time.Time           `json:"created_at"`
+	AppliedAt      *time.Time          `json:"applied_at,omitempty"`
+}
+
+/* AdviseIndexRequest requests an index recommendation for a vector column in the neuronip schema */
+type AdviseIndexRequest struct {
+	TableName  string `json:"table_name"`
+	ColumnName string `json:"column_name"`
+	Apply      bool   `json:"apply"`     // Build or rebuild the index when it differs from the recommendation
+	Benchmark  bool   `json:"benchmark"` // Measure recall against exact search
+	SampleSize int    `json:"sample_size,omitempty"`
+	K          int    `json:"k,omitempty"`
+}
+
+/* VectorIndexName returns the advisor-managed index name for a vector column */
+func VectorIndexName(tableName, columnName string) string {
+	return fmt.Sprintf("idx_%s_%s_vector", tableName, columnName)
+}
+
+/* Analyze collects size, dimensionality, query volume and the current index of a vector column */
+func (s *IndexAdvisorService) Analyze(ctx context.Context, tableName, columnName string) (*VectorIndexStats, error) {
+	if !vectorIdentifierPattern.MatchString(tableName) || !vectorIdentifierPattern.MatchString(columnName) {
+		return nil, fmt.Errorf("invalid table or column name")
+	}
+
+	stats := &VectorIndexStats{TableName: tableName, ColumnName: columnName}
+
+	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM neuronip.%s WHERE %s IS NOT NULL`, tableName, columnName)
+	if err := s.pool.QueryRow(ctx, countQuery).Scan(&stats.RowCount); err != nil {
+		return nil, fmt.Errorf("failed to get row count: %w", err)
+	}
+
+	dimsQuery := fmt.Sprintf(`SELECT vector_dims(%s) FROM neuronip.%s WHERE %s IS NOT NULL LIMIT 1`, columnName, tableName, columnName)
+	err := s.pool.QueryRow(ctx, dimsQuery).Scan(&stats.Dimensions)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to get vector dimensions: %w", err)
+	}
+
+	// Query volume from retrievals recorded against this table over the last week; absent metrics mean no signal
+	s.pool.QueryRow(ctx, `
+		SELECT COUNT(*)::float / 7
+		FROM neuronip.retrieval_metrics
+		WHERE created_at > NOW() - INTERVAL '7 days' AND metadata->>'table' = $1`, tableName).Scan(&stats.DailyQueries)
+
+	current, err := s.currentIndex(ctx, tableName, VectorIndexName(tableName, columnName))
+	if err != nil {
+		return nil, err
+	}
+	stats.CurrentIndex = current
+
+	return stats, nil
+}
+
+/* currentIndex loads the definition of an existing vector index, or nil when it does not exist or is invalid */
+func (s *IndexAdvisorService) currentIndex(ctx context.Context, tableName, indexName string) (*VectorIndexInfo, error) {
+	query := `
+		SELECT i.relname, am.amname, pg_get_indexdef(i.oid), pg_relation_size(i.oid)
+		FROM pg_index x
+		JOIN pg_class i ON i.oid = x.indexrelid
+		JOIN pg_class t ON t.oid = x.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_am am ON am.oid = i.relam
+		WHERE n.nspname = 'neuronip' AND t.relname = $1 AND i.relname = $2 AND x.indisvalid`
+
+	var info VectorIndexInfo
+	var accessMethod, definition string
+	err := s.pool.QueryRow(ctx, query, tableName, indexName).Scan(&info.Name, &accessMethod, &definition, &info.SizeBytes)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current index: %w", err)
+	}
+
+	info.Type = accessMethod
+	if accessMethod == "ivfflat" {
+		info.Type = "ivf"
+	}
+	info.Params = make(map[string]int)
+	for _, match := range indexParamPattern.FindAllStringSubmatch(definition, -1) {
+		if value, err := strconv.Atoi(match[2]); err == nil {
+			info.Params[match[1]] = value
+		}
+	}
+	return &info, nil
+}
+
+/* RecommendVectorIndex chooses an index type and parameters for a vector column */
+func RecommendVectorIndex(stats VectorIndexStats) IndexRecommendation {
+	if stats.Dimensions > maxIndexedDimensions {
+		return IndexRecommendation{
+			IndexType: "none",
+			Params:    map[string]int{},
+			Reason:    fmt.Sprintf("%d dimensions exceeds the %d dimension limit for vector indexes; use exact search or reduce dimensionality", stats.Dimensions, maxIndexedDimensions),
+		}
+	}
+	if stats.RowCount < exactSearchMaxRows {
+		return IndexRecommendation{
+			IndexType: "none",
+			Params:    map[string]int{},
+			Reason:    fmt.Sprintf("%d rows is small enough for exact search with perfect recall", stats.RowCount),
+		}
+	}
+
+	if stats.RowCount >= ivfMinRows && stats.DailyQueries < ivfMaxDailyQueries {
+		lists := int(math.Sqrt(float64(stats.RowCount)))
+		return IndexRecommendation{
+			IndexType: "ivf",
+			Params: map[string]int{
+				"lists":  lists,
+				"probes": int(math.Max(1, math.Sqrt(float64(lists)))),
+			},
+			Reason: fmt.Sprintf("%d rows with %.0f daily queries favors IVF's faster build and smaller memory footprint", stats.RowCount, stats.DailyQueries),
+		}
+	}
+
+	// Higher dimensional vectors need more graph connections for the same recall
+	m := 16
+	switch {
+	case stats.Dimensions <= 256:
+		m = 12
+	case stats.Dimensions > 1024:
+		m = 24
+	}
+
+	efConstruction, efSearch := 64, 40
+	switch {
+	case stats.RowCount >= 1000000:
+		efConstruction, efSearch = 200, 120
+	case stats.RowCount >= 100000:
+		efConstruction, efSearch = 128, 80
+	}
+
+	return IndexRecommendation{
+		IndexType: "hnsw",
+		Params: map[string]int{
+			"m":               m,
+			"ef_construction": efConstruction,
+			"ef_search":       efSearch,
+		},
+		Reason: fmt.Sprintf("%d rows of %d dimensions with %.0f daily queries favors HNSW's query speed and recall", stats.RowCount, stats.Dimensions, stats.DailyQueries),
+	}
+}
+
+/* matchesRecommendation reports whether an existing index already has the recommended build parameters */
+func matchesRecommendation(current *VectorIndexInfo, rec IndexRecommendation) bool {
+	if current == nil || current.Type != rec.IndexType {
+		return false
+	}
+	switch rec.IndexType {
+	case "hnsw":
+		return current.Params["m"] == rec.Params["m"] && current.Params["ef_construction"] == rec.Params["ef_construction"]
+	case "ivf":
+		return current.Params["lists"] == rec.Params["lists"]
+	}
+	return false
+}
+
+/* Advise analyzes a vector column, records a recommendation and optionally applies and benchmarks it */
+func (s *IndexAdvisorService) Advise(ctx context.Context, req AdviseIndexRequest) (*IndexAdvisory, error) {
+	stats, err := s.Analyze(ctx, req.TableName, req.ColumnName)
+	if err != nil {
+		return nil, err
+	}
+
+	advisory := &IndexAdvisory{
+		ID:             uuid.New(),
+		IndexName:      VectorIndexName(req.TableName, req.ColumnName),
+		Stats:          *stats,
+		Recommendation: RecommendVectorIndex(*stats),
+		Status:         "recommended",
+		CreatedAt:      time.Now(),
+	}
+	if err := s.saveAdvisory(ctx, advisory); err != nil {
+		return nil, err
+	}
+
+	rec := advisory.Recommendation
+	if req.Apply && rec.IndexType != "none" {
+		if matchesRecommendation(stats.CurrentIndex, rec) {
+			advisory.Status = "unchanged"
+		} else {
+			advisory.Status = "building"
+			s.updateAdvisory(ctx, advisory)
+
+			if err := s.applyRecommendation(ctx, advisory.IndexName, req.TableName, req.ColumnName, stats.CurrentIndex, rec); err != nil {
+				errMsg := err.Error()
+				advisory.Status = "failed"
+				advisory.ErrorMessage = &errMsg
+				s.updateAdvisory(ctx, advisory)
+				return advisory, nil
+			}
+			appliedAt := time.Now()
+			advisory.Status = "built"
+			advisory.AppliedAt = &appliedAt
+		}
+	}
+
+	// Benchmark whichever index now exists, tuning the search parameter towards the target recall
+	if req.Benchmark {
+		current, err := s.currentIndex(ctx, req.TableName, advisory.IndexName)
+		if err == nil && current != nil {
+			searchParams := searchParamsFor(current, rec)
+			benchmark, err := s.benchmarkWithTuning(ctx, req, current, searchParams)
+			if err != nil {
+				errMsg := fmt.Sprintf("benchmark failed: %v", err)
+				advisory.ErrorMessage = &errMsg
+			} else {
+				advisory.Benchmark = benchmark
+				if current.Type == rec.IndexType {
+					for k, v := range benchmark.SearchParams {
+						rec.Params[k] = v
+					}
+				}
+			}
+		}
+	}
+
+	s.updateAdvisory(ctx, advisory)
+	return advisory, nil
+}
+
+/* applyRecommendation builds the recommended index, rebuilding concurrently when one already exists */
+func (s *IndexAdvisorService) applyRecommendation(ctx context.Context, indexName, tableName, columnName string, current *VectorIndexInfo, rec IndexRecommendation) error {
+	fullTableName := fmt.Sprintf("neuronip.%s", tableName)
+	options := map[string]interface{}{}
+	for k, v := range rec.Params {
+		options[k] = v
+	}
+
+	if current == nil {
+		options["concurrently"] = true
+		return s.neurondbClient.CreateVectorIndex(ctx, indexName, fullTableName, columnName, rec.IndexType, options)
+	}
+	return s.neurondbClient.RebuildVectorIndex(ctx, indexName, fullTableName, columnName, rec.IndexType, options)
+}
+
+/* searchParamsFor picks the starting query-time parameter for an index */
+func searchParamsFor(current *VectorIndexInfo, rec IndexRecommendation) map[string]int {
+	if current.Type == "ivf" {
+		probes := rec.Params["probes"]
+		if current.Type != rec.IndexType || probes == 0 {
+			probes = int(math.Max(1, math.Sqrt(float64(current.Params["lists"]))))
+		}
+		return map[string]int{"probes": probes}
+	}
+	efSearch := rec.Params["ef_search"]
+	if current.Type != rec.IndexType || efSearch == 0 {
+		efSearch = 40
+	}
+	return map[string]int{"ef_search": efSearch}
+}
+
+/* benchmarkWithTuning benchmarks an index and doubles the search parameter until recall reaches the target */
+func (s *IndexAdvisorService) benchmarkWithTuning(ctx context.Context, req AdviseIndexRequest, current *VectorIndexInfo, searchParams map[string]int) (*IndexBenchmark, error) {
+	var benchmark *IndexBenchmark
+	for round := 0; round < maxRecallTuningRounds; round++ {
+		result, err := s.Benchmark(ctx, req.TableName, req.ColumnName, current.Type, searchParams, req.SampleSize, req.K)
+		if err != nil {
+			return nil, err
+		}
+		benchmark = result
+		if benchmark.Recall >= TargetIndexRecall {
+			break
+		}
+
+		next := make(map[string]int, len(searchParams))
+		for k, v := range searchParams {
+			next[k] = v
+		}
+		if current.Type == "ivf" {
+			next["probes"] = int(math.Min(float64(searchParams["probes"]*2), float64(current.Params["lists"])))
+		} else {
+			next["ef_search"] = int(math.Min(float64(searchParams["ef_search"]*2), 1000))
+		}
+		if next["probes"] == searchParams["probes"] && next["ef_search"] == searchParams["ef_search"] {
+			break // Search parameter is already at its maximum
+		}
+		searchParams = next
+	}
+	return benchmark, nil
+}
+
+/* Benchmark measures recall@k of index search against exact search for sampled vectors from the table */
+func (s *IndexAdvisorService) Benchmark(ctx context.Context, tableName, columnName, indexType string, searchParams map[string]int, sampleSize, k int) (*IndexBenchmark, error) {
+	if !vectorIdentifierPattern.MatchString(tableName) || !vectorIdentifierPattern.MatchString(columnName) {
+		return nil, fmt.Errorf("invalid table or column name")
+	}
+	if sampleSize <= 0 {
+		sampleSize = 20
+	}
+	if k <= 0 {
+		k = 10
+	}
+
+	sampleQuery := fmt.Sprintf(`
+		SELECT id::text, %s::text FROM neuronip.%s
+		WHERE %s IS NOT NULL
+		ORDER BY random()
+		LIMIT $1`, columnName, tableName, columnName)
+	rows, err := s.pool.Query(ctx, sampleQuery, sampleSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample query vectors: %w", err)
+	}
+	type sample struct{ id, vector string }
+	var samples []sample
+	for rows.Next() {
+		var smp sample
+		if err := rows.Scan(&smp.id, &smp.vector); err == nil {
+			samples = append(samples, smp)
+		}
+	}
+	rows.Close()
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no vectors to benchmark")
+	}
+
+	exactSettings := []string{"SET LOCAL enable_indexscan = off", "SET LOCAL enable_bitmapscan = off"}
+	indexSettings := []string{"SET LOCAL enable_seqscan = off"}
+	if indexType == "ivf" {
+		indexSettings = append(indexSettings, fmt.Sprintf("SET LOCAL ivfflat.probes = %d", searchParams["probes"]))
+	} else {
+		indexSettings = append(indexSettings, fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", searchParams["ef_search"]))
+	}
+
+	knnQuery := fmt.Sprintf(`
+		SELECT id::text FROM neuronip.%s
+		WHERE %s IS NOT NULL AND id::text != $2
+		ORDER BY %s <=> $1::vector
+		LIMIT $3`, tableName, columnName, columnName)
+
+	var totalRecall float64
+	var exactTotal, indexTotal time.Duration
+	for _, smp := range samples {
+		exactIDs, exactDuration, err := s.runKNN(ctx, exactSettings, knnQuery, smp.vector, smp.id, k)
+		if err != nil {
+			return nil, err
+		}
+		indexIDs, indexDuration, err := s.runKNN(ctx, indexSettings, knnQuery, smp.vector, smp.id, k)
+		if err != nil {
+			return nil, err
+		}
+		exactTotal += exactDuration
+		indexTotal += indexDuration
+
+		if len(exactIDs) == 0 {
+			totalRecall += 1
+			continue
+		}
+		found := make(map[string]bool, len(indexIDs))
+		for _, id := range indexIDs {
+			found[id] = true
+		}
+		hits := 0
+		for _, id := range exactIDs {
+			if found[id] {
+				hits++
+			}
+		}
+		totalRecall += float64(hits) / float64(len(exactIDs))
+	}
+
+	n := float64(len(samples))
+	return &IndexBenchmark{
+		SampleSize:     len(samples),
+		K:              k,
+		Recall:         totalRecall / n,
+		ExactLatencyMs: float64(exactTotal.Microseconds()) / 1000 / n,
+		IndexLatencyMs: float64(indexTotal.Microseconds()) / 1000 / n,
+		SearchParams:   searchParams,
+	}, nil
+}
+
+/* runKNN runs a nearest neighbour query with planner settings scoped to a read-only transaction */
+func (s *IndexAdvisorService) runKNN(ctx context.Context, settings []string, query, vector, excludeID string, k int) ([]string, time.Duration, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to begin benchmark transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, setting := range settings {
+		if _, err := tx.Exec(ctx, setting); err != nil {
+			return nil, 0, fmt.Errorf("failed to apply benchmark setting: %w", err)
+		}
+	}
+
+	start := time.Now()
+	rows, err := tx.Query(ctx, query, vector, excludeID, k)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to run benchmark query: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, time.Since(start), nil
+}
+
+/* saveAdvisory inserts an advisory record */
+func (s *IndexAdvisorService) saveAdvisory(ctx context.Context, advisory *IndexAdvisory) error {
+	var currentType *string
+	currentParams := map[string]int{}
+	if advisory.Stats.CurrentIndex != nil {
+		currentType = &advisory.Stats.CurrentIndex.Type
+		currentParams = advisory.Stats.CurrentIndex.Params
+	}
+	currentParamsJSON, _ := json.Marshal(currentParams)
+	recommendedParamsJSON, _ := json.Marshal(advisory.Recommendation.Params)
+
+	query := `
+		INSERT INTO neuronip.vector_index_advisories
+		(id, table_name, column_name, index_name, row_count, dimensions, daily_queries,
+		 current_index_type, current_params, recommended_type, recommended_params, reason, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+
+	_, err := s.pool.Exec(ctx, query,
+		advisory.ID, advisory.Stats.TableName, advisory.Stats.ColumnName, advisory.IndexName,
+		advisory.Stats.RowCount, advisory.Stats.Dimensions, advisory.Stats.DailyQueries,
+		currentType, currentParamsJSON, advisory.Recommendation.IndexType, recommendedParamsJSON,
+		advisory.Recommendation.Reason, advisory.Status, advisory.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save index advisory: %w", err)
+	}
+	return nil
+}
+
+/* updateAdvisory persists status, tuned parameters and benchmark results */
+func (s *IndexAdvisorService) updateAdvisory(ctx context.Context, advisory *IndexAdvisory) {
+	recommendedParamsJSON, _ := json.Marshal(advisory.Recommendation.Params)
+	var benchmarkJSON []byte
+	if advisory.Benchmark != nil {
+		benchmarkJSON, _ = json.Marshal(advisory.Benchmark)
+	}
+
+	s.pool.Exec(ctx, `
+		UPDATE neuronip.vector_index_advisories
+		SET status = $1, recommended_params = $2, benchmark = $3, error_message = $4, applied_at = $5
+		WHERE id = $6`,
+		advisory.Status, recommendedParamsJSON, benchmarkJSON, advisory.ErrorMessage, advisory.AppliedAt, advisory.ID)
+}
+
+/* GetLatestAdvisory returns the most recent advisory for an index */
+func (s *IndexAdvisorService) GetLatestAdvisory(ctx context.Context, indexName string) (*IndexAdvisory, error) {
+	query := `
+		SELECT id, table_name, column_name, index_name, row_count, dimensions, daily_queries,
+		       current_index_type, current_params, recommended_type, recommended_params, reason,
+		       status, benchmark, error_message, created_at, applied_at
+		FROM neuronip.vector_index_advisories
+		WHERE index_name = $1
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	var advisory IndexAdvisory
+	var currentType, reason *string
+	var currentParamsJSON, recommendedParamsJSON, benchmarkJSON []byte
+	err := s.pool.QueryRow(ctx, query, indexName).Scan(
+		&advisory.ID, &advisory.Stats.TableName, &advisory.Stats.ColumnName, &advisory.IndexName,
+		&advisory.Stats.RowCount, &advisory.Stats.Dimensions, &advisory.Stats.DailyQueries,
+		&currentType, &currentParamsJSON, &advisory.Recommendation.IndexType, &recommendedParamsJSON, &reason,
+		&advisory.Status, &benchmarkJSON, &advisory.ErrorMessage, &advisory.CreatedAt, &advisory.AppliedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index advisory: %w", err)
+	}
+
+	if currentType != nil {
+		advisory.Stats.CurrentIndex = &VectorIndexInfo{Name: indexName, Type: *currentType}
+		json.Unmarshal(currentParamsJSON, &advisory.Stats.CurrentIndex.Params)
+	}
+	if reason != nil {
+		advisory.Recommendation.Reason = *reason
+	}
+	json.Unmarshal(recommendedParamsJSON, &advisory.Recommendation.Params)
+	if benchmarkJSON != nil {
+		var benchmark IndexBenchmark
+		if err := json.Unmarshal(benchmarkJSON, &benchmark); err == nil {
+			advisory.Benchmark = &benchmark
+		}
+	}
+
+	return &advisory, nil
+}
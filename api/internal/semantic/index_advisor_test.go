@@ -0,0 +1,136 @@
+package semantic
+
+import (
+	"reflect"
+	"testing"
+)
+
+/* TestRecommendVectorIndex checks the index type and parameters chosen across table sizes, dimensions and query volume */
+func TestRecommendVectorIndex(t *testing.T) {
+	tests := []struct {
+		name      string
+		stats     VectorIndexStats
+		indexType string
+		params    map[string]int
+	}{
+		{name: "too many dimensions", stats: VectorIndexStats{RowCount: 1000000, Dimensions: 3072}, indexType: "none", params: map[string]int{}},
+		{name: "small table", stats: VectorIndexStats{RowCount: 4999, Dimensions: 384}, indexType: "none", params: map[string]int{}},
+		{
+			name:      "smallest indexed table",
+			stats:     VectorIndexStats{RowCount: 5000, Dimensions: 384, DailyQueries: 10},
+			indexType: "hnsw",
+			params:    map[string]int{"m": 16, "ef_construction": 64, "ef_search": 40},
+		},
+		{
+			name:      "low dimensions",
+			stats:     VectorIndexStats{RowCount: 50000, Dimensions: 256},
+			indexType: "hnsw",
+			params:    map[string]int{"m": 12, "ef_construction": 64, "ef_search": 40},
+		},
+		{
+			name:      "high dimensions at the limit",
+			stats:     VectorIndexStats{RowCount: 200000, Dimensions: 2000, DailyQueries: 5000},
+			indexType: "hnsw",
+			params:    map[string]int{"m": 24, "ef_construction": 128, "ef_search": 80},
+		},
+		{
+			name:      "large lightly queried table",
+			stats:     VectorIndexStats{RowCount: 1000000, Dimensions: 768, DailyQueries: 500},
+			indexType: "ivf",
+			params:    map[string]int{"lists": 1000, "probes": 31},
+		},
+		{
+			name:      "large busy table",
+			stats:     VectorIndexStats{RowCount: 1000000, Dimensions: 768, DailyQueries: 1000},
+			indexType: "hnsw",
+			params:    map[string]int{"m": 16, "ef_construction": 200, "ef_search": 120},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := RecommendVectorIndex(tt.stats)
+			if rec.IndexType != tt.indexType || !reflect.DeepEqual(rec.Params, tt.params) {
+				t.Errorf("expected %s %v, got %s %v", tt.indexType, tt.params, rec.IndexType, rec.Params)
+			}
+			if rec.Reason == "" {
+				t.Error("expected a reason for the recommendation")
+			}
+		})
+	}
+}
+
+/* TestMatchesRecommendation checks that only build parameters decide whether an index is rebuilt */
+func TestMatchesRecommendation(t *testing.T) {
+	hnsw := IndexRecommendation{IndexType: "hnsw", Params: map[string]int{"m": 16, "ef_construction": 64, "ef_search": 40}}
+	ivf := IndexRecommendation{IndexType: "ivf", Params: map[string]int{"lists": 1000, "probes": 31}}
+
+	tests := []struct {
+		name    string
+		current *VectorIndexInfo
+		rec     IndexRecommendation
+		matches bool
+	}{
+		{name: "no index", rec: hnsw},
+		{name: "same hnsw", current: &VectorIndexInfo{Type: "hnsw", Params: map[string]int{"m": 16, "ef_construction": 64}}, rec: hnsw, matches: true},
+		{name: "different m", current: &VectorIndexInfo{Type: "hnsw", Params: map[string]int{"m": 24, "ef_construction": 64}}, rec: hnsw},
+		{name: "same ivf", current: &VectorIndexInfo{Type: "ivf", Params: map[string]int{"lists": 1000}}, rec: ivf, matches: true},
+		{name: "different lists", current: &VectorIndexInfo{Type: "ivf", Params: map[string]int{"lists": 500}}, rec: ivf},
+		{name: "different type", current: &VectorIndexInfo{Type: "ivf", Params: map[string]int{"lists": 1000}}, rec: hnsw},
+		{name: "exact search recommended", current: &VectorIndexInfo{Type: "none"}, rec: IndexRecommendation{IndexType: "none"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesRecommendation(tt.current, tt.rec); got != tt.matches {
+				t.Errorf("expected %v, got %v", tt.matches, got)
+			}
+		})
+	}
+}
+
+/* TestSearchParamsFor checks the starting search parameter for the index that exists */
+func TestSearchParamsFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		current *VectorIndexInfo
+		rec     IndexRecommendation
+		want    map[string]int
+	}{
+		{
+			name:    "recommended hnsw",
+			current: &VectorIndexInfo{Type: "hnsw"},
+			rec:     IndexRecommendation{IndexType: "hnsw", Params: map[string]int{"ef_search": 80}},
+			want:    map[string]int{"ef_search": 80},
+		},
+		{
+			name:    "hnsw kept over an ivf recommendation",
+			current: &VectorIndexInfo{Type: "hnsw"},
+			rec:     IndexRecommendation{IndexType: "ivf", Params: map[string]int{"lists": 1000, "probes": 31}},
+			want:    map[string]int{"ef_search": 40},
+		},
+		{
+			name:    "recommended ivf",
+			current: &VectorIndexInfo{Type: "ivf", Params: map[string]int{"lists": 1000}},
+			rec:     IndexRecommendation{IndexType: "ivf", Params: map[string]int{"lists": 1000, "probes": 31}},
+			want:    map[string]int{"probes": 31},
+		},
+		{
+			name:    "ivf kept over an hnsw recommendation",
+			current: &VectorIndexInfo{Type: "ivf", Params: map[string]int{"lists": 400}},
+			rec:     IndexRecommendation{IndexType: "hnsw", Params: map[string]int{"ef_search": 80}},
+			want:    map[string]int{"probes": 20},
+		},
+		{
+			name:    "ivf without lists",
+			current: &VectorIndexInfo{Type: "ivf", Params: map[string]int{}},
+			rec:     IndexRecommendation{IndexType: "none", Params: map[string]int{}},
+			want:    map[string]int{"probes": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchParamsFor(tt.current, tt.rec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/cache"
 	"github.com/neurondb/NeuronIP/api/internal/neurondb"
 )
 
@@ -15,6 +16,7 @@ import (
 type PipelineService struct {
 	pool           *pgxpool.Pool
 	neurondbClient *neurondb.Client
+	semanticCache  *cache.SemanticCacheService
 }
 
 /* NewPipelineService creates a new pipeline service */
@@ -30,6 +32,15 @@ func NewPipelineServiceWithClient(pool *pgxpool.Pool, neurondbClient *neurondb.C
 	}
 }
 
+/* NewPipelineServiceWithSemanticCache creates a pipeline service that invalidates cached answers for replayed documents */
+func NewPipelineServiceWithSemanticCache(pool *pgxpool.Pool, neurondbClient *neurondb.Client, semanticCache *cache.SemanticCacheService) *PipelineService {
+	return &PipelineService{
+		pool:           pool,
+		neurondbClient: neurondbClient,
+		semanticCache:  semanticCache,
+	}
+}
+
 /* Pipeline represents a versioned chunking/embedding pipeline */
 type Pipeline struct {
 	ID                uuid.UUID              `json:"id"`
@@ -227,6 +238,9 @@ func (s *PipelineService) ReplayPipeline(ctx context.Context, documentIDs []uuid
 		}
 	}
 
+	// Answers retrieved from the old chunks no longer match the index
+	invalidateCachedAnswers(ctx, s.semanticCache, nil, documentIDs...)
+
 	// Record replay
 	replayQuery := `
 		INSERT INTO neuronip.pipeline_replays (
@@ -10,9 +10,12 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/cache"
 	"github.com/neurondb/NeuronIP/api/internal/compliance"
 	"github.com/neurondb/NeuronIP/api/internal/db"
+	"github.com/neurondb/NeuronIP/api/internal/ingestion/dedup"
 	"github.com/neurondb/NeuronIP/api/internal/knowledgegraph"
+	"github.com/neurondb/NeuronIP/api/internal/logging"
 	"github.com/neurondb/NeuronIP/api/internal/mcp"
 	"github.com/neurondb/NeuronIP/api/internal/neurondb"
 )
@@ -24,6 +27,9 @@ type Service struct {
 	neurondbClient *neurondb.Client
 	mcpClient      *mcp.Client
 	auditService   *compliance.AuditService
+	dedupService   *dedup.Service
+	indexAdvisor   *IndexAdvisorService
+	semanticCache  *cache.SemanticCacheService
 }
 
 /* NewService creates a new semantic search service */
@@ -34,6 +40,30 @@ func NewService(queries *db.Queries, pool *pgxpool.Pool, neurondbClient *neurond
 		neurondbClient: neurondbClient,
 		mcpClient:      mcpClient,
 		auditService:   compliance.NewAuditService(pool),
+		dedupService:   dedup.NewService(pool),
+		indexAdvisor:   NewIndexAdvisorService(pool, neurondbClient),
+	}
+}
+
+/* NewServiceWithSemanticCache creates a semantic search service that invalidates cached answers when documents change */
+func NewServiceWithSemanticCache(queries *db.Queries, pool *pgxpool.Pool, neurondbClient *neurondb.Client, mcpClient *mcp.Client, semanticCache *cache.SemanticCacheService) *Service {
+	service := NewService(queries, pool, neurondbClient, mcpClient)
+	service.semanticCache = semanticCache
+	return service
+}
+
+/* invalidateCachedAnswers drops cached answers that cite the documents or were retrieved from their collection */
+func invalidateCachedAnswers(ctx context.Context, semanticCache *cache.SemanticCacheService, collectionID *uuid.UUID, documentIDs ...uuid.UUID) {
+	if semanticCache == nil {
+		return
+	}
+	if _, err := semanticCache.InvalidateDocuments(ctx, documentIDs); err != nil {
+		logging.Error("Failed to invalidate cached answers for documents", "documents", documentIDs, "error", err)
+	}
+	if collectionID != nil {
+		if _, err := semanticCache.InvalidateCollection(ctx, *collectionID); err != nil {
+			logging.Error("Failed to invalidate cached answers for collection", "collection_id", *collectionID, "error", err)
+		}
 	}
 }
 
@@ -44,6 +74,7 @@ type SearchRequest struct {
 	Limit        int
 	Threshold    float64
 	DistanceMetric string // "cosine" (default), "l2", "inner_product"
+	CollapseDuplicates bool // Return one result per canonical document
 }
 
 /* SearchResult represents a search result */
@@ -54,6 +85,7 @@ type SearchResult struct {
 	ContentType  string                 `json:"content_type"`
 	Similarity   float64                `json:"similarity"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	DuplicateCount int                  `json:"duplicate_count,omitempty"` // Results collapsed into this one
 }
 
 /* Search performs semantic search on knowledge documents */
@@ -75,14 +107,64 @@ func (s *Service) Search(ctx context.Context, req SearchRequest) ([]SearchResult
 	}
 
 	// Use appropriate search method based on distance metric
+	var results []SearchResult
 	switch req.DistanceMetric {
 	case "l2":
-		return s.searchWithL2(ctx, req, queryEmbedding)
+		results, err = s.searchWithL2(ctx, req, queryEmbedding)
 	case "inner_product":
-		return s.searchWithInnerProduct(ctx, req, queryEmbedding)
+		results, err = s.searchWithInnerProduct(ctx, req, queryEmbedding)
 	default: // cosine (default)
-		return s.searchWithCosine(ctx, req, queryEmbedding)
+		results, err = s.searchWithCosine(ctx, req, queryEmbedding)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if req.CollapseDuplicates {
+		return s.collapseDuplicates(ctx, results), nil
 	}
+	return results, nil
+}
+
+/* ListDocumentDuplicates lists documents linked to a canonical document as exact or near duplicates */
+func (s *Service) ListDocumentDuplicates(ctx context.Context, canonicalID uuid.UUID) ([]dedup.Duplicate, error) {
+	return s.dedupService.ListDuplicates(ctx, canonicalID)
+}
+
+/* collapseDuplicates keeps the best-ranked result per canonical document, counting the rest */
+func (s *Service) collapseDuplicates(ctx context.Context, results []SearchResult) []SearchResult {
+	documentIDs := make([]uuid.UUID, 0, len(results))
+	for _, result := range results {
+		documentIDs = append(documentIDs, result.DocumentID)
+	}
+
+	canonicalIDs, err := s.dedupService.CanonicalIDs(ctx, documentIDs)
+	if err != nil {
+		// Fall back to collapsing repeated chunks of the same document
+		canonicalIDs = make(map[uuid.UUID]uuid.UUID)
+	}
+
+	positions := make(map[uuid.UUID]int)
+	seen := make(map[uuid.UUID]bool)
+	collapsed := make([]SearchResult, 0, len(results))
+	for _, result := range results {
+		key := result.DocumentID
+		if canonicalID, ok := canonicalIDs[key]; ok {
+			key = canonicalID
+		}
+		if pos, ok := positions[key]; ok {
+			// Only distinct duplicate documents count; repeated chunks of one document do not
+			if !seen[result.DocumentID] {
+				seen[result.DocumentID] = true
+				collapsed[pos].DuplicateCount++
+			}
+			continue
+		}
+		positions[key] = len(collapsed)
+		seen[result.DocumentID] = true
+		collapsed = append(collapsed, result)
+	}
+	return collapsed
 }
 
 /* searchWithCosine performs cosine similarity search */
@@ -286,32 +368,6 @@ func (s *Service) CalculateVectorSimilarity(ctx context.Context, embedding1Str,
 	return 0, fmt.Errorf("similarity score not found in MCP result")
 }
 
-/* parseEmbeddingString parses an embedding string to float64 slice */
-func parseEmbeddingString(embeddingStr string) ([]float64, error) {
-	// Remove brackets and whitespace
-	embeddingStr = strings.TrimSpace(embeddingStr)
-	embeddingStr = strings.TrimPrefix(embeddingStr, "[")
-	embeddingStr = strings.TrimSuffix(embeddingStr, "]")
-	
-	// Split by comma
-	parts := strings.Split(embeddingStr, ",")
-	result := make([]float64, 0, len(parts))
-	
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if part == "" {
-			continue
-		}
-		val, err := strconv.ParseFloat(part, 64)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse embedding value '%s': %w", part, err)
-		}
-		result = append(result, val)
-	}
-	
-	return result, nil
-}
-
 /* PerformVectorArithmetic performs arithmetic operations on vectors using MCP */
 func (s *Service) PerformVectorArithmetic(ctx context.Context, embedding1Str, embedding2Str string, operation string) ([]float64, error) {
 	if s.mcpClient == nil {
@@ -589,7 +645,7 @@ func (s *Service) CompareDocuments(ctx context.Context, docID1, docID2 uuid.UUID
 /* ensureVectorIndex ensures a vector index exists on the specified table and column */
 func (s *Service) ensureVectorIndex(ctx context.Context, tableName string, columnName string) error {
 	// Check if index already exists
-	indexName := fmt.Sprintf("idx_%s_%s_vector", tableName, columnName)
+	indexName := VectorIndexName(tableName, columnName)
 	checkQuery := `
 		SELECT COUNT(*) FROM pg_indexes 
 		WHERE indexname = $1 AND tablename = $2`
@@ -600,31 +656,22 @@ func (s *Service) ensureVectorIndex(ctx context.Context, tableName string, colum
 		return nil // Index already exists
 	}
 
-	// Get row count to determine index parameters
-	var rowCount int
-	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM neuronip.%s WHERE %s IS NOT NULL`, tableName, columnName)
-	err = s.pool.QueryRow(ctx, countQuery).Scan(&rowCount)
+	// Let the index advisor choose index type and parameters from size, dimensionality and query volume
+	stats, err := s.indexAdvisor.Analyze(ctx, tableName, columnName)
 	if err != nil {
-		return fmt.Errorf("failed to get row count: %w", err)
+		return fmt.Errorf("failed to analyze vector column: %w", err)
+	}
+	recommendation := RecommendVectorIndex(*stats)
+	if recommendation.IndexType == "none" {
+		return nil // Exact search is preferable at this size
 	}
 
-	// Choose index type and parameters based on data size
-	var indexType string
-	var options map[string]interface{}
-	
-	if rowCount < 10000 {
-		// Small dataset - use IVF
-		indexType = "ivf"
-		options = map[string]interface{}{
-			"lists": 100,
-		}
-	} else {
-		// Large dataset - use HNSW
-		indexType = "hnsw"
-		options = map[string]interface{}{
-			"m":              16,
-			"ef_construction": 64,
-		}
+	indexType := recommendation.IndexType
+	options := map[string]interface{}{
+		"concurrently": true, // Don't block concurrent document ingestion
+	}
+	for k, v := range recommendation.Params {
+		options[k] = v
 	}
 
 	// Try MCP first if available
@@ -654,12 +701,34 @@ func (s *Service) ensureVectorIndex(ctx context.Context, tableName string, colum
 	return nil
 }
 
-/* GetIndexStatus gets the status of a vector index using MCP */
+/* GetIndexStatus gets the status of a vector index using MCP, with the latest advisor recommendation and benchmark */
 func (s *Service) GetIndexStatus(ctx context.Context, indexName string) (map[string]interface{}, error) {
+	status := make(map[string]interface{})
 	if s.mcpClient != nil {
-		return s.mcpClient.IndexStatus(ctx, indexName)
+		mcpStatus, err := s.mcpClient.IndexStatus(ctx, indexName)
+		if err == nil {
+			for k, v := range mcpStatus {
+				status[k] = v
+			}
+		}
+	}
+
+	if advisory, err := s.indexAdvisor.GetLatestAdvisory(ctx, indexName); err == nil {
+		status["advisor"] = advisory
+		if current, err := s.indexAdvisor.currentIndex(ctx, advisory.Stats.TableName, indexName); err == nil {
+			status["index"] = current
+		}
+	}
+
+	if len(status) == 0 {
+		return nil, fmt.Errorf("no status available for index %s", indexName)
 	}
-	return nil, fmt.Errorf("MCP client not configured")
+	return status, nil
+}
+
+/* AdviseVectorIndex recommends, optionally builds and benchmarks a vector index */
+func (s *Service) AdviseVectorIndex(ctx context.Context, req AdviseIndexRequest) (*IndexAdvisory, error) {
+	return s.indexAdvisor.Advise(ctx, req)
 }
 
 /* TuneVectorIndex tunes a vector index using MCP */
@@ -778,6 +847,7 @@ func (s *Service) AnswerWithRAG(ctx context.Context, query string, collectionID
 		CollectionID: collectionID,
 		Limit:        5,
 		Threshold:    0.5,
+		CollapseDuplicates: true, // Duplicate passages waste context
 	}
 	
 	searchResults, err := s.Search(ctx, searchReq)
@@ -942,7 +1012,6 @@ func (s *Service) CreateDocumentWithImage(ctx context.Context, doc *db.Knowledge
 	// Generate multimodal embedding (text + image)
 	modelName := "sentence-transformers/all-MiniLM-L6-v2"
 	var multimodalEmbedding string
-	var err error
 
 	// Try MCP EmbedMultimodal first if available
 	if s.mcpClient != nil && imageData != nil {
@@ -981,6 +1050,7 @@ func (s *Service) CreateDocumentWithImage(ctx context.Context, doc *db.Knowledge
 		"has_image":   true,
 	})
 
+	invalidateCachedAnswers(ctx, s.semanticCache, doc.CollectionID, doc.ID)
 	return nil
 }
 
@@ -1003,6 +1073,17 @@ func (s *Service) CreateDocumentWithCachedEmbedding(ctx context.Context, doc *db
 	doc.Metadata["version"] = 1
 	doc.Metadata["used_cached_embedding"] = true
 
+	// Detect exact and near duplicates within the collection before inserting
+	fingerprint := dedup.ComputeFingerprint(doc.Content)
+	duplicate, err := s.dedupService.FindDuplicate(ctx, doc.CollectionID, fingerprint, nil)
+	if err != nil {
+		duplicate = nil
+	}
+	if duplicate != nil {
+		doc.Metadata["duplicate_of"] = duplicate.CanonicalID.String()
+		doc.Metadata["duplicate_type"] = duplicate.Type
+	}
+
 	// Insert document
 	insertQuery := `
 		INSERT INTO neuronip.knowledge_documents 
@@ -1011,7 +1092,7 @@ func (s *Service) CreateDocumentWithCachedEmbedding(ctx context.Context, doc *db
 		RETURNING id, created_at, updated_at`
 	
 	metadataJSON, _ := json.Marshal(doc.Metadata)
-	err := s.pool.QueryRow(ctx, insertQuery,
+	err = s.pool.QueryRow(ctx, insertQuery,
 		doc.CollectionID, doc.Title, doc.Content, doc.ContentType,
 		doc.Source, doc.SourceURL, metadataJSON,
 	).Scan(&doc.ID, &doc.CreatedAt, &doc.UpdatedAt)
@@ -1019,6 +1100,22 @@ func (s *Service) CreateDocumentWithCachedEmbedding(ctx context.Context, doc *db
 		return fmt.Errorf("failed to create document: %w", err)
 	}
 
+	if err := s.dedupService.Record(ctx, doc.ID, fingerprint, duplicate); err != nil {
+		logging.Error("Failed to record document fingerprint", "document_id", doc.ID, "error", err)
+	}
+
+	// Exact duplicates are linked to the canonical document instead of being re-embedded
+	if duplicate != nil && duplicate.Type == dedup.DuplicateExact {
+		s.auditService.LogDocumentEvent(ctx, "create", doc.ID, userID, map[string]interface{}{
+			"title":        doc.Title,
+			"content_type": doc.ContentType,
+			"version":      1,
+			"duplicate_of": duplicate.CanonicalID.String(),
+		})
+		invalidateCachedAnswers(ctx, s.semanticCache, doc.CollectionID, doc.ID)
+		return nil
+	}
+
 	// Chunk the document
 	chunks := ChunkText(doc.Content, *config)
 	modelName := "sentence-transformers/all-MiniLM-L6-v2"
@@ -1062,6 +1159,7 @@ func (s *Service) CreateDocumentWithCachedEmbedding(ctx context.Context, doc *db
 		"used_cached": true,
 	})
 
+	invalidateCachedAnswers(ctx, s.semanticCache, doc.CollectionID, doc.ID)
 	return nil
 }
 
@@ -1094,7 +1192,6 @@ func (s *Service) CreateDocumentWithImageOnly(ctx context.Context, doc *db.Knowl
 	// Generate image-only embedding
 	modelName := "sentence-transformers/all-MiniLM-L6-v2"
 	var imageEmbedding string
-	var err error
 
 	// Try MCP EmbedImage first if available
 	if s.mcpClient != nil && imageData != nil {
@@ -1129,6 +1226,7 @@ func (s *Service) CreateDocumentWithImageOnly(ctx context.Context, doc *db.Knowl
 		"image_only":  true,
 	})
 
+	invalidateCachedAnswers(ctx, s.semanticCache, doc.CollectionID, doc.ID)
 	return nil
 }
 
@@ -1210,6 +1308,18 @@ func (s *Service) CreateDocument(ctx context.Context, doc *db.KnowledgeDocument,
 	// Set version to 1 for new documents
 	doc.Metadata["version"] = 1
 
+	// Detect exact and near duplicates within the collection before inserting
+	fingerprint := dedup.ComputeFingerprint(doc.Content)
+	duplicate, err := s.dedupService.FindDuplicate(ctx, doc.CollectionID, fingerprint, nil)
+	if err != nil {
+		// Dedup is best effort; ingest as a new document
+		duplicate = nil
+	}
+	if duplicate != nil {
+		doc.Metadata["duplicate_of"] = duplicate.CanonicalID.String()
+		doc.Metadata["duplicate_type"] = duplicate.Type
+	}
+
 	// Insert document
 	insertQuery := `
 		INSERT INTO neuronip.knowledge_documents 
@@ -1218,7 +1328,7 @@ func (s *Service) CreateDocument(ctx context.Context, doc *db.KnowledgeDocument,
 		RETURNING id, created_at, updated_at`
 	
 	metadataJSON, _ := json.Marshal(doc.Metadata)
-	err := s.pool.QueryRow(ctx, insertQuery,
+	err = s.pool.QueryRow(ctx, insertQuery,
 		doc.CollectionID, doc.Title, doc.Content, doc.ContentType,
 		doc.Source, doc.SourceURL, metadataJSON,
 	).Scan(&doc.ID, &doc.CreatedAt, &doc.UpdatedAt)
@@ -1226,6 +1336,11 @@ func (s *Service) CreateDocument(ctx context.Context, doc *db.KnowledgeDocument,
 		return fmt.Errorf("failed to create document: %w", err)
 	}
 
+	if err := s.dedupService.Record(ctx, doc.ID, fingerprint, duplicate); err != nil {
+		// Dedup is best effort; don't fail document creation
+		logging.Error("Failed to record document fingerprint", "document_id", doc.ID, "error", err)
+	}
+
 	// Log audit event for document creation
 	s.auditService.LogDocumentEvent(ctx, "create", doc.ID, userID, map[string]interface{}{
 		"title":       doc.Title,
@@ -1234,6 +1349,12 @@ func (s *Service) CreateDocument(ctx context.Context, doc *db.KnowledgeDocument,
 		"chunks_count": 0, // Will update after chunks are created
 	})
 
+	// Exact duplicates are linked to the canonical document instead of being re-embedded
+	if duplicate != nil && duplicate.Type == dedup.DuplicateExact {
+		invalidateCachedAnswers(ctx, s.semanticCache, doc.CollectionID, doc.ID)
+		return nil
+	}
+
 	// Chunk the document
 	chunks := ChunkText(doc.Content, *config)
 
@@ -1303,6 +1424,7 @@ func (s *Service) CreateDocument(ctx context.Context, doc *db.KnowledgeDocument,
 		}
 	}()
 
+	invalidateCachedAnswers(ctx, s.semanticCache, doc.CollectionID, doc.ID)
 	return nil
 }
 
@@ -1347,6 +1469,7 @@ func (s *Service) UpdateDocument(ctx context.Context, docID uuid.UUID, updates *
 
 	// Increment version
 	newVersion := currentVersion + 1
+	previousHash := dedup.ComputeFingerprint(currentDoc.Content).ContentHash
 
 	// Prepare update data (merge with current)
 	if updates.Title != "" {
@@ -1374,6 +1497,28 @@ func (s *Service) UpdateDocument(ctx context.Context, docID uuid.UUID, updates *
 	currentMetadata["version"] = newVersion
 	currentDoc.Metadata = currentMetadata
 
+	// Documents that duplicated the old content must not stay linked to the new content
+	fingerprint := dedup.ComputeFingerprint(currentDoc.Content)
+	if fingerprint.ContentHash != previousHash {
+		if _, err := s.dedupService.DetachDuplicates(ctx, docID); err != nil {
+			return fmt.Errorf("failed to detach duplicates: %w", err)
+		}
+	}
+
+	// Re-evaluate the document's own duplicate link for the new content
+	duplicate, err := s.dedupService.FindDuplicate(ctx, currentDoc.CollectionID, fingerprint, &docID)
+	if err != nil {
+		logging.Error("Failed to check document duplicates", "document_id", docID, "error", err)
+		duplicate = nil
+	}
+	if duplicate != nil && duplicate.CanonicalID != docID {
+		currentMetadata["duplicate_of"] = duplicate.CanonicalID.String()
+		currentMetadata["duplicate_type"] = duplicate.Type
+	} else {
+		delete(currentMetadata, "duplicate_of")
+		delete(currentMetadata, "duplicate_type")
+	}
+
 	// Delete old embeddings
 	deleteEmbedsQuery := `DELETE FROM neuronip.knowledge_embeddings WHERE document_id = $1`
 	_, err = s.pool.Exec(ctx, deleteEmbedsQuery, docID)
@@ -1397,11 +1542,16 @@ func (s *Service) UpdateDocument(ctx context.Context, docID uuid.UUID, updates *
 		return fmt.Errorf("failed to update document: %w", err)
 	}
 
+	if err := s.dedupService.Record(ctx, docID, fingerprint, duplicate); err != nil {
+		logging.Error("Failed to record document fingerprint", "document_id", docID, "error", err)
+	}
+
 	// Chunk the updated document using NeuronDB ProcessDocument if available
 	var chunks []string
-	var chunkData []map[string]interface{}
 	
-	if config.EnableChunking {
+	// Exact duplicates are served by the canonical document's embeddings and get no chunks of their own
+	exactDuplicate := duplicate != nil && duplicate.Type == dedup.DuplicateExact && duplicate.CanonicalID != docID
+	if !exactDuplicate && config.EnableChunking {
 		// Try using NeuronDB ProcessDocument first
 		processedChunks, err := s.neurondbClient.ProcessDocument(ctx, currentDoc.Content, config.ChunkSize, config.ChunkOverlap)
 		if err == nil && len(processedChunks) > 0 {
@@ -1411,12 +1561,11 @@ func (s *Service) UpdateDocument(ctx context.Context, docID uuid.UUID, updates *
 					chunks = append(chunks, text)
 				}
 			}
-			chunkData = processedChunks
 		} else {
 			// Fallback to local chunking
 			chunks = ChunkText(currentDoc.Content, *config)
 		}
-	} else {
+	} else if !exactDuplicate {
 		chunks = []string{currentDoc.Content}
 	}
 
@@ -1465,6 +1614,7 @@ func (s *Service) UpdateDocument(ctx context.Context, docID uuid.UUID, updates *
 		"chunks_count": len(chunks),
 	})
 
+	invalidateCachedAnswers(ctx, s.semanticCache, currentDoc.CollectionID, docID)
 	return nil
 }
 
@@ -1518,6 +1668,7 @@ func (s *Service) RAG(ctx context.Context, req RAGRequest) (*RAGResult, error) {
 		CollectionID: req.CollectionID,
 		Limit:        req.Limit * 2, // Get more results for context
 		Threshold:    req.Threshold,
+		CollapseDuplicates: true,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to perform semantic search: %w", err)
@@ -13,7 +13,9 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/neurondb/NeuronIP/api/internal/agent"
 	"github.com/neurondb/NeuronIP/api/internal/db"
+	"github.com/neurondb/NeuronIP/api/internal/logging"
 	"github.com/neurondb/NeuronIP/api/internal/neurondb"
+	"github.com/neurondb/NeuronIP/api/internal/observability"
 )
 
 /* Service provides customer support memory functionality */
@@ -22,6 +24,7 @@ type Service struct {
 	pool         *pgxpool.Pool
 	agentClient  *agent.Client
 	neurondbClient *neurondb.Client
+	groundedness   *observability.GroundednessService
 }
 
 /* NewService creates a new support service */
@@ -34,6 +37,13 @@ func NewService(queries *db.Queries, pool *pgxpool.Pool, agentClient *agent.Clie
 	}
 }
 
+/* NewServiceWithGroundedness creates a support service that verifies generated replies against the ticket history */
+func NewServiceWithGroundedness(queries *db.Queries, pool *pgxpool.Pool, agentClient *agent.Client, neurondbClient *neurondb.Client, groundedness *observability.GroundednessService) *Service {
+	service := NewService(queries, pool, agentClient, neurondbClient)
+	service.groundedness = groundedness
+	return service
+}
+
 /* TicketRequest represents a ticket creation request */
 type TicketRequest struct {
 	CustomerID    string
@@ -496,7 +506,7 @@ func (s *Service) GetSimilarCasesHybrid(ctx context.Context, ticketID uuid.UUID,
 	}
 
 	// Get ticket conversations for context
-	ticket, conversations, err := s.GetTicket(ctx, ticketID)
+	_, conversations, err := s.GetTicket(ctx, ticketID)
 	if err != nil {
 		return []SimilarCase{}, nil
 	}
@@ -513,18 +523,8 @@ func (s *Service) GetSimilarCasesHybrid(ctx context.Context, ticketID uuid.UUID,
 		return nil, fmt.Errorf("failed to generate embedding: %w", err)
 	}
 
-	// Use NeuronDB HybridSearch for better results
-	tableName := "neuronip.support_tickets st JOIN neuronip.support_conversations sc ON sc.ticket_id = st.id"
-	embeddingColumn := "sc.embedding"
-	textColumn := "st.subject || ' ' || COALESCE(sc.message_text, '')"
-	weights := map[string]float64{
-		"semantic": 0.7,
-		"keyword":  0.3,
-	}
-
-	// Build WHERE clause for filtering
-	// Note: HybridSearch in client may not support complex WHERE clauses directly
-	// So we'll use the direct SQL approach with hybrid search logic
+	// Hybrid search weighs semantic similarity at 0.7 and keyword rank at 0.3.
+	// HybridSearch in the client does not support the ticket join, so the logic is written in SQL.
 	searchQuery := `
 		WITH semantic_results AS (
 			SELECT DISTINCT
@@ -606,12 +606,32 @@ func (s *Service) GetSimilarCasesHybrid(ctx context.Context, ticketID uuid.UUID,
 	return similarCases, nil
 }
 
-/* GenerateReply generates a context-aware reply */
+/* VerifiedReply is a generated reply with its claim-level groundedness report */
+type VerifiedReply struct {
+	Reply        string                            `json:"reply"`
+	SimilarCases []SimilarCase                     `json:"similar_cases,omitempty"`
+	Groundedness *observability.GroundednessReport `json:"groundedness,omitempty"`
+}
+
+/* GenerateReply generates a context-aware reply; replies blocked for insufficient grounding return an error */
 func (s *Service) GenerateReply(ctx context.Context, ticketID uuid.UUID) (string, []SimilarCase, error) {
+	verified, err := s.GenerateVerifiedReply(ctx, ticketID)
+	if err != nil {
+		return "", nil, err
+	}
+	if verified.Groundedness != nil && verified.Groundedness.Action == observability.GroundednessActionBlock {
+		return "", verified.SimilarCases, fmt.Errorf("reply blocked: groundedness %.2f is below threshold %.2f",
+			verified.Groundedness.Score, verified.Groundedness.Threshold)
+	}
+	return verified.Reply, verified.SimilarCases, nil
+}
+
+/* GenerateVerifiedReply generates a context-aware reply and verifies its claims against the ticket conversation and similar cases */
+func (s *Service) GenerateVerifiedReply(ctx context.Context, ticketID uuid.UUID) (*VerifiedReply, error) {
 	// Get ticket and conversations
 	ticket, conversations, err := s.GetTicket(ctx, ticketID)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to get ticket: %w", err)
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
 	}
 
 	// Get similar cases
@@ -651,10 +671,45 @@ func (s *Service) GenerateReply(ctx context.Context, ticketID uuid.UUID) (string
 	// Generate reply using NeuronAgent
 	reply, err := s.agentClient.GenerateReply(ctx, context, prompt)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to generate reply: %w", err)
+		return nil, fmt.Errorf("failed to generate reply: %w", err)
+	}
+
+	verified := &VerifiedReply{Reply: reply, SimilarCases: similarCases}
+	if s.groundedness.Enabled() {
+		var sources []observability.GroundingSource
+		for _, conv := range conversations {
+			sources = append(sources, observability.GroundingSource{ID: conv.ID.String(), Text: conv.MessageText})
+		}
+		// Resolutions of similar cases live in their conversations, so claims drawn from them are verified against the messages
+		for i, similar := range similarCases {
+			sources = append(sources, observability.GroundingSource{ID: similar.TicketID.String(), Text: similar.Subject})
+			messages, err := s.GetConversations(ctx, similar.TicketID)
+			if err != nil {
+				logging.Error("Failed to load similar case conversations", "ticket_id", similar.TicketID, "error", err)
+				continue
+			}
+			similarCases[i].Conversations = messages
+			for _, message := range messages {
+				sources = append(sources, observability.GroundingSource{ID: message.ID.String(), Text: message.MessageText})
+			}
+		}
+
+		report, err := s.groundedness.VerifyAndRecord(ctx, nil, &ticketID, reply, sources, map[string]interface{}{
+			"source":        "support",
+			"ticket_number": ticket.TicketNumber,
+			"reply":         reply,
+		})
+		if err != nil {
+			logging.Error("Groundedness verification failed", "ticket_id", ticketID, "error", err)
+			if report == nil {
+				report = s.groundedness.FailedReport(err)
+			}
+		}
+		verified.Groundedness = report
+		verified.Reply = s.groundedness.Enforce(reply, report)
 	}
 
-	return reply, similarCases, nil
+	return verified, nil
 }
 
 /* CreateSupportSession creates a NeuronAgent session for a support ticket */
@@ -165,8 +165,8 @@ func (s *IsolationTestSuite) RunAllIsolationTests(ctx context.Context, tenantID
 	return results, nil
 }
 
-/* TestIsolationInTestSuite is a Go test function for running isolation tests */
-func TestIsolationInTestSuite(t *testing.T, service *TenancyService, pool *pgxpool.Pool) {
+/* RunIsolationTestSuite runs the isolation tests from a Go test against a live service */
+func RunIsolationTestSuite(t *testing.T, service *TenancyService, pool *pgxpool.Pool) {
 	ctx := context.Background()
 	testSuite := NewIsolationTestSuite(service, pool)
 
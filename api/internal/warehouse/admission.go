@@ -0,0 +1,456 @@
+package warehouse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+/* Admission decisions */
+const (
+	AdmissionRun      = "run"      // Admitted through the queue and executed now
+	AdmissionAsync    = "async"    // Over the cost limit; handed to the async query service
+	AdmissionRejected = "rejected" // Over the cost limit with no async service, or queue wait exceeded
+)
+
+/* Admission error codes */
+const (
+	AdmissionErrorCostLimit    = "cost_limit_exceeded"
+	AdmissionErrorQueueTimeout = "queue_timeout"
+)
+
+/* Hotspot thresholds */
+const (
+	hotspotSeqScanRows    = 10000  // Sequential scans estimated to read at least this many rows
+	hotspotCostShare      = 0.3    // Nodes whose own cost is at least this share of the plan
+	hotspotNestedLoopRows = 100000 // Nested loops estimated to produce at least this many rows
+	maxHotspots           = 5
+)
+
+/* AdmissionConfig controls EXPLAIN-based admission of warehouse queries */
+type AdmissionConfig struct {
+	MaxConcurrent    int           // Queries executing at once across all classes
+	MaxQueueWait     time.Duration // Longest a query waits for a slot before it is rejected
+	ExplainTimeout   time.Duration
+	DefaultCostLimit float64 // Planner cost limit for roles without sandbox_roles.max_query_cost
+	AsyncExpiryHours int     // Lifetime of async results for queries moved off the interactive path
+}
+
+/* DefaultAdmissionConfig returns the default admission settings */
+func DefaultAdmissionConfig() AdmissionConfig {
+	return AdmissionConfig{
+		MaxConcurrent:    8,
+		MaxQueueWait:     30 * time.Second,
+		ExplainTimeout:   5 * time.Second,
+		DefaultCostLimit: 100000,
+		AsyncExpiryHours: 24,
+	}
+}
+
+/* PlanHotspot is a plan node likely to dominate execution time */
+type PlanHotspot struct {
+	NodeType      string  `json:"node_type"`
+	Relation      string  `json:"relation,omitempty"`
+	Alias         string  `json:"alias,omitempty"`
+	EstimatedRows float64 `json:"estimated_rows"`
+	TotalCost     float64 `json:"total_cost"`
+	SelfCost      float64 `json:"self_cost"`
+	CostShare     float64 `json:"cost_share"` // Self cost as a fraction of the plan's total cost
+	Reason        string  `json:"reason"`
+}
+
+/* PlanEstimate is the planner's cost estimate for a query */
+type PlanEstimate struct {
+	TotalCost     float64       `json:"total_cost"`
+	StartupCost   float64       `json:"startup_cost"`
+	EstimatedRows float64       `json:"estimated_rows"`
+	Hotspots      []PlanHotspot `json:"hotspots"`
+}
+
+/* AdmissionResult describes how a query was admitted */
+type AdmissionResult struct {
+	Decision      string        `json:"decision"`
+	Class         string        `json:"class"` // Queue class: tenant:<id>, role:<name> or default
+	EstimatedCost float64       `json:"estimated_cost"`
+	CostLimit     float64       `json:"cost_limit"`
+	Plan          *PlanEstimate `json:"plan,omitempty"`
+	QueueWaitMs   int64         `json:"queue_wait_ms"`
+	AsyncQueryID  *uuid.UUID    `json:"async_query_id,omitempty"`
+	Reason        string        `json:"reason,omitempty"`
+}
+
+/* AdmissionError reports a query that could not be admitted */
+type AdmissionError struct {
+	Code    string
+	Message string
+	Result  *AdmissionResult
+}
+
+func (e *AdmissionError) Error() string {
+	return e.Message
+}
+
+/* AsAdmissionError returns the admission error in err's chain, or nil */
+func AsAdmissionError(err error) *AdmissionError {
+	var admissionErr *AdmissionError
+	if errors.As(err, &admissionErr) {
+		return admissionErr
+	}
+	return nil
+}
+
+/* planNode is a node of EXPLAIN (FORMAT JSON) output */
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name"`
+	Schema       string     `json:"Schema"`
+	Alias        string     `json:"Alias"`
+	StartupCost  float64    `json:"Startup Cost"`
+	TotalCost    float64    `json:"Total Cost"`
+	PlanRows     float64    `json:"Plan Rows"`
+	Plans        []planNode `json:"Plans"`
+}
+
+/* ExplainQuery estimates a validated query's cost from its EXPLAIN (FORMAT JSON) plan without executing it */
+func (s *GovernanceService) ExplainQuery(ctx context.Context, sql string) (*PlanEstimate, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin explain transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `SELECT set_config('statement_timeout', $1, true)`,
+		fmt.Sprintf("%dms", s.admission.ExplainTimeout.Milliseconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure explain: %w", err)
+	}
+
+	var planJSON []byte
+	if err := tx.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+sql).Scan(&planJSON); err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+
+	var plans []struct {
+		Plan planNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(planJSON, &plans); err != nil {
+		return nil, fmt.Errorf("failed to parse query plan: %w", err)
+	}
+	if len(plans) == 0 {
+		return nil, fmt.Errorf("failed to parse query plan: empty plan")
+	}
+
+	root := plans[0].Plan
+	return &PlanEstimate{
+		TotalCost:     root.TotalCost,
+		StartupCost:   root.StartupCost,
+		EstimatedRows: root.PlanRows,
+		Hotspots:      planHotspots(root),
+	}, nil
+}
+
+/* planHotspots returns the most expensive plan nodes: large sequential scans, large nested loops and nodes dominating the plan's cost */
+func planHotspots(root planNode) []PlanHotspot {
+	hotspots := []PlanHotspot{}
+	var walk func(node planNode)
+	walk = func(node planNode) {
+		selfCost := node.TotalCost
+		for _, child := range node.Plans {
+			selfCost -= child.TotalCost
+			walk(child)
+		}
+		if selfCost < 0 {
+			selfCost = 0
+		}
+		share := 0.0
+		if root.TotalCost > 0 {
+			share = selfCost / root.TotalCost
+		}
+
+		var reason string
+		switch {
+		case node.NodeType == "Seq Scan" && node.PlanRows >= hotspotSeqScanRows:
+			reason = fmt.Sprintf("sequential scan estimated at %.0f rows", node.PlanRows)
+		case node.NodeType == "Nested Loop" && node.PlanRows >= hotspotNestedLoopRows:
+			reason = fmt.Sprintf("nested loop estimated to produce %.0f rows", node.PlanRows)
+		case share >= hotspotCostShare:
+			reason = fmt.Sprintf("%.0f%% of the plan's estimated cost", share*100)
+		default:
+			return
+		}
+
+		relation := node.RelationName
+		if relation != "" && node.Schema != "" {
+			relation = node.Schema + "." + relation
+		}
+		hotspots = append(hotspots, PlanHotspot{
+			NodeType:      node.NodeType,
+			Relation:      relation,
+			Alias:         node.Alias,
+			EstimatedRows: node.PlanRows,
+			TotalCost:     node.TotalCost,
+			SelfCost:      selfCost,
+			CostShare:     share,
+			Reason:        reason,
+		})
+	}
+	walk(root)
+
+	sort.SliceStable(hotspots, func(i, j int) bool { return hotspots[i].SelfCost > hotspots[j].SelfCost })
+	if len(hotspots) > maxHotspots {
+		hotspots = hotspots[:maxHotspots]
+	}
+	return hotspots
+}
+
+/* AdmitQuery decides how a validated query runs. Queries within the cost limit wait for a slot in the
+ * weighted queue and the caller must call release when execution finishes; queries over the limit are
+ * submitted to the async query service, or rejected when none is configured.
+ * plan is the query's estimate when the caller already explained it; otherwise the query is explained here. */
+func (s *GovernanceService) AdmitQuery(ctx context.Context, sql string, plan *PlanEstimate, userID *string, asyncParams map[string]interface{}) (*AdmissionResult, func(), error) {
+	role := s.roleForUser(ctx, userID)
+	class := admissionClassKey(ctx, role)
+
+	if plan == nil {
+		var err error
+		plan, err = s.ExplainQuery(ctx, sql)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	result := &AdmissionResult{
+		Class:         class,
+		EstimatedCost: plan.TotalCost,
+		CostLimit:     s.getCostLimit(ctx, role),
+		Plan:          plan,
+	}
+
+	if result.EstimatedCost > result.CostLimit {
+		if s.asyncQueries == nil {
+			result.Decision = AdmissionRejected
+			result.Reason = fmt.Sprintf("estimated cost %.0f exceeds the limit of %.0f", result.EstimatedCost, result.CostLimit)
+			s.recordAdmission(ctx, result)
+			return nil, nil, &AdmissionError{Code: AdmissionErrorCostLimit, Message: result.Reason, Result: result}
+		}
+
+		params := map[string]interface{}{
+			"admission_class": class,
+			"estimated_cost":  result.EstimatedCost,
+		}
+		for k, v := range asyncParams {
+			params[k] = v
+		}
+		asyncQuery, err := s.asyncQueries.SubmitAsyncQuery(ctx, sql, params, userID, s.admission.AsyncExpiryHours)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to submit query for async execution: %w", err)
+		}
+		result.Decision = AdmissionAsync
+		result.AsyncQueryID = &asyncQuery.ID
+		result.Reason = fmt.Sprintf("estimated cost %.0f exceeds the interactive limit of %.0f; running asynchronously", result.EstimatedCost, result.CostLimit)
+		s.recordAdmission(ctx, result)
+		return result, nil, nil
+	}
+
+	wait, release, err := s.queue.acquire(ctx, class, s.classWeight(ctx, class), result.EstimatedCost, s.admission.MaxQueueWait)
+	result.QueueWaitMs = wait.Milliseconds()
+	if err != nil {
+		result.Decision = AdmissionRejected
+		result.Reason = err.Error()
+		s.recordAdmission(context.WithoutCancel(ctx), result)
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, &AdmissionError{Code: AdmissionErrorQueueTimeout, Message: result.Reason, Result: result}
+	}
+
+	result.Decision = AdmissionRun
+	s.recordAdmission(ctx, result)
+	return result, release, nil
+}
+
+/* roleForUser returns the user's role, or an empty string when unknown */
+func (s *GovernanceService) roleForUser(ctx context.Context, userID *string) string {
+	if userID == nil {
+		return ""
+	}
+	var role string
+	s.pool.QueryRow(ctx, `SELECT role FROM neuronip.users WHERE id::text = $1`, *userID).Scan(&role)
+	return role
+}
+
+/* classWeight returns the queue weight configured for a class, defaulting to 1 */
+func (s *GovernanceService) classWeight(ctx context.Context, class string) float64 {
+	var weight float64
+	err := s.pool.QueryRow(ctx, `SELECT weight FROM neuronip.warehouse_admission_classes WHERE class_key = $1`, class).Scan(&weight)
+	if err != nil || weight <= 0 {
+		return 1
+	}
+	return weight
+}
+
+/* recordAdmission stores an admission decision; recording is best effort */
+func (s *GovernanceService) recordAdmission(ctx context.Context, result *AdmissionResult) {
+	var hotspotsJSON []byte
+	if result.Plan != nil {
+		hotspotsJSON, _ = json.Marshal(result.Plan.Hotspots)
+	}
+	s.pool.Exec(ctx, `
+		INSERT INTO neuronip.warehouse_admissions
+		(class_key, decision, estimated_cost, cost_limit, hotspots, queue_wait_ms, async_query_id, reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		result.Class, result.Decision, result.EstimatedCost, result.CostLimit, hotspotsJSON,
+		result.QueueWaitMs, result.AsyncQueryID, result.Reason)
+}
+
+/* admissionClassKey queues queries per tenant when the request carries one, otherwise per role */
+func admissionClassKey(ctx context.Context, role string) string {
+	if tenantID := ctx.Value("tenant_id"); tenantID != nil {
+		if tenant := fmt.Sprintf("%v", tenantID); tenant != "" {
+			return "tenant:" + tenant
+		}
+	}
+	if role != "" {
+		return "role:" + role
+	}
+	return "default"
+}
+
+/* admissionQueue shares execution slots between classes by weighted fair queuing.
+ * Each class has a virtual clock advanced by the planner cost of the queries it runs divided by
+ * its weight; a free slot goes to the waiting class with the smallest clock. */
+type admissionQueue struct {
+	mu      sync.Mutex
+	slots   int
+	running int
+	clock   float64 // Virtual time of the most recent admission
+	classes map[string]*admissionClass
+}
+
+/* admissionClass is the queue state of one tenant or role */
+type admissionClass struct {
+	weight      float64
+	virtualTime float64
+	waiters     []*admissionWaiter
+}
+
+/* admissionWaiter is a query waiting for a slot */
+type admissionWaiter struct {
+	cost     float64
+	ready    chan struct{}
+	admitted bool
+}
+
+func newAdmissionQueue(slots int) *admissionQueue {
+	if slots <= 0 {
+		slots = DefaultAdmissionConfig().MaxConcurrent
+	}
+	return &admissionQueue{slots: slots, classes: make(map[string]*admissionClass)}
+}
+
+/* acquire waits for an execution slot and returns how long it waited and the function that frees the slot */
+func (q *admissionQueue) acquire(ctx context.Context, class string, weight, cost float64, maxWait time.Duration) (time.Duration, func(), error) {
+	start := time.Now()
+	w := &admissionWaiter{cost: max(cost, 1), ready: make(chan struct{})}
+
+	q.mu.Lock()
+	c := q.classes[class]
+	if c == nil {
+		c = &admissionClass{}
+		q.classes[class] = c
+	}
+	c.weight = weight
+	if len(c.waiters) == 0 {
+		// An idle class rejoins at the current virtual time instead of spending saved-up credit
+		c.virtualTime = max(c.virtualTime, q.clock)
+	}
+	c.waiters = append(c.waiters, w)
+	q.dispatch()
+	q.mu.Unlock()
+
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timer.C:
+		err = fmt.Errorf("no execution slot became available within %s", maxWait)
+	}
+
+	if err != nil {
+		q.mu.Lock()
+		if !w.admitted {
+			c.waiters = removeWaiter(c.waiters, w)
+			q.mu.Unlock()
+			return time.Since(start), nil, err
+		}
+		// Admitted while giving up; keep the slot
+		q.mu.Unlock()
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			q.mu.Lock()
+			q.running--
+			q.dispatch()
+			q.mu.Unlock()
+		})
+	}
+	return time.Since(start), release, nil
+}
+
+/* dispatch admits waiters while slots are free; callers hold q.mu.
+ * Idle classes whose clock has fallen behind the queue's are dropped, since they would rejoin at the queue's
+ * clock anyway. Once nothing runs or waits every class is dropped, so the map holds only classes with recent traffic. */
+func (q *admissionQueue) dispatch() {
+	for q.running < q.slots {
+		var next *admissionClass
+		for key, c := range q.classes {
+			if len(c.waiters) == 0 {
+				if c.virtualTime <= q.clock {
+					delete(q.classes, key)
+				}
+				continue
+			}
+			if next == nil || c.virtualTime < next.virtualTime {
+				next = c
+			}
+		}
+		if next == nil {
+			if q.running == 0 {
+				// No class has anything to be served ahead of, so their clocks no longer matter
+				clear(q.classes)
+				q.clock = 0
+			}
+			return
+		}
+
+		w := next.waiters[0]
+		next.waiters = next.waiters[1:]
+		q.clock = next.virtualTime
+		next.virtualTime += w.cost / next.weight
+		q.running++
+		w.admitted = true
+		close(w.ready)
+	}
+}
+
+func removeWaiter(waiters []*admissionWaiter, w *admissionWaiter) []*admissionWaiter {
+	for i, candidate := range waiters {
+		if candidate == w {
+			return append(waiters[:i], waiters[i+1:]...)
+		}
+	}
+	return waiters
+}
@@ -0,0 +1,111 @@
+package warehouse
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+/* TestAdmissionQueueForgetsIdleClasses checks that idle classes leave the queue */
+func TestAdmissionQueueForgetsIdleClasses(t *testing.T) {
+	q := newAdmissionQueue(2)
+	ctx := context.Background()
+
+	_, releaseLong, err := q.acquire(ctx, "tenant:long", 1, 1000, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, class := range []string{"tenant:a", "tenant:b", "tenant:c"} {
+		_, release, err := q.acquire(ctx, class, 1, 10, time.Second)
+		if err != nil {
+			t.Fatalf("%s: %v", class, err)
+		}
+		release()
+	}
+
+	// While a query runs, classes that are idle but ahead of the queue's clock keep their place
+	q.mu.Lock()
+	if q.classes["tenant:long"] == nil {
+		t.Errorf("expected the running class to be kept")
+	}
+	q.mu.Unlock()
+
+	releaseLong()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.classes) != 0 {
+		t.Fatalf("expected no classes once the queue is idle, got %d", len(q.classes))
+	}
+}
+
+/* TestAdmissionQueueWeightedOrder checks that a free slot goes to the waiting class with the smallest virtual time */
+func TestAdmissionQueueWeightedOrder(t *testing.T) {
+	q := newAdmissionQueue(1)
+	ctx := context.Background()
+
+	_, release, err := q.acquire(ctx, "tenant:busy", 1, 100, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan string, 2)
+	start := func(class string) {
+		go func() {
+			_, release, err := q.acquire(ctx, class, 1, 10, time.Second)
+			if err == nil {
+				order <- class
+				release()
+			}
+		}()
+	}
+	// tenant:busy has spent 100 units, so its second query waits behind tenant:new's
+	start("tenant:busy")
+	waitForWaiters(t, q, 1)
+	start("tenant:new")
+	waitForWaiters(t, q, 2)
+	release()
+
+	if first := <-order; first != "tenant:new" {
+		t.Fatalf("expected tenant:new to be admitted first, got %s", first)
+	}
+	<-order
+}
+
+func waitForWaiters(t *testing.T, q *admissionQueue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		q.mu.Lock()
+		waiting := 0
+		for _, c := range q.classes {
+			waiting += len(c.waiters)
+		}
+		q.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d waiters", n)
+}
+
+/* TestPlanHotspots checks which plan nodes are reported as hotspots */
+func TestPlanHotspots(t *testing.T) {
+	root := planNode{
+		NodeType: "Hash Join", TotalCost: 1200, PlanRows: 500,
+		Plans: []planNode{
+			{NodeType: "Seq Scan", RelationName: "orders", Schema: "sales", TotalCost: 700, PlanRows: 50000},
+			{NodeType: "Index Scan", RelationName: "customers", TotalCost: 50, PlanRows: 100},
+		},
+	}
+	hotspots := planHotspots(root)
+	if len(hotspots) != 2 {
+		t.Fatalf("expected 2 hotspots, got %+v", hotspots)
+	}
+	if hotspots[0].Relation != "sales.orders" || hotspots[0].SelfCost != 700 {
+		t.Errorf("expected the sequential scan first, got %+v", hotspots[0])
+	}
+	if hotspots[1].NodeType != "Hash Join" || hotspots[1].SelfCost != 450 {
+		t.Errorf("expected the join's own cost second, got %+v", hotspots[1])
+	}
+}
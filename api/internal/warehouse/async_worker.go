@@ -0,0 +1,162 @@
+package warehouse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/neurondb/NeuronIP/api/internal/execution"
+	"github.com/neurondb/NeuronIP/api/internal/logging"
+)
+
+/* asyncQueryTimeout bounds a query admission moved off the interactive path */
+const asyncQueryTimeout = 30 * time.Minute
+
+/* StartAsyncQueryWorker runs queries admission moved off the interactive path. It polls the job queue for
+ * async query jobs and, for each, runs the query and completes its async query and warehouse query records.
+ * It stops when ctx is done; without an async query service there is nothing to run. */
+func (s *Service) StartAsyncQueryWorker(ctx context.Context, jobs *execution.JobQueueService, workerID string, pollInterval time.Duration) {
+	if s.governance == nil || s.governance.asyncQueries == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			// Drain the queue before waiting for the next tick
+			for s.runNextAsyncQuery(ctx, jobs, workerID) {
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+/* runNextAsyncQuery takes one async query job off the queue and runs it, reporting whether there was one */
+func (s *Service) runNextAsyncQuery(ctx context.Context, jobs *execution.JobQueueService, workerID string) bool {
+	job, err := jobs.DequeueJob(ctx, workerID, []string{execution.AsyncQueryJobType})
+	if err != nil {
+		logging.Error("Async query worker failed to take a job", "worker_id", workerID, "error", err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	if err := s.runAsyncQuery(ctx, job); err != nil {
+		jobs.FailJob(ctx, job.ID, err.Error(), false)
+		return true
+	}
+	jobs.CompleteJob(ctx, job.ID, nil)
+	return true
+}
+
+/* runAsyncQuery runs a queued query in a read-only transaction and stores its results with the warehouse query */
+func (s *Service) runAsyncQuery(ctx context.Context, job *execution.Job) error {
+	queryID, err := uuid.Parse(fmt.Sprintf("%v", job.JobPayload["query_id"]))
+	if err != nil {
+		return fmt.Errorf("async query job has no query_id: %w", err)
+	}
+	queryText, _ := job.JobPayload["query_text"].(string)
+	var warehouseQueryID *uuid.UUID
+	if params, ok := job.JobPayload["query_params"].(map[string]interface{}); ok {
+		if id, err := uuid.Parse(fmt.Sprintf("%v", params["warehouse_query_id"])); err == nil {
+			warehouseQueryID = &id
+		}
+	}
+
+	asyncQueries := s.governance.asyncQueries
+	started, err := asyncQueries.StartAsyncQuery(ctx, queryID)
+	if err != nil {
+		return err
+	}
+	if !started {
+		// Cancelled while queued
+		if warehouseQueryID != nil {
+			s.pool.Exec(ctx, `UPDATE neuronip.warehouse_queries SET status = $1, error_message = $2, executed_at = $3 WHERE id = $4`,
+				"failed", "cancelled before it ran", time.Now(), *warehouseQueryID)
+		}
+		return nil
+	}
+
+	start := time.Now()
+	execCtx, cancel := context.WithTimeout(ctx, asyncQueryTimeout)
+	results, err := s.queryReadOnly(execCtx, queryText)
+	cancel()
+	if err != nil {
+		asyncQueries.FailAsyncQuery(ctx, queryID, err.Error())
+		if warehouseQueryID != nil {
+			s.pool.Exec(ctx, `UPDATE neuronip.warehouse_queries SET status = $1, error_message = $2, executed_at = $3 WHERE id = $4`,
+				"failed", err.Error(), time.Now(), *warehouseQueryID)
+		}
+		return err
+	}
+	executionTimeMs := int(time.Since(start).Milliseconds())
+
+	resultLocation := "neuronip.query_results"
+	if warehouseQueryID != nil {
+		s.storeAsyncResults(ctx, *warehouseQueryID, queryText, results, executionTimeMs)
+		resultLocation = fmt.Sprintf("neuronip.query_results:%s", *warehouseQueryID)
+	}
+	return asyncQueries.CompleteAsyncQuery(ctx, queryID, resultLocation, int64(len(results)), executionTimeMs)
+}
+
+/* queryReadOnly runs a validated query in a read-only transaction, so the database refuses writes the validator missed */
+func (s *Service) queryReadOnly(ctx context.Context, sql string) ([]map[string]interface{}, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin query transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, sql)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute SQL: %w", err)
+	}
+	defer rows.Close()
+
+	results := []map[string]interface{}{}
+	fieldDescriptions := rows.FieldDescriptions()
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get row values: %w", err)
+		}
+		row := make(map[string]interface{}, len(values))
+		for i, desc := range fieldDescriptions {
+			row[desc.Name] = values[i]
+		}
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to execute SQL: %w", err)
+	}
+	return results, nil
+}
+
+/* storeAsyncResults completes the warehouse query with its results and charts, as executeValidatedQuery does for queries run interactively */
+func (s *Service) storeAsyncResults(ctx context.Context, queryID uuid.UUID, sql string, results []map[string]interface{}, executionTimeMs int) {
+	s.pool.Exec(ctx, `UPDATE neuronip.warehouse_queries SET status = $1, executed_at = $2 WHERE id = $3`,
+		"completed", time.Now(), queryID)
+
+	resultData, _ := json.Marshal(results)
+	s.pool.Exec(ctx, `
+		INSERT INTO neuronip.query_results
+		(query_id, result_data, row_count, execution_time_ms, created_at)
+		VALUES ($1, $2, $3, $4, $5)`, queryID, resultData, len(results), executionTimeMs, time.Now())
+
+	charts := s.recommendCharts(ctx, sql, results)
+	if charts != nil {
+		chartConfig, chartType := topChart(charts)
+		chartConfigJSON, _ := json.Marshal(chartConfig)
+		chartsJSON, _ := json.Marshal(charts)
+		s.pool.Exec(ctx, `UPDATE neuronip.query_results SET chart_config = $1, chart_type = $2, chart_recommendations = $3 WHERE query_id = $4`,
+			chartConfigJSON, chartType, chartsJSON, queryID)
+	}
+}
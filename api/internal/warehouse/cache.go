@@ -6,15 +6,18 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/cache"
 )
 
 /* CacheService provides query result caching */
 type CacheService struct {
-	pool *pgxpool.Pool
+	pool          *pgxpool.Pool
+	semanticCache *cache.SemanticCacheService
 }
 
 /* NewCacheService creates a new cache service */
@@ -22,6 +25,11 @@ func NewCacheService(pool *pgxpool.Pool) *CacheService {
 	return &CacheService{pool: pool}
 }
 
+/* NewCacheServiceWithSemanticCache creates a cache service that also invalidates semantic cache answers on table changes */
+func NewCacheServiceWithSemanticCache(pool *pgxpool.Pool, semanticCache *cache.SemanticCacheService) *CacheService {
+	return &CacheService{pool: pool, semanticCache: semanticCache}
+}
+
 /* CacheEntry represents a cached query result */
 type CacheEntry struct {
 	ID           uuid.UUID              `json:"id"`
@@ -34,6 +42,8 @@ type CacheEntry struct {
 	HitCount     int                    `json:"hit_count"`
 	CreatedAt    time.Time              `json:"created_at"`
 	LastAccessedAt *time.Time           `json:"last_accessed_at,omitempty"`
+	SourceTables []string               `json:"source_tables,omitempty"`
+	StaleSince   *time.Time             `json:"stale_since,omitempty"` // Set when a source table changed within its staleness tolerance
 }
 
 /* GetCacheKey generates a cache key from query and parameters */
@@ -51,7 +61,7 @@ func (s *CacheService) GetCacheKey(queryText string, params map[string]interface
 func (s *CacheService) GetCachedResult(ctx context.Context, cacheKey string) (*CacheEntry, error) {
 	query := `
 		SELECT id, cache_key, query_hash, query_text, result_data, expires_at,
-		       hit_count, created_at, last_accessed_at
+		       hit_count, created_at, last_accessed_at, source_tables, stale_since
 		FROM neuronip.query_cache
 		WHERE cache_key = $1 AND expires_at > NOW()
 	`
@@ -62,7 +72,7 @@ func (s *CacheService) GetCachedResult(ctx context.Context, cacheKey string) (*C
 	err := s.pool.QueryRow(ctx, query, cacheKey).Scan(
 		&entry.ID, &entry.CacheKey, &entry.QueryHash, &entry.QueryText,
 		&resultDataJSON, &entry.ExpiresAt, &entry.HitCount,
-		&entry.CreatedAt, &lastAccessedAt,
+		&entry.CreatedAt, &lastAccessedAt, &entry.SourceTables, &entry.StaleSince,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("cache miss: %w", err)
@@ -90,20 +100,26 @@ func (s *CacheService) SetCachedResult(ctx context.Context, queryText string, pa
 	resultDataJSON, _ := json.Marshal(results)
 	expiresAt := time.Now().Add(ttl)
 
+	// Record the tables the query reads so data changes can invalidate the entry.
+	// Queries that do not parse are cached without tables and only expire by TTL.
+	tables, _ := ReferencedTables(queryText)
+
 	query := `
 		INSERT INTO neuronip.query_cache (
 			cache_key, query_hash, query_text, result_data, ttl_seconds,
-			expires_at, hit_count, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, 0, NOW())
+			expires_at, hit_count, created_at, source_tables
+		) VALUES ($1, $2, $3, $4, $5, $6, 0, NOW(), $7)
 		ON CONFLICT (cache_key) DO UPDATE SET
 			result_data = $4,
 			ttl_seconds = $5,
 			expires_at = $6,
-			hit_count = 0
+			hit_count = 0,
+			source_tables = $7,
+			stale_since = NULL
 	`
 	_, err := s.pool.Exec(ctx, query,
 		cacheKey, queryHash, queryText, resultDataJSON,
-		int(ttl.Seconds()), expiresAt,
+		int(ttl.Seconds()), expiresAt, cache.NormalizeTableNames(tables),
 	)
 	return err
 }
@@ -118,11 +134,13 @@ func (s *CacheService) InvalidateCache(ctx context.Context, invalidationRule Inv
 		query = `DELETE FROM neuronip.query_cache WHERE query_hash = $1`
 		args = []interface{}{invalidationRule.Value}
 	case "schema":
-		query = `DELETE FROM neuronip.query_cache WHERE query_text LIKE $1`
-		args = []interface{}{fmt.Sprintf("%%FROM %s.%%", invalidationRule.Value)}
+		query = `
+			DELETE FROM neuronip.query_cache
+			WHERE EXISTS (SELECT 1 FROM unnest(source_tables) t WHERE t LIKE $1)`
+		args = []interface{}{strings.ToLower(invalidationRule.Value) + ".%"}
 	case "table":
-		query = `DELETE FROM neuronip.query_cache WHERE query_text LIKE $1`
-		args = []interface{}{fmt.Sprintf("%%FROM %s%%", invalidationRule.Value)}
+		query = `DELETE FROM neuronip.query_cache WHERE source_tables && $1`
+		args = []interface{}{cache.NormalizeTableNames([]string{invalidationRule.Value})}
 	case "time":
 		query = `DELETE FROM neuronip.query_cache WHERE expires_at < NOW()`
 		args = []interface{}{}
@@ -140,6 +158,178 @@ type InvalidationRule struct {
 	Value string
 }
 
+/* TableStaleness is how long cached results may outlive a change to a table */
+type TableStaleness struct {
+	TableName           string    `json:"table_name"`
+	MaxStalenessSeconds int       `json:"max_staleness_seconds"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+/* TableInvalidation summarizes the cache entries affected by a table change */
+type TableInvalidation struct {
+	Tables          []string `json:"tables"`
+	Reason          string   `json:"reason"`
+	EntriesRemoved  int64    `json:"entries_removed"`
+	EntriesExpiring int64    `json:"entries_expiring"`
+}
+
+/* TablesChanged invalidates cached results that read any of the changed tables */
+func (s *CacheService) TablesChanged(ctx context.Context, change cache.TableChange) error {
+	_, err := s.InvalidateTables(ctx, change)
+	return err
+}
+
+/* InvalidateTables removes or shortens cached results for changed tables according to their staleness tolerances */
+func (s *CacheService) InvalidateTables(ctx context.Context, change cache.TableChange) (*TableInvalidation, error) {
+	result := &TableInvalidation{Reason: string(change.Reason)}
+	if len(change.Tables) == 0 {
+		return result, nil
+	}
+
+	// Schema changes can break cached result shapes, so they never allow stale reads
+	tolerances := map[string]time.Duration{}
+	if change.Reason != cache.TableChangeSchema {
+		var err error
+		tolerances, err = s.tableStalenessFor(ctx, cache.NormalizeTableNames(change.Tables))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Group each changed table with its unqualified alias under the tolerance of the most specific match
+	groups := make(map[time.Duration][]string)
+	for _, table := range change.Tables {
+		names := cache.NormalizeTableNames([]string{table})
+		var tolerance time.Duration
+		for _, name := range names {
+			if d, ok := tolerances[name]; ok {
+				tolerance = d
+				break
+			}
+		}
+		groups[tolerance] = append(groups[tolerance], names...)
+		result.Tables = append(result.Tables, names...)
+	}
+	result.Tables = cache.NormalizeTableNames(result.Tables)
+
+	for tolerance, tables := range groups {
+		if tolerance <= 0 {
+			tag, err := s.pool.Exec(ctx, `
+				DELETE FROM neuronip.query_cache
+				WHERE source_tables && $1`, tables)
+			if err != nil {
+				return nil, fmt.Errorf("failed to invalidate cached results by table: %w", err)
+			}
+			result.EntriesRemoved += tag.RowsAffected()
+			if _, err := s.semanticCache.InvalidateTables(ctx, tables); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		tag, err := s.pool.Exec(ctx, `
+			UPDATE neuronip.query_cache
+			SET expires_at = LEAST(expires_at, NOW() + $2 * INTERVAL '1 second'),
+			    stale_since = COALESCE(stale_since, NOW())
+			WHERE source_tables && $1 AND expires_at > NOW()`,
+			tables, int64(tolerance.Seconds()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to expire cached results by table: %w", err)
+		}
+		result.EntriesExpiring += tag.RowsAffected()
+		if _, err := s.semanticCache.ExpireTables(ctx, tables, tolerance); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO neuronip.cache_invalidation_events
+			(tables, reason, source, entries_removed, entries_expiring)
+		VALUES ($1, $2, $3, $4, $5)`,
+		result.Tables, result.Reason, change.Source, result.EntriesRemoved, result.EntriesExpiring)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record cache invalidation: %w", err)
+	}
+
+	return result, nil
+}
+
+/* tableStalenessFor loads the staleness tolerances configured for the given normalized table names */
+func (s *CacheService) tableStalenessFor(ctx context.Context, tables []string) (map[string]time.Duration, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT table_name, max_staleness_seconds
+		FROM neuronip.cache_table_staleness
+		WHERE table_name = ANY($1)`, tables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load table staleness: %w", err)
+	}
+	defer rows.Close()
+
+	tolerances := make(map[string]time.Duration)
+	for rows.Next() {
+		var name string
+		var seconds int
+		if err := rows.Scan(&name, &seconds); err != nil {
+			return nil, fmt.Errorf("failed to scan table staleness: %w", err)
+		}
+		tolerances[name] = time.Duration(seconds) * time.Second
+	}
+	return tolerances, rows.Err()
+}
+
+/* SetTableStaleness sets how long cached results may be served after a table changes; zero removes the tolerance */
+func (s *CacheService) SetTableStaleness(ctx context.Context, tableName string, maxStaleness time.Duration) error {
+	names := cache.NormalizeTableNames([]string{tableName})
+	if len(names) == 0 {
+		return fmt.Errorf("table name is required")
+	}
+	if maxStaleness < 0 {
+		return fmt.Errorf("max staleness must not be negative")
+	}
+
+	if maxStaleness == 0 {
+		_, err := s.pool.Exec(ctx, `DELETE FROM neuronip.cache_table_staleness WHERE table_name = $1`, names[0])
+		if err != nil {
+			return fmt.Errorf("failed to remove table staleness: %w", err)
+		}
+		return nil
+	}
+
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO neuronip.cache_table_staleness (table_name, max_staleness_seconds, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (table_name) DO UPDATE SET
+			max_staleness_seconds = $2,
+			updated_at = NOW()`,
+		names[0], int(maxStaleness.Seconds()))
+	if err != nil {
+		return fmt.Errorf("failed to set table staleness: %w", err)
+	}
+	return nil
+}
+
+/* ListTableStaleness lists configured table staleness tolerances */
+func (s *CacheService) ListTableStaleness(ctx context.Context) ([]TableStaleness, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT table_name, max_staleness_seconds, updated_at
+		FROM neuronip.cache_table_staleness
+		ORDER BY table_name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list table staleness: %w", err)
+	}
+	defer rows.Close()
+
+	var tolerances []TableStaleness
+	for rows.Next() {
+		var t TableStaleness
+		if err := rows.Scan(&t.TableName, &t.MaxStalenessSeconds, &t.UpdatedAt); err != nil {
+			continue
+		}
+		tolerances = append(tolerances, t)
+	}
+	return tolerances, nil
+}
+
 /* GetCacheStats gets cache statistics */
 func (s *CacheService) GetCacheStats(ctx context.Context) (map[string]interface{}, error) {
 	query := `
@@ -0,0 +1,597 @@
+package warehouse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/neurondb/NeuronIP/api/internal/classification"
+)
+
+/* VegaLiteSchema is the schema URL of the chart specifications produced for query results */
+const VegaLiteSchema = "https://vega.github.io/schema/vega-lite/v5.json"
+
+/* ChartDataName is the named data source chart specs read query results from; clients bind the result rows to it */
+const ChartDataName = "results"
+
+/* Vega-Lite field types */
+const (
+	FieldQuantitative = "quantitative"
+	FieldTemporal     = "temporal"
+	FieldOrdinal      = "ordinal"
+	FieldNominal      = "nominal"
+)
+
+/* Field roles */
+const (
+	FieldMeasure   = "measure"
+	FieldDimension = "dimension"
+)
+
+/* Geographic roles, from catalog classification or column names */
+const (
+	GeoCountry    = classification.GeoCountry
+	GeoState      = classification.GeoState
+	GeoCity       = classification.GeoCity
+	GeoPostalCode = classification.GeoPostalCode
+	GeoLatitude   = classification.GeoLatitude
+	GeoLongitude  = classification.GeoLongitude
+)
+
+/* maxChartRecommendations is the number of charts returned besides the table */
+const maxChartRecommendations = 5
+
+/* GeoTopology is a TopoJSON source used to draw regions named in query results */
+type GeoTopology struct {
+	URL        string
+	Feature    string // TopoJSON object holding the regions
+	Key        string // Feature property matched against result values
+	Projection string
+}
+
+/* GeoTopologies are the region maps used for choropleth charts by geographic role */
+var GeoTopologies = map[string]GeoTopology{
+	GeoCountry: {URL: "https://cdn.jsdelivr.net/npm/world-atlas@2/countries-110m.json", Feature: "countries", Key: "properties.name", Projection: "equalEarth"},
+	GeoState:   {URL: "https://cdn.jsdelivr.net/npm/us-atlas@3/states-10m.json", Feature: "states", Key: "properties.name", Projection: "albersUsa"},
+}
+
+/* ResultField describes a result column for chart recommendation */
+type ResultField struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"` // Vega-Lite type
+	Role        string `json:"role"` // measure or dimension
+	Cardinality int    `json:"cardinality"`
+	Geo         string `json:"geo,omitempty"`
+
+	min float64
+}
+
+/* ChartRecommendation is a ranked Vega-Lite chart for a query result */
+type ChartRecommendation struct {
+	Rank   int                    `json:"rank"`
+	Mark   string                 `json:"mark"`
+	Title  string                 `json:"title"`
+	Score  float64                `json:"score"`
+	Reason string                 `json:"reason"`
+	Spec   map[string]interface{} `json:"spec,omitempty"` // Vega-Lite spec; absent for the table view
+}
+
+/* recommendCharts recommends charts for query results, using catalog classification to find geographic columns */
+func (s *Service) recommendCharts(ctx context.Context, sql string, results []map[string]interface{}) []ChartRecommendation {
+	if len(results) == 0 {
+		return nil
+	}
+	return RecommendCharts(results, s.geographicColumns(ctx, sql))
+}
+
+/* SuggestCharts recommends charts for results supplied by a client, such as a saved answer */
+func (s *Service) SuggestCharts(ctx context.Context, sql string, results []map[string]interface{}) []ChartRecommendation {
+	return s.recommendCharts(ctx, sql, results)
+}
+
+/* geographicColumns returns the geographic role of classified columns of the tables a query reads */
+func (s *Service) geographicColumns(ctx context.Context, sql string) map[string]string {
+	geo := make(map[string]string)
+	if s.pool == nil || sql == "" {
+		return geo
+	}
+	tables, err := ReferencedTables(sql)
+	if err != nil || len(tables) == 0 {
+		return geo
+	}
+	// Unqualified tables resolve to the public schema
+	schemas := make([]string, len(tables))
+	names := make([]string, len(tables))
+	for i, table := range tables {
+		schemas[i] = "public"
+		names[i] = table
+		if dot := strings.LastIndex(table, "."); dot >= 0 {
+			schemas[i] = table[:dot]
+			names[i] = table[dot+1:]
+		}
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT column_name, COALESCE(metadata->>'geo_role', ''), COALESCE(detected_patterns, '{}')
+		FROM neuronip.data_classifications
+		WHERE (schema_name, table_name) IN (SELECT * FROM unnest($1::text[], $2::text[]))`, schemas, names)
+	if err != nil {
+		return geo
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var column, role string
+		var patterns []string
+		if err := rows.Scan(&column, &role, &patterns); err != nil {
+			continue
+		}
+		for _, pattern := range patterns {
+			if role == "" && strings.HasPrefix(pattern, "geo:") {
+				role = strings.TrimPrefix(pattern, "geo:")
+			}
+		}
+		if role != "" {
+			geo[strings.ToLower(column)] = role
+		}
+	}
+	return geo
+}
+
+/* ProfileResultFields infers the Vega-Lite type, role and cardinality of each result column.
+ * geo maps lower-cased column names to geographic roles from catalog classification. */
+func ProfileResultFields(results []map[string]interface{}, geo map[string]string) []ResultField {
+	names := make(map[string]bool)
+	for _, row := range results {
+		for name := range row {
+			names[name] = true
+		}
+	}
+	columns := make([]string, 0, len(names))
+	for name := range names {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+
+	fields := make([]ResultField, 0, len(columns))
+	for _, column := range columns {
+		var numeric, temporal, other int
+		distinct := make(map[string]bool)
+		min := math.Inf(1)
+		for _, row := range results {
+			v := row[column]
+			if v == nil {
+				continue
+			}
+			if len(distinct) <= 1000 {
+				distinct[fmt.Sprint(v)] = true
+			}
+			if f, ok := numericValue(v); ok {
+				numeric++
+				min = math.Min(min, f)
+			} else if isTemporalValue(v) {
+				temporal++
+			} else {
+				other++
+			}
+		}
+
+		field := ResultField{Name: column, Type: FieldNominal, Role: FieldDimension, Cardinality: len(distinct), min: min}
+		name := strings.ToLower(column)
+		switch {
+		case numeric > 0 && temporal == 0 && other == 0:
+			field.Type, field.Role = FieldQuantitative, FieldMeasure
+			switch {
+			case name == "id" || strings.HasSuffix(name, "_id"):
+				field.Type, field.Role = FieldNominal, FieldDimension
+			case name == "year" || name == "quarter" || name == "month" || name == "week" || strings.HasSuffix(name, "_year"):
+				field.Type, field.Role = FieldOrdinal, FieldDimension
+			}
+		case temporal > 0 && numeric == 0 && other == 0:
+			field.Type = FieldTemporal
+		}
+
+		if role, ok := geo[name]; ok {
+			field.Geo = role
+		} else {
+			field.Geo = classification.GeographicRole(column)
+		}
+		if field.Geo == GeoLatitude || field.Geo == GeoLongitude {
+			if field.Type != FieldQuantitative {
+				field.Geo = ""
+			} else {
+				field.Role = FieldDimension
+			}
+		} else if field.Geo != "" && field.Type != FieldNominal {
+			field.Geo = ""
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+/* RecommendCharts returns Vega-Lite charts for query results ranked by fit, followed by the table view */
+func RecommendCharts(results []map[string]interface{}, geo map[string]string) []ChartRecommendation {
+	fields := ProfileResultFields(results, geo)
+
+	var measures, temporals, categories []ResultField
+	var latitude, longitude *ResultField
+	for i := range fields {
+		f := fields[i]
+		switch {
+		case f.Geo == GeoLatitude:
+			latitude = &fields[i]
+		case f.Geo == GeoLongitude:
+			longitude = &fields[i]
+		case f.Role == FieldMeasure:
+			measures = append(measures, f)
+		case f.Type == FieldTemporal:
+			temporals = append(temporals, f)
+		case f.Cardinality > 0:
+			categories = append(categories, f)
+		}
+	}
+	// Lowest cardinality first: the best axis or color candidate
+	sort.SliceStable(categories, func(i, j int) bool {
+		return categories[i].Cardinality < categories[j].Cardinality
+	})
+
+	var charts []ChartRecommendation
+	add := func(mark string, score float64, title, reason string, spec map[string]interface{}) {
+		charts = append(charts, ChartRecommendation{Mark: mark, Score: score, Title: title, Reason: reason, Spec: spec})
+	}
+	rows := len(results)
+
+	// Single-value answers
+	if rows == 1 && len(temporals) == 0 && len(categories) == 0 && len(measures) > 0 {
+		m := measures[0]
+		add("text", 0.95, m.Name, "a single value is best shown as a number", vegaLite(
+			map[string]interface{}{"type": "text", "fontSize": 48, "fontWeight": "bold"},
+			map[string]interface{}{"text": fieldDef(m, map[string]interface{}{"format": ",.2~f"})}))
+	}
+
+	// Measures over time
+	if len(temporals) > 0 && len(measures) > 0 {
+		t := temporals[0]
+		title := measureNames(measures) + " over " + t.Name
+		encoding := map[string]interface{}{"x": fieldDef(t, nil)}
+		var transform []interface{}
+		color := smallCategory(categories, 10)
+		switch {
+		case len(measures) > 1:
+			transform = foldMeasures(measures)
+			encoding["y"] = map[string]interface{}{"field": "value", "type": FieldQuantitative}
+			encoding["color"] = map[string]interface{}{"field": "measure", "type": FieldNominal}
+		case color != nil:
+			encoding["y"] = fieldDef(measures[0], nil)
+			encoding["color"] = fieldDef(*color, nil)
+			title += " by " + color.Name
+		default:
+			encoding["y"] = fieldDef(measures[0], nil)
+		}
+		encoding["tooltip"] = tooltip(fields)
+
+		line := vegaLite(map[string]interface{}{"type": "line", "point": rows <= 60}, encoding)
+		withTransform(line, transform)
+		add("line", 0.95, title, fmt.Sprintf("%s is temporal and %s is a measure", t.Name, measures[0].Name), line)
+
+		if color != nil && len(measures) == 1 {
+			area := vegaLite("area", copyEncoding(encoding))
+			add("area", 0.7, title, "stacked areas show each "+color.Name+"'s share of the total over time", area)
+		}
+		if rows <= 24 {
+			bar := vegaLite("bar", copyEncoding(encoding))
+			withTransform(bar, transform)
+			add("bar", 0.72, title, "few time periods can be compared as bars", bar)
+		}
+	}
+
+	// Measures by category
+	if len(categories) > 0 && len(measures) > 0 {
+		c, m := categories[0], measures[0]
+		title := m.Name + " by " + c.Name
+		sortOrder := "-x"
+		encoding := map[string]interface{}{"tooltip": tooltip(fields)}
+		score := 0.9
+		if c.Cardinality > 12 {
+			// Many categories read better as horizontal bars
+			encoding["y"] = fieldDef(c, map[string]interface{}{"sort": sortOrder})
+			encoding["x"] = fieldDef(m, nil)
+			if c.Cardinality > 50 {
+				score = 0.6
+			}
+		} else {
+			sortOrder = "-y"
+			encoding["x"] = fieldDef(c, map[string]interface{}{"sort": sortOrder})
+			encoding["y"] = fieldDef(m, nil)
+		}
+		if len(temporals) > 0 {
+			score -= 0.1
+		}
+		add("bar", score, title, fmt.Sprintf("%s has %d categories and %s is a measure", c.Name, c.Cardinality, m.Name), vegaLite("bar", encoding))
+
+		if len(measures) > 1 && c.Cardinality <= 20 {
+			grouped := vegaLite("bar", map[string]interface{}{
+				"x":       fieldDef(c, nil),
+				"xOffset": map[string]interface{}{"field": "measure", "type": FieldNominal},
+				"y":       map[string]interface{}{"field": "value", "type": FieldQuantitative},
+				"color":   map[string]interface{}{"field": "measure", "type": FieldNominal},
+				"tooltip": tooltip(fields),
+			})
+			withTransform(grouped, foldMeasures(measures))
+			add("bar", score-0.05, measureNames(measures)+" by "+c.Name, "several measures are compared side by side", grouped)
+		}
+
+		if len(categories) > 1 {
+			c2 := categories[1]
+			if c.Cardinality <= 10 {
+				stacked := vegaLite("bar", map[string]interface{}{
+					"x":       fieldDef(c2, nil),
+					"y":       fieldDef(m, nil),
+					"color":   fieldDef(c, nil),
+					"tooltip": tooltip(fields),
+				})
+				add("bar", 0.82, m.Name+" by "+c2.Name+" and "+c.Name, c.Name+" has few enough values to stack", stacked)
+			}
+			if c.Cardinality <= 50 && c2.Cardinality <= 50 {
+				heatmap := vegaLite("rect", map[string]interface{}{
+					"x":       fieldDef(c2, nil),
+					"y":       fieldDef(c, nil),
+					"color":   fieldDef(m, nil),
+					"tooltip": tooltip(fields),
+				})
+				add("rect", 0.75, m.Name+" by "+c.Name+" and "+c2.Name, "two categorical columns form a grid", heatmap)
+			}
+		}
+
+		if c.Cardinality <= 6 && len(measures) == 1 && m.min >= 0 && len(temporals) == 0 {
+			pie := vegaLite("arc", map[string]interface{}{
+				"theta":   fieldDef(m, map[string]interface{}{"stack": true}),
+				"color":   fieldDef(c, nil),
+				"tooltip": tooltip(fields),
+			})
+			add("arc", 0.6, title, "a few non-negative parts of a whole", pie)
+		}
+	}
+
+	// Maps
+	if latitude != nil && longitude != nil {
+		encoding := map[string]interface{}{
+			"latitude":  map[string]interface{}{"field": latitude.Name, "type": FieldQuantitative},
+			"longitude": map[string]interface{}{"field": longitude.Name, "type": FieldQuantitative},
+			"tooltip":   tooltip(fields),
+		}
+		title := "locations"
+		if len(measures) > 0 {
+			encoding["size"] = fieldDef(measures[0], nil)
+			title = measures[0].Name + " by location"
+		}
+		points := vegaLite("circle", encoding)
+		points["projection"] = map[string]interface{}{"type": "equalEarth"}
+		add("circle", 0.93, title, "latitude and longitude place each row on a map", points)
+	}
+	for _, c := range categories {
+		topology, ok := GeoTopologies[c.Geo]
+		if !ok || len(measures) == 0 {
+			continue
+		}
+		m := measures[0]
+		choropleth := map[string]interface{}{
+			"$schema": VegaLiteSchema,
+			"data": map[string]interface{}{
+				"url":    topology.URL,
+				"format": map[string]interface{}{"type": "topojson", "feature": topology.Feature},
+			},
+			"transform": []interface{}{map[string]interface{}{
+				"lookup": topology.Key,
+				"from": map[string]interface{}{
+					"data":   map[string]interface{}{"name": ChartDataName},
+					"key":    c.Name,
+					"fields": []string{m.Name},
+				},
+			}},
+			"projection": map[string]interface{}{"type": topology.Projection},
+			"mark":       "geoshape",
+			"encoding": map[string]interface{}{
+				"color":   fieldDef(m, nil),
+				"tooltip": []interface{}{map[string]interface{}{"field": topology.Key, "type": FieldNominal, "title": c.Name}, fieldDef(m, nil)},
+			},
+		}
+		add("geoshape", 0.92, m.Name+" by "+c.Name, c.Name+" is a geographic field ("+c.Geo+")", choropleth)
+		break
+	}
+
+	// Relationships between measures
+	if len(measures) >= 2 && rows > 2 {
+		encoding := map[string]interface{}{
+			"x":       fieldDef(measures[0], nil),
+			"y":       fieldDef(measures[1], nil),
+			"tooltip": tooltip(fields),
+		}
+		if color := smallCategory(categories, 10); color != nil {
+			encoding["color"] = fieldDef(*color, nil)
+		}
+		if len(measures) > 2 {
+			encoding["size"] = fieldDef(measures[2], nil)
+		}
+		score := 0.7
+		if len(categories) == 0 && len(temporals) == 0 {
+			score = 0.85
+		}
+		add("point", score, measures[1].Name+" vs "+measures[0].Name, "two measures can be compared for correlation", vegaLite("point", encoding))
+	}
+
+	// Distribution of a single measure
+	if len(measures) == 1 && len(categories) == 0 && len(temporals) == 0 && rows > 5 {
+		histogram := vegaLite("bar", map[string]interface{}{
+			"x": fieldDef(measures[0], map[string]interface{}{"bin": true}),
+			"y": map[string]interface{}{"aggregate": "count", "type": FieldQuantitative},
+		})
+		add("bar", 0.6, "distribution of "+measures[0].Name, "a single measure is summarized by its distribution", histogram)
+	}
+
+	sort.SliceStable(charts, func(i, j int) bool {
+		return charts[i].Score > charts[j].Score
+	})
+	if len(charts) > maxChartRecommendations {
+		charts = charts[:maxChartRecommendations]
+	}
+	charts = append(charts, ChartRecommendation{Mark: "table", Score: 0.1, Title: "table", Reason: "every result can be shown as a table"})
+	for i := range charts {
+		charts[i].Rank = i + 1
+	}
+	return charts
+}
+
+/* topChart returns the best chart's spec and mark for the legacy chart_config and chart_type fields */
+func topChart(charts []ChartRecommendation) (map[string]interface{}, string) {
+	if len(charts) == 0 {
+		return nil, ""
+	}
+	return charts[0].Spec, charts[0].Mark
+}
+
+/* ValidateChartSpec checks that a saved chart is a Vega-Lite specification */
+func ValidateChartSpec(spec map[string]interface{}) error {
+	if len(spec) == 0 {
+		return fmt.Errorf("chart spec is required")
+	}
+	if schema, ok := spec["$schema"]; ok {
+		if s, _ := schema.(string); !strings.Contains(s, "vega-lite") {
+			return fmt.Errorf("chart spec $schema must be a Vega-Lite schema")
+		}
+	}
+	hasView := false
+	for _, key := range []string{"mark", "layer", "concat", "hconcat", "vconcat", "facet", "repeat"} {
+		if _, ok := spec[key]; ok {
+			hasView = true
+		}
+	}
+	if !hasView {
+		return fmt.Errorf("chart spec must have a mark or a composition (layer, concat, facet or repeat)")
+	}
+	if encoded, err := json.Marshal(spec); err != nil {
+		return fmt.Errorf("chart spec is not valid JSON: %w", err)
+	} else if len(encoded) > 256<<10 {
+		return fmt.Errorf("chart spec is larger than 256KB; bind data by name instead of inlining it")
+	}
+	return nil
+}
+
+/* vegaLite builds a single-view spec reading the named query results */
+func vegaLite(mark interface{}, encoding map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"$schema":  VegaLiteSchema,
+		"data":     map[string]interface{}{"name": ChartDataName},
+		"mark":     mark,
+		"encoding": encoding,
+	}
+}
+
+func withTransform(spec map[string]interface{}, transform []interface{}) {
+	if len(transform) > 0 {
+		spec["transform"] = transform
+	}
+}
+
+func fieldDef(f ResultField, extra map[string]interface{}) map[string]interface{} {
+	def := map[string]interface{}{"field": f.Name, "type": f.Type}
+	for k, v := range extra {
+		def[k] = v
+	}
+	return def
+}
+
+func tooltip(fields []ResultField) []interface{} {
+	defs := make([]interface{}, 0, len(fields))
+	for _, f := range fields {
+		defs = append(defs, fieldDef(f, nil))
+	}
+	return defs
+}
+
+/* foldMeasures turns several measure columns into measure/value rows */
+func foldMeasures(measures []ResultField) []interface{} {
+	names := make([]string, len(measures))
+	for i, m := range measures {
+		names[i] = m.Name
+	}
+	return []interface{}{map[string]interface{}{"fold": names, "as": []string{"measure", "value"}}}
+}
+
+func measureNames(measures []ResultField) string {
+	if len(measures) == 1 {
+		return measures[0].Name
+	}
+	return measures[0].Name + " and " + measures[1].Name
+}
+
+/* smallCategory returns the first categorical field with between 2 and max values */
+func smallCategory(categories []ResultField, max int) *ResultField {
+	for i := range categories {
+		if categories[i].Cardinality > 1 && categories[i].Cardinality <= max {
+			return &categories[i]
+		}
+	}
+	return nil
+}
+
+func copyEncoding(encoding map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(encoding))
+	for k, v := range encoding {
+		out[k] = v
+	}
+	return out
+}
+
+func numericValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case pgtype.Numeric:
+		f, err := n.Float64Value()
+		return f.Float64, err == nil && f.Valid
+	}
+	return 0, false
+}
+
+func isTemporalValue(v interface{}) bool {
+	switch t := v.(type) {
+	case time.Time:
+		return true
+	case string:
+		if _, err := time.Parse(time.RFC3339, t); err == nil {
+			return true
+		}
+		for _, layout := range []string{"2006-01-02", "2006-01-02 15:04:05", "2006-01"} {
+			if _, err := time.Parse(layout, t); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package warehouse
+
+import (
+	"testing"
+	"time"
+)
+
+/* TestRecommendCharts checks the best chart recommended for common result shapes */
+func TestRecommendCharts(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rowsOf := func(n int, row func(i int) map[string]interface{}) []map[string]interface{} {
+		results := make([]map[string]interface{}, n)
+		for i := range results {
+			results[i] = row(i)
+		}
+		return results
+	}
+	regions := []string{"EMEA", "APAC", "AMER", "LATAM"}
+
+	tests := []struct {
+		name    string
+		results []map[string]interface{}
+		geo     map[string]string
+		mark    string
+	}{
+		{name: "single value", results: rowsOf(1, func(i int) map[string]interface{} { return map[string]interface{}{"total": 42.5} }), mark: "text"},
+		{name: "time series", results: rowsOf(30, func(i int) map[string]interface{} {
+			return map[string]interface{}{"day": start.AddDate(0, 0, i), "revenue": float64(100 + i)}
+		}), mark: "line"},
+		{name: "categories", results: rowsOf(4, func(i int) map[string]interface{} {
+			return map[string]interface{}{"region": regions[i], "revenue": float64(10 * (i + 1))}
+		}), mark: "bar"},
+		{name: "points on a map", results: rowsOf(20, func(i int) map[string]interface{} {
+			return map[string]interface{}{"pickup_lat": 40 + float64(i)/100, "pickup_lon": -73 - float64(i)/100, "fare": float64(i)}
+		}), mark: "circle"},
+		{name: "named geographic column", results: rowsOf(3, func(i int) map[string]interface{} {
+			return map[string]interface{}{"billing_country": []string{"France", "Germany", "Spain"}[i], "revenue": float64(i + 1)}
+		}), mark: "geoshape"},
+		{name: "classified geographic column", results: rowsOf(3, func(i int) map[string]interface{} {
+			return map[string]interface{}{"market": []string{"France", "Germany", "Spain"}[i], "revenue": float64(i + 1)}
+		}), geo: map[string]string{"market": GeoCountry}, mark: "geoshape"},
+		{name: "distribution", results: rowsOf(50, func(i int) map[string]interface{} {
+			return map[string]interface{}{"latency_ms": float64(i * i % 97)}
+		}), mark: "bar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			charts := RecommendCharts(tt.results, tt.geo)
+			if len(charts) < 2 {
+				t.Fatalf("expected a chart and the table view, got %+v", charts)
+			}
+			if charts[0].Mark != tt.mark {
+				t.Errorf("expected a %s chart first, got %s (%s)", tt.mark, charts[0].Mark, charts[0].Reason)
+			}
+			last := charts[len(charts)-1]
+			if last.Mark != "table" || last.Spec != nil {
+				t.Errorf("expected the table view last, got %+v", last)
+			}
+			for i, chart := range charts {
+				if chart.Rank != i+1 {
+					t.Errorf("expected rank %d, got %d", i+1, chart.Rank)
+				}
+				if chart.Mark != "table" {
+					if err := ValidateChartSpec(chart.Spec); err != nil {
+						t.Errorf("%s chart: %v", chart.Mark, err)
+					}
+				}
+			}
+			if len(charts) > maxChartRecommendations+1 {
+				t.Errorf("expected at most %d charts, got %d", maxChartRecommendations+1, len(charts))
+			}
+		})
+	}
+}
@@ -0,0 +1,689 @@
+package warehouse
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+/* Result stream event types */
+const (
+	ResultEventColumns = "columns" // First event: query ID, SQL and column descriptions
+	ResultEventRow     = "row"
+	ResultEventEnd     = "end"
+	ResultEventError   = "error" // Written by the transport when a stream fails after it started
+)
+
+/* Cursor error codes */
+const (
+	CursorErrorInvalidToken  = "invalid_token"
+	CursorErrorNotFound      = "not_found"
+	CursorErrorLimitExceeded = "limit_exceeded"
+	CursorErrorOtherReplica  = "other_replica" // The token belongs to a cursor held by another server process
+)
+
+/* ResultLimits bounds paginated and streamed results for a role */
+type ResultLimits struct {
+	DefaultPageSize   int
+	MaxPageSize       int
+	MaxTotalRows      int           // Row cap for a cursor or stream
+	MaxOpenCursors    int           // Open cursors and streams per user
+	CursorIdleTimeout time.Duration // Cursors unused for this long are closed
+	StatementTimeout  time.Duration
+}
+
+/* DefaultResultLimits returns the limits used for roles without configured limits */
+func DefaultResultLimits() ResultLimits {
+	return ResultLimits{
+		DefaultPageSize:   1000,
+		MaxPageSize:       10000,
+		MaxTotalRows:      1000000,
+		MaxOpenCursors:    4,
+		CursorIdleTimeout: 5 * time.Minute,
+		StatementTimeout:  5 * time.Minute,
+	}
+}
+
+/* ResultColumn describes a result column */
+type ResultColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+/* ResultPage is one page of a cursor-paginated query result */
+type ResultPage struct {
+	QueryID       uuid.UUID                `json:"query_id"`
+	CursorID      uuid.UUID                `json:"cursor_id"`
+	SQL           string                   `json:"sql,omitempty"` // Set on the first page
+	Columns       []ResultColumn           `json:"columns"`
+	Rows          []map[string]interface{} `json:"rows"`
+	RowOffset     int64                    `json:"row_offset"` // Rows preceding this page
+	NextPageToken string                   `json:"next_page_token,omitempty"`
+	Done          bool                     `json:"done"`
+	RowLimit      int                      `json:"row_limit"`
+	LimitApplied  bool                     `json:"limit_applied"` // The query was capped at RowLimit
+}
+
+/* ResultStreamEvent is one event of a streamed query result */
+type ResultStreamEvent struct {
+	Type         string         `json:"type"`
+	QueryID      *uuid.UUID     `json:"query_id,omitempty"`
+	SQL          string         `json:"sql,omitempty"`
+	Columns      []ResultColumn `json:"columns,omitempty"`
+	Values       []interface{}  `json:"values,omitempty"` // Row values in column order
+	RowCount     *int64         `json:"row_count,omitempty"`
+	RowLimit     int            `json:"row_limit,omitempty"`
+	LimitApplied bool           `json:"limit_applied,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+/* CursorError is returned for invalid page tokens, unknown cursors and exhausted cursor limits */
+type CursorError struct {
+	Code    string
+	Message string
+}
+
+func (e *CursorError) Error() string {
+	return e.Message
+}
+
+/* AsCursorError returns the cursor error wrapped in err, or nil */
+func AsCursorError(err error) *CursorError {
+	var cursorErr *CursorError
+	if errors.As(err, &cursorErr) {
+		return cursorErr
+	}
+	return nil
+}
+
+/* resultCursors tracks open server-side cursors and per-user result slots */
+type resultCursors struct {
+	mu       sync.Mutex
+	cursors  map[uuid.UUID]*resultCursor
+	active   map[string]int // Open cursors and streams per user
+	total    int            // Open cursors and streams across users
+	maxTotal int            // Cap on total, so held connections cannot drain the pool
+	tokenKey []byte         // Signs page tokens so clients cannot forge positions
+	instance [8]byte        // Identifies this process in page tokens; cursors live in one process only
+}
+
+/* defaultMaxOpenCursorsTotal caps open cursors across users when the pool size is unknown */
+const defaultMaxOpenCursorsTotal = 8
+
+/* maxOpenCursorsForPool caps open cursors across users at a quarter of the pool.
+ * Each cursor or stream holds a pool connection until it closes, so the rest stay free for other queries. */
+func maxOpenCursorsForPool(pool *pgxpool.Pool) int {
+	if pool == nil {
+		return defaultMaxOpenCursorsTotal
+	}
+	if max := int(pool.Config().MaxConns) / 4; max > 0 {
+		return max
+	}
+	return 1
+}
+
+/* resultCursor is a SQL cursor held open in a read-only snapshot transaction */
+type resultCursor struct {
+	mu        sync.Mutex
+	id        uuid.UUID
+	queryID   uuid.UUID
+	owner     string
+	name      string
+	conn      *pgxpool.Conn
+	tx        pgx.Tx
+	columns   []ResultColumn
+	position  int64 // Rows consumed so far
+	pageSize  int
+	limits    ResultLimits
+	limited   bool
+	idleTimer *time.Timer
+	lastUsed  time.Time
+	openedAt  time.Time
+	closed    bool
+}
+
+func newResultCursors(maxTotal int) *resultCursors {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("failed to generate page token key: %v", err))
+	}
+	m := &resultCursors{
+		cursors:  make(map[uuid.UUID]*resultCursor),
+		active:   make(map[string]int),
+		maxTotal: maxTotal,
+		tokenKey: key,
+	}
+	if _, err := rand.Read(m.instance[:]); err != nil {
+		panic(fmt.Sprintf("failed to generate cursor instance ID: %v", err))
+	}
+	return m
+}
+
+/* acquireSlot reserves one of the user's cursor slots, within the cap across users */
+func (m *resultCursors) acquireSlot(owner string, max int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if max > 0 && m.active[owner] >= max {
+		return &CursorError{
+			Code:    CursorErrorLimitExceeded,
+			Message: fmt.Sprintf("too many open result cursors (limit %d); close a cursor or finish a stream first", max),
+		}
+	}
+	if m.maxTotal > 0 && m.total >= m.maxTotal {
+		return &CursorError{
+			Code:    CursorErrorLimitExceeded,
+			Message: "too many result cursors are open on the server; try again shortly",
+		}
+	}
+	m.active[owner]++
+	m.total++
+	return nil
+}
+
+/* releaseSlot frees a slot taken by acquireSlot */
+func (m *resultCursors) releaseSlot(owner string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.total > 0 {
+		m.total--
+	}
+	if m.active[owner] <= 1 {
+		delete(m.active, owner)
+		return
+	}
+	m.active[owner]--
+}
+
+func (m *resultCursors) get(id uuid.UUID) *resultCursor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cursors[id]
+}
+
+func (m *resultCursors) put(c *resultCursor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cursors[c.id] = c
+}
+
+func (m *resultCursors) remove(id uuid.UUID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.cursors, id)
+}
+
+/* encodeToken builds an opaque page token: instance ID, cursor ID, row position and a truncated HMAC */
+func (m *resultCursors) encodeToken(id uuid.UUID, position int64) string {
+	payload := make([]byte, 32)
+	copy(payload, m.instance[:])
+	copy(payload[8:], id[:])
+	binary.BigEndian.PutUint64(payload[24:], uint64(position))
+	mac := hmac.New(sha256.New, m.tokenKey)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(append(payload, mac.Sum(nil)[:16]...))
+}
+
+/* decodeToken verifies a page token and returns its cursor ID and row position.
+ * The instance ID is checked before the HMAC, whose key is also per process, so a token sent
+ * to the wrong replica is reported as such rather than as forged. */
+func (m *resultCursors) decodeToken(token string) (uuid.UUID, int64, error) {
+	invalid := &CursorError{Code: CursorErrorInvalidToken, Message: "invalid page token"}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != 48 {
+		return uuid.Nil, 0, invalid
+	}
+	if !hmac.Equal(raw[:8], m.instance[:]) {
+		return uuid.Nil, 0, &CursorError{
+			Code: CursorErrorOtherReplica,
+			Message: "page token was issued by another API replica, or by this one before it restarted; " +
+				"page requests must reach the replica that opened the cursor",
+		}
+	}
+	mac := hmac.New(sha256.New, m.tokenKey)
+	mac.Write(raw[:32])
+	if !hmac.Equal(raw[32:], mac.Sum(nil)[:16]) {
+		return uuid.Nil, 0, invalid
+	}
+	id, err := uuid.FromBytes(raw[8:24])
+	if err != nil {
+		return uuid.Nil, 0, invalid
+	}
+	return id, int64(binary.BigEndian.Uint64(raw[24:32])), nil
+}
+
+/* OpenCursor runs a query behind a server-side cursor and returns its first page.
+ * All pages are read from one repeatable-read snapshot, so rows do not shift between pages. */
+func (s *Service) OpenCursor(ctx context.Context, req QueryRequest, pageSize int) (*ResultPage, error) {
+	limits := s.resultLimitsFor(ctx, req.UserID)
+	pageSize = clampPageSize(pageSize, limits)
+	owner := ownerKey(req.UserID)
+
+	if err := s.cursors.acquireSlot(owner, limits.MaxOpenCursors); err != nil {
+		return nil, err
+	}
+
+	generatedSQL, validated, req, err := s.prepareResultQuery(ctx, req, limits)
+	if err != nil {
+		s.cursors.releaseSlot(owner)
+		return nil, err
+	}
+
+	openedAt := time.Now()
+	queryID, err := s.createQueryRecord(ctx, req, generatedSQL, openedAt)
+	if err != nil {
+		s.cursors.releaseSlot(owner)
+		return nil, err
+	}
+
+	conn, tx, err := s.beginSnapshot(ctx, limits)
+	if err != nil {
+		s.finishResultQuery(queryID, openedAt, 0, err)
+		s.cursors.releaseSlot(owner)
+		return nil, err
+	}
+
+	cursorID := uuid.New()
+	cursor := &resultCursor{
+		id:       cursorID,
+		queryID:  queryID,
+		owner:    owner,
+		name:     "warehouse_cursor_" + strings.ReplaceAll(cursorID.String(), "-", ""),
+		conn:     conn,
+		tx:       tx,
+		pageSize: pageSize,
+		limits:   limits,
+		limited:  validated.LimitApplied,
+		openedAt: openedAt,
+	}
+
+	// Cursor statements use the simple protocol so per-cursor names do not fill the statement cache.
+	// SCROLL lets a client retry a page by moving back to the position in its token
+	_, err = tx.Exec(ctx, fmt.Sprintf("DECLARE %s SCROLL CURSOR FOR %s", cursor.name, generatedSQL), pgx.QueryExecModeSimpleProtocol)
+	if err != nil {
+		err = fmt.Errorf("failed to declare cursor: %w", err)
+		s.closeCursor(cursor, err)
+		return nil, err
+	}
+
+	cursor.mu.Lock()
+	defer cursor.mu.Unlock()
+	s.cursors.put(cursor)
+	cursor.idleTimer = time.AfterFunc(limits.CursorIdleTimeout, func() { s.expireCursor(cursorID) })
+
+	page, err := s.fetchPage(ctx, cursor, 0)
+	if err != nil {
+		return nil, err
+	}
+	page.SQL = generatedSQL
+	return page, nil
+}
+
+/* NextPage returns the page a token points at; tokens may be replayed to retry a page */
+func (s *Service) NextPage(ctx context.Context, pageToken string, userID *string) (*ResultPage, error) {
+	cursorID, position, err := s.cursors.decodeToken(pageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := s.cursors.get(cursorID)
+	if cursor == nil || cursor.owner != ownerKey(userID) {
+		return nil, &CursorError{Code: CursorErrorNotFound, Message: "cursor not found or expired"}
+	}
+
+	cursor.mu.Lock()
+	defer cursor.mu.Unlock()
+	if cursor.closed {
+		return nil, &CursorError{Code: CursorErrorNotFound, Message: "cursor not found or expired"}
+	}
+	cursor.idleTimer.Stop()
+	return s.fetchPage(ctx, cursor, position)
+}
+
+/* CloseCursor closes a cursor before it has been read to the end */
+func (s *Service) CloseCursor(ctx context.Context, cursorID uuid.UUID, userID *string) error {
+	cursor := s.cursors.get(cursorID)
+	if cursor == nil || cursor.owner != ownerKey(userID) {
+		return &CursorError{Code: CursorErrorNotFound, Message: "cursor not found or expired"}
+	}
+
+	cursor.mu.Lock()
+	defer cursor.mu.Unlock()
+	if !cursor.closed {
+		s.closeCursor(cursor, nil)
+	}
+	return nil
+}
+
+/* fetchPage reads the page starting at position; the caller holds the cursor lock */
+func (s *Service) fetchPage(ctx context.Context, cursor *resultCursor, position int64) (*ResultPage, error) {
+	cursor.lastUsed = time.Now()
+	if position != cursor.position {
+		if _, err := cursor.tx.Exec(ctx, fmt.Sprintf("MOVE ABSOLUTE %d FROM %s", position, cursor.name), pgx.QueryExecModeSimpleProtocol); err != nil {
+			err = fmt.Errorf("failed to position cursor: %w", err)
+			s.closeCursor(cursor, err)
+			return nil, err
+		}
+		cursor.position = position
+	}
+
+	rows, err := cursor.tx.Query(ctx, fmt.Sprintf("FETCH FORWARD %d FROM %s", cursor.pageSize, cursor.name), pgx.QueryExecModeSimpleProtocol)
+	if err != nil {
+		err = fmt.Errorf("failed to fetch page: %w", err)
+		s.closeCursor(cursor, err)
+		return nil, err
+	}
+
+	if cursor.columns == nil {
+		cursor.columns = resultColumns(cursor.conn.Conn(), rows.FieldDescriptions())
+	}
+	results := make([]map[string]interface{}, 0, cursor.pageSize)
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			rows.Close()
+			err = fmt.Errorf("failed to get row values: %w", err)
+			s.closeCursor(cursor, err)
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(values))
+		for i, column := range cursor.columns {
+			row[column.Name] = values[i]
+		}
+		results = append(results, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		err = fmt.Errorf("failed to fetch page: %w", err)
+		s.closeCursor(cursor, err)
+		return nil, err
+	}
+
+	page := &ResultPage{
+		QueryID:      cursor.queryID,
+		CursorID:     cursor.id,
+		Columns:      cursor.columns,
+		Rows:         results,
+		RowOffset:    position,
+		RowLimit:     cursor.limits.MaxTotalRows,
+		LimitApplied: cursor.limited,
+	}
+	cursor.position = position + int64(len(results))
+
+	if len(results) < cursor.pageSize {
+		page.Done = true
+		s.closeCursor(cursor, nil)
+		return page, nil
+	}
+
+	page.NextPageToken = s.cursors.encodeToken(cursor.id, cursor.position)
+	cursor.idleTimer.Reset(cursor.limits.CursorIdleTimeout)
+	return page, nil
+}
+
+/* expireCursor closes a cursor that was idle for longer than its role allows */
+func (s *Service) expireCursor(cursorID uuid.UUID) {
+	cursor := s.cursors.get(cursorID)
+	if cursor == nil {
+		return
+	}
+	cursor.mu.Lock()
+	defer cursor.mu.Unlock()
+	// A page may have been fetched while the timer was firing
+	if !cursor.closed && time.Since(cursor.lastUsed) >= cursor.limits.CursorIdleTimeout {
+		s.closeCursor(cursor, nil)
+	}
+}
+
+/* closeCursor ends the snapshot, returns the connection and records the outcome; the caller holds the cursor lock */
+func (s *Service) closeCursor(cursor *resultCursor, cause error) {
+	cursor.closed = true
+	if cursor.idleTimer != nil {
+		cursor.idleTimer.Stop()
+	}
+	s.cursors.remove(cursor.id)
+
+	closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// The snapshot is read-only, so rolling back releases it without side effects
+	cursor.tx.Rollback(closeCtx)
+	cursor.conn.Release()
+
+	s.finishResultQuery(cursor.queryID, cursor.openedAt, cursor.position, cause)
+	s.cursors.releaseSlot(cursor.owner)
+}
+
+/* StreamQuery runs a query and emits its rows as they are read from the database.
+ * Rows are not buffered; emit errors (e.g. a disconnected client) stop the query. */
+func (s *Service) StreamQuery(ctx context.Context, req QueryRequest, emit func(ResultStreamEvent) error) error {
+	limits := s.resultLimitsFor(ctx, req.UserID)
+	owner := ownerKey(req.UserID)
+
+	if err := s.cursors.acquireSlot(owner, limits.MaxOpenCursors); err != nil {
+		return err
+	}
+	defer s.cursors.releaseSlot(owner)
+
+	generatedSQL, validated, req, err := s.prepareResultQuery(ctx, req, limits)
+	if err != nil {
+		return err
+	}
+
+	startedAt := time.Now()
+	queryID, err := s.createQueryRecord(ctx, req, generatedSQL, startedAt)
+	if err != nil {
+		return err
+	}
+
+	var rowCount int64
+	err = s.streamRows(ctx, limits, generatedSQL, func(columns []ResultColumn) error {
+		return emit(ResultStreamEvent{
+			Type:         ResultEventColumns,
+			QueryID:      &queryID,
+			SQL:          generatedSQL,
+			Columns:      columns,
+			RowLimit:     limits.MaxTotalRows,
+			LimitApplied: validated.LimitApplied,
+		})
+	}, func(values []interface{}) error {
+		rowCount++
+		return emit(ResultStreamEvent{Type: ResultEventRow, Values: values})
+	})
+	s.finishResultQuery(queryID, startedAt, rowCount, err)
+	if err != nil {
+		return err
+	}
+
+	return emit(ResultStreamEvent{Type: ResultEventEnd, QueryID: &queryID, RowCount: &rowCount})
+}
+
+/* streamRows executes sql in a read-only snapshot and hands each row to onRow */
+func (s *Service) streamRows(ctx context.Context, limits ResultLimits, sql string, onColumns func([]ResultColumn) error, onRow func([]interface{}) error) error {
+	conn, tx, err := s.beginSnapshot(ctx, limits)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	defer tx.Rollback(context.Background())
+
+	rows, err := tx.Query(ctx, sql)
+	if err != nil {
+		return fmt.Errorf("failed to execute SQL: %w", err)
+	}
+	defer rows.Close()
+
+	if err := onColumns(resultColumns(conn.Conn(), rows.FieldDescriptions())); err != nil {
+		return err
+	}
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return fmt.Errorf("failed to get row values: %w", err)
+		}
+		if err := onRow(values); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to execute SQL: %w", err)
+	}
+	return nil
+}
+
+/* prepareResultQuery converts the question to SQL and validates it with the role's row cap.
+ * Rows are sent as they are read, so only parse, policy and planner failures are corrected; the returned
+ * request tags the query history entry with its attempt. */
+func (s *Service) prepareResultQuery(ctx context.Context, req QueryRequest, limits ResultLimits) (string, *ValidatedSQL, QueryRequest, error) {
+	schemaMetadata, err := s.schemaContext(ctx, req.SchemaID)
+	if err != nil {
+		return "", nil, req, err
+	}
+
+	policy := s.sqlPolicy
+	policy.MaxRows = limits.MaxTotalRows
+	c := s.newSQLCorrection(req, schemaMetadata)
+	validated, _, err := s.prepareSQL(ctx, c, policy)
+	if err != nil {
+		return "", nil, req, err
+	}
+
+	attemptReq := c.request()
+	s.recordAttempt(ctx, c, validated.SQL, AttemptStageSucceeded, nil, attemptReq.queryID)
+	return validated.SQL, validated, attemptReq, nil
+}
+
+/* beginSnapshot starts a read-only repeatable-read transaction on a dedicated connection */
+func (s *Service) beginSnapshot(ctx context.Context, limits ResultLimits) (*pgxpool.Conn, pgx.Tx, error) {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		conn.Release()
+		return nil, nil, fmt.Errorf("failed to begin snapshot: %w", err)
+	}
+
+	// The idle timeout lets the server end the snapshot if this process never closes the cursor
+	idleTimeout := limits.CursorIdleTimeout + time.Minute
+	_, err = tx.Exec(ctx, `SELECT set_config('statement_timeout', $1, true), set_config('idle_in_transaction_session_timeout', $2, true)`,
+		fmt.Sprintf("%dms", limits.StatementTimeout.Milliseconds()), fmt.Sprintf("%dms", idleTimeout.Milliseconds()))
+	if err != nil {
+		tx.Rollback(ctx)
+		conn.Release()
+		return nil, nil, fmt.Errorf("failed to configure snapshot: %w", err)
+	}
+	return conn, tx, nil
+}
+
+/* finishResultQuery records the outcome of a cursor or stream */
+func (s *Service) finishResultQuery(queryID uuid.UUID, startedAt time.Time, rowCount int64, cause error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if cause != nil {
+		s.pool.Exec(ctx, `UPDATE neuronip.warehouse_queries SET status = $1, error_message = $2, executed_at = $3 WHERE id = $4`,
+			"failed", cause.Error(), time.Now(), queryID)
+		return
+	}
+
+	s.pool.Exec(ctx, `UPDATE neuronip.warehouse_queries SET status = $1, executed_at = $2 WHERE id = $3`,
+		"completed", time.Now(), queryID)
+	s.pool.Exec(ctx, `
+		INSERT INTO neuronip.query_results (query_id, row_count, execution_time_ms, created_at)
+		VALUES ($1, $2, $3, $4)`,
+		queryID, rowCount, int(time.Since(startedAt).Milliseconds()), time.Now())
+}
+
+/* resultLimitsFor returns the result limits of the user's role, falling back to the service defaults */
+func (s *Service) resultLimitsFor(ctx context.Context, userID *string) ResultLimits {
+	if userID == nil {
+		return s.resultLimits
+	}
+
+	query := `
+		SELECT l.default_page_size, l.max_page_size, l.max_total_rows, l.max_open_cursors,
+		       l.cursor_idle_seconds, l.statement_timeout_seconds
+		FROM neuronip.users u
+		JOIN neuronip.warehouse_result_limits l ON l.role_name = u.role
+		WHERE u.id::text = $1`
+
+	limits := s.resultLimits
+	var idleSeconds, timeoutSeconds int
+	err := s.pool.QueryRow(ctx, query, *userID).Scan(
+		&limits.DefaultPageSize, &limits.MaxPageSize, &limits.MaxTotalRows, &limits.MaxOpenCursors,
+		&idleSeconds, &timeoutSeconds,
+	)
+	if err != nil {
+		return s.resultLimits
+	}
+	limits.CursorIdleTimeout = time.Duration(idleSeconds) * time.Second
+	limits.StatementTimeout = time.Duration(timeoutSeconds) * time.Second
+	return normalizeResultLimits(limits)
+}
+
+/* normalizeResultLimits replaces unset or invalid limits with defaults */
+func normalizeResultLimits(limits ResultLimits) ResultLimits {
+	defaults := DefaultResultLimits()
+	if limits.MaxPageSize <= 0 {
+		limits.MaxPageSize = defaults.MaxPageSize
+	}
+	if limits.DefaultPageSize <= 0 || limits.DefaultPageSize > limits.MaxPageSize {
+		limits.DefaultPageSize = min(defaults.DefaultPageSize, limits.MaxPageSize)
+	}
+	if limits.MaxTotalRows <= 0 {
+		limits.MaxTotalRows = defaults.MaxTotalRows
+	}
+	if limits.CursorIdleTimeout <= 0 {
+		limits.CursorIdleTimeout = defaults.CursorIdleTimeout
+	}
+	if limits.StatementTimeout <= 0 {
+		limits.StatementTimeout = defaults.StatementTimeout
+	}
+	return limits
+}
+
+/* clampPageSize applies the role's default and maximum page size */
+func clampPageSize(pageSize int, limits ResultLimits) int {
+	if pageSize <= 0 {
+		return limits.DefaultPageSize
+	}
+	if pageSize > limits.MaxPageSize {
+		return limits.MaxPageSize
+	}
+	return pageSize
+}
+
+/* ownerKey identifies the user a cursor belongs to; anonymous callers share one key */
+func ownerKey(userID *string) string {
+	if userID == nil {
+		return ""
+	}
+	return *userID
+}
+
+/* resultColumns describes result columns using the connection's type map */
+func resultColumns(conn *pgx.Conn, fields []pgconn.FieldDescription) []ResultColumn {
+	columns := make([]ResultColumn, len(fields))
+	for i, field := range fields {
+		typeName := "unknown"
+		if dataType, ok := conn.TypeMap().TypeForOID(field.DataTypeOID); ok {
+			typeName = dataType.Name
+		}
+		columns[i] = ResultColumn{Name: field.Name, Type: typeName}
+	}
+	return columns
+}
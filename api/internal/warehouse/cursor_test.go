@@ -0,0 +1,71 @@
+package warehouse
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+/* TestCursorSlots checks the per-user cursor limit and the cap across users */
+func TestCursorSlots(t *testing.T) {
+	m := newResultCursors(3)
+
+	if err := m.acquireSlot("alice", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.acquireSlot("alice", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.acquireSlot("alice", 2); AsCursorError(err) == nil {
+		t.Fatalf("expected alice's third cursor to be rejected, got %v", err)
+	}
+
+	if err := m.acquireSlot("bob", 2); err != nil {
+		t.Fatal(err)
+	}
+	// bob is under the per-user limit, but the server has no cursors left
+	if err := m.acquireSlot("bob", 2); AsCursorError(err) == nil {
+		t.Fatalf("expected the cap across users to reject bob's cursor, got %v", err)
+	}
+
+	m.releaseSlot("alice")
+	if err := m.acquireSlot("bob", 2); err != nil {
+		t.Fatalf("expected a released slot to be reusable, got %v", err)
+	}
+}
+
+/* TestPageTokens checks that tokens round-trip, reject tampering and name the replica problem when sent to another process */
+func TestPageTokens(t *testing.T) {
+	m := newResultCursors(3)
+	id := uuid.New()
+	token := m.encodeToken(id, 2000)
+
+	gotID, position, err := m.decodeToken(token)
+	if err != nil || gotID != id || position != 2000 {
+		t.Fatalf("expected cursor %s at 2000, got %s at %d (%v)", id, gotID, position, err)
+	}
+
+	raw, _ := base64.RawURLEncoding.DecodeString(token)
+	raw[len(raw)-20]++ // Move the position
+	tampered := base64.RawURLEncoding.EncodeToString(raw)
+
+	tests := []struct {
+		name  string
+		token string
+		code  string
+	}{
+		{name: "not base64", token: "!!", code: CursorErrorInvalidToken},
+		{name: "truncated", token: token[:20], code: CursorErrorInvalidToken},
+		{name: "tampered position", token: tampered, code: CursorErrorInvalidToken},
+		{name: "other replica", token: newResultCursors(3).encodeToken(id, 2000), code: CursorErrorOtherReplica},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := m.decodeToken(tt.token)
+			if cursorErr := AsCursorError(err); cursorErr == nil || cursorErr.Code != tt.code {
+				t.Fatalf("expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+}
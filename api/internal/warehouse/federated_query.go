@@ -0,0 +1,85 @@
+package warehouse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/neurondb/NeuronIP/api/internal/warehouse/federation"
+)
+
+/* PlanFederatedQuery explains how a federated query would be split across connectors */
+func (s *Service) PlanFederatedQuery(ctx context.Context, sql string) (*federation.Plan, error) {
+	if s.federation == nil {
+		return nil, fmt.Errorf("federated queries are not configured")
+	}
+	validated, err := ValidateSQL(sql, s.sqlPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SQL: %w", err)
+	}
+	return s.federation.Plan(ctx, validated.SQL)
+}
+
+/* ExecuteFederatedQuery runs SQL over tables named connector.schema.table and records it in query history.
+ * The query passes the same SQL policy as other warehouse queries, including the row cap. */
+func (s *Service) ExecuteFederatedQuery(ctx context.Context, sql string, userID *string) (*QueryResponse, error) {
+	if s.federation == nil {
+		return nil, fmt.Errorf("federated queries are not configured")
+	}
+	validated, err := ValidateSQL(sql, s.sqlPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SQL: %w", err)
+	}
+
+	now := time.Now()
+	req := QueryRequest{Query: sql, UserID: userID}
+	queryID, err := s.createQueryRecord(ctx, req, validated.SQL, now)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.federation.Execute(ctx, validated.SQL)
+	if err != nil {
+		s.pool.Exec(ctx, `UPDATE neuronip.warehouse_queries SET status = $1, error_message = $2, executed_at = $3 WHERE id = $4`,
+			"failed", err.Error(), time.Now(), queryID)
+		return nil, err
+	}
+
+	results := make([]map[string]interface{}, 0, len(result.Rows))
+	for _, values := range result.Rows {
+		row := make(map[string]interface{}, len(result.Columns))
+		for i, column := range result.Columns {
+			row[column] = values[i]
+		}
+		results = append(results, row)
+	}
+
+	s.pool.Exec(ctx, `UPDATE neuronip.warehouse_queries SET status = $1, executed_at = $2 WHERE id = $3`,
+		"completed", time.Now(), queryID)
+
+	resultData, _ := json.Marshal(results)
+	s.pool.Exec(ctx, `
+		INSERT INTO neuronip.query_results
+		(query_id, result_data, row_count, execution_time_ms, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		queryID, resultData, len(results), int(time.Since(now).Milliseconds()), time.Now())
+
+	charts := s.recommendCharts(ctx, validated.SQL, results)
+	chartConfig, chartType := topChart(charts)
+	return &QueryResponse{
+		QueryID:     queryID,
+		SQL:         validated.SQL,
+		Results:     results,
+		Explanation: s.generateResultExplanation(results),
+		ChartConfig: chartConfig,
+		ChartType:   chartType,
+		Charts:      charts,
+		Metadata: map[string]interface{}{
+			"federation": map[string]interface{}{
+				"plan":  result.Plan,
+				"stats": result.Stats,
+			},
+		},
+	}, nil
+}
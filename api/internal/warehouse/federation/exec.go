@@ -0,0 +1,1151 @@
+package federation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neurondb/NeuronIP/api/internal/connectors"
+)
+
+/* Config bounds the resources a federated query may use */
+type Config struct {
+	MemoryLimitBytes int64         // Rows held in memory by joins and aggregations before spilling
+	SpillDir         string        // Parent directory for spill files; empty uses the system temp directory
+	SpillPartitions  int           // Hash partitions for spilled joins and aggregations
+	MaxResultRows    int           // Maximum rows returned
+	QueryTimeout     time.Duration // Maximum duration of a federated query
+}
+
+/* DefaultConfig returns the default federation limits */
+func DefaultConfig() Config {
+	return Config{
+		MemoryLimitBytes: 256 << 20,
+		SpillPartitions:  16,
+		MaxResultRows:    100000,
+		QueryTimeout:     5 * time.Minute,
+	}
+}
+
+/* Service runs SQL that joins tables across data source connectors */
+type Service struct {
+	connectors *connectors.ConnectorService
+	config     Config
+
+	mu  sync.Mutex
+	dbs map[uuid.UUID]*connectorDB
+}
+
+/* connectorDB is an open connection to a connector, reopened when the connector changes */
+type connectorDB struct {
+	db        *sql.DB
+	updatedAt time.Time
+}
+
+/* NewService creates a federated query service over the configured connectors */
+func NewService(connectorService *connectors.ConnectorService, config Config) *Service {
+	defaults := DefaultConfig()
+	if config.MemoryLimitBytes <= 0 {
+		config.MemoryLimitBytes = defaults.MemoryLimitBytes
+	}
+	if config.SpillPartitions <= 0 {
+		config.SpillPartitions = defaults.SpillPartitions
+	}
+	if config.MaxResultRows <= 0 {
+		config.MaxResultRows = defaults.MaxResultRows
+	}
+	if config.QueryTimeout <= 0 {
+		config.QueryTimeout = defaults.QueryTimeout
+	}
+	return &Service{
+		connectors: connectorService,
+		config:     config,
+		dbs:        make(map[uuid.UUID]*connectorDB),
+	}
+}
+
+/* Result is the result of a federated query */
+type Result struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+	Plan    *Plan           `json:"plan"`
+	Stats   Stats           `json:"stats"`
+}
+
+/* Stats describes the work done by a federated query */
+type Stats struct {
+	RowsScanned       map[string]int64 `json:"rows_scanned"` // By table alias
+	PeakMemoryBytes   int64            `json:"peak_memory_bytes"`
+	SpilledPartitions int              `json:"spilled_partitions"`
+	SpillBytes        int64            `json:"spill_bytes"`
+	DurationMs        int64            `json:"duration_ms"`
+}
+
+/* AsPlanError returns the planning error in err's chain, if any */
+func AsPlanError(err error) *PlanError {
+	var planErr *PlanError
+	if errors.As(err, &planErr) {
+		return planErr
+	}
+	return nil
+}
+
+/* Plan explains how a federated query would run without running it */
+func (s *Service) Plan(ctx context.Context, query string) (*Plan, error) {
+	return buildPlan(query, func(name string) (*connectors.DataSourceConnector, error) {
+		return s.connectors.GetConnectorByName(ctx, name)
+	})
+}
+
+/* Execute runs a federated query.
+ * Each connector receives a subquery with the filters and columns it can answer; joins, cross-connector
+ * filters, aggregation, ordering and limits run in the API within the memory limit, spilling hash
+ * partitions to disk when it is reached. */
+func (s *Service) Execute(ctx context.Context, query string) (*Result, error) {
+	start := time.Now()
+	plan, err := s.Plan(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.QueryTimeout)
+	defer cancel()
+
+	stats := Stats{RowsScanned: make(map[string]int64)}
+	var result *Result
+	if plan.PushedDown {
+		result, err = s.executePushedDown(ctx, plan, &stats)
+	} else {
+		result, err = s.executeFederated(ctx, plan, &stats)
+	}
+	if err != nil {
+		return nil, err
+	}
+	stats.DurationMs = time.Since(start).Milliseconds()
+	result.Plan = plan
+	result.Stats = stats
+	return result, nil
+}
+
+/* executePushedDown runs a query whose tables all belong to one connector on that connector */
+func (s *Service) executePushedDown(ctx context.Context, plan *Plan, stats *Stats) (*Result, error) {
+	t := plan.tables[0]
+	rows, err := s.remoteQuery(ctx, t.connector, plan.Subqueries[0].SQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	result := &Result{Columns: columns, Rows: [][]interface{}{}}
+	err = readRows(rows.Rows, len(columns), func(row []interface{}) error {
+		if len(result.Rows) >= s.config.MaxResultRows {
+			return fmt.Errorf("result exceeds %d rows", s.config.MaxResultRows)
+		}
+		result.Rows = append(result.Rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats.RowsScanned[t.connector.Name] = int64(len(result.Rows))
+	plan.Columns = columns
+	return result, nil
+}
+
+/* remoteRows is a connector result set and the read-only transaction it is read in, if any */
+type remoteRows struct {
+	*sql.Rows
+	tx *sql.Tx
+}
+
+/* Close closes the result set and ends its transaction */
+func (r *remoteRows) Close() error {
+	err := r.Rows.Close()
+	if r.tx != nil {
+		r.tx.Rollback()
+	}
+	return err
+}
+
+/* remoteQuery runs a statement on a connector inside a read-only transaction where the driver supports one.
+ * The planner's function deny list stays in force as a second line of defence, and the only one for other drivers. */
+func (s *Service) remoteQuery(ctx context.Context, connector *connectors.DataSourceConnector, query string) (*remoteRows, error) {
+	db, err := s.db(ctx, connector)
+	if err != nil {
+		return nil, err
+	}
+	dialect, err := connectors.GetQueryDialect(connector.ConnectorType)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dialect.ReadOnlyTx {
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query connector %s: %w", connector.Name, err)
+		}
+		return &remoteRows{Rows: rows}, nil
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin read-only transaction on connector %s: %w", connector.Name, err)
+	}
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to query connector %s: %w", connector.Name, err)
+	}
+	return &remoteRows{Rows: rows, tx: tx}, nil
+}
+
+/* db returns an open connection to a connector */
+func (s *Service) db(ctx context.Context, connector *connectors.DataSourceConnector) (*sql.DB, error) {
+	s.mu.Lock()
+	cached, ok := s.dbs[connector.ID]
+	s.mu.Unlock()
+	if ok && cached.updatedAt.Equal(connector.UpdatedAt) {
+		return cached.db, nil
+	}
+
+	db, err := connectors.OpenQueryDB(ctx, connector)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.dbs[connector.ID]; ok {
+		if current.updatedAt.Equal(connector.UpdatedAt) {
+			db.Close()
+			return current.db, nil
+		}
+		current.db.Close()
+	}
+	s.dbs[connector.ID] = &connectorDB{db: db, updatedAt: connector.UpdatedAt}
+	return db, nil
+}
+
+/* Close closes the connector connections */
+func (s *Service) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, cached := range s.dbs {
+		cached.db.Close()
+		delete(s.dbs, id)
+	}
+}
+
+/* errLimitReached stops reading rows once LIMIT rows have been produced */
+var errLimitReached = errors.New("limit reached")
+
+/* rowIterator produces rows until fn returns an error */
+type rowIterator func(fn func(row []interface{}) error) error
+
+/* executor runs one federated query */
+type executor struct {
+	svc     *Service
+	plan    *Plan
+	budget  *memoryBudget
+	dir     *spillDir
+	stats   *Stats
+	columns [][]string // Columns returned by each table's subquery
+	offsets []int      // Position of each table's first column in a joined row
+	stores  []*partitionedStore
+}
+
+func (s *Service) executeFederated(ctx context.Context, plan *Plan, stats *Stats) (*Result, error) {
+	dir, err := newSpillDir(s.config.SpillDir)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.remove()
+
+	e := &executor{
+		svc:     s,
+		plan:    plan,
+		budget:  &memoryBudget{limit: s.config.MemoryLimitBytes},
+		dir:     dir,
+		stats:   stats,
+		columns: make([][]string, len(plan.tables)),
+	}
+	defer func() {
+		stats.PeakMemoryBytes = e.budget.peak
+		stats.SpillBytes = dir.bytes
+		for _, store := range e.stores {
+			stats.SpilledPartitions += store.spilledPartitions()
+		}
+	}()
+
+	// Build sides are read first so that the first table can be streamed through every join
+	builds := make([]*partitionedStore, len(plan.joins))
+	for i, step := range plan.joins {
+		build, err := e.loadBuildSide(ctx, step)
+		if err != nil {
+			return nil, err
+		}
+		builds[i] = build
+	}
+
+	first, err := e.openScan(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer first.rows.Close()
+
+	e.offsets = make([]int, len(plan.tables))
+	for i := 1; i < len(plan.tables); i++ {
+		e.offsets[i] = e.offsets[i-1] + len(e.columns[i-1])
+	}
+
+	input := first.iterate
+	for i, step := range plan.joins {
+		input, err = e.join(input, step, builds[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(plan.residual) > 0 {
+		input, err = e.filter(input)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	columns, err := e.outputColumns()
+	if err != nil {
+		return nil, err
+	}
+	sink := &resultSink{orderBy: plan.orderBy, limit: -1, maxRows: s.config.MaxResultRows}
+	if plan.Limit != nil {
+		sink.limit = *plan.Limit
+	}
+
+	if len(plan.groupBy) > 0 || plan.hasAggregates() {
+		agg, err := e.newAggregator()
+		if err != nil {
+			return nil, err
+		}
+		if err := input(agg.add); err != nil {
+			return nil, err
+		}
+		if err := agg.finish(sink.add); err != nil && err != errLimitReached {
+			return nil, err
+		}
+	} else {
+		project, err := e.projection()
+		if err != nil {
+			return nil, err
+		}
+		err = input(func(row []interface{}) error {
+			out := make([]interface{}, len(project))
+			for i, idx := range project {
+				out[i] = row[idx]
+			}
+			return sink.add(out)
+		})
+		if err != nil && err != errLimitReached {
+			return nil, err
+		}
+	}
+
+	return &Result{Columns: columns, Rows: sink.finish()}, nil
+}
+
+func (p *Plan) hasAggregates() bool {
+	for _, out := range p.outputs {
+		if out.aggregate != "" {
+			return true
+		}
+	}
+	return false
+}
+
+/* tableScan reads the rows of one table's subquery */
+type tableScan struct {
+	e     *executor
+	table int
+	rows  *remoteRows
+}
+
+func (e *executor) openScan(ctx context.Context, table int) (*tableScan, error) {
+	t := e.plan.tables[table]
+	rows, err := e.svc.remoteQuery(ctx, t.connector, t.subquerySQL())
+	if err != nil {
+		return nil, err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	e.columns[table] = columns
+	return &tableScan{e: e, table: table, rows: rows}, nil
+}
+
+func (s *tableScan) iterate(fn func(row []interface{}) error) error {
+	alias := s.e.plan.tables[s.table].alias
+	return readRows(s.rows.Rows, len(s.e.columns[s.table]), func(row []interface{}) error {
+		s.e.stats.RowsScanned[alias]++
+		return fn(row)
+	})
+}
+
+/* readRows scans and normalizes each row of a result set */
+func readRows(rows *sql.Rows, width int, fn func(row []interface{}) error) error {
+	for rows.Next() {
+		values := make([]interface{}, width)
+		pointers := make([]interface{}, width)
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		for i, v := range values {
+			values[i] = normalizeValue(v)
+		}
+		if err := fn(values); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rows: %w", err)
+	}
+	return nil
+}
+
+/* loadBuildSide reads a joined table into a hash-partitioned store keyed by its join columns */
+func (e *executor) loadBuildSide(ctx context.Context, step joinStep) (*partitionedStore, error) {
+	scan, err := e.openScan(ctx, step.table)
+	if err != nil {
+		return nil, err
+	}
+	defer scan.rows.Close()
+
+	keys := make([]int, len(step.buildKeys))
+	for i, c := range step.buildKeys {
+		idx, err := columnIndex(e.columns[step.table], c)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = idx
+	}
+
+	store := newPartitionedStore(e.budget, e.dir, e.svc.config.SpillPartitions)
+	e.stores = append(e.stores, store)
+	err = scan.iterate(func(row []interface{}) error {
+		// NULL keys never match
+		key, ok := joinKey(row, keys)
+		if !ok {
+			return nil
+		}
+		return store.add(key, row)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return store, store.finish()
+}
+
+/* join hash-joins the input with a build side.
+ * Input rows whose partition of the build side was spilled are spilled too and joined partition by
+ * partition once the input is exhausted. */
+func (e *executor) join(input rowIterator, step joinStep, build *partitionedStore) (rowIterator, error) {
+	probe := make([]int, len(step.probeKeys))
+	for i, c := range step.probeKeys {
+		idx, err := e.index(c)
+		if err != nil {
+			return nil, err
+		}
+		probe[i] = idx
+	}
+	buildWidth := len(e.columns[step.table])
+	partitions := len(build.partitions)
+
+	return func(fn func(row []interface{}) error) error {
+		emit := func(row []interface{}, matches [][]interface{}) error {
+			if len(matches) == 0 {
+				if step.kind != JoinLeft {
+					return nil
+				}
+				return fn(append(append(make([]interface{}, 0, len(row)+buildWidth), row...), make([]interface{}, buildWidth)...))
+			}
+			for _, match := range matches {
+				if err := fn(append(append(make([]interface{}, 0, len(row)+len(match)), row...), match...)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		table := make(map[string][][]interface{})
+		for p := 0; p < partitions; p++ {
+			if !build.spilled(p) {
+				build.each(p, func(key string, row []interface{}) error {
+					table[key] = append(table[key], row)
+					return nil
+				})
+			}
+		}
+
+		deferred := make([]*spillFile, partitions)
+		err := input(func(row []interface{}) error {
+			key, ok := joinKey(row, probe)
+			if !ok {
+				return emit(row, nil)
+			}
+			p := partitionOf(key, partitions)
+			if !build.spilled(p) {
+				return emit(row, table[key])
+			}
+			if deferred[p] == nil {
+				file, err := e.dir.create()
+				if err != nil {
+					return err
+				}
+				deferred[p] = file
+			}
+			return deferred[p].write(append([]interface{}{key}, row...))
+		})
+		if err != nil {
+			return err
+		}
+		table = nil
+		build.release()
+
+		for p, file := range deferred {
+			if file == nil {
+				continue
+			}
+			if err := file.finish(); err != nil {
+				return err
+			}
+			if err := e.joinPartition(build, p, file, emit); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+/* joinPartition joins a spilled partition of the input with the same partition of the build side */
+func (e *executor) joinPartition(build *partitionedStore, p int, probe *spillFile, emit func(row []interface{}, matches [][]interface{}) error) error {
+	var reserved int64
+	defer func() { e.budget.release(reserved) }()
+
+	table := make(map[string][][]interface{})
+	err := build.each(p, func(key string, row []interface{}) error {
+		size := rowSize(row)
+		if !e.budget.reserve(size) {
+			return fmt.Errorf("join partition exceeds the federation memory limit; increase the memory limit or spill partitions")
+		}
+		reserved += size
+		table[key] = append(table[key], row)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return probe.each(func(row []interface{}) error {
+		key, _ := row[0].(string)
+		return emit(row[1:], table[key])
+	})
+}
+
+/* filter applies comparisons between columns of different connectors */
+func (e *executor) filter(input rowIterator) (rowIterator, error) {
+	type check struct {
+		left, right int
+		op          string
+	}
+	var checks []check
+	for _, cmp := range e.plan.residual {
+		left, err := e.index(cmp.left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := e.index(cmp.right)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check{left: left, right: right, op: cmp.op})
+	}
+
+	return func(fn func(row []interface{}) error) error {
+		return input(func(row []interface{}) error {
+			for _, c := range checks {
+				if !compareOp(row[c.left], c.op, row[c.right]) {
+					return nil
+				}
+			}
+			return fn(row)
+		})
+	}, nil
+}
+
+/* index resolves a column to its position in a joined row */
+func (e *executor) index(c colRef) (int, error) {
+	for i, t := range e.plan.tables {
+		if t.alias == c.alias {
+			idx, err := columnIndex(e.columns[i], c)
+			if err != nil {
+				return 0, err
+			}
+			return e.offsets[i] + idx, nil
+		}
+	}
+	return 0, planErrorf("unknown table alias %s", c.alias)
+}
+
+/* columnIndex finds a column in a subquery result, ignoring case when the connector changed it */
+func columnIndex(columns []string, c colRef) (int, error) {
+	for i, name := range columns {
+		if name == c.column {
+			return i, nil
+		}
+	}
+	for i, name := range columns {
+		if strings.EqualFold(name, c.column) {
+			return i, nil
+		}
+	}
+	return 0, planErrorf("column %s does not exist", c)
+}
+
+/* outputColumns names the result columns, expanding * with the columns each connector returned */
+func (e *executor) outputColumns() ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, out := range e.plan.outputs {
+		if out.star == "" {
+			names = append(names, out.name)
+			seen[out.name] = true
+			continue
+		}
+		for i, t := range e.plan.tables {
+			if out.star != "*" && out.star != t.alias {
+				continue
+			}
+			for _, column := range e.columns[i] {
+				name := column
+				if seen[name] {
+					name = t.alias + "." + column
+				}
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	e.plan.Columns = names
+	return names, nil
+}
+
+/* projection resolves the positions of the output columns in a joined row */
+func (e *executor) projection() ([]int, error) {
+	var project []int
+	for _, out := range e.plan.outputs {
+		if out.star == "" {
+			idx, err := e.index(*out.column)
+			if err != nil {
+				return nil, err
+			}
+			project = append(project, idx)
+			continue
+		}
+		for i, t := range e.plan.tables {
+			if out.star != "*" && out.star != t.alias {
+				continue
+			}
+			for j := range e.columns[i] {
+				project = append(project, e.offsets[i]+j)
+			}
+		}
+	}
+	return project, nil
+}
+
+/* aggregator is a hash aggregation; once the memory limit is reached, rows of new groups are
+ * partitioned to disk and aggregated one partition at a time */
+type aggregator struct {
+	e        *executor
+	groupBy  []int
+	outputs  []aggregateOutput
+	groups   map[string]*aggregateGroup
+	reserved int64
+	overflow *partitionedStore
+}
+
+/* aggregateOutput is a GROUP BY column or an aggregate in the select list */
+type aggregateOutput struct {
+	group     int // Index into the group key for GROUP BY columns, -1 for aggregates
+	aggregate string
+	arg       int // Argument position in the joined row, -1 for count(*)
+}
+
+type aggregateGroup struct {
+	keys   []interface{}
+	states []aggregateState
+}
+
+type aggregateState struct {
+	count    int64
+	intSum   int64
+	floatSum float64
+	isFloat  bool
+	value    interface{}
+	distinct map[string]struct{}
+}
+
+func (e *executor) newAggregator() (*aggregator, error) {
+	a := &aggregator{e: e, groups: make(map[string]*aggregateGroup)}
+	for _, c := range e.plan.groupBy {
+		idx, err := e.index(c)
+		if err != nil {
+			return nil, err
+		}
+		a.groupBy = append(a.groupBy, idx)
+	}
+	for _, out := range e.plan.outputs {
+		if out.aggregate == "" {
+			group := -1
+			for i, c := range e.plan.groupBy {
+				if c == *out.column {
+					group = i
+				}
+			}
+			a.outputs = append(a.outputs, aggregateOutput{group: group})
+			continue
+		}
+		arg := -1
+		if out.arg != nil {
+			idx, err := e.index(*out.arg)
+			if err != nil {
+				return nil, err
+			}
+			arg = idx
+		}
+		a.outputs = append(a.outputs, aggregateOutput{group: -1, aggregate: out.aggregate, arg: arg})
+	}
+	return a, nil
+}
+
+func (a *aggregator) add(row []interface{}) error {
+	key := groupKey(row, a.groupBy)
+	group, ok := a.groups[key]
+	if !ok {
+		if a.overflow != nil {
+			return a.overflow.add(key, row)
+		}
+		keys := make([]interface{}, len(a.groupBy))
+		for i, idx := range a.groupBy {
+			keys[i] = row[idx]
+		}
+		size := rowSize(keys) + int64(len(key)) + int64(64*len(a.outputs))
+		if !a.e.budget.reserve(size) {
+			a.overflow = newPartitionedStore(a.e.budget, a.e.dir, a.e.svc.config.SpillPartitions)
+			a.e.stores = append(a.e.stores, a.overflow)
+			return a.overflow.add(key, row)
+		}
+		a.reserved += size
+		group = &aggregateGroup{keys: keys, states: make([]aggregateState, len(a.outputs))}
+		a.groups[key] = group
+	}
+	return a.accumulate(group, row)
+}
+
+func (a *aggregator) accumulate(group *aggregateGroup, row []interface{}) error {
+	for i, out := range a.outputs {
+		if out.aggregate == "" {
+			continue
+		}
+		state := &group.states[i]
+		if out.arg < 0 {
+			state.count++
+			continue
+		}
+		v := row[out.arg]
+		if v == nil {
+			continue
+		}
+		switch out.aggregate {
+		case AggregateCount:
+			state.count++
+		case AggregateCountDistinct:
+			key, _ := canonicalValue(v)
+			if state.distinct == nil {
+				state.distinct = make(map[string]struct{})
+			}
+			if _, seen := state.distinct[key]; !seen {
+				if !a.e.budget.reserve(int64(len(key) + 16)) {
+					return fmt.Errorf("count(DISTINCT) exceeds the federation memory limit")
+				}
+				a.reserved += int64(len(key) + 16)
+				state.distinct[key] = struct{}{}
+			}
+		case AggregateSum, AggregateAvg:
+			switch n := v.(type) {
+			case int64:
+				state.intSum += n
+			default:
+				f, ok := toFloat(v)
+				if !ok {
+					return planErrorf("%s requires numeric values", out.aggregate)
+				}
+				state.floatSum += f
+				state.isFloat = true
+			}
+			state.count++
+		case AggregateMin:
+			if state.value == nil || compareValues(v, state.value) < 0 {
+				state.value = v
+			}
+		case AggregateMax:
+			if state.value == nil || compareValues(v, state.value) > 0 {
+				state.value = v
+			}
+		}
+	}
+	return nil
+}
+
+/* finish emits the groups held in memory, then aggregates each spilled partition */
+func (a *aggregator) finish(fn func(row []interface{}) error) error {
+	if len(a.groups) == 0 && a.overflow == nil && len(a.groupBy) == 0 {
+		// Aggregates without GROUP BY return one row even without input
+		a.groups[""] = &aggregateGroup{states: make([]aggregateState, len(a.outputs))}
+	}
+	if err := a.emit(fn); err != nil {
+		return err
+	}
+	if a.overflow == nil {
+		return nil
+	}
+
+	overflow := a.overflow
+	if err := overflow.finish(); err != nil {
+		return err
+	}
+	for p := range overflow.partitions {
+		partition := &aggregator{e: a.e, groupBy: a.groupBy, outputs: a.outputs, groups: make(map[string]*aggregateGroup)}
+		err := overflow.each(p, func(key string, row []interface{}) error {
+			group, ok := partition.groups[key]
+			if !ok {
+				keys := make([]interface{}, len(a.groupBy))
+				for i, idx := range a.groupBy {
+					keys[i] = row[idx]
+				}
+				size := rowSize(keys) + int64(len(key)) + int64(64*len(a.outputs))
+				if !a.e.budget.reserve(size) {
+					return fmt.Errorf("aggregation partition exceeds the federation memory limit; increase the memory limit or spill partitions")
+				}
+				partition.reserved += size
+				group = &aggregateGroup{keys: keys, states: make([]aggregateState, len(a.outputs))}
+				partition.groups[key] = group
+			}
+			return partition.accumulate(group, row)
+		})
+		if err != nil {
+			partition.release()
+			return err
+		}
+		if err := partition.emit(fn); err != nil {
+			return err
+		}
+	}
+	overflow.release()
+	return nil
+}
+
+/* emit produces the output row of each group and frees the groups */
+func (a *aggregator) emit(fn func(row []interface{}) error) error {
+	defer a.release()
+	for _, group := range a.groups {
+		row := make([]interface{}, len(a.outputs))
+		for i, out := range a.outputs {
+			if out.aggregate == "" {
+				row[i] = group.keys[out.group]
+				continue
+			}
+			row[i] = group.states[i].result(out.aggregate)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *aggregator) release() {
+	a.e.budget.release(a.reserved)
+	a.reserved = 0
+	a.groups = make(map[string]*aggregateGroup)
+}
+
+func (s *aggregateState) result(aggregate string) interface{} {
+	switch aggregate {
+	case AggregateCount:
+		return s.count
+	case AggregateCountDistinct:
+		return int64(len(s.distinct))
+	case AggregateSum:
+		if s.count == 0 {
+			return nil
+		}
+		if s.isFloat {
+			return s.floatSum + float64(s.intSum)
+		}
+		return s.intSum
+	case AggregateAvg:
+		if s.count == 0 {
+			return nil
+		}
+		return (s.floatSum + float64(s.intSum)) / float64(s.count)
+	}
+	return s.value
+}
+
+/* resultSink collects output rows, keeping only the first LIMIT rows in ORDER BY order */
+type resultSink struct {
+	orderBy []orderItem
+	limit   int64
+	maxRows int
+	rows    [][]interface{}
+}
+
+func (s *resultSink) add(row []interface{}) error {
+	if s.limit == 0 {
+		return errLimitReached
+	}
+	s.rows = append(s.rows, row)
+	bounded := s.limit > 0 && s.limit <= int64(s.maxRows)
+	if len(s.orderBy) == 0 {
+		if s.limit > 0 && int64(len(s.rows)) >= s.limit {
+			return errLimitReached
+		}
+	} else if bounded && int64(len(s.rows)) >= 2*s.limit {
+		s.sort()
+		s.rows = s.rows[:s.limit]
+	}
+	if !bounded && len(s.rows) > s.maxRows {
+		return fmt.Errorf("result exceeds %d rows; add a LIMIT", s.maxRows)
+	}
+	return nil
+}
+
+func (s *resultSink) finish() [][]interface{} {
+	if len(s.orderBy) > 0 {
+		s.sort()
+	}
+	if s.limit >= 0 && int64(len(s.rows)) > s.limit {
+		s.rows = s.rows[:s.limit]
+	}
+	if s.rows == nil {
+		return [][]interface{}{}
+	}
+	return s.rows
+}
+
+/* sort orders rows like PostgreSQL: NULLs last ascending and first descending */
+func (s *resultSink) sort() {
+	sort.SliceStable(s.rows, func(i, j int) bool {
+		for _, item := range s.orderBy {
+			c := compareValues(s.rows[i][item.output], s.rows[j][item.output])
+			if c == 0 {
+				continue
+			}
+			if item.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+/* normalizeValue converts driver values to int64, float64, string, bool, time.Time or nil */
+func normalizeValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case nil, int64, float64, string, bool, time.Time:
+		return value
+	case []byte:
+		return string(value)
+	case int:
+		return int64(value)
+	case int32:
+		return int64(value)
+	case int16:
+		return int64(value)
+	case int8:
+		return int64(value)
+	case uint64:
+		if value > math.MaxInt64 {
+			return float64(value)
+		}
+		return int64(value)
+	case uint32:
+		return int64(value)
+	case uint16:
+		return int64(value)
+	case uint8:
+		return int64(value)
+	case float32:
+		return float64(value)
+	}
+	return fmt.Sprint(v)
+}
+
+/* canonicalValue encodes a value so that equal values from different databases get equal keys.
+ * Integers compare equal whether a driver returned them as numbers or as text. */
+func canonicalValue(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case nil:
+		return "", false
+	case int64:
+		return "i" + strconv.FormatInt(value, 10), true
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) < 1e18 {
+			return "i" + strconv.FormatInt(int64(value), 10), true
+		}
+		return "f" + strconv.FormatFloat(value, 'g', -1, 64), true
+	case string:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(n, 10) == value {
+			return "i" + value, true
+		}
+		return "s" + value, true
+	case bool:
+		if value {
+			return "b1", true
+		}
+		return "b0", true
+	case time.Time:
+		return "t" + value.UTC().Format(time.RFC3339Nano), true
+	}
+	return "s" + fmt.Sprint(v), true
+}
+
+/* joinKey builds the hash key of a row's join columns; rows with a NULL key column have none */
+func joinKey(row []interface{}, columns []int) (string, bool) {
+	var b strings.Builder
+	for _, idx := range columns {
+		part, ok := canonicalValue(row[idx])
+		if !ok {
+			return "", false
+		}
+		b.WriteString(strconv.Itoa(len(part)))
+		b.WriteByte(':')
+		b.WriteString(part)
+	}
+	return b.String(), true
+}
+
+/* groupKey builds the hash key of a row's GROUP BY columns; NULLs form their own group */
+func groupKey(row []interface{}, columns []int) string {
+	var b strings.Builder
+	for _, idx := range columns {
+		part, ok := canonicalValue(row[idx])
+		if !ok {
+			b.WriteString("n;")
+			continue
+		}
+		b.WriteString(strconv.Itoa(len(part)))
+		b.WriteByte(':')
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case int64:
+		return float64(value), true
+	case float64:
+		return value, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+/* compareValues orders two values, comparing numbers numerically even when a driver returned text.
+ * NULL sorts after every other value. */
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	_, aText := a.(string)
+	_, bText := b.(string)
+	if !aText || !bText {
+		if x, ok := toFloat(a); ok {
+			if y, ok := toFloat(b); ok {
+				switch {
+				case x < y:
+					return -1
+				case x > y:
+					return 1
+				}
+				return 0
+			}
+		}
+	}
+	if x, ok := a.(time.Time); ok {
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y)
+		}
+	}
+	if x, ok := a.(bool); ok {
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0
+			case !x:
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+/* compareOp evaluates a comparison; comparisons with NULL are false */
+func compareOp(a interface{}, op string, b interface{}) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	c := compareValues(a, b)
+	switch op {
+	case "=":
+		return c == 0
+	case "<>":
+		return c != 0
+	case "<":
+		return c < 0
+	case ">":
+		return c > 0
+	case "<=":
+		return c <= 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
@@ -0,0 +1,981 @@
+package federation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/neurondb/NeuronIP/api/internal/connectors"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+)
+
+/* Join kinds */
+const (
+	JoinInner = "inner"
+	JoinLeft  = "left"
+)
+
+/* Aggregate functions evaluated in the API */
+const (
+	AggregateCount         = "count"
+	AggregateCountDistinct = "count_distinct"
+	AggregateSum           = "sum"
+	AggregateAvg           = "avg"
+	AggregateMin           = "min"
+	AggregateMax           = "max"
+)
+
+/* Plan is how a federated query is split into per-connector subqueries and combined in the API */
+type Plan struct {
+	Subqueries      []Subquery `json:"subqueries"`
+	Joins           []JoinStep `json:"joins,omitempty"`
+	ResidualFilters []string   `json:"residual_filters,omitempty"` // Cross-connector comparisons applied after joining
+	GroupBy         []string   `json:"group_by,omitempty"`
+	Aggregates      []string   `json:"aggregates,omitempty"`
+	OrderBy         []string   `json:"order_by,omitempty"`
+	Limit           *int64     `json:"limit,omitempty"`
+	Columns         []string   `json:"columns"`
+	PushedDown      bool       `json:"pushed_down"` // The whole query runs on a single connector
+
+	tables   []*tableRef
+	joins    []joinStep
+	residual []comparison
+	outputs  []output
+	groupBy  []colRef
+	orderBy  []orderItem
+}
+
+/* Subquery is the part of a federated query sent to one connector */
+type Subquery struct {
+	Alias         string   `json:"alias"`
+	Connector     string   `json:"connector"`
+	ConnectorType string   `json:"connector_type"`
+	Table         string   `json:"table"`
+	Columns       []string `json:"columns"` // Projection pushed down; empty selects all columns
+	Filters       []string `json:"filters,omitempty"`
+	SQL           string   `json:"sql"`
+}
+
+/* JoinStep joins one more connector table into the result with a hash join */
+type JoinStep struct {
+	Alias string   `json:"alias"`
+	Type  string   `json:"type"`
+	Keys  []string `json:"keys"`
+}
+
+/* PlanError reports a query that cannot be federated */
+type PlanError struct {
+	Message string
+}
+
+func (e *PlanError) Error() string {
+	return e.Message
+}
+
+func planErrorf(format string, args ...interface{}) error {
+	return &PlanError{Message: fmt.Sprintf(format, args...)}
+}
+
+/* tableRef is a connector table referenced by the query */
+type tableRef struct {
+	alias     string
+	connector *connectors.DataSourceConnector
+	dialect   connectors.QueryDialect
+	schema    string
+	table     string
+	columns   []string // Requested columns in projection order; nil with star
+	star      bool
+	filters   []string
+	nullable  bool // Right side of a LEFT JOIN
+}
+
+func (t *tableRef) addColumn(name string) {
+	for _, c := range t.columns {
+		if c == name {
+			return
+		}
+	}
+	t.columns = append(t.columns, name)
+}
+
+type colRef struct {
+	alias  string
+	column string
+}
+
+func (c colRef) String() string {
+	return c.alias + "." + c.column
+}
+
+type joinStep struct {
+	table     int // Index into tables of the joined (build) side
+	kind      string
+	probeKeys []colRef
+	buildKeys []colRef
+}
+
+type comparison struct {
+	left  colRef
+	op    string
+	right colRef
+}
+
+type output struct {
+	name      string
+	column    *colRef // Plain column output
+	aggregate string
+	arg       *colRef // Aggregate argument; nil for count(*)
+	star      string  // Table alias expanded by * or alias.*; "*" for all tables
+}
+
+type orderItem struct {
+	output int
+	desc   bool
+}
+
+/* connectorResolver loads the connector a table reference names */
+type connectorResolver func(name string) (*connectors.DataSourceConnector, error)
+
+/* buildPlan parses a federated query whose tables are named connector.schema.table */
+func buildPlan(sql string, resolve connectorResolver) (*Plan, error) {
+	tree, err := pg_query.Parse(sql)
+	if err != nil {
+		return nil, planErrorf("failed to parse query: %v", err)
+	}
+	if len(tree.Stmts) != 1 {
+		return nil, planErrorf("federated queries must be a single statement")
+	}
+	stmt := tree.Stmts[0].Stmt.GetSelectStmt()
+	if stmt == nil || stmt.Op != pg_query.SetOperation_SETOP_NONE {
+		return nil, planErrorf("federated queries must be a single SELECT")
+	}
+	if stmt.WithClause != nil || len(stmt.DistinctClause) > 0 || stmt.HavingClause != nil || len(stmt.WindowClause) > 0 ||
+		stmt.LimitOffset != nil || stmt.IntoClause != nil || len(stmt.LockingClause) > 0 {
+		return nil, planErrorf("federated queries support SELECT, FROM with JOIN ... ON, WHERE, GROUP BY, ORDER BY and LIMIT")
+	}
+	if len(stmt.FromClause) != 1 {
+		return nil, planErrorf("federated queries must join tables with explicit JOIN ... ON")
+	}
+
+	p := &Plan{}
+	aliases := make(map[string]int)
+	var onConditions [][]*pg_query.Node
+
+	// Flatten the left-deep join tree into tables and join steps
+	var walk func(node *pg_query.Node) error
+	walk = func(node *pg_query.Node) error {
+		if rv := node.GetRangeVar(); rv != nil {
+			t, err := newTableRef(rv, resolve)
+			if err != nil {
+				return err
+			}
+			if _, dup := aliases[t.alias]; dup {
+				return planErrorf("table alias %s is used more than once", t.alias)
+			}
+			aliases[t.alias] = len(p.tables)
+			p.tables = append(p.tables, t)
+			return nil
+		}
+		join := node.GetJoinExpr()
+		if join == nil {
+			return planErrorf("federated queries can only read connector tables")
+		}
+		if join.IsNatural || len(join.UsingClause) > 0 || join.Quals == nil {
+			return planErrorf("joins must use an ON condition")
+		}
+		if join.Rarg.GetRangeVar() == nil {
+			return planErrorf("the right side of each join must be a table")
+		}
+		var kind string
+		switch join.Jointype {
+		case pg_query.JoinType_JOIN_INNER:
+			kind = JoinInner
+		case pg_query.JoinType_JOIN_LEFT:
+			kind = JoinLeft
+		default:
+			return planErrorf("only INNER and LEFT joins are supported")
+		}
+		if err := walk(join.Larg); err != nil {
+			return err
+		}
+		if err := walk(join.Rarg); err != nil {
+			return err
+		}
+		right := len(p.tables) - 1
+		if kind == JoinLeft {
+			p.tables[right].nullable = true
+		}
+		p.joins = append(p.joins, joinStep{table: right, kind: kind})
+		onConditions = append(onConditions, conjuncts(join.Quals))
+		return nil
+	}
+	if err := walk(stmt.FromClause[0]); err != nil {
+		return nil, err
+	}
+
+	r := &resolver{plan: p, aliases: aliases}
+
+	// Single-connector PostgreSQL queries run where the data is
+	if connector := p.singleConnector(); connector != nil && connector.ConnectorType != connectors.ConnectorRedshift {
+		if dialect, _ := connectors.GetQueryDialect(connector.ConnectorType); dialect.Driver == "postgres" {
+			return r.pushDownWhole(tree, stmt)
+		}
+	}
+
+	// Join conditions: equalities become hash keys, single-table conditions are pushed to the joined table
+	for i, conditions := range onConditions {
+		step := &p.joins[i]
+		buildAlias := p.tables[step.table].alias
+		for _, cond := range conditions {
+			used := r.aliasesIn(cond)
+			if len(used) == 1 && used[0] == buildAlias {
+				if err := r.pushFilter(p.tables[step.table], cond); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			left, op, right, ok := r.columnComparison(cond)
+			if !ok || op != "=" {
+				return nil, planErrorf("join conditions must be equalities between columns or filters on the joined table")
+			}
+			switch {
+			case right.alias == buildAlias && aliases[left.alias] < step.table:
+				step.probeKeys, step.buildKeys = append(step.probeKeys, left), append(step.buildKeys, right)
+			case left.alias == buildAlias && aliases[right.alias] < step.table:
+				step.probeKeys, step.buildKeys = append(step.probeKeys, right), append(step.buildKeys, left)
+			default:
+				return nil, planErrorf("join condition must compare %s with a table joined before it", buildAlias)
+			}
+		}
+		if len(step.buildKeys) == 0 {
+			return nil, planErrorf("join with %s has no equality condition", buildAlias)
+		}
+		for k := range step.buildKeys {
+			r.use(step.probeKeys[k])
+			r.use(step.buildKeys[k])
+		}
+	}
+
+	// WHERE: single-table conditions are pushed down, column comparisons across tables run after joining
+	for _, cond := range conjuncts(stmt.WhereClause) {
+		used := r.aliasesIn(cond)
+		switch len(used) {
+		case 0:
+			return nil, planErrorf("conditions must reference a table column")
+		case 1:
+			t := p.tables[aliases[used[0]]]
+			if t.nullable {
+				return nil, planErrorf("filters on %s, the optional side of a LEFT JOIN, belong in its ON clause", t.alias)
+			}
+			if err := r.pushFilter(t, cond); err != nil {
+				return nil, err
+			}
+		default:
+			left, op, right, ok := r.columnComparison(cond)
+			if !ok {
+				return nil, planErrorf("conditions across connectors must compare two columns")
+			}
+			p.residual = append(p.residual, comparison{left: left, op: op, right: right})
+			r.use(left)
+			r.use(right)
+		}
+	}
+
+	if err := r.resolveOutputs(stmt); err != nil {
+		return nil, err
+	}
+	if err := r.resolveOrder(stmt); err != nil {
+		return nil, err
+	}
+	if stmt.LimitCount != nil {
+		limit, ok := integerConst(stmt.LimitCount)
+		if !ok || limit < 0 {
+			return nil, planErrorf("LIMIT must be a non-negative integer")
+		}
+		p.Limit = &limit
+	}
+
+	p.describe()
+	return p, nil
+}
+
+/* singleConnector returns the connector every table belongs to, or nil */
+func (p *Plan) singleConnector() *connectors.DataSourceConnector {
+	first := p.tables[0].connector
+	for _, t := range p.tables[1:] {
+		if t.connector.ID != first.ID {
+			return nil
+		}
+	}
+	return first
+}
+
+func newTableRef(rv *pg_query.RangeVar, resolve connectorResolver) (*tableRef, error) {
+	if rv.Catalogname == "" || rv.Schemaname == "" {
+		return nil, planErrorf("table %s must be named connector.schema.table", rv.Relname)
+	}
+	connector, err := resolve(rv.Catalogname)
+	if err != nil {
+		return nil, planErrorf("unknown connector %s", rv.Catalogname)
+	}
+	if !connector.Enabled {
+		return nil, planErrorf("connector %s is disabled", connector.Name)
+	}
+	dialect, err := connectors.GetQueryDialect(connector.ConnectorType)
+	if err != nil {
+		return nil, planErrorf("%v", err)
+	}
+	alias := rv.Relname
+	if rv.Alias != nil && rv.Alias.Aliasname != "" {
+		alias = rv.Alias.Aliasname
+	}
+	return &tableRef{alias: alias, connector: connector, dialect: dialect, schema: rv.Schemaname, table: rv.Relname}, nil
+}
+
+/* resolver resolves column references against the query's tables */
+type resolver struct {
+	plan    *Plan
+	aliases map[string]int
+}
+
+/* column resolves a column reference; unqualified names are allowed only with a single table */
+func (r *resolver) column(node *pg_query.Node) (colRef, bool, error) {
+	ref := node.GetColumnRef()
+	if ref == nil {
+		return colRef{}, false, nil
+	}
+	var parts []string
+	for _, field := range ref.Fields {
+		if field.GetAStar() != nil {
+			return colRef{}, false, planErrorf("* is only allowed in the select list")
+		}
+		parts = append(parts, field.GetString_().GetSval())
+	}
+	switch len(parts) {
+	case 1:
+		if len(r.plan.tables) != 1 {
+			return colRef{}, false, planErrorf("column %s must be qualified with its table alias", parts[0])
+		}
+		return colRef{alias: r.plan.tables[0].alias, column: parts[0]}, true, nil
+	case 2:
+		if _, ok := r.aliases[parts[0]]; !ok {
+			return colRef{}, false, planErrorf("unknown table alias %s", parts[0])
+		}
+		return colRef{alias: parts[0], column: parts[1]}, true, nil
+	}
+	return colRef{}, false, planErrorf("column reference %s must be alias.column", strings.Join(parts, "."))
+}
+
+/* use adds a column to its table's pushed-down projection */
+func (r *resolver) use(c colRef) {
+	t := r.plan.tables[r.aliases[c.alias]]
+	if !t.star {
+		t.addColumn(c.column)
+	}
+}
+
+/* aliasesIn lists the table aliases a condition references */
+func (r *resolver) aliasesIn(node *pg_query.Node) []string {
+	seen := make(map[string]bool)
+	var out []string
+	var visit func(n *pg_query.Node)
+	visit = func(n *pg_query.Node) {
+		if n == nil {
+			return
+		}
+		if ref := n.GetColumnRef(); ref != nil {
+			alias := ""
+			if len(ref.Fields) == 2 {
+				alias = ref.Fields[0].GetString_().GetSval()
+			} else if len(r.plan.tables) == 1 {
+				alias = r.plan.tables[0].alias
+			}
+			if alias != "" && !seen[alias] {
+				seen[alias] = true
+				out = append(out, alias)
+			}
+			return
+		}
+		for _, child := range childNodes(n) {
+			visit(child)
+		}
+	}
+	visit(node)
+	return out
+}
+
+/* columnComparison matches "alias.column op alias.column" */
+func (r *resolver) columnComparison(node *pg_query.Node) (colRef, string, colRef, bool) {
+	expr := node.GetAExpr()
+	if expr == nil || expr.Kind != pg_query.A_Expr_Kind_AEXPR_OP || len(expr.Name) != 1 {
+		return colRef{}, "", colRef{}, false
+	}
+	op := expr.Name[0].GetString_().GetSval()
+	switch op {
+	case "=", "<>", "!=", "<", ">", "<=", ">=":
+	default:
+		return colRef{}, "", colRef{}, false
+	}
+	left, ok, err := r.column(expr.Lexpr)
+	if !ok || err != nil {
+		return colRef{}, "", colRef{}, false
+	}
+	right, ok, err := r.column(expr.Rexpr)
+	if !ok || err != nil {
+		return colRef{}, "", colRef{}, false
+	}
+	if op == "!=" {
+		op = "<>"
+	}
+	return left, op, right, true
+}
+
+/* pushFilter renders a single-table condition in the connector's dialect */
+func (r *resolver) pushFilter(t *tableRef, cond *pg_query.Node) error {
+	rendered, err := renderCondition(cond, t)
+	if err != nil {
+		return err
+	}
+	t.filters = append(t.filters, rendered)
+	return nil
+}
+
+/* resolveOutputs resolves the select list and GROUP BY */
+func (r *resolver) resolveOutputs(stmt *pg_query.SelectStmt) error {
+	p := r.plan
+	hasAggregate := false
+	for _, target := range stmt.TargetList {
+		res := target.GetResTarget()
+		if res == nil || res.Val == nil {
+			return planErrorf("unsupported select list entry")
+		}
+
+		// * and alias.*
+		if ref := res.Val.GetColumnRef(); ref != nil && ref.Fields[len(ref.Fields)-1].GetAStar() != nil {
+			star := "*"
+			if len(ref.Fields) == 2 {
+				star = ref.Fields[0].GetString_().GetSval()
+				if _, ok := r.aliases[star]; !ok {
+					return planErrorf("unknown table alias %s", star)
+				}
+			}
+			for _, t := range p.tables {
+				if star == "*" || star == t.alias {
+					t.star = true
+					t.columns = nil
+				}
+			}
+			p.outputs = append(p.outputs, output{star: star})
+			continue
+		}
+
+		if c, ok, err := r.column(res.Val); err != nil {
+			return err
+		} else if ok {
+			name := res.Name
+			if name == "" {
+				name = c.column
+			}
+			col := c
+			p.outputs = append(p.outputs, output{name: name, column: &col})
+			r.use(c)
+			continue
+		}
+
+		fn := res.Val.GetFuncCall()
+		if fn == nil {
+			return planErrorf("select list entries must be columns or aggregates (count, sum, avg, min, max)")
+		}
+		out, err := r.aggregate(fn)
+		if err != nil {
+			return err
+		}
+		if res.Name != "" {
+			out.name = res.Name
+		}
+		hasAggregate = true
+		p.outputs = append(p.outputs, out)
+	}
+
+	for _, node := range stmt.GroupClause {
+		c, ok, err := r.column(node)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return planErrorf("GROUP BY must list columns")
+		}
+		p.groupBy = append(p.groupBy, c)
+		r.use(c)
+	}
+
+	if hasAggregate || len(p.groupBy) > 0 {
+		for _, out := range p.outputs {
+			if out.star != "" {
+				return planErrorf("* cannot be combined with GROUP BY or aggregates")
+			}
+			if out.column != nil && !containsCol(p.groupBy, *out.column) {
+				return planErrorf("column %s must appear in GROUP BY or be used in an aggregate", out.column)
+			}
+		}
+	}
+	return nil
+}
+
+/* aggregate resolves an aggregate call in the select list */
+func (r *resolver) aggregate(fn *pg_query.FuncCall) (output, error) {
+	if len(fn.Funcname) != 1 || fn.AggFilter != nil || fn.Over != nil || len(fn.AggOrder) > 0 {
+		return output{}, planErrorf("unsupported function in select list")
+	}
+	name := strings.ToLower(fn.Funcname[0].GetString_().GetSval())
+	out := output{name: name}
+
+	switch name {
+	case AggregateCount:
+		out.aggregate = AggregateCount
+		if fn.AggStar {
+			return out, nil
+		}
+		if fn.AggDistinct {
+			out.aggregate = AggregateCountDistinct
+		}
+	case AggregateSum, AggregateAvg, AggregateMin, AggregateMax:
+		if fn.AggDistinct {
+			return output{}, planErrorf("DISTINCT is only supported with count")
+		}
+		out.aggregate = name
+	default:
+		return output{}, planErrorf("unsupported function %s; aggregates across connectors are count, sum, avg, min and max", name)
+	}
+
+	if len(fn.Args) != 1 {
+		return output{}, planErrorf("%s takes one column", name)
+	}
+	c, ok, err := r.column(fn.Args[0])
+	if err != nil {
+		return output{}, err
+	}
+	if !ok {
+		return output{}, planErrorf("%s arguments must be columns", name)
+	}
+	out.arg = &c
+	r.use(c)
+	return out, nil
+}
+
+/* resolveOrder maps ORDER BY items onto output columns by name, position or column */
+func (r *resolver) resolveOrder(stmt *pg_query.SelectStmt) error {
+	p := r.plan
+	for _, node := range stmt.SortClause {
+		sort := node.GetSortBy()
+		if sort == nil || sort.SortbyDir == pg_query.SortByDir_SORTBY_USING {
+			return planErrorf("unsupported ORDER BY item")
+		}
+		index := -1
+		if position, ok := integerConst(sort.Node); ok {
+			index = int(position) - 1
+		} else if ref := sort.Node.GetColumnRef(); ref != nil && len(ref.Fields) == 1 {
+			name := ref.Fields[0].GetString_().GetSval()
+			for i, out := range p.outputs {
+				if out.name == name {
+					index = i
+					break
+				}
+			}
+		}
+		if index < 0 {
+			if c, ok, _ := r.column(sort.Node); ok {
+				for i, out := range p.outputs {
+					if out.column != nil && *out.column == c {
+						index = i
+						break
+					}
+				}
+			}
+		}
+		if index < 0 || index >= len(p.outputs) || p.outputs[index].star != "" {
+			return planErrorf("ORDER BY must name a selected column or its position")
+		}
+		p.orderBy = append(p.orderBy, orderItem{output: index, desc: sort.SortbyDir == pg_query.SortByDir_SORTBY_DESC})
+	}
+	return nil
+}
+
+/* pushDownWhole sends a query over a single PostgreSQL connector to that connector unchanged apart from table names */
+func (r *resolver) pushDownWhole(tree *pg_query.ParseResult, stmt *pg_query.SelectStmt) (*Plan, error) {
+	p := r.plan
+	var strip func(node *pg_query.Node)
+	strip = func(node *pg_query.Node) {
+		if rv := node.GetRangeVar(); rv != nil {
+			rv.Catalogname = ""
+			return
+		}
+		if join := node.GetJoinExpr(); join != nil {
+			strip(join.Larg)
+			strip(join.Rarg)
+		}
+	}
+	strip(stmt.FromClause[0])
+
+	sql, err := pg_query.Deparse(tree)
+	if err != nil {
+		return nil, planErrorf("failed to rewrite query: %v", err)
+	}
+
+	var tables []string
+	for _, t := range p.tables {
+		tables = append(tables, t.schema+"."+t.table)
+	}
+	connector := p.tables[0].connector
+	p.PushedDown = true
+	p.Subqueries = []Subquery{{
+		Connector:     connector.Name,
+		ConnectorType: string(connector.ConnectorType),
+		Table:         strings.Join(tables, ", "),
+		SQL:           sql,
+	}}
+	return p, nil
+}
+
+/* describe fills the exported plan fields and each table's subquery SQL */
+func (p *Plan) describe() {
+	for _, t := range p.tables {
+		sub := Subquery{
+			Alias:         t.alias,
+			Connector:     t.connector.Name,
+			ConnectorType: string(t.connector.ConnectorType),
+			Table:         t.schema + "." + t.table,
+			Columns:       t.columns,
+			Filters:       t.filters,
+			SQL:           t.subquerySQL(),
+		}
+		p.Subqueries = append(p.Subqueries, sub)
+	}
+	for _, step := range p.joins {
+		js := JoinStep{Alias: p.tables[step.table].alias, Type: step.kind}
+		for k := range step.buildKeys {
+			js.Keys = append(js.Keys, step.probeKeys[k].String()+" = "+step.buildKeys[k].String())
+		}
+		p.Joins = append(p.Joins, js)
+	}
+	for _, cmp := range p.residual {
+		p.ResidualFilters = append(p.ResidualFilters, cmp.left.String()+" "+cmp.op+" "+cmp.right.String())
+	}
+	for _, c := range p.groupBy {
+		p.GroupBy = append(p.GroupBy, c.String())
+	}
+	seen := make(map[string]int)
+	for i := range p.outputs {
+		out := &p.outputs[i]
+		if out.star == "" {
+			// Result rows are keyed by column name, so repeated names get a suffix
+			if n := seen[out.name]; n > 0 {
+				seen[out.name] = n + 1
+				out.name = fmt.Sprintf("%s_%d", out.name, n+1)
+			} else {
+				seen[out.name] = 1
+			}
+		}
+		if out.aggregate != "" {
+			arg := "*"
+			if out.arg != nil {
+				arg = out.arg.String()
+			}
+			p.Aggregates = append(p.Aggregates, out.aggregate+"("+arg+")")
+		}
+		if out.star == "" {
+			p.Columns = append(p.Columns, out.name)
+		}
+	}
+	for _, item := range p.orderBy {
+		direction := "ASC"
+		if item.desc {
+			direction = "DESC"
+		}
+		p.OrderBy = append(p.OrderBy, p.outputs[item.output].name+" "+direction)
+	}
+}
+
+/* subquerySQL is the query sent to the table's connector */
+func (t *tableRef) subquerySQL() string {
+	d := t.dialect
+	projection := "*"
+	if !t.star {
+		if len(t.columns) == 0 {
+			projection = "1 AS " + d.QuoteIdent("__row")
+		} else {
+			var cols []string
+			for _, c := range t.columns {
+				cols = append(cols, d.QuoteIdent(t.alias)+"."+d.QuoteIdent(c))
+			}
+			projection = strings.Join(cols, ", ")
+		}
+	}
+	sql := fmt.Sprintf("SELECT %s FROM %s.%s AS %s", projection, d.QuoteIdent(t.schema), d.QuoteIdent(t.table), d.QuoteIdent(t.alias))
+	if len(t.filters) > 0 {
+		sql += " WHERE " + strings.Join(t.filters, " AND ")
+	}
+	return sql
+}
+
+/* renderCondition renders a single-table condition for the table's connector.
+ * PostgreSQL connectors receive any condition as deparsed; other dialects receive comparisons, IN, LIKE,
+ * BETWEEN and NULL tests over columns and literals, combined with AND, OR and NOT. */
+func renderCondition(node *pg_query.Node, t *tableRef) (string, error) {
+	if sql, err := renderPortable(node, t); err == nil {
+		return sql, nil
+	} else if t.dialect.Driver != "postgres" {
+		return "", err
+	}
+	if containsSubquery(node) {
+		return "", planErrorf("subqueries are not supported in federated queries")
+	}
+
+	wrapper := &pg_query.ParseResult{Stmts: []*pg_query.RawStmt{{Stmt: &pg_query.Node{Node: &pg_query.Node_SelectStmt{
+		SelectStmt: &pg_query.SelectStmt{WhereClause: node, Op: pg_query.SetOperation_SETOP_NONE},
+	}}}}}
+	sql, err := pg_query.Deparse(wrapper)
+	if err != nil {
+		return "", planErrorf("failed to render condition: %v", err)
+	}
+	_, where, found := strings.Cut(sql, "WHERE ")
+	if !found {
+		return "", planErrorf("failed to render condition")
+	}
+	return "(" + where + ")", nil
+}
+
+func renderPortable(node *pg_query.Node, t *tableRef) (string, error) {
+	d := t.dialect
+	switch n := node.Node.(type) {
+	case *pg_query.Node_ColumnRef:
+		fields := n.ColumnRef.Fields
+		column := fields[len(fields)-1].GetString_().GetSval()
+		if column == "" {
+			return "", planErrorf("unsupported column reference")
+		}
+		return d.QuoteIdent(t.alias) + "." + d.QuoteIdent(column), nil
+
+	case *pg_query.Node_AConst:
+		return renderConst(n.AConst, d)
+
+	case *pg_query.Node_TypeCast:
+		// Literals are coerced by comparison with the column
+		if n.TypeCast.Arg.GetAConst() != nil {
+			return renderPortable(n.TypeCast.Arg, t)
+		}
+
+	case *pg_query.Node_BoolExpr:
+		var parts []string
+		for _, arg := range n.BoolExpr.Args {
+			part, err := renderPortable(arg, t)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, part)
+		}
+		switch n.BoolExpr.Boolop {
+		case pg_query.BoolExprType_AND_EXPR:
+			return "(" + strings.Join(parts, " AND ") + ")", nil
+		case pg_query.BoolExprType_OR_EXPR:
+			return "(" + strings.Join(parts, " OR ") + ")", nil
+		case pg_query.BoolExprType_NOT_EXPR:
+			return "(NOT " + parts[0] + ")", nil
+		}
+
+	case *pg_query.Node_NullTest:
+		arg, err := renderPortable(n.NullTest.Arg, t)
+		if err != nil {
+			return "", err
+		}
+		if n.NullTest.Nulltesttype == pg_query.NullTestType_IS_NOT_NULL {
+			return arg + " IS NOT NULL", nil
+		}
+		return arg + " IS NULL", nil
+
+	case *pg_query.Node_AExpr:
+		expr := n.AExpr
+		if len(expr.Name) != 1 {
+			break
+		}
+		op := expr.Name[0].GetString_().GetSval()
+		left, err := renderPortable(expr.Lexpr, t)
+		if err != nil {
+			return "", err
+		}
+		switch expr.Kind {
+		case pg_query.A_Expr_Kind_AEXPR_OP:
+			switch op {
+			case "=", "<>", "!=", "<", ">", "<=", ">=":
+				right, err := renderPortable(expr.Rexpr, t)
+				if err != nil {
+					return "", err
+				}
+				if op == "!=" {
+					op = "<>"
+				}
+				return "(" + left + " " + op + " " + right + ")", nil
+			}
+		case pg_query.A_Expr_Kind_AEXPR_LIKE:
+			right, err := renderPortable(expr.Rexpr, t)
+			if err != nil {
+				return "", err
+			}
+			if op == "!~~" {
+				return "(" + left + " NOT LIKE " + right + ")", nil
+			}
+			return "(" + left + " LIKE " + right + ")", nil
+		case pg_query.A_Expr_Kind_AEXPR_IN:
+			items, err := renderList(expr.Rexpr, t)
+			if err != nil {
+				return "", err
+			}
+			if op == "<>" {
+				return "(" + left + " NOT IN (" + strings.Join(items, ", ") + "))", nil
+			}
+			return "(" + left + " IN (" + strings.Join(items, ", ") + "))", nil
+		case pg_query.A_Expr_Kind_AEXPR_BETWEEN, pg_query.A_Expr_Kind_AEXPR_NOT_BETWEEN:
+			items, err := renderList(expr.Rexpr, t)
+			if err != nil || len(items) != 2 {
+				return "", planErrorf("unsupported BETWEEN condition")
+			}
+			keyword := " BETWEEN "
+			if expr.Kind == pg_query.A_Expr_Kind_AEXPR_NOT_BETWEEN {
+				keyword = " NOT BETWEEN "
+			}
+			return "(" + left + keyword + items[0] + " AND " + items[1] + ")", nil
+		}
+	}
+	return "", planErrorf("condition cannot be pushed down to %s connector %s", t.connector.ConnectorType, t.connector.Name)
+}
+
+func renderList(node *pg_query.Node, t *tableRef) ([]string, error) {
+	list := node.GetList()
+	if list == nil {
+		return nil, planErrorf("expected a list of values")
+	}
+	var items []string
+	for _, item := range list.Items {
+		rendered, err := renderPortable(item, t)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, rendered)
+	}
+	return items, nil
+}
+
+func renderConst(c *pg_query.A_Const, d connectors.QueryDialect) (string, error) {
+	if c.Isnull {
+		return "NULL", nil
+	}
+	switch v := c.Val.(type) {
+	case *pg_query.A_Const_Ival:
+		return strconv.FormatInt(int64(v.Ival.Ival), 10), nil
+	case *pg_query.A_Const_Fval:
+		if _, err := strconv.ParseFloat(v.Fval.Fval, 64); err != nil {
+			return "", planErrorf("invalid number %s", v.Fval.Fval)
+		}
+		return v.Fval.Fval, nil
+	case *pg_query.A_Const_Sval:
+		return d.QuoteString(v.Sval.Sval), nil
+	case *pg_query.A_Const_Boolval:
+		// SQL Server has no boolean literals; its bit columns compare with 1 and 0
+		if d.Driver == "sqlserver" {
+			if v.Boolval.Boolval {
+				return "1", nil
+			}
+			return "0", nil
+		}
+		if v.Boolval.Boolval {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	}
+	return "", planErrorf("unsupported literal")
+}
+
+/* conjuncts splits a condition on top-level ANDs */
+func conjuncts(node *pg_query.Node) []*pg_query.Node {
+	if node == nil {
+		return nil
+	}
+	if b := node.GetBoolExpr(); b != nil && b.Boolop == pg_query.BoolExprType_AND_EXPR {
+		var out []*pg_query.Node
+		for _, arg := range b.Args {
+			out = append(out, conjuncts(arg)...)
+		}
+		return out
+	}
+	return []*pg_query.Node{node}
+}
+
+/* childNodes returns the expression children of the node types allowed in conditions */
+func childNodes(n *pg_query.Node) []*pg_query.Node {
+	switch v := n.Node.(type) {
+	case *pg_query.Node_AExpr:
+		return []*pg_query.Node{v.AExpr.Lexpr, v.AExpr.Rexpr}
+	case *pg_query.Node_BoolExpr:
+		return v.BoolExpr.Args
+	case *pg_query.Node_NullTest:
+		return []*pg_query.Node{v.NullTest.Arg}
+	case *pg_query.Node_TypeCast:
+		return []*pg_query.Node{v.TypeCast.Arg}
+	case *pg_query.Node_List:
+		return v.List.Items
+	case *pg_query.Node_FuncCall:
+		return v.FuncCall.Args
+	case *pg_query.Node_CoalesceExpr:
+		return v.CoalesceExpr.Args
+	case *pg_query.Node_CaseExpr:
+		children := []*pg_query.Node{v.CaseExpr.Arg, v.CaseExpr.Defresult}
+		for _, when := range v.CaseExpr.Args {
+			if w := when.GetCaseWhen(); w != nil {
+				children = append(children, w.Expr, w.Result)
+			}
+		}
+		return children
+	case *pg_query.Node_SubLink:
+		return []*pg_query.Node{v.SubLink.Testexpr, v.SubLink.Subselect}
+	}
+	return nil
+}
+
+func containsSubquery(node *pg_query.Node) bool {
+	if node == nil {
+		return false
+	}
+	if node.GetSubLink() != nil {
+		return true
+	}
+	for _, child := range childNodes(node) {
+		if containsSubquery(child) {
+			return true
+		}
+	}
+	return false
+}
+
+func integerConst(node *pg_query.Node) (int64, bool) {
+	c := node.GetAConst()
+	if c == nil || c.Isnull {
+		return 0, false
+	}
+	switch v := c.Val.(type) {
+	case *pg_query.A_Const_Ival:
+		return int64(v.Ival.Ival), true
+	case *pg_query.A_Const_Fval:
+		n, err := strconv.ParseInt(v.Fval.Fval, 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func containsCol(cols []colRef, c colRef) bool {
+	for _, x := range cols {
+		if x == c {
+			return true
+		}
+	}
+	return false
+}
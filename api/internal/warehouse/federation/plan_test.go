@@ -0,0 +1,162 @@
+package federation
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/neurondb/NeuronIP/api/internal/connectors"
+)
+
+/* testResolver resolves the connectors used by the planner tests: shop (MySQL), crm and billing (PostgreSQL), archive (disabled) */
+func testResolver() connectorResolver {
+	known := map[string]*connectors.DataSourceConnector{
+		"shop":    {ID: uuid.New(), Name: "shop", ConnectorType: connectors.ConnectorMySQL, Enabled: true},
+		"crm":     {ID: uuid.New(), Name: "crm", ConnectorType: connectors.ConnectorPostgreSQL, Enabled: true},
+		"billing": {ID: uuid.New(), Name: "billing", ConnectorType: connectors.ConnectorPostgreSQL, Enabled: true},
+		"archive": {ID: uuid.New(), Name: "archive", ConnectorType: connectors.ConnectorPostgreSQL},
+	}
+	return func(name string) (*connectors.DataSourceConnector, error) {
+		if c, ok := known[name]; ok {
+			return c, nil
+		}
+		return nil, fmt.Errorf("connector %s not found", name)
+	}
+}
+
+/* TestBuildPlan checks how federated queries are split into subqueries and API-side steps */
+func TestBuildPlan(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		pushedDown bool
+		subqueries []string
+		joins      []JoinStep
+		residual   []string
+		groupBy    []string
+		aggregates []string
+		orderBy    []string
+		columns    []string
+		limit      int64
+	}{
+		{
+			name: "cross-connector join",
+			query: `SELECT o.id, c.name FROM shop.sales.orders o JOIN crm.public.customers c ON o.customer_id = c.id AND c.active = true
+				WHERE o.total > 100 AND o.placed_at > c.created_at ORDER BY o.id DESC LIMIT 10`,
+			subqueries: []string{
+				"SELECT `o`.`customer_id`, `o`.`placed_at`, `o`.`id` FROM `sales`.`orders` AS `o` WHERE (`o`.`total` > 100)",
+				`SELECT "c"."id", "c"."created_at", "c"."name" FROM "public"."customers" AS "c" WHERE ("c"."active" = TRUE)`,
+			},
+			joins:    []JoinStep{{Alias: "c", Type: JoinInner, Keys: []string{"o.customer_id = c.id"}}},
+			residual: []string{"o.placed_at > c.created_at"},
+			orderBy:  []string{"id DESC"},
+			columns:  []string{"id", "name"},
+			limit:    10,
+		},
+		{
+			name: "aggregate over a left join",
+			query: `SELECT c.region, count(*), sum(o.total) AS revenue FROM shop.sales.orders o
+				LEFT JOIN crm.public.customers c ON o.customer_id = c.id GROUP BY c.region ORDER BY revenue DESC`,
+			subqueries: []string{
+				"SELECT `o`.`customer_id`, `o`.`total` FROM `sales`.`orders` AS `o`",
+				`SELECT "c"."id", "c"."region" FROM "public"."customers" AS "c"`,
+			},
+			joins:      []JoinStep{{Alias: "c", Type: JoinLeft, Keys: []string{"o.customer_id = c.id"}}},
+			groupBy:    []string{"c.region"},
+			aggregates: []string{"count(*)", "sum(o.total)"},
+			orderBy:    []string{"revenue DESC"},
+			columns:    []string{"region", "count", "revenue"},
+		},
+		{
+			name:       "star over one table",
+			query:      `SELECT * FROM shop.sales.orders o WHERE o.status IN ('open', 'it''s paid')`,
+			subqueries: []string{"SELECT * FROM `sales`.`orders` AS `o` WHERE (`o`.`status` IN ('open', 'it''s paid'))"},
+		},
+		{
+			name:       "repeated output names",
+			query:      `SELECT o.id, i.id FROM shop.sales.orders o JOIN billing.public.invoices i ON i.order_id = o.id`,
+			subqueries: []string{"SELECT `o`.`id` FROM `sales`.`orders` AS `o`", `SELECT "i"."order_id", "i"."id" FROM "public"."invoices" AS "i"`},
+			joins:      []JoinStep{{Alias: "i", Type: JoinInner, Keys: []string{"o.id = i.order_id"}}},
+			columns:    []string{"id", "id_2"},
+		},
+		{
+			name:       "single PostgreSQL connector",
+			query:      `SELECT c.name, a.city FROM crm.public.customers c JOIN crm.public.addresses a ON a.customer_id = c.id WHERE c.active`,
+			pushedDown: true,
+			subqueries: []string{"SELECT c.name, a.city FROM public.customers c JOIN public.addresses a ON a.customer_id = c.id WHERE c.active"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := buildPlan(tt.query, testResolver())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plan.PushedDown != tt.pushedDown {
+				t.Errorf("expected pushed down %v, got %v", tt.pushedDown, plan.PushedDown)
+			}
+			var subqueries []string
+			for _, sub := range plan.Subqueries {
+				subqueries = append(subqueries, sub.SQL)
+			}
+			for _, check := range []struct {
+				what      string
+				got, want interface{}
+			}{
+				{"subqueries", subqueries, tt.subqueries},
+				{"joins", plan.Joins, tt.joins},
+				{"residual filters", plan.ResidualFilters, tt.residual},
+				{"group by", plan.GroupBy, tt.groupBy},
+				{"aggregates", plan.Aggregates, tt.aggregates},
+				{"order by", plan.OrderBy, tt.orderBy},
+				{"columns", plan.Columns, tt.columns},
+			} {
+				if !reflect.DeepEqual(check.got, check.want) {
+					t.Errorf("%s: expected %#v, got %#v", check.what, check.want, check.got)
+				}
+			}
+			if tt.limit > 0 && (plan.Limit == nil || *plan.Limit != tt.limit) {
+				t.Errorf("expected limit %d, got %v", tt.limit, plan.Limit)
+			}
+		})
+	}
+}
+
+/* TestBuildPlanRejects checks the queries the planner cannot federate */
+func TestBuildPlanRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "two statements", query: `SELECT o.id FROM shop.sales.orders o; SELECT 1`},
+		{name: "union", query: `SELECT o.id FROM shop.sales.orders o UNION SELECT c.id FROM crm.public.customers c`},
+		{name: "offset", query: `SELECT o.id FROM shop.sales.orders o LIMIT 10 OFFSET 5`},
+		{name: "comma join", query: `SELECT o.id FROM shop.sales.orders o, crm.public.customers c`},
+		{name: "unqualified table", query: `SELECT o.id FROM sales.orders o`},
+		{name: "unknown connector", query: `SELECT o.id FROM erp.sales.orders o`},
+		{name: "disabled connector", query: `SELECT o.id FROM archive.public.orders o`},
+		{name: "right join", query: `SELECT o.id FROM shop.sales.orders o RIGHT JOIN crm.public.customers c ON o.customer_id = c.id`},
+		{name: "join using", query: `SELECT o.id FROM shop.sales.orders o JOIN crm.public.customers c USING (id)`},
+		{name: "join without equality", query: `SELECT o.id FROM shop.sales.orders o JOIN crm.public.customers c ON o.customer_id > c.id`},
+		{name: "duplicate alias", query: `SELECT o.id FROM shop.sales.orders o JOIN crm.public.customers o ON o.id = o.id`},
+		{name: "where on optional side", query: `SELECT o.id FROM shop.sales.orders o LEFT JOIN crm.public.customers c ON o.customer_id = c.id WHERE c.active = true`},
+		{name: "unqualified column across tables", query: `SELECT id FROM shop.sales.orders o JOIN crm.public.customers c ON o.customer_id = c.id`},
+		{name: "ungrouped column", query: `SELECT c.region, o.id, count(*) FROM shop.sales.orders o JOIN crm.public.customers c ON o.customer_id = c.id GROUP BY c.region`},
+		{name: "star with aggregate", query: `SELECT *, count(*) FROM shop.sales.orders o`},
+		{name: "unsupported aggregate", query: `SELECT stddev(o.total) FROM shop.sales.orders o`},
+		{name: "subquery on MySQL", query: `SELECT o.id FROM shop.sales.orders o JOIN crm.public.customers c ON o.customer_id = c.id WHERE o.id IN (SELECT 1)`},
+		{name: "order by unselected column", query: `SELECT o.id FROM shop.sales.orders o ORDER BY o.total`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := buildPlan(tt.query, testResolver())
+			if err == nil {
+				t.Fatalf("expected the query to be rejected, got %+v", plan.Subqueries)
+			}
+			if AsPlanError(err) == nil {
+				t.Fatalf("expected a plan error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package federation
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+/* memoryBudget tracks the bytes of rows held in memory by all operators of a query */
+type memoryBudget struct {
+	limit int64
+	used  int64
+	peak  int64
+}
+
+func (b *memoryBudget) reserve(n int64) bool {
+	if b.used+n > b.limit {
+		return false
+	}
+	b.used += n
+	b.peak = max(b.peak, b.used)
+	return true
+}
+
+func (b *memoryBudget) release(n int64) {
+	b.used -= n
+}
+
+/* rowSize estimates the memory a row occupies */
+func rowSize(row []interface{}) int64 {
+	size := int64(24 + 16*len(row))
+	for _, v := range row {
+		switch value := v.(type) {
+		case string:
+			size += int64(len(value))
+		case time.Time:
+			size += 24
+		}
+	}
+	return size
+}
+
+/* spillDir holds the spill files of one query */
+type spillDir struct {
+	path  string
+	files int
+	bytes int64
+}
+
+func newSpillDir(parent string) (*spillDir, error) {
+	path, err := os.MkdirTemp(parent, "federation-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	return &spillDir{path: path}, nil
+}
+
+func (d *spillDir) remove() {
+	os.RemoveAll(d.path)
+}
+
+/* spillValue is the on-disk form of a row value */
+type spillValue struct {
+	Kind uint8
+	I    int64
+	F    float64
+	S    string
+	T    time.Time
+}
+
+const (
+	spillNull uint8 = iota
+	spillInt
+	spillFloat
+	spillString
+	spillTime
+	spillBool
+)
+
+func toSpillValue(v interface{}) spillValue {
+	switch value := v.(type) {
+	case int64:
+		return spillValue{Kind: spillInt, I: value}
+	case float64:
+		return spillValue{Kind: spillFloat, F: value}
+	case string:
+		return spillValue{Kind: spillString, S: value}
+	case time.Time:
+		return spillValue{Kind: spillTime, T: value}
+	case bool:
+		if value {
+			return spillValue{Kind: spillBool, I: 1}
+		}
+		return spillValue{Kind: spillBool}
+	}
+	return spillValue{Kind: spillNull}
+}
+
+func (v spillValue) value() interface{} {
+	switch v.Kind {
+	case spillInt:
+		return v.I
+	case spillFloat:
+		return v.F
+	case spillString:
+		return v.S
+	case spillTime:
+		return v.T
+	case spillBool:
+		return v.I == 1
+	}
+	return nil
+}
+
+/* spillFile is an append-only file of rows */
+type spillFile struct {
+	dir  *spillDir
+	path string
+	file *os.File
+	buf  *bufio.Writer
+	enc  *gob.Encoder
+	rows int
+}
+
+func (d *spillDir) create() (*spillFile, error) {
+	d.files++
+	path := filepath.Join(d.path, fmt.Sprintf("spill-%d.gob", d.files))
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill file: %w", err)
+	}
+	buf := bufio.NewWriter(file)
+	return &spillFile{dir: d, path: path, file: file, buf: buf, enc: gob.NewEncoder(buf)}, nil
+}
+
+func (f *spillFile) write(row []interface{}) error {
+	values := make([]spillValue, len(row))
+	for i, v := range row {
+		values[i] = toSpillValue(v)
+	}
+	if err := f.enc.Encode(values); err != nil {
+		return fmt.Errorf("failed to write spill file: %w", err)
+	}
+	f.rows++
+	return nil
+}
+
+/* finish flushes the file and records its size */
+func (f *spillFile) finish() error {
+	if f.file == nil {
+		return nil
+	}
+	if err := f.buf.Flush(); err != nil {
+		return fmt.Errorf("failed to write spill file: %w", err)
+	}
+	if info, err := f.file.Stat(); err == nil {
+		f.dir.bytes += info.Size()
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+/* each reads the rows of a finished file */
+func (f *spillFile) each(fn func(row []interface{}) error) error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to open spill file: %w", err)
+	}
+	defer file.Close()
+
+	dec := gob.NewDecoder(bufio.NewReader(file))
+	for {
+		var values []spillValue
+		if err := dec.Decode(&values); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read spill file: %w", err)
+		}
+		row := make([]interface{}, len(values))
+		for i, v := range values {
+			row[i] = v.value()
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+/* keyedRow is a row with its canonical hash key */
+type keyedRow struct {
+	key string
+	row []interface{}
+}
+
+/* partition holds rows in memory until the budget runs out, after which its rows go to disk */
+type partition struct {
+	rows  []keyedRow
+	bytes int64
+	file  *spillFile
+}
+
+/* partitionedStore hash-partitions rows by key so that a partition can be spilled and processed on its own */
+type partitionedStore struct {
+	budget     *memoryBudget
+	dir        *spillDir
+	partitions []partition
+}
+
+func newPartitionedStore(budget *memoryBudget, dir *spillDir, partitions int) *partitionedStore {
+	return &partitionedStore{budget: budget, dir: dir, partitions: make([]partition, partitions)}
+}
+
+func partitionOf(key string, partitions int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(partitions))
+}
+
+/* add stores a row, spilling the largest in-memory partition when the memory budget is exhausted */
+func (s *partitionedStore) add(key string, row []interface{}) error {
+	p := &s.partitions[partitionOf(key, len(s.partitions))]
+	if p.file != nil {
+		return p.file.write(append([]interface{}{key}, row...))
+	}
+	size := rowSize(row) + int64(len(key))
+	for !s.budget.reserve(size) {
+		victim := s.largestInMemory()
+		if victim == nil {
+			return s.spillPartition(p, key, row)
+		}
+		if err := s.spill(victim); err != nil {
+			return err
+		}
+		if victim == p {
+			return p.file.write(append([]interface{}{key}, row...))
+		}
+	}
+	p.rows = append(p.rows, keyedRow{key: key, row: row})
+	p.bytes += size
+	return nil
+}
+
+func (s *partitionedStore) spillPartition(p *partition, key string, row []interface{}) error {
+	if err := s.spill(p); err != nil {
+		return err
+	}
+	return p.file.write(append([]interface{}{key}, row...))
+}
+
+func (s *partitionedStore) largestInMemory() *partition {
+	var largest *partition
+	for i := range s.partitions {
+		p := &s.partitions[i]
+		if p.file == nil && len(p.rows) > 0 && (largest == nil || p.bytes > largest.bytes) {
+			largest = p
+		}
+	}
+	return largest
+}
+
+/* spill moves a partition's rows to disk; later rows for it are written straight to the file */
+func (s *partitionedStore) spill(p *partition) error {
+	file, err := s.dir.create()
+	if err != nil {
+		return err
+	}
+	p.file = file
+	for _, kr := range p.rows {
+		if err := file.write(append([]interface{}{kr.key}, kr.row...)); err != nil {
+			return err
+		}
+	}
+	s.budget.release(p.bytes)
+	p.rows, p.bytes = nil, 0
+	return nil
+}
+
+/* finish flushes every spilled partition */
+func (s *partitionedStore) finish() error {
+	for i := range s.partitions {
+		if f := s.partitions[i].file; f != nil {
+			if err := f.finish(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *partitionedStore) spilled(i int) bool {
+	return s.partitions[i].file != nil
+}
+
+func (s *partitionedStore) spilledPartitions() int {
+	n := 0
+	for i := range s.partitions {
+		if s.spilled(i) {
+			n++
+		}
+	}
+	return n
+}
+
+/* each visits the rows of one partition, reading them back from disk when it was spilled */
+func (s *partitionedStore) each(i int, fn func(key string, row []interface{}) error) error {
+	p := &s.partitions[i]
+	if p.file == nil {
+		for _, kr := range p.rows {
+			if err := fn(kr.key, kr.row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return p.file.each(func(row []interface{}) error {
+		key, _ := row[0].(string)
+		return fn(key, row[1:])
+	})
+}
+
+/* release frees the memory of in-memory partitions */
+func (s *partitionedStore) release() {
+	for i := range s.partitions {
+		s.budget.release(s.partitions[i].bytes)
+		s.partitions[i].rows, s.partitions[i].bytes = nil, 0
+	}
+}
@@ -6,61 +6,90 @@ import (
 	"strings"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/neurondb/NeuronIP/api/internal/execution"
 )
 
 /* GovernanceService provides query governance functionality */
 type GovernanceService struct {
-	pool *pgxpool.Pool
+	pool         *pgxpool.Pool
+	admission    AdmissionConfig
+	asyncQueries *execution.AsyncQueryService
+	queue        *admissionQueue
 }
 
 /* NewGovernanceService creates a new governance service */
 func NewGovernanceService(pool *pgxpool.Pool) *GovernanceService {
-	return &GovernanceService{pool: pool}
+	admission := DefaultAdmissionConfig()
+	return &GovernanceService{pool: pool, admission: admission, queue: newAdmissionQueue(admission.MaxConcurrent)}
 }
 
-/* ValidateQuery validates a query against governance rules */
+/* NewGovernanceServiceWithAdmission creates a governance service that moves queries over their cost limit to asyncQueries */
+func NewGovernanceServiceWithAdmission(pool *pgxpool.Pool, asyncQueries *execution.AsyncQueryService, admission AdmissionConfig) *GovernanceService {
+	defaults := DefaultAdmissionConfig()
+	if admission.MaxConcurrent <= 0 {
+		admission.MaxConcurrent = defaults.MaxConcurrent
+	}
+	if admission.MaxQueueWait <= 0 {
+		admission.MaxQueueWait = defaults.MaxQueueWait
+	}
+	if admission.ExplainTimeout <= 0 {
+		admission.ExplainTimeout = defaults.ExplainTimeout
+	}
+	if admission.DefaultCostLimit <= 0 {
+		admission.DefaultCostLimit = defaults.DefaultCostLimit
+	}
+	if admission.AsyncExpiryHours <= 0 {
+		admission.AsyncExpiryHours = defaults.AsyncExpiryHours
+	}
+	return &GovernanceService{
+		pool:         pool,
+		admission:    admission,
+		asyncQueries: asyncQueries,
+		queue:        newAdmissionQueue(admission.MaxConcurrent),
+	}
+}
+
+/* ValidateQuery validates a query against governance rules.
+ * The query is parsed as PostgreSQL and must be a single read-only SELECT; role policy adds
+ * denied functions, schema restrictions and the row cap on top of the defaults. */
 func (s *GovernanceService) ValidateQuery(ctx context.Context, queryText, userRole string, userID *string) (*QueryValidationResult, error) {
 	result := &QueryValidationResult{
 		Allowed: true,
 		Warnings: []string{},
 	}
 
-	// Check read-only mode
-	if s.isReadOnlyMode(ctx, userRole) {
-		if s.containsWriteOperations(queryText) {
-			result.Allowed = false
-			result.BlockedReason = "Read-only mode: write operations not allowed"
-			return result, nil
+	validated, err := ValidateSQL(queryText, s.policyForRole(ctx, userRole))
+	if err != nil {
+		validationErr := AsSQLValidationError(err)
+		if validationErr == nil {
+			return nil, fmt.Errorf("failed to validate query: %w", err)
 		}
-	}
-
-	// Check function allow-list
-	allowed, reason := s.checkFunctionAllowList(ctx, queryText, userRole)
-	if !allowed {
 		result.Allowed = false
-		result.BlockedReason = reason
+		result.BlockedReason = validationErr.Error()
+		result.Violations = validationErr.Violations
 		return result, nil
 	}
-
-	// Check sandbox role restrictions
-	if s.isSandboxRole(ctx, userRole) {
-		sandboxResult := s.checkSandboxRestrictions(ctx, queryText, userRole)
-		if !sandboxResult.Allowed {
-			result.Allowed = false
-			result.BlockedReason = sandboxResult.BlockedReason
-			return result, nil
-		}
-		result.Warnings = append(result.Warnings, sandboxResult.Warnings...)
+	result.SQL = validated.SQL
+	if validated.LimitApplied {
+		result.Warnings = append(result.Warnings, "Row limit applied to query")
 	}
 
-	// Estimate query cost
-	cost, err := s.estimateQueryCost(ctx, queryText)
-	if err == nil {
-		result.EstimatedCost = cost
-		// Check cost limits
-		if cost > s.getCostLimit(ctx, userRole) {
-			result.Warnings = append(result.Warnings, fmt.Sprintf("Query cost (%v) exceeds recommended limit", cost))
+	// Estimate query cost from the planner
+	plan, err := s.ExplainQuery(ctx, validated.SQL)
+	if err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Cost estimate unavailable: %v", err))
+		return result, nil
+	}
+	result.EstimatedCost = plan.TotalCost
+	result.CostLimit = s.getCostLimit(ctx, userRole)
+	result.Plan = plan
+	result.Admission = AdmissionRun
+	if plan.TotalCost > result.CostLimit {
+		result.Admission = AdmissionRejected
+		if s.asyncQueries != nil {
+			result.Admission = AdmissionAsync
 		}
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Query cost (%.0f) exceeds the limit of %.0f", plan.TotalCost, result.CostLimit))
 	}
 
 	return result, nil
@@ -70,37 +99,42 @@ func (s *GovernanceService) ValidateQuery(ctx context.Context, queryText, userRo
 type QueryValidationResult struct {
 	Allowed        bool
 	BlockedReason  string
+	Violations     []SQLViolation // Rules broken by the query, when blocked by the validator
+	SQL            string         // Statement to execute, with the row limit applied
 	Warnings       []string
 	EstimatedCost  float64
+	CostLimit      float64
+	Plan           *PlanEstimate // EXPLAIN estimate with plan hotspots
+	Admission      string        // Decision the query would get: run, async or rejected
 }
 
-/* isReadOnlyMode checks if user role is in read-only mode */
-func (s *GovernanceService) isReadOnlyMode(ctx context.Context, role string) bool {
-	query := `SELECT read_only FROM neuronip.sandbox_roles WHERE role_name = $1`
-	var readOnly bool
-	err := s.pool.QueryRow(ctx, query, role).Scan(&readOnly)
+/* policyForRole builds the SQL policy for a role from the default policy, the function allow-list and sandbox settings */
+func (s *GovernanceService) policyForRole(ctx context.Context, role string) SQLPolicy {
+	policy := DefaultSQLPolicy()
+	policy.DeniedFunctions = append(policy.DeniedFunctions, s.deniedFunctionsForRole(ctx, role)...)
+
+	query := `
+		SELECT max_result_rows, allowed_schemas, blocked_schemas
+		FROM neuronip.sandbox_roles
+		WHERE role_name = $1
+	`
+	var maxRows *int
+	var allowedSchemas, blockedSchemas []string
+	err := s.pool.QueryRow(ctx, query, role).Scan(&maxRows, &allowedSchemas, &blockedSchemas)
 	if err != nil {
-		return false
+		return policy
 	}
-	return readOnly
-}
 
-/* containsWriteOperations checks if query contains write operations */
-func (s *GovernanceService) containsWriteOperations(queryText string) bool {
-	upperQuery := strings.ToUpper(queryText)
-	writeOps := []string{"INSERT", "UPDATE", "DELETE", "DROP", "CREATE", "ALTER", "TRUNCATE"}
-	for _, op := range writeOps {
-		if strings.Contains(upperQuery, op) {
-			return true
-		}
+	policy.DeniedSchemas = append(policy.DeniedSchemas, blockedSchemas...)
+	policy.AllowedSchemas = allowedSchemas
+	if maxRows != nil && *maxRows > 0 && *maxRows < policy.MaxRows {
+		policy.MaxRows = *maxRows
 	}
-	return false
+	return policy
 }
 
-/* checkFunctionAllowList checks if query uses only allowed functions */
-func (s *GovernanceService) checkFunctionAllowList(ctx context.Context, queryText, role string) (bool, string) {
-	// Extract function names from query (simplified)
-	// In production, use proper SQL parser
+/* deniedFunctionsForRole returns functions from the allow-list that are not granted to the role */
+func (s *GovernanceService) deniedFunctionsForRole(ctx context.Context, role string) []string {
 	query := `
 		SELECT function_name, allowed_for_roles
 		FROM neuronip.allowed_functions
@@ -108,120 +142,30 @@ func (s *GovernanceService) checkFunctionAllowList(ctx context.Context, queryTex
 	`
 	rows, err := s.pool.Query(ctx, query)
 	if err != nil {
-		return true, "" // Allow if check fails
+		return nil // Fall back to the default deny list if the allow-list is unavailable
 	}
 	defer rows.Close()
 
-	allowedFunctions := make(map[string]bool)
+	var denied []string
 	for rows.Next() {
 		var funcName string
 		var allowedRoles []string
-		rows.Scan(&funcName, &allowedRoles)
-		// Check if role is allowed
-		allowed := len(allowedRoles) == 0 // Empty means all roles
+		if err := rows.Scan(&funcName, &allowedRoles); err != nil {
+			continue
+		}
+		// Empty means all roles
+		allowed := len(allowedRoles) == 0
 		for _, r := range allowedRoles {
 			if r == role {
 				allowed = true
 				break
 			}
 		}
-		allowedFunctions[strings.ToUpper(funcName)] = allowed
-	}
-
-	// Check query for disallowed functions (simplified check)
-	upperQuery := strings.ToUpper(queryText)
-	for funcName, allowed := range allowedFunctions {
-		if !allowed && strings.Contains(upperQuery, funcName+"(") {
-			return false, fmt.Sprintf("Function %s is not allowed for role %s", funcName, role)
-		}
-	}
-
-	return true, ""
-}
-
-/* isSandboxRole checks if role is a sandbox role */
-func (s *GovernanceService) isSandboxRole(ctx context.Context, role string) bool {
-	query := `SELECT COUNT(*) FROM neuronip.sandbox_roles WHERE role_name = $1`
-	var count int
-	s.pool.QueryRow(ctx, query, role).Scan(&count)
-	return count > 0
-}
-
-/* checkSandboxRestrictions checks sandbox role restrictions */
-func (s *GovernanceService) checkSandboxRestrictions(ctx context.Context, queryText, role string) *QueryValidationResult {
-	result := &QueryValidationResult{Allowed: true, Warnings: []string{}}
-
-	query := `
-		SELECT max_query_time_seconds, max_result_rows, max_query_cost,
-		       allowed_schemas, blocked_schemas, read_only
-		FROM neuronip.sandbox_roles
-		WHERE role_name = $1
-	`
-	var maxTime, maxRows int
-	var maxCost *float64
-	var allowedSchemas, blockedSchemas []string
-	var readOnly bool
-
-	err := s.pool.QueryRow(ctx, query, role).Scan(
-		&maxTime, &maxRows, &maxCost, &allowedSchemas, &blockedSchemas, &readOnly,
-	)
-	if err != nil {
-		return result
-	}
-
-	// Check schema access
-	if len(blockedSchemas) > 0 {
-		for _, schema := range blockedSchemas {
-			if strings.Contains(strings.ToUpper(queryText), strings.ToUpper(schema)+".") {
-				result.Allowed = false
-				result.BlockedReason = fmt.Sprintf("Access to schema %s is blocked for sandbox role", schema)
-				return result
-			}
-		}
-	}
-
-	if len(allowedSchemas) > 0 {
-		hasAllowedSchema := false
-		for _, schema := range allowedSchemas {
-			if strings.Contains(strings.ToUpper(queryText), strings.ToUpper(schema)+".") {
-				hasAllowedSchema = true
-				break
-			}
-		}
-		if !hasAllowedSchema {
-			result.Warnings = append(result.Warnings, "Query may access schemas outside allowed list")
+		if !allowed {
+			denied = append(denied, funcName)
 		}
 	}
-
-	// Check read-only
-	if readOnly && s.containsWriteOperations(queryText) {
-		result.Allowed = false
-		result.BlockedReason = "Sandbox role is read-only"
-		return result
-	}
-
-	return result
-}
-
-/* estimateQueryCost estimates query execution cost */
-func (s *GovernanceService) estimateQueryCost(ctx context.Context, queryText string) (float64, error) {
-	// Simplified cost estimation
-	// In production, use EXPLAIN to get actual cost estimate
-	words := strings.Fields(queryText)
-	complexity := float64(len(words))
-	
-	// Check for expensive operations
-	if strings.Contains(strings.ToUpper(queryText), "JOIN") {
-		complexity *= 1.5
-	}
-	if strings.Contains(strings.ToUpper(queryText), "GROUP BY") {
-		complexity *= 1.3
-	}
-	if strings.Contains(strings.ToUpper(queryText), "ORDER BY") {
-		complexity *= 1.2
-	}
-
-	return complexity, nil
+	return denied
 }
 
 /* getCostLimit gets cost limit for a role */
@@ -232,7 +176,7 @@ func (s *GovernanceService) getCostLimit(ctx context.Context, role string) float
 	if maxCost != nil {
 		return *maxCost
 	}
-	return 1000.0 // Default limit
+	return s.admission.DefaultCostLimit
 }
 
 /* SanitizeQuery sanitizes a query for safe execution */
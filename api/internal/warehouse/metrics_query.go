@@ -0,0 +1,102 @@
+package warehouse
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/neurondb/NeuronIP/api/internal/catalog"
+)
+
+/* generateSQL converts a question to SQL.
+ * Questions fully understood in terms of approved metrics are compiled by the semantic layer, which also
+ * returns a description of the compiled query; other questions go to NeuronAgent, with the approved metric
+ * definitions as context when the question names a metric. */
+func (s *Service) generateSQL(ctx context.Context, question string, schemaMetadata map[string]interface{}) (string, map[string]interface{}, error) {
+	if s.metrics != nil {
+		metrics, err := s.metrics.ListApprovedDefinitions(ctx)
+		if err == nil && len(metrics) > 0 {
+			q, mentioned := catalog.ParseMetricQuestion(question, metrics)
+			if q != nil {
+				compiled, err := catalog.CompileMetrics(metrics, *q)
+				if err == nil {
+					return compiled.SQL, semanticLayerMetadata(*q, compiled), nil
+				}
+			}
+			if mentioned {
+				withMetrics := make(map[string]interface{}, len(schemaMetadata)+1)
+				for k, v := range schemaMetadata {
+					withMetrics[k] = v
+				}
+				withMetrics["metrics"] = metricDefinitionsContext(metrics)
+				schemaMetadata = withMetrics
+			}
+		}
+	}
+
+	generatedSQL, err := s.agentClient.ConvertNLToSQL(ctx, question, schemaMetadata)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to convert NL to SQL: %w", err)
+	}
+	return generatedSQL, nil, nil
+}
+
+/* CompileMetricQuery compiles a metric query without running it */
+func (s *Service) CompileMetricQuery(ctx context.Context, q catalog.MetricQuery) (*catalog.CompiledMetricQuery, error) {
+	if s.metrics == nil {
+		return nil, fmt.Errorf("metric semantic layer is not configured")
+	}
+	return s.metrics.CompileMetricQuery(ctx, q)
+}
+
+/* ExecuteMetricQuery compiles a metric query and runs it like any other warehouse query */
+func (s *Service) ExecuteMetricQuery(ctx context.Context, q catalog.MetricQuery, userID *string) (*QueryResponse, error) {
+	compiled, err := s.CompileMetricQuery(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	validated, err := ValidateSQL(compiled.SQL, s.sqlPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SQL: %w", err)
+	}
+
+	req := QueryRequest{
+		Query:  "metrics: " + strings.Join(compiled.Metrics, ", "),
+		UserID: userID,
+	}
+	if len(compiled.Dimensions) > 0 {
+		req.Query += " by " + strings.Join(compiled.Dimensions, ", ")
+	}
+
+	return s.executeValidatedQuery(ctx, req, validated, map[string]interface{}{
+		"semantic_layer": semanticLayerMetadata(q, compiled),
+	})
+}
+
+/* semanticLayerMetadata describes a compiled metric query in query response metadata */
+func semanticLayerMetadata(q catalog.MetricQuery, compiled *catalog.CompiledMetricQuery) map[string]interface{} {
+	return map[string]interface{}{
+		"metric_query": q,
+		"metrics":      compiled.Metrics,
+		"dimensions":   compiled.Dimensions,
+		"tables":       compiled.Tables,
+	}
+}
+
+/* metricDefinitionsContext describes approved metrics for NL-to-SQL conversion */
+func metricDefinitionsContext(metrics []catalog.Metric) []map[string]interface{} {
+	definitions := make([]map[string]interface{}, 0, len(metrics))
+	for _, metric := range metrics {
+		entry := map[string]interface{}{
+			"name":         metric.Name,
+			"display_name": metric.DisplayName,
+			"definition":   metric.Definition,
+		}
+		if metric.Description != nil {
+			entry["description"] = *metric.Description
+		}
+		definitions = append(definitions, entry)
+	}
+	return definitions
+}
@@ -12,7 +12,8 @@ import (
 
 /* SavedSearchService provides saved search management */
 type SavedSearchService struct {
-	pool *pgxpool.Pool
+	pool      *pgxpool.Pool
+	templates *QueryTemplateService
 }
 
 /* NewSavedSearchService creates a new saved search service */
@@ -20,6 +21,11 @@ func NewSavedSearchService(pool *pgxpool.Pool) *SavedSearchService {
 	return &SavedSearchService{pool: pool}
 }
 
+/* NewSavedSearchServiceWithTemplates creates a saved search service that can run template-backed searches */
+func NewSavedSearchServiceWithTemplates(pool *pgxpool.Pool, templates *QueryTemplateService) *SavedSearchService {
+	return &SavedSearchService{pool: pool, templates: templates}
+}
+
 /* SavedSearch represents a saved hybrid search */
 type SavedSearch struct {
 	ID            uuid.UUID              `json:"id"`
@@ -36,6 +42,8 @@ type SavedSearch struct {
 	IsPublic      bool                   `json:"is_public"`
 	OwnerID       *string                `json:"owner_id,omitempty"`
 	Tags          []string               `json:"tags,omitempty"`
+	TemplateID    *uuid.UUID             `json:"template_id,omitempty"`     // Query template run instead of the natural language query
+	TemplateParams map[string]interface{} `json:"template_params,omitempty"` // Stored template parameter values
 	CreatedAt     time.Time              `json:"created_at"`
 	UpdatedAt     time.Time              `json:"updated_at"`
 }
@@ -44,13 +52,15 @@ type SavedSearch struct {
 func (s *SavedSearchService) CreateSavedSearch(ctx context.Context, search SavedSearch) (*SavedSearch, error) {
 	searchID := uuid.New()
 	sqlFiltersJSON, _ := json.Marshal(search.SQLFilters)
+	templateParamsJSON, _ := json.Marshal(search.TemplateParams)
 
 	query := `
 		INSERT INTO neuronip.saved_searches (
 			id, name, description, query, semantic_query, schema_id,
 			sql_filters, semantic_table, semantic_column, limit_count,
-			threshold, is_public, owner_id, tags, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW(), NOW())
+			threshold, is_public, owner_id, tags, template_id, template_params,
+			created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NOW(), NOW())
 		RETURNING created_at, updated_at
 	`
 	var createdAt, updatedAt time.Time
@@ -58,6 +68,7 @@ func (s *SavedSearchService) CreateSavedSearch(ctx context.Context, search Saved
 		searchID, search.Name, search.Description, search.Query, search.SemanticQuery,
 		search.SchemaID, sqlFiltersJSON, search.SemanticTable, search.SemanticColumn,
 		search.Limit, search.Threshold, search.IsPublic, search.OwnerID, search.Tags,
+		search.TemplateID, templateParamsJSON,
 	).Scan(&createdAt, &updatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create saved search: %w", err)
@@ -74,12 +85,13 @@ func (s *SavedSearchService) GetSavedSearch(ctx context.Context, searchID uuid.U
 	query := `
 		SELECT id, name, description, query, semantic_query, schema_id,
 		       sql_filters, semantic_table, semantic_column, limit_count,
-		       threshold, is_public, owner_id, tags, created_at, updated_at
+		       threshold, is_public, owner_id, tags, template_id, template_params,
+		       created_at, updated_at
 		FROM neuronip.saved_searches
 		WHERE id = $1
 	`
 	var search SavedSearch
-	var sqlFiltersJSON []byte
+	var sqlFiltersJSON, templateParamsJSON []byte
 	var semanticQuery, description, ownerID *string
 	var schemaID *uuid.UUID
 
@@ -87,13 +99,14 @@ func (s *SavedSearchService) GetSavedSearch(ctx context.Context, searchID uuid.U
 		&search.ID, &search.Name, &description, &search.Query, &semanticQuery,
 		&schemaID, &sqlFiltersJSON, &search.SemanticTable, &search.SemanticColumn,
 		&search.Limit, &search.Threshold, &search.IsPublic, &ownerID, &search.Tags,
-		&search.CreatedAt, &search.UpdatedAt,
+		&search.TemplateID, &templateParamsJSON, &search.CreatedAt, &search.UpdatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("saved search not found: %w", err)
 	}
 
 	json.Unmarshal(sqlFiltersJSON, &search.SQLFilters)
+	json.Unmarshal(templateParamsJSON, &search.TemplateParams)
 	search.SemanticQuery = semanticQuery
 	search.Description = description
 	search.SchemaID = schemaID
@@ -111,7 +124,8 @@ func (s *SavedSearchService) ListSavedSearches(ctx context.Context, userID *stri
 		query = `
 			SELECT id, name, description, query, semantic_query, schema_id,
 			       sql_filters, semantic_table, semantic_column, limit_count,
-			       threshold, is_public, owner_id, tags, created_at, updated_at
+			       threshold, is_public, owner_id, tags, template_id, template_params,
+			       created_at, updated_at
 			FROM neuronip.saved_searches
 			WHERE is_public = true
 			ORDER BY created_at DESC
@@ -121,7 +135,8 @@ func (s *SavedSearchService) ListSavedSearches(ctx context.Context, userID *stri
 		query = `
 			SELECT id, name, description, query, semantic_query, schema_id,
 			       sql_filters, semantic_table, semantic_column, limit_count,
-			       threshold, is_public, owner_id, tags, created_at, updated_at
+			       threshold, is_public, owner_id, tags, template_id, template_params,
+			       created_at, updated_at
 			FROM neuronip.saved_searches
 			WHERE owner_id = $1 OR is_public = true
 			ORDER BY created_at DESC
@@ -131,7 +146,8 @@ func (s *SavedSearchService) ListSavedSearches(ctx context.Context, userID *stri
 		query = `
 			SELECT id, name, description, query, semantic_query, schema_id,
 			       sql_filters, semantic_table, semantic_column, limit_count,
-			       threshold, is_public, owner_id, tags, created_at, updated_at
+			       threshold, is_public, owner_id, tags, template_id, template_params,
+			       created_at, updated_at
 			FROM neuronip.saved_searches
 			ORDER BY created_at DESC
 		`
@@ -147,7 +163,7 @@ func (s *SavedSearchService) ListSavedSearches(ctx context.Context, userID *stri
 	var searches []SavedSearch
 	for rows.Next() {
 		var search SavedSearch
-		var sqlFiltersJSON []byte
+		var sqlFiltersJSON, templateParamsJSON []byte
 		var semanticQuery, description, ownerID *string
 		var schemaID *uuid.UUID
 
@@ -155,13 +171,14 @@ func (s *SavedSearchService) ListSavedSearches(ctx context.Context, userID *stri
 			&search.ID, &search.Name, &description, &search.Query, &semanticQuery,
 			&schemaID, &sqlFiltersJSON, &search.SemanticTable, &search.SemanticColumn,
 			&search.Limit, &search.Threshold, &search.IsPublic, &ownerID, &search.Tags,
-			&search.CreatedAt, &search.UpdatedAt,
+			&search.TemplateID, &templateParamsJSON, &search.CreatedAt, &search.UpdatedAt,
 		)
 		if err != nil {
 			continue
 		}
 
 		json.Unmarshal(sqlFiltersJSON, &search.SQLFilters)
+		json.Unmarshal(templateParamsJSON, &search.TemplateParams)
 		search.SemanticQuery = semanticQuery
 		search.Description = description
 		search.SchemaID = schemaID
@@ -175,11 +192,20 @@ func (s *SavedSearchService) ListSavedSearches(ctx context.Context, userID *stri
 
 /* ExecuteSavedSearch executes a saved search */
 func (s *SavedSearchService) ExecuteSavedSearch(ctx context.Context, searchID uuid.UUID, service *Service) (*HybridSearchResponse, error) {
+	return s.ExecuteSavedSearchWithParams(ctx, searchID, service, nil)
+}
+
+/* ExecuteSavedSearchWithParams executes a saved search; for template-backed searches, params override the stored parameter values */
+func (s *SavedSearchService) ExecuteSavedSearchWithParams(ctx context.Context, searchID uuid.UUID, service *Service, params map[string]interface{}) (*HybridSearchResponse, error) {
 	search, err := s.GetSavedSearch(ctx, searchID)
 	if err != nil {
 		return nil, err
 	}
 
+	if search.TemplateID != nil {
+		return s.executeTemplateSearch(ctx, search, params)
+	}
+
 	req := HybridSearchRequest{
 		Query:         search.Query,
 		SemanticQuery: "",
@@ -203,22 +229,52 @@ func (s *SavedSearchService) ExecuteSavedSearch(ctx context.Context, searchID uu
 	return service.ExecuteHybridSearch(ctx, req)
 }
 
+/* executeTemplateSearch runs the template a saved search references with its stored parameter values */
+func (s *SavedSearchService) executeTemplateSearch(ctx context.Context, search *SavedSearch, params map[string]interface{}) (*HybridSearchResponse, error) {
+	if s.templates == nil {
+		return nil, fmt.Errorf("saved search %s references a query template but templates are not configured", search.ID)
+	}
+
+	values := make(map[string]interface{}, len(search.TemplateParams)+len(params))
+	for name, value := range search.TemplateParams {
+		values[name] = value
+	}
+	for name, value := range params {
+		values[name] = value
+	}
+
+	result, err := s.templates.ExecuteTemplate(ctx, *search.TemplateID, search.OwnerID, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HybridSearchResponse{
+		QueryID:         uuid.New(),
+		SQLResults:      result.Rows,
+		CombinedResults: result.Rows,
+		Explanation:     fmt.Sprintf("Saved search '%s' ran its query template and returned %d rows.", search.Name, result.RowCount),
+	}, nil
+}
+
 /* UpdateSavedSearch updates a saved search */
 func (s *SavedSearchService) UpdateSavedSearch(ctx context.Context, searchID uuid.UUID, search SavedSearch) error {
 	sqlFiltersJSON, _ := json.Marshal(search.SQLFilters)
+	templateParamsJSON, _ := json.Marshal(search.TemplateParams)
 
 	query := `
 		UPDATE neuronip.saved_searches
 		SET name = $1, description = $2, query = $3, semantic_query = $4,
 		    schema_id = $5, sql_filters = $6, semantic_table = $7,
 		    semantic_column = $8, limit_count = $9, threshold = $10,
-		    is_public = $11, tags = $12, updated_at = NOW()
-		WHERE id = $13
+		    is_public = $11, tags = $12, template_id = $13, template_params = $14,
+		    updated_at = NOW()
+		WHERE id = $15
 	`
 	_, err := s.pool.Exec(ctx, query,
 		search.Name, search.Description, search.Query, search.SemanticQuery,
 		search.SchemaID, sqlFiltersJSON, search.SemanticTable, search.SemanticColumn,
-		search.Limit, search.Threshold, search.IsPublic, search.Tags, searchID,
+		search.Limit, search.Threshold, search.IsPublic, search.Tags,
+		search.TemplateID, templateParamsJSON, searchID,
 	)
 	return err
 }
@@ -12,8 +12,11 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/neurondb/NeuronIP/api/internal/agent"
+	"github.com/neurondb/NeuronIP/api/internal/cache"
+	"github.com/neurondb/NeuronIP/api/internal/catalog"
 	"github.com/neurondb/NeuronIP/api/internal/mcp"
 	"github.com/neurondb/NeuronIP/api/internal/neurondb"
+	"github.com/neurondb/NeuronIP/api/internal/warehouse/federation"
 )
 
 /* Service provides data warehouse Q&A functionality */
@@ -22,6 +25,14 @@ type Service struct {
 	agentClient    *agent.Client
 	neurondbClient *neurondb.Client
 	mcpClient      *mcp.Client
+	semanticCache  *cache.SemanticCacheService
+	sqlPolicy      SQLPolicy
+	resultLimits   ResultLimits
+	cursors        *resultCursors
+	governance     *GovernanceService
+	metrics        *catalog.MetricsService
+	correction     CorrectionConfig
+	federation     *federation.Service
 }
 
 /* NewService creates a new warehouse service */
@@ -31,9 +42,39 @@ func NewService(pool *pgxpool.Pool, agentClient *agent.Client, neurondbClient *n
 		agentClient:    agentClient,
 		neurondbClient: neurondbClient,
 		mcpClient:      mcpClient,
+		sqlPolicy:      DefaultSQLPolicy(),
+		resultLimits:   DefaultResultLimits(),
+		cursors:        newResultCursors(maxOpenCursorsForPool(pool)),
+		correction:     DefaultCorrectionConfig(),
 	}
 }
 
+/* ServiceOptions configures the optional parts of a warehouse service; zero values leave the defaults in place */
+type ServiceOptions struct {
+	SemanticCache *cache.SemanticCacheService // Serves repeated questions from the semantic answer cache
+	ResultLimits  *ResultLimits               // Limits for paginated and streamed results
+	Governance    *GovernanceService          // Admits queries by their EXPLAIN cost
+	Metrics       *catalog.MetricsService     // Compiles questions about approved catalog metrics to SQL
+	Correction    *CorrectionConfig           // Bounds on NL-to-SQL correction
+	Federation    *federation.Service         // Runs queries joining tables across data source connectors
+}
+
+/* NewServiceWithOptions creates a warehouse service with the given optional components */
+func NewServiceWithOptions(pool *pgxpool.Pool, agentClient *agent.Client, neurondbClient *neurondb.Client, mcpClient *mcp.Client, opts ServiceOptions) *Service {
+	svc := NewService(pool, agentClient, neurondbClient, mcpClient)
+	svc.semanticCache = opts.SemanticCache
+	if opts.ResultLimits != nil {
+		svc.resultLimits = normalizeResultLimits(*opts.ResultLimits)
+	}
+	svc.governance = opts.Governance
+	svc.metrics = opts.Metrics
+	if opts.Correction != nil && opts.Correction.MaxAttempts > 0 {
+		svc.correction = *opts.Correction
+	}
+	svc.federation = opts.Federation
+	return svc
+}
+
 /* QueryRequest represents a natural language query */
 type QueryRequest struct {
 	Query         string
@@ -41,6 +82,11 @@ type QueryRequest struct {
 	UserID        *string
 	SemanticQuery *string                // Optional semantic similarity search
 	SQLFilters    map[string]interface{} // Optional SQL filter conditions
+	BypassCache   bool                   // Skip the semantic answer cache
+
+	correctionID *uuid.UUID // Question this attempt belongs to, set by the correction loop
+	attempt      int
+	queryID      *uuid.UUID // Query history ID assigned before execution
 }
 
 /* QueryResponse represents the query response */
@@ -51,6 +97,10 @@ type QueryResponse struct {
 	Explanation string                   `json:"explanation"`
 	ChartConfig map[string]interface{}   `json:"chart_config,omitempty"`
 	ChartType   string                   `json:"chart_type,omitempty"`
+	Charts      []ChartRecommendation    `json:"charts,omitempty"` // Ranked Vega-Lite alternatives; ChartConfig is the first
+	Metadata    map[string]interface{}   `json:"metadata,omitempty"`
+	Cache       *cache.HitInfo           `json:"cache,omitempty"`
+	Admission   *AdmissionResult         `json:"admission,omitempty"`
 }
 
 /* Schema represents a warehouse schema */
@@ -58,6 +108,7 @@ type Schema struct {
 	ID           uuid.UUID                `json:"id"`
 	SchemaName   string                   `json:"schema_name"`
 	DatabaseName string                   `json:"database_name"`
+	ConnectorID  *uuid.UUID               `json:"connector_id,omitempty"` // Connector the schema describes; its profiling results inform conversion
 	Description  *string                  `json:"description,omitempty"`
 	Tables       []map[string]interface{} `json:"tables"`
 	LastSyncedAt *time.Time               `json:"last_synced_at,omitempty"`
@@ -68,70 +119,165 @@ type Schema struct {
 /* ExecuteQuery executes a natural language query */
 func (s *Service) ExecuteQuery(ctx context.Context, req QueryRequest) (*QueryResponse, error) {
 	// Get schema if provided
-	var schema *Schema
-	var schemaMetadata map[string]interface{}
-	if req.SchemaID != nil {
-		s, err := s.GetSchema(ctx, *req.SchemaID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get schema: %w", err)
+	schemaMetadata, err := s.schemaContext(ctx, req.SchemaID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Serve semantically equivalent questions from the cache within the caller's permission scope
+	useCache := s.semanticCache.Enabled() && s.neurondbClient != nil && !req.BypassCache
+	var scopeKey, partition, questionEmbedding string
+	if useCache {
+		embedding, err := s.neurondbClient.GenerateEmbedding(ctx, req.Query, "sentence-transformers/all-MiniLM-L6-v2")
+		if err == nil {
+			// An unresolved permission scope skips the cache instead of sharing a looser key
+			scopeKey, err = s.semanticCache.ScopeKey(ctx, req.UserID)
 		}
-		schema = s
-		schemaMetadata = map[string]interface{}{
-			"schema_name":   schema.SchemaName,
-			"database_name": schema.DatabaseName,
-			"tables":        schema.Tables,
+		if err != nil {
+			useCache = false
+		} else {
+			questionEmbedding = embedding
+			if req.SchemaID != nil {
+				partition = req.SchemaID.String()
+			}
+			var cached QueryResponse
+			hit, err := s.semanticCache.Lookup(ctx, cache.NamespaceWarehouse, scopeKey, partition, questionEmbedding, &cached)
+			if err == nil && hit != nil {
+				return s.serveCachedQuery(ctx, req, &cached, hit)
+			}
 		}
 	}
 
-	// Convert NL to SQL using NeuronAgent
-	generatedSQL, err := s.agentClient.ConvertNLToSQL(ctx, req.Query, schemaMetadata)
+	response, validated, err := s.executeWithCorrection(ctx, req, schemaMetadata)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert NL to SQL: %w", err)
+		return nil, err
+	}
+
+	// Caching is best effort; queries handed to async execution have no results to cache
+	if useCache && (response.Admission == nil || response.Admission.Decision == AdmissionRun) {
+		s.semanticCache.Store(ctx, cache.NamespaceWarehouse, scopeKey, partition, req.Query, questionEmbedding, response,
+			cache.CacheEntrySources{Tables: validated.Tables})
 	}
 
-	// Validate SQL syntax (basic validation)
-	if err := s.validateSQL(generatedSQL); err != nil {
-		return nil, fmt.Errorf("invalid SQL: %w", err)
+	return response, nil
+}
+
+/* serveCachedQuery answers a query from a cached response under a query record of its own,
+ * so the requester never sees or acts on another user's query ID */
+func (s *Service) serveCachedQuery(ctx context.Context, req QueryRequest, cached *QueryResponse, hit *cache.HitInfo) (*QueryResponse, error) {
+	startedAt := time.Now()
+	queryID, err := s.createQueryRecord(ctx, req, cached.SQL, startedAt)
+	if err != nil {
+		return nil, err
+	}
+	s.finishResultQuery(queryID, startedAt, int64(len(cached.Results)), nil)
+
+	cached.QueryID = queryID
+	cached.Cache = hit
+	cached.Admission = nil // Cache hits use no execution slot
+	return cached, nil
+}
+
+/* executeWithCorrection converts the question to SQL and runs it, regenerating the query with the failure as
+ * feedback when it does not parse, plan or run, or when generated SQL returns no rows, up to the configured number of attempts */
+func (s *Service) executeWithCorrection(ctx context.Context, req QueryRequest, schemaMetadata map[string]interface{}) (*QueryResponse, *ValidatedSQL, error) {
+	c := s.newSQLCorrection(req, schemaMetadata)
+	var emptyResponse *QueryResponse
+	var emptyValidated *ValidatedSQL
+
+	for {
+		// Questions about approved metrics compile through the semantic layer; the validated statement carries the row cap
+		validated, semanticLayer, err := s.prepareSQL(ctx, c, s.sqlPolicy)
+		if err != nil {
+			if emptyResponse != nil && AsSQLCorrectionError(err) != nil {
+				// No correction did better than the query that ran and returned nothing
+				emptyResponse.Metadata["sql_attempts"] = c.attempts
+				return emptyResponse, emptyValidated, nil
+			}
+			return nil, nil, err
+		}
+
+		metadata := make(map[string]interface{})
+		if semanticLayer != nil {
+			metadata["semantic_layer"] = semanticLayer
+		}
+		attemptReq := c.request()
+		response, err := s.executeValidatedQuery(ctx, attemptReq, validated, metadata)
+		if err != nil {
+			s.recordAttempt(ctx, c, validated.SQL, AttemptStageExecution, err, attemptReq.queryID)
+			if !isCorrectableSQLError(err) {
+				return nil, nil, err
+			}
+			if !c.remaining() {
+				return nil, nil, c.failure()
+			}
+			continue
+		}
+
+		if c.retriesEmpty(response, semanticLayer != nil) {
+			s.recordAttempt(ctx, c, validated.SQL, AttemptStageEmptyResult, fmt.Errorf("query returned no rows"), &response.QueryID)
+			emptyResponse, emptyValidated = response, validated
+			continue
+		}
+
+		s.recordAttempt(ctx, c, validated.SQL, AttemptStageSucceeded, nil, &response.QueryID)
+		response.Metadata["sql_attempts"] = c.attempts
+		return response, validated, nil
 	}
+}
+
+/* executeValidatedQuery admits, runs and records a validated query and explains its results */
+func (s *Service) executeValidatedQuery(ctx context.Context, req QueryRequest, validated *ValidatedSQL, metadata map[string]interface{}) (*QueryResponse, error) {
+	generatedSQL := validated.SQL
 
 	// Use MCP PostgreSQL query optimization if available
 	if s.mcpClient != nil {
-		// Use MCP PostgreSQL tools for query optimization and plan analysis
-		if s.mcpClient != nil {
-			// Get query plan
-			plan, err := s.mcpClient.PostgreSQLQueryPlan(ctx, generatedSQL)
-			if err == nil && plan != nil {
-				// Store plan in metadata
-				if metadata == nil {
-					metadata = make(map[string]interface{})
-				}
-				metadata["query_plan"] = plan
-			}
+		// Get query plan
+		plan, err := s.mcpClient.PostgreSQLQueryPlan(ctx, generatedSQL)
+		if err == nil && plan != nil {
+			// Store plan in metadata
+			metadata["query_plan"] = plan
+		}
 
-			// Get optimization suggestions
-			optimization, err := s.mcpClient.PostgreSQLQueryOptimization(ctx, generatedSQL)
+		// Get optimization suggestions
+		optimization, err := s.mcpClient.PostgreSQLQueryOptimization(ctx, generatedSQL)
 		if err == nil {
-			// Log optimization suggestions (could be used to improve query)
 			if suggestions, ok := optimization["suggestions"].([]interface{}); ok && len(suggestions) > 0 {
-				// Store optimization suggestions (could be added to response metadata)
+				metadata["optimization_suggestions"] = suggestions
 			}
 		}
 	}
 
 	// Create warehouse query record
-	queryID := uuid.New()
 	now := time.Now()
-	insertQuery := `
-		INSERT INTO neuronip.warehouse_queries 
-		(id, user_id, natural_language_query, generated_sql, schema_id, status, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id`
-
-	err = s.pool.QueryRow(ctx, insertQuery,
-		queryID, req.UserID, req.Query, generatedSQL, req.SchemaID, "executing", now,
-	).Scan(&queryID)
+	queryID, err := s.createQueryRecord(ctx, req, generatedSQL, now)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create query record: %w", err)
+		return nil, err
+	}
+
+	// Admit the query by its planned cost; queries over the limit continue asynchronously
+	var admission *AdmissionResult
+	if s.governance != nil {
+		var release func()
+		admission, release, err = s.governance.AdmitQuery(ctx, generatedSQL, validated.plan, req.UserID, map[string]interface{}{
+			"warehouse_query_id": queryID.String(),
+		})
+		if err != nil {
+			s.pool.Exec(ctx, `UPDATE neuronip.warehouse_queries SET status = $1, error_message = $2, executed_at = $3 WHERE id = $4`,
+				"failed", err.Error(), time.Now(), queryID)
+			return nil, err
+		}
+		if admission.Decision == AdmissionAsync {
+			s.pool.Exec(ctx, `UPDATE neuronip.warehouse_queries SET status = $1 WHERE id = $2`, "pending", queryID)
+			return &QueryResponse{
+				QueryID:     queryID,
+				SQL:         generatedSQL,
+				Explanation: admission.Reason,
+				Metadata:    metadata,
+				Admission:   admission,
+			}, nil
+		}
+		defer release()
 	}
 
 	// Execute SQL with timeout - use MCP if available for better execution
@@ -139,7 +285,7 @@ func (s *Service) ExecuteQuery(ctx context.Context, req QueryRequest) (*QueryRes
 	defer cancel()
 
 	var results []map[string]interface{}
-	var execErr error
+	executed := false
 
 	// Try MCP PostgreSQLExecuteQuery first if available
 	if s.mcpClient != nil {
@@ -161,42 +307,24 @@ func (s *Service) ExecuteQuery(ctx context.Context, req QueryRequest) (*QueryRes
 					}
 				}
 			}
-			// If we got results from MCP, skip direct execution
-			if len(results) > 0 || execErr == nil {
-				// Use MCP results or continue with empty results
-				goto processResults
-			}
+			// If MCP executed the query, skip direct execution
+			executed = true
 		}
 		// Fall through to direct execution if MCP fails
 	}
 
 	// Direct SQL execution (fallback or primary method)
-	rows, execErr := s.pool.Query(execCtx, generatedSQL)
-	if execErr != nil {
-		// Update query status to failed
-		s.pool.Exec(ctx, `UPDATE neuronip.warehouse_queries SET status = $1, error_message = $2, executed_at = $3 WHERE id = $4`,
-			"failed", execErr.Error(), time.Now(), queryID)
-		return nil, fmt.Errorf("failed to execute SQL: %w", execErr)
-	}
-	defer rows.Close()
-
-	// Parse results from direct execution
-	fieldDescriptions := rows.FieldDescriptions()
-	for rows.Next() {
-		values, err := rows.Values()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get row values: %w", err)
-		}
-
-		row := make(map[string]interface{})
-		for i, desc := range fieldDescriptions {
-			row[desc.Name] = values[i]
+	if !executed {
+		rows, execErr := s.queryReadOnly(execCtx, generatedSQL)
+		if execErr != nil {
+			// Update query status to failed
+			s.pool.Exec(ctx, `UPDATE neuronip.warehouse_queries SET status = $1, error_message = $2, executed_at = $3 WHERE id = $4`,
+				"failed", execErr.Error(), time.Now(), queryID)
+			return nil, execErr
 		}
-		results = append(results, row)
+		results = rows
 	}
 
-processResults:
-
 	// Update query status to completed
 	executedAt := time.Now()
 	s.pool.Exec(ctx, `UPDATE neuronip.warehouse_queries SET status = $1, executed_at = $2 WHERE id = $3`,
@@ -211,14 +339,16 @@ processResults:
 	executionTimeMs := int(time.Since(now).Milliseconds())
 	s.pool.Exec(ctx, resultInsertQuery, queryID, resultData, len(results), executionTimeMs, time.Now())
 
-	// Generate chart config
-	chartConfig, chartType := s.generateChartConfig(results)
+	// Recommend charts; the best one is also the chart config
+	charts := s.recommendCharts(ctx, generatedSQL, results)
+	chartConfig, chartType := topChart(charts)
 
 	// Update result with chart config
-	if chartConfig != nil {
+	if charts != nil {
 		chartConfigJSON, _ := json.Marshal(chartConfig)
-		s.pool.Exec(ctx, `UPDATE neuronip.query_results SET chart_config = $1, chart_type = $2 WHERE query_id = $3`,
-			chartConfigJSON, chartType, queryID)
+		chartsJSON, _ := json.Marshal(charts)
+		s.pool.Exec(ctx, `UPDATE neuronip.query_results SET chart_config = $1, chart_type = $2, chart_recommendations = $3 WHERE query_id = $4`,
+			chartConfigJSON, chartType, chartsJSON, queryID)
 	}
 
 	// Generate explanation
@@ -241,14 +371,113 @@ processResults:
 		s.pool.Exec(ctx, explanationInsertQuery, queryID, insightExplanation, "insight", time.Now())
 	}
 
-	return &QueryResponse{
+	response := &QueryResponse{
 		QueryID:     queryID,
 		SQL:         generatedSQL,
 		Results:     results,
 		Explanation: explanation,
 		ChartConfig: chartConfig,
 		ChartType:   chartType,
-	}, nil
+		Charts:      charts,
+		Metadata:    metadata,
+		Admission:   admission,
+	}
+
+	return response, nil
+}
+
+/* schemaContext returns the schema description passed to NL-to-SQL conversion, or nil without a schema */
+func (s *Service) schemaContext(ctx context.Context, schemaID *uuid.UUID) (map[string]interface{}, error) {
+	if schemaID == nil {
+		return nil, nil
+	}
+	schema, err := s.GetSchema(ctx, *schemaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema: %w", err)
+	}
+	schemaMetadata := map[string]interface{}{
+		"schema_name":   schema.SchemaName,
+		"database_name": schema.DatabaseName,
+		"tables":        schema.Tables,
+	}
+	// Hints are best effort; conversion works without them
+	if keys, joins := s.structureHints(ctx, schema.ConnectorID, schema.SchemaName); len(keys) > 0 || len(joins) > 0 {
+		schemaMetadata["suggested_keys"] = keys
+		schemaMetadata["suggested_joins"] = joins
+	}
+	return schemaMetadata, nil
+}
+
+/* structureHints returns the candidate keys and unrejected suggested joins that profiling discovered for a
+ * schema's tables, so conversion can pick join columns the schema doesn't declare. Only the schema's own
+ * connector is consulted, since other connectors may have a schema of the same name. */
+func (s *Service) structureHints(ctx context.Context, connectorID *uuid.UUID, schemaName string) (map[string]interface{}, []map[string]interface{}) {
+	keys := map[string]interface{}{}
+	if connectorID == nil {
+		return keys, nil
+	}
+	rows, err := s.pool.Query(ctx, `
+		SELECT table_name, candidate_keys
+		FROM neuronip.table_structures
+		WHERE connector_id = $1 AND schema_name = $2 AND jsonb_array_length(candidate_keys) > 0`, *connectorID, schemaName)
+	if err == nil {
+		for rows.Next() {
+			var table string
+			var keysJSON []byte
+			if rows.Scan(&table, &keysJSON) == nil {
+				var tableKeys []map[string]interface{}
+				json.Unmarshal(keysJSON, &tableKeys)
+				keys[table] = tableKeys
+			}
+		}
+		rows.Close()
+	}
+
+	var joins []map[string]interface{}
+	rows, err = s.pool.Query(ctx, `
+		SELECT from_schema, from_table, from_column, to_schema, to_table, to_column, confidence, status
+		FROM neuronip.suggested_joins
+		WHERE connector_id = $1 AND (from_schema = $2 OR to_schema = $2) AND status <> 'rejected'
+		ORDER BY confidence DESC
+		LIMIT 100`, *connectorID, schemaName)
+	if err == nil {
+		for rows.Next() {
+			var fromSchema, fromTable, fromColumn, toSchema, toTable, toColumn, status string
+			var confidence float64
+			if rows.Scan(&fromSchema, &fromTable, &fromColumn, &toSchema, &toTable, &toColumn, &confidence, &status) == nil {
+				joins = append(joins, map[string]interface{}{
+					"from":       fromSchema + "." + fromTable + "." + fromColumn,
+					"to":         toSchema + "." + toTable + "." + toColumn,
+					"confidence": confidence,
+					"accepted":   status == "accepted",
+				})
+			}
+		}
+		rows.Close()
+	}
+	return keys, joins
+}
+
+/* createQueryRecord stores a query in the executing state */
+func (s *Service) createQueryRecord(ctx context.Context, req QueryRequest, generatedSQL string, createdAt time.Time) (uuid.UUID, error) {
+	queryID := uuid.New()
+	if req.queryID != nil {
+		queryID = *req.queryID
+	}
+	insertQuery := `
+		INSERT INTO neuronip.warehouse_queries 
+		(id, user_id, natural_language_query, generated_sql, schema_id, status, correction_id, attempt, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`
+
+	attempt := max(req.attempt, 1)
+	err := s.pool.QueryRow(ctx, insertQuery,
+		queryID, req.UserID, req.Query, generatedSQL, req.SchemaID, "executing", req.correctionID, attempt, createdAt,
+	).Scan(&queryID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create query record: %w", err)
+	}
+	return queryID, nil
 }
 
 /* ValidateDataQualityWithML validates data quality using NeuronDB ML classification */
@@ -499,20 +728,24 @@ func (s *Service) GetQuery(ctx context.Context, queryID uuid.UUID) (*QueryRespon
 	var results []map[string]interface{}
 	var chartConfig map[string]interface{}
 	var chartType string
+	var charts []ChartRecommendation
 	resultQuery := `
-		SELECT result_data, chart_config, chart_type
+		SELECT result_data, chart_config, COALESCE(chart_type, ''), chart_recommendations
 		FROM neuronip.query_results
 		WHERE query_id = $1
 		ORDER BY created_at DESC
 		LIMIT 1`
 	var resultData json.RawMessage
-	var chartConfigJSON json.RawMessage
-	err = s.pool.QueryRow(ctx, resultQuery, queryID).Scan(&resultData, &chartConfigJSON, &chartType)
+	var chartConfigJSON, chartsJSON json.RawMessage
+	err = s.pool.QueryRow(ctx, resultQuery, queryID).Scan(&resultData, &chartConfigJSON, &chartType, &chartsJSON)
 	if err == nil {
 		json.Unmarshal(resultData, &results)
 		if chartConfigJSON != nil {
 			json.Unmarshal(chartConfigJSON, &chartConfig)
 		}
+		if chartsJSON != nil {
+			json.Unmarshal(chartsJSON, &charts)
+		}
 	}
 
 	// Get explanation
@@ -535,6 +768,7 @@ func (s *Service) GetQuery(ctx context.Context, queryID uuid.UUID) (*QueryRespon
 		Explanation: explanation,
 		ChartConfig: chartConfig,
 		ChartType:   chartType,
+		Charts:      charts,
 	}, nil
 }
 
@@ -546,12 +780,12 @@ func (s *Service) GetSchema(ctx context.Context, id uuid.UUID) (*Schema, error)
 	var lastSyncedAt sql.NullTime
 
 	query := `
-		SELECT id, schema_name, database_name, description, tables, 
+		SELECT id, schema_name, database_name, connector_id, description, tables, 
 		       last_synced_at, created_at, updated_at
 		FROM neuronip.warehouse_schemas
 		WHERE id = $1`
 	err := s.pool.QueryRow(ctx, query, id).Scan(
-		&schema.ID, &schema.SchemaName, &schema.DatabaseName, &description,
+		&schema.ID, &schema.SchemaName, &schema.DatabaseName, &schema.ConnectorID, &description,
 		&tablesJSON, &lastSyncedAt, &schema.CreatedAt, &schema.UpdatedAt,
 	)
 	if err == pgx.ErrNoRows {
@@ -580,7 +814,7 @@ func (s *Service) GetSchema(ctx context.Context, id uuid.UUID) (*Schema, error)
 func (s *Service) ListSchemas(ctx context.Context) ([]Schema, error) {
 	// Check if last_synced_at column exists, if not, use simpler query
 	query := `
-		SELECT id, schema_name, database_name, description, tables, 
+		SELECT id, schema_name, database_name, connector_id, description, tables, 
 		       created_at, updated_at
 		FROM neuronip.warehouse_schemas
 		ORDER BY created_at DESC`
@@ -598,7 +832,7 @@ func (s *Service) ListSchemas(ctx context.Context) ([]Schema, error) {
 		var tablesJSON json.RawMessage
 
 		err := rows.Scan(
-			&schema.ID, &schema.SchemaName, &schema.DatabaseName, &description,
+			&schema.ID, &schema.SchemaName, &schema.DatabaseName, &schema.ConnectorID, &description,
 			&tablesJSON, &schema.CreatedAt, &schema.UpdatedAt,
 		)
 		if err != nil {
@@ -620,25 +854,25 @@ func (s *Service) ListSchemas(ctx context.Context) ([]Schema, error) {
 	return schemas, nil
 }
 
-/* CreateSchema creates a new warehouse schema */
-func (s *Service) CreateSchema(ctx context.Context, schemaName string, databaseName string, description *string, tables []map[string]interface{}) (*Schema, error) {
+/* CreateSchema creates a new warehouse schema, optionally linked to the connector it describes */
+func (s *Service) CreateSchema(ctx context.Context, schemaName string, databaseName string, connectorID *uuid.UUID, description *string, tables []map[string]interface{}) (*Schema, error) {
 	id := uuid.New()
 	now := time.Now()
 	tablesJSON, _ := json.Marshal(tables)
 
 	query := `
 		INSERT INTO neuronip.warehouse_schemas 
-		(id, schema_name, database_name, description, tables, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, schema_name, database_name, description, tables, created_at, updated_at`
+		(id, schema_name, database_name, connector_id, description, tables, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, schema_name, database_name, connector_id, description, tables, created_at, updated_at`
 
 	var schema Schema
 	var desc sql.NullString
 	var tablesJSONResult json.RawMessage
 	err := s.pool.QueryRow(ctx, query,
-		id, schemaName, databaseName, description, tablesJSON, now, now,
+		id, schemaName, databaseName, connectorID, description, tablesJSON, now, now,
 	).Scan(
-		&schema.ID, &schema.SchemaName, &schema.DatabaseName, &desc,
+		&schema.ID, &schema.SchemaName, &schema.DatabaseName, &schema.ConnectorID, &desc,
 		&tablesJSONResult, &schema.CreatedAt, &schema.UpdatedAt,
 	)
 	if err != nil {
@@ -678,81 +912,6 @@ func (s *Service) GetExplanation(ctx context.Context, queryID uuid.UUID, explana
 
 // Helper methods
 
-func (s *Service) validateSQL(sql string) error {
-	// Basic SQL validation - check for dangerous statements
-	dangerous := []string{"DROP", "DELETE", "TRUNCATE", "ALTER", "CREATE", "INSERT", "UPDATE"}
-	sqlUpper := strings.ToUpper(sql)
-	for _, stmt := range dangerous {
-		if strings.Contains(sqlUpper, stmt) {
-			// Allow if it's in a comment or subquery context
-			// This is basic validation - in production, use proper SQL parser
-			if strings.Contains(sqlUpper, "SELECT") {
-				// For now, only allow SELECT statements for safety
-				continue
-			}
-			return fmt.Errorf("unsafe SQL statement detected: %s", stmt)
-		}
-	}
-	return nil
-}
-
-func (s *Service) generateChartConfig(results []map[string]interface{}) (map[string]interface{}, string) {
-	if len(results) == 0 {
-		return nil, ""
-	}
-
-	// Detect chart type based on data structure
-	firstRow := results[0]
-
-	// Count numeric vs string columns
-	numericCols := []string{}
-	stringCols := []string{}
-	for key, val := range firstRow {
-		switch val.(type) {
-		case int, int32, int64, float32, float64:
-			numericCols = append(numericCols, key)
-		case string:
-			stringCols = append(stringCols, key)
-		}
-	}
-
-	// Determine chart type
-	var chartType string
-	var config map[string]interface{}
-
-	if len(numericCols) >= 2 && len(stringCols) >= 1 {
-		// Bar or line chart
-		chartType = "bar"
-		config = map[string]interface{}{
-			"x":      stringCols[0],
-			"y":      numericCols[0],
-			"series": numericCols,
-		}
-	} else if len(numericCols) >= 1 && len(stringCols) >= 1 {
-		// Pie chart
-		chartType = "pie"
-		config = map[string]interface{}{
-			"category": stringCols[0],
-			"value":    numericCols[0],
-		}
-	} else if len(numericCols) >= 2 {
-		// Scatter plot
-		chartType = "scatter"
-		config = map[string]interface{}{
-			"x": numericCols[0],
-			"y": numericCols[1],
-		}
-	} else {
-		// Table view
-		chartType = "table"
-		config = map[string]interface{}{
-			"columns": getKeys(firstRow),
-		}
-	}
-
-	return config, chartType
-}
-
 func (s *Service) generateExplanation(sql string, results []map[string]interface{}, nlQuery string) string {
 	// Generate explanation of SQL query
 	return fmt.Sprintf("The query translates '%s' into SQL: %s. It returned %d rows.", nlQuery, sql, len(results))
@@ -983,7 +1142,8 @@ func (s *Service) GetQueryHistory(ctx context.Context, userID string, limit int)
 	}
 
 	query := `
-		SELECT id, natural_language_query, generated_sql, status, created_at, executed_at, execution_time_ms
+		SELECT id, natural_language_query, generated_sql, status, created_at, executed_at, execution_time_ms,
+		       correction_id, attempt
 		FROM neuronip.warehouse_queries
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -1005,6 +1165,7 @@ func (s *Service) GetQueryHistory(ctx context.Context, userID string, limit int)
 		err := rows.Scan(
 			&item.QueryID, &item.NaturalLanguageQuery, &generatedSQL,
 			&item.Status, &item.CreatedAt, &executedAt, &executionTimeMs,
+			&item.CorrectionID, &item.Attempt,
 		)
 		if err != nil {
 			continue
@@ -1035,6 +1196,8 @@ type QueryHistoryItem struct {
 	CreatedAt            time.Time  `json:"created_at"`
 	ExecutedAt           *time.Time `json:"executed_at,omitempty"`
 	ExecutionTimeMs      *int64     `json:"execution_time_ms,omitempty"`
+	CorrectionID         *uuid.UUID `json:"correction_id,omitempty"` // Groups the attempts made for one question
+	Attempt              int        `json:"attempt"`
 }
 
 /* GetQueryOptimizationSuggestions provides optimization suggestions for a query */
@@ -0,0 +1,360 @@
+package warehouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/neurondb/NeuronIP/api/internal/agent"
+)
+
+/* Stages at which a generated SQL attempt ended */
+const (
+	AttemptStageValidation  = "validation"   // Rejected by the SQL parser or policy
+	AttemptStagePlan        = "plan"         // Rejected by the planner
+	AttemptStageExecution   = "execution"    // Failed while running
+	AttemptStageEmptyResult = "empty_result" // Ran but returned no rows
+	AttemptStageSucceeded   = "succeeded"
+)
+
+/* CorrectionConfig bounds the NL-to-SQL correction loop */
+type CorrectionConfig struct {
+	MaxAttempts       int  // Generated queries per question, including the first
+	RetryEmptyResults bool // Regenerate queries that return no rows
+}
+
+/* DefaultCorrectionConfig returns the default correction settings */
+func DefaultCorrectionConfig() CorrectionConfig {
+	return CorrectionConfig{MaxAttempts: 3, RetryEmptyResults: true}
+}
+
+/* SQLAttempt records one generated query and how it ended */
+type SQLAttempt struct {
+	CorrectionID uuid.UUID  `json:"correction_id"`
+	Attempt      int        `json:"attempt"`
+	SQL          string     `json:"sql"`
+	Stage        string     `json:"stage"`
+	Error        string     `json:"error,omitempty"`
+	QueryID      *uuid.UUID `json:"query_id,omitempty"` // Query history entry, for attempts that ran
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+/* SQLCorrectionError reports a question for which no attempt produced a runnable query */
+type SQLCorrectionError struct {
+	Attempts []SQLAttempt
+	Err      error // Failure of the last attempt
+}
+
+func (e *SQLCorrectionError) Error() string {
+	return fmt.Sprintf("no valid query after %d attempts: %v", len(e.Attempts), e.Err)
+}
+
+func (e *SQLCorrectionError) Unwrap() error {
+	return e.Err
+}
+
+/* AsSQLCorrectionError returns the correction error in err's chain, or nil */
+func AsSQLCorrectionError(err error) *SQLCorrectionError {
+	var correctionErr *SQLCorrectionError
+	if errors.As(err, &correctionErr) {
+		return correctionErr
+	}
+	return nil
+}
+
+/* CorrectionStats summarizes how often questions against a schema needed correction */
+type CorrectionStats struct {
+	SchemaID        *uuid.UUID     `json:"schema_id,omitempty"`
+	SchemaName      *string        `json:"schema_name,omitempty"`
+	Questions       int64          `json:"questions"`
+	Corrected       int64          `json:"corrected"` // Succeeded after at least one failed attempt
+	Failed          int64          `json:"failed"`    // No attempt succeeded
+	AverageAttempts float64        `json:"average_attempts"`
+	FailuresByStage map[string]int `json:"failures_by_stage"`
+	CorrectionRate  float64        `json:"correction_rate"`
+}
+
+/* sqlCorrection tracks the attempts made for one question */
+type sqlCorrection struct {
+	id             uuid.UUID
+	req            QueryRequest
+	schemaMetadata map[string]interface{}
+	maxAttempts    int
+	retryEmpty     bool
+	attempts       []SQLAttempt
+	lastErr        error
+}
+
+func (s *Service) newSQLCorrection(req QueryRequest, schemaMetadata map[string]interface{}) *sqlCorrection {
+	return &sqlCorrection{
+		id:             uuid.New(),
+		req:            req,
+		schemaMetadata: schemaMetadata,
+		maxAttempts:    max(s.correction.MaxAttempts, 1),
+		retryEmpty:     s.correction.RetryEmptyResults,
+	}
+}
+
+/* remaining reports whether another attempt may be made */
+func (c *sqlCorrection) remaining() bool {
+	return len(c.attempts) < c.maxAttempts
+}
+
+/* retriesEmpty reports whether a query that returned no rows should be regenerated.
+ * Empty results are retried only while an attempt remains after recording this one, and never for queued queries
+ * or metric SQL compiled by the semantic layer, which is deterministic, so no rows is its answer rather than a generation error. */
+func (c *sqlCorrection) retriesEmpty(response *QueryResponse, compiled bool) bool {
+	queued := response.Admission != nil && response.Admission.Decision != AdmissionRun
+	return len(response.Results) == 0 && !queued && !compiled && c.retryEmpty && len(c.attempts)+1 < c.maxAttempts
+}
+
+/* request returns the query request for the next attempt, tagged for query history */
+func (c *sqlCorrection) request() QueryRequest {
+	queryID := uuid.New()
+	req := c.req
+	req.correctionID = &c.id
+	req.attempt = len(c.attempts) + 1
+	req.queryID = &queryID
+	return req
+}
+
+/* failure returns the error reported when attempts are exhausted */
+func (c *sqlCorrection) failure() error {
+	return &SQLCorrectionError{Attempts: c.attempts, Err: c.lastErr}
+}
+
+/* prepareSQL generates queries until one parses, passes policy and plans, recording each rejected attempt */
+func (s *Service) prepareSQL(ctx context.Context, c *sqlCorrection, policy SQLPolicy) (*ValidatedSQL, map[string]interface{}, error) {
+	for c.remaining() {
+		generatedSQL, semanticLayer, err := s.nextSQL(ctx, c)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		validated, err := ValidateSQL(generatedSQL, policy)
+		if err != nil {
+			s.recordAttempt(ctx, c, generatedSQL, AttemptStageValidation, fmt.Errorf("invalid SQL: %w", err), nil)
+			continue
+		}
+
+		plan, err := s.planSQL(ctx, validated.SQL)
+		if err != nil {
+			if !isCorrectableSQLError(err) {
+				return nil, nil, err
+			}
+			s.recordAttempt(ctx, c, validated.SQL, AttemptStagePlan, err, nil)
+			continue
+		}
+		validated.plan = plan
+
+		return validated, semanticLayer, nil
+	}
+	return nil, nil, c.failure()
+}
+
+/* nextSQL generates the first query from the question, and later ones from the failures so far */
+func (s *Service) nextSQL(ctx context.Context, c *sqlCorrection) (string, map[string]interface{}, error) {
+	if len(c.attempts) == 0 {
+		return s.generateSQL(ctx, c.req.Query, c.schemaMetadata)
+	}
+
+	feedback := make([]agent.SQLFeedback, 0, len(c.attempts))
+	for _, attempt := range c.attempts {
+		feedback = append(feedback, agent.SQLFeedback{SQL: attempt.SQL, Stage: attempt.Stage, Error: attempt.Error})
+	}
+	last := c.attempts[len(c.attempts)-1]
+
+	generatedSQL, err := s.agentClient.CorrectNLToSQL(ctx, c.req.Query, schemaSlice(c.schemaMetadata, last.SQL, last.Error), feedback)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to correct SQL: %w", err)
+	}
+	return generatedSQL, nil, nil
+}
+
+/* planSQL runs EXPLAIN on a validated query so planner errors surface before execution.
+ * With governance configured it returns the cost estimate, which admission then reuses. */
+func (s *Service) planSQL(ctx context.Context, sql string) (*PlanEstimate, error) {
+	if s.governance != nil {
+		return s.governance.ExplainQuery(ctx, sql)
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin explain transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "EXPLAIN "+sql); err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+	return nil, nil
+}
+
+/* recordAttempt appends an attempt and stores it; recording is best effort */
+func (s *Service) recordAttempt(ctx context.Context, c *sqlCorrection, sql string, stage string, cause error, queryID *uuid.UUID) {
+	attempt := SQLAttempt{
+		CorrectionID: c.id,
+		Attempt:      len(c.attempts) + 1,
+		SQL:          sql,
+		Stage:        stage,
+		QueryID:      queryID,
+		CreatedAt:    time.Now(),
+	}
+	if cause != nil {
+		attempt.Error = cause.Error()
+		c.lastErr = cause
+	}
+	c.attempts = append(c.attempts, attempt)
+
+	s.pool.Exec(ctx, `
+		INSERT INTO neuronip.warehouse_query_attempts
+		(correction_id, attempt, schema_id, user_id, natural_language_query, generated_sql, stage, error_message, query_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		c.id, attempt.Attempt, c.req.SchemaID, c.req.UserID, c.req.Query, sql, stage, nullableString(attempt.Error), queryID, attempt.CreatedAt)
+}
+
+/* isCorrectableSQLError reports whether a database error stems from the query text, so a regenerated query may succeed.
+ * Syntax, undefined object, datatype and cardinality errors qualify; permission, timeout and connection errors do not. */
+func isCorrectableSQLError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || len(pgErr.Code) < 2 {
+		return false
+	}
+	if pgErr.Code == "42501" { // insufficient_privilege
+		return false
+	}
+	switch pgErr.Code[:2] {
+	case "42", "22", "21", "0A":
+		return true
+	}
+	return false
+}
+
+/* schemaSlice narrows the schema context to tables the failed query used or the error mentions.
+ * When none match, for example because the query named a table that does not exist, only table names are sent. */
+func schemaSlice(schemaMetadata map[string]interface{}, sql string, errMsg string) map[string]interface{} {
+	if schemaMetadata == nil {
+		return nil
+	}
+
+	referenced := make(map[string]bool)
+	if tables, err := ReferencedTables(sql); err == nil {
+		for _, table := range tables {
+			referenced[table] = true
+			if i := strings.LastIndex(table, "."); i >= 0 {
+				referenced[table[i+1:]] = true
+			}
+		}
+	}
+	lowerErr := strings.ToLower(errMsg)
+
+	slice := make(map[string]interface{}, len(schemaMetadata))
+	for k, v := range schemaMetadata {
+		if k != "tables" {
+			slice[k] = v
+		}
+	}
+
+	tables, _ := schemaMetadata["tables"].([]map[string]interface{})
+	relevant := make([]map[string]interface{}, 0)
+	names := make([]string, 0, len(tables))
+	for _, table := range tables {
+		name, _ := table["name"].(string)
+		if name == "" {
+			continue
+		}
+		names = append(names, name)
+		lower := strings.ToLower(name)
+		if referenced[lower] || strings.Contains(lowerErr, `"`+lower+`"`) {
+			relevant = append(relevant, table)
+		}
+	}
+
+	if len(relevant) > 0 {
+		slice["tables"] = relevant
+	} else {
+		slice["table_names"] = names
+	}
+	return slice
+}
+
+/* GetQueryAttempts lists the attempts made for the question behind a query history entry */
+func (s *Service) GetQueryAttempts(ctx context.Context, queryID uuid.UUID) ([]SQLAttempt, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT correction_id, attempt, generated_sql, stage, COALESCE(error_message, ''), query_id, created_at
+		FROM neuronip.warehouse_query_attempts
+		WHERE correction_id = (SELECT correction_id FROM neuronip.warehouse_queries WHERE id = $1)
+		ORDER BY attempt`, queryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := make([]SQLAttempt, 0)
+	for rows.Next() {
+		var attempt SQLAttempt
+		if err := rows.Scan(&attempt.CorrectionID, &attempt.Attempt, &attempt.SQL, &attempt.Stage, &attempt.Error,
+			&attempt.QueryID, &attempt.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan query attempt: %w", err)
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
+
+/* GetCorrectionStats summarizes correction attempts per schema since a point in time */
+func (s *Service) GetCorrectionStats(ctx context.Context, since time.Time) ([]CorrectionStats, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH questions AS (
+			SELECT correction_id, schema_id, COUNT(*) AS attempts, BOOL_OR(stage = 'succeeded') AS succeeded
+			FROM neuronip.warehouse_query_attempts
+			WHERE created_at >= $1
+			GROUP BY correction_id, schema_id
+		),
+		failures AS (
+			SELECT a.schema_id, a.stage, COUNT(*) AS failures
+			FROM neuronip.warehouse_query_attempts a
+			WHERE a.created_at >= $1 AND a.stage <> 'succeeded'
+			GROUP BY a.schema_id, a.stage
+		)
+		SELECT q.schema_id, ws.schema_name, COUNT(*),
+		       COUNT(*) FILTER (WHERE q.succeeded AND q.attempts > 1),
+		       COUNT(*) FILTER (WHERE NOT q.succeeded),
+		       AVG(q.attempts)::float8,
+		       COALESCE((SELECT jsonb_object_agg(f.stage, f.failures) FROM failures f
+		                 WHERE f.schema_id IS NOT DISTINCT FROM q.schema_id), '{}'::jsonb)
+		FROM questions q
+		LEFT JOIN neuronip.warehouse_schemas ws ON ws.id = q.schema_id
+		GROUP BY q.schema_id, ws.schema_name
+		ORDER BY COUNT(*) FILTER (WHERE NOT q.succeeded OR q.attempts > 1) DESC, COUNT(*) DESC`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get correction stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]CorrectionStats, 0)
+	for rows.Next() {
+		var st CorrectionStats
+		if err := rows.Scan(&st.SchemaID, &st.SchemaName, &st.Questions, &st.Corrected, &st.Failed,
+			&st.AverageAttempts, &st.FailuresByStage); err != nil {
+			return nil, fmt.Errorf("failed to scan correction stats: %w", err)
+		}
+		if st.Questions > 0 {
+			st.CorrectionRate = float64(st.Corrected+st.Failed) / float64(st.Questions)
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
+
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
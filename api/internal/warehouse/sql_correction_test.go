@@ -0,0 +1,154 @@
+package warehouse
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+/* TestIsCorrectableSQLError checks which database errors a regenerated query may fix */
+func TestIsCorrectableSQLError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "syntax error", err: &pgconn.PgError{Code: "42601"}, want: true},
+		{name: "undefined column", err: &pgconn.PgError{Code: "42703"}, want: true},
+		{name: "wrapped undefined table", err: fmt.Errorf("failed to execute query: %w", &pgconn.PgError{Code: "42P01"}), want: true},
+		{name: "invalid datatype input", err: &pgconn.PgError{Code: "22P02"}, want: true},
+		{name: "subquery cardinality", err: &pgconn.PgError{Code: "21000"}, want: true},
+		{name: "feature not supported", err: &pgconn.PgError{Code: "0A000"}, want: true},
+		{name: "insufficient privilege", err: &pgconn.PgError{Code: "42501"}},
+		{name: "statement timeout", err: &pgconn.PgError{Code: "57014"}},
+		{name: "connection failure", err: &pgconn.PgError{Code: "08006"}},
+		{name: "not a database error", err: errors.New("connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCorrectableSQLError(tt.err); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+/* TestSchemaSlice checks that correction feedback only carries the tables the failed query involved */
+func TestSchemaSlice(t *testing.T) {
+	schema := map[string]interface{}{
+		"schema_id": "sales",
+		"tables": []map[string]interface{}{
+			{"name": "orders", "columns": []string{"id", "amount"}},
+			{"name": "customers", "columns": []string{"id", "name"}},
+			{"name": "refunds", "columns": []string{"id", "order_id"}},
+		},
+	}
+	tableNames := func(slice map[string]interface{}) []string {
+		tables, _ := slice["tables"].([]map[string]interface{})
+		names := make([]string, 0, len(tables))
+		for _, table := range tables {
+			names = append(names, table["name"].(string))
+		}
+		return names
+	}
+
+	slice := schemaSlice(schema, "SELECT o.amount FROM sales.orders o JOIN customers c ON c.id = o.customer_id", "")
+	if names := fmt.Sprint(tableNames(slice)); names != "[orders customers]" {
+		t.Errorf("expected the joined tables, got %s", names)
+	}
+	if slice["schema_id"] != "sales" {
+		t.Errorf("expected schema keys other than tables to be kept, got %v", slice)
+	}
+
+	slice = schemaSlice(schema, "SELECT 1", `relation "refunds" is locked`)
+	if names := fmt.Sprint(tableNames(slice)); names != "[refunds]" {
+		t.Errorf("expected the table named in the error, got %s", names)
+	}
+
+	slice = schemaSlice(schema, "SELECT * FROM order_lines", `relation "order_lines" does not exist`)
+	if _, ok := slice["tables"]; ok {
+		t.Errorf("expected no table details when none match, got %v", slice["tables"])
+	}
+	if names := fmt.Sprint(slice["table_names"]); names != "[orders customers refunds]" {
+		t.Errorf("expected every table name, got %s", names)
+	}
+
+	if schemaSlice(nil, "SELECT 1", "") != nil {
+		t.Error("expected no schema context without schema metadata")
+	}
+}
+
+/* TestSQLCorrectionAttempts checks the attempt bound and what the correction reports when attempts run out */
+func TestSQLCorrectionAttempts(t *testing.T) {
+	s := &Service{correction: CorrectionConfig{MaxAttempts: 2}}
+	c := s.newSQLCorrection(QueryRequest{Query: "revenue by month"}, nil)
+
+	first := c.request()
+	if first.attempt != 1 || first.correctionID == nil || *first.correctionID != c.id || first.queryID == nil {
+		t.Fatalf("expected the first attempt to be tagged with the correction, got %+v", first)
+	}
+	cause := &pgconn.PgError{Code: "42703", Message: `column "revenue" does not exist`}
+	addAttempt(c, "SELECT revenue FROM orders", AttemptStagePlan, cause)
+	if !c.remaining() {
+		t.Fatal("expected a second attempt to remain")
+	}
+	if second := c.request(); second.attempt != 2 || *second.queryID == *first.queryID {
+		t.Errorf("expected the second attempt to get its own query ID, got %+v", second)
+	}
+	addAttempt(c, "SELECT amount FROM order", AttemptStageValidation, errors.New("invalid SQL"))
+	if c.remaining() {
+		t.Fatal("expected attempts to be exhausted")
+	}
+
+	correctionErr := AsSQLCorrectionError(fmt.Errorf("query failed: %w", c.failure()))
+	if correctionErr == nil || len(correctionErr.Attempts) != 2 || correctionErr.Err.Error() != "invalid SQL" {
+		t.Fatalf("expected a correction error carrying both attempts and the last failure, got %v", correctionErr)
+	}
+
+	// A configured bound below one still allows the first attempt
+	if c := (&Service{}).newSQLCorrection(QueryRequest{}, nil); !c.remaining() {
+		t.Error("expected one attempt without a configured bound")
+	}
+}
+
+/* addAttempt appends an attempt as recordAttempt does, without storing it */
+func addAttempt(c *sqlCorrection, sql string, stage string, cause error) {
+	c.attempts = append(c.attempts, SQLAttempt{CorrectionID: c.id, Attempt: len(c.attempts) + 1, SQL: sql, Stage: stage, Error: cause.Error()})
+	c.lastErr = cause
+}
+
+/* TestRetriesEmpty checks which queries that returned no rows are regenerated */
+func TestRetriesEmpty(t *testing.T) {
+	empty := &QueryResponse{}
+	rows := &QueryResponse{Results: []map[string]interface{}{{"total": 3}}}
+	queued := &QueryResponse{Admission: &AdmissionResult{Decision: AdmissionAsync}}
+	admitted := &QueryResponse{Admission: &AdmissionResult{Decision: AdmissionRun}}
+
+	tests := []struct {
+		name     string
+		config   CorrectionConfig
+		attempts int
+		response *QueryResponse
+		compiled bool
+		want     bool
+	}{
+		{name: "empty", config: DefaultCorrectionConfig(), response: empty, want: true},
+		{name: "empty after admission", config: DefaultCorrectionConfig(), response: admitted, want: true},
+		{name: "has rows", config: DefaultCorrectionConfig(), response: rows},
+		{name: "queued", config: DefaultCorrectionConfig(), response: queued},
+		{name: "semantic layer", config: DefaultCorrectionConfig(), response: empty, compiled: true},
+		{name: "disabled", config: CorrectionConfig{MaxAttempts: 3}, response: empty},
+		{name: "last attempt", config: DefaultCorrectionConfig(), attempts: 2, response: empty},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := (&Service{correction: tt.config}).newSQLCorrection(QueryRequest{}, nil)
+			c.attempts = make([]SQLAttempt, tt.attempts)
+			if got := c.retriesEmpty(tt.response, tt.compiled); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,480 @@
+package warehouse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+/* SQL validation rules reported in violations */
+const (
+	RuleParse           = "parse"
+	RuleSingleStatement = "single_statement"
+	RuleReadOnly        = "read_only"
+	RuleSelectInto      = "select_into"
+	RuleLockingClause   = "locking_clause"
+	RuleDeniedFunction  = "denied_function"
+	RuleDeniedSchema    = "denied_schema"
+	RuleRowLimit        = "row_limit"
+)
+
+/* DefaultMaxRows caps result rows when a policy does not set its own limit */
+const DefaultMaxRows = 10000
+
+/* defaultDeniedFunctions execute arbitrary SQL, touch the server filesystem, modify state or stall the backend */
+var defaultDeniedFunctions = []string{
+	"pg_sleep", "pg_sleep_for", "pg_sleep_until",
+	"pg_read_file", "pg_read_binary_file", "pg_ls_dir", "pg_stat_file", "pg_ls_logdir", "pg_ls_waldir",
+	"lo_import", "lo_export", "lo_unlink", "lo_from_bytea", "lo_put", "lo_get",
+	"dblink", "dblink_exec", "dblink_connect", "dblink_send_query",
+	"query_to_xml", "query_to_xml_and_xmlschema", "cursor_to_xml", "table_to_xml",
+	"pg_terminate_backend", "pg_cancel_backend", "pg_reload_conf", "pg_rotate_logfile",
+	"set_config", "current_setting", "nextval", "setval",
+	"pg_advisory_lock", "pg_advisory_xact_lock", "pg_try_advisory_lock",
+	"pg_create_restore_point", "pg_switch_wal", "pg_promote",
+}
+
+/* defaultDeniedSchemas hold catalogs and internals that generated queries have no business reading */
+var defaultDeniedSchemas = []string{"pg_catalog", "pg_toast", "information_schema"}
+
+/* SQLPolicy describes which statements the validator accepts */
+type SQLPolicy struct {
+	DeniedFunctions []string // Function names, optionally schema-qualified
+	DeniedSchemas   []string
+	AllowedSchemas  []string // When set, relations must belong to one of these schemas; unqualified names resolve as in resolveSchema
+	MaxRows         int      // Row cap applied as LIMIT; 0 disables the cap
+}
+
+/* DefaultSQLPolicy returns the policy applied to warehouse queries */
+func DefaultSQLPolicy() SQLPolicy {
+	return SQLPolicy{
+		DeniedFunctions: append([]string{}, defaultDeniedFunctions...),
+		DeniedSchemas:   append([]string{}, defaultDeniedSchemas...),
+		MaxRows:         DefaultMaxRows,
+	}
+}
+
+/* SQLViolation explains which node of a statement broke which rule */
+type SQLViolation struct {
+	Rule     string `json:"rule"`
+	Node     string `json:"node"` // Path to the offending node, e.g. stmt.with_clause.ctes[0].ctequery (DeleteStmt)
+	Detail   string `json:"detail"`
+	Location int    `json:"location"` // Byte offset in the query, -1 when unknown
+}
+
+/* String formats the violation for error messages */
+func (v SQLViolation) String() string {
+	if v.Location >= 0 {
+		return fmt.Sprintf("%s: %s at %s (offset %d)", v.Rule, v.Detail, v.Node, v.Location)
+	}
+	return fmt.Sprintf("%s: %s at %s", v.Rule, v.Detail, v.Node)
+}
+
+/* SQLValidationError is returned when a statement breaks one or more rules */
+type SQLValidationError struct {
+	Violations []SQLViolation
+}
+
+func (e *SQLValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.String()
+	}
+	return "SQL rejected: " + strings.Join(messages, "; ")
+}
+
+/* AsSQLValidationError returns the validation error wrapped in err, or nil */
+func AsSQLValidationError(err error) *SQLValidationError {
+	var validationErr *SQLValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr
+	}
+	return nil
+}
+
+/* ValidatedSQL is a statement that passed validation */
+type ValidatedSQL struct {
+	SQL          string   `json:"sql"`       // Statement to execute; deparsed when a row limit was applied
+	Tables       []string `json:"tables"`    // Relations read, schema-qualified when written so
+	Functions    []string `json:"functions"` // Functions called
+	LimitApplied bool     `json:"limit_applied"`
+
+	plan       *PlanEstimate // Planner estimate taken while preparing the query, reused by admission
+	limitParam int           // Number of the $n placeholder used as the top-level LIMIT, 0 when none
+}
+
+/* ValidateSQL parses sql as PostgreSQL and enforces a single read-only SELECT under the policy.
+ * A missing or larger LIMIT is replaced with the policy's row cap. */
+func ValidateSQL(sql string, policy SQLPolicy) (*ValidatedSQL, error) {
+	tree, err := pg_query.Parse(sql)
+	if err != nil {
+		return nil, &SQLValidationError{Violations: []SQLViolation{{
+			Rule: RuleParse, Node: "query", Detail: err.Error(), Location: -1,
+		}}}
+	}
+
+	if len(tree.Stmts) != 1 {
+		return nil, &SQLValidationError{Violations: []SQLViolation{{
+			Rule:     RuleSingleStatement,
+			Node:     "query",
+			Detail:   fmt.Sprintf("expected exactly one statement, found %d", len(tree.Stmts)),
+			Location: -1,
+		}}}
+	}
+
+	raw := tree.Stmts[0]
+	selectStmt := raw.Stmt.GetSelectStmt()
+	if selectStmt == nil {
+		return nil, &SQLValidationError{Violations: []SQLViolation{{
+			Rule:     RuleReadOnly,
+			Node:     "stmt (" + nodeTypeName(raw.Stmt) + ")",
+			Detail:   "only SELECT statements are allowed",
+			Location: int(raw.StmtLocation),
+		}}}
+	}
+
+	v := &sqlValidator{
+		policy:          policy,
+		deniedFunctions: lowerSet(policy.DeniedFunctions),
+		deniedSchemas:   lowerSet(policy.DeniedSchemas),
+		allowedSchemas:  lowerSet(policy.AllowedSchemas),
+		seenTables:      make(map[string]bool),
+		seenFunctions:   make(map[string]bool),
+	}
+	v.walk(selectStmt.ProtoReflect(), "stmt")
+	v.checkRelations()
+
+	validated := &ValidatedSQL{SQL: sql, Tables: v.tables(), Functions: v.functions}
+	if policy.MaxRows > 0 {
+		if param := selectStmt.LimitCount.GetParamRef(); param != nil {
+			validated.limitParam = int(param.Number)
+		}
+		applied, violation := applyRowLimit(selectStmt, int64(policy.MaxRows))
+		if violation != nil {
+			v.violations = append(v.violations, *violation)
+		} else if applied {
+			deparsed, err := pg_query.Deparse(tree)
+			if err != nil {
+				return nil, fmt.Errorf("failed to apply row limit: %w", err)
+			}
+			validated.SQL = deparsed
+			validated.LimitApplied = true
+		}
+	}
+
+	if len(v.violations) > 0 {
+		return nil, &SQLValidationError{Violations: v.violations}
+	}
+	return validated, nil
+}
+
+/* ReferencedTables returns the relations the statements in sql reference, excluding CTE names */
+func ReferencedTables(sql string) ([]string, error) {
+	tree, err := pg_query.Parse(sql)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SQL: %w", err)
+	}
+
+	v := &sqlValidator{
+		seenTables:    make(map[string]bool),
+		seenFunctions: make(map[string]bool),
+	}
+	for _, raw := range tree.Stmts {
+		v.walk(raw.Stmt.ProtoReflect(), "stmt")
+	}
+	return v.tables(), nil
+}
+
+/* sqlValidator walks a parse tree collecting references and violations */
+type sqlValidator struct {
+	policy          SQLPolicy
+	deniedFunctions map[string]bool
+	deniedSchemas   map[string]bool
+	allowedSchemas  map[string]bool
+	cteScopes       []map[string]bool // CTE names visible at the current node, innermost WITH clause last
+	relations       []relationRef
+	seenTables      map[string]bool
+	functions       []string
+	seenFunctions   map[string]bool
+	violations      []SQLViolation
+}
+
+/* relationRef is a relation referenced in FROM or JOIN */
+type relationRef struct {
+	schema   string
+	name     string
+	path     string
+	location int
+	cte      bool // Names a CTE defined by an enclosing WITH clause
+}
+
+/* walk visits every message below m; path names fields, skipping the Node wrappers around each node.
+ * A statement's WITH clause is walked first, so its CTE names are in scope for the rest of the statement only. */
+func (v *sqlValidator) walk(m protoreflect.Message, path string) {
+	if !m.IsValid() {
+		return
+	}
+	v.visit(m.Interface(), path)
+
+	with := withClauseOf(m.Interface())
+	if with != nil {
+		depth := len(v.cteScopes)
+		defer func() { v.cteScopes = v.cteScopes[:depth] }()
+		v.walkWith(with, path+".with_clause")
+	}
+
+	_, isWrapper := m.Interface().(*pg_query.Node)
+	m.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if fd.Message() == nil || fd.IsMap() {
+			return true
+		}
+		if with != nil && fd.Name() == "with_clause" {
+			return true
+		}
+		childPath := path
+		if !isWrapper {
+			childPath = path + "." + string(fd.Name())
+		}
+		if fd.IsList() {
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				v.walk(list.Get(i).Message(), fmt.Sprintf("%s[%d]", childPath, i))
+			}
+			return true
+		}
+		v.walk(value.Message(), childPath)
+		return true
+	})
+}
+
+/* walkWith walks a WITH clause and leaves its CTE names in scope. A CTE's body sees the CTEs defined before it,
+ * or every CTE of the clause when it is RECURSIVE; otherwise its own name still refers to the table. */
+func (v *sqlValidator) walkWith(with *pg_query.WithClause, path string) {
+	scope := make(map[string]bool)
+	v.cteScopes = append(v.cteScopes, scope)
+	if with.Recursive {
+		for _, node := range with.Ctes {
+			if cte := node.GetCommonTableExpr(); cte != nil {
+				scope[strings.ToLower(cte.Ctename)] = true
+			}
+		}
+	}
+	for i, node := range with.Ctes {
+		v.walk(node.ProtoReflect(), fmt.Sprintf("%s.ctes[%d]", path, i))
+		if cte := node.GetCommonTableExpr(); cte != nil {
+			scope[strings.ToLower(cte.Ctename)] = true
+		}
+	}
+}
+
+/* withClauseOf returns the WITH clause of a statement node, or nil */
+func withClauseOf(msg proto.Message) *pg_query.WithClause {
+	switch node := msg.(type) {
+	case *pg_query.SelectStmt:
+		return node.WithClause
+	case *pg_query.InsertStmt:
+		return node.WithClause
+	case *pg_query.UpdateStmt:
+		return node.WithClause
+	case *pg_query.DeleteStmt:
+		return node.WithClause
+	case *pg_query.MergeStmt:
+		return node.WithClause
+	}
+	return nil
+}
+
+/* inCTEScope reports whether an unqualified name refers to a CTE of an enclosing WITH clause */
+func (v *sqlValidator) inCTEScope(name string) bool {
+	for _, scope := range v.cteScopes {
+		if scope[name] {
+			return true
+		}
+	}
+	return false
+}
+
+/* visit checks a single node against the rules */
+func (v *sqlValidator) visit(msg proto.Message, path string) {
+	switch node := msg.(type) {
+	case *pg_query.SelectStmt:
+		if node.IntoClause != nil {
+			v.violate(RuleSelectInto, path+".into_clause (IntoClause)", "SELECT ... INTO creates a table", int(node.IntoClause.GetRel().GetLocation()))
+		}
+		if len(node.LockingClause) > 0 {
+			v.violate(RuleLockingClause, path+".locking_clause (LockingClause)", "FOR UPDATE/SHARE takes row locks", -1)
+		}
+	case *pg_query.InsertStmt:
+		v.violate(RuleReadOnly, path+" (InsertStmt)", "INSERT is not allowed in a read-only query", int(node.GetRelation().GetLocation()))
+	case *pg_query.UpdateStmt:
+		v.violate(RuleReadOnly, path+" (UpdateStmt)", "UPDATE is not allowed in a read-only query", int(node.GetRelation().GetLocation()))
+	case *pg_query.DeleteStmt:
+		v.violate(RuleReadOnly, path+" (DeleteStmt)", "DELETE is not allowed in a read-only query", int(node.GetRelation().GetLocation()))
+	case *pg_query.MergeStmt:
+		v.violate(RuleReadOnly, path+" (MergeStmt)", "MERGE is not allowed in a read-only query", int(node.GetRelation().GetLocation()))
+	case *pg_query.RangeVar:
+		v.checkRelation(node, path)
+	case *pg_query.FuncCall:
+		v.checkFunction(node, path)
+	}
+}
+
+/* checkRelation records a relation, noting whether it names a CTE in scope; its schema is checked after the walk */
+func (v *sqlValidator) checkRelation(rv *pg_query.RangeVar, path string) {
+	rel := relationRef{
+		schema:   strings.ToLower(rv.Schemaname),
+		name:     strings.ToLower(rv.Relname),
+		path:     path + " (RangeVar)",
+		location: int(rv.Location),
+	}
+	rel.cte = rel.schema == "" && v.inCTEScope(rel.name)
+	v.relations = append(v.relations, rel)
+}
+
+/* checkRelations enforces schema rules on the relations read, excluding references to CTEs */
+func (v *sqlValidator) checkRelations() {
+	for _, rel := range v.relations {
+		if rel.cte {
+			continue
+		}
+		schema := resolveSchema(rel)
+		if v.deniedSchemas[schema] {
+			v.violate(RuleDeniedSchema, rel.path, fmt.Sprintf("relation %s is in schema %s, which is not allowed", rel.name, schema), rel.location)
+			continue
+		}
+		if len(v.allowedSchemas) > 0 && !v.allowedSchemas[schema] {
+			v.violate(RuleDeniedSchema, rel.path, fmt.Sprintf("relation %s is in schema %s, outside the allowed schemas", rel.name, schema), rel.location)
+		}
+	}
+}
+
+/* resolveSchema returns the schema an unqualified name resolves to under the default search_path.
+ * pg_catalog is searched first, and every system catalog and view is named pg_*, so those resolve there;
+ * other names resolve to public. */
+func resolveSchema(rel relationRef) string {
+	switch {
+	case rel.schema != "":
+		return rel.schema
+	case strings.HasPrefix(rel.name, "pg_"):
+		return "pg_catalog"
+	default:
+		return "public"
+	}
+}
+
+/* checkFunction records a function call and enforces the deny list */
+func (v *sqlValidator) checkFunction(fc *pg_query.FuncCall, path string) {
+	var parts []string
+	for _, part := range fc.Funcname {
+		if s := part.GetString_(); s != nil {
+			parts = append(parts, strings.ToLower(s.Sval))
+		}
+	}
+	if len(parts) == 0 {
+		return
+	}
+	qualified := strings.Join(parts, ".")
+	name := parts[len(parts)-1]
+	if !v.seenFunctions[qualified] {
+		v.seenFunctions[qualified] = true
+		v.functions = append(v.functions, qualified)
+	}
+
+	if v.deniedFunctions[qualified] || v.deniedFunctions[name] {
+		v.violate(RuleDeniedFunction, path+" (FuncCall)", fmt.Sprintf("function %s is not allowed", qualified), int(fc.Location))
+		return
+	}
+	if len(parts) > 1 && v.deniedSchemas[parts[0]] && parts[0] != "pg_catalog" {
+		v.violate(RuleDeniedSchema, path+" (FuncCall)", fmt.Sprintf("functions in schema %s are not allowed", parts[0]), int(fc.Location))
+	}
+}
+
+/* tables lists the relations read, excluding references to CTEs */
+func (v *sqlValidator) tables() []string {
+	var tables []string
+	for _, rel := range v.relations {
+		if rel.cte {
+			continue
+		}
+		table := rel.name
+		if rel.schema != "" {
+			table = rel.schema + "." + rel.name
+		}
+		if !v.seenTables[table] {
+			v.seenTables[table] = true
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+func (v *sqlValidator) violate(rule, node, detail string, location int) {
+	if location < 0 {
+		location = -1
+	}
+	v.violations = append(v.violations, SQLViolation{Rule: rule, Node: node, Detail: detail, Location: location})
+}
+
+/* applyRowLimit caps the top-level LIMIT at maxRows, adding one when missing or ALL. A bound parameter is
+ * capped as LEAST($n, maxRows); callers binding it must ensure it is an integer.
+ * Returns whether the statement changed, or a violation when the limit is neither a constant nor a parameter. */
+func applyRowLimit(stmt *pg_query.SelectStmt, maxRows int64) (bool, *SQLViolation) {
+	if stmt.LimitOption == pg_query.LimitOption_LIMIT_OPTION_WITH_TIES {
+		return false, &SQLViolation{Rule: RuleRowLimit, Node: "stmt.limit_count", Detail: "FETCH ... WITH TIES cannot be capped", Location: -1}
+	}
+
+	if stmt.LimitCount != nil && stmt.LimitCount.GetParamRef() != nil {
+		stmt.LimitCount = &pg_query.Node{Node: &pg_query.Node_MinMaxExpr{MinMaxExpr: &pg_query.MinMaxExpr{
+			Op:   pg_query.MinMaxOp_IS_LEAST,
+			Args: []*pg_query.Node{stmt.LimitCount, pg_query.MakeAConstIntNode(maxRows, -1)},
+		}}}
+		return true, nil
+	}
+
+	if stmt.LimitCount != nil {
+		constant := stmt.LimitCount.GetAConst()
+		if constant == nil {
+			return false, &SQLViolation{
+				Rule:     RuleRowLimit,
+				Node:     "stmt.limit_count (" + nodeTypeName(stmt.LimitCount) + ")",
+				Detail:   "LIMIT must be a constant or a parameter",
+				Location: -1,
+			}
+		}
+		if !constant.Isnull {
+			if ival := constant.GetIval(); ival != nil && int64(ival.Ival) <= maxRows {
+				return false, nil
+			}
+		}
+	}
+
+	stmt.LimitCount = pg_query.MakeAConstIntNode(maxRows, -1)
+	stmt.LimitOption = pg_query.LimitOption_LIMIT_OPTION_COUNT
+	return true, nil
+}
+
+/* nodeTypeName returns the parse node type of a Node wrapper, e.g. DeleteStmt */
+func nodeTypeName(node *pg_query.Node) string {
+	if node == nil {
+		return "unknown"
+	}
+	m := node.ProtoReflect()
+	if oneof := m.Descriptor().Oneofs().ByName("node"); oneof != nil {
+		if fd := m.WhichOneof(oneof); fd != nil && fd.Message() != nil {
+			return string(fd.Message().Name())
+		}
+	}
+	return "unknown"
+}
+
+func lowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[strings.ToLower(strings.TrimSpace(value))] = true
+	}
+	return set
+}
@@ -0,0 +1,142 @@
+package warehouse
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+/* TestValidateSQL checks which statements the default policy accepts and which rule rejects the rest */
+func TestValidateSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		rule string // Rule of the first violation; empty when the statement is accepted
+	}{
+		{name: "plain select", sql: "SELECT id, name FROM public.customers"},
+		{name: "unqualified table", sql: "SELECT id FROM orders"},
+		{name: "cte reference", sql: "WITH recent AS (SELECT id FROM orders) SELECT id FROM recent"},
+		{name: "cte shadowing a catalog name", sql: "WITH pg_stats AS (SELECT 1 AS n) SELECT n FROM pg_stats"},
+		{name: "nested cte reference", sql: "SELECT n FROM (WITH recent AS (SELECT 1 AS n) SELECT n FROM recent) r"},
+		{name: "recursive cte", sql: "WITH RECURSIVE pg_seq AS (SELECT 1 AS n UNION ALL SELECT n + 1 FROM pg_seq WHERE n < 5) SELECT n FROM pg_seq"},
+		{
+			name: "cte name outside its scope",
+			sql:  "SELECT * FROM pg_authid, (WITH pg_authid AS (SELECT 1) SELECT * FROM pg_authid) x",
+			rule: RuleDeniedSchema,
+		},
+		{
+			name: "cte name in a sibling subquery",
+			sql:  "SELECT (SELECT count(*) FROM pg_authid) FROM (WITH pg_authid AS (SELECT 1) SELECT * FROM pg_authid) x",
+			rule: RuleDeniedSchema,
+		},
+		{name: "cte body reading its own name", sql: "WITH pg_authid AS (SELECT * FROM pg_authid) SELECT * FROM pg_authid", rule: RuleDeniedSchema},
+		{name: "parse error", sql: "SELEC id FROM orders", rule: RuleParse},
+		{name: "two statements", sql: "SELECT 1; SELECT 2", rule: RuleSingleStatement},
+		{name: "delete", sql: "DELETE FROM orders", rule: RuleReadOnly},
+		{name: "data-modifying cte", sql: "WITH gone AS (DELETE FROM orders RETURNING id) SELECT id FROM gone", rule: RuleReadOnly},
+		{name: "select into", sql: "SELECT id INTO copy FROM orders", rule: RuleSelectInto},
+		{name: "for update", sql: "SELECT id FROM orders FOR UPDATE", rule: RuleLockingClause},
+		{name: "sleep", sql: "SELECT pg_sleep(10)", rule: RuleDeniedFunction},
+		{name: "qualified denied function", sql: "SELECT pg_catalog.pg_read_file('/etc/passwd')", rule: RuleDeniedFunction},
+		{name: "current_setting", sql: "SELECT current_setting('data_directory')", rule: RuleDeniedFunction},
+		{name: "lo_get", sql: "SELECT lo_get(16384)", rule: RuleDeniedFunction},
+		{name: "qualified catalog", sql: "SELECT usename FROM pg_catalog.pg_user", rule: RuleDeniedSchema},
+		{name: "unqualified catalog", sql: "SELECT usename, passwd FROM pg_shadow", rule: RuleDeniedSchema},
+		{name: "catalog in subquery", sql: "SELECT id FROM orders WHERE EXISTS (SELECT 1 FROM pg_authid)", rule: RuleDeniedSchema},
+		{name: "information schema", sql: "SELECT table_name FROM information_schema.tables", rule: RuleDeniedSchema},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateSQL(tt.sql, DefaultSQLPolicy())
+			if tt.rule == "" {
+				if err != nil {
+					t.Fatalf("expected %q to pass, got %v", tt.sql, err)
+				}
+				return
+			}
+			validationErr := AsSQLValidationError(err)
+			if validationErr == nil {
+				t.Fatalf("expected %q to be rejected by %s, got %v", tt.sql, tt.rule, err)
+			}
+			if got := validationErr.Violations[0].Rule; got != tt.rule {
+				t.Fatalf("expected rule %s, got %s (%v)", tt.rule, got, validationErr)
+			}
+		})
+	}
+}
+
+/* TestValidateSQLAllowedSchemas checks that allowed schemas apply to unqualified names through the search_path */
+func TestValidateSQLAllowedSchemas(t *testing.T) {
+	policy := DefaultSQLPolicy()
+	policy.AllowedSchemas = []string{"sales"}
+
+	tests := []struct {
+		sql     string
+		allowed bool
+	}{
+		{sql: "SELECT id FROM sales.orders", allowed: true},
+		{sql: "SELECT id FROM finance.ledger", allowed: false},
+		{sql: "SELECT id FROM orders", allowed: false}, // Resolves to public
+		{sql: "WITH o AS (SELECT id FROM sales.orders) SELECT id FROM o", allowed: true},
+	}
+	for _, tt := range tests {
+		_, err := ValidateSQL(tt.sql, policy)
+		if tt.allowed && err != nil {
+			t.Errorf("expected %q to pass, got %v", tt.sql, err)
+		}
+		if !tt.allowed && AsSQLValidationError(err) == nil {
+			t.Errorf("expected %q to be rejected, got %v", tt.sql, err)
+		}
+	}
+}
+
+/* TestValidateSQLRowLimit checks that a missing or larger LIMIT is replaced with the policy's cap */
+func TestValidateSQLRowLimit(t *testing.T) {
+	policy := DefaultSQLPolicy()
+	policy.MaxRows = 100
+
+	tests := []struct {
+		sql     string
+		applied bool
+		limit   string
+	}{
+		{sql: "SELECT id FROM orders", applied: true, limit: "LIMIT 100"},
+		{sql: "SELECT id FROM orders LIMIT 5000", applied: true, limit: "LIMIT 100"},
+		{sql: "SELECT id FROM orders LIMIT 10", applied: false, limit: "LIMIT 10"},
+		{sql: "SELECT id FROM orders LIMIT $1", applied: true, limit: "LIMIT LEAST($1, 100)"},
+	}
+	for _, tt := range tests {
+		validated, err := ValidateSQL(tt.sql, policy)
+		if err != nil {
+			t.Fatalf("expected %q to pass, got %v", tt.sql, err)
+		}
+		if validated.LimitApplied != tt.applied {
+			t.Errorf("%q: expected LimitApplied=%v, got %v", tt.sql, tt.applied, validated.LimitApplied)
+		}
+		if !strings.Contains(validated.SQL, tt.limit) {
+			t.Errorf("%q: expected %s in %q", tt.sql, tt.limit, validated.SQL)
+		}
+	}
+}
+
+/* TestReferencedTables checks that CTE names are not reported as tables */
+func TestReferencedTables(t *testing.T) {
+	tables, err := ReferencedTables("WITH r AS (SELECT id FROM sales.orders) SELECT r.id FROM r JOIN customers c ON c.id = r.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(tables)
+	if strings.Join(tables, ",") != "customers,sales.orders" {
+		t.Fatalf("unexpected tables: %v", tables)
+	}
+
+	// A CTE only hides the tables named like it inside the statement that defines it
+	tables, err = ReferencedTables("SELECT * FROM orders, (WITH orders AS (SELECT 1) SELECT * FROM orders) x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(tables, ",") != "orders" {
+		t.Fatalf("expected orders outside the CTE's scope to be reported, got %v", tables)
+	}
+}
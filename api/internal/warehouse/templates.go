@@ -0,0 +1,716 @@
+package warehouse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+)
+
+/* Template parameter types */
+const (
+	ParamTypeDate   = "date"
+	ParamTypeEnum   = "enum"
+	ParamTypeNumber = "number"
+	ParamTypeList   = "list"
+	ParamTypeLookup = "lookup" // A value that must exist in a table column
+)
+
+/* List item types */
+const (
+	ListItemText    = "text"
+	ListItemNumber  = "number"
+	ListItemInteger = "integer"
+	ListItemDate    = "date"
+)
+
+/* ErrTemplateNotFound is returned for templates that do not exist or that the user cannot see */
+var ErrTemplateNotFound = errors.New("query template not found")
+
+/* ErrTemplateInUse is returned when deleting a template that saved searches or answer cards still run */
+var ErrTemplateInUse = errors.New("query template is used by saved searches or answer cards")
+
+var (
+	parameterNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	relativeDatePattern  = regexp.MustCompile(`^today(?:([+-])(\d+)d)?$`)
+)
+
+/* QueryTemplateService manages parameterized query templates */
+type QueryTemplateService struct {
+	pool      *pgxpool.Pool
+	sqlPolicy SQLPolicy
+}
+
+/* NewQueryTemplateService creates a new query template service */
+func NewQueryTemplateService(pool *pgxpool.Pool) *QueryTemplateService {
+	return &QueryTemplateService{pool: pool, sqlPolicy: DefaultSQLPolicy()}
+}
+
+/* QueryTemplate is a read-only SQL statement with named, typed parameters written as :name */
+type QueryTemplate struct {
+	ID          uuid.UUID           `json:"id"`
+	Name        string              `json:"name"`
+	Description *string             `json:"description,omitempty"`
+	SQL         string              `json:"sql"`
+	Parameters  []TemplateParameter `json:"parameters"`
+	SchemaID    *uuid.UUID          `json:"schema_id,omitempty"`
+	OwnerID     *string             `json:"owner_id,omitempty"`
+	IsPublic    bool                `json:"is_public"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+/* TemplateParameter declares one template parameter */
+type TemplateParameter struct {
+	Name        string          `json:"name"`
+	Type        string          `json:"type"` // date, enum, number, list or lookup
+	Label       string          `json:"label,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Required    bool            `json:"required"`
+	Default     interface{}     `json:"default,omitempty"` // Dates also accept today, today-7d, today+1d
+	Options     []string        `json:"options,omitempty"` // Enum values, or allowed list items
+	Min         *float64        `json:"min,omitempty"`
+	Max         *float64        `json:"max,omitempty"`
+	Integer     bool            `json:"integer,omitempty"`   // Number must be whole and binds as bigint
+	ItemType    string          `json:"item_type,omitempty"` // List items: text, number, integer or date
+	MaxItems    int             `json:"max_items,omitempty"`
+	Lookup      *TemplateLookup `json:"lookup,omitempty"`
+}
+
+/* TemplateLookup names the column lookup parameter values must exist in */
+type TemplateLookup struct {
+	Schema string `json:"schema,omitempty"`
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
+
+/* ParameterViolation explains why a parameter definition or value was rejected */
+type ParameterViolation struct {
+	Parameter string `json:"parameter"`
+	Detail    string `json:"detail"`
+}
+
+/* TemplateParameterError is returned for invalid parameter definitions or values */
+type TemplateParameterError struct {
+	Violations []ParameterViolation
+}
+
+func (e *TemplateParameterError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = fmt.Sprintf("%s: %s", v.Parameter, v.Detail)
+	}
+	return "invalid template parameters: " + strings.Join(messages, "; ")
+}
+
+/* AsTemplateParameterError returns the parameter error wrapped in err, or nil */
+func AsTemplateParameterError(err error) *TemplateParameterError {
+	var paramErr *TemplateParameterError
+	if errors.As(err, &paramErr) {
+		return paramErr
+	}
+	return nil
+}
+
+/* IsTemplateInUse reports whether err means the template is still referenced */
+func IsTemplateInUse(err error) bool {
+	return errors.Is(err, ErrTemplateInUse)
+}
+
+/* IsTemplateNotFound reports whether err means the template does not exist or is hidden from the user */
+func IsTemplateNotFound(err error) bool {
+	return errors.Is(err, ErrTemplateNotFound)
+}
+
+/* TemplateResult is the result of executing a template */
+type TemplateResult struct {
+	TemplateID uuid.UUID                `json:"template_id"`
+	SQL        string                   `json:"sql"`        // Statement with $n placeholders as executed
+	Parameters map[string]interface{}   `json:"parameters"` // Resolved values, defaults included
+	Columns    []ResultColumn           `json:"columns"`
+	Rows       []map[string]interface{} `json:"rows"`
+	RowCount   int                      `json:"row_count"`
+}
+
+/* CreateTemplate validates and stores a query template */
+func (s *QueryTemplateService) CreateTemplate(ctx context.Context, template QueryTemplate) (*QueryTemplate, error) {
+	if err := s.validateTemplate(ctx, template); err != nil {
+		return nil, err
+	}
+
+	template.ID = uuid.New()
+	paramsJSON, _ := json.Marshal(template.Parameters)
+
+	query := `
+		INSERT INTO neuronip.query_templates
+		(id, name, description, sql_text, parameters, schema_id, owner_id, is_public, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		RETURNING created_at, updated_at`
+	err := s.pool.QueryRow(ctx, query,
+		template.ID, template.Name, template.Description, template.SQL, paramsJSON,
+		template.SchemaID, template.OwnerID, template.IsPublic,
+	).Scan(&template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query template: %w", err)
+	}
+	return &template, nil
+}
+
+/* GetTemplate retrieves a query template by ID; templates that are neither public nor owned by userID are not found */
+func (s *QueryTemplateService) GetTemplate(ctx context.Context, templateID uuid.UUID, userID *string) (*QueryTemplate, error) {
+	query := `
+		SELECT id, name, description, sql_text, parameters, schema_id, owner_id, is_public, created_at, updated_at
+		FROM neuronip.query_templates
+		WHERE id = $1 AND (is_public = true OR ($2::text IS NOT NULL AND owner_id = $2))`
+
+	var template QueryTemplate
+	var paramsJSON []byte
+	err := s.pool.QueryRow(ctx, query, templateID, userID).Scan(
+		&template.ID, &template.Name, &template.Description, &template.SQL, &paramsJSON,
+		&template.SchemaID, &template.OwnerID, &template.IsPublic, &template.CreatedAt, &template.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query template: %w", err)
+	}
+	json.Unmarshal(paramsJSON, &template.Parameters)
+	return &template, nil
+}
+
+/* ListTemplates lists templates owned by the user and public templates */
+func (s *QueryTemplateService) ListTemplates(ctx context.Context, userID *string) ([]QueryTemplate, error) {
+	query := `
+		SELECT id, name, description, sql_text, parameters, schema_id, owner_id, is_public, created_at, updated_at
+		FROM neuronip.query_templates
+		WHERE is_public = true OR ($1::text IS NOT NULL AND owner_id = $1)
+		ORDER BY name`
+
+	rows, err := s.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list query templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []QueryTemplate{}
+	for rows.Next() {
+		var template QueryTemplate
+		var paramsJSON []byte
+		err := rows.Scan(
+			&template.ID, &template.Name, &template.Description, &template.SQL, &paramsJSON,
+			&template.SchemaID, &template.OwnerID, &template.IsPublic, &template.CreatedAt, &template.UpdatedAt,
+		)
+		if err != nil {
+			continue
+		}
+		json.Unmarshal(paramsJSON, &template.Parameters)
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+/* DeleteTemplate deletes a query template owned by userID; templates still used by saved searches or answer cards are kept */
+func (s *QueryTemplateService) DeleteTemplate(ctx context.Context, templateID uuid.UUID, userID string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM neuronip.query_templates WHERE id = $1 AND owner_id = $2`, templateID, userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			return ErrTemplateInUse
+		}
+		return fmt.Errorf("failed to delete query template: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+/* ExecuteTemplate resolves parameter values and runs the template with every value bound server-side.
+ * The template must be public or owned by userID. */
+func (s *QueryTemplateService) ExecuteTemplate(ctx context.Context, templateID uuid.UUID, userID *string, values map[string]interface{}) (*TemplateResult, error) {
+	template, err := s.GetTemplate(ctx, templateID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled, names, err := compileTemplateSQL(template.SQL)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := s.resolveParameters(ctx, template.Parameters, values)
+	if err != nil {
+		return nil, err
+	}
+
+	validated, err := ValidateSQL(compiled, s.sqlPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template SQL: %w", err)
+	}
+	if err := checkLimitParameter(template.Parameters, names, validated); err != nil {
+		return nil, err
+	}
+
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = resolved[name]
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// Template SQL runs read-only like every other warehouse execution path
+	tx, err := s.pool.BeginTx(execCtx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin template transaction: %w", err)
+	}
+	defer tx.Rollback(execCtx)
+
+	rows, err := tx.Query(execCtx, validated.SQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+	defer rows.Close()
+
+	fieldDescriptions := rows.FieldDescriptions()
+	columns := resultColumns(rows.Conn(), fieldDescriptions)
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		rowValues, err := rows.Values()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get row values: %w", err)
+		}
+		row := make(map[string]interface{}, len(rowValues))
+		for i, desc := range fieldDescriptions {
+			row[desc.Name] = rowValues[i]
+		}
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return &TemplateResult{
+		TemplateID: template.ID,
+		SQL:        validated.SQL,
+		Parameters: resolved,
+		Columns:    columns,
+		Rows:       results,
+		RowCount:   len(results),
+	}, nil
+}
+
+/* validateTemplate checks parameter definitions, placeholders, the statement itself and parameter defaults */
+func (s *QueryTemplateService) validateTemplate(ctx context.Context, template QueryTemplate) error {
+	if strings.TrimSpace(template.Name) == "" {
+		return &TemplateParameterError{Violations: []ParameterViolation{{Parameter: "name", Detail: "template name is required"}}}
+	}
+
+	var violations []ParameterViolation
+	declared := make(map[string]bool)
+	for _, param := range template.Parameters {
+		if declared[param.Name] {
+			violations = append(violations, ParameterViolation{Parameter: param.Name, Detail: "declared more than once"})
+			continue
+		}
+		declared[param.Name] = true
+		paramViolations := validateParameterDefinition(param)
+		if len(paramViolations) == 0 && param.Type == ParamTypeLookup {
+			if _, err := s.lookupSQL(param.Lookup); err != nil {
+				paramViolations = append(paramViolations, ParameterViolation{Parameter: param.Name, Detail: fmt.Sprintf("lookup rejected: %v", err)})
+			}
+		}
+		violations = append(violations, paramViolations...)
+	}
+
+	compiled, names, err := compileTemplateSQL(template.SQL)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if !declared[name] {
+			violations = append(violations, ParameterViolation{Parameter: name, Detail: "used in SQL but not declared"})
+		}
+	}
+	if len(violations) > 0 {
+		return &TemplateParameterError{Violations: violations}
+	}
+
+	validated, err := ValidateSQL(compiled, s.sqlPolicy)
+	if err != nil {
+		return fmt.Errorf("invalid template SQL: %w", err)
+	}
+	if err := checkLimitParameter(template.Parameters, names, validated); err != nil {
+		return err
+	}
+
+	// Defaults must be valid values, so resolve them with no caller values
+	if _, err := s.resolveParameters(ctx, template.Parameters, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+/* checkLimitParameter requires a parameter used as the LIMIT to be an integer number, so the row cap holds */
+func checkLimitParameter(params []TemplateParameter, names []string, validated *ValidatedSQL) error {
+	if validated.limitParam == 0 || validated.limitParam > len(names) {
+		return nil
+	}
+	name := names[validated.limitParam-1]
+	for _, param := range params {
+		if param.Name == name && param.Type == ParamTypeNumber && param.Integer {
+			return nil
+		}
+	}
+	return &TemplateParameterError{Violations: []ParameterViolation{{Parameter: name, Detail: "parameters used as LIMIT must be integer numbers"}}}
+}
+
+/* validateParameterDefinition checks one parameter declaration */
+func validateParameterDefinition(param TemplateParameter) []ParameterViolation {
+	var violations []ParameterViolation
+	violate := func(detail string) {
+		violations = append(violations, ParameterViolation{Parameter: param.Name, Detail: detail})
+	}
+
+	if !parameterNamePattern.MatchString(param.Name) {
+		violate("name must start with a letter or underscore and contain only letters, digits and underscores")
+	}
+	switch param.Type {
+	case ParamTypeDate, ParamTypeNumber:
+	case ParamTypeEnum:
+		if len(param.Options) == 0 {
+			violate("enum parameters need options")
+		}
+	case ParamTypeList:
+		switch param.ItemType {
+		case "", ListItemText, ListItemNumber, ListItemInteger, ListItemDate:
+		default:
+			violate(fmt.Sprintf("unknown list item type %q", param.ItemType))
+		}
+	case ParamTypeLookup:
+		if param.Lookup == nil || param.Lookup.Table == "" || param.Lookup.Column == "" {
+			violate("lookup parameters need a table and column")
+		}
+	default:
+		violate(fmt.Sprintf("unknown parameter type %q", param.Type))
+	}
+	if param.Min != nil && param.Max != nil && *param.Min > *param.Max {
+		violate("min is greater than max")
+	}
+	return violations
+}
+
+/* resolveParameters validates caller values, applies defaults and converts values to their bind types */
+func (s *QueryTemplateService) resolveParameters(ctx context.Context, params []TemplateParameter, values map[string]interface{}) (map[string]interface{}, error) {
+	var violations []ParameterViolation
+	declared := make(map[string]bool, len(params))
+	resolved := make(map[string]interface{}, len(params))
+
+	for _, param := range params {
+		declared[param.Name] = true
+
+		value, provided := values[param.Name]
+		if !provided || value == nil {
+			value = param.Default
+		}
+		if value == nil {
+			if param.Required {
+				violations = append(violations, ParameterViolation{Parameter: param.Name, Detail: "value is required"})
+			}
+			resolved[param.Name] = nil
+			continue
+		}
+
+		bound, err := s.convertParameter(ctx, param, value)
+		if err != nil {
+			violations = append(violations, ParameterViolation{Parameter: param.Name, Detail: err.Error()})
+			continue
+		}
+		resolved[param.Name] = bound
+	}
+
+	for name := range values {
+		if !declared[name] {
+			violations = append(violations, ParameterViolation{Parameter: name, Detail: "unknown parameter"})
+		}
+	}
+
+	if len(violations) > 0 {
+		return nil, &TemplateParameterError{Violations: violations}
+	}
+	return resolved, nil
+}
+
+/* convertParameter checks a value against its declaration and returns the value to bind */
+func (s *QueryTemplateService) convertParameter(ctx context.Context, param TemplateParameter, value interface{}) (interface{}, error) {
+	switch param.Type {
+	case ParamTypeDate:
+		return parseDateValue(value)
+
+	case ParamTypeEnum:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string")
+		}
+		if !containsString(param.Options, text) {
+			return nil, fmt.Errorf("%q is not one of %s", text, strings.Join(param.Options, ", "))
+		}
+		return text, nil
+
+	case ParamTypeNumber:
+		number, err := parseNumberValue(value)
+		if err != nil {
+			return nil, err
+		}
+		if param.Min != nil && number < *param.Min {
+			return nil, fmt.Errorf("must be at least %v", *param.Min)
+		}
+		if param.Max != nil && number > *param.Max {
+			return nil, fmt.Errorf("must be at most %v", *param.Max)
+		}
+		if param.Integer {
+			if number != math.Trunc(number) {
+				return nil, fmt.Errorf("must be a whole number")
+			}
+			return int64(number), nil
+		}
+		return number, nil
+
+	case ParamTypeList:
+		return convertListValue(param, value)
+
+	case ParamTypeLookup:
+		text, ok := value.(string)
+		if !ok {
+			if number, err := parseNumberValue(value); err == nil {
+				text = strconv.FormatFloat(number, 'f', -1, 64)
+			} else {
+				return nil, fmt.Errorf("expected a string or number")
+			}
+		}
+		if err := s.checkLookup(ctx, param.Lookup, text); err != nil {
+			return nil, err
+		}
+		return text, nil
+	}
+	return nil, fmt.Errorf("unknown parameter type %q", param.Type)
+}
+
+/* lookupSQL builds the existence check for a lookup column and validates it against the SQL policy,
+ * so a lookup cannot reach schemas the template's own SQL could not */
+func (s *QueryTemplateService) lookupSQL(lookup *TemplateLookup) (*ValidatedSQL, error) {
+	table := pgx.Identifier{lookup.Table}
+	if lookup.Schema != "" {
+		table = pgx.Identifier{lookup.Schema, lookup.Table}
+	}
+	query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE %s::text = $1)`,
+		table.Sanitize(), pgx.Identifier{lookup.Column}.Sanitize())
+	return ValidateSQL(query, s.sqlPolicy)
+}
+
+/* checkLookup verifies that value exists in the lookup column */
+func (s *QueryTemplateService) checkLookup(ctx context.Context, lookup *TemplateLookup, value string) error {
+	validated, err := s.lookupSQL(lookup)
+	if err != nil {
+		return fmt.Errorf("lookup rejected: %v", err)
+	}
+
+	var exists bool
+	if err := s.pool.QueryRow(ctx, validated.SQL, value).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up value: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("%q not found in %s.%s", value, lookup.Table, lookup.Column)
+	}
+	return nil
+}
+
+/* convertListValue converts a JSON array, or a comma-separated string, to a typed slice bound as an array */
+func convertListValue(param TemplateParameter, value interface{}) (interface{}, error) {
+	var items []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		items = v
+	case []string:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("expected a list")
+	}
+
+	if param.MaxItems > 0 && len(items) > param.MaxItems {
+		return nil, fmt.Errorf("at most %d items allowed", param.MaxItems)
+	}
+	if param.Required && len(items) == 0 {
+		return nil, fmt.Errorf("at least one item is required")
+	}
+
+	switch param.ItemType {
+	case ListItemNumber, ListItemInteger:
+		numbers := make([]float64, len(items))
+		integers := make([]int64, len(items))
+		for i, item := range items {
+			number, err := parseNumberValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %v", i, err)
+			}
+			if param.ItemType == ListItemInteger && number != math.Trunc(number) {
+				return nil, fmt.Errorf("item %d: must be a whole number", i)
+			}
+			numbers[i] = number
+			integers[i] = int64(number)
+		}
+		if param.ItemType == ListItemInteger {
+			return integers, nil
+		}
+		return numbers, nil
+
+	case ListItemDate:
+		dates := make([]time.Time, len(items))
+		for i, item := range items {
+			date, err := parseDateValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %v", i, err)
+			}
+			dates[i] = date
+		}
+		return dates, nil
+
+	default:
+		texts := make([]string, len(items))
+		for i, item := range items {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("item %d: expected a string", i)
+			}
+			if len(param.Options) > 0 && !containsString(param.Options, text) {
+				return nil, fmt.Errorf("item %d: %q is not one of %s", i, text, strings.Join(param.Options, ", "))
+			}
+			texts[i] = text
+		}
+		return texts, nil
+	}
+}
+
+/* parseDateValue accepts YYYY-MM-DD, RFC 3339 timestamps and today, today-Nd or today+Nd */
+func parseDateValue(value interface{}) (time.Time, error) {
+	text, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("expected a date string")
+	}
+	text = strings.TrimSpace(text)
+
+	if match := relativeDatePattern.FindStringSubmatch(strings.ToLower(text)); match != nil {
+		now := time.Now().UTC()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		if match[2] == "" {
+			return today, nil
+		}
+		days, _ := strconv.Atoi(match[2])
+		if match[1] == "-" {
+			days = -days
+		}
+		return today.AddDate(0, 0, days), nil
+	}
+	if date, err := time.Parse("2006-01-02", text); err == nil {
+		return date, nil
+	}
+	if timestamp, err := time.Parse(time.RFC3339, text); err == nil {
+		return timestamp, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date (use YYYY-MM-DD, RFC 3339 or today-Nd)", text)
+}
+
+/* parseNumberValue accepts JSON numbers and numeric strings */
+func parseNumberValue(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", v)
+		}
+		return number, nil
+	}
+	return 0, fmt.Errorf("expected a number")
+}
+
+/* compileTemplateSQL replaces :name placeholders with $n and returns the names in placeholder order.
+ * Placeholders are found with the PostgreSQL scanner, so string literals, comments and :: casts are left alone. */
+func compileTemplateSQL(sql string) (string, []string, error) {
+	scan, err := pg_query.Scan(sql)
+	if err != nil {
+		return "", nil, &SQLValidationError{Violations: []SQLViolation{{
+			Rule: RuleParse, Node: "query", Detail: err.Error(), Location: -1,
+		}}}
+	}
+
+	var out strings.Builder
+	var names []string
+	positions := make(map[string]int)
+	last := 0
+	tokens := scan.Tokens
+	for i, token := range tokens {
+		if token.Token == pg_query.Token_PARAM {
+			return "", nil, &TemplateParameterError{Violations: []ParameterViolation{{
+				Parameter: sql[token.Start:token.End],
+				Detail:    "use named :parameters instead of positional placeholders",
+			}}}
+		}
+		if token.Token != pg_query.Token_ASCII_58 || i+1 >= len(tokens) {
+			continue
+		}
+		next := tokens[i+1]
+		name := sql[next.Start:next.End]
+		if next.Start != token.End || !parameterNamePattern.MatchString(name) {
+			continue
+		}
+
+		position, seen := positions[name]
+		if !seen {
+			names = append(names, name)
+			position = len(names)
+			positions[name] = position
+		}
+		out.WriteString(sql[last:token.Start])
+		out.WriteString("$" + strconv.Itoa(position))
+		last = int(next.End)
+	}
+	out.WriteString(sql[last:])
+	return out.String(), names, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package warehouse
+
+import (
+	"context"
+	"testing"
+)
+
+/* TestLookupSQL checks that lookup columns are held to the same SQL policy as template statements */
+func TestLookupSQL(t *testing.T) {
+	s := &QueryTemplateService{sqlPolicy: DefaultSQLPolicy()}
+
+	tests := []struct {
+		name    string
+		lookup  TemplateLookup
+		allowed bool
+	}{
+		{name: "qualified table", lookup: TemplateLookup{Schema: "sales", Table: "regions", Column: "code"}, allowed: true},
+		{name: "unqualified table", lookup: TemplateLookup{Table: "regions", Column: "code"}, allowed: true},
+		{name: "catalog schema", lookup: TemplateLookup{Schema: "pg_catalog", Table: "pg_authid", Column: "rolname"}},
+		{name: "unqualified catalog table", lookup: TemplateLookup{Table: "pg_shadow", Column: "usename"}},
+		{name: "information schema", lookup: TemplateLookup{Schema: "information_schema", Table: "tables", Column: "table_name"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.lookupSQL(&tt.lookup)
+			if tt.allowed && err != nil {
+				t.Fatalf("expected lookup to pass, got %v", err)
+			}
+			if !tt.allowed && AsSQLValidationError(err) == nil {
+				t.Fatalf("expected lookup to be rejected, got %v", err)
+			}
+		})
+	}
+}
+
+/* TestValidateTemplateLimitParameter checks that only integer number parameters may be used as the LIMIT */
+func TestValidateTemplateLimitParameter(t *testing.T) {
+	s := &QueryTemplateService{sqlPolicy: DefaultSQLPolicy()}
+
+	tests := []struct {
+		name    string
+		param   TemplateParameter
+		allowed bool
+	}{
+		{name: "integer number", param: TemplateParameter{Name: "n", Type: ParamTypeNumber, Integer: true, Default: 10}, allowed: true},
+		{name: "fractional number", param: TemplateParameter{Name: "n", Type: ParamTypeNumber, Default: 10}},
+		{name: "enum", param: TemplateParameter{Name: "n", Type: ParamTypeEnum, Options: []string{"10"}, Default: "10"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.validateTemplate(context.Background(), QueryTemplate{
+				Name:       "top orders",
+				SQL:        "SELECT id FROM orders ORDER BY total DESC LIMIT :n",
+				Parameters: []TemplateParameter{tt.param},
+			})
+			if tt.allowed && err != nil {
+				t.Fatalf("expected template to pass, got %v", err)
+			}
+			if !tt.allowed && AsTemplateParameterError(err) == nil {
+				t.Fatalf("expected template to be rejected, got %v", err)
+			}
+		})
+	}
+}
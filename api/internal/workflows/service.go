@@ -160,23 +160,11 @@ func (s *Service) ExecuteWorkflowViaNeuronAgent(ctx context.Context, workflowID
 		return nil, fmt.Errorf("agent client not configured")
 	}
 
-	// Get workflow definition
-	workflow, err := s.GetWorkflow(ctx, workflowID)
-	if err != nil {
+	// Check the workflow exists
+	if _, err := s.GetWorkflow(ctx, workflowID); err != nil {
 		return nil, fmt.Errorf("failed to get workflow: %w", err)
 	}
 
-	// Convert workflow definition to NeuronAgent format
-	definition := map[string]interface{}{
-		"steps":      workflow.WorkflowDefinition,
-		"start_step": workflow.WorkflowDefinition["start_step"],
-	}
-
-	config := map[string]interface{}{
-		"workflow_id": workflowID.String(),
-		"enabled":     workflow.Enabled,
-	}
-
 	// Execute via NeuronAgent
 	result, err := s.agentClient.ExecuteWorkflow(ctx, workflowID.String(), input)
 	if err != nil {
@@ -1106,6 +1094,44 @@ func (s *Service) GetWorkflowExecutionStatus(ctx context.Context, executionID uu
 	return &status, nil
 }
 
+/* workflowExecution is a stored workflow execution */
+type workflowExecution struct {
+	ID         uuid.UUID              `json:"id"`
+	WorkflowID uuid.UUID              `json:"workflow_id"`
+	Status     string                 `json:"status"`
+	InputData  map[string]interface{} `json:"input_data,omitempty"`
+	OutputData map[string]interface{} `json:"output_data,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+}
+
+/* loadExecution reads a stored workflow execution for snapshots; the agent session, when one ran it, is carried in Metadata */
+func (s *Service) loadExecution(ctx context.Context, executionID uuid.UUID) (*workflowExecution, error) {
+	var execution workflowExecution
+	var inputJSON, outputJSON []byte
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, workflow_id, status, input_data, output_data
+		FROM neuronip.workflow_executions
+		WHERE id = $1`, executionID).Scan(
+		&execution.ID, &execution.WorkflowID, &execution.Status, &inputJSON, &outputJSON,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get execution: %w", err)
+	}
+
+	if len(inputJSON) > 0 {
+		json.Unmarshal(inputJSON, &execution.InputData)
+	}
+	if len(outputJSON) > 0 {
+		json.Unmarshal(outputJSON, &execution.OutputData)
+	}
+	execution.Metadata = map[string]interface{}{}
+	if sid, ok := execution.OutputData["session_id"].(string); ok {
+		execution.Metadata["session_id"] = sid
+	}
+
+	return &execution, nil
+}
+
 /* WorkflowExecutionStatus represents workflow execution status */
 type WorkflowExecutionStatus struct {
 	ExecutionID     uuid.UUID                `json:"execution_id"`
@@ -1672,7 +1698,7 @@ func (s *Service) CreateWorkflowSnapshot(ctx context.Context, executionID uuid.U
 	}
 
 	// Get execution state
-	execution, err := s.GetWorkflowExecution(ctx, executionID)
+	execution, err := s.loadExecution(ctx, executionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get execution: %w", err)
 	}
@@ -1738,7 +1764,7 @@ func (s *Service) ListWorkflowSnapshots(ctx context.Context, executionID uuid.UU
 	}
 
 	// Get session ID from execution
-	execution, err := s.GetWorkflowExecution(ctx, executionID)
+	execution, err := s.loadExecution(ctx, executionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get execution: %w", err)
 	}
@@ -0,0 +1,84 @@
+-- Migration: Retrieval Evaluation
+-- Description: Adds golden query sets and offline retrieval evaluation runs with baseline comparison
+
+-- Golden query sets: Named collections of judged queries
+CREATE TABLE IF NOT EXISTS neuronip.golden_query_sets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    description TEXT,
+    collection_id UUID REFERENCES neuronip.knowledge_collections(id) ON DELETE SET NULL,
+    baseline_run_id UUID,
+    regression_thresholds JSONB DEFAULT '{}', -- metric name -> maximum allowed drop
+    created_by TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE neuronip.golden_query_sets IS 'Golden query sets for offline retrieval evaluation';
+
+-- Golden queries: Queries with graded relevance judgments
+CREATE TABLE IF NOT EXISTS neuronip.golden_queries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    set_id UUID NOT NULL REFERENCES neuronip.golden_query_sets(id) ON DELETE CASCADE,
+    query_text TEXT NOT NULL,
+    judgments JSONB NOT NULL DEFAULT '[]', -- [{target_id, target_type, grade}]
+    metadata JSONB DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE neuronip.golden_queries IS 'Golden queries with relevant document or chunk IDs and graded relevance';
+
+CREATE INDEX IF NOT EXISTS idx_golden_queries_set ON neuronip.golden_queries(set_id);
+
+-- Retrieval evaluation runs: One replay of a golden set against a search configuration
+CREATE TABLE IF NOT EXISTS neuronip.retrieval_evaluation_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    set_id UUID NOT NULL REFERENCES neuronip.golden_query_sets(id) ON DELETE CASCADE,
+    config JSONB NOT NULL DEFAULT '{}', -- distance metric, pipeline version, chunking, reranker, k values
+    status TEXT NOT NULL CHECK (status IN ('running', 'completed', 'failed')) DEFAULT 'running',
+    metrics JSONB DEFAULT '{}', -- recall@k, precision@k, mrr, ndcg@k
+    baseline_run_id UUID REFERENCES neuronip.retrieval_evaluation_runs(id) ON DELETE SET NULL,
+    comparison JSONB,
+    passed BOOLEAN,
+    queries_evaluated INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    triggered_by TEXT,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+COMMENT ON TABLE neuronip.retrieval_evaluation_runs IS 'Offline retrieval evaluation runs over golden query sets';
+
+CREATE INDEX IF NOT EXISTS idx_retrieval_evaluation_runs_set ON neuronip.retrieval_evaluation_runs(set_id);
+CREATE INDEX IF NOT EXISTS idx_retrieval_evaluation_runs_started ON neuronip.retrieval_evaluation_runs(started_at DESC);
+
+ALTER TABLE neuronip.golden_query_sets DROP CONSTRAINT IF EXISTS fk_golden_query_sets_baseline_run;
+ALTER TABLE neuronip.golden_query_sets ADD CONSTRAINT fk_golden_query_sets_baseline_run
+    FOREIGN KEY (baseline_run_id) REFERENCES neuronip.retrieval_evaluation_runs(id) ON DELETE SET NULL;
+
+-- Retrieval evaluation results: Per-query ranking and metrics for a run
+CREATE TABLE IF NOT EXISTS neuronip.retrieval_evaluation_results (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    run_id UUID NOT NULL REFERENCES neuronip.retrieval_evaluation_runs(id) ON DELETE CASCADE,
+    query_id UUID NOT NULL REFERENCES neuronip.golden_queries(id) ON DELETE CASCADE,
+    retrieved_ids JSONB NOT NULL DEFAULT '[]',
+    metrics JSONB DEFAULT '{}',
+    latency_ms BIGINT NOT NULL DEFAULT 0,
+    error_message TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE neuronip.retrieval_evaluation_results IS 'Per-query results of retrieval evaluation runs';
+
+CREATE INDEX IF NOT EXISTS idx_retrieval_evaluation_results_run ON neuronip.retrieval_evaluation_results(run_id);
+
+-- Update trigger for golden_query_sets
+CREATE OR REPLACE FUNCTION neuronip.update_golden_query_sets_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_update_golden_query_sets_updated_at ON neuronip.golden_query_sets;
+CREATE TRIGGER trigger_update_golden_query_sets_updated_at
+    BEFORE UPDATE ON neuronip.golden_query_sets
+    FOR EACH ROW
+    EXECUTE FUNCTION neuronip.update_golden_query_sets_updated_at();
@@ -0,0 +1,67 @@
+-- Migration: Semantic Answer Cache
+-- Description: Adds an embedding-keyed answer cache for RAG and warehouse NL questions, scoped by permissions
+
+-- Semantic answer cache: Answers keyed on question embedding and permission scope
+CREATE TABLE IF NOT EXISTS neuronip.semantic_answer_cache (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    namespace TEXT NOT NULL CHECK (namespace IN ('rag', 'warehouse')),
+    scope_key TEXT NOT NULL, -- Hash of tenant, roles and optionally user
+    partition_key TEXT NOT NULL DEFAULT '', -- Collection ID for RAG, schema ID for warehouse
+    question_text TEXT NOT NULL,
+    question_embedding vector NOT NULL,
+    answer JSONB NOT NULL,
+    source_document_ids UUID[] DEFAULT '{}',
+    source_tables TEXT[] DEFAULT '{}',
+    hit_count INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_hit_at TIMESTAMPTZ
+);
+COMMENT ON TABLE neuronip.semantic_answer_cache IS 'Semantic cache of RAG and warehouse answers keyed on question embedding and permission scope';
+
+CREATE INDEX IF NOT EXISTS idx_semantic_answer_cache_lookup ON neuronip.semantic_answer_cache(namespace, scope_key, partition_key);
+CREATE INDEX IF NOT EXISTS idx_semantic_answer_cache_expires ON neuronip.semantic_answer_cache(expires_at);
+CREATE INDEX IF NOT EXISTS idx_semantic_answer_cache_documents ON neuronip.semantic_answer_cache USING GIN(source_document_ids);
+CREATE INDEX IF NOT EXISTS idx_semantic_answer_cache_tables ON neuronip.semantic_answer_cache USING GIN(source_tables);
+
+-- Invalidate cached RAG answers when knowledge documents change
+CREATE OR REPLACE FUNCTION neuronip.invalidate_semantic_answer_cache_on_document()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        -- A new document can answer questions cached for its collection or across all collections
+        DELETE FROM neuronip.semantic_answer_cache
+        WHERE namespace = 'rag'
+          AND (partition_key = '' OR partition_key = COALESCE(NEW.collection_id::text, ''));
+        RETURN NEW;
+    END IF;
+
+    DELETE FROM neuronip.semantic_answer_cache
+    WHERE OLD.id = ANY(source_document_ids);
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_invalidate_semantic_answer_cache_on_document ON neuronip.knowledge_documents;
+CREATE TRIGGER trigger_invalidate_semantic_answer_cache_on_document
+    AFTER INSERT OR UPDATE OR DELETE ON neuronip.knowledge_documents
+    FOR EACH ROW
+    EXECUTE FUNCTION neuronip.invalidate_semantic_answer_cache_on_document();
+
+-- Function to clean expired semantic cache entries
+CREATE OR REPLACE FUNCTION neuronip.cleanup_expired_semantic_answer_cache()
+RETURNS INTEGER AS $$
+DECLARE
+    deleted_count INTEGER;
+BEGIN
+    DELETE FROM neuronip.semantic_answer_cache
+    WHERE expires_at < NOW();
+
+    GET DIAGNOSTICS deleted_count = ROW_COUNT;
+    RETURN deleted_count;
+END;
+$$ LANGUAGE plpgsql;
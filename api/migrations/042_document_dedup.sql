@@ -0,0 +1,67 @@
+-- Migration: Document Deduplication
+-- Description: Adds content-hash and SimHash fingerprints to knowledge documents and links duplicates to a canonical document
+
+ALTER TABLE neuronip.knowledge_documents ADD COLUMN IF NOT EXISTS content_hash TEXT; -- SHA-256 of normalized content
+ALTER TABLE neuronip.knowledge_documents ADD COLUMN IF NOT EXISTS simhash BIGINT; -- 64-bit SimHash over word shingles
+ALTER TABLE neuronip.knowledge_documents ADD COLUMN IF NOT EXISTS canonical_document_id UUID REFERENCES neuronip.knowledge_documents(id) ON DELETE SET NULL;
+ALTER TABLE neuronip.knowledge_documents ADD COLUMN IF NOT EXISTS duplicate_type TEXT CHECK (duplicate_type IN ('exact', 'near'));
+ALTER TABLE neuronip.knowledge_documents ADD COLUMN IF NOT EXISTS duplicate_similarity FLOAT;
+
+COMMENT ON COLUMN neuronip.knowledge_documents.canonical_document_id IS 'Canonical document this document duplicates; exact duplicates are not embedded';
+
+CREATE INDEX IF NOT EXISTS idx_knowledge_documents_content_hash ON neuronip.knowledge_documents(collection_id, content_hash);
+CREATE INDEX IF NOT EXISTS idx_knowledge_documents_canonical ON neuronip.knowledge_documents(canonical_document_id) WHERE canonical_document_id IS NOT NULL;
+
+-- SimHash bands: 16-bit slices of each SimHash for near-duplicate candidate lookup
+CREATE TABLE IF NOT EXISTS neuronip.document_simhash_bands (
+    document_id UUID NOT NULL REFERENCES neuronip.knowledge_documents(id) ON DELETE CASCADE,
+    band_index SMALLINT NOT NULL,
+    band_value INTEGER NOT NULL,
+    PRIMARY KEY (document_id, band_index)
+);
+COMMENT ON TABLE neuronip.document_simhash_bands IS 'SimHash bands for near-duplicate document lookup';
+
+CREATE INDEX IF NOT EXISTS idx_document_simhash_bands_lookup ON neuronip.document_simhash_bands(band_index, band_value);
+
+-- Promote a duplicate when its canonical document is deleted so exact duplicates keep their embeddings
+CREATE OR REPLACE FUNCTION neuronip.promote_duplicate_on_canonical_delete()
+RETURNS TRIGGER AS $$
+DECLARE
+    successor_id UUID;
+BEGIN
+    SELECT id INTO successor_id
+    FROM neuronip.knowledge_documents
+    WHERE canonical_document_id = OLD.id
+    ORDER BY (duplicate_type = 'exact') DESC, created_at
+    LIMIT 1;
+
+    IF successor_id IS NULL THEN
+        RETURN OLD;
+    END IF;
+
+    -- Exact duplicates were never embedded; hand over the canonical embeddings
+    IF NOT EXISTS (SELECT 1 FROM neuronip.knowledge_embeddings WHERE document_id = successor_id) THEN
+        UPDATE neuronip.knowledge_embeddings SET document_id = successor_id WHERE document_id = OLD.id;
+    END IF;
+
+    UPDATE neuronip.knowledge_documents
+    SET canonical_document_id = NULL, duplicate_type = NULL, duplicate_similarity = NULL
+    WHERE id = successor_id;
+
+    UPDATE neuronip.knowledge_documents
+    SET canonical_document_id = successor_id
+    WHERE canonical_document_id = OLD.id AND id != successor_id;
+
+    IF NOT EXISTS (SELECT 1 FROM neuronip.document_simhash_bands WHERE document_id = successor_id) THEN
+        UPDATE neuronip.document_simhash_bands SET document_id = successor_id WHERE document_id = OLD.id;
+    END IF;
+
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_promote_duplicate_on_canonical_delete ON neuronip.knowledge_documents;
+CREATE TRIGGER trigger_promote_duplicate_on_canonical_delete
+    BEFORE DELETE ON neuronip.knowledge_documents
+    FOR EACH ROW
+    EXECUTE FUNCTION neuronip.promote_duplicate_on_canonical_delete();
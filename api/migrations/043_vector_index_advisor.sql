@@ -0,0 +1,26 @@
+-- Migration: Vector Index Advisor
+-- Description: Records vector index recommendations, builds and recall benchmarks
+
+-- Vector index advisories: One analysis of a vector column with its recommendation and benchmark
+CREATE TABLE IF NOT EXISTS neuronip.vector_index_advisories (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    table_name TEXT NOT NULL,
+    column_name TEXT NOT NULL,
+    index_name TEXT NOT NULL,
+    row_count BIGINT NOT NULL DEFAULT 0,
+    dimensions INTEGER NOT NULL DEFAULT 0,
+    daily_queries FLOAT NOT NULL DEFAULT 0,
+    current_index_type TEXT,
+    current_params JSONB DEFAULT '{}',
+    recommended_type TEXT NOT NULL CHECK (recommended_type IN ('hnsw', 'ivf', 'none')),
+    recommended_params JSONB DEFAULT '{}', -- m, ef_construction, ef_search or lists, probes
+    reason TEXT,
+    status TEXT NOT NULL CHECK (status IN ('recommended', 'building', 'built', 'unchanged', 'failed')) DEFAULT 'recommended',
+    benchmark JSONB, -- recall@k against exact search on a sample, latencies
+    error_message TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    applied_at TIMESTAMPTZ
+);
+COMMENT ON TABLE neuronip.vector_index_advisories IS 'Vector index advisor recommendations, builds and recall benchmarks';
+
+CREATE INDEX IF NOT EXISTS idx_vector_index_advisories_index ON neuronip.vector_index_advisories(index_name, created_at DESC);
@@ -0,0 +1,33 @@
+-- Migration: RAG Conversations
+-- Description: Conversation sessions for follow-up RAG questions with rewritten queries and rolling summaries
+
+-- RAG conversations: One multi-turn session
+CREATE TABLE IF NOT EXISTS neuronip.rag_conversations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id TEXT,
+    collection_id TEXT,
+    summary TEXT NOT NULL DEFAULT '', -- Rolling summary of turns older than the recent window
+    summarized_turns INTEGER NOT NULL DEFAULT 0, -- Number of leading turns folded into the summary
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE neuronip.rag_conversations IS 'Multi-turn RAG conversation sessions';
+
+CREATE INDEX IF NOT EXISTS idx_rag_conversations_user ON neuronip.rag_conversations(user_id, updated_at DESC);
+
+-- RAG conversation turns: Question, standalone rewrite and answer of each turn
+CREATE TABLE IF NOT EXISTS neuronip.rag_conversation_turns (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id UUID NOT NULL REFERENCES neuronip.rag_conversations(id) ON DELETE CASCADE,
+    turn_index INTEGER NOT NULL,
+    question TEXT NOT NULL, -- Original question as asked
+    rewritten_query TEXT NOT NULL, -- Standalone query used for retrieval
+    answer TEXT NOT NULL DEFAULT '',
+    source_document_ids UUID[] DEFAULT '{}',
+    context_keys TEXT[] DEFAULT '{}', -- Hashes of context passages shown to the model in this turn
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(conversation_id, turn_index)
+);
+COMMENT ON TABLE neuronip.rag_conversation_turns IS 'Turns of RAG conversations with original and rewritten queries';
+
+CREATE INDEX IF NOT EXISTS idx_rag_conversation_turns_conversation ON neuronip.rag_conversation_turns(conversation_id, turn_index);
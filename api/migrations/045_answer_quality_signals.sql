@@ -0,0 +1,42 @@
+-- Migration: Answer Quality Signals
+-- Description: Storage for retrieval metrics and hallucination signals, including claim-level groundedness evidence
+
+-- Retrieval metrics: Documents retrieved and used per query
+CREATE TABLE IF NOT EXISTS neuronip.retrieval_metrics (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    query_id UUID,
+    agent_run_id UUID,
+    retrieval_type TEXT NOT NULL,
+    documents_retrieved INTEGER NOT NULL DEFAULT 0,
+    documents_used INTEGER NOT NULL DEFAULT 0,
+    hit_rate FLOAT NOT NULL DEFAULT 0,
+    evidence_coverage FLOAT NOT NULL DEFAULT 0,
+    avg_similarity FLOAT NOT NULL DEFAULT 0,
+    retrieval_latency_ms BIGINT NOT NULL DEFAULT 0,
+    metadata JSONB DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE neuronip.retrieval_metrics IS 'Retrieval quality metrics per query';
+
+CREATE INDEX IF NOT EXISTS idx_retrieval_metrics_query ON neuronip.retrieval_metrics(query_id);
+CREATE INDEX IF NOT EXISTS idx_retrieval_metrics_created ON neuronip.retrieval_metrics(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_retrieval_metrics_table ON neuronip.retrieval_metrics((metadata->>'table'), created_at DESC);
+
+-- Hallucination signals: Risk signals for generated answers; groundedness checks store per-claim evidence in metadata
+CREATE TABLE IF NOT EXISTS neuronip.hallucination_signals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    query_id UUID,
+    agent_run_id UUID,
+    response_id UUID, -- Answer or support ticket the signal describes
+    confidence_score FLOAT NOT NULL DEFAULT 0,
+    risk_level TEXT NOT NULL CHECK (risk_level IN ('low', 'medium', 'high', 'critical')),
+    citation_accuracy FLOAT NOT NULL DEFAULT 0,
+    evidence_strength FLOAT NOT NULL DEFAULT 0,
+    flags JSONB DEFAULT '[]',
+    metadata JSONB DEFAULT '{}', -- groundedness score and claims with supporting evidence
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE neuronip.hallucination_signals IS 'Hallucination risk signals and claim-level groundedness results';
+
+CREATE INDEX IF NOT EXISTS idx_hallucination_signals_query ON neuronip.hallucination_signals(query_id);
+CREATE INDEX IF NOT EXISTS idx_hallucination_signals_risk ON neuronip.hallucination_signals(risk_level, created_at DESC);
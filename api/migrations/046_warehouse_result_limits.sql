@@ -0,0 +1,16 @@
+-- Migration: Warehouse Result Limits
+-- Description: Per-role limits for cursor-paginated and streamed warehouse query results
+
+-- Warehouse result limits: Page size, row cap, open cursors and timeouts per role
+CREATE TABLE IF NOT EXISTS neuronip.warehouse_result_limits (
+    role_name TEXT PRIMARY KEY,
+    default_page_size INTEGER NOT NULL DEFAULT 1000,
+    max_page_size INTEGER NOT NULL DEFAULT 10000,
+    max_total_rows INTEGER NOT NULL DEFAULT 1000000, -- Row cap for a cursor or stream
+    max_open_cursors INTEGER NOT NULL DEFAULT 4, -- Open cursors per user
+    cursor_idle_seconds INTEGER NOT NULL DEFAULT 300, -- Cursors unused for this long are closed
+    statement_timeout_seconds INTEGER NOT NULL DEFAULT 300,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE neuronip.warehouse_result_limits IS 'Per-role limits for paginated and streamed warehouse results';
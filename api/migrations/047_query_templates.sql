@@ -0,0 +1,30 @@
+-- Migration: Query Templates
+-- Description: Parameterized SQL templates with typed parameters, referenced by saved searches and answer cards
+
+-- Query templates: Read-only SQL with named :parameters bound server-side
+CREATE TABLE IF NOT EXISTS neuronip.query_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    description TEXT,
+    sql_text TEXT NOT NULL, -- SQL with :name placeholders
+    parameters JSONB NOT NULL DEFAULT '[]', -- Declared parameters: name, type, default and validation rules
+    schema_id UUID REFERENCES neuronip.warehouse_schemas(id) ON DELETE SET NULL,
+    owner_id TEXT,
+    is_public BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE neuronip.query_templates IS 'Parameterized query templates with typed parameters';
+
+CREATE INDEX IF NOT EXISTS idx_query_templates_owner ON neuronip.query_templates(owner_id);
+CREATE INDEX IF NOT EXISTS idx_query_templates_public ON neuronip.query_templates(is_public) WHERE is_public = true;
+
+-- Saved searches and answer cards can run a template with stored parameter values.
+-- Templates in use cannot be deleted, so a template-backed search never silently falls back to its NL query.
+ALTER TABLE neuronip.saved_searches
+    ADD COLUMN IF NOT EXISTS template_id UUID REFERENCES neuronip.query_templates(id) ON DELETE RESTRICT,
+    ADD COLUMN IF NOT EXISTS template_params JSONB DEFAULT '{}';
+
+ALTER TABLE IF EXISTS neuronip.answer_cards
+    ADD COLUMN IF NOT EXISTS template_id UUID REFERENCES neuronip.query_templates(id) ON DELETE RESTRICT,
+    ADD COLUMN IF NOT EXISTS template_params JSONB DEFAULT '{}';
@@ -0,0 +1,33 @@
+-- Migration: Table-Aware Cache Invalidation
+-- Description: Records the tables each cached query reads so ingestion, CDC and schema changes invalidate affected entries
+
+-- Query cache: Source tables parsed from the cached SQL
+ALTER TABLE neuronip.query_cache
+    ADD COLUMN IF NOT EXISTS source_tables TEXT[] DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS stale_since TIMESTAMPTZ; -- First change to a source table served within its staleness tolerance
+
+CREATE INDEX IF NOT EXISTS idx_query_cache_tables ON neuronip.query_cache USING GIN(source_tables);
+
+-- Table staleness: How long cached results may be served after a table changes
+CREATE TABLE IF NOT EXISTS neuronip.cache_table_staleness (
+    table_name TEXT PRIMARY KEY, -- Lowercased, optionally schema-qualified
+    max_staleness_seconds INTEGER NOT NULL CHECK (max_staleness_seconds > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE neuronip.cache_table_staleness IS 'Per-table tolerances for bounded-stale cached reads';
+
+-- Cache invalidation events: Audit of table-driven invalidations
+CREATE TABLE IF NOT EXISTS neuronip.cache_invalidation_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tables TEXT[] NOT NULL,
+    reason TEXT NOT NULL CHECK (reason IN ('ingestion', 'cdc', 'schema_change')),
+    source TEXT, -- Data source, connector or job that changed the tables
+    entries_removed BIGINT NOT NULL DEFAULT 0,
+    entries_expiring BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE neuronip.cache_invalidation_events IS 'Cache entries invalidated by ingestion, CDC and schema changes';
+
+CREATE INDEX IF NOT EXISTS idx_cache_invalidation_events_created ON neuronip.cache_invalidation_events(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_cache_invalidation_events_tables ON neuronip.cache_invalidation_events USING GIN(tables);
@@ -0,0 +1,30 @@
+-- Migration: Warehouse Query Admission Control
+-- Description: Queue weights per tenant or role and a log of EXPLAIN-based admission decisions
+
+-- Admission classes: Share of execution slots for a tenant or role
+CREATE TABLE IF NOT EXISTS neuronip.warehouse_admission_classes (
+    class_key TEXT PRIMARY KEY, -- tenant:<id>, role:<name> or default
+    weight NUMERIC(8,2) NOT NULL DEFAULT 1 CHECK (weight > 0),
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE neuronip.warehouse_admission_classes IS 'Weighted fair queue classes for warehouse query admission';
+
+-- Admission decisions: How each query was admitted and why
+CREATE TABLE IF NOT EXISTS neuronip.warehouse_admissions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    class_key TEXT NOT NULL,
+    decision TEXT NOT NULL CHECK (decision IN ('run', 'async', 'rejected')),
+    estimated_cost DOUBLE PRECISION NOT NULL,
+    cost_limit DOUBLE PRECISION NOT NULL,
+    hotspots JSONB DEFAULT '[]', -- Plan nodes dominating the estimate
+    queue_wait_ms BIGINT NOT NULL DEFAULT 0,
+    async_query_id UUID REFERENCES neuronip.async_queries(id) ON DELETE SET NULL,
+    reason TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE neuronip.warehouse_admissions IS 'EXPLAIN-based admission decisions for warehouse queries';
+
+CREATE INDEX IF NOT EXISTS idx_warehouse_admissions_class ON neuronip.warehouse_admissions(class_key, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_warehouse_admissions_decision ON neuronip.warehouse_admissions(decision, created_at DESC);
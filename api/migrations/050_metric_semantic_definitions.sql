@@ -0,0 +1,8 @@
+-- Migration: Metric Semantic Definitions
+-- Description: Measures, dimensions, join paths and time grains that compile approved catalog metrics to SQL
+
+-- Metric catalog: Semantic-layer definition of each metric
+ALTER TABLE neuronip.metric_catalog
+    ADD COLUMN IF NOT EXISTS semantic_definition JSONB; -- base_table, measure, dimensions, joins, time_column, time_grains, filters
+
+CREATE INDEX IF NOT EXISTS idx_metric_catalog_semantic ON neuronip.metric_catalog(status) WHERE semantic_definition IS NOT NULL;
@@ -0,0 +1,29 @@
+-- Migration: Warehouse Query Attempts
+-- Description: Records each SQL attempt of the self-correcting NL-to-SQL loop
+
+-- Warehouse queries: Attempt that produced each history entry
+ALTER TABLE neuronip.warehouse_queries
+    ADD COLUMN IF NOT EXISTS correction_id UUID, -- Groups the attempts made for one question
+    ADD COLUMN IF NOT EXISTS attempt INTEGER NOT NULL DEFAULT 1;
+
+CREATE INDEX IF NOT EXISTS idx_warehouse_queries_correction ON neuronip.warehouse_queries(correction_id) WHERE correction_id IS NOT NULL;
+
+-- Warehouse query attempts: Generated SQL, the stage it reached and the error fed back for correction
+CREATE TABLE IF NOT EXISTS neuronip.warehouse_query_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    correction_id UUID NOT NULL,
+    attempt INTEGER NOT NULL CHECK (attempt > 0),
+    schema_id UUID REFERENCES neuronip.warehouse_schemas(id) ON DELETE SET NULL,
+    user_id TEXT,
+    natural_language_query TEXT NOT NULL,
+    generated_sql TEXT,
+    stage TEXT NOT NULL CHECK (stage IN ('validation', 'plan', 'execution', 'empty_result', 'succeeded')),
+    error_message TEXT,
+    query_id UUID, -- warehouse_queries entry, for attempts that ran
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(correction_id, attempt)
+);
+COMMENT ON TABLE neuronip.warehouse_query_attempts IS 'Attempts of the self-correcting NL-to-SQL loop';
+
+CREATE INDEX IF NOT EXISTS idx_warehouse_query_attempts_created ON neuronip.warehouse_query_attempts(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_warehouse_query_attempts_schema ON neuronip.warehouse_query_attempts(schema_id, created_at DESC);
@@ -0,0 +1,10 @@
+-- Migration: Vega-Lite Chart Specs
+-- Description: Ranked chart recommendations for query results and saved chart specs for answer cards
+
+-- Query results: All recommended charts; chart_config keeps the first
+ALTER TABLE neuronip.query_results
+    ADD COLUMN IF NOT EXISTS chart_recommendations JSONB; -- [{rank, mark, title, score, reason, spec}]
+
+-- Answer cards: Vega-Lite spec chosen for the card, reading the card result from the named data source "results"
+ALTER TABLE IF EXISTS neuronip.answer_cards
+    ADD COLUMN IF NOT EXISTS chart_spec JSONB;
@@ -0,0 +1,6 @@
+-- Migration: Quality Violation Sample Keys
+-- Description: Sample offending keys for consistency rules (referential integrity, cross-column invariants, reconciliation)
+
+-- Violations: Keys of sample offending rows, e.g. orphaned foreign keys or keys that differ between source and target
+ALTER TABLE neuronip.data_quality_violations
+    ADD COLUMN IF NOT EXISTS sample_keys JSONB DEFAULT '[]'; -- ["42", "order_id=7, line=2 (missing from target)"]
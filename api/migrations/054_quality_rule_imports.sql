@@ -0,0 +1,7 @@
+-- Migration: Quality Rule Imports
+-- Description: Key rules imported from dbt and Great Expectations test suites on their source test ID
+
+-- Data Quality Rules: One rule per imported test and connector, so re-imports update rules in place
+CREATE UNIQUE INDEX IF NOT EXISTS idx_quality_rules_import_test
+    ON neuronip.data_quality_rules(connector_id, (metadata->>'import_test_id'))
+    WHERE metadata ? 'import_test_id';
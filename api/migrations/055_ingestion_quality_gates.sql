@@ -0,0 +1,6 @@
+-- Migration: Ingestion Quality Gates
+-- Description: Outcomes of the quality rules run against each ingestion job's staged batch before commit
+
+-- Ingestion jobs: One entry per gate with its severity, status and the action taken on the load
+ALTER TABLE neuronip.ingestion_jobs
+    ADD COLUMN IF NOT EXISTS quality_gate_results JSONB; -- [{"rule_id": "...", "severity": "quarantine", "status": "failed", "action": "quarantined", "quarantined_rows": 12}]
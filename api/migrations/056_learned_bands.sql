@@ -0,0 +1,18 @@
+-- Migration: Learned Bands
+-- Description: Series history for learned dynamic thresholds on row counts, freshness lags and alert metrics
+
+-- Metric observations: One value of a series per check; bands are learned from the daily averages
+CREATE TABLE IF NOT EXISTS neuronip.metric_observations (
+    id BIGSERIAL PRIMARY KEY,
+    series_kind TEXT NOT NULL CHECK (series_kind IN ('row_count', 'freshness_lag', 'metric')),
+    series_key TEXT NOT NULL, -- connector/schema.table, freshness monitor ID or metric name
+    value FLOAT8 NOT NULL,
+    observed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE neuronip.metric_observations IS 'Series history for learned dynamic thresholds';
+
+CREATE INDEX IF NOT EXISTS idx_metric_observations_series ON neuronip.metric_observations(series_kind, series_key, observed_at);
+
+-- Quality checks: The learned band a check was graded against, for rules with threshold_mode learned_band
+ALTER TABLE neuronip.data_quality_checks
+    ADD COLUMN IF NOT EXISTS band JSONB;
@@ -0,0 +1,50 @@
+-- Migration: Data Contracts
+-- Description: Versioned contracts between the producer and consumers of a dataset, validated on every connector sync
+
+-- Data contracts: One row per version; at most one version of a dataset is active
+CREATE TABLE IF NOT EXISTS neuronip.data_contracts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    connector_id UUID NOT NULL REFERENCES neuronip.data_source_connectors(id) ON DELETE CASCADE,
+    schema_name TEXT NOT NULL DEFAULT '',
+    table_name TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'active', 'superseded', 'retired')),
+    owner_id UUID NOT NULL REFERENCES neuronip.users(id), -- Producer
+    consumers UUID[] NOT NULL DEFAULT '{}',
+    columns JSONB NOT NULL DEFAULT '[]', -- [{"name": "id", "type": "bigint", "nullable": false, "constraints": {"unique": true}}]
+    freshness_sla JSONB, -- {"column": "updated_at", "max_lag_minutes": 60}
+    enforcement TEXT NOT NULL DEFAULT 'warn' CHECK (enforcement IN ('warn', 'block')),
+    changes JSONB NOT NULL DEFAULT '[]', -- Schema changes from the previous version
+    breaking BOOLEAN NOT NULL DEFAULT false,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    activated_at TIMESTAMPTZ,
+    UNIQUE(connector_id, schema_name, table_name, version)
+);
+COMMENT ON TABLE neuronip.data_contracts IS 'Versioned data contracts declaring a dataset''s schema, constraints and freshness SLA';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_contracts_active ON neuronip.data_contracts(connector_id, schema_name, table_name)
+    WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_data_contracts_owner ON neuronip.data_contracts(owner_id);
+
+-- Contract violations: Found while validating a connector sync against the active contract
+CREATE TABLE IF NOT EXISTS neuronip.data_contract_violations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    contract_id UUID NOT NULL REFERENCES neuronip.data_contracts(id) ON DELETE CASCADE,
+    sync_history_id UUID REFERENCES neuronip.connector_sync_history(id) ON DELETE SET NULL,
+    ingestion_job_id UUID REFERENCES neuronip.ingestion_jobs(id) ON DELETE SET NULL,
+    violation_type TEXT NOT NULL,
+    column_name TEXT,
+    message TEXT NOT NULL,
+    severity TEXT NOT NULL CHECK (severity IN ('breaking', 'non_breaking')),
+    details JSONB NOT NULL DEFAULT '{}',
+    detected_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE neuronip.data_contract_violations IS 'Data contract violations found on connector syncs and ingestion jobs';
+
+CREATE INDEX IF NOT EXISTS idx_data_contract_violations_contract ON neuronip.data_contract_violations(contract_id, detected_at);
+
+-- Sync history: Contract violations found on the sync
+ALTER TABLE neuronip.connector_sync_history
+    ADD COLUMN IF NOT EXISTS contract_violations INTEGER NOT NULL DEFAULT 0;
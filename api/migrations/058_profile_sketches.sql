@@ -0,0 +1,27 @@
+-- Migration: Profile Sketches
+-- Description: Mergeable column sketches from sketch-mode profiling, stored per partition so profiles grow as partitions arrive
+
+-- Profile sketches: One per column and partition; partition_column '' holds the whole-table sketch
+CREATE TABLE IF NOT EXISTS neuronip.profile_sketches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connector_id UUID NOT NULL REFERENCES neuronip.data_source_connectors(id) ON DELETE CASCADE,
+    schema_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    column_name TEXT NOT NULL,
+    partition_column TEXT NOT NULL DEFAULT '',
+    partition_value TEXT NOT NULL DEFAULT '',
+    sample_method TEXT NOT NULL CHECK (sample_method IN ('system', 'bernoulli', 'reservoir')),
+    sample_fraction FLOAT8 NOT NULL,
+    rows_read BIGINT NOT NULL DEFAULT 0,
+    null_count BIGINT NOT NULL DEFAULT 0,
+    numeric_count BIGINT NOT NULL DEFAULT 0,
+    min_value TEXT,
+    max_value TEXT,
+    hll BYTEA NOT NULL, -- HyperLogLog registers
+    tdigest JSONB, -- Null unless the column is numeric
+    top_k JSONB NOT NULL, -- Space-Saving counters
+    reservoir TEXT[], -- Uniform sample of values for pattern detection
+    profiled_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(connector_id, schema_name, table_name, column_name, partition_column, partition_value)
+);
+COMMENT ON TABLE neuronip.profile_sketches IS 'Mergeable HyperLogLog, t-digest and top-k sketches of profiled columns';
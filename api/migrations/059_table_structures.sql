@@ -0,0 +1,45 @@
+-- Migration: Table Structures
+-- Description: Discovered candidate keys, functional dependencies, correlations and suggested joins between tables
+
+-- Table structures: The latest structure discovery of each table
+CREATE TABLE IF NOT EXISTS neuronip.table_structures (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connector_id UUID NOT NULL REFERENCES neuronip.data_source_connectors(id) ON DELETE CASCADE,
+    schema_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    sample_rows INTEGER NOT NULL DEFAULT 0,
+    sample_fraction FLOAT8 NOT NULL DEFAULT 1,
+    candidate_keys JSONB NOT NULL DEFAULT '[]',
+    functional_dependencies JSONB NOT NULL DEFAULT '[]',
+    inclusion_dependencies JSONB NOT NULL DEFAULT '[]',
+    correlations JSONB NOT NULL DEFAULT '[]',
+    constant_columns TEXT[] NOT NULL DEFAULT '{}',
+    discovered_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(connector_id, schema_name, table_name)
+);
+COMMENT ON TABLE neuronip.table_structures IS 'Candidate keys, functional and inclusion dependencies and correlations discovered from table samples';
+
+-- Suggested joins: Foreign keys suggested by inclusion dependencies, reviewed before they are trusted
+CREATE TABLE IF NOT EXISTS neuronip.suggested_joins (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connector_id UUID NOT NULL REFERENCES neuronip.data_source_connectors(id) ON DELETE CASCADE,
+    from_schema TEXT NOT NULL,
+    from_table TEXT NOT NULL,
+    from_column TEXT NOT NULL,
+    to_schema TEXT NOT NULL,
+    to_table TEXT NOT NULL,
+    to_column TEXT NOT NULL,
+    coverage FLOAT8 NOT NULL, -- Share of the referencing column's distinct values found in the referenced key
+    confidence FLOAT8 NOT NULL,
+    status TEXT NOT NULL DEFAULT 'suggested' CHECK (status IN ('suggested', 'accepted', 'rejected')),
+    evidence JSONB NOT NULL DEFAULT '{}',
+    reviewed_by TEXT,
+    reviewed_at TIMESTAMPTZ,
+    discovered_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(connector_id, from_schema, from_table, from_column, to_schema, to_table, to_column)
+);
+COMMENT ON TABLE neuronip.suggested_joins IS 'Join paths suggested by inclusion dependencies between tables';
+
+CREATE INDEX IF NOT EXISTS idx_suggested_joins_from ON neuronip.suggested_joins(connector_id, from_schema, from_table);
+CREATE INDEX IF NOT EXISTS idx_suggested_joins_to ON neuronip.suggested_joins(connector_id, to_schema, to_table);
+CREATE INDEX IF NOT EXISTS idx_suggested_joins_status ON neuronip.suggested_joins(status);
@@ -0,0 +1,18 @@
+-- Migration: Profile Snapshots
+-- Description: Table profiles kept per run, so distribution drift can be measured between runs
+
+-- Profile snapshots: One per ProfileTable run; data_profiles keeps only the latest profile
+CREATE TABLE IF NOT EXISTS neuronip.profile_snapshots (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    profile_id UUID NOT NULL, -- The run's table profile result
+    connector_id UUID NOT NULL REFERENCES neuronip.data_source_connectors(id) ON DELETE CASCADE,
+    schema_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    mode TEXT NOT NULL CHECK (mode IN ('exact', 'sketch')),
+    row_count BIGINT NOT NULL DEFAULT 0,
+    columns JSONB NOT NULL DEFAULT '[]', -- Per column: type, null and distinct ratios, patterns, percentiles, categories
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE neuronip.profile_snapshots IS 'Per-run table profile snapshots compared for distribution drift';
+
+CREATE INDEX IF NOT EXISTS idx_profile_snapshots_table ON neuronip.profile_snapshots(connector_id, schema_name, table_name, created_at DESC);
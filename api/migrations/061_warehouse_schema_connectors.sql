@@ -0,0 +1,7 @@
+-- Migration: Warehouse Schema Connectors
+-- Description: The connector a warehouse schema describes, so profiling results of that connector can inform its queries
+
+ALTER TABLE neuronip.warehouse_schemas
+    ADD COLUMN IF NOT EXISTS connector_id UUID REFERENCES neuronip.data_source_connectors(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_warehouse_schemas_connector ON neuronip.warehouse_schemas(connector_id);
@@ -0,0 +1,32 @@
+-- Migration: Reports
+-- Description: Custom reports whose sections run validated SQL or query templates, and their generated results
+
+CREATE TABLE IF NOT EXISTS neuronip.reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    description TEXT,
+    category TEXT,
+    definition JSONB NOT NULL DEFAULT '{}', -- Sections, filters, aggregations and visualizations
+    schedule JSONB,
+    format TEXT NOT NULL DEFAULT 'json',
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_by UUID REFERENCES neuronip.users(id) ON DELETE SET NULL, -- Template sections run with this user's access to templates
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    metadata JSONB DEFAULT '{}'
+);
+COMMENT ON TABLE neuronip.reports IS 'Custom reports with SQL and query template sections';
+
+CREATE INDEX IF NOT EXISTS idx_reports_category ON neuronip.reports(category);
+CREATE INDEX IF NOT EXISTS idx_reports_created_at ON neuronip.reports(created_at DESC);
+
+CREATE TABLE IF NOT EXISTS neuronip.report_results (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    report_id UUID NOT NULL REFERENCES neuronip.reports(id) ON DELETE CASCADE,
+    generated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    status TEXT NOT NULL, -- completed, partial
+    result JSONB NOT NULL DEFAULT '{}'
+);
+COMMENT ON TABLE neuronip.report_results IS 'Generated report results';
+
+CREATE INDEX IF NOT EXISTS idx_report_results_report ON neuronip.report_results(report_id, generated_at DESC);